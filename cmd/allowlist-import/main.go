@@ -0,0 +1,96 @@
+// Команда allowlist-import импортирует GUID в список разрешенных для автоматического создания пользователей.
+// GUID читаются из файла, переданного первым аргументом, или из stdin, по одному на строку.
+// Пустые строки и строки, начинающиеся с #, пропускаются.
+//
+// Использование:
+//
+//	allowlist-import guids.txt
+//	cat guids.txt | allowlist-import
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/config"
+	"github.com/maksemen2/medods-task/internal/pkg/database"
+	"github.com/maksemen2/medods-task/internal/pkg/log"
+	postgresqlrepo "github.com/maksemen2/medods-task/internal/repository/postgresql"
+	"go.uber.org/zap"
+	"io"
+	"os"
+	"strings"
+)
+
+// batchSize - количество GUID, добавляемых одним запросом
+const batchSize = 1000
+
+// readGUIDs читает GUID из r по одному на строку.
+// Возвращает ошибку с номером строки, если GUID некорректен.
+func readGUIDs(r io.Reader) ([]uuid.UUID, error) {
+	var guids []uuid.UUID
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" || strings.HasPrefix(raw, "#") {
+			continue
+		}
+
+		guid, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		guids = append(guids, guid)
+	}
+
+	return guids, scanner.Err()
+}
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		panic("Failed to load config: " + err.Error())
+	}
+
+	logger, err := log.NewZapLogger(cfg.Logger.Level)
+	if err != nil {
+		panic("Failed to create logger: " + err.Error())
+	}
+
+	input := os.Stdin
+	if len(os.Args) > 1 {
+		input, err = os.Open(os.Args[1])
+		if err != nil {
+			logger.Fatal("Failed to open input file", zap.Error(err))
+		}
+		defer input.Close()
+	}
+
+	guids, err := readGUIDs(input)
+	if err != nil {
+		logger.Fatal("Failed to read GUIDs", zap.Error(err))
+	}
+
+	db, err := database.NewPostgresDB(cfg.Database.DSN(), cfg.Database.MaxOpenConns, cfg.Database.MaxIdleConns)
+	if err != nil {
+		logger.Fatal("Failed to connect to database", zap.Error(err))
+	}
+	defer db.Close()
+
+	allowlistRepo := postgresqlrepo.NewPostgresqlAllowlistRepo(db, logger)
+
+	var added int
+	for start := 0; start < len(guids); start += batchSize {
+		end := min(start+batchSize, len(guids))
+
+		n, err := allowlistRepo.Add(context.Background(), guids[start:end])
+		if err != nil {
+			logger.Fatal("Failed to import GUIDs", zap.Error(err), zap.Int("imported", added))
+		}
+		added += n
+	}
+
+	logger.Info("GUIDs imported", zap.Int("read", len(guids)), zap.Int("added", added))
+}
@@ -2,21 +2,106 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"github.com/maksemen2/medods-task/internal/config"
+	"github.com/maksemen2/medods-task/internal/delivery/grpc/authapi"
+	"github.com/maksemen2/medods-task/internal/delivery/grpc/extauthz"
 	"github.com/maksemen2/medods-task/internal/delivery/http/routes"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
 	"github.com/maksemen2/medods-task/internal/pkg/auth/jwt"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/magiclink"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/webauthn"
 	"github.com/maksemen2/medods-task/internal/pkg/database"
+	"github.com/maksemen2/medods-task/internal/pkg/geoip"
 	"github.com/maksemen2/medods-task/internal/pkg/log"
+	"github.com/maksemen2/medods-task/internal/pkg/mail"
+	"github.com/maksemen2/medods-task/internal/pkg/risk"
 	postgresqlrepo "github.com/maksemen2/medods-task/internal/repository/postgresql"
 	"github.com/maksemen2/medods-task/internal/service"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 )
 
+const magicLinkPurpose = "magic-link-login" // Назначение подписи ссылок для входа
+
+// loadRiskRules загружает правила оценки риска из файла.
+// Если путь не задан, возвращает правила по умолчанию.
+func loadRiskRules(path string) (risk.Rules, error) {
+	if path == "" {
+		return risk.DefaultRules(), nil
+	}
+	return risk.LoadRules(path)
+}
+
+// reloadRiskRulesOnSignal перечитывает правила оценки риска при получении SIGHUP.
+// При ошибке загрузки продолжают действовать предыдущие правила.
+func reloadRiskRulesOnSignal(engine *risk.RuleEngine, path string, logger *zap.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		rules, err := loadRiskRules(path)
+		if err != nil {
+			logger.Error("Failed to reload risk rules", zap.Error(err))
+			continue
+		}
+		engine.SetRules(rules)
+		logger.Info("Risk rules reloaded", zap.String("path", path))
+	}
+}
+
+// serveGRPC запускает gRPC сервер на адресе addr и регистрирует на нем сервис функцией register.
+// Если адрес не задан, сервер не запускается и возвращается nil.
+func serveGRPC(logger *zap.Logger, name, addr string, register func(*grpc.Server), opts ...grpc.ServerOption) *grpc.Server {
+	if addr == "" {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		logger.Fatal("Failed to listen gRPC address", zap.String("server", name), zap.Error(err))
+	}
+
+	server := grpc.NewServer(opts...)
+	register(server)
+
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			logger.Fatal("Failed to start gRPC server", zap.String("server", name), zap.Error(err))
+		}
+	}()
+
+	logger.Info("gRPC server started", zap.String("server", name), zap.String("addr", listener.Addr().String()))
+	return server
+}
+
+// stopGRPC дожидается завершения обрабатываемых gRPC запросов, но не дольше, чем до отмены ctx.
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	if server == nil {
+		return
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
+
 func main() {
 
 	cfg, err := config.Load()
@@ -35,11 +120,164 @@ func main() {
 	}
 	defer db.Close()
 
+	ipChangePolicy, err := domain.ParseIPChangePolicy(cfg.Auth.IPChangePolicy)
+	if err != nil {
+		logger.Fatal("Invalid auth config", zap.Error(err))
+	}
+
+	provisioningMode, err := domain.ParseProvisioningMode(cfg.Auth.ProvisioningMode)
+	if err != nil {
+		logger.Fatal("Invalid auth config", zap.Error(err))
+	}
+
 	tokenRepo := postgresqlrepo.NewPostgresqlTokenRepo(db, logger)
 	userRepo := postgresqlrepo.NewPostgresqlUserRepo(db, logger)
+	auditRepo := postgresqlrepo.NewPostgresqlAuditRepo(db, logger)
+	allowlistRepo := postgresqlrepo.NewPostgresqlAllowlistRepo(db, logger)
+	credentialRepo := postgresqlrepo.NewPostgresqlCredentialRepo(db, logger)
+	clientRepo := postgresqlrepo.NewPostgresqlClientRepo(db, logger)
+	roleRepo := postgresqlrepo.NewPostgresqlRoleRepo(db, logger)
 	tokenManager := jwt.NewManager([]byte(cfg.Auth.JWTSecret), time.Duration(cfg.Auth.AccessTTL)*time.Second)
 
-	router := routes.New(logger, service.NewAuthServiceImpl(userRepo, tokenRepo, tokenManager, logger, time.Duration(cfg.Auth.RefreshTTL)*time.Second))
+	serviceOpts := []service.AuthServiceOption{
+		service.WithIPChangePolicy(ipChangePolicy),
+		service.WithAuditRepo(auditRepo),
+		service.WithProvisioning(provisioningMode, allowlistRepo),
+		service.WithCredentialRepo(credentialRepo),
+		service.WithRoles(roleRepo),
+		service.WithVerifyCacheTTL(time.Duration(cfg.Auth.VerifyCacheTTL) * time.Second),
+	}
+
+	if cfg.GeoIP.DBPath != "" {
+		geoLocator, err := geoip.NewMMDBLocator(cfg.GeoIP.DBPath)
+		if err != nil {
+			logger.Fatal("Failed to open GeoIP database", zap.Error(err))
+		}
+		defer geoLocator.Close()
+
+		serviceOpts = append(serviceOpts, service.WithGeoLocator(geoLocator, cfg.GeoIP.MaxTravelSpeed))
+	}
+
+	if cfg.MFA.EncryptionKey != "" {
+		key, err := base64.StdEncoding.DecodeString(cfg.MFA.EncryptionKey)
+		if err != nil {
+			logger.Fatal("Invalid MFA encryption key", zap.Error(err))
+		}
+
+		secretCipher, err := crypto.NewCipher(key)
+		if err != nil {
+			logger.Fatal("Invalid MFA encryption key", zap.Error(err))
+		}
+
+		serviceOpts = append(serviceOpts, service.WithTOTP(
+			postgresqlrepo.NewPostgresqlTOTPRepo(db, logger),
+			postgresqlrepo.NewPostgresqlMFAChallengeRepo(db, logger),
+			secretCipher, cfg.MFA.Issuer, time.Duration(cfg.MFA.ChallengeTTL)*time.Second,
+		), service.WithRecoveryCodes(postgresqlrepo.NewPostgresqlRecoveryCodeRepo(db, logger)))
+	} else {
+		logger.Warn("MFA_ENCRYPTION_KEY is not set, two-factor authentication is disabled")
+	}
+
+	if cfg.WebAuthn.RPID != "" {
+		ceremonyTTL := time.Duration(cfg.WebAuthn.Timeout) * time.Second
+
+		relyingParty, err := webauthn.NewRelyingParty(cfg.WebAuthn.RPID, cfg.WebAuthn.RPName, cfg.WebAuthn.Origins, ceremonyTTL)
+		if err != nil {
+			logger.Fatal("Invalid WebAuthn config", zap.Error(err))
+		}
+
+		serviceOpts = append(serviceOpts, service.WithWebAuthn(
+			relyingParty,
+			postgresqlrepo.NewPostgresqlPasskeyRepo(db, logger),
+			postgresqlrepo.NewPostgresqlWebAuthnSessionRepo(db, logger),
+			ceremonyTTL,
+		))
+	}
+
+	var mailSender mail.Sender = mail.NewLogSender(logger)
+	if cfg.Mail.FilePath != "" {
+		mailSender = mail.NewFileSender(cfg.Mail.FilePath)
+	}
+
+	if cfg.MagicLink.Secret != "" {
+		serviceOpts = append(serviceOpts, service.WithMagicLink(
+			magiclink.NewSigner([]byte(cfg.MagicLink.Secret), magicLinkPurpose),
+			postgresqlrepo.NewPostgresqlMagicLinkRepo(db, logger),
+			mailSender, cfg.MagicLink.URL, time.Duration(cfg.MagicLink.TTL)*time.Second,
+		))
+	}
+
+	if cfg.PasswordReset.Enabled {
+		serviceOpts = append(serviceOpts, service.WithPasswordReset(
+			postgresqlrepo.NewPostgresqlPasswordResetRepo(db, logger),
+			mailSender, cfg.PasswordReset.URL, time.Duration(cfg.PasswordReset.TTL)*time.Second,
+		))
+	}
+
+	if cfg.OAuth.Enabled {
+		serviceOpts = append(serviceOpts, service.WithOAuth(
+			clientRepo,
+			postgresqlrepo.NewPostgresqlAuthorizationCodeRepo(db, logger),
+			time.Duration(cfg.OAuth.CodeTTL)*time.Second,
+		))
+
+		serviceOpts = append(serviceOpts, service.WithDeviceAuthorization(
+			postgresqlrepo.NewPostgresqlDeviceAuthorizationRepo(db, logger),
+			cfg.OAuth.DeviceVerificationURL,
+			time.Duration(cfg.OAuth.DeviceCodeTTL)*time.Second,
+			time.Duration(cfg.OAuth.DevicePollInterval)*time.Second,
+		))
+		serviceOpts = append(serviceOpts, service.WithTokenExchangeTTL(time.Duration(cfg.OAuth.TokenExchangeTTL)*time.Second))
+	}
+
+	var userServiceOpts []service.UserServiceOption
+	if cfg.EmailVerification.Enabled || cfg.EmailVerification.Required {
+		userServiceOpts = append(userServiceOpts, service.WithEmailVerification(
+			postgresqlrepo.NewPostgresqlEmailVerificationRepo(db, logger),
+			mailSender, cfg.EmailVerification.URL,
+			time.Duration(cfg.EmailVerification.TTL)*time.Second,
+			time.Duration(cfg.EmailVerification.ResendInterval)*time.Second,
+		))
+		serviceOpts = append(serviceOpts, service.WithEmailVerifiedClaim(cfg.EmailVerification.Required))
+	}
+
+	if cfg.Risk.Enabled {
+		rules, err := loadRiskRules(cfg.Risk.RulesPath)
+		if err != nil {
+			logger.Fatal("Failed to load risk rules", zap.Error(err))
+		}
+
+		riskEngine := risk.NewRuleEngine(rules)
+		failureWindow := time.Duration(cfg.Risk.FailureWindow) * time.Second
+		serviceOpts = append(serviceOpts, service.WithRiskEngine(riskEngine, risk.NewMemoryFailureTracker(failureWindow)))
+
+		// Правила перечитываются по SIGHUP, чтобы их можно было настраивать без перезапуска сервиса
+		go reloadRiskRulesOnSignal(riskEngine, cfg.Risk.RulesPath, logger)
+	}
+
+	authService := service.NewAuthServiceImpl(
+		userRepo, tokenRepo, tokenManager, logger, time.Duration(cfg.Auth.RefreshTTL)*time.Second,
+		serviceOpts...,
+	)
+
+	if cfg.Admin.APIKey == "" {
+		logger.Warn("ADMIN_API_KEY is not set, admin endpoints are disabled")
+	}
+
+	userService := service.NewUserServiceImpl(userRepo, logger, userServiceOpts...)
+	auditService := service.NewAuditServiceImpl(auditRepo, logger)
+	provisioningService := service.NewProvisioningServiceImpl(allowlistRepo, logger)
+	var clientServiceOpts []service.ClientServiceOption
+	if cfg.OAuth.Enabled && len(cfg.OAuth.RegistrationTokens) > 0 {
+		clientServiceOpts = append(clientServiceOpts, service.WithRegistration(
+			cfg.OAuth.RegistrationTokens, cfg.OAuth.RegistrationScopes, cfg.OAuth.RegistrationURL,
+		))
+	}
+
+	clientService := service.NewClientServiceImpl(clientRepo, logger, clientServiceOpts...)
+	roleService := service.NewRoleServiceImpl(roleRepo, userRepo, logger)
+
+	router := routes.New(logger, authService, userService, auditService, provisioningService, clientService, roleService, cfg.Admin.APIKey)
 
 	srv := &http.Server{
 		Addr:    cfg.HTTP.Addr(),
@@ -54,13 +292,26 @@ func main() {
 
 	logger.Info("Server started", zap.String("addr", srv.Addr))
 
+	extAuthzServer := serveGRPC(logger, "ext_authz", cfg.ExtAuthz.Addr, extauthz.NewServer(logger, authService).Register)
+	grpcAddr := cfg.GRPC.Addr
+	if grpcAddr != "" && cfg.GRPC.APIKey == "" {
+		logger.Warn("GRPC_API_KEY is not set, gRPC API is disabled")
+		grpcAddr = ""
+	}
+	grpcServer := serveGRPC(logger, "grpc", grpcAddr, authapi.NewServer(logger, authService).Register,
+		grpc.UnaryInterceptor(authapi.NewAPIKeyInterceptor(cfg.GRPC.APIKey)))
+
 	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, os.Kill)
+	signal.Notify(quit, syscall.SIGTERM, os.Interrupt)
 	<-quit
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	for _, server := range []*grpc.Server{extAuthzServer, grpcServer} {
+		stopGRPC(ctx, server)
+	}
+
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Fatal("Server forced to shutdown", zap.Error(err))
 	}
@@ -6,14 +6,14 @@ import (
 )
 
 type DatabaseConfig struct {
-	Host         string `env:"DB_HOST" env-default:"localhost"`
-	Port         string `env:"DB_PORT" env-default:"5432"`
-	User         string `env:"DB_USER" env-default:"postgres"`
-	Pass         string `env:"DB_PASS" env-default:"postgres"`
-	Name         string `env:"DB_NAME" env-default:"postgres"`
-	SSLMode      string `env:"DB_SSLMODE" env-default:"disable"`
-	MaxOpenConns int    `env:"DB_MAX_OPEN_CONNS" env-default:"10"`
-	MaxIdleConns int    `env:"DB_MAX_IDLE_CONNS" env-default:"5"`
+	Host         string `env:"DB_HOST" envDefault:"localhost"`
+	Port         string `env:"DB_PORT" envDefault:"5432"`
+	User         string `env:"DB_USER" envDefault:"postgres"`
+	Pass         string `env:"DB_PASS" envDefault:"postgres"`
+	Name         string `env:"DB_NAME" envDefault:"postgres"`
+	SSLMode      string `env:"DB_SSLMODE" envDefault:"disable"`
+	MaxOpenConns int    `env:"DB_MAX_OPEN_CONNS" envDefault:"10"`
+	MaxIdleConns int    `env:"DB_MAX_IDLE_CONNS" envDefault:"5"`
 }
 
 func (c *DatabaseConfig) DSN() string {
@@ -24,14 +24,87 @@ func (c *DatabaseConfig) DSN() string {
 }
 
 type AuthConfig struct {
-	JWTSecret  string `env:"JWT_SECRET" env-default:"secret"`
-	AccessTTL  int    `env:"ACCESS_EXPIRATION_SECONDS" env-default:"1800"`    // Время жизни Access-токена в секундах, по умолчанию 30 минут
-	RefreshTTL int    `env:"REFRESH_EXPIRATION_SECONDS" env-default:"604800"` // Время жизни Refresh-токена в секундах, по умолчанию 7 дней
+	JWTSecret  string `env:"JWT_SECRET" envDefault:"secret"`
+	AccessTTL  int    `env:"ACCESS_EXPIRATION_SECONDS" envDefault:"1800"`    // Время жизни Access-токена в секундах, по умолчанию 30 минут
+	RefreshTTL int    `env:"REFRESH_EXPIRATION_SECONDS" envDefault:"604800"` // Время жизни Refresh-токена в секундах, по умолчанию 7 дней
+	// Политика при обновлении токенов с другого IP: notify, deny или step_up.
+	// Может быть переопределена для конкретного пользователя.
+	IPChangePolicy string `env:"IP_CHANGE_POLICY" envDefault:"notify"`
+	// Режим создания пользователей при аутентификации по незарегистрированному GUID: auto, deny или allowlist.
+	ProvisioningMode string `env:"PROVISIONING_MODE" envDefault:"auto"`
+	// Время в секундах, на которое /verify запоминает результат проверки отзыва токена, по умолчанию 5 секунд
+	VerifyCacheTTL int `env:"VERIFY_CACHE_TTL_SECONDS" envDefault:"5"`
+}
+
+type GeoIPConfig struct {
+	DBPath         string  `env:"GEOIP_DB_PATH"`                           // Путь к базе в формате MaxMind DB (.mmdb). Если не задан, GeoIP отключен
+	MaxTravelSpeed float64 `env:"GEOIP_MAX_TRAVEL_SPEED" envDefault:"900"` // Скорость в км/ч, выше которой перемещение между операциями считается невозможным
+}
+
+type RiskConfig struct {
+	Enabled       bool   `env:"RISK_ENABLED" envDefault:"false"`              // Включает оценку риска операций выдачи и обновления токенов
+	RulesPath     string `env:"RISK_RULES_PATH"`                              // Путь к JSON файлу с правилами. Если не задан, используются правила по умолчанию
+	FailureWindow int    `env:"RISK_FAILURE_WINDOW_SECONDS" envDefault:"900"` // Окно учета неудачных попыток в секундах, по умолчанию 15 минут
+}
+
+type MFAConfig struct {
+	EncryptionKey string `env:"MFA_ENCRYPTION_KEY"`                         // Ключ шифрования секретов TOTP в base64 (32 байта). Если не задан, второй фактор отключен
+	Issuer        string `env:"MFA_ISSUER" envDefault:"medods-task"`        // Название сервиса в приложении-аутентификаторе
+	ChallengeTTL  int    `env:"MFA_CHALLENGE_TTL_SECONDS" envDefault:"300"` // Время жизни MFA-челленджа в секундах, по умолчанию 5 минут
+}
+
+type WebAuthnConfig struct {
+	RPID    string   `env:"WEBAUTHN_RP_ID"`                            // Домен сайта (RP ID). Если не задан, вход по passkey отключен
+	RPName  string   `env:"WEBAUTHN_RP_NAME" envDefault:"medods-task"` // Отображаемое название сайта
+	Origins []string `env:"WEBAUTHN_ORIGINS" envSeparator:","`         // Origin, с которых разрешены церемонии WebAuthn, через запятую
+	Timeout int      `env:"WEBAUTHN_TIMEOUT_SECONDS" envDefault:"300"` // Время на церемонию WebAuthn в секундах, по умолчанию 5 минут
+}
+
+type MailConfig struct {
+	FilePath string `env:"MAIL_FILE_PATH"` // Файл, в который дописываются письма. Если не задан, письма пишутся в лог
+}
+
+type MagicLinkConfig struct {
+	Secret string `env:"MAGIC_LINK_SECRET"`                                                    // Ключ подписи ссылок для входа. Если не задан, вход по ссылке отключен
+	URL    string `env:"MAGIC_LINK_URL" envDefault:"http://localhost:8080/login/magic/verify"` // Адрес подтверждения, к которому добавляется параметр token
+	TTL    int    `env:"MAGIC_LINK_TTL_SECONDS" envDefault:"900"`                              // Время жизни ссылки в секундах, по умолчанию 15 минут
+}
+
+type PasswordResetConfig struct {
+	Enabled bool   `env:"PASSWORD_RESET_ENABLED"`                                               // Включить сброс пароля по ссылке из письма
+	URL     string `env:"PASSWORD_RESET_URL" envDefault:"http://localhost:8080/password/reset"` // Адрес страницы сброса пароля, к которому добавляется параметр token
+	TTL     int    `env:"PASSWORD_RESET_TTL_SECONDS" envDefault:"3600"`                         // Время жизни ссылки в секундах, по умолчанию час
+}
+
+type EmailVerificationConfig struct {
+	Enabled        bool   `env:"EMAIL_VERIFICATION_ENABLED"`                                             // Отправлять письма подтверждения email
+	Required       bool   `env:"EMAIL_VERIFICATION_REQUIRED"`                                            // Выдавать токены по /auth только пользователям с подтвержденным email
+	URL            string `env:"EMAIL_VERIFICATION_URL" envDefault:"http://localhost:8080/email/verify"` // Адрес подтверждения, к которому добавляется параметр token
+	TTL            int    `env:"EMAIL_VERIFICATION_TTL_SECONDS" envDefault:"86400"`                      // Время жизни токена подтверждения в секундах, по умолчанию сутки
+	ResendInterval int    `env:"EMAIL_VERIFICATION_RESEND_INTERVAL_SECONDS" envDefault:"60"`             // Минимальный интервал между отправками письма в секундах
+}
+
+type OAuthConfig struct {
+	Enabled bool `env:"OAUTH_ENABLED"`                          // Включает сервер авторизации OAuth. Клиенты регистрируются через /admin/clients
+	CodeTTL int  `env:"OAUTH_CODE_TTL_SECONDS" envDefault:"60"` // Время жизни кода авторизации в секундах, по умолчанию минута
+	// Токены первичного доступа партнеров для динамической регистрации клиентов через запятую. Если не заданы, /register отключен
+	RegistrationTokens []string `env:"OAUTH_REGISTRATION_TOKENS" envSeparator:","`
+	RegistrationScopes []string `env:"OAUTH_REGISTRATION_SCOPES" envSeparator:","`                         // Области доступа, которые партнеры могут запросить для своих клиентов
+	RegistrationURL    string   `env:"OAUTH_REGISTRATION_URL" envDefault:"http://localhost:8080/register"` // Внешний адрес эндпоинта /register
+	// Адрес страницы ввода кода устройства, который показывается пользователю в ответе /device/code
+	DeviceVerificationURL string `env:"OAUTH_DEVICE_VERIFICATION_URL" envDefault:"http://localhost:8080/device"`
+	DeviceCodeTTL         int    `env:"OAUTH_DEVICE_CODE_TTL_SECONDS" envDefault:"600"`    // Время жизни кода устройства в секундах, по умолчанию 10 минут
+	DevicePollInterval    int    `env:"OAUTH_DEVICE_POLL_INTERVAL_SECONDS" envDefault:"5"` // Минимальный интервал опроса /token устройством в секундах
+	TokenExchangeTTL      int    `env:"OAUTH_TOKEN_EXCHANGE_TTL_SECONDS" envDefault:"300"` // Время жизни токенов, полученных обменом, в секундах, по умолчанию 5 минут
+}
+
+type AdminConfig struct {
+	APIKey string `env:"ADMIN_API_KEY"` // API-ключ административных эндпоинтов. Если не задан, административные эндпоинты недоступны
 }
 
 type HTTPConfig struct {
-	Host string `env:"HTTP_HOST" env-default:"0.0.0.0"`
-	Port string `env:"HTTP_PORT" env-default:"8080"`
+	Host string `env:"HTTP_HOST" envDefault:"0.0.0.0"`
+	Port string `env:"HTTP_PORT" envDefault:"8080"`
 }
 
 func (c HTTPConfig) Addr() string {
@@ -41,15 +114,36 @@ func (c HTTPConfig) Addr() string {
 	return fmt.Sprintf("%s:%s", c.Host, c.Port)
 }
 
+type ExtAuthzConfig struct {
+	Addr string `env:"EXT_AUTHZ_ADDR"` // Адрес gRPC сервера внешней авторизации Envoy, например :9001. Если не задан, сервер не запускается
+}
+
+type GRPCConfig struct {
+	Addr   string `env:"GRPC_ADDR"`    // Адрес gRPC API аутентификации, например :9090. Если не задан, сервер не запускается
+	APIKey string `env:"GRPC_API_KEY"` // Ключ сервисов, вызывающих gRPC API аутентификации. Если не задан, сервер не запускается
+}
+
 type LoggerConfig struct {
-	Level string `env:"LOG_LEVEL" env-default:"debug"` // debug, info, warn, error или silent
+	Level string `env:"LOG_LEVEL" envDefault:"debug"` // debug, info, warn, error или silent
 }
 
 type Config struct {
-	Database DatabaseConfig
-	Auth     AuthConfig
-	HTTP     HTTPConfig
-	Logger   LoggerConfig
+	Database          DatabaseConfig
+	Auth              AuthConfig
+	GeoIP             GeoIPConfig
+	Risk              RiskConfig
+	MFA               MFAConfig
+	WebAuthn          WebAuthnConfig
+	Mail              MailConfig
+	MagicLink         MagicLinkConfig
+	PasswordReset     PasswordResetConfig
+	EmailVerification EmailVerificationConfig
+	OAuth             OAuthConfig
+	Admin             AdminConfig
+	HTTP              HTTPConfig
+	ExtAuthz          ExtAuthzConfig
+	GRPC              GRPCConfig
+	Logger            LoggerConfig
 }
 
 func Load() (*Config, error) {
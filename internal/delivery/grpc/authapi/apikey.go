@@ -0,0 +1,29 @@
+package authapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// NewAPIKeyInterceptor - перехватчик унарных вызовов, который пропускает только вызовы с ключом сервиса
+// в метаданных "authorization: Bearer <ключ>". API выдает токены по GUID пользователя, поэтому вызывать его
+// могут только доверенные сервисы. Ключ сравнивается за постоянное время. Если ключ не задан, все вызовы отклоняются.
+func NewAPIKeyInterceptor(apiKey string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var provided string
+		if values := metadata.ValueFromIncomingContext(ctx, "authorization"); len(values) > 0 {
+			provided, _ = strings.CutPrefix(values[0], "Bearer ")
+		}
+
+		if apiKey == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(apiKey)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid service credentials")
+		}
+
+		return handler(ctx, req)
+	}
+}
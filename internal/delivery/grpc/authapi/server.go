@@ -0,0 +1,146 @@
+package authapi
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	authv1 "github.com/maksemen2/medods-task/api/proto/auth/v1"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/service"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"net"
+)
+
+// Server - имплементация gRPC API аутентификации (auth.v1.AuthService) для внутренних сервисов.
+// Повторяет поведение HTTP эндпоинтов GET /auth и POST /refresh поверх service.IAuthService.
+type Server struct {
+	authv1.UnimplementedAuthServiceServer
+	logger  *zap.Logger
+	service service.IAuthService
+}
+
+func NewServer(logger *zap.Logger, service service.IAuthService) *Server {
+	return &Server{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// Register регистрирует API аутентификации на gRPC сервере.
+func (s *Server) Register(server *grpc.Server) {
+	authv1.RegisterAuthServiceServer(server, s)
+}
+
+// handleError преобразует доменные ошибки в ошибки gRPC с кодами, соответствующими статусам HTTP эндпоинтов
+func (s *Server) handleError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrUnexpected):
+		return status.Error(codes.Internal, "internal error")
+	case errors.Is(err, domain.ErrInvalidAccessToken), errors.Is(err, domain.ErrInvalidRefreshToken), errors.Is(err, domain.ErrTokenNotFound):
+		return status.Error(codes.InvalidArgument, "invalid token")
+	case errors.Is(err, domain.ErrUserNotFound):
+		return status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, domain.ErrIPChangeDenied), errors.Is(err, domain.ErrRiskDenied):
+		return status.Error(codes.Unauthenticated, "request denied")
+	case errors.Is(err, domain.ErrEmailNotVerified):
+		return status.Error(codes.PermissionDenied, "email not verified")
+	default:
+		s.logger.Error("unexpected error from authService", zap.Error(err))
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// clientInfo возвращает IP-адрес и User-Agent клиента, которые сервис использует так же, как для HTTP запросов.
+func clientInfo(ctx context.Context) (ip, userAgent string) {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("user-agent"); len(values) > 0 {
+			userAgent = values[0]
+		}
+	}
+
+	return ip, userAgent
+}
+
+func tokenPair(domainAuth *domain.UserAuth) *authv1.TokenPair {
+	return &authv1.TokenPair{
+		AccessToken:  domainAuth.AccessToken,
+		RefreshToken: domainAuth.RefreshToken,
+	}
+}
+
+// AuthenticateUser выдает пару токенов пользователю по GUID или MFA-челлендж, если требуется второй фактор.
+func (s *Server) AuthenticateUser(ctx context.Context, req *authv1.AuthenticateUserRequest) (*authv1.AuthenticateUserResponse, error) {
+	guid, err := uuid.Parse(req.GetGuid())
+	if err != nil {
+		s.logger.Debug("error parsing guid", zap.Error(err))
+		return nil, status.Error(codes.InvalidArgument, "invalid guid")
+	}
+
+	ip, userAgent := clientInfo(ctx)
+	domainAuth, err := s.service.AuthenticateUser(ctx, guid, ip, userAgent)
+	if err != nil {
+		return nil, s.handleError(err)
+	}
+
+	if domainAuth.MFARequired() {
+		return &authv1.AuthenticateUserResponse{
+			Result: &authv1.AuthenticateUserResponse_MfaChallenge{
+				MfaChallenge: &authv1.MFAChallenge{
+					MfaToken:  domainAuth.MFAToken,
+					ExpiresAt: timestamppb.New(domainAuth.MFAExpiresAt),
+				},
+			},
+		}, nil
+	}
+
+	return &authv1.AuthenticateUserResponse{
+		Result: &authv1.AuthenticateUserResponse_Tokens{Tokens: tokenPair(domainAuth)},
+	}, nil
+}
+
+// RefreshToken обновляет пару токенов.
+func (s *Server) RefreshToken(ctx context.Context, req *authv1.RefreshTokenRequest) (*authv1.RefreshTokenResponse, error) {
+	if req.GetAccessToken() == "" || req.GetRefreshToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "access_token and refresh_token are required")
+	}
+
+	ip, userAgent := clientInfo(ctx)
+	domainAuth, err := s.service.RefreshToken(ctx, req.GetAccessToken(), req.GetRefreshToken(), ip, userAgent)
+	if err != nil {
+		return nil, s.handleError(err)
+	}
+
+	return &authv1.RefreshTokenResponse{Tokens: tokenPair(domainAuth)}, nil
+}
+
+// RevokeToken отзывает сессию Access токена или все сессии пользователя, если передан all_sessions.
+func (s *Server) RevokeToken(ctx context.Context, req *authv1.RevokeTokenRequest) (*authv1.RevokeTokenResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.Unauthenticated, "access_token is required")
+	}
+
+	ip, userAgent := clientInfo(ctx)
+	revoked, err := s.service.RevokeToken(ctx, req.GetAccessToken(), req.GetAllSessions(), ip, userAgent)
+	if err != nil {
+		// Невалидный Access токен здесь означает отсутствие аутентификации, а не ошибку в запросе
+		if errors.Is(err, domain.ErrInvalidAccessToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return nil, s.handleError(err)
+	}
+
+	return &authv1.RevokeTokenResponse{RevokedSessions: int32(revoked)}, nil
+}
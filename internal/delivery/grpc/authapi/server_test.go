@@ -0,0 +1,196 @@
+package authapi_test
+
+import (
+	"context"
+	"github.com/google/uuid"
+	authv1 "github.com/maksemen2/medods-task/api/proto/auth/v1"
+	"github.com/maksemen2/medods-task/internal/delivery/grpc/authapi"
+	"github.com/maksemen2/medods-task/internal/domain"
+	mock_service "github.com/maksemen2/medods-task/internal/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+	"time"
+)
+
+const serviceKey = "service-key"
+
+// newClient поднимает API аутентификации в памяти процесса и возвращает подключенный к нему клиент с ключом сервиса.
+func newClient(t *testing.T) (authv1.AuthServiceClient, *mock_service.MockIAuthService) {
+	return newClientWithKey(t, serviceKey)
+}
+
+// newClientWithKey возвращает клиент, который передает ключ сервиса apiKey или, если он пуст, не передает ключ.
+func newClientWithKey(t *testing.T, apiKey string) (authv1.AuthServiceClient, *mock_service.MockIAuthService) {
+	ctrl := gomock.NewController(t)
+	authService := mock_service.NewMockIAuthService(ctrl)
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(grpc.UnaryInterceptor(authapi.NewAPIKeyInterceptor(serviceKey)))
+	authapi.NewServer(zap.NewNop(), authService).Register(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	withKey := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if apiKey != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+apiKey)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(withKey),
+		grpc.WithUserAgent("orders-service"))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return authv1.NewAuthServiceClient(conn), authService
+}
+
+func TestServer_AuthenticateUser(t *testing.T) {
+	t.Run("token pair", func(t *testing.T) {
+		client, authService := newClient(t)
+		guid := uuid.New()
+
+		authService.EXPECT().AuthenticateUser(gomock.Any(), guid, "bufconn", gomock.Any()).
+			DoAndReturn(func(ctx context.Context, guid uuid.UUID, ip, userAgent string) (*domain.UserAuth, error) {
+				assert.Contains(t, userAgent, "orders-service")
+				return &domain.UserAuth{AccessToken: "access", RefreshToken: "refresh"}, nil
+			})
+
+		resp, err := client.AuthenticateUser(context.Background(), &authv1.AuthenticateUserRequest{Guid: guid.String()})
+		require.NoError(t, err)
+		assert.Equal(t, "access", resp.GetTokens().GetAccessToken())
+		assert.Equal(t, "refresh", resp.GetTokens().GetRefreshToken())
+	})
+
+	t.Run("mfa challenge", func(t *testing.T) {
+		client, authService := newClient(t)
+		guid := uuid.New()
+		expiresAt := time.Now().Add(5 * time.Minute).UTC()
+
+		authService.EXPECT().AuthenticateUser(gomock.Any(), guid, gomock.Any(), gomock.Any()).
+			Return(&domain.UserAuth{MFAToken: "mfa", MFAExpiresAt: expiresAt}, nil)
+
+		resp, err := client.AuthenticateUser(context.Background(), &authv1.AuthenticateUserRequest{Guid: guid.String()})
+		require.NoError(t, err)
+		assert.Nil(t, resp.GetTokens())
+		assert.Equal(t, "mfa", resp.GetMfaChallenge().GetMfaToken())
+		assert.True(t, expiresAt.Equal(resp.GetMfaChallenge().GetExpiresAt().AsTime()))
+	})
+
+	t.Run("invalid guid", func(t *testing.T) {
+		client, _ := newClient(t)
+
+		_, err := client.AuthenticateUser(context.Background(), &authv1.AuthenticateUserRequest{Guid: "not-a-guid"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		client, authService := newClient(t)
+
+		authService.EXPECT().AuthenticateUser(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, domain.ErrUserNotFound)
+
+		_, err := client.AuthenticateUser(context.Background(), &authv1.AuthenticateUserRequest{Guid: uuid.NewString()})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestServer_RefreshToken(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		client, authService := newClient(t)
+
+		authService.EXPECT().RefreshToken(gomock.Any(), "access", "refresh", gomock.Any(), gomock.Any()).
+			Return(&domain.UserAuth{AccessToken: "new-access", RefreshToken: "new-refresh"}, nil)
+
+		resp, err := client.RefreshToken(context.Background(), &authv1.RefreshTokenRequest{AccessToken: "access", RefreshToken: "refresh"})
+		require.NoError(t, err)
+		assert.Equal(t, "new-access", resp.GetTokens().GetAccessToken())
+		assert.Equal(t, "new-refresh", resp.GetTokens().GetRefreshToken())
+	})
+
+	t.Run("missing tokens", func(t *testing.T) {
+		client, _ := newClient(t)
+
+		_, err := client.RefreshToken(context.Background(), &authv1.RefreshTokenRequest{AccessToken: "access"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("invalid refresh token", func(t *testing.T) {
+		client, authService := newClient(t)
+
+		authService.EXPECT().RefreshToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, domain.ErrInvalidRefreshToken)
+
+		_, err := client.RefreshToken(context.Background(), &authv1.RefreshTokenRequest{AccessToken: "access", RefreshToken: "refresh"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("ip change denied", func(t *testing.T) {
+		client, authService := newClient(t)
+
+		authService.EXPECT().RefreshToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, domain.ErrIPChangeDenied)
+
+		_, err := client.RefreshToken(context.Background(), &authv1.RefreshTokenRequest{AccessToken: "access", RefreshToken: "refresh"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func TestServer_RevokeToken(t *testing.T) {
+	t.Run("all sessions", func(t *testing.T) {
+		client, authService := newClient(t)
+
+		authService.EXPECT().RevokeToken(gomock.Any(), "access", true, gomock.Any(), gomock.Any()).Return(3, nil)
+
+		resp, err := client.RevokeToken(context.Background(), &authv1.RevokeTokenRequest{AccessToken: "access", AllSessions: true})
+		require.NoError(t, err)
+		assert.Equal(t, int32(3), resp.GetRevokedSessions())
+	})
+
+	t.Run("no token", func(t *testing.T) {
+		client, _ := newClient(t)
+
+		_, err := client.RevokeToken(context.Background(), &authv1.RevokeTokenRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		client, authService := newClient(t)
+
+		authService.EXPECT().RevokeToken(gomock.Any(), "revoked", false, gomock.Any(), gomock.Any()).Return(0, domain.ErrInvalidAccessToken)
+
+		_, err := client.RevokeToken(context.Background(), &authv1.RevokeTokenRequest{AccessToken: "revoked"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("unexpected error", func(t *testing.T) {
+		client, authService := newClient(t)
+
+		authService.EXPECT().RevokeToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(0, domain.ErrUnexpected)
+
+		_, err := client.RevokeToken(context.Background(), &authv1.RevokeTokenRequest{AccessToken: "access"})
+		assert.Equal(t, codes.Internal, status.Code(err))
+	})
+}
+
+func TestAPIKeyInterceptor(t *testing.T) {
+	for name, apiKey := range map[string]string{"no key": "", "wrong key": "another-key"} {
+		t.Run(name, func(t *testing.T) {
+			// Мок без ожиданий: вызов без ключа не должен дойти до сервиса
+			client, _ := newClientWithKey(t, apiKey)
+
+			_, err := client.AuthenticateUser(context.Background(), &authv1.AuthenticateUserRequest{Guid: uuid.NewString()})
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package extauthz
+
+import (
+	"context"
+	"errors"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/oauth"
+	"github.com/maksemen2/medods-task/internal/service"
+	"go.uber.org/zap"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// Заголовки, которые Envoy добавляет в запрос к защищаемому сервису. Совпадают с заголовками ответа /verify
+const (
+	headerUserID      = "x-user-id"     // GUID пользователя или идентификатор клиента OAuth
+	headerTokenID     = "x-token-id"    // Идентификатор (jti) Access токена
+	headerScopes      = "x-scopes"      // Области доступа клиента OAuth через пробел
+	headerPermissions = "x-permissions" // Разрешения ролей пользователя через пробел
+	headerActor       = "x-actor"       // Сторона, действующая от имени пользователя (claim act)
+)
+
+// Server - имплементация внешней авторизации Envoy (envoy.service.auth.v3.Authorization).
+// Проверяет Access токен из заголовка Authorization так же, как эндпоинт /verify, но без лишнего HTTP перехода.
+type Server struct {
+	authv3.UnimplementedAuthorizationServer
+	logger  *zap.Logger
+	service service.IAuthService
+}
+
+func NewServer(logger *zap.Logger, service service.IAuthService) *Server {
+	return &Server{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// Register регистрирует сервер внешней авторизации на gRPC сервере.
+func (s *Server) Register(server *grpc.Server) {
+	authv3.RegisterAuthorizationServer(server, s)
+}
+
+// denied формирует ответ, с которым Envoy отклоняет запрос со статусом 401 и заголовком WWW-Authenticate (RFC 6750, раздел 3).
+func denied(challenge string) *authv3.CheckResponse {
+	return &authv3.CheckResponse{
+		Status: &rpcstatus.Status{Code: int32(codes.Unauthenticated)},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{
+			DeniedResponse: &authv3.DeniedHttpResponse{
+				Status:  &typev3.HttpStatus{Code: typev3.StatusCode_Unauthorized},
+				Headers: []*corev3.HeaderValueOption{header("WWW-Authenticate", challenge)},
+			},
+		},
+	}
+}
+
+// header формирует заголовок, который заменяет одноименный заголовок запроса.
+// Так клиент не может подставить свои значения заголовков идентификации.
+func header(key, value string) *corev3.HeaderValueOption {
+	return &corev3.HeaderValueOption{
+		Header:       &corev3.HeaderValue{Key: key, Value: value},
+		AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+	}
+}
+
+// Check проверяет подпись, срок действия и отзыв Access токена запроса.
+// Если токен действителен, Envoy пропускает запрос, добавив в него заголовки x-user-id, x-token-id, x-scopes,
+// x-permissions и x-actor для токенов, полученных обменом. У остальных токенов заголовок x-actor удаляется из запроса.
+// Иначе Envoy отвечает клиенту 401, в том числе для токенов, требующих повторной аутентификации (политика step_up),
+// с ошибкой insufficient_user_authentication (RFC 9470). При внутренней ошибке возвращается ошибка gRPC с кодом Internal,
+// и решение принимается по настройке failure_mode_allow фильтра ext_authz.
+func (s *Server) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	// Envoy передает названия заголовков в нижнем регистре
+	authorization := req.GetAttributes().GetRequest().GetHttp().GetHeaders()["authorization"]
+	accessToken, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok || accessToken == "" {
+		return denied(`Bearer`), nil
+	}
+
+	verified, err := s.service.VerifyToken(ctx, accessToken)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidAccessToken) {
+			return denied(`Bearer error="invalid_token"`), nil
+		}
+		if errors.Is(err, domain.ErrReauthRequired) {
+			return denied(`Bearer error="insufficient_user_authentication"`), nil
+		}
+		if !errors.Is(err, domain.ErrUnexpected) {
+			s.logger.Error("unexpected error from authService", zap.Error(err))
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	allowed := &authv3.OkHttpResponse{
+		Headers: []*corev3.HeaderValueOption{
+			header(headerUserID, verified.Subject),
+			header(headerTokenID, verified.JTI.String()),
+			header(headerScopes, oauth.FormatScope(verified.Scopes)),
+			header(headerPermissions, oauth.FormatScope(verified.Permissions)),
+		},
+	}
+	// Клиент не должен выдавать себя за сторону, действующую от имени пользователя
+	if verified.Actor != "" {
+		allowed.Headers = append(allowed.Headers, header(headerActor, verified.Actor))
+	} else {
+		allowed.HeadersToRemove = []string{headerActor}
+	}
+
+	return &authv3.CheckResponse{
+		Status:       &rpcstatus.Status{Code: int32(codes.OK)},
+		HttpResponse: &authv3.CheckResponse_OkResponse{OkResponse: allowed},
+	}, nil
+}
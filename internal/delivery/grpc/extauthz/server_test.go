@@ -0,0 +1,163 @@
+package extauthz_test
+
+import (
+	"context"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/delivery/grpc/extauthz"
+	"github.com/maksemen2/medods-task/internal/domain"
+	mock_service "github.com/maksemen2/medods-task/internal/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+)
+
+// newClient поднимает сервер внешней авторизации в памяти процесса и возвращает подключенный к нему клиент.
+func newClient(t *testing.T, authService *mock_service.MockIAuthService) authv3.AuthorizationClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	extauthz.NewServer(zap.NewNop(), authService).Register(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return authv3.NewAuthorizationClient(conn)
+}
+
+func checkRequest(headers map[string]string) *authv3.CheckRequest {
+	return &authv3.CheckRequest{
+		Attributes: &authv3.AttributeContext{
+			Request: &authv3.AttributeContext_Request{
+				Http: &authv3.AttributeContext_HttpRequest{Method: "GET", Path: "/orders", Headers: headers},
+			},
+		},
+	}
+}
+
+func TestServer_Check(t *testing.T) {
+	t.Run("valid token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		authService := mock_service.NewMockIAuthService(ctrl)
+		client := newClient(t, authService)
+
+		guid, jti := uuid.New(), uuid.New()
+		authService.EXPECT().VerifyToken(gomock.Any(), "access").Return(&domain.VerifiedToken{
+			Subject:     guid.String(),
+			JTI:         jti,
+			Permissions: []string{"orders:read"},
+		}, nil)
+
+		resp, err := client.Check(context.Background(), checkRequest(map[string]string{
+			"authorization": "Bearer access",
+			"x-user-id":     "spoofed",
+			"x-actor":       "spoofed",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, int32(codes.OK), resp.GetStatus().GetCode())
+		assert.Equal(t, []string{"x-actor"}, resp.GetOkResponse().GetHeadersToRemove())
+
+		headers := map[string]string{}
+		for _, option := range resp.GetOkResponse().GetHeaders() {
+			headers[option.GetHeader().GetKey()] = option.GetHeader().GetValue()
+			assert.Equal(t, "OVERWRITE_IF_EXISTS_OR_ADD", option.GetAppendAction().String())
+		}
+		assert.Equal(t, map[string]string{
+			"x-user-id":     guid.String(),
+			"x-token-id":    jti.String(),
+			"x-scopes":      "",
+			"x-permissions": "orders:read",
+		}, headers)
+	})
+
+	t.Run("exchanged token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		authService := mock_service.NewMockIAuthService(ctrl)
+		client := newClient(t, authService)
+
+		agent := uuid.New()
+		authService.EXPECT().VerifyToken(gomock.Any(), "access").Return(&domain.VerifiedToken{
+			Subject: uuid.NewString(),
+			JTI:     uuid.New(),
+			Actor:   agent.String(),
+		}, nil)
+
+		resp, err := client.Check(context.Background(), checkRequest(map[string]string{"authorization": "Bearer access"}))
+		require.NoError(t, err)
+
+		headers := map[string]string{}
+		for _, option := range resp.GetOkResponse().GetHeaders() {
+			headers[option.GetHeader().GetKey()] = option.GetHeader().GetValue()
+		}
+		assert.Equal(t, agent.String(), headers["x-actor"])
+		assert.Empty(t, resp.GetOkResponse().GetHeadersToRemove())
+	})
+
+	t.Run("no token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		client := newClient(t, mock_service.NewMockIAuthService(ctrl))
+
+		resp, err := client.Check(context.Background(), checkRequest(nil))
+		require.NoError(t, err)
+		assert.Equal(t, int32(codes.Unauthenticated), resp.GetStatus().GetCode())
+		assert.Equal(t, typev3.StatusCode_Unauthorized, resp.GetDeniedResponse().GetStatus().GetCode())
+	})
+
+	t.Run("invalid or revoked token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		authService := mock_service.NewMockIAuthService(ctrl)
+		client := newClient(t, authService)
+
+		authService.EXPECT().VerifyToken(gomock.Any(), "revoked").Return(nil, domain.ErrInvalidAccessToken)
+
+		resp, err := client.Check(context.Background(), checkRequest(map[string]string{"authorization": "Bearer revoked"}))
+		require.NoError(t, err)
+		assert.Equal(t, int32(codes.Unauthenticated), resp.GetStatus().GetCode())
+
+		denied := resp.GetDeniedResponse()
+		assert.Equal(t, typev3.StatusCode_Unauthorized, denied.GetStatus().GetCode())
+		require.Len(t, denied.GetHeaders(), 1)
+		assert.Equal(t, `Bearer error="invalid_token"`, denied.GetHeaders()[0].GetHeader().GetValue())
+	})
+
+	t.Run("token requires re-authentication", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		authService := mock_service.NewMockIAuthService(ctrl)
+		client := newClient(t, authService)
+
+		authService.EXPECT().VerifyToken(gomock.Any(), "step-up").Return(nil, domain.ErrReauthRequired)
+
+		resp, err := client.Check(context.Background(), checkRequest(map[string]string{"authorization": "Bearer step-up"}))
+		require.NoError(t, err)
+		assert.Equal(t, int32(codes.Unauthenticated), resp.GetStatus().GetCode())
+
+		denied := resp.GetDeniedResponse()
+		assert.Equal(t, typev3.StatusCode_Unauthorized, denied.GetStatus().GetCode())
+		require.Len(t, denied.GetHeaders(), 1)
+		assert.Equal(t, `Bearer error="insufficient_user_authentication"`, denied.GetHeaders()[0].GetHeader().GetValue())
+	})
+
+	t.Run("unexpected error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		authService := mock_service.NewMockIAuthService(ctrl)
+		client := newClient(t, authService)
+
+		authService.EXPECT().VerifyToken(gomock.Any(), "access").Return(nil, domain.ErrUnexpected)
+
+		_, err := client.Check(context.Background(), checkRequest(map[string]string{"authorization": "Bearer access"}))
+		assert.Equal(t, codes.Internal, status.Code(err))
+	})
+}
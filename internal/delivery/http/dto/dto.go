@@ -1,5 +1,7 @@
 package dto
 
+import "time"
+
 type AuthQueryParams struct {
 	GUID string `form:"guid" binding:"required"`
 }
@@ -13,3 +15,321 @@ type RefreshRequest struct {
 	AccessToken  string `json:"access_token" binding:"required"`
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
+
+type RegisterUserRequest struct {
+	Email       string `json:"email" binding:"required"`
+	DisplayName string `json:"display_name"`
+	Password    string `json:"password"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// MFAChallengeResponse возвращается вместо пары токенов, если у пользователя подключен второй фактор.
+type MFAChallengeResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// MFAVerifyRequest - запрос на проверку второго фактора. Передается либо код TOTP, либо код восстановления.
+type MFAVerifyRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code" binding:"required_without=RecoveryCode,excluded_with=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code" binding:"required_without=Code"`
+}
+
+type TOTPEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	Codes []string `json:"codes"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type MagicLinkQueryParams struct {
+	Token string `form:"token" binding:"required"`
+}
+
+type PasswordForgotRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type PasswordResetRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// AuthorizeParams - параметры запроса авторизации OAuth 2.0 с PKCE.
+// Передаются в query GET /authorize и скрытыми полями формы страницы входа.
+type AuthorizeParams struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	State               string `form:"state"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+	Scope               string `form:"scope"`
+}
+
+// AuthorizeForm - форма страницы входа OAuth.
+// На первом шаге передаются email и пароль, на шаге второго фактора - mfa_token и code.
+type AuthorizeForm struct {
+	AuthorizeParams
+	Email    string `form:"email"`
+	Password string `form:"password"`
+	MFAToken string `form:"mfa_token"`
+	Code     string `form:"code"`
+	Action   string `form:"action"`
+}
+
+// TokenRequest - параметры запроса к эндпоинту OAuth /token (application/x-www-form-urlencoded).
+type TokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"` // Секрет конфиденциального клиента, если он не передан в заголовке Authorization
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	AccessToken  string `form:"access_token"` // Access токен, с которым выдан Refresh токен
+	Scope        string `form:"scope"`        // Запрошенные области доступа (client_credentials, обмен токена)
+	DeviceCode   string `form:"device_code"`  // Код устройства (urn:ietf:params:oauth:grant-type:device_code)
+	// Параметры обмена токена (urn:ietf:params:oauth:grant-type:token-exchange, RFC 8693)
+	SubjectToken       string `form:"subject_token"`
+	SubjectTokenType   string `form:"subject_token_type"`
+	ActorToken         string `form:"actor_token"`
+	ActorTokenType     string `form:"actor_token_type"`
+	RequestedTokenType string `form:"requested_token_type"`
+}
+
+type TokenResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type,omitempty"` // Тип выданного токена, только для обмена токена
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int    `json:"expires_in"`
+	RefreshToken    string `json:"refresh_token,omitempty"`
+	Scope           string `json:"scope,omitempty"`
+}
+
+// DeviceCodeRequest - параметры запроса к эндпоинту /device/code (application/x-www-form-urlencoded).
+type DeviceCodeRequest struct {
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"` // Секрет конфиденциального клиента, если он не передан в заголовке Authorization
+	Scope        string `form:"scope"`
+}
+
+// DeviceCodeResponse - ответ эндпоинта /device/code (RFC 8628, раздел 3.2).
+type DeviceCodeResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// DeviceForm - форма страницы подтверждения устройства.
+// На первом шаге передаются пользовательский код, email и пароль, на шаге второго фактора - mfa_token и code.
+type DeviceForm struct {
+	UserCode string `form:"user_code"`
+	Email    string `form:"email"`
+	Password string `form:"password"`
+	MFAToken string `form:"mfa_token"`
+	Code     string `form:"code"`
+	Action   string `form:"action"`
+}
+
+// OAuthErrorResponse - ответ с ошибкой эндпоинта /token (RFC 6749, раздел 5.2).
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// ClientRequest - параметры клиента OAuth в запросах регистрации и изменения.
+// Время жизни токенов задается в секундах, 0 означает глобальное значение.
+type ClientRequest struct {
+	Name            string   `json:"name" binding:"required"`
+	Confidential    bool     `json:"confidential"` // Учитывается только при регистрации
+	GrantTypes      []string `json:"grant_types" binding:"required"`
+	RedirectURIs    []string `json:"redirect_uris"`
+	Scopes          []string `json:"scopes"`
+	AccessTokenTTL  int      `json:"access_token_ttl"`
+	RefreshTokenTTL int      `json:"refresh_token_ttl"`
+}
+
+type ClientResponse struct {
+	ClientID        string    `json:"client_id"`
+	ClientSecret    string    `json:"client_secret,omitempty"` // Возвращается только при регистрации
+	Name            string    `json:"name"`
+	Confidential    bool      `json:"confidential"`
+	GrantTypes      []string  `json:"grant_types"`
+	RedirectURIs    []string  `json:"redirect_uris"`
+	Scopes          []string  `json:"scopes"`
+	AccessTokenTTL  int       `json:"access_token_ttl"`
+	RefreshTokenTTL int       `json:"refresh_token_ttl"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type ClientListResponse struct {
+	Clients []ClientResponse `json:"clients"`
+}
+
+type ClientSecretResponse struct {
+	ClientSecret string `json:"client_secret"`
+}
+
+// ClientMetadata - метаданные клиента в запросах динамической регистрации (RFC 7591, раздел 2).
+// Неизвестные поля игнорируются.
+type ClientMetadata struct {
+	ClientID                string   `json:"client_id"` // Передается только при изменении регистрации (RFC 7592)
+	ClientName              string   `json:"client_name"`
+	RedirectURIs            []string `json:"redirect_uris"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
+	GrantTypes              []string `json:"grant_types"`
+	ResponseTypes           []string `json:"response_types"`
+	Scope                   string   `json:"scope"` // Области доступа через пробел
+}
+
+// ClientRegistrationResponse - ответ эндпоинта динамической регистрации (RFC 7591, раздел 3.2.1; RFC 7592, раздел 3).
+type ClientRegistrationResponse struct {
+	ClientID                string   `json:"client_id"`
+	ClientSecret            string   `json:"client_secret,omitempty"`            // Возвращается только при регистрации
+	ClientIDIssuedAt        int64    `json:"client_id_issued_at"`                // Время регистрации в Unix-секундах
+	ClientSecretExpiresAt   *int64   `json:"client_secret_expires_at,omitempty"` // 0 - секрет бессрочный. Передается вместе с секретом
+	RegistrationAccessToken string   `json:"registration_access_token,omitempty"`
+	RegistrationClientURI   string   `json:"registration_client_uri"`
+	ClientName              string   `json:"client_name"`
+	RedirectURIs            []string `json:"redirect_uris"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
+	GrantTypes              []string `json:"grant_types"`
+	ResponseTypes           []string `json:"response_types"`
+	Scope                   string   `json:"scope,omitempty"`
+}
+
+type EmailVerificationRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type EmailVerifyQueryParams struct {
+	Token string `form:"token" binding:"required"`
+}
+
+// Бинарные поля ответов аутентификатора передаются в base64url, как в PublicKeyCredential.toJSON().
+
+type PasskeyAttestationResponse struct {
+	ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+	AttestationObject string `json:"attestationObject" binding:"required"`
+}
+
+type PasskeyRegistrationRequest struct {
+	ID       string                     `json:"id" binding:"required"`
+	RawID    string                     `json:"rawId"`
+	Type     string                     `json:"type" binding:"required,eq=public-key"`
+	Response PasskeyAttestationResponse `json:"response" binding:"required"`
+}
+
+type PasskeyAssertionResponse struct {
+	ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+	AuthenticatorData string `json:"authenticatorData" binding:"required"`
+	Signature         string `json:"signature" binding:"required"`
+	UserHandle        string `json:"userHandle"`
+}
+
+type PasskeyLoginRequest struct {
+	ID       string                   `json:"id" binding:"required"`
+	RawID    string                   `json:"rawId"`
+	Type     string                   `json:"type" binding:"required,eq=public-key"`
+	Response PasskeyAssertionResponse `json:"response" binding:"required"`
+}
+
+type PasskeyResponse struct {
+	CredentialID string    `json:"credential_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type TOTPConfirmRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type UserResponse struct {
+	GUID        string    `json:"guid"`
+	Email       string    `json:"email"`
+	DisplayName string    `json:"display_name,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type AllowlistRequest struct {
+	GUIDs []string `json:"guids" binding:"required,min=1,max=10000,dive,uuid"`
+}
+
+type AllowlistResponse struct {
+	Added int `json:"added"`
+}
+
+// RoleRequest - разрешения роли в запросе ее создания или изменения. Пустой список снимает с роли все разрешения.
+type RoleRequest struct {
+	Permissions []string `json:"permissions" binding:"max=256"`
+}
+
+type RoleResponse struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
+
+type RoleListResponse struct {
+	Roles []RoleResponse `json:"roles"`
+}
+
+// UserRolesRequest - роли, которые назначаются пользователю вместо текущих. Пустой список снимает все роли.
+type UserRolesRequest struct {
+	Roles []string `json:"roles" binding:"max=64"`
+}
+
+// IPChangePolicyRequest - персональная политика смены IP пользователя.
+// Пустая строка сбрасывает переопределение к глобальной политике.
+type IPChangePolicyRequest struct {
+	Policy *string `json:"policy" binding:"required"`
+}
+
+type AuditQueryParams struct {
+	GUID   string    `form:"guid"`
+	Type   string    `form:"type"`
+	IP     string    `form:"ip"`
+	From   time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To     time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Cursor string    `form:"cursor"`
+	Limit  int       `form:"limit" binding:"omitempty,min=1"`
+	Format string    `form:"format" binding:"omitempty,oneof=json csv ndjson"`
+}
+
+type AuditEvent struct {
+	ID        string         `json:"id"`
+	Type      string         `json:"type"`
+	UserID    string         `json:"user_id,omitempty"`
+	JTI       string         `json:"jti,omitempty"`
+	IP        string         `json:"ip"`
+	UserAgent string         `json:"user_agent"`
+	RequestID string         `json:"request_id"`
+	Reason    string         `json:"reason,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type AuditListResponse struct {
+	Events     []AuditEvent `json:"events"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
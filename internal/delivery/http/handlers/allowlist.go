@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/service"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// AllowlistHandler - структура для обработки запросов управления списком GUID,
+// для которых разрешено автоматическое создание пользователей.
+type AllowlistHandler struct {
+	logger  *zap.Logger
+	service service.IProvisioningService
+}
+
+func NewAllowlistHandler(logger *zap.Logger, service service.IProvisioningService) *AllowlistHandler {
+	return &AllowlistHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+func (h *AllowlistHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/allowlist", h.POSTAllowlist)
+	router.DELETE("/allowlist/:guid", h.DELETEAllowlist)
+}
+
+// handleError - хелпер для обработки доменных ошибок, возвращаемых сервисом
+func (h *AllowlistHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrUnexpected):
+		c.AbortWithStatus(http.StatusInternalServerError)
+	case errors.Is(err, domain.ErrAllowlistEntryNotFound):
+		c.AbortWithStatus(http.StatusNotFound)
+	default:
+		h.logger.Error("unexpected error from provisioningService", zap.Error(err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}
+}
+
+func (h *AllowlistHandler) POSTAllowlist(c *gin.Context) {
+	var req dto.AllowlistRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Debug("error binding json", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	guids := make([]uuid.UUID, len(req.GUIDs))
+	for i, raw := range req.GUIDs {
+		guid, err := uuid.Parse(raw)
+		if err != nil {
+			h.logger.Debug("error parsing guid", zap.Error(err))
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		guids[i] = guid
+	}
+
+	added, err := h.service.AllowGUIDs(c.Request.Context(), guids)
+
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.AllowlistResponse{Added: added})
+}
+
+func (h *AllowlistHandler) DELETEAllowlist(c *gin.Context) {
+	guid, err := uuid.Parse(c.Param("guid"))
+
+	if err != nil {
+		h.logger.Debug("error parsing guid", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if err = h.service.DisallowGUID(c.Request.Context(), guid); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,97 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/delivery/http/handlers"
+	"github.com/maksemen2/medods-task/internal/domain"
+	mock_service "github.com/maksemen2/medods-task/internal/service/mocks"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowlistHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(ctrl *gomock.Controller) (*gin.Engine, *mock_service.MockIProvisioningService) {
+		mockService := mock_service.NewMockIProvisioningService(ctrl)
+		h := handlers.NewAllowlistHandler(zap.NewNop(), mockService)
+
+		router := gin.New()
+		h.RegisterRoutes(router.Group("/admin"))
+		return router, mockService
+	}
+
+	t.Run("add", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		guids := []uuid.UUID{uuid.New(), uuid.New()}
+		mockService.EXPECT().AllowGUIDs(gomock.Any(), guids).Return(2, nil)
+
+		body, _ := json.Marshal(dto.AllowlistRequest{GUIDs: []string{guids[0].String(), guids[1].String()}})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/admin/allowlist", bytes.NewReader(body))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response dto.AllowlistResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, 2, response.Added)
+	})
+
+	t.Run("add invalid guid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newRouter(ctrl)
+
+		body, _ := json.Marshal(dto.AllowlistRequest{GUIDs: []string{"invalid"}})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/admin/allowlist", bytes.NewReader(body))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		guid := uuid.New()
+		mockService.EXPECT().DisallowGUID(gomock.Any(), guid).Return(nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("DELETE", "/admin/allowlist/"+guid.String(), nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("remove missing", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().DisallowGUID(gomock.Any(), gomock.Any()).Return(domain.ErrAllowlistEntryNotFound)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("DELETE", "/admin/allowlist/"+uuid.New().String(), nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
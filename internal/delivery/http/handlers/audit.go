@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/service"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+// auditCSVHeader - заголовок CSV выгрузки журнала аудита.
+var auditCSVHeader = []string{"id", "type", "user_id", "jti", "ip", "user_agent", "request_id", "reason", "details", "created_at"}
+
+// AuditHandler - структура для обработки запросов к журналу аудита.
+type AuditHandler struct {
+	logger  *zap.Logger
+	service service.IAuditService
+}
+
+func NewAuditHandler(logger *zap.Logger, service service.IAuditService) *AuditHandler {
+	return &AuditHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+func (h *AuditHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/audit", h.GETAudit)
+}
+
+// handleError - хелпер для обработки доменных ошибок, возвращаемых сервисом
+func (h *AuditHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrUnexpected):
+		c.AbortWithStatus(http.StatusInternalServerError)
+	case errors.Is(err, domain.ErrInvalidCursor), errors.Is(err, domain.ErrInvalidFilter):
+		c.AbortWithStatus(http.StatusBadRequest)
+	default:
+		h.logger.Error("unexpected error from auditService", zap.Error(err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}
+}
+
+// toAuditEventDTO преобразует доменное событие аудита в DTO.
+func toAuditEventDTO(event domain.AuthEvent) dto.AuditEvent {
+	result := dto.AuditEvent{
+		ID:        event.ID.String(),
+		Type:      string(event.Type),
+		IP:        event.IP,
+		UserAgent: event.UserAgent,
+		RequestID: event.RequestID,
+		Reason:    event.Reason,
+		Details:   event.Details,
+		CreatedAt: event.CreatedAt,
+	}
+	if event.GUID != uuid.Nil {
+		result.UserID = event.GUID.String()
+	}
+	if event.JTI != uuid.Nil {
+		result.JTI = event.JTI.String()
+	}
+	return result
+}
+
+// toAuditCSVRecord преобразует DTO события аудита в строку CSV.
+func toAuditCSVRecord(event dto.AuditEvent) ([]string, error) {
+	var details string
+	if len(event.Details) > 0 {
+		raw, err := json.Marshal(event.Details)
+		if err != nil {
+			return nil, err
+		}
+		details = string(raw)
+	}
+
+	return []string{
+		event.ID, event.Type, event.UserID, event.JTI, event.IP, event.UserAgent,
+		event.RequestID, event.Reason, details, event.CreatedAt.Format(time.RFC3339Nano),
+	}, nil
+}
+
+// GETAudit возвращает события журнала аудита по фильтрам.
+// В формате json (по умолчанию) возвращается одна страница событий с курсором следующей страницы,
+// в форматах csv и ndjson выгружаются все события, удовлетворяющие фильтрам.
+func (h *AuditHandler) GETAudit(c *gin.Context) {
+	var query dto.AuditQueryParams
+
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.logger.Debug("error binding query", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	filter := domain.AuditFilter{
+		Type:  domain.AuthEventType(query.Type),
+		IP:    query.IP,
+		From:  query.From,
+		To:    query.To,
+		Limit: query.Limit,
+	}
+
+	if query.GUID != "" {
+		guid, err := uuid.Parse(query.GUID)
+		if err != nil {
+			h.logger.Debug("error parsing guid", zap.Error(err))
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		filter.GUID = guid
+	}
+
+	switch query.Format {
+	case "csv":
+		h.exportCSV(c, filter)
+	case "ndjson":
+		h.exportNDJSON(c, filter)
+	default:
+		h.listJSON(c, filter, query.Cursor)
+	}
+}
+
+func (h *AuditHandler) listJSON(c *gin.Context, filter domain.AuditFilter, cursor string) {
+	page, err := h.service.ListEvents(c.Request.Context(), filter, cursor)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response := dto.AuditListResponse{
+		Events:     make([]dto.AuditEvent, 0, len(page.Events)),
+		NextCursor: page.NextCursor,
+	}
+	for _, event := range page.Events {
+		response.Events = append(response.Events, toAuditEventDTO(event))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// export выгружает события в тело ответа с помощью write, после чего вызывает flush.
+// Ответ начинается при записи первого события, поэтому ошибка, возникшая до этого,
+// возвращается клиенту кодом ответа. После начала выгрузки ошибка только логируется.
+func (h *AuditHandler) export(c *gin.Context, filter domain.AuditFilter, contentType, filename string, write func(event dto.AuditEvent) error, flush func() error) {
+	started := false
+	begin := func() {
+		if !started {
+			c.Header("Content-Type", contentType)
+			c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+			c.Status(http.StatusOK)
+			started = true
+		}
+	}
+
+	err := h.service.ExportEvents(c.Request.Context(), filter, func(event domain.AuthEvent) error {
+		begin()
+		return write(toAuditEventDTO(event))
+	})
+	if err != nil {
+		if !started {
+			h.handleError(c, err)
+			return
+		}
+		h.logger.Error("error exporting audit events", zap.Error(err))
+		return
+	}
+
+	begin()
+	if err := flush(); err != nil {
+		h.logger.Error("error exporting audit events", zap.Error(err))
+	}
+}
+
+func (h *AuditHandler) exportCSV(c *gin.Context, filter domain.AuditFilter) {
+	writer := csv.NewWriter(c.Writer)
+	headerWritten := false
+
+	writeHeader := func() error {
+		if headerWritten {
+			return nil
+		}
+		headerWritten = true
+		return writer.Write(auditCSVHeader)
+	}
+
+	h.export(c, filter, "text/csv", "audit.csv",
+		func(event dto.AuditEvent) error {
+			if err := writeHeader(); err != nil {
+				return err
+			}
+			record, err := toAuditCSVRecord(event)
+			if err != nil {
+				return err
+			}
+			return writer.Write(record)
+		},
+		func() error {
+			if err := writeHeader(); err != nil {
+				return err
+			}
+			writer.Flush()
+			return writer.Error()
+		},
+	)
+}
+
+func (h *AuditHandler) exportNDJSON(c *gin.Context, filter domain.AuditFilter) {
+	encoder := json.NewEncoder(c.Writer)
+
+	h.export(c, filter, "application/x-ndjson", "audit.ndjson",
+		func(event dto.AuditEvent) error {
+			return encoder.Encode(event)
+		},
+		func() error {
+			return nil
+		},
+	)
+}
@@ -0,0 +1,168 @@
+package handlers_test
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/delivery/http/handlers"
+	"github.com/maksemen2/medods-task/internal/domain"
+	mock_service "github.com/maksemen2/medods-task/internal/service/mocks"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditHandler_GETAudit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	event := domain.AuthEvent{
+		ID:        uuid.New(),
+		Type:      domain.AuthEventRefreshFailed,
+		GUID:      uuid.New(),
+		IP:        "127.0.0.1",
+		UserAgent: "curl/8.0",
+		RequestID: "request-id",
+		Reason:    domain.FailureReasonTokenNotFound,
+		Details:   map[string]any{"country": "RU"},
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	newRouter := func(ctrl *gomock.Controller) (*gin.Engine, *mock_service.MockIAuditService) {
+		mockService := mock_service.NewMockIAuditService(ctrl)
+		h := handlers.NewAuditHandler(zap.NewNop(), mockService)
+
+		router := gin.New()
+		router.GET("/admin/audit", h.GETAudit)
+		return router, mockService
+	}
+
+	t.Run("json page with filters", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		mockService.EXPECT().ListEvents(gomock.Any(), gomock.Any(), "cursor").DoAndReturn(
+			func(_ any, filter domain.AuditFilter, _ string) (*domain.AuditPage, error) {
+				assert.Equal(t, event.GUID, filter.GUID)
+				assert.Equal(t, domain.AuthEventRefreshFailed, filter.Type)
+				assert.Equal(t, "127.0.0.1", filter.IP)
+				assert.True(t, from.Equal(filter.From))
+				assert.True(t, filter.To.IsZero())
+				assert.Equal(t, 10, filter.Limit)
+				return &domain.AuditPage{Events: []domain.AuthEvent{event}, NextCursor: "next"}, nil
+			})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/admin/audit?guid="+event.GUID.String()+
+			"&type=refresh_failed&ip=127.0.0.1&from=2025-01-01T00:00:00Z&limit=10&cursor=cursor", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response dto.AuditListResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.Events, 1)
+		assert.Equal(t, "next", response.NextCursor)
+		assert.Equal(t, event.ID.String(), response.Events[0].ID)
+		assert.Equal(t, event.GUID.String(), response.Events[0].UserID)
+		assert.Empty(t, response.Events[0].JTI)
+		assert.Equal(t, domain.FailureReasonTokenNotFound, response.Events[0].Reason)
+	})
+
+	t.Run("csv export", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().ExportEvents(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ any, _ domain.AuditFilter, fn func(domain.AuthEvent) error) error {
+				return fn(event)
+			})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/admin/audit?format=csv", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+		records, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, "id", records[0][0])
+		assert.Equal(t, event.ID.String(), records[1][0])
+		assert.Equal(t, `{"country":"RU"}`, records[1][8])
+	})
+
+	t.Run("ndjson export", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().ExportEvents(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ any, _ domain.AuditFilter, fn func(domain.AuthEvent) error) error {
+				if err := fn(event); err != nil {
+					return err
+				}
+				return fn(event)
+			})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/admin/audit?format=ndjson", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+		scanner := bufio.NewScanner(strings.NewReader(w.Body.String()))
+		lines := 0
+		for scanner.Scan() {
+			var line dto.AuditEvent
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+			assert.Equal(t, event.ID.String(), line.ID)
+			lines++
+		}
+		assert.Equal(t, 2, lines)
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newRouter(ctrl)
+
+		for _, query := range []string{"guid=invalid", "from=yesterday", "format=xml", "limit=-1"} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/admin/audit?"+query, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().ListEvents(gomock.Any(), gomock.Any(), "bad").Return(nil, domain.ErrInvalidCursor)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/admin/audit?cursor=bad", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
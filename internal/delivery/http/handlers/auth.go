@@ -9,6 +9,7 @@ import (
 	"github.com/maksemen2/medods-task/internal/service"
 	"go.uber.org/zap"
 	"net/http"
+	"strings"
 )
 
 // AuthHandler - структура для обработки запросов аутентификации.
@@ -27,6 +28,27 @@ func NewAuthHandler(logger *zap.Logger, service service.IAuthService) *AuthHandl
 func (h *AuthHandler) RegisterRoutes(router *gin.RouterGroup) {
 	router.GET("/auth", h.GETAuth)
 	router.POST("/refresh", h.POSTRefresh)
+	router.POST("/login", h.POSTLogin)
+	router.POST("/password", h.POSTPassword)
+	router.POST("/mfa/verify", h.POSTMFAVerify)
+	router.POST("/mfa/totp/enroll", h.POSTTOTPEnroll)
+	router.POST("/mfa/totp/confirm", h.POSTTOTPConfirm)
+	router.POST("/mfa/recovery-codes", h.POSTRecoveryCodes)
+	router.POST("/webauthn/register/options", h.POSTPasskeyRegisterOptions)
+	router.POST("/webauthn/register", h.POSTPasskeyRegister)
+	router.POST("/webauthn/login/options", h.POSTPasskeyLoginOptions)
+	router.POST("/webauthn/login", h.POSTPasskeyLogin)
+	router.POST("/login/magic", h.POSTMagicLink)
+	router.GET("/login/magic/verify", h.GETMagicLinkVerify)
+	router.POST("/password/forgot", h.POSTPasswordForgot)
+	router.POST("/password/reset", h.POSTPasswordReset)
+	router.GET("/authorize", h.GETAuthorize)
+	router.POST("/authorize", h.POSTAuthorize)
+	router.POST("/token", h.POSTToken)
+	router.POST("/device/code", h.POSTDeviceCode)
+	router.GET("/device", h.GETDevice)
+	router.POST("/device", h.POSTDevice)
+	router.GET("/verify", h.GETVerify)
 }
 
 // handleError - хелпер для обработки доменных ошибок, возвращаемых сервисом
@@ -36,12 +58,71 @@ func (h *AuthHandler) handleError(c *gin.Context, err error) {
 		c.AbortWithStatus(http.StatusInternalServerError)
 	case errors.Is(err, domain.ErrInvalidAccessToken), errors.Is(err, domain.ErrInvalidRefreshToken), errors.Is(err, domain.ErrTokenNotFound):
 		c.AbortWithStatus(http.StatusBadRequest)
+	case errors.Is(err, domain.ErrUserNotFound):
+		c.AbortWithStatus(http.StatusNotFound)
+	case errors.Is(err, domain.ErrWeakPassword), errors.Is(err, domain.ErrTOTPNotEnrolled),
+		errors.Is(err, domain.ErrInvalidWebAuthnResponse), errors.Is(err, domain.ErrWebAuthnCeremonyNotFound),
+		errors.Is(err, domain.ErrInvalidEmail), errors.Is(err, domain.ErrInvalidResetToken):
+		c.AbortWithStatus(http.StatusBadRequest)
+	case errors.Is(err, domain.ErrIPChangeDenied), errors.Is(err, domain.ErrRiskDenied), errors.Is(err, domain.ErrInvalidCredentials),
+		errors.Is(err, domain.ErrInvalidMFAToken), errors.Is(err, domain.ErrInvalidMFACode), errors.Is(err, domain.ErrInvalidMagicLink):
+		c.AbortWithStatus(http.StatusUnauthorized)
+	case errors.Is(err, domain.ErrEmailNotVerified):
+		c.AbortWithStatus(http.StatusForbidden)
+	case errors.Is(err, domain.ErrTOTPAlreadyEnabled), errors.Is(err, domain.ErrPasskeyExists):
+		c.AbortWithStatus(http.StatusConflict)
+	case errors.Is(err, domain.ErrMFADisabled), errors.Is(err, domain.ErrWebAuthnDisabled), errors.Is(err, domain.ErrMagicLinkDisabled),
+		errors.Is(err, domain.ErrPasswordResetDisabled):
+		c.AbortWithStatus(http.StatusNotFound)
 	default:
 		h.logger.Error("unexpected error from authService", zap.Error(err))
 		c.AbortWithStatus(http.StatusInternalServerError)
 	}
 }
 
+// writeAuth отправляет клиенту пару токенов или MFA-челлендж, если требуется второй фактор.
+func writeAuth(c *gin.Context, domainAuth *domain.UserAuth) {
+	if domainAuth.MFARequired() {
+		c.JSON(http.StatusOK, dto.MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    domainAuth.MFAToken,
+			ExpiresAt:   domainAuth.MFAExpiresAt,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.AuthResponse{
+		AccessToken:  domainAuth.AccessToken,
+		RefreshToken: domainAuth.RefreshToken,
+	})
+}
+
+// bearerToken извлекает Access токен из заголовка "Authorization: Bearer <токен>".
+// Если токен не передан, отвечает 401 и возвращает false.
+func bearerToken(c *gin.Context) (string, bool) {
+	accessToken, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || accessToken == "" {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return "", false
+	}
+	return accessToken, true
+}
+
+// handleSessionError обрабатывает ошибки эндпоинтов, требующих Access токен.
+// Невалидный Access токен здесь означает отсутствие аутентификации, а не ошибку в теле запроса.
+// На токен, требующий повторной аутентификации, отвечает 401 с ошибкой insufficient_user_authentication (RFC 9470).
+func (h *AuthHandler) handleSessionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidAccessToken):
+		c.AbortWithStatus(http.StatusUnauthorized)
+	case errors.Is(err, domain.ErrReauthRequired):
+		c.Header("WWW-Authenticate", `Bearer error="insufficient_user_authentication"`)
+		c.AbortWithStatus(http.StatusUnauthorized)
+	default:
+		h.handleError(c, err)
+	}
+}
+
 func (h *AuthHandler) GETAuth(c *gin.Context) {
 	var query dto.AuthQueryParams
 
@@ -59,17 +140,14 @@ func (h *AuthHandler) GETAuth(c *gin.Context) {
 		return
 	}
 
-	domainAuth, err := h.service.AuthenticateUser(c.Request.Context(), guid, c.ClientIP())
+	domainAuth, err := h.service.AuthenticateUser(c.Request.Context(), guid, c.ClientIP(), c.Request.UserAgent())
 
 	if err != nil {
 		h.handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, dto.AuthResponse{
-		AccessToken:  domainAuth.AccessToken,
-		RefreshToken: domainAuth.RefreshToken,
-	})
+	writeAuth(c, domainAuth)
 }
 
 func (h *AuthHandler) POSTRefresh(c *gin.Context) {
@@ -81,7 +159,7 @@ func (h *AuthHandler) POSTRefresh(c *gin.Context) {
 		return
 	}
 
-	domainAuth, err := h.service.RefreshToken(c.Request.Context(), req.AccessToken, req.RefreshToken, c.ClientIP())
+	domainAuth, err := h.service.RefreshToken(c.Request.Context(), req.AccessToken, req.RefreshToken, c.ClientIP(), c.Request.UserAgent())
 
 	if err != nil {
 		h.handleError(c, err)
@@ -93,3 +171,47 @@ func (h *AuthHandler) POSTRefresh(c *gin.Context) {
 		RefreshToken: domainAuth.RefreshToken,
 	})
 }
+
+func (h *AuthHandler) POSTLogin(c *gin.Context) {
+	var req dto.LoginRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Debug("error binding json", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	domainAuth, err := h.service.Login(c.Request.Context(), req.Email, req.Password, c.ClientIP(), c.Request.UserAgent())
+
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	writeAuth(c, domainAuth)
+}
+
+// POSTPassword меняет пароль пользователя. Access токен передается в заголовке "Authorization: Bearer <токен>".
+func (h *AuthHandler) POSTPassword(c *gin.Context) {
+	accessToken, ok := bearerToken(c)
+	if !ok {
+		return
+	}
+
+	var req dto.ChangePasswordRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Debug("error binding json", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	err := h.service.ChangePassword(c.Request.Context(), accessToken, req.CurrentPassword, req.NewPassword, c.ClientIP(), c.Request.UserAgent())
+
+	if err != nil {
+		h.handleSessionError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -38,7 +39,7 @@ func TestAuthHandler_GETAuth(t *testing.T) {
 			RefreshToken: "refresh",
 		}
 
-		mockService.EXPECT().AuthenticateUser(gomock.Any(), guid, gomock.Any()).
+		mockService.EXPECT().AuthenticateUser(gomock.Any(), guid, gomock.Any(), gomock.Any()).
 			Return(&domain.UserAuth{
 				AccessToken:  expected.AccessToken,
 				RefreshToken: expected.RefreshToken,
@@ -84,7 +85,7 @@ func TestAuthHandler_GETAuth(t *testing.T) {
 		router.GET("/auth", h.GETAuth)
 
 		guid := uuid.New()
-		mockService.EXPECT().AuthenticateUser(gomock.Any(), guid, gomock.Any()).
+		mockService.EXPECT().AuthenticateUser(gomock.Any(), guid, gomock.Any(), gomock.Any()).
 			Return(nil, domain.ErrUnexpected)
 
 		w := httptest.NewRecorder()
@@ -93,6 +94,48 @@ func TestAuthHandler_GETAuth(t *testing.T) {
 
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockService := mock_service.NewMockIAuthService(ctrl)
+		h := handlers.NewAuthHandler(zap.NewNop(), mockService)
+
+		router := gin.New()
+		router.GET("/auth", h.GETAuth)
+
+		guid := uuid.New()
+		mockService.EXPECT().AuthenticateUser(gomock.Any(), guid, gomock.Any(), gomock.Any()).
+			Return(nil, domain.ErrUserNotFound)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/auth?guid="+guid.String(), nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("email not verified", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockService := mock_service.NewMockIAuthService(ctrl)
+		h := handlers.NewAuthHandler(zap.NewNop(), mockService)
+
+		router := gin.New()
+		router.GET("/auth", h.GETAuth)
+
+		guid := uuid.New()
+		mockService.EXPECT().AuthenticateUser(gomock.Any(), guid, gomock.Any(), gomock.Any()).
+			Return(nil, domain.ErrEmailNotVerified)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/auth?guid="+guid.String(), nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
 }
 
 func TestAuthHandler_POSTRefresh(t *testing.T) {
@@ -118,7 +161,7 @@ func TestAuthHandler_POSTRefresh(t *testing.T) {
 			RefreshToken: "new_refresh",
 		}
 
-		mockService.EXPECT().RefreshToken(gomock.Any(), request.AccessToken, request.RefreshToken, gomock.Any()).
+		mockService.EXPECT().RefreshToken(gomock.Any(), request.AccessToken, request.RefreshToken, gomock.Any(), gomock.Any()).
 			Return(&domain.UserAuth{
 				AccessToken:  expected.AccessToken,
 				RefreshToken: expected.RefreshToken,
@@ -169,7 +212,7 @@ func TestAuthHandler_POSTRefresh(t *testing.T) {
 			RefreshToken: "refresh",
 		}
 
-		mockService.EXPECT().RefreshToken(gomock.Any(), request.AccessToken, request.RefreshToken, gomock.Any()).
+		mockService.EXPECT().RefreshToken(gomock.Any(), request.AccessToken, request.RefreshToken, gomock.Any(), gomock.Any()).
 			Return(nil, domain.ErrInvalidAccessToken)
 
 		body, _ := json.Marshal(request)
@@ -179,4 +222,212 @@ func TestAuthHandler_POSTRefresh(t *testing.T) {
 
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
+
+	t.Run("ip change denied", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockService := mock_service.NewMockIAuthService(ctrl)
+		logger := zap.NewNop()
+		h := handlers.NewAuthHandler(logger, mockService)
+
+		router := gin.New()
+		router.POST("/refresh", h.POSTRefresh)
+
+		request := dto.RefreshRequest{
+			AccessToken:  "access",
+			RefreshToken: "refresh",
+		}
+
+		mockService.EXPECT().RefreshToken(gomock.Any(), request.AccessToken, request.RefreshToken, gomock.Any(), gomock.Any()).
+			Return(nil, domain.ErrIPChangeDenied)
+
+		body, _ := json.Marshal(request)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/refresh", bytes.NewReader(body))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("risk denied", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockService := mock_service.NewMockIAuthService(ctrl)
+		logger := zap.NewNop()
+		h := handlers.NewAuthHandler(logger, mockService)
+
+		router := gin.New()
+		router.POST("/refresh", h.POSTRefresh)
+
+		request := dto.RefreshRequest{
+			AccessToken:  "access",
+			RefreshToken: "refresh",
+		}
+
+		mockService.EXPECT().RefreshToken(gomock.Any(), request.AccessToken, request.RefreshToken, gomock.Any(), "test-agent").
+			Return(nil, domain.ErrRiskDenied)
+
+		body, _ := json.Marshal(request)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/refresh", bytes.NewReader(body))
+		req.Header.Set("User-Agent", "test-agent")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestAuthHandler_POSTLogin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(ctrl *gomock.Controller) (*gin.Engine, *mock_service.MockIAuthService) {
+		mockService := mock_service.NewMockIAuthService(ctrl)
+		h := handlers.NewAuthHandler(zap.NewNop(), mockService)
+
+		router := gin.New()
+		router.POST("/login", h.POSTLogin)
+		return router, mockService
+	}
+
+	login := func(router *gin.Engine, request any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(request)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/login", bytes.NewReader(body))
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().Login(gomock.Any(), "user@example.com", "correct horse", gomock.Any(), gomock.Any()).
+			Return(&domain.UserAuth{AccessToken: "access", RefreshToken: "refresh"}, nil)
+
+		w := login(router, dto.LoginRequest{Email: "user@example.com", Password: "correct horse"})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response dto.AuthResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, dto.AuthResponse{AccessToken: "access", RefreshToken: "refresh"}, response)
+	})
+
+	t.Run("mfa required", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		expiresAt := time.Date(2025, 1, 1, 0, 5, 0, 0, time.UTC)
+		mockService.EXPECT().Login(gomock.Any(), "user@example.com", "correct horse", gomock.Any(), gomock.Any()).
+			Return(&domain.UserAuth{MFAToken: "mfa", MFAExpiresAt: expiresAt}, nil)
+
+		w := login(router, dto.LoginRequest{Email: "user@example.com", Password: "correct horse"})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response dto.MFAChallengeResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, dto.MFAChallengeResponse{MFARequired: true, MFAToken: "mfa", ExpiresAt: expiresAt}, response)
+		assert.NotContains(t, w.Body.String(), "access_token")
+	})
+
+	t.Run("missing password", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newRouter(ctrl)
+
+		w := login(router, map[string]string{"email": "user@example.com"})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("invalid credentials", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().Login(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, domain.ErrInvalidCredentials)
+
+		w := login(router, dto.LoginRequest{Email: "user@example.com", Password: "wrong password"})
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestAuthHandler_POSTPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(ctrl *gomock.Controller) (*gin.Engine, *mock_service.MockIAuthService) {
+		mockService := mock_service.NewMockIAuthService(ctrl)
+		h := handlers.NewAuthHandler(zap.NewNop(), mockService)
+
+		router := gin.New()
+		router.POST("/password", h.POSTPassword)
+		return router, mockService
+	}
+
+	changePassword := func(router *gin.Engine, authorization string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(dto.ChangePasswordRequest{CurrentPassword: "correct horse", NewPassword: "battery staple"})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/password", bytes.NewReader(body))
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().ChangePassword(gomock.Any(), "access", "correct horse", "battery staple", gomock.Any(), gomock.Any()).
+			Return(nil)
+
+		w := changePassword(router, "Bearer access")
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newRouter(ctrl)
+
+		w := changePassword(router, "")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("service errors", func(t *testing.T) {
+		cases := map[error]int{
+			domain.ErrInvalidAccessToken: http.StatusUnauthorized,
+			domain.ErrInvalidCredentials: http.StatusUnauthorized,
+			domain.ErrWeakPassword:       http.StatusBadRequest,
+			domain.ErrUnexpected:         http.StatusInternalServerError,
+		}
+
+		for serviceErr, status := range cases {
+			ctrl := gomock.NewController(t)
+
+			router, mockService := newRouter(ctrl)
+			mockService.EXPECT().ChangePassword(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(serviceErr)
+
+			w := changePassword(router, "Bearer access")
+
+			assert.Equal(t, status, w.Code, serviceErr.Error())
+			ctrl.Finish()
+		}
+	})
 }
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/service"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+// ClientHandler - структура для обработки запросов управления реестром клиентов OAuth.
+type ClientHandler struct {
+	logger  *zap.Logger
+	service service.IClientService
+}
+
+func NewClientHandler(logger *zap.Logger, service service.IClientService) *ClientHandler {
+	return &ClientHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+func (h *ClientHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/clients", h.POSTClients)
+	router.GET("/clients", h.GETClients)
+	router.GET("/clients/:id", h.GETClient)
+	router.PUT("/clients/:id", h.PUTClient)
+	router.POST("/clients/:id/secret", h.POSTClientSecret)
+	router.DELETE("/clients/:id", h.DELETEClient)
+}
+
+// handleError - хелпер для обработки доменных ошибок, возвращаемых сервисом
+func (h *ClientHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrUnexpected):
+		c.AbortWithStatus(http.StatusInternalServerError)
+	case errors.Is(err, domain.ErrInvalidClientMetadata):
+		c.AbortWithStatus(http.StatusBadRequest)
+	case errors.Is(err, domain.ErrClientNotFound):
+		c.AbortWithStatus(http.StatusNotFound)
+	default:
+		h.logger.Error("unexpected error from clientService", zap.Error(err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}
+}
+
+func clientFromRequest(req dto.ClientRequest) domain.OAuthClient {
+	grantTypes := make([]domain.GrantType, len(req.GrantTypes))
+	for i, grantType := range req.GrantTypes {
+		grantTypes[i] = domain.GrantType(grantType)
+	}
+
+	return domain.OAuthClient{
+		Name:         req.Name,
+		GrantTypes:   grantTypes,
+		RedirectURIs: req.RedirectURIs,
+		Scopes:       req.Scopes,
+		AccessTTL:    time.Duration(req.AccessTokenTTL) * time.Second,
+		RefreshTTL:   time.Duration(req.RefreshTokenTTL) * time.Second,
+	}
+}
+
+func clientResponse(client *domain.OAuthClient) dto.ClientResponse {
+	grantTypes := make([]string, len(client.GrantTypes))
+	for i, grantType := range client.GrantTypes {
+		grantTypes[i] = string(grantType)
+	}
+
+	// Пустые списки отдаются как [], а не null
+	redirectURIs := client.RedirectURIs
+	if redirectURIs == nil {
+		redirectURIs = []string{}
+	}
+	scopes := client.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	return dto.ClientResponse{
+		ClientID:        client.ID,
+		Name:            client.Name,
+		Confidential:    client.Confidential(),
+		GrantTypes:      grantTypes,
+		RedirectURIs:    redirectURIs,
+		Scopes:          scopes,
+		AccessTokenTTL:  int(client.AccessTTL.Seconds()),
+		RefreshTokenTTL: int(client.RefreshTTL.Seconds()),
+		CreatedAt:       client.CreatedAt,
+		UpdatedAt:       client.UpdatedAt,
+	}
+}
+
+// POSTClients регистрирует клиента OAuth. Секрет конфиденциального клиента возвращается только в этом ответе.
+func (h *ClientHandler) POSTClients(c *gin.Context) {
+	var req dto.ClientRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Debug("error binding json", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	client, secret, err := h.service.CreateClient(c.Request.Context(), clientFromRequest(req), req.Confidential)
+
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response := clientResponse(client)
+	response.ClientSecret = secret
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *ClientHandler) GETClients(c *gin.Context) {
+	clients, err := h.service.ListClients(c.Request.Context())
+
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response := dto.ClientListResponse{Clients: make([]dto.ClientResponse, len(clients))}
+	for i := range clients {
+		response.Clients[i] = clientResponse(&clients[i])
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *ClientHandler) GETClient(c *gin.Context) {
+	client, err := h.service.GetClient(c.Request.Context(), c.Param("id"))
+
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, clientResponse(client))
+}
+
+// PUTClient заменяет параметры клиента. Тип клиента (поле confidential) и секрет не меняются.
+func (h *ClientHandler) PUTClient(c *gin.Context) {
+	var req dto.ClientRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Debug("error binding json", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	client := clientFromRequest(req)
+	client.ID = c.Param("id")
+
+	updated, err := h.service.UpdateClient(c.Request.Context(), client)
+
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, clientResponse(updated))
+}
+
+// POSTClientSecret выдает клиенту новый секрет.
+func (h *ClientHandler) POSTClientSecret(c *gin.Context) {
+	secret, err := h.service.RotateClientSecret(c.Request.Context(), c.Param("id"))
+
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, dto.ClientSecretResponse{ClientSecret: secret})
+}
+
+func (h *ClientHandler) DELETEClient(c *gin.Context) {
+	if err := h.service.DeleteClient(c.Request.Context(), c.Param("id")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,174 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/delivery/http/handlers"
+	"github.com/maksemen2/medods-task/internal/domain"
+	mock_service "github.com/maksemen2/medods-task/internal/service/mocks"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(ctrl *gomock.Controller) (*gin.Engine, *mock_service.MockIClientService) {
+		mockService := mock_service.NewMockIClientService(ctrl)
+		h := handlers.NewClientHandler(zap.NewNop(), mockService)
+
+		router := gin.New()
+		h.RegisterRoutes(router.Group("/admin"))
+		return router, mockService
+	}
+
+	clientRequest := dto.ClientRequest{
+		Name:           "Backend",
+		Confidential:   true,
+		GrantTypes:     []string{"authorization_code"},
+		RedirectURIs:   []string{"https://app.example.com/callback"},
+		AccessTokenTTL: 300,
+	}
+
+	t.Run("create", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().CreateClient(gomock.Any(), gomock.Any(), true).
+			DoAndReturn(func(_ any, client domain.OAuthClient, confidential bool) (*domain.OAuthClient, string, error) {
+				assert.Equal(t, []domain.GrantType{domain.GrantTypeAuthorizationCode}, client.GrantTypes)
+				assert.Equal(t, 5*time.Minute, client.AccessTTL)
+				client.ID = "client"
+				client.SecretHash = "hash"
+				return &client, "secret", nil
+			})
+
+		body, _ := json.Marshal(clientRequest)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/admin/clients", bytes.NewReader(body))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		var response dto.ClientResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "client", response.ClientID)
+		assert.Equal(t, "secret", response.ClientSecret)
+		assert.True(t, response.Confidential)
+		assert.Equal(t, []string{}, response.Scopes)
+		assert.Equal(t, 300, response.AccessTokenTTL)
+	})
+
+	t.Run("create invalid metadata", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().CreateClient(gomock.Any(), gomock.Any(), true).Return(nil, "", domain.ErrInvalidClientMetadata)
+
+		body, _ := json.Marshal(clientRequest)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/admin/clients", bytes.NewReader(body))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().ListClients(gomock.Any()).Return([]domain.OAuthClient{{ID: "a"}, {ID: "b"}}, nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/admin/clients", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response dto.ClientListResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.Clients, 2)
+		assert.Equal(t, "b", response.Clients[1].ClientID)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().UpdateClient(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ any, client domain.OAuthClient) (*domain.OAuthClient, error) {
+				assert.Equal(t, "client", client.ID)
+				return &client, nil
+			})
+
+		body, _ := json.Marshal(clientRequest)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/admin/clients/client", bytes.NewReader(body))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response dto.ClientResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Empty(t, response.ClientSecret)
+	})
+
+	t.Run("rotate secret", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().RotateClientSecret(gomock.Any(), "client").Return("secret", nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/admin/clients/client/secret", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response dto.ClientSecretResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "secret", response.ClientSecret)
+	})
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"delete", nil, http.StatusNoContent},
+		{"delete not found", domain.ErrClientNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			router, mockService := newRouter(ctrl)
+
+			mockService.EXPECT().DeleteClient(gomock.Any(), "client").Return(tt.err)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("DELETE", "/admin/clients/client", nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/oauth"
+	"go.uber.org/zap"
+	"html/template"
+	"net/http"
+)
+
+var deviceTemplate = template.Must(template.ParseFS(templatesFS, "templates/device.html"))
+
+// devicePage - данные страницы подтверждения устройства.
+// Пока ClientName пуст, страница предлагает ввести код. Done - итоговое сообщение после решения пользователя.
+type devicePage struct {
+	UserCode   string
+	ClientName string
+	Email      string
+	MFAToken   string
+	Error      string
+	Done       string
+}
+
+// renderDevicePage отрисовывает страницу подтверждения устройства.
+func (h *AuthHandler) renderDevicePage(c *gin.Context, status int, page devicePage) {
+	h.renderPage(c, deviceTemplate, status, page)
+}
+
+// handleDeviceError обрабатывает ошибки страницы подтверждения устройства.
+// При неверном коде страница возвращается к шагу ввода кода.
+func (h *AuthHandler) handleDeviceError(c *gin.Context, page devicePage, err error) {
+	switch {
+	case errors.Is(err, domain.ErrOAuthDisabled), errors.Is(err, domain.ErrDeviceFlowDisabled):
+		c.AbortWithStatus(http.StatusNotFound)
+	case errors.Is(err, domain.ErrInvalidUserCode):
+		h.renderDevicePage(c, http.StatusBadRequest, devicePage{
+			UserCode: page.UserCode,
+			Error:    "Код неверен или устарел. Запросите новый код на устройстве.",
+		})
+	case errors.Is(err, domain.ErrRiskDenied):
+		page.MFAToken = ""
+		page.Error = "Вход запрещен из соображений безопасности."
+		h.renderDevicePage(c, http.StatusForbidden, page)
+	default:
+		if !errors.Is(err, domain.ErrUnexpected) {
+			h.logger.Error("unexpected error from authService", zap.Error(err))
+		}
+		page.MFAToken = ""
+		page.Error = "Что-то пошло не так, попробуйте позже."
+		h.renderDevicePage(c, http.StatusInternalServerError, page)
+	}
+}
+
+// POSTDeviceCode выдает устройству код для опроса /token и пользовательский код для страницы подтверждения (RFC 8628).
+func (h *AuthHandler) POSTDeviceCode(c *gin.Context) {
+	var req dto.DeviceCodeRequest
+
+	if err := c.ShouldBind(&req); err != nil {
+		h.logger.Debug("error binding form", zap.Error(err))
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidRequest, "")
+		return
+	}
+
+	creds, ok := clientCredentials(c, req.ClientID, req.ClientSecret)
+	if !ok {
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidRequest, "conflicting client credentials")
+		return
+	}
+	if creds.ID == "" {
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidRequest, "client_id is required")
+		return
+	}
+
+	deviceCode, err := h.service.StartDeviceAuthorization(c.Request.Context(), creds, req.Scope, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		h.handleTokenError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, dto.DeviceCodeResponse{
+		DeviceCode:              deviceCode.DeviceCode,
+		UserCode:                deviceCode.UserCode,
+		VerificationURI:         deviceCode.VerificationURI,
+		VerificationURIComplete: deviceCode.VerificationURIComplete,
+		ExpiresIn:               int(deviceCode.ExpiresIn.Seconds()),
+		Interval:                int(deviceCode.Interval.Seconds()),
+	})
+}
+
+// GETDevice показывает страницу ввода кода устройства. Если код передан в query (verification_uri_complete),
+// он сразу проверяется и показывается форма входа.
+func (h *AuthHandler) GETDevice(c *gin.Context) {
+	page := devicePage{UserCode: c.Query("user_code")}
+
+	if page.UserCode == "" {
+		h.renderDevicePage(c, http.StatusOK, page)
+		return
+	}
+
+	client, err := h.service.LookupDeviceAuthorization(c.Request.Context(), page.UserCode, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		h.handleDeviceError(c, page, err)
+		return
+	}
+
+	page.ClientName = client.Name
+	h.renderDevicePage(c, http.StatusOK, page)
+}
+
+// POSTDevice принимает форму страницы подтверждения: email и пароль либо код второго фактора.
+// После успешного входа устройство получает токены при следующем опросе /token.
+func (h *AuthHandler) POSTDevice(c *gin.Context) {
+	var form dto.DeviceForm
+
+	if err := c.ShouldBind(&form); err != nil {
+		h.logger.Debug("error binding form", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	ctx := c.Request.Context()
+	ip, userAgent := c.ClientIP(), c.Request.UserAgent()
+	page := devicePage{UserCode: form.UserCode, Email: form.Email}
+
+	client, err := h.service.LookupDeviceAuthorization(ctx, form.UserCode, ip, userAgent)
+	if err != nil {
+		h.handleDeviceError(c, page, err)
+		return
+	}
+	page.ClientName = client.Name
+
+	if form.Action == authorizeActionDeny {
+		if err := h.service.DenyDeviceAuthorization(ctx, form.UserCode, ip, userAgent); err != nil {
+			h.handleDeviceError(c, page, err)
+			return
+		}
+		page.Done = "Доступ запрещен. Устройство не получит доступ к вашему аккаунту."
+		h.renderDevicePage(c, http.StatusOK, page)
+		return
+	}
+
+	var grant *domain.AuthorizationGrant
+
+	if form.MFAToken != "" {
+		err = h.service.ApproveDeviceWithMFA(ctx, form.UserCode, form.MFAToken, form.Code, ip, userAgent)
+	} else {
+		grant, err = h.service.ApproveDeviceWithPassword(ctx, form.UserCode, form.Email, form.Password, ip, userAgent)
+	}
+
+	switch {
+	case err == nil:
+	case errors.Is(err, domain.ErrInvalidCredentials):
+		page.Error = "Неверный email или пароль."
+		h.renderDevicePage(c, http.StatusUnauthorized, page)
+		return
+	case errors.Is(err, domain.ErrInvalidMFACode):
+		page.MFAToken = form.MFAToken
+		page.Error = "Неверный код."
+		h.renderDevicePage(c, http.StatusUnauthorized, page)
+		return
+	case errors.Is(err, domain.ErrInvalidMFAToken):
+		page.Error = "Время на ввод кода истекло, войдите снова."
+		h.renderDevicePage(c, http.StatusUnauthorized, page)
+		return
+	default:
+		h.handleDeviceError(c, page, err)
+		return
+	}
+
+	// После второго фактора grant не выдается: доступ уже разрешен
+	if grant != nil && grant.MFARequired() {
+		page.MFAToken = grant.MFAToken
+		h.renderDevicePage(c, http.StatusOK, page)
+		return
+	}
+
+	page.Done = "Устройство подключено. Вернитесь к нему, чтобы продолжить."
+	h.renderDevicePage(c, http.StatusOK, page)
+}
@@ -0,0 +1,341 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var deviceClient = &domain.OAuthClient{ID: "cli", Name: "Internal CLI"}
+
+func TestAuthHandler_POSTDeviceCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().StartDeviceAuthorization(gomock.Any(), domain.ClientCredentials{ID: "cli"}, "profile", gomock.Any(), gomock.Any()).
+			Return(&domain.DeviceCode{
+				DeviceCode:              "device",
+				UserCode:                "BDFH-KLMN",
+				VerificationURI:         "https://auth.example.com/device",
+				VerificationURIComplete: "https://auth.example.com/device?user_code=BDFH-KLMN",
+				ExpiresIn:               10 * time.Minute,
+				Interval:                5 * time.Second,
+			}, nil)
+
+		w := postForm(router, "/device/code", url.Values{"client_id": {"cli"}, "scope": {"profile"}})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		var response dto.DeviceCodeResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "device", response.DeviceCode)
+		assert.Equal(t, "BDFH-KLMN", response.UserCode)
+		assert.Equal(t, 600, response.ExpiresIn)
+		assert.Equal(t, 5, response.Interval)
+	})
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantError  string
+	}{
+		{"grant not allowed", domain.ErrUnauthorizedClient, http.StatusBadRequest, "unauthorized_client"},
+		{"invalid scope", domain.ErrInvalidScope, http.StatusBadRequest, "invalid_scope"},
+		{"invalid client", domain.ErrInvalidClient, http.StatusUnauthorized, "invalid_client"},
+		{"disabled", domain.ErrDeviceFlowDisabled, http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			router, mockService := newOAuthRouter(ctrl)
+
+			mockService.EXPECT().StartDeviceAuthorization(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, tt.err)
+
+			w := postForm(router, "/device/code", url.Values{"client_id": {"cli"}})
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.wantError)
+		})
+	}
+
+	t.Run("missing client id", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newOAuthRouter(ctrl)
+
+		w := postForm(router, "/device/code", url.Values{"scope": {"profile"}})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_request")
+	})
+}
+
+func TestAuthHandler_POSTToken_DeviceCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	deviceValues := url.Values{
+		"grant_type":  {string(domain.GrantTypeDeviceCode)},
+		"client_id":   {"cli"},
+		"device_code": {"device"},
+	}
+
+	t.Run("approved", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().ExchangeDeviceCode(gomock.Any(), domain.ClientCredentials{ID: "cli"}, "device", gomock.Any(), gomock.Any()).
+			Return(&domain.UserAuth{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: time.Minute}, nil)
+
+		w := postForm(router, "/token", deviceValues)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response dto.TokenResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "access", response.AccessToken)
+		assert.Equal(t, "refresh", response.RefreshToken)
+	})
+
+	tests := []struct {
+		name      string
+		err       error
+		wantError string
+	}{
+		{"pending", domain.ErrAuthorizationPending, "authorization_pending"},
+		{"slow down", domain.ErrSlowDown, "slow_down"},
+		{"expired", domain.ErrExpiredDeviceCode, "expired_token"},
+		{"denied", domain.ErrAccessDenied, "access_denied"},
+		{"used code", domain.ErrInvalidGrant, "invalid_grant"},
+		{"disabled", domain.ErrUnsupportedGrantType, "unsupported_grant_type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			router, mockService := newOAuthRouter(ctrl)
+
+			mockService.EXPECT().ExchangeDeviceCode(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, tt.err)
+
+			w := postForm(router, "/token", deviceValues)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var response dto.OAuthErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.wantError, response.Error)
+		})
+	}
+
+	t.Run("missing device code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newOAuthRouter(ctrl)
+
+		w := postForm(router, "/token", url.Values{"grant_type": {string(domain.GrantTypeDeviceCode)}, "client_id": {"cli"}})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_request")
+	})
+}
+
+func TestAuthHandler_GETDevice(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("code entry", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newOAuthRouter(ctrl)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/device", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `name="user_code"`)
+		assert.NotContains(t, w.Body.String(), `name="password"`)
+		assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+	})
+
+	t.Run("code from verification uri", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().LookupDeviceAuthorization(gomock.Any(), "BDFH-KLMN", gomock.Any(), gomock.Any()).Return(deviceClient, nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/device?user_code=BDFH-KLMN", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "Internal CLI")
+		assert.Contains(t, w.Body.String(), `name="password"`)
+	})
+
+	t.Run("invalid code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().LookupDeviceAuthorization(gomock.Any(), "BDFH-KLMN", gomock.Any(), gomock.Any()).Return(nil, domain.ErrInvalidUserCode)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/device?user_code=BDFH-KLMN", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "Код неверен")
+		assert.NotContains(t, w.Body.String(), `name="password"`)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().LookupDeviceAuthorization(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, domain.ErrDeviceFlowDisabled)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/device?user_code=BDFH-KLMN", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestAuthHandler_POSTDevice(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	loginValues := url.Values{
+		"user_code": {"BDFH-KLMN"},
+		"email":     {"user@example.com"},
+		"password":  {"correct horse"},
+		"action":    {"allow"},
+	}
+
+	t.Run("approve", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().LookupDeviceAuthorization(gomock.Any(), "BDFH-KLMN", gomock.Any(), gomock.Any()).Return(deviceClient, nil)
+		mockService.EXPECT().ApproveDeviceWithPassword(gomock.Any(), "BDFH-KLMN", "user@example.com", "correct horse", gomock.Any(), gomock.Any()).
+			Return(&domain.AuthorizationGrant{}, nil)
+
+		w := postForm(router, "/device", loginValues)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "Устройство подключено")
+	})
+
+	t.Run("mfa required renders code form", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().LookupDeviceAuthorization(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(deviceClient, nil)
+		mockService.EXPECT().ApproveDeviceWithPassword(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&domain.AuthorizationGrant{MFAToken: "mfa-token"}, nil)
+
+		w := postForm(router, "/device", loginValues)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `value="mfa-token"`)
+		assert.Contains(t, w.Body.String(), `name="code"`)
+	})
+
+	t.Run("mfa", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().LookupDeviceAuthorization(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(deviceClient, nil)
+		mockService.EXPECT().ApproveDeviceWithMFA(gomock.Any(), "BDFH-KLMN", "mfa-token", "123456", gomock.Any(), gomock.Any()).Return(nil)
+
+		w := postForm(router, "/device", url.Values{
+			"user_code": {"BDFH-KLMN"},
+			"mfa_token": {"mfa-token"},
+			"code":      {"123456"},
+			"action":    {"allow"},
+		})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "Устройство подключено")
+	})
+
+	t.Run("wrong password renders page again", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().LookupDeviceAuthorization(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(deviceClient, nil)
+		mockService.EXPECT().ApproveDeviceWithPassword(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, domain.ErrInvalidCredentials)
+
+		w := postForm(router, "/device", loginValues)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "Неверный email или пароль")
+		assert.Contains(t, w.Body.String(), `value="user@example.com"`)
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().LookupDeviceAuthorization(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(deviceClient, nil)
+		mockService.EXPECT().DenyDeviceAuthorization(gomock.Any(), "BDFH-KLMN", gomock.Any(), gomock.Any()).Return(nil)
+
+		w := postForm(router, "/device", url.Values{"user_code": {"BDFH-KLMN"}, "action": {"deny"}})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "Доступ запрещен")
+	})
+
+	t.Run("expired code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().LookupDeviceAuthorization(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, domain.ErrInvalidUserCode)
+
+		w := postForm(router, "/device", loginValues)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "Код неверен")
+	})
+}
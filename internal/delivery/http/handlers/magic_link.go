@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// POSTMagicLink отправляет на email одноразовую ссылку для входа.
+// Ответ одинаков для зарегистрированных и незарегистрированных email.
+func (h *AuthHandler) POSTMagicLink(c *gin.Context) {
+	var req dto.MagicLinkRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Debug("error binding json", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	err := h.service.RequestMagicLink(c.Request.Context(), req.Email, c.ClientIP(), c.Request.UserAgent())
+
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// GETMagicLinkVerify обменивает токен из ссылки на пару токенов.
+func (h *AuthHandler) GETMagicLinkVerify(c *gin.Context) {
+	var params dto.MagicLinkQueryParams
+
+	if err := c.ShouldBindQuery(&params); err != nil {
+		h.logger.Debug("error binding query", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	domainAuth, err := h.service.VerifyMagicLink(c.Request.Context(), params.Token, c.ClientIP(), c.Request.UserAgent())
+
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	writeAuth(c, domainAuth)
+}
@@ -0,0 +1,122 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/delivery/http/handlers"
+	"github.com/maksemen2/medods-task/internal/domain"
+	mock_service "github.com/maksemen2/medods-task/internal/service/mocks"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMagicLinkRouter(ctrl *gomock.Controller) (*gin.Engine, *mock_service.MockIAuthService) {
+	mockService := mock_service.NewMockIAuthService(ctrl)
+	h := handlers.NewAuthHandler(zap.NewNop(), mockService)
+
+	router := gin.New()
+	router.POST("/login/magic", h.POSTMagicLink)
+	router.GET("/login/magic/verify", h.GETMagicLinkVerify)
+	return router, mockService
+}
+
+func TestAuthHandler_POSTMagicLink(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("accepted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newMagicLinkRouter(ctrl)
+
+		mockService.EXPECT().RequestMagicLink(gomock.Any(), "user@example.com", gomock.Any(), gomock.Any()).Return(nil)
+
+		w := postMFA(router, "/login/magic", "", dto.MagicLinkRequest{Email: "user@example.com"})
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+	})
+
+	t.Run("invalid email", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newMagicLinkRouter(ctrl)
+
+		mockService.EXPECT().RequestMagicLink(gomock.Any(), "nope", gomock.Any(), gomock.Any()).Return(domain.ErrInvalidEmail)
+
+		w := postMFA(router, "/login/magic", "", dto.MagicLinkRequest{Email: "nope"})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newMagicLinkRouter(ctrl)
+
+		mockService.EXPECT().RequestMagicLink(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(domain.ErrMagicLinkDisabled)
+
+		w := postMFA(router, "/login/magic", "", dto.MagicLinkRequest{Email: "user@example.com"})
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestAuthHandler_GETMagicLinkVerify(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	get := func(router *gin.Engine, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newMagicLinkRouter(ctrl)
+
+		mockService.EXPECT().VerifyMagicLink(gomock.Any(), "signed.token", gomock.Any(), gomock.Any()).
+			Return(&domain.UserAuth{AccessToken: "access", RefreshToken: "refresh"}, nil)
+
+		w := get(router, "/login/magic/verify?token=signed.token")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response dto.AuthResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, dto.AuthResponse{AccessToken: "access", RefreshToken: "refresh"}, response)
+	})
+
+	t.Run("used link", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newMagicLinkRouter(ctrl)
+
+		mockService.EXPECT().VerifyMagicLink(gomock.Any(), "signed.token", gomock.Any(), gomock.Any()).Return(nil, domain.ErrInvalidMagicLink)
+
+		w := get(router, "/login/magic/verify?token=signed.token")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newMagicLinkRouter(ctrl)
+
+		w := get(router, "/login/magic/verify")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
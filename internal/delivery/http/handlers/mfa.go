@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// POSTMFAVerify обменивает MFA-челлендж и код второго фактора на пару токенов.
+// Вместо кода TOTP может быть передан код восстановления.
+func (h *AuthHandler) POSTMFAVerify(c *gin.Context) {
+	var req dto.MFAVerifyRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Debug("error binding json", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	var (
+		domainAuth *domain.UserAuth
+		err        error
+	)
+
+	if req.RecoveryCode != "" {
+		domainAuth, err = h.service.VerifyMFARecovery(c.Request.Context(), req.MFAToken, req.RecoveryCode, c.ClientIP(), c.Request.UserAgent())
+	} else {
+		domainAuth, err = h.service.VerifyMFA(c.Request.Context(), req.MFAToken, req.Code, c.ClientIP(), c.Request.UserAgent())
+	}
+
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	writeAuth(c, domainAuth)
+}
+
+// POSTTOTPEnroll начинает подключение TOTP. Access токен передается в заголовке "Authorization: Bearer <токен>".
+func (h *AuthHandler) POSTTOTPEnroll(c *gin.Context) {
+	accessToken, ok := bearerToken(c)
+	if !ok {
+		return
+	}
+
+	setup, err := h.service.EnrollTOTP(c.Request.Context(), accessToken)
+
+	if err != nil {
+		h.handleSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.TOTPEnrollResponse{
+		Secret:          setup.Secret,
+		ProvisioningURI: setup.ProvisioningURI,
+	})
+}
+
+// POSTTOTPConfirm подтверждает подключение TOTP кодом из приложения-аутентификатора.
+// Access токен передается в заголовке "Authorization: Bearer <токен>".
+func (h *AuthHandler) POSTTOTPConfirm(c *gin.Context) {
+	accessToken, ok := bearerToken(c)
+	if !ok {
+		return
+	}
+
+	var req dto.TOTPConfirmRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Debug("error binding json", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	err := h.service.ConfirmTOTP(c.Request.Context(), accessToken, req.Code, c.ClientIP(), c.Request.UserAgent())
+
+	if err != nil {
+		h.handleSessionError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// POSTRecoveryCodes генерирует новый набор кодов восстановления взамен предыдущего.
+// Коды возвращаются только в этом ответе. Access токен передается в заголовке "Authorization: Bearer <токен>".
+func (h *AuthHandler) POSTRecoveryCodes(c *gin.Context) {
+	accessToken, ok := bearerToken(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.service.GenerateRecoveryCodes(c.Request.Context(), accessToken, c.ClientIP(), c.Request.UserAgent())
+
+	if err != nil {
+		h.handleSessionError(c, err)
+		return
+	}
+
+	// Коды показываются один раз и не должны оседать в кешах
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{Codes: codes})
+}
@@ -0,0 +1,265 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/delivery/http/handlers"
+	"github.com/maksemen2/medods-task/internal/domain"
+	mock_service "github.com/maksemen2/medods-task/internal/service/mocks"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMFARouter(ctrl *gomock.Controller) (*gin.Engine, *mock_service.MockIAuthService) {
+	mockService := mock_service.NewMockIAuthService(ctrl)
+	h := handlers.NewAuthHandler(zap.NewNop(), mockService)
+
+	router := gin.New()
+	router.POST("/mfa/verify", h.POSTMFAVerify)
+	router.POST("/mfa/totp/enroll", h.POSTTOTPEnroll)
+	router.POST("/mfa/totp/confirm", h.POSTTOTPConfirm)
+	router.POST("/mfa/recovery-codes", h.POSTRecoveryCodes)
+	return router, mockService
+}
+
+func postMFA(router *gin.Engine, path, authorization string, request any) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(request)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", path, bytes.NewReader(body))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthHandler_POSTMFAVerify(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newMFARouter(ctrl)
+
+		mockService.EXPECT().VerifyMFA(gomock.Any(), "mfa", "123456", gomock.Any(), gomock.Any()).
+			Return(&domain.UserAuth{AccessToken: "access", RefreshToken: "refresh"}, nil)
+
+		w := postMFA(router, "/mfa/verify", "", dto.MFAVerifyRequest{MFAToken: "mfa", Code: "123456"})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response dto.AuthResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, dto.AuthResponse{AccessToken: "access", RefreshToken: "refresh"}, response)
+	})
+
+	t.Run("recovery code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newMFARouter(ctrl)
+
+		mockService.EXPECT().VerifyMFARecovery(gomock.Any(), "mfa", "ABCDE-FGH23", gomock.Any(), gomock.Any()).
+			Return(&domain.UserAuth{AccessToken: "access", RefreshToken: "refresh"}, nil)
+
+		w := postMFA(router, "/mfa/verify", "", dto.MFAVerifyRequest{MFAToken: "mfa", RecoveryCode: "ABCDE-FGH23"})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("missing code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newMFARouter(ctrl)
+
+		w := postMFA(router, "/mfa/verify", "", map[string]string{"mfa_token": "mfa"})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("both codes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newMFARouter(ctrl)
+
+		w := postMFA(router, "/mfa/verify", "", dto.MFAVerifyRequest{MFAToken: "mfa", Code: "123456", RecoveryCode: "ABCDE-FGH23"})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("service errors", func(t *testing.T) {
+		cases := map[error]int{
+			domain.ErrInvalidMFAToken: http.StatusUnauthorized,
+			domain.ErrInvalidMFACode:  http.StatusUnauthorized,
+			domain.ErrMFADisabled:     http.StatusNotFound,
+			domain.ErrUnexpected:      http.StatusInternalServerError,
+		}
+
+		for serviceErr, status := range cases {
+			ctrl := gomock.NewController(t)
+
+			router, mockService := newMFARouter(ctrl)
+			mockService.EXPECT().VerifyMFA(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(nil, serviceErr)
+
+			w := postMFA(router, "/mfa/verify", "", dto.MFAVerifyRequest{MFAToken: "mfa", Code: "123456"})
+
+			assert.Equal(t, status, w.Code, serviceErr.Error())
+			ctrl.Finish()
+		}
+	})
+}
+
+func TestAuthHandler_POSTTOTPEnroll(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newMFARouter(ctrl)
+
+		mockService.EXPECT().EnrollTOTP(gomock.Any(), "access").
+			Return(&domain.TOTPSetup{Secret: "SECRET", ProvisioningURI: "otpauth://totp/x"}, nil)
+
+		w := postMFA(router, "/mfa/totp/enroll", "Bearer access", nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response dto.TOTPEnrollResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, dto.TOTPEnrollResponse{Secret: "SECRET", ProvisioningURI: "otpauth://totp/x"}, response)
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newMFARouter(ctrl)
+
+		w := postMFA(router, "/mfa/totp/enroll", "", nil)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("token requires re-authentication", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newMFARouter(ctrl)
+		mockService.EXPECT().EnrollTOTP(gomock.Any(), "step-up").Return(nil, domain.ErrReauthRequired)
+
+		w := postMFA(router, "/mfa/totp/enroll", "Bearer step-up", nil)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, `Bearer error="insufficient_user_authentication"`, w.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("service errors", func(t *testing.T) {
+		cases := map[error]int{
+			domain.ErrInvalidAccessToken: http.StatusUnauthorized,
+			domain.ErrTOTPAlreadyEnabled: http.StatusConflict,
+			domain.ErrMFADisabled:        http.StatusNotFound,
+		}
+
+		for serviceErr, status := range cases {
+			ctrl := gomock.NewController(t)
+
+			router, mockService := newMFARouter(ctrl)
+			mockService.EXPECT().EnrollTOTP(gomock.Any(), gomock.Any()).Return(nil, serviceErr)
+
+			w := postMFA(router, "/mfa/totp/enroll", "Bearer access", nil)
+
+			assert.Equal(t, status, w.Code, serviceErr.Error())
+			ctrl.Finish()
+		}
+	})
+}
+
+func TestAuthHandler_POSTTOTPConfirm(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newMFARouter(ctrl)
+
+		mockService.EXPECT().ConfirmTOTP(gomock.Any(), "access", "123456", gomock.Any(), gomock.Any()).Return(nil)
+
+		w := postMFA(router, "/mfa/totp/confirm", "Bearer access", dto.TOTPConfirmRequest{Code: "123456"})
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("service errors", func(t *testing.T) {
+		cases := map[error]int{
+			domain.ErrInvalidAccessToken: http.StatusUnauthorized,
+			domain.ErrInvalidMFACode:     http.StatusUnauthorized,
+			domain.ErrTOTPNotEnrolled:    http.StatusBadRequest,
+			domain.ErrTOTPAlreadyEnabled: http.StatusConflict,
+		}
+
+		for serviceErr, status := range cases {
+			ctrl := gomock.NewController(t)
+
+			router, mockService := newMFARouter(ctrl)
+			mockService.EXPECT().ConfirmTOTP(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(serviceErr)
+
+			w := postMFA(router, "/mfa/totp/confirm", "Bearer access", dto.TOTPConfirmRequest{Code: "123456"})
+
+			assert.Equal(t, status, w.Code, serviceErr.Error())
+			ctrl.Finish()
+		}
+	})
+}
+
+func TestAuthHandler_POSTRecoveryCodes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newMFARouter(ctrl)
+
+		mockService.EXPECT().GenerateRecoveryCodes(gomock.Any(), "access", gomock.Any(), gomock.Any()).
+			Return([]string{"ABCDE-FGH23", "JKLMN-PQR45"}, nil)
+
+		w := postMFA(router, "/mfa/recovery-codes", "Bearer access", nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		var response dto.RecoveryCodesResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, []string{"ABCDE-FGH23", "JKLMN-PQR45"}, response.Codes)
+	})
+
+	t.Run("service errors", func(t *testing.T) {
+		cases := map[error]int{
+			domain.ErrInvalidAccessToken: http.StatusUnauthorized,
+			domain.ErrTOTPNotEnrolled:    http.StatusBadRequest,
+			domain.ErrMFADisabled:        http.StatusNotFound,
+		}
+
+		for serviceErr, status := range cases {
+			ctrl := gomock.NewController(t)
+
+			router, mockService := newMFARouter(ctrl)
+			mockService.EXPECT().GenerateRecoveryCodes(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, serviceErr)
+
+			w := postMFA(router, "/mfa/recovery-codes", "Bearer access", nil)
+
+			assert.Equal(t, status, w.Code, serviceErr.Error())
+			ctrl.Finish()
+		}
+	})
+}
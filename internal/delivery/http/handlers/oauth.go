@@ -0,0 +1,357 @@
+package handlers
+
+import (
+	"embed"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/oauth"
+	"go.uber.org/zap"
+	"html/template"
+	"net/http"
+	"net/url"
+)
+
+const authorizeActionDeny = "deny"
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var authorizeTemplate = template.Must(template.ParseFS(templatesFS, "templates/authorize.html"))
+
+// authorizePage - данные страницы входа OAuth.
+// Fatal означает, что запрос нельзя вернуть клиенту редиректом и показывается только Error.
+type authorizePage struct {
+	ClientName string
+	Request    dto.AuthorizeParams
+	Email      string
+	MFAToken   string
+	Error      string
+	Fatal      bool
+}
+
+func authorizationRequest(params dto.AuthorizeParams) domain.AuthorizationRequest {
+	return domain.AuthorizationRequest{
+		ResponseType:        params.ResponseType,
+		ClientID:            params.ClientID,
+		RedirectURI:         params.RedirectURI,
+		State:               params.State,
+		CodeChallenge:       params.CodeChallenge,
+		CodeChallengeMethod: params.CodeChallengeMethod,
+		Scope:               params.Scope,
+	}
+}
+
+// renderPage отрисовывает страницу с формой входа.
+// Страница запрещает встраивание во фреймы и кэширование, так как содержит форму ввода пароля.
+func (h *AuthHandler) renderPage(c *gin.Context, tmpl *template.Template, status int, data any) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(status)
+
+	if err := tmpl.Execute(c.Writer, data); err != nil {
+		h.logger.Error("error rendering page", zap.String("template", tmpl.Name()), zap.Error(err))
+	}
+}
+
+// renderAuthorizePage отрисовывает страницу входа OAuth.
+func (h *AuthHandler) renderAuthorizePage(c *gin.Context, status int, page authorizePage) {
+	h.renderPage(c, authorizeTemplate, status, page)
+}
+
+// redirectAuthorize возвращает пользователя на redirect_uri клиента с параметрами ответа и исходным state.
+func redirectAuthorize(c *gin.Context, req dto.AuthorizeParams, params url.Values) {
+	params.Set("state", req.State)
+	c.Redirect(http.StatusFound, oauth.RedirectURL(req.RedirectURI, params))
+}
+
+func redirectAuthorizeError(c *gin.Context, req dto.AuthorizeParams, code string) {
+	redirectAuthorize(c, req, url.Values{"error": {code}})
+}
+
+// handleAuthorizeError обрабатывает ошибки проверки запроса авторизации.
+// Пока клиент и redirect_uri не проверены, перенаправлять на redirect_uri нельзя, поэтому ошибка показывается на странице.
+func (h *AuthHandler) handleAuthorizeError(c *gin.Context, req dto.AuthorizeParams, err error) {
+	switch {
+	case errors.Is(err, domain.ErrOAuthDisabled):
+		c.AbortWithStatus(http.StatusNotFound)
+	case errors.Is(err, domain.ErrInvalidClient), errors.Is(err, domain.ErrInvalidRedirectURI):
+		h.renderAuthorizePage(c, http.StatusBadRequest, authorizePage{
+			Error: "Неизвестное приложение или недопустимый адрес возврата.",
+			Fatal: true,
+		})
+	case errors.Is(err, domain.ErrUnsupportedResponseType):
+		redirectAuthorizeError(c, req, oauth.ErrorUnsupportedResponseType)
+	case errors.Is(err, domain.ErrUnauthorizedClient):
+		redirectAuthorizeError(c, req, oauth.ErrorUnauthorizedClient)
+	case errors.Is(err, domain.ErrInvalidOAuthRequest):
+		redirectAuthorizeError(c, req, oauth.ErrorInvalidRequest)
+	case errors.Is(err, domain.ErrInvalidScope):
+		redirectAuthorizeError(c, req, oauth.ErrorInvalidScope)
+	case errors.Is(err, domain.ErrRiskDenied):
+		redirectAuthorizeError(c, req, oauth.ErrorAccessDenied)
+	default:
+		if !errors.Is(err, domain.ErrUnexpected) {
+			h.logger.Error("unexpected error from authService", zap.Error(err))
+		}
+		redirectAuthorizeError(c, req, oauth.ErrorServerError)
+	}
+}
+
+// GETAuthorize проверяет запрос авторизации OAuth и показывает страницу входа.
+func (h *AuthHandler) GETAuthorize(c *gin.Context) {
+	var params dto.AuthorizeParams
+
+	if err := c.ShouldBindQuery(&params); err != nil {
+		h.logger.Debug("error binding query", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	client, err := h.service.ValidateAuthorizationRequest(c.Request.Context(), authorizationRequest(params))
+
+	if err != nil {
+		h.handleAuthorizeError(c, params, err)
+		return
+	}
+
+	h.renderAuthorizePage(c, http.StatusOK, authorizePage{ClientName: client.Name, Request: params})
+}
+
+// POSTAuthorize принимает форму страницы входа: email и пароль либо код второго фактора.
+// После успешного входа перенаправляет пользователя на redirect_uri с кодом авторизации.
+func (h *AuthHandler) POSTAuthorize(c *gin.Context) {
+	var form dto.AuthorizeForm
+
+	if err := c.ShouldBind(&form); err != nil {
+		h.logger.Debug("error binding form", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	ctx := c.Request.Context()
+	req := authorizationRequest(form.AuthorizeParams)
+
+	client, err := h.service.ValidateAuthorizationRequest(ctx, req)
+
+	if err != nil {
+		h.handleAuthorizeError(c, form.AuthorizeParams, err)
+		return
+	}
+
+	if form.Action == authorizeActionDeny {
+		redirectAuthorizeError(c, form.AuthorizeParams, oauth.ErrorAccessDenied)
+		return
+	}
+
+	page := authorizePage{ClientName: client.Name, Request: form.AuthorizeParams, Email: form.Email}
+
+	var grant *domain.AuthorizationGrant
+
+	if form.MFAToken != "" {
+		grant, err = h.service.AuthorizeWithMFA(ctx, req, form.MFAToken, form.Code, c.ClientIP(), c.Request.UserAgent())
+	} else {
+		grant, err = h.service.AuthorizeWithPassword(ctx, req, form.Email, form.Password, c.ClientIP(), c.Request.UserAgent())
+	}
+
+	switch {
+	case err == nil:
+	case errors.Is(err, domain.ErrInvalidCredentials):
+		page.Error = "Неверный email или пароль."
+		h.renderAuthorizePage(c, http.StatusUnauthorized, page)
+		return
+	case errors.Is(err, domain.ErrInvalidMFACode):
+		page.MFAToken = form.MFAToken
+		page.Error = "Неверный код."
+		h.renderAuthorizePage(c, http.StatusUnauthorized, page)
+		return
+	case errors.Is(err, domain.ErrInvalidMFAToken):
+		page.Error = "Время на ввод кода истекло, войдите снова."
+		h.renderAuthorizePage(c, http.StatusUnauthorized, page)
+		return
+	default:
+		h.handleAuthorizeError(c, form.AuthorizeParams, err)
+		return
+	}
+
+	if grant.MFARequired() {
+		page.MFAToken = grant.MFAToken
+		h.renderAuthorizePage(c, http.StatusOK, page)
+		return
+	}
+
+	redirectAuthorize(c, form.AuthorizeParams, url.Values{"code": {grant.Code}})
+}
+
+// writeOAuthError отвечает ошибкой эндпоинта /token в формате RFC 6749.
+func writeOAuthError(c *gin.Context, status int, code, description string) {
+	c.Header("Cache-Control", "no-store")
+	c.AbortWithStatusJSON(status, dto.OAuthErrorResponse{Error: code, ErrorDescription: description})
+}
+
+// handleTokenError обрабатывает доменные ошибки эндпоинта /token.
+func (h *AuthHandler) handleTokenError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrOAuthDisabled), errors.Is(err, domain.ErrDeviceFlowDisabled):
+		c.AbortWithStatus(http.StatusNotFound)
+	case errors.Is(err, domain.ErrInvalidClient):
+		// Клиенту, аутентифицировавшемуся через заголовок Authorization, сообщается схема аутентификации (RFC 6749, раздел 5.2)
+		if _, _, ok := c.Request.BasicAuth(); ok {
+			c.Header("WWW-Authenticate", `Basic realm="token"`)
+		}
+		writeOAuthError(c, http.StatusUnauthorized, oauth.ErrorInvalidClient, "")
+	case errors.Is(err, domain.ErrUnauthorizedClient):
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorUnauthorizedClient, "")
+	case errors.Is(err, domain.ErrInvalidGrant):
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidGrant, "")
+	case errors.Is(err, domain.ErrInvalidScope):
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidScope, "")
+	case errors.Is(err, domain.ErrInvalidOAuthRequest):
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidRequest, "")
+	case errors.Is(err, domain.ErrUnsupportedGrantType):
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorUnsupportedGrantType, "")
+	case errors.Is(err, domain.ErrAuthorizationPending):
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorAuthorizationPending, "")
+	case errors.Is(err, domain.ErrSlowDown):
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorSlowDown, "")
+	case errors.Is(err, domain.ErrExpiredDeviceCode):
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorExpiredToken, "")
+	case errors.Is(err, domain.ErrAccessDenied):
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorAccessDenied, "")
+	default:
+		if !errors.Is(err, domain.ErrUnexpected) {
+			h.logger.Error("unexpected error from authService", zap.Error(err))
+		}
+		writeOAuthError(c, http.StatusInternalServerError, oauth.ErrorServerError, "")
+	}
+}
+
+// clientCredentials извлекает учетные данные клиента из заголовка Authorization (client_secret_basic)
+// или из тела запроса (client_secret_post). Передавать их обоими способами одновременно нельзя.
+func clientCredentials(c *gin.Context, formClientID, formSecret string) (domain.ClientCredentials, bool) {
+	username, password, ok := c.Request.BasicAuth()
+	if !ok {
+		return domain.ClientCredentials{ID: formClientID, Secret: formSecret}, true
+	}
+
+	if formSecret != "" {
+		return domain.ClientCredentials{}, false
+	}
+
+	// Идентификатор и секрет в заголовке закодированы как application/x-www-form-urlencoded (RFC 6749, раздел 2.3.1)
+	clientID, err := url.QueryUnescape(username)
+	if err != nil {
+		return domain.ClientCredentials{}, false
+	}
+	secret, err := url.QueryUnescape(password)
+	if err != nil {
+		return domain.ClientCredentials{}, false
+	}
+
+	if formClientID != "" && formClientID != clientID {
+		return domain.ClientCredentials{}, false
+	}
+
+	return domain.ClientCredentials{ID: clientID, Secret: secret}, true
+}
+
+// POSTToken обменивает код авторизации, код устройства или Refresh токен на пару токенов для клиента OAuth,
+// а также выдает клиенту Access токен от его собственного имени (client_credentials).
+func (h *AuthHandler) POSTToken(c *gin.Context) {
+	var req dto.TokenRequest
+
+	if err := c.ShouldBind(&req); err != nil {
+		h.logger.Debug("error binding form", zap.Error(err))
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidRequest, "")
+		return
+	}
+
+	creds, ok := clientCredentials(c, req.ClientID, req.ClientSecret)
+	if !ok {
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidRequest, "conflicting client credentials")
+		return
+	}
+	if creds.ID == "" {
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidRequest, "client_id is required")
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	var (
+		domainAuth *domain.UserAuth
+		err        error
+	)
+
+	switch domain.GrantType(req.GrantType) {
+	case domain.GrantTypeAuthorizationCode:
+		if req.Code == "" || req.RedirectURI == "" || req.CodeVerifier == "" {
+			writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidRequest, "code, redirect_uri and code_verifier are required")
+			return
+		}
+		domainAuth, err = h.service.ExchangeAuthorizationCode(ctx, creds, req.Code, req.RedirectURI, req.CodeVerifier, c.ClientIP(), c.Request.UserAgent())
+	case domain.GrantTypeRefreshToken:
+		if req.RefreshToken == "" || req.AccessToken == "" {
+			writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidRequest, "refresh_token and access_token are required")
+			return
+		}
+		domainAuth, err = h.service.ExchangeRefreshToken(ctx, creds, req.AccessToken, req.RefreshToken, c.ClientIP(), c.Request.UserAgent())
+	case domain.GrantTypeClientCredentials:
+		domainAuth, err = h.service.ExchangeClientCredentials(ctx, creds, req.Scope, c.ClientIP(), c.Request.UserAgent())
+	case domain.GrantTypeDeviceCode:
+		if req.DeviceCode == "" {
+			writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidRequest, "device_code is required")
+			return
+		}
+		domainAuth, err = h.service.ExchangeDeviceCode(ctx, creds, req.DeviceCode, c.ClientIP(), c.Request.UserAgent())
+	case domain.GrantTypeTokenExchange:
+		if req.SubjectToken == "" || req.SubjectTokenType == "" {
+			writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidRequest, "subject_token and subject_token_type are required")
+			return
+		}
+		if req.ActorToken != "" && req.ActorTokenType == "" {
+			writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidRequest, "actor_token_type is required")
+			return
+		}
+		// Выдаются только Access токены
+		if req.RequestedTokenType != "" && req.RequestedTokenType != oauth.TokenTypeAccessToken {
+			writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidRequest, "unsupported requested_token_type")
+			return
+		}
+		domainAuth, err = h.service.ExchangeToken(ctx, creds, domain.TokenExchangeRequest{
+			SubjectToken:     req.SubjectToken,
+			SubjectTokenType: req.SubjectTokenType,
+			ActorToken:       req.ActorToken,
+			ActorTokenType:   req.ActorTokenType,
+			Scope:            req.Scope,
+		}, c.ClientIP(), c.Request.UserAgent())
+	case "":
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidRequest, "grant_type is required")
+		return
+	default:
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorUnsupportedGrantType, "")
+		return
+	}
+
+	if err != nil {
+		h.handleTokenError(c, err)
+		return
+	}
+
+	response := dto.TokenResponse{
+		AccessToken:  domainAuth.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(domainAuth.ExpiresIn.Seconds()),
+		RefreshToken: domainAuth.RefreshToken,
+		Scope:        oauth.FormatScope(domainAuth.Scopes),
+	}
+	if domain.GrantType(req.GrantType) == domain.GrantTypeTokenExchange {
+		response.IssuedTokenType = oauth.TokenTypeAccessToken
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, response)
+}
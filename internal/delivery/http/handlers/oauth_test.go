@@ -0,0 +1,539 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/delivery/http/handlers"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/oauth"
+	mock_service "github.com/maksemen2/medods-task/internal/service/mocks"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOAuthRouter(ctrl *gomock.Controller) (*gin.Engine, *mock_service.MockIAuthService) {
+	mockService := mock_service.NewMockIAuthService(ctrl)
+	h := handlers.NewAuthHandler(zap.NewNop(), mockService)
+
+	router := gin.New()
+	router.GET("/authorize", h.GETAuthorize)
+	router.POST("/authorize", h.POSTAuthorize)
+	router.POST("/token", h.POSTToken)
+	router.POST("/device/code", h.POSTDeviceCode)
+	router.GET("/device", h.GETDevice)
+	router.POST("/device", h.POSTDevice)
+	return router, mockService
+}
+
+func postForm(router *gin.Engine, path string, form url.Values) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func authorizeValues() url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {"spa"},
+		"redirect_uri":          {"https://app.example.com/callback"},
+		"state":                 {"xyz"},
+		"code_challenge":        {"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+var oauthClient = &domain.OAuthClient{ID: "spa", Name: "Demo SPA", RedirectURIs: []string{"https://app.example.com/callback"}}
+
+func TestAuthHandler_GETAuthorize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("renders login page", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().ValidateAuthorizationRequest(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ any, req domain.AuthorizationRequest) (*domain.OAuthClient, error) {
+				assert.Equal(t, "spa", req.ClientID)
+				assert.Equal(t, "xyz", req.State)
+				return oauthClient, nil
+			})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/authorize?"+authorizeValues().Encode(), nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "Demo SPA")
+		assert.Contains(t, w.Body.String(), `name="password"`)
+		assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("unknown client is not redirected", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().ValidateAuthorizationRequest(gomock.Any(), gomock.Any()).Return(nil, domain.ErrInvalidRedirectURI)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/authorize?"+authorizeValues().Encode(), nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Empty(t, w.Header().Get("Location"))
+	})
+
+	t.Run("invalid request is redirected with error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().ValidateAuthorizationRequest(gomock.Any(), gomock.Any()).Return(nil, domain.ErrInvalidOAuthRequest)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/authorize?"+authorizeValues().Encode(), nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://app.example.com/callback?error=invalid_request&state=xyz", w.Header().Get("Location"))
+	})
+
+	t.Run("scope not allowed is redirected with error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().ValidateAuthorizationRequest(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ any, req domain.AuthorizationRequest) (*domain.OAuthClient, error) {
+				assert.Equal(t, "admin", req.Scope)
+				return nil, domain.ErrInvalidScope
+			})
+
+		values := authorizeValues()
+		values.Set("scope", "admin")
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/authorize?"+values.Encode(), nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://app.example.com/callback?error=invalid_scope&state=xyz", w.Header().Get("Location"))
+	})
+
+	t.Run("oauth disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().ValidateAuthorizationRequest(gomock.Any(), gomock.Any()).Return(nil, domain.ErrOAuthDisabled)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/authorize", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestAuthHandler_POSTAuthorize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("redirects with code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().ValidateAuthorizationRequest(gomock.Any(), gomock.Any()).Return(oauthClient, nil)
+		mockService.EXPECT().AuthorizeWithPassword(gomock.Any(), gomock.Any(), "user@example.com", "secret", gomock.Any(), gomock.Any()).
+			Return(&domain.AuthorizationGrant{Code: "code"}, nil)
+
+		form := authorizeValues()
+		form.Set("email", "user@example.com")
+		form.Set("password", "secret")
+		w := postForm(router, "/authorize", form)
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://app.example.com/callback?code=code&state=xyz", w.Header().Get("Location"))
+	})
+
+	t.Run("wrong password renders page again", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().ValidateAuthorizationRequest(gomock.Any(), gomock.Any()).Return(oauthClient, nil)
+		mockService.EXPECT().AuthorizeWithPassword(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, domain.ErrInvalidCredentials)
+
+		form := authorizeValues()
+		form.Set("email", "user@example.com")
+		form.Set("password", "wrong")
+		w := postForm(router, "/authorize", form)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), `value="user@example.com"`)
+	})
+
+	t.Run("mfa required renders code form", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().ValidateAuthorizationRequest(gomock.Any(), gomock.Any()).Return(oauthClient, nil).Times(2)
+		mockService.EXPECT().AuthorizeWithPassword(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&domain.AuthorizationGrant{MFAToken: "mfa", MFAExpiresAt: time.Now().Add(time.Minute)}, nil)
+		mockService.EXPECT().AuthorizeWithMFA(gomock.Any(), gomock.Any(), "mfa", "123456", gomock.Any(), gomock.Any()).
+			Return(&domain.AuthorizationGrant{Code: "code"}, nil)
+
+		form := authorizeValues()
+		form.Set("email", "user@example.com")
+		form.Set("password", "secret")
+		w := postForm(router, "/authorize", form)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `name="mfa_token" value="mfa"`)
+
+		form = authorizeValues()
+		form.Set("mfa_token", "mfa")
+		form.Set("code", "123456")
+		w = postForm(router, "/authorize", form)
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://app.example.com/callback?code=code&state=xyz", w.Header().Get("Location"))
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().ValidateAuthorizationRequest(gomock.Any(), gomock.Any()).Return(oauthClient, nil)
+
+		form := authorizeValues()
+		form.Set("action", "deny")
+		w := postForm(router, "/authorize", form)
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://app.example.com/callback?error=access_denied&state=xyz", w.Header().Get("Location"))
+	})
+}
+
+func TestAuthHandler_POSTToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	codeForm := func() url.Values {
+		return url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {"spa"},
+			"code":          {"code"},
+			"redirect_uri":  {"https://app.example.com/callback"},
+			"code_verifier": {"verifier"},
+		}
+	}
+
+	t.Run("authorization code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().ExchangeAuthorizationCode(gomock.Any(), domain.ClientCredentials{ID: "spa"}, "code", "https://app.example.com/callback", "verifier", gomock.Any(), gomock.Any()).
+			Return(&domain.UserAuth{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 15 * time.Minute, Scopes: []string{"profile", "email"}}, nil)
+
+		w := postForm(router, "/token", codeForm())
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		var response dto.TokenResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, dto.TokenResponse{AccessToken: "access", TokenType: "Bearer", ExpiresIn: 900, RefreshToken: "refresh", Scope: "profile email"}, response)
+	})
+
+	t.Run("refresh token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().ExchangeRefreshToken(gomock.Any(), domain.ClientCredentials{ID: "spa"}, "access", "refresh", gomock.Any(), gomock.Any()).
+			Return(&domain.UserAuth{AccessToken: "access2", RefreshToken: "refresh2", ExpiresIn: time.Minute}, nil)
+
+		w := postForm(router, "/token", url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {"spa"},
+			"access_token":  {"access"},
+			"refresh_token": {"refresh"},
+		})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("client credentials", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().ExchangeClientCredentials(gomock.Any(), domain.ClientCredentials{ID: "backend", Secret: "secret"}, "jobs", gomock.Any(), gomock.Any()).
+			Return(&domain.UserAuth{AccessToken: "access", ExpiresIn: time.Minute, Scopes: []string{"jobs"}}, nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/token", strings.NewReader(url.Values{
+			"grant_type": {"client_credentials"},
+			"scope":      {"jobs"},
+		}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth("backend", "secret")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response dto.TokenResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, dto.TokenResponse{AccessToken: "access", TokenType: "Bearer", ExpiresIn: 60, Scope: "jobs"}, response)
+	})
+
+	t.Run("client credentials with invalid scope", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().ExchangeClientCredentials(gomock.Any(), gomock.Any(), "admin", gomock.Any(), gomock.Any()).Return(nil, domain.ErrInvalidScope)
+
+		w := postForm(router, "/token", url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {"backend"},
+			"client_secret": {"secret"},
+			"scope":         {"admin"},
+		})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var response dto.OAuthErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "invalid_scope", response.Error)
+	})
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantError  string
+	}{
+		{"invalid grant", domain.ErrInvalidGrant, http.StatusBadRequest, "invalid_grant"},
+		{"invalid client", domain.ErrInvalidClient, http.StatusUnauthorized, "invalid_client"},
+		{"unauthorized client", domain.ErrUnauthorizedClient, http.StatusBadRequest, "unauthorized_client"},
+		{"unexpected", domain.ErrUnexpected, http.StatusInternalServerError, "server_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			router, mockService := newOAuthRouter(ctrl)
+
+			mockService.EXPECT().ExchangeAuthorizationCode(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(nil, tt.err)
+
+			w := postForm(router, "/token", codeForm())
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			var response dto.OAuthErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.wantError, response.Error)
+		})
+	}
+
+	t.Run("client secret basic", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().ExchangeAuthorizationCode(gomock.Any(), domain.ClientCredentials{ID: "backend app", Secret: "s3cret/+"}, "code", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, domain.ErrInvalidClient)
+
+		form := codeForm()
+		form.Del("client_id")
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(url.QueryEscape("backend app"), url.QueryEscape("s3cret/+"))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, `Basic realm="token"`, w.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("conflicting client credentials", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newOAuthRouter(ctrl)
+
+		form := codeForm()
+		form.Set("client_secret", "secret")
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth("spa", "secret")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var response dto.OAuthErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "invalid_request", response.Error)
+	})
+
+	t.Run("missing client id", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newOAuthRouter(ctrl)
+
+		form := codeForm()
+		form.Del("client_id")
+		w := postForm(router, "/token", form)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("missing code verifier", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newOAuthRouter(ctrl)
+
+		form := codeForm()
+		form.Del("code_verifier")
+		w := postForm(router, "/token", form)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var response dto.OAuthErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "invalid_request", response.Error)
+	})
+
+	t.Run("unsupported grant type", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newOAuthRouter(ctrl)
+
+		w := postForm(router, "/token", url.Values{"grant_type": {"password"}, "client_id": {"spa"}})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var response dto.OAuthErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "unsupported_grant_type", response.Error)
+	})
+}
+
+func TestAuthHandler_POSTToken_TokenExchange(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	exchangeForm := func() url.Values {
+		return url.Values{
+			"grant_type":         {string(domain.GrantTypeTokenExchange)},
+			"client_id":          {"support"},
+			"client_secret":      {"secret"},
+			"subject_token":      {"6f1d1c6e-3b1a-4c8e-9a51-1f3c2a9e7b10"},
+			"subject_token_type": {oauth.TokenTypeUserID},
+			"actor_token":        {"agent-access"},
+			"actor_token_type":   {oauth.TokenTypeAccessToken},
+			"scope":              {"profile"},
+		}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().ExchangeToken(gomock.Any(), domain.ClientCredentials{ID: "support", Secret: "secret"}, domain.TokenExchangeRequest{
+			SubjectToken:     "6f1d1c6e-3b1a-4c8e-9a51-1f3c2a9e7b10",
+			SubjectTokenType: oauth.TokenTypeUserID,
+			ActorToken:       "agent-access",
+			ActorTokenType:   oauth.TokenTypeAccessToken,
+			Scope:            "profile",
+		}, gomock.Any(), gomock.Any()).Return(&domain.UserAuth{AccessToken: "access", ExpiresIn: 5 * time.Minute, Scopes: []string{"profile"}}, nil)
+
+		w := postForm(router, "/token", exchangeForm())
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response dto.TokenResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, dto.TokenResponse{
+			AccessToken:     "access",
+			IssuedTokenType: oauth.TokenTypeAccessToken,
+			TokenType:       "Bearer",
+			ExpiresIn:       300,
+			Scope:           "profile",
+		}, response)
+	})
+
+	t.Run("invalid subject token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newOAuthRouter(ctrl)
+
+		mockService.EXPECT().ExchangeToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, domain.ErrInvalidGrant)
+
+		w := postForm(router, "/token", exchangeForm())
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_grant")
+	})
+
+	invalidRequests := []struct {
+		name   string
+		modify func(form url.Values)
+	}{
+		{"missing subject token", func(form url.Values) { form.Del("subject_token") }},
+		{"missing subject token type", func(form url.Values) { form.Del("subject_token_type") }},
+		{"missing actor token type", func(form url.Values) { form.Del("actor_token_type") }},
+		{"unsupported requested token type", func(form url.Values) {
+			form.Set("requested_token_type", "urn:ietf:params:oauth:token-type:refresh_token")
+		}},
+	}
+
+	for _, tt := range invalidRequests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			router, _ := newOAuthRouter(ctrl)
+
+			form := exchangeForm()
+			tt.modify(form)
+			w := postForm(router, "/token", form)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), "invalid_request")
+		})
+	}
+}
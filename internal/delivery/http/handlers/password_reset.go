@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// POSTPasswordForgot отправляет на email одноразовую ссылку для сброса пароля.
+// Ответ одинаков для зарегистрированных и незарегистрированных email.
+func (h *AuthHandler) POSTPasswordForgot(c *gin.Context) {
+	var req dto.PasswordForgotRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Debug("error binding json", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	err := h.service.RequestPasswordReset(c.Request.Context(), req.Email, c.ClientIP(), c.Request.UserAgent())
+
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// POSTPasswordReset задает новый пароль по токену из письма.
+func (h *AuthHandler) POSTPasswordReset(c *gin.Context) {
+	var req dto.PasswordResetRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Debug("error binding json", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	err := h.service.ResetPassword(c.Request.Context(), req.Token, req.NewPassword, c.ClientIP(), c.Request.UserAgent())
+
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
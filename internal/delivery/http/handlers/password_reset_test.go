@@ -0,0 +1,122 @@
+package handlers_test
+
+import (
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/delivery/http/handlers"
+	"github.com/maksemen2/medods-task/internal/domain"
+	mock_service "github.com/maksemen2/medods-task/internal/service/mocks"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPasswordResetRouter(ctrl *gomock.Controller) (*gin.Engine, *mock_service.MockIAuthService) {
+	mockService := mock_service.NewMockIAuthService(ctrl)
+	h := handlers.NewAuthHandler(zap.NewNop(), mockService)
+
+	router := gin.New()
+	router.POST("/password/forgot", h.POSTPasswordForgot)
+	router.POST("/password/reset", h.POSTPasswordReset)
+	return router, mockService
+}
+
+func TestAuthHandler_POSTPasswordForgot(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("accepted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newPasswordResetRouter(ctrl)
+
+		mockService.EXPECT().RequestPasswordReset(gomock.Any(), "user@example.com", gomock.Any(), gomock.Any()).Return(nil)
+
+		w := postMFA(router, "/password/forgot", "", dto.PasswordForgotRequest{Email: "user@example.com"})
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+	})
+
+	t.Run("missing email", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newPasswordResetRouter(ctrl)
+
+		w := postMFA(router, "/password/forgot", "", map[string]string{})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newPasswordResetRouter(ctrl)
+
+		mockService.EXPECT().RequestPasswordReset(gomock.Any(), "user@example.com", gomock.Any(), gomock.Any()).Return(domain.ErrPasswordResetDisabled)
+
+		w := postMFA(router, "/password/forgot", "", dto.PasswordForgotRequest{Email: "user@example.com"})
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestAuthHandler_POSTPasswordReset(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	request := dto.PasswordResetRequest{Token: "token", NewPassword: "new password"}
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newPasswordResetRouter(ctrl)
+
+		mockService.EXPECT().ResetPassword(gomock.Any(), "token", "new password", gomock.Any(), gomock.Any()).Return(nil)
+
+		w := postMFA(router, "/password/reset", "", request)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newPasswordResetRouter(ctrl)
+
+		mockService.EXPECT().ResetPassword(gomock.Any(), "token", "new password", gomock.Any(), gomock.Any()).Return(domain.ErrInvalidResetToken)
+
+		w := postMFA(router, "/password/reset", "", request)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("weak password", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newPasswordResetRouter(ctrl)
+
+		mockService.EXPECT().ResetPassword(gomock.Any(), "token", "new password", gomock.Any(), gomock.Any()).Return(domain.ErrWeakPassword)
+
+		w := postMFA(router, "/password/reset", "", request)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newPasswordResetRouter(ctrl)
+
+		w := postMFA(router, "/password/reset", "", dto.PasswordResetRequest{NewPassword: "new password"})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
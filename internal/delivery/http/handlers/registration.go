@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/oauth"
+	"github.com/maksemen2/medods-task/internal/service"
+	"go.uber.org/zap"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Способы аутентификации клиента на эндпоинте /token (RFC 7591, раздел 2).
+// Конфиденциальный клиент может пользоваться обоими способами с секретом независимо от выбранного при регистрации.
+const (
+	authMethodNone              = "none"
+	authMethodClientSecretBasic = "client_secret_basic"
+	authMethodClientSecretPost  = "client_secret_post"
+)
+
+// responseTypeCode - единственный поддерживаемый тип ответа эндпоинта /authorize.
+const responseTypeCode = "code"
+
+// RegistrationHandler - структура для обработки запросов динамической регистрации клиентов OAuth (RFC 7591, RFC 7592).
+type RegistrationHandler struct {
+	logger  *zap.Logger
+	service service.IClientService
+}
+
+func NewRegistrationHandler(logger *zap.Logger, service service.IClientService) *RegistrationHandler {
+	return &RegistrationHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+func (h *RegistrationHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/register", h.POSTRegister)
+	router.GET("/register/:client_id", h.GETRegistration)
+	router.PUT("/register/:client_id", h.PUTRegistration)
+	router.DELETE("/register/:client_id", h.DELETERegistration)
+}
+
+// handleError - хелпер для обработки доменных ошибок, возвращаемых сервисом
+func (h *RegistrationHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrRegistrationDisabled):
+		c.AbortWithStatus(http.StatusNotFound)
+	case errors.Is(err, domain.ErrInvalidRegistrationToken):
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.AbortWithStatus(http.StatusUnauthorized)
+	case errors.Is(err, domain.ErrInvalidClientRedirectURIs):
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidRedirectURI, "")
+	case errors.Is(err, domain.ErrInvalidClientMetadata):
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidClientMetadata, "")
+	case errors.Is(err, domain.ErrUnexpected):
+		c.AbortWithStatus(http.StatusInternalServerError)
+	default:
+		h.logger.Error("unexpected error from clientService", zap.Error(err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}
+}
+
+// registrationToken извлекает токен первичного доступа или токен доступа к регистрации из заголовка "Authorization: Bearer <токен>".
+// Отсутствующий токен проверяется сервисом наравне с неверным.
+func registrationToken(c *gin.Context) string {
+	token, _ := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return token
+}
+
+// clientFromMetadata преобразует метаданные RFC 7591 в параметры клиента.
+// Отсутствующие поля заполняются значениями по умолчанию из RFC 7591, раздел 2.
+// Возвращает клиента и признак конфиденциальности или domain.ErrInvalidClientMetadata.
+func clientFromMetadata(req dto.ClientMetadata) (domain.OAuthClient, bool, error) {
+	var confidential bool
+	switch req.TokenEndpointAuthMethod {
+	case "", authMethodClientSecretBasic, authMethodClientSecretPost:
+		confidential = true
+	case authMethodNone:
+		confidential = false
+	default:
+		return domain.OAuthClient{}, false, domain.ErrInvalidClientMetadata
+	}
+
+	rawGrantTypes := req.GrantTypes
+	if len(rawGrantTypes) == 0 {
+		rawGrantTypes = []string{string(domain.GrantTypeAuthorizationCode)}
+	}
+	grantTypes := make([]domain.GrantType, len(rawGrantTypes))
+	for i, grantType := range rawGrantTypes {
+		grantTypes[i] = domain.GrantType(grantType)
+	}
+
+	// Тип ответа code допустим только вместе с authorization_code и наоборот
+	responseTypes := req.ResponseTypes
+	if len(responseTypes) == 0 && slices.Contains(grantTypes, domain.GrantTypeAuthorizationCode) {
+		responseTypes = []string{responseTypeCode}
+	}
+	for _, responseType := range responseTypes {
+		if responseType != responseTypeCode {
+			return domain.OAuthClient{}, false, domain.ErrInvalidClientMetadata
+		}
+	}
+	if (len(responseTypes) > 0) != slices.Contains(grantTypes, domain.GrantTypeAuthorizationCode) {
+		return domain.OAuthClient{}, false, domain.ErrInvalidClientMetadata
+	}
+
+	scopes, ok := oauth.ParseScope(req.Scope)
+	if !ok {
+		return domain.OAuthClient{}, false, domain.ErrInvalidClientMetadata
+	}
+
+	return domain.OAuthClient{
+		Name:         req.ClientName,
+		GrantTypes:   grantTypes,
+		RedirectURIs: req.RedirectURIs,
+		Scopes:       scopes,
+	}, confidential, nil
+}
+
+func registrationResponse(registration *domain.ClientRegistration) dto.ClientRegistrationResponse {
+	client := &registration.Client
+
+	grantTypes := make([]string, len(client.GrantTypes))
+	for i, grantType := range client.GrantTypes {
+		grantTypes[i] = string(grantType)
+	}
+
+	responseTypes := []string{}
+	if client.AllowsGrant(domain.GrantTypeAuthorizationCode) {
+		responseTypes = append(responseTypes, responseTypeCode)
+	}
+
+	authMethod := authMethodNone
+	if client.Confidential() {
+		authMethod = authMethodClientSecretBasic
+	}
+
+	redirectURIs := client.RedirectURIs
+	if redirectURIs == nil {
+		redirectURIs = []string{}
+	}
+
+	response := dto.ClientRegistrationResponse{
+		ClientID:                client.ID,
+		ClientSecret:            registration.Secret,
+		ClientIDIssuedAt:        client.CreatedAt.Unix(),
+		RegistrationAccessToken: registration.RegistrationToken,
+		RegistrationClientURI:   registration.RegistrationURI,
+		ClientName:              client.Name,
+		RedirectURIs:            redirectURIs,
+		TokenEndpointAuthMethod: authMethod,
+		GrantTypes:              grantTypes,
+		ResponseTypes:           responseTypes,
+		Scope:                   oauth.FormatScope(client.Scopes),
+	}
+
+	// Секрет выдается бессрочно (RFC 7591, раздел 3.2.1)
+	if registration.Secret != "" {
+		var neverExpires int64
+		response.ClientSecretExpiresAt = &neverExpires
+	}
+
+	return response
+}
+
+// POSTRegister регистрирует клиента по запросу партнера с токеном первичного доступа в заголовке Authorization.
+// Секрет клиента возвращается только в этом ответе.
+func (h *RegistrationHandler) POSTRegister(c *gin.Context) {
+	var req dto.ClientMetadata
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Debug("error binding json", zap.Error(err))
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidClientMetadata, "")
+		return
+	}
+
+	client, confidential, err := clientFromMetadata(req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	registration, err := h.service.RegisterClient(c.Request.Context(), registrationToken(c), client, confidential)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, registrationResponse(registration))
+}
+
+// GETRegistration возвращает текущие метаданные клиента по токену доступа к регистрации.
+func (h *RegistrationHandler) GETRegistration(c *gin.Context) {
+	registration, err := h.service.GetRegisteredClient(c.Request.Context(), c.Param("client_id"), registrationToken(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, registrationResponse(registration))
+}
+
+// PUTRegistration заменяет метаданные клиента. client_id в теле должен совпадать с адресом (RFC 7592, раздел 2.2).
+// В ответе возвращается новый токен доступа к регистрации, старый перестает действовать.
+func (h *RegistrationHandler) PUTRegistration(c *gin.Context) {
+	var req dto.ClientMetadata
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Debug("error binding json", zap.Error(err))
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidClientMetadata, "")
+		return
+	}
+
+	clientID := c.Param("client_id")
+	if req.ClientID != clientID {
+		writeOAuthError(c, http.StatusBadRequest, oauth.ErrorInvalidClientMetadata, "client_id does not match")
+		return
+	}
+
+	client, confidential, err := clientFromMetadata(req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	client.ID = clientID
+
+	registration, err := h.service.UpdateRegisteredClient(c.Request.Context(), registrationToken(c), client, confidential)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, registrationResponse(registration))
+}
+
+// DELETERegistration удаляет клиента по токену доступа к регистрации.
+func (h *RegistrationHandler) DELETERegistration(c *gin.Context) {
+	if err := h.service.DeleteRegisteredClient(c.Request.Context(), c.Param("client_id"), registrationToken(c)); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
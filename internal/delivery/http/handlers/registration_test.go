@@ -0,0 +1,269 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/delivery/http/handlers"
+	"github.com/maksemen2/medods-task/internal/domain"
+	mock_service "github.com/maksemen2/medods-task/internal/service/mocks"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistrationHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(ctrl *gomock.Controller) (*gin.Engine, *mock_service.MockIClientService) {
+		mockService := mock_service.NewMockIClientService(ctrl)
+		h := handlers.NewRegistrationHandler(zap.NewNop(), mockService)
+
+		router := gin.New()
+		h.RegisterRoutes(router.Group("/"))
+		return router, mockService
+	}
+
+	send := func(router *gin.Engine, method, path string, body any) *httptest.ResponseRecorder {
+		var raw []byte
+		if body != nil {
+			raw, _ = json.Marshal(body)
+		}
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Authorization", "Bearer token")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	metadata := dto.ClientMetadata{
+		ClientName:   "Partner app",
+		RedirectURIs: []string{"https://partner.example.com/callback"},
+		Scope:        "profile email",
+	}
+
+	registration := func(client domain.OAuthClient, secret, registrationToken string) *domain.ClientRegistration {
+		client.ID = "client"
+		client.CreatedAt = time.Unix(1700000000, 0)
+		if secret != "" {
+			client.SecretHash = "hash"
+		}
+		return &domain.ClientRegistration{
+			Client:            client,
+			Secret:            secret,
+			RegistrationToken: registrationToken,
+			RegistrationURI:   "https://auth.example.com/register/client",
+		}
+	}
+
+	t.Run("register with defaults", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().RegisterClient(gomock.Any(), "token", gomock.Any(), true).
+			DoAndReturn(func(_ any, _ string, client domain.OAuthClient, confidential bool) (*domain.ClientRegistration, error) {
+				assert.Equal(t, []domain.GrantType{domain.GrantTypeAuthorizationCode}, client.GrantTypes)
+				assert.Equal(t, []string{"profile", "email"}, client.Scopes)
+				return registration(client, "secret", "registration"), nil
+			})
+
+		w := send(router, "POST", "/register", metadata)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		var response dto.ClientRegistrationResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "client", response.ClientID)
+		assert.Equal(t, "secret", response.ClientSecret)
+		require.NotNil(t, response.ClientSecretExpiresAt)
+		assert.Zero(t, *response.ClientSecretExpiresAt)
+		assert.Equal(t, int64(1700000000), response.ClientIDIssuedAt)
+		assert.Equal(t, "registration", response.RegistrationAccessToken)
+		assert.Equal(t, "client_secret_basic", response.TokenEndpointAuthMethod)
+		assert.Equal(t, []string{"code"}, response.ResponseTypes)
+		assert.Equal(t, "profile email", response.Scope)
+	})
+
+	t.Run("register public client", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().RegisterClient(gomock.Any(), "token", gomock.Any(), false).
+			DoAndReturn(func(_ any, _ string, client domain.OAuthClient, _ bool) (*domain.ClientRegistration, error) {
+				return registration(client, "", "registration"), nil
+			})
+
+		public := metadata
+		public.TokenEndpointAuthMethod = "none"
+		public.GrantTypes = []string{"authorization_code", "refresh_token"}
+		w := send(router, "POST", "/register", public)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response dto.ClientRegistrationResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Empty(t, response.ClientSecret)
+		assert.Nil(t, response.ClientSecretExpiresAt)
+		assert.Equal(t, "none", response.TokenEndpointAuthMethod)
+	})
+
+	invalid := []struct {
+		name   string
+		modify func(req *dto.ClientMetadata)
+	}{
+		{"unknown auth method", func(req *dto.ClientMetadata) { req.TokenEndpointAuthMethod = "private_key_jwt" }},
+		{"unknown response type", func(req *dto.ClientMetadata) { req.ResponseTypes = []string{"token"} }},
+		{"response type without authorization code", func(req *dto.ClientMetadata) {
+			req.GrantTypes = []string{"client_credentials"}
+			req.ResponseTypes = []string{"code"}
+		}},
+		{"invalid scope", func(req *dto.ClientMetadata) { req.Scope = "profile \"email\"" }},
+	}
+
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			router, _ := newRouter(ctrl)
+
+			req := metadata
+			tt.modify(&req)
+			w := send(router, "POST", "/register", req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), "invalid_client_metadata")
+		})
+	}
+
+	errorCases := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantError  string
+	}{
+		{"registration disabled", domain.ErrRegistrationDisabled, http.StatusNotFound, ""},
+		{"invalid initial access token", domain.ErrInvalidRegistrationToken, http.StatusUnauthorized, ""},
+		{"invalid redirect uri", domain.ErrInvalidClientRedirectURIs, http.StatusBadRequest, "invalid_redirect_uri"},
+		{"invalid metadata", domain.ErrInvalidClientMetadata, http.StatusBadRequest, "invalid_client_metadata"},
+	}
+
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			router, mockService := newRouter(ctrl)
+
+			mockService.EXPECT().RegisterClient(gomock.Any(), "token", gomock.Any(), true).Return(nil, tt.err)
+
+			w := send(router, "POST", "/register", metadata)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.wantError)
+		})
+	}
+
+	t.Run("get", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().GetRegisteredClient(gomock.Any(), "client", "token").
+			Return(registration(domain.OAuthClient{GrantTypes: []domain.GrantType{domain.GrantTypeClientCredentials}, SecretHash: "hash"}, "", ""), nil)
+
+		w := send(router, "GET", "/register/client", nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response dto.ClientRegistrationResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Empty(t, response.RegistrationAccessToken)
+		assert.Equal(t, []string{}, response.ResponseTypes)
+		assert.Equal(t, "client_secret_basic", response.TokenEndpointAuthMethod)
+	})
+
+	t.Run("get with invalid token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().GetRegisteredClient(gomock.Any(), "client", "token").Return(nil, domain.ErrInvalidRegistrationToken)
+
+		w := send(router, "GET", "/register/client", nil)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+	})
+
+	t.Run("update", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().UpdateRegisteredClient(gomock.Any(), "token", gomock.Any(), true).
+			DoAndReturn(func(_ any, _ string, client domain.OAuthClient, _ bool) (*domain.ClientRegistration, error) {
+				assert.Equal(t, "client", client.ID)
+				return registration(client, "", "new-registration"), nil
+			})
+
+		req := metadata
+		req.ClientID = "client"
+		w := send(router, "PUT", "/register/client", req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response dto.ClientRegistrationResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "new-registration", response.RegistrationAccessToken)
+	})
+
+	t.Run("update with mismatched client id", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newRouter(ctrl)
+
+		req := metadata
+		req.ClientID = "other"
+		w := send(router, "PUT", "/register/client", req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	deleteCases := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"delete", nil, http.StatusNoContent},
+		{"delete with invalid token", domain.ErrInvalidRegistrationToken, http.StatusUnauthorized},
+	}
+
+	for _, tt := range deleteCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			router, mockService := newRouter(ctrl)
+
+			mockService.EXPECT().DeleteRegisteredClient(gomock.Any(), "client", "token").Return(tt.err)
+
+			w := send(router, "DELETE", "/register/client", nil)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/service"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// RoleHandler - структура для обработки запросов управления ролями пользователей и их разрешениями.
+type RoleHandler struct {
+	logger  *zap.Logger
+	service service.IRoleService
+}
+
+func NewRoleHandler(logger *zap.Logger, service service.IRoleService) *RoleHandler {
+	return &RoleHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+func (h *RoleHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/roles", h.GETRoles)
+	router.PUT("/roles/:name", h.PUTRole)
+	router.DELETE("/roles/:name", h.DELETERole)
+	router.GET("/users/:guid/roles", h.GETUserRoles)
+	router.PUT("/users/:guid/roles", h.PUTUserRoles)
+}
+
+// handleError - хелпер для обработки доменных ошибок, возвращаемых сервисом
+func (h *RoleHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrUnexpected):
+		c.AbortWithStatus(http.StatusInternalServerError)
+	case errors.Is(err, domain.ErrInvalidRole):
+		c.AbortWithStatus(http.StatusBadRequest)
+	case errors.Is(err, domain.ErrRoleNotFound), errors.Is(err, domain.ErrUserNotFound):
+		c.AbortWithStatus(http.StatusNotFound)
+	default:
+		h.logger.Error("unexpected error from roleService", zap.Error(err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}
+}
+
+func roleResponse(role domain.Role) dto.RoleResponse {
+	// Пустые списки отдаются как [], а не null
+	permissions := role.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return dto.RoleResponse{Name: role.Name, Permissions: permissions}
+}
+
+func roleListResponse(roles []domain.Role) dto.RoleListResponse {
+	response := dto.RoleListResponse{Roles: make([]dto.RoleResponse, len(roles))}
+	for i, role := range roles {
+		response.Roles[i] = roleResponse(role)
+	}
+	return response
+}
+
+func (h *RoleHandler) GETRoles(c *gin.Context) {
+	roles, err := h.service.ListRoles(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, roleListResponse(roles))
+}
+
+// PUTRole создает роль или заменяет ее разрешения.
+func (h *RoleHandler) PUTRole(c *gin.Context) {
+	var req dto.RoleRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Debug("error binding json", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	role, err := h.service.SaveRole(c.Request.Context(), domain.Role{Name: c.Param("name"), Permissions: req.Permissions})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, roleResponse(*role))
+}
+
+func (h *RoleHandler) DELETERole(c *gin.Context) {
+	if err := h.service.DeleteRole(c.Request.Context(), c.Param("name")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *RoleHandler) GETUserRoles(c *gin.Context) {
+	guid, err := uuid.Parse(c.Param("guid"))
+
+	if err != nil {
+		h.logger.Debug("error parsing guid", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	roles, err := h.service.GetUserRoles(c.Request.Context(), guid)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, roleListResponse(roles))
+}
+
+// PUTUserRoles заменяет роли пользователя. Изменения попадают в его токены при их обновлении.
+func (h *RoleHandler) PUTUserRoles(c *gin.Context) {
+	guid, err := uuid.Parse(c.Param("guid"))
+
+	if err != nil {
+		h.logger.Debug("error parsing guid", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	var req dto.UserRolesRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Debug("error binding json", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	roles, err := h.service.SetUserRoles(c.Request.Context(), guid, req.Roles)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, roleListResponse(roles))
+}
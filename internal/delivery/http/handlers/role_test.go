@@ -0,0 +1,147 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/delivery/http/handlers"
+	"github.com/maksemen2/medods-task/internal/domain"
+	mock_service "github.com/maksemen2/medods-task/internal/service/mocks"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(ctrl *gomock.Controller) (*gin.Engine, *mock_service.MockIRoleService) {
+		mockService := mock_service.NewMockIRoleService(ctrl)
+		h := handlers.NewRoleHandler(zap.NewNop(), mockService)
+
+		router := gin.New()
+		h.RegisterRoutes(router.Group("/admin"))
+		return router, mockService
+	}
+
+	t.Run("list", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().ListRoles(gomock.Any()).Return([]domain.Role{{Name: "guest"}}, nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/admin/roles", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"roles":[{"name":"guest","permissions":[]}]}`, w.Body.String())
+	})
+
+	t.Run("save", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		role := domain.Role{Name: "support", Permissions: []string{"users:read"}}
+		mockService.EXPECT().SaveRole(gomock.Any(), role).Return(&role, nil)
+
+		body, _ := json.Marshal(dto.RoleRequest{Permissions: role.Permissions})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/admin/roles/support", bytes.NewReader(body))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response dto.RoleResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, dto.RoleResponse{Name: "support", Permissions: []string{"users:read"}}, response)
+	})
+
+	t.Run("save invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().SaveRole(gomock.Any(), gomock.Any()).Return(nil, domain.ErrInvalidRole)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/admin/roles/support", bytes.NewReader([]byte(`{"permissions":["users read"]}`)))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("delete not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().DeleteRole(gomock.Any(), "support").Return(domain.ErrRoleNotFound)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("DELETE", "/admin/roles/support", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("set user roles", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		guid := uuid.New()
+		mockService.EXPECT().SetUserRoles(gomock.Any(), guid, []string{"support"}).
+			Return([]domain.Role{{Name: "support", Permissions: []string{"users:read"}}}, nil)
+
+		body, _ := json.Marshal(dto.UserRolesRequest{Roles: []string{"support"}})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/admin/users/"+guid.String()+"/roles", bytes.NewReader(body))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"roles":[{"name":"support","permissions":["users:read"]}]}`, w.Body.String())
+	})
+
+	t.Run("set unknown role", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		guid := uuid.New()
+		mockService.EXPECT().SetUserRoles(gomock.Any(), guid, []string{"unknown"}).Return(nil, domain.ErrRoleNotFound)
+
+		body, _ := json.Marshal(dto.UserRolesRequest{Roles: []string{"unknown"}})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/admin/users/"+guid.String()+"/roles", bytes.NewReader(body))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("get user roles invalid guid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newRouter(ctrl)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/admin/users/invalid/roles", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/service"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// UserHandler - структура для обработки запросов управления пользователями.
+type UserHandler struct {
+	logger  *zap.Logger
+	service service.IUserService
+}
+
+func NewUserHandler(logger *zap.Logger, service service.IUserService) *UserHandler {
+	return &UserHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+func (h *UserHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/users", h.POSTUsers)
+	router.POST("/email/verification", h.POSTEmailVerification)
+	router.GET("/email/verify", h.GETEmailVerify)
+}
+
+// RegisterAdminRoutes регистрирует административные эндпоинты управления пользователями.
+// Группа router должна быть защищена административной аутентификацией.
+func (h *UserHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	router.PUT("/users/:guid/ip-change-policy", h.PUTIPChangePolicy)
+}
+
+// handleError - хелпер для обработки доменных ошибок, возвращаемых сервисом
+func (h *UserHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrUnexpected):
+		c.AbortWithStatus(http.StatusInternalServerError)
+	case errors.Is(err, domain.ErrInvalidEmail), errors.Is(err, domain.ErrInvalidDisplayName), errors.Is(err, domain.ErrWeakPassword),
+		errors.Is(err, domain.ErrInvalidVerificationToken), errors.Is(err, domain.ErrInvalidIPChangePolicy):
+		c.AbortWithStatus(http.StatusBadRequest)
+	case errors.Is(err, domain.ErrEmailTaken):
+		c.AbortWithStatus(http.StatusConflict)
+	case errors.Is(err, domain.ErrEmailVerificationDisabled), errors.Is(err, domain.ErrUserNotFound):
+		c.AbortWithStatus(http.StatusNotFound)
+	default:
+		h.logger.Error("unexpected error from userService", zap.Error(err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}
+}
+
+func (h *UserHandler) POSTUsers(c *gin.Context) {
+	var req dto.RegisterUserRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Debug("error binding json", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.service.Register(c.Request.Context(), req.Email, req.DisplayName, req.Password)
+
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.UserResponse{
+		GUID:        user.GUID.String(),
+		Email:       user.Email,
+		DisplayName: user.DisplayName,
+		CreatedAt:   user.CreatedAt,
+	})
+}
+
+// POSTEmailVerification повторно отправляет письмо подтверждения email.
+// Ответ одинаков для зарегистрированных, незарегистрированных и уже подтвержденных email.
+func (h *UserHandler) POSTEmailVerification(c *gin.Context) {
+	var req dto.EmailVerificationRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Debug("error binding json", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RequestEmailVerification(c.Request.Context(), req.Email); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// GETEmailVerify подтверждает email по токену из письма.
+func (h *UserHandler) GETEmailVerify(c *gin.Context) {
+	var params dto.EmailVerifyQueryParams
+
+	if err := c.ShouldBindQuery(&params); err != nil {
+		h.logger.Debug("error binding query", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.VerifyEmail(c.Request.Context(), params.Token); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// PUTIPChangePolicy переопределяет политику смены IP пользователя.
+// Новая политика применяется при следующем обновлении его токенов.
+func (h *UserHandler) PUTIPChangePolicy(c *gin.Context) {
+	guid, err := uuid.Parse(c.Param("guid"))
+
+	if err != nil {
+		h.logger.Debug("error parsing guid", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	var req dto.IPChangePolicyRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Debug("error binding json", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.SetIPChangePolicy(c.Request.Context(), guid, *req.Policy); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
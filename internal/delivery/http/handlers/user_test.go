@@ -0,0 +1,265 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/delivery/http/handlers"
+	"github.com/maksemen2/medods-task/internal/domain"
+	mock_service "github.com/maksemen2/medods-task/internal/service/mocks"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserHandler_POSTUsers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(ctrl *gomock.Controller) (*gin.Engine, *mock_service.MockIUserService) {
+		mockService := mock_service.NewMockIUserService(ctrl)
+		h := handlers.NewUserHandler(zap.NewNop(), mockService)
+
+		router := gin.New()
+		router.POST("/users", h.POSTUsers)
+		return router, mockService
+	}
+
+	post := func(router *gin.Engine, body any) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/users", bytes.NewReader(raw))
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		user := &domain.User{GUID: uuid.New(), Email: "user@example.com", DisplayName: "Ivan", CreatedAt: time.Now().UTC()}
+		mockService.EXPECT().Register(gomock.Any(), "User@Example.com", "Ivan", "").Return(user, nil)
+
+		w := post(router, dto.RegisterUserRequest{Email: "User@Example.com", DisplayName: "Ivan"})
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response dto.UserResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, user.GUID.String(), response.GUID)
+		assert.Equal(t, user.Email, response.Email)
+		assert.Equal(t, user.DisplayName, response.DisplayName)
+	})
+
+	t.Run("missing email", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newRouter(ctrl)
+
+		w := post(router, map[string]string{"display_name": "Ivan"})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("service errors", func(t *testing.T) {
+		cases := map[error]int{
+			domain.ErrInvalidEmail:       http.StatusBadRequest,
+			domain.ErrInvalidDisplayName: http.StatusBadRequest,
+			domain.ErrEmailTaken:         http.StatusConflict,
+			domain.ErrUnexpected:         http.StatusInternalServerError,
+		}
+
+		for serviceErr, status := range cases {
+			ctrl := gomock.NewController(t)
+
+			router, mockService := newRouter(ctrl)
+			mockService.EXPECT().Register(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, serviceErr)
+
+			w := post(router, dto.RegisterUserRequest{Email: "user@example.com"})
+
+			assert.Equal(t, status, w.Code, serviceErr.Error())
+			ctrl.Finish()
+		}
+	})
+}
+
+func TestUserHandler_EmailVerification(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(ctrl *gomock.Controller) (*gin.Engine, *mock_service.MockIUserService) {
+		mockService := mock_service.NewMockIUserService(ctrl)
+		h := handlers.NewUserHandler(zap.NewNop(), mockService)
+
+		router := gin.New()
+		h.RegisterRoutes(router.Group(""))
+		return router, mockService
+	}
+
+	serve := func(router *gin.Engine, method, path string, body any) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewReader(raw))
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("resend accepted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+		mockService.EXPECT().RequestEmailVerification(gomock.Any(), "user@example.com").Return(nil)
+
+		w := serve(router, "POST", "/email/verification", dto.EmailVerificationRequest{Email: "user@example.com"})
+		assert.Equal(t, http.StatusAccepted, w.Code)
+	})
+
+	t.Run("resend without email", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newRouter(ctrl)
+
+		w := serve(router, "POST", "/email/verification", map[string]string{})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("resend disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+		mockService.EXPECT().RequestEmailVerification(gomock.Any(), "user@example.com").Return(domain.ErrEmailVerificationDisabled)
+
+		w := serve(router, "POST", "/email/verification", dto.EmailVerificationRequest{Email: "user@example.com"})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("verify success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+		mockService.EXPECT().VerifyEmail(gomock.Any(), "token").Return(nil)
+
+		w := serve(router, "GET", "/email/verify?token=token", nil)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("verify invalid token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+		mockService.EXPECT().VerifyEmail(gomock.Any(), "token").Return(domain.ErrInvalidVerificationToken)
+
+		w := serve(router, "GET", "/email/verify?token=token", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("verify without token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newRouter(ctrl)
+
+		w := serve(router, "GET", "/email/verify", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestUserHandler_PUTIPChangePolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(ctrl *gomock.Controller) (*gin.Engine, *mock_service.MockIUserService) {
+		mockService := mock_service.NewMockIUserService(ctrl)
+		h := handlers.NewUserHandler(zap.NewNop(), mockService)
+
+		router := gin.New()
+		h.RegisterAdminRoutes(router.Group("/admin"))
+		return router, mockService
+	}
+
+	serve := func(router *gin.Engine, path string, body any) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", path, bytes.NewReader(raw))
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	guid := uuid.New()
+	path := "/admin/users/" + guid.String() + "/ip-change-policy"
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+		mockService.EXPECT().SetIPChangePolicy(gomock.Any(), guid, "deny").Return(nil)
+
+		w := serve(router, path, map[string]string{"policy": "deny"})
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+		mockService.EXPECT().SetIPChangePolicy(gomock.Any(), guid, "").Return(nil)
+
+		w := serve(router, path, map[string]string{"policy": ""})
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("missing policy", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newRouter(ctrl)
+
+		w := serve(router, path, map[string]string{})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("invalid guid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newRouter(ctrl)
+
+		w := serve(router, "/admin/users/not-a-guid/ip-change-policy", map[string]string{"policy": "deny"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+		mockService.EXPECT().SetIPChangePolicy(gomock.Any(), guid, "allow").Return(domain.ErrInvalidIPChangePolicy)
+
+		w := serve(router, path, map[string]string{"policy": "allow"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+		mockService.EXPECT().SetIPChangePolicy(gomock.Any(), guid, "deny").Return(domain.ErrUserNotFound)
+
+		w := serve(router, path, map[string]string{"policy": "deny"})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
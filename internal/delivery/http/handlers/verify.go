@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/oauth"
+	"net/http"
+	"strings"
+)
+
+// Заголовки ответа эндпоинта /verify, которые прокси передает защищаемому приложению
+const (
+	headerUserID      = "X-User-Id"     // GUID пользователя или идентификатор клиента OAuth
+	headerTokenID     = "X-Token-Id"    // Идентификатор (jti) Access токена
+	headerScopes      = "X-Scopes"      // Области доступа клиента OAuth через пробел
+	headerPermissions = "X-Permissions" // Разрешения ролей пользователя через пробел
+	headerActor       = "X-Actor"       // Сторона, действующая от имени пользователя (claim act), только для токенов, полученных обменом
+)
+
+// GETVerify проверяет Access токен из заголовка "Authorization: Bearer <токен>" для прокси, которые защищают
+// другие приложения (nginx auth_request, Traefik ForwardAuth). Отвечает 200 с заголовками X-User-Id, X-Token-Id,
+// X-Scopes, X-Permissions и X-Actor (для токенов, полученных обменом), если токен действителен и не отозван,
+// иначе 401 с заголовком WWW-Authenticate (RFC 6750, раздел 3).
+// Токен, требующий повторной аутентификации (политика step_up), отклоняется с ошибкой insufficient_user_authentication (RFC 9470).
+func (h *AuthHandler) GETVerify(c *gin.Context) {
+	accessToken, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || accessToken == "" {
+		c.Header("WWW-Authenticate", `Bearer`)
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	verified, err := h.service.VerifyToken(c.Request.Context(), accessToken)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidAccessToken) {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		}
+		h.handleSessionError(c, err)
+		return
+	}
+
+	c.Header(headerUserID, verified.Subject)
+	c.Header(headerTokenID, verified.JTI.String())
+	c.Header(headerScopes, oauth.FormatScope(verified.Scopes))
+	c.Header(headerPermissions, oauth.FormatScope(verified.Permissions))
+	if verified.Actor != "" {
+		c.Header(headerActor, verified.Actor)
+	}
+	c.Status(http.StatusOK)
+}
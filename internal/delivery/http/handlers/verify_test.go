@@ -0,0 +1,139 @@
+package handlers_test
+
+import (
+	"github.com/maksemen2/medods-task/internal/delivery/http/handlers"
+	"github.com/maksemen2/medods-task/internal/domain"
+	mock_service "github.com/maksemen2/medods-task/internal/service/mocks"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthHandler_GETVerify(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(ctrl *gomock.Controller) (*gin.Engine, *mock_service.MockIAuthService) {
+		mockService := mock_service.NewMockIAuthService(ctrl)
+		h := handlers.NewAuthHandler(zap.NewNop(), mockService)
+
+		router := gin.New()
+		router.GET("/verify", h.GETVerify)
+		return router, mockService
+	}
+
+	verify := func(router *gin.Engine, header string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/verify", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		guid, jti := uuid.New(), uuid.New()
+		mockService.EXPECT().VerifyToken(gomock.Any(), "access").Return(&domain.VerifiedToken{
+			Subject:     guid.String(),
+			JTI:         jti,
+			Scopes:      []string{"orders:read", "users:read"},
+			Permissions: []string{"reports:read"},
+		}, nil)
+
+		w := verify(router, "Bearer access")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, guid.String(), w.Header().Get("X-User-Id"))
+		assert.Equal(t, jti.String(), w.Header().Get("X-Token-Id"))
+		assert.Equal(t, "orders:read users:read", w.Header().Get("X-Scopes"))
+		assert.Equal(t, "reports:read", w.Header().Get("X-Permissions"))
+		assert.Empty(t, w.Header().Values("X-Actor"))
+	})
+
+	t.Run("exchanged token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		guid, agent := uuid.New(), uuid.New()
+		mockService.EXPECT().VerifyToken(gomock.Any(), "access").Return(&domain.VerifiedToken{
+			Subject:  guid.String(),
+			JTI:      uuid.New(),
+			ClientID: "support",
+			Actor:    agent.String(),
+		}, nil)
+
+		w := verify(router, "Bearer access")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, guid.String(), w.Header().Get("X-User-Id"))
+		assert.Equal(t, agent.String(), w.Header().Get("X-Actor"))
+	})
+
+	t.Run("no token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newRouter(ctrl)
+
+		w := verify(router, "")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("invalid or revoked token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().VerifyToken(gomock.Any(), "revoked").Return(nil, domain.ErrInvalidAccessToken)
+
+		w := verify(router, "Bearer revoked")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, `Bearer error="invalid_token"`, w.Header().Get("WWW-Authenticate"))
+		assert.Empty(t, w.Header().Get("X-User-Id"))
+	})
+
+	t.Run("token requires re-authentication", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().VerifyToken(gomock.Any(), "step-up").Return(nil, domain.ErrReauthRequired)
+
+		w := verify(router, "Bearer step-up")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, `Bearer error="insufficient_user_authentication"`, w.Header().Get("WWW-Authenticate"))
+		assert.Empty(t, w.Header().Get("X-User-Id"))
+	})
+
+	t.Run("unexpected error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newRouter(ctrl)
+
+		mockService.EXPECT().VerifyToken(gomock.Any(), "access").Return(nil, domain.ErrUnexpected)
+
+		w := verify(router, "Bearer access")
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
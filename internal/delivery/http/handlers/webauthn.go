@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"github.com/gin-gonic/gin"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/webauthn"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+)
+
+// decodeBase64URL декодирует бинарное поле ответа аутентификатора.
+// Браузеры кодируют поля без выравнивания, но выравнивание тоже допускается.
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+// decodeBase64URLFields декодирует несколько полей, останавливаясь на первой ошибке.
+func decodeBase64URLFields(values []string, targets ...*[]byte) error {
+	for i, value := range values {
+		decoded, err := decodeBase64URL(value)
+		if err != nil {
+			return err
+		}
+		*targets[i] = decoded
+	}
+	return nil
+}
+
+// POSTPasskeyRegisterOptions начинает регистрацию passkey и возвращает параметры для navigator.credentials.create().
+// Access токен передается в заголовке "Authorization: Bearer <токен>".
+func (h *AuthHandler) POSTPasskeyRegisterOptions(c *gin.Context) {
+	accessToken, ok := bearerToken(c)
+	if !ok {
+		return
+	}
+
+	options, err := h.service.BeginPasskeyRegistration(c.Request.Context(), accessToken)
+
+	if err != nil {
+		h.handleSessionError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, options)
+}
+
+// POSTPasskeyRegister завершает регистрацию passkey ответом аутентификатора.
+// Access токен передается в заголовке "Authorization: Bearer <токен>".
+func (h *AuthHandler) POSTPasskeyRegister(c *gin.Context) {
+	accessToken, ok := bearerToken(c)
+	if !ok {
+		return
+	}
+
+	var req dto.PasskeyRegistrationRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Debug("error binding json", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	var response webauthn.RegistrationResponse
+
+	err := decodeBase64URLFields(
+		[]string{req.Response.ClientDataJSON, req.Response.AttestationObject},
+		&response.ClientDataJSON, &response.AttestationObject,
+	)
+	if err != nil {
+		h.logger.Debug("error decoding passkey registration", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	passkey, err := h.service.FinishPasskeyRegistration(c.Request.Context(), accessToken, response, c.ClientIP(), c.Request.UserAgent())
+
+	if err != nil {
+		h.handleSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.PasskeyResponse{
+		CredentialID: base64.RawURLEncoding.EncodeToString(passkey.CredentialID),
+		CreatedAt:    passkey.CreatedAt,
+	})
+}
+
+// POSTPasskeyLoginOptions начинает вход по passkey и возвращает параметры для navigator.credentials.get().
+func (h *AuthHandler) POSTPasskeyLoginOptions(c *gin.Context) {
+	options, err := h.service.BeginPasskeyLogin(c.Request.Context())
+
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, options)
+}
+
+// POSTPasskeyLogin обменивает ответ аутентификатора на пару токенов.
+func (h *AuthHandler) POSTPasskeyLogin(c *gin.Context) {
+	var req dto.PasskeyLoginRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Debug("error binding json", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	var response webauthn.AssertionResponse
+
+	err := decodeBase64URLFields(
+		[]string{req.ID, req.Response.ClientDataJSON, req.Response.AuthenticatorData, req.Response.Signature, req.Response.UserHandle},
+		&response.CredentialID, &response.ClientDataJSON, &response.AuthenticatorData, &response.Signature, &response.UserHandle,
+	)
+	if err != nil {
+		h.logger.Debug("error decoding passkey assertion", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	domainAuth, err := h.service.FinishPasskeyLogin(c.Request.Context(), response, c.ClientIP(), c.Request.UserAgent())
+
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	writeAuth(c, domainAuth)
+}
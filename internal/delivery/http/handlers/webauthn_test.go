@@ -0,0 +1,244 @@
+package handlers_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/maksemen2/medods-task/internal/delivery/http/dto"
+	"github.com/maksemen2/medods-task/internal/delivery/http/handlers"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/webauthn"
+	mock_service "github.com/maksemen2/medods-task/internal/service/mocks"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newWebAuthnRouter(ctrl *gomock.Controller) (*gin.Engine, *mock_service.MockIAuthService) {
+	mockService := mock_service.NewMockIAuthService(ctrl)
+	h := handlers.NewAuthHandler(zap.NewNop(), mockService)
+
+	router := gin.New()
+	router.POST("/webauthn/register/options", h.POSTPasskeyRegisterOptions)
+	router.POST("/webauthn/register", h.POSTPasskeyRegister)
+	router.POST("/webauthn/login/options", h.POSTPasskeyLoginOptions)
+	router.POST("/webauthn/login", h.POSTPasskeyLogin)
+	return router, mockService
+}
+
+func b64(value string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(value))
+}
+
+func TestAuthHandler_POSTPasskeyRegister(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	request := dto.PasskeyRegistrationRequest{
+		ID:    b64("credential"),
+		RawID: b64("credential"),
+		Type:  "public-key",
+		Response: dto.PasskeyAttestationResponse{
+			ClientDataJSON:    b64("client-data"),
+			AttestationObject: b64("attestation"),
+		},
+	}
+
+	t.Run("options", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newWebAuthnRouter(ctrl)
+
+		mockService.EXPECT().BeginPasskeyRegistration(gomock.Any(), "access").
+			Return(&webauthn.CreationOptions{Challenge: "challenge"}, nil)
+
+		w := postMFA(router, "/webauthn/register/options", "Bearer access", nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response webauthn.CreationOptions
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "challenge", response.Challenge)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newWebAuthnRouter(ctrl)
+
+		createdAt := time.Now().UTC().Truncate(time.Second)
+		mockService.EXPECT().FinishPasskeyRegistration(gomock.Any(), "access", webauthn.RegistrationResponse{
+			ClientDataJSON:    []byte("client-data"),
+			AttestationObject: []byte("attestation"),
+		}, gomock.Any(), gomock.Any()).Return(&domain.Passkey{CredentialID: []byte("credential"), CreatedAt: createdAt}, nil)
+
+		w := postMFA(router, "/webauthn/register", "Bearer access", request)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response dto.PasskeyResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, b64("credential"), response.CredentialID)
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newWebAuthnRouter(ctrl)
+
+		w := postMFA(router, "/webauthn/register", "", request)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("not base64url", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newWebAuthnRouter(ctrl)
+
+		invalid := request
+		invalid.Response.ClientDataJSON = "not base64!"
+		w := postMFA(router, "/webauthn/register", "Bearer access", invalid)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("already registered", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newWebAuthnRouter(ctrl)
+
+		mockService.EXPECT().FinishPasskeyRegistration(gomock.Any(), "access", gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, domain.ErrPasskeyExists)
+
+		w := postMFA(router, "/webauthn/register", "Bearer access", request)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("revoked session", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newWebAuthnRouter(ctrl)
+
+		mockService.EXPECT().FinishPasskeyRegistration(gomock.Any(), "access", gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, domain.ErrInvalidAccessToken)
+
+		w := postMFA(router, "/webauthn/register", "Bearer access", request)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestAuthHandler_POSTPasskeyLogin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	request := dto.PasskeyLoginRequest{
+		ID:    b64("credential"),
+		RawID: b64("credential"),
+		Type:  "public-key",
+		Response: dto.PasskeyAssertionResponse{
+			ClientDataJSON:    b64("client-data"),
+			AuthenticatorData: b64("auth-data"),
+			Signature:         b64("signature"),
+			UserHandle:        b64("user"),
+		},
+	}
+
+	t.Run("options", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newWebAuthnRouter(ctrl)
+
+		mockService.EXPECT().BeginPasskeyLogin(gomock.Any()).Return(&webauthn.RequestOptions{Challenge: "challenge", RPID: "localhost"}, nil)
+
+		w := postMFA(router, "/webauthn/login/options", "", nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newWebAuthnRouter(ctrl)
+
+		mockService.EXPECT().FinishPasskeyLogin(gomock.Any(), webauthn.AssertionResponse{
+			CredentialID:      []byte("credential"),
+			ClientDataJSON:    []byte("client-data"),
+			AuthenticatorData: []byte("auth-data"),
+			Signature:         []byte("signature"),
+			UserHandle:        []byte("user"),
+		}, gomock.Any(), gomock.Any()).Return(&domain.UserAuth{AccessToken: "access", RefreshToken: "refresh"}, nil)
+
+		w := postMFA(router, "/webauthn/login", "", request)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response dto.AuthResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, dto.AuthResponse{AccessToken: "access", RefreshToken: "refresh"}, response)
+	})
+
+	t.Run("invalid passkey", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newWebAuthnRouter(ctrl)
+
+		mockService.EXPECT().FinishPasskeyLogin(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, domain.ErrInvalidCredentials)
+
+		w := postMFA(router, "/webauthn/login", "", request)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("expired ceremony", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newWebAuthnRouter(ctrl)
+
+		mockService.EXPECT().FinishPasskeyLogin(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, domain.ErrWebAuthnCeremonyNotFound)
+
+		w := postMFA(router, "/webauthn/login", "", request)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, mockService := newWebAuthnRouter(ctrl)
+
+		mockService.EXPECT().BeginPasskeyLogin(gomock.Any()).Return(nil, domain.ErrWebAuthnDisabled)
+
+		w := postMFA(router, "/webauthn/login/options", "", nil)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		router, _ := newWebAuthnRouter(ctrl)
+
+		invalid := request
+		invalid.Type = "password"
+		w := postMFA(router, "/webauthn/login", "", invalid)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewAdminAuth - мидлварь для Gin, которая пропускает только запросы
+// с API-ключом администратора в заголовке "Authorization: Bearer <ключ>".
+// Ключ сравнивается за постоянное время. Если ключ не задан, все запросы отклоняются.
+func NewAdminAuth(apiKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || apiKey == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(apiKey)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,48 @@
+package middleware_test
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/maksemen2/medods-task/internal/delivery/http/middleware"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewAdminAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(apiKey string) *gin.Engine {
+		router := gin.New()
+		router.GET("/admin", middleware.NewAdminAuth(apiKey), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		return router
+	}
+
+	tests := []struct {
+		name   string
+		apiKey string
+		header string
+		status int
+	}{
+		{name: "valid key", apiKey: "admin_key", header: "Bearer admin_key", status: http.StatusOK},
+		{name: "wrong key", apiKey: "admin_key", header: "Bearer wrong_key", status: http.StatusUnauthorized},
+		{name: "no header", apiKey: "admin_key", header: "", status: http.StatusUnauthorized},
+		{name: "not bearer", apiKey: "admin_key", header: "Basic admin_key", status: http.StatusUnauthorized},
+		{name: "key not configured", apiKey: "", header: "Bearer ", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/admin", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			newRouter(tt.apiKey).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
@@ -3,16 +3,19 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/maksemen2/medods-task/internal/delivery/http/handlers"
+	"github.com/maksemen2/medods-task/internal/delivery/http/middleware"
 	"github.com/maksemen2/medods-task/internal/pkg/log"
+	"github.com/maksemen2/medods-task/internal/pkg/requestid"
 	"github.com/maksemen2/medods-task/internal/service"
 	"go.uber.org/zap"
 )
 
 // New настраивает роутинг приложения и устанавливает мидлвари.
+// Административные эндпоинты защищены API-ключом adminAPIKey.
 // Возвращает инстанс gin.Engine
-func New(logger *zap.Logger, authService service.IAuthService) *gin.Engine {
+func New(logger *zap.Logger, authService service.IAuthService, userService service.IUserService, auditService service.IAuditService, provisioningService service.IProvisioningService, clientService service.IClientService, roleService service.IRoleService, adminAPIKey string) *gin.Engine {
 	router := gin.New()
-	router.Use(gin.Recovery(), log.NewMiddleware(logger))
+	router.Use(gin.Recovery(), requestid.NewMiddleware(), log.NewMiddleware(logger))
 
 	authGroup := router.Group("")
 
@@ -20,5 +23,33 @@ func New(logger *zap.Logger, authService service.IAuthService) *gin.Engine {
 
 	authHandler.RegisterRoutes(authGroup)
 
+	userHandler := handlers.NewUserHandler(logger, userService)
+
+	userHandler.RegisterRoutes(authGroup)
+
+	registrationHandler := handlers.NewRegistrationHandler(logger, clientService)
+
+	registrationHandler.RegisterRoutes(authGroup)
+
+	adminGroup := router.Group("/admin", middleware.NewAdminAuth(adminAPIKey))
+
+	auditHandler := handlers.NewAuditHandler(logger, auditService)
+
+	auditHandler.RegisterRoutes(adminGroup)
+
+	allowlistHandler := handlers.NewAllowlistHandler(logger, provisioningService)
+
+	allowlistHandler.RegisterRoutes(adminGroup)
+
+	clientHandler := handlers.NewClientHandler(logger, clientService)
+
+	clientHandler.RegisterRoutes(adminGroup)
+
+	roleHandler := handlers.NewRoleHandler(logger, roleService)
+
+	roleHandler.RegisterRoutes(adminGroup)
+
+	userHandler.RegisterAdminRoutes(adminGroup)
+
 	return router
 }
@@ -1,13 +1,69 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
-	ErrUserExists          = errors.New("user already exists")
-	ErrUserNotFound        = errors.New("user not found")
-	ErrTokenNotFound       = errors.New("token not found")
-	ErrTokenExists         = errors.New("token already exists")
-	ErrUnexpected          = errors.New("unexpected error")
-	ErrInvalidRefreshToken = errors.New("invalid refresh token provided")
-	ErrInvalidAccessToken  = errors.New("invalid access token provided")
+	ErrUserExists                = errors.New("user already exists")
+	ErrUserNotFound              = errors.New("user not found")
+	ErrEmailTaken                = errors.New("email already taken")
+	ErrInvalidEmail              = errors.New("invalid email")
+	ErrInvalidDisplayName        = errors.New("invalid display name")
+	ErrAllowlistEntryNotFound    = errors.New("allowlist entry not found")
+	ErrInvalidCredentials        = errors.New("invalid email or password")
+	ErrWeakPassword              = errors.New("password does not meet requirements")
+	ErrCredentialsNotFound       = errors.New("credentials not found")
+	ErrMFADisabled               = errors.New("mfa is not configured")
+	ErrTOTPAlreadyEnabled        = errors.New("totp is already enabled")
+	ErrTOTPNotEnrolled           = errors.New("totp is not enrolled")
+	ErrMFAChallengeNotFound      = errors.New("mfa challenge not found")
+	ErrInvalidMFAToken           = errors.New("invalid or expired mfa token")
+	ErrInvalidMFACode            = errors.New("invalid mfa code")
+	ErrWebAuthnDisabled          = errors.New("webauthn is not configured")
+	ErrPasskeyExists             = errors.New("passkey already registered")
+	ErrPasskeyNotFound           = errors.New("passkey not found")
+	ErrWebAuthnCeremonyNotFound  = errors.New("webauthn ceremony not found")
+	ErrInvalidWebAuthnResponse   = errors.New("invalid webauthn response")
+	ErrMagicLinkDisabled         = errors.New("magic link login is not configured")
+	ErrInvalidMagicLink          = errors.New("invalid, expired or used magic link")
+	ErrEmailVerificationDisabled = errors.New("email verification is not configured")
+	ErrInvalidVerificationToken  = errors.New("invalid, expired or used email verification token")
+	ErrEmailNotVerified          = errors.New("email is not verified")
+	ErrPasswordResetDisabled     = errors.New("password reset is not configured")
+	ErrInvalidResetToken         = errors.New("invalid, expired or used password reset token")
+	ErrOAuthDisabled             = errors.New("oauth is not configured")
+	ErrInvalidClient             = errors.New("unknown oauth client or bad client credentials")
+	ErrClientNotFound            = errors.New("oauth client not found")
+	ErrInvalidClientMetadata     = errors.New("invalid oauth client metadata")
+	ErrInvalidClientRedirectURIs = fmt.Errorf("%w: invalid redirect uris", ErrInvalidClientMetadata)
+	ErrRegistrationDisabled      = errors.New("dynamic client registration is not configured")
+	ErrInvalidRegistrationToken  = errors.New("invalid initial or registration access token")
+	ErrUnauthorizedClient        = errors.New("grant type is not allowed for the client")
+	ErrInvalidScope              = errors.New("scope is not allowed for the client")
+	ErrInvalidRedirectURI        = errors.New("redirect uri is not registered for the client")
+	ErrInvalidOAuthRequest       = errors.New("invalid oauth request")
+	ErrUnsupportedResponseType   = errors.New("unsupported response type")
+	ErrUnsupportedGrantType      = errors.New("unsupported grant type")
+	ErrInvalidGrant              = errors.New("invalid, expired or used authorization grant")
+	ErrDeviceFlowDisabled        = errors.New("device authorization is not configured")
+	ErrInvalidUserCode           = errors.New("invalid, expired or used user code")
+	ErrAuthorizationPending      = errors.New("device authorization is pending")
+	ErrSlowDown                  = errors.New("device is polling too frequently")
+	ErrExpiredDeviceCode         = errors.New("device code has expired")
+	ErrAccessDenied              = errors.New("user denied the authorization request")
+	ErrRoleNotFound              = errors.New("role not found")
+	ErrInvalidRole               = errors.New("invalid role name or permissions")
+	ErrTokenNotFound             = errors.New("token not found")
+	ErrTokenExists               = errors.New("token already exists")
+	ErrUnexpected                = errors.New("unexpected error")
+	ErrInvalidRefreshToken       = errors.New("invalid refresh token provided")
+	ErrInvalidAccessToken        = errors.New("invalid access token provided")
+	ErrReauthRequired            = errors.New("access token requires re-authentication")
+	ErrIPChangeDenied            = errors.New("token refresh from another ip address is denied")
+	ErrInvalidIPChangePolicy     = errors.New("invalid ip change policy")
+	ErrRiskDenied                = errors.New("operation denied by risk assessment")
+	ErrInvalidCursor             = errors.New("invalid pagination cursor")
+	ErrInvalidFilter             = errors.New("invalid filter")
 )
@@ -1,7 +1,503 @@
 package domain
 
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"slices"
+	"time"
+)
+
+// User - доменная модель зарегистрированного пользователя.
+type User struct {
+	GUID        uuid.UUID // Идентификатор пользователя
+	Email       string    // Нормализованный email пользователя, пуст для автоматически созданных пользователей
+	DisplayName string    // Отображаемое имя, может быть пустым
+	CreatedAt   time.Time // Время регистрации
+}
+
 // UserAuth - доменная модель для хранения и передачи данных аутентификации пользователя.
+// Если для выдачи токенов требуется второй фактор, вместо пары токенов заполняются поля MFA.
 type UserAuth struct {
-	AccessToken  string // Токен доступа
-	RefreshToken string // Refresh - токен, закодированный в base64
+	AccessToken  string        // Токен доступа
+	RefreshToken string        // Refresh - токен, закодированный в base64
+	MFAToken     string        // Токен MFA-челленджа, который обменивается на пару токенов после проверки второго фактора
+	MFAExpiresAt time.Time     // Время истечения MFA-челленджа
+	ExpiresIn    time.Duration // Время жизни Access токена, заполняется для ответов эндпоинта OAuth /token
+	Scopes       []string      // Области доступа Access токена клиента OAuth
+}
+
+// MFARequired сообщает, что вместо пары токенов выдан MFA-челлендж.
+func (a *UserAuth) MFARequired() bool {
+	return a.MFAToken != ""
+}
+
+// VerifiedToken - результат проверки Access токена для прокси, которые защищают другие приложения (forward auth).
+type VerifiedToken struct {
+	Subject     string    // GUID пользователя или идентификатор клиента OAuth, если токен выдан клиенту от его имени
+	JTI         uuid.UUID // Идентификатор токена
+	ClientID    string    // Клиент OAuth, которому выдан токен, или пустая строка
+	Scopes      []string  // Области доступа клиента OAuth
+	Permissions []string  // Разрешения ролей пользователя
+	Actor       string    // Субъект стороны, действующей от имени пользователя (RFC 8693, claim act), или пустая строка
+}
+
+// IPChangePolicy - политика поведения сервиса при обновлении токенов с IP-адреса,
+// отличного от того, с которого был выдан Access токен.
+type IPChangePolicy string
+
+const (
+	IPChangePolicyNotify IPChangePolicy = "notify"  // Пользователь уведомляется, обновление проходит как обычно
+	IPChangePolicyDeny   IPChangePolicy = "deny"    // Обновление токенов запрещается
+	IPChangePolicyStepUp IPChangePolicy = "step_up" // Обновление разрешается, но новый токен требует повторной аутентификации
+)
+
+// ParseIPChangePolicy парсит политику смены IP из строки.
+// Пустая строка трактуется как политика по умолчанию (IPChangePolicyNotify).
+func ParseIPChangePolicy(raw string) (IPChangePolicy, error) {
+	switch policy := IPChangePolicy(raw); policy {
+	case "":
+		return IPChangePolicyNotify, nil
+	case IPChangePolicyNotify, IPChangePolicyDeny, IPChangePolicyStepUp:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid ip change policy: %s", raw)
+	}
+}
+
+// ProvisioningMode - режим автоматического создания пользователей
+// при аутентификации по незарегистрированному GUID.
+type ProvisioningMode string
+
+const (
+	ProvisioningModeAuto      ProvisioningMode = "auto"      // Пользователь создается для любого GUID
+	ProvisioningModeDeny      ProvisioningMode = "deny"      // Аутентификация незарегистрированных пользователей запрещена
+	ProvisioningModeAllowlist ProvisioningMode = "allowlist" // Пользователь создается только для GUID из списка разрешенных
+)
+
+// ParseProvisioningMode парсит режим создания пользователей из строки.
+// Пустая строка трактуется как режим по умолчанию (ProvisioningModeAuto), сохраняющий прежнее поведение GET /auth.
+func ParseProvisioningMode(raw string) (ProvisioningMode, error) {
+	switch mode := ProvisioningMode(raw); mode {
+	case "":
+		return ProvisioningModeAuto, nil
+	case ProvisioningModeAuto, ProvisioningModeDeny, ProvisioningModeAllowlist:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid provisioning mode: %s", raw)
+	}
+}
+
+// GeoLocation - географическое положение, с которого выполнялась операция.
+type GeoLocation struct {
+	Country string `json:"country"` // ISO-код страны
+	City    string `json:"city"`    // Город
+}
+
+// RiskEventType - тип события риска.
+type RiskEventType string
+
+const (
+	// RiskEventImpossibleTravel - две последовательные операции в рамках одной сессии выполнены из мест,
+	// расстояние между которыми невозможно преодолеть за прошедшее время.
+	RiskEventImpossibleTravel RiskEventType = "impossible_travel"
+	// RiskEventElevatedRisk - оценка риска операции превысила порог уведомления.
+	RiskEventElevatedRisk RiskEventType = "elevated_risk"
+	// RiskEventRiskDenied - операция запрещена по результатам оценки риска.
+	RiskEventRiskDenied RiskEventType = "risk_denied"
+)
+
+// RiskEvent - доменная модель события риска, обнаруженного при аутентификации или обновлении токенов.
+type RiskEvent struct {
+	Type         RiskEventType
+	GUID         uuid.UUID          // GUID пользователя
+	JTI          uuid.UUID          // ID Access токена, при использовании которого обнаружено событие
+	PrevIP       string             // IP-адрес предыдущей операции в сессии
+	IP           string             // IP-адрес текущей операции
+	UserAgent    string             // User-Agent текущей операции
+	PrevLocation *GeoLocation       // Положение предыдущей операции, если известно
+	Location     *GeoLocation       // Положение текущей операции, если известно
+	DistanceKM   float64            // Расстояние между операциями в километрах
+	Elapsed      time.Duration      // Время, прошедшее между операциями
+	Score        float64            // Оценка риска операции, если событие возбуждено движком оценки риска
+	Signals      map[string]float64 // Сработавшие сигналы риска и их вклад в оценку
+	OccurredAt   time.Time          // Время обнаружения события
+}
+
+// AuthEventType - тип события аутентификации, записываемого в журнал аудита.
+type AuthEventType string
+
+const (
+	AuthEventTokenIssued          AuthEventType = "token_issued"           // Выдана новая пара токенов
+	AuthEventTokenRefreshed       AuthEventType = "token_refreshed"        // Пара токенов обновлена
+	AuthEventRefreshFailed        AuthEventType = "refresh_failed"         // Неудачная попытка обновления токенов
+	AuthEventTokenRevoked         AuthEventType = "token_revoked"          // Refresh токен отозван
+	AuthEventIPChanged            AuthEventType = "ip_changed"             // Токены обновляются с другого IP-адреса
+	AuthEventRiskDecision         AuthEventType = "risk_decision"          // Решение движка оценки риска
+	AuthEventRiskDetected         AuthEventType = "risk_detected"          // Обнаружено событие риска
+	AuthEventUserProvisioned      AuthEventType = "user_provisioned"       // Пользователь создан автоматически при первой аутентификации
+	AuthEventLoginFailed          AuthEventType = "login_failed"           // Неудачная попытка входа по email и паролю
+	AuthEventPasswordChanged      AuthEventType = "password_changed"       // Пароль пользователя изменен
+	AuthEventPasswordChangeFailed AuthEventType = "password_change_failed" // Неудачная попытка смены пароля
+	AuthEventMFAChallenge         AuthEventType = "mfa_challenge"          // Первый фактор пройден, выдан MFA-челлендж
+	AuthEventMFAFailed            AuthEventType = "mfa_failed"             // Неудачная проверка второго фактора
+	AuthEventTOTPEnabled          AuthEventType = "totp_enabled"           // Пользователь подключил TOTP
+	AuthEventRecoveryCodesIssued  AuthEventType = "recovery_codes_issued"  // Пользователь сгенерировал новый набор кодов восстановления
+	AuthEventRecoveryCodeUsed     AuthEventType = "recovery_code_used"     // Код восстановления использован вместо второго фактора
+	AuthEventPasskeyRegistered    AuthEventType = "passkey_registered"     // Пользователь зарегистрировал passkey
+	AuthEventMagicLinkSent        AuthEventType = "magic_link_sent"        // Пользователю отправлена ссылка для входа
+	AuthEventPasswordResetSent    AuthEventType = "password_reset_sent"    // Пользователю отправлена ссылка для сброса пароля
+	AuthEventPasswordReset        AuthEventType = "password_reset"         // Пароль пользователя сброшен по ссылке из письма
+	AuthEventPasswordResetFailed  AuthEventType = "password_reset_failed"  // Неудачная попытка сброса пароля
+	AuthEventTokenExchanged       AuthEventType = "token_exchanged"        // Клиенту выдан токен для действий от имени пользователя
+)
+
+// AuthMethod - способ аутентификации, которым была получена пара токенов.
+type AuthMethod string
+
+const (
+	AuthMethodGUID         AuthMethod = "guid"          // Аутентификация по GUID пользователя
+	AuthMethodPassword     AuthMethod = "password"      // Аутентификация по email и паролю
+	AuthMethodTOTP         AuthMethod = "totp"          // Одноразовый код TOTP в качестве второго фактора
+	AuthMethodRecoveryCode AuthMethod = "recovery_code" // Код восстановления вместо второго фактора
+	AuthMethodPasskey      AuthMethod = "passkey"       // Вход по passkey (WebAuthn) с верификацией пользователя
+	AuthMethodMagicLink    AuthMethod = "magic_link"    // Вход по одноразовой ссылке из письма
+)
+
+// Значения claim amr Access токена по RFC 8176.
+const (
+	AMRPassword = "pwd" // Аутентификация по паролю
+	AMROTP      = "otp" // Одноразовый код
+	AMRMFA      = "mfa" // Использовано несколько факторов
+	AMRHWK      = "hwk" // Подтверждение владения ключом, защищенным аутентификатором
+)
+
+// AMR возвращает значение claim amr для способа аутентификации.
+// Для способов, не описанных в RFC 8176, возвращает пустую строку.
+func (m AuthMethod) AMR() string {
+	switch m {
+	case AuthMethodPassword:
+		return AMRPassword
+	case AuthMethodTOTP:
+		return AMROTP
+	case AuthMethodPasskey:
+		return AMRHWK
+	default:
+		return ""
+	}
+}
+
+// MultiFactor сообщает, подтверждает ли способ аутентификации сам по себе несколько факторов.
+// Passkey с верификацией пользователя объединяет владение аутентификатором и PIN-код или биометрию.
+func (m AuthMethod) MultiFactor() bool {
+	return m == AuthMethodPasskey
+}
+
+// TOTPEnrollment - подключение TOTP пользователя.
+type TOTPEnrollment struct {
+	UserID          uuid.UUID // Идентификатор пользователя
+	EncryptedSecret string    // Зашифрованный секрет TOTP
+	Confirmed       bool      // Подключение подтверждено кодом из приложения-аутентификатора
+	LastUsedStep    int64     // Последний использованный временной шаг, для защиты от повторного использования кода
+}
+
+// TOTPSetup - данные для подключения TOTP в приложении-аутентификаторе.
+type TOTPSetup struct {
+	Secret          string // Секрет в base32 для ручного ввода
+	ProvisioningURI string // URI otpauth:// для QR-кода
+}
+
+// RecoveryCode - неиспользованный код восстановления пользователя.
+type RecoveryCode struct {
+	ID       uuid.UUID // Идентификатор кода
+	UserID   uuid.UUID // Идентификатор пользователя
+	CodeHash string    // bcrypt хеш нормализованного кода
+}
+
+// Passkey - зарегистрированные учетные данные WebAuthn пользователя.
+type Passkey struct {
+	CredentialID []byte    // ID учетных данных, назначенный аутентификатором
+	UserID       uuid.UUID // Идентификатор пользователя
+	PublicKey    []byte    // Открытый ключ в формате COSE
+	SignCount    uint32    // Последнее известное значение счетчика подписей аутентификатора
+	CreatedAt    time.Time // Время регистрации
+}
+
+// EmailVerification - выданный токен подтверждения email.
+type EmailVerification struct {
+	ID        uuid.UUID // Идентификатор
+	UserID    uuid.UUID // Идентификатор пользователя
+	Email     string    // Email, на который отправлен токен. Подтверждается, только если email пользователя не изменился
+	TokenHash string    // SHA-256 хеш токена
+	ExpiresAt time.Time // Время истечения
+	CreatedAt time.Time // Время выдачи, используется для ограничения частоты повторных отправок
+}
+
+// PasswordReset - выданный токен сброса пароля.
+type PasswordReset struct {
+	ID        uuid.UUID // Идентификатор
+	UserID    uuid.UUID // Идентификатор пользователя
+	TokenHash string    // SHA-256 хеш токена
+	ExpiresAt time.Time // Время истечения
+	CreatedAt time.Time // Время выдачи
+}
+
+// GrantType - способ получения токенов клиентом OAuth (RFC 6749, grant_type).
+type GrantType string
+
+const (
+	GrantTypeAuthorizationCode GrantType = "authorization_code" // Код авторизации с PKCE
+	GrantTypeRefreshToken      GrantType = "refresh_token"      // Обновление токенов
+	GrantTypeClientCredentials GrantType = "client_credentials" // Токен клиента от его собственного имени, только для конфиденциальных клиентов
+	// Авторизация устройства без браузера: пользователь подтверждает вход на другом устройстве (RFC 8628)
+	GrantTypeDeviceCode GrantType = "urn:ietf:params:oauth:grant-type:device_code"
+	// Обмен токена на токен, действующий от имени пользователя (RFC 8693), только для конфиденциальных клиентов
+	GrantTypeTokenExchange GrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+)
+
+// ParseGrantType проверяет, что способ получения токенов поддерживается.
+func ParseGrantType(raw string) (GrantType, error) {
+	switch grantType := GrantType(raw); grantType {
+	case GrantTypeAuthorizationCode, GrantTypeRefreshToken, GrantTypeClientCredentials, GrantTypeDeviceCode, GrantTypeTokenExchange:
+		return grantType, nil
+	default:
+		return "", ErrUnsupportedGrantType
+	}
+}
+
+// ConfidentialOnly сообщает, что способ доступен только конфиденциальным клиентам.
+func (g GrantType) ConfidentialOnly() bool {
+	return g == GrantTypeClientCredentials || g == GrantTypeTokenExchange
+}
+
+// OAuthClient - зарегистрированный клиент OAuth 2.0.
+type OAuthClient struct {
+	ID           string        // Идентификатор клиента (client_id)
+	Name         string        // Отображаемое на странице входа название приложения
+	SecretHash   string        // SHA-256 хеш секрета клиента. Пуст у публичных клиентов
+	GrantTypes   []GrantType   // Разрешенные клиенту способы получения токенов
+	RedirectURIs []string      // Разрешенные адреса перенаправления, сравниваются с redirect_uri точно
+	Scopes       []string      // Разрешенные клиенту области доступа
+	AccessTTL    time.Duration // Время жизни Access токенов клиента. Если 0, используется глобальное
+	RefreshTTL   time.Duration // Время жизни Refresh токенов клиента. Если 0, используется глобальное
+	// SHA-256 хеш токена доступа к регистрации (RFC 7592). Пуст у клиентов, зарегистрированных администратором
+	RegistrationTokenHash string
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+}
+
+// Confidential сообщает, что клиент аутентифицируется секретом (RFC 6749, раздел 2.1).
+func (c *OAuthClient) Confidential() bool {
+	return c.SecretHash != ""
+}
+
+// AllowsGrant сообщает, разрешен ли клиенту способ получения токенов.
+func (c *OAuthClient) AllowsGrant(grantType GrantType) bool {
+	return slices.Contains(c.GrantTypes, grantType)
+}
+
+// AllowsRedirectURI сообщает, зарегистрирован ли адрес перенаправления для клиента.
+func (c *OAuthClient) AllowsRedirectURI(redirectURI string) bool {
+	return slices.Contains(c.RedirectURIs, redirectURI)
+}
+
+// AllowsScopes сообщает, разрешены ли клиенту все запрошенные области доступа.
+func (c *OAuthClient) AllowsScopes(scopes []string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(c.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// PermissionImpersonate - разрешение роли, которое позволяет сотруднику получать обменом токена (RFC 8693)
+// токены, действующие от имени других пользователей.
+const PermissionImpersonate = "users:impersonate"
+
+// Role - роль пользователя. Разрешения ролей пользователя попадают в claim scope его Access токенов.
+type Role struct {
+	Name        string   // Название роли, например admin
+	Permissions []string // Разрешения роли, например orders:read
+}
+
+// ClientRegistration - результат динамической регистрации клиента OAuth (RFC 7591) или ее изменения (RFC 7592).
+type ClientRegistration struct {
+	Client            OAuthClient
+	Secret            string // Секрет конфиденциального клиента. Выдается только при регистрации
+	RegistrationToken string // Токен доступа к регистрации для чтения, изменения и удаления клиента
+	RegistrationURI   string // Адрес управления регистрацией клиента (registration_client_uri)
+}
+
+// ClientCredentials - учетные данные, которыми клиент OAuth аутентифицируется на эндпоинте /token.
+type ClientCredentials struct {
+	ID     string // Идентификатор клиента (client_id)
+	Secret string // Секрет клиента. Пуст у публичных клиентов
+}
+
+// TokenExchangeRequest - параметры обмена токена (RFC 8693, раздел 2.1).
+type TokenExchangeRequest struct {
+	SubjectToken     string // Access токен пользователя или его GUID, в зависимости от SubjectTokenType
+	SubjectTokenType string // Тип SubjectToken
+	ActorToken       string // Access токен сотрудника, который действует от имени пользователя. Может быть пуст
+	ActorTokenType   string // Тип ActorToken
+	Scope            string // Запрошенные области доступа через пробел
+}
+
+// AuthorizationRequest - параметры запроса авторизации OAuth 2.0 (RFC 6749, раздел 4.1.1) с PKCE (RFC 7636).
+type AuthorizationRequest struct {
+	ResponseType        string // Тип ответа, поддерживается только code
+	ClientID            string // Идентификатор клиента
+	RedirectURI         string // Адрес перенаправления
+	State               string // Значение, которое возвращается клиенту без изменений
+	CodeChallenge       string // PKCE code_challenge
+	CodeChallengeMethod string // PKCE code_challenge_method, поддерживается только S256
+	Scope               string // Запрошенные области доступа через пробел
+}
+
+// AuthorizationGrant - результат успешной аутентификации на странице входа:
+// код авторизации или MFA-челлендж, если у пользователя подключен второй фактор.
+// При подтверждении устройства (RFC 8628) код не выдается, и пустой результат означает, что доступ разрешен.
+type AuthorizationGrant struct {
+	Code         string    // Код авторизации для обмена на токены
+	MFAToken     string    // Токен MFA-челленджа
+	MFAExpiresAt time.Time // Время истечения MFA-челленджа
+}
+
+// MFARequired сообщает, что вместо кода авторизации выдан MFA-челлендж.
+func (g *AuthorizationGrant) MFARequired() bool {
+	return g.MFAToken != ""
+}
+
+// AuthorizationCode - выданный код авторизации OAuth 2.0.
+type AuthorizationCode struct {
+	CodeHash      string       // SHA-256 хеш кода
+	ClientID      string       // Клиент, которому выдан код
+	UserID        uuid.UUID    // Пользователь, разрешивший доступ
+	RedirectURI   string       // Адрес перенаправления из запроса авторизации
+	CodeChallenge string       // PKCE code_challenge (S256)
+	AuthMethods   []AuthMethod // Пройденные пользователем способы аутентификации
+	Scopes        []string     // Области доступа, разрешенные пользователем
+	ExpiresAt     time.Time    // Время истечения
+}
+
+// DeviceAuthorizationStatus - состояние запроса авторизации устройства.
+type DeviceAuthorizationStatus string
+
+const (
+	DeviceAuthorizationPending  DeviceAuthorizationStatus = "pending"  // Пользователь еще не ввел код
+	DeviceAuthorizationApproved DeviceAuthorizationStatus = "approved" // Пользователь разрешил доступ
+	DeviceAuthorizationDenied   DeviceAuthorizationStatus = "denied"   // Пользователь отказал в доступе
+)
+
+// DeviceAuthorization - запрос авторизации устройства OAuth 2.0 (RFC 8628).
+type DeviceAuthorization struct {
+	DeviceCodeHash string                    // SHA-256 хеш кода устройства
+	UserCodeHash   string                    // SHA-256 хеш нормализованного пользовательского кода
+	ClientID       string                    // Клиент, запросивший авторизацию
+	Scopes         []string                  // Запрошенные области доступа
+	Status         DeviceAuthorizationStatus // Состояние запроса
+	UserID         uuid.UUID                 // Пользователь, разрешивший или запретивший доступ. uuid.Nil, пока запрос ожидает
+	AuthMethods    []AuthMethod              // Пройденные пользователем способы аутентификации
+	Interval       time.Duration             // Минимальный интервал опроса эндпоинта /token
+	LastPolledAt   time.Time                 // Время предыдущего опроса. Нулевое, если устройство еще не опрашивало
+	ExpiresAt      time.Time                 // Время истечения
+}
+
+// DeviceCode - выданные устройству коды для авторизации (RFC 8628, раздел 3.2).
+type DeviceCode struct {
+	DeviceCode              string        // Код устройства для опроса эндпоинта /token
+	UserCode                string        // Код, который пользователь вводит на странице подтверждения
+	VerificationURI         string        // Адрес страницы подтверждения
+	VerificationURIComplete string        // Адрес страницы подтверждения с уже подставленным кодом
+	ExpiresIn               time.Duration // Время жизни кодов
+	Interval                time.Duration // Минимальный интервал опроса эндпоинта /token
+}
+
+// WebAuthnCeremony - тип церемонии WebAuthn.
+type WebAuthnCeremony string
+
+const (
+	WebAuthnCeremonyRegistration WebAuthnCeremony = "registration" // Регистрация passkey
+	WebAuthnCeremonyLogin        WebAuthnCeremony = "login"        // Вход по passkey
+)
+
+// WebAuthnSession - церемония WebAuthn, ожидающая ответа аутентификатора.
+type WebAuthnSession struct {
+	ID            uuid.UUID        // Идентификатор церемонии
+	ChallengeHash string           // SHA-256 хеш challenge церемонии
+	Ceremony      WebAuthnCeremony // Тип церемонии
+	UserID        uuid.UUID        // Пользователь, регистрирующий passkey. uuid.Nil для входа
+	ExpiresAt     time.Time        // Время истечения
+}
+
+// MFAChallenge - ожидающая проверки второго фактора аутентификация.
+type MFAChallenge struct {
+	ID        uuid.UUID  // Идентификатор челленджа
+	TokenHash string     // SHA-256 хеш токена челленджа
+	UserID    uuid.UUID  // Идентификатор пользователя, прошедшего первый фактор
+	Method    AuthMethod // Способ аутентификации первым фактором
+	Attempts  int        // Количество неудачных попыток проверки второго фактора
+	ExpiresAt time.Time  // Время истечения
+}
+
+// Причины неудачного обновления токенов, входа и смены пароля.
+const (
+	FailureReasonBadAccessToken  = "bad_access_token"       // Access токен невалиден или просрочен
+	FailureReasonBadRefreshToken = "bad_refresh_token"      // Refresh токен невалиден или не соответствует Access токену
+	FailureReasonTokenNotFound   = "token_not_found"        // Refresh токен не найден или просрочен
+	FailureReasonUnknownEmail    = "unknown_email"          // Пользователь с таким email не найден или не имеет пароля
+	FailureReasonBadPassword     = "bad_password"           // Пароль не совпадает
+	FailureReasonNoPassword      = "no_password"            // У пользователя не задан пароль
+	FailureReasonBadMFACode      = "bad_mfa_code"           // Код второго фактора неверен или уже использован
+	FailureReasonBadRecoveryCode = "bad_recovery_code"      // Код восстановления неверен или уже использован
+	FailureReasonBadPasskey      = "bad_passkey"            // Passkey не найден или ответ аутентификатора не прошел проверку
+	FailureReasonSignCount       = "sign_count"             // Счетчик подписей passkey не увеличился, возможен клон аутентификатора
+	FailureReasonBadMagicLink    = "bad_magic_link"         // Ссылка для входа невалидна или просрочена
+	FailureReasonReusedMagicLink = "reused_magic_link"      // Ссылка для входа уже была использована
+	FailureReasonEmailUnverified = "email_unverified"       // Email пользователя не подтвержден, а конфигурация этого требует
+	FailureReasonBadResetToken   = "bad_reset_token"        // Токен сброса пароля неверен, просрочен или уже использован
+	FailureReasonUnknownClient   = "unknown_client"         // Клиент OAuth, которому выдан токен, удален
+	FailureReasonBadAuthCode     = "bad_authorization_code" // Код авторизации OAuth неверен, просрочен, уже использован или не прошел проверку PKCE
+	FailureReasonBadDeviceCode   = "bad_device_code"        // Код устройства неверен, уже использован или выдан другому клиенту
+	FailureReasonBadUserCode     = "bad_user_code"          // Пользовательский код устройства неверен, просрочен или уже использован
+	FailureReasonBadSubjectToken = "bad_subject_token"      // Токен или GUID пользователя при обмене токена неверен
+	FailureReasonBadActorToken   = "bad_actor_token"        // Токен сотрудника при обмене токена неверен, выдан другому клиенту или у сотрудника нет разрешения users:impersonate
+)
+
+// AuthEvent - доменная модель события аутентификации в журнале аудита.
+type AuthEvent struct {
+	ID        uuid.UUID
+	Type      AuthEventType
+	GUID      uuid.UUID      // GUID пользователя, uuid.Nil если пользователь неизвестен
+	JTI       uuid.UUID      // ID Access токена, uuid.Nil если токен неизвестен
+	IP        string         // IP-адрес клиента
+	UserAgent string         // User-Agent клиента
+	RequestID string         // ID HTTP запроса
+	Reason    string         // Причина неудачи для AuthEventRefreshFailed
+	Details   map[string]any // Дополнительные данные события
+	CreatedAt time.Time
+}
+
+// AuditCursor - позиция в журнале аудита для постраничной выборки.
+// События упорядочены от новых к старым по времени создания и ID.
+type AuditCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// AuditFilter - фильтр выборки событий из журнала аудита. Пустые поля не участвуют в фильтрации.
+type AuditFilter struct {
+	GUID  uuid.UUID     // GUID пользователя
+	Type  AuthEventType // Тип события
+	IP    string        // IP-адрес клиента
+	From  time.Time     // Начало интервала (включительно)
+	To    time.Time     // Конец интервала (не включительно)
+	After *AuditCursor  // Позиция, после которой начинается выборка
+	Limit int           // Максимальное количество событий
+}
+
+// AuditPage - страница событий журнала аудита.
+type AuditPage struct {
+	Events     []AuthEvent
+	NextCursor string // Курсор следующей страницы, пустой если страница последняя
 }
@@ -3,6 +3,7 @@ package auth
 import (
 	"errors"
 	"github.com/google/uuid"
+	"time"
 )
 
 var (
@@ -13,13 +14,52 @@ var (
 
 // Claims описывает payload Access токенов.
 type Claims interface {
-	GetGUID() uuid.UUID // GetGUID возвращает ID пользователя из Claims токена.
-	GetIP() string      // GetIP возвращает IP-адрес из Claims токена
-	GetJTI() uuid.UUID  // GetJTI возвращает ID токена из Claims токена
+	GetGUID() uuid.UUID       // GetGUID возвращает ID пользователя из Claims токена или uuid.Nil, если токен выдан клиенту OAuth от его имени
+	GetSubjectID() string     // GetSubjectID возвращает субъект токена: GUID пользователя или идентификатор клиента OAuth
+	IsClientToken() bool      // IsClientToken сообщает, выдан ли токен клиенту OAuth от его собственного имени (client_credentials)
+	GetIP() string            // GetIP возвращает IP-адрес из Claims токена
+	GetJTI() uuid.UUID        // GetJTI возвращает ID токена из Claims токена
+	RequiresReauth() bool     // RequiresReauth сообщает, требует ли токен повторной аутентификации пользователя
+	GetIssueTime() time.Time  // GetIssueTime возвращает время выпуска токена или нулевое время, если оно неизвестно
+	GetUserAgentHash() string // GetUserAgentHash возвращает отпечаток User-Agent, для которого был выпущен токен
+	GetAMR() []string         // GetAMR возвращает методы аутентификации (RFC 8176), которыми была подтверждена личность пользователя
+	IsEmailVerified() bool    // IsEmailVerified сообщает, был ли email пользователя подтвержден на момент выпуска токена
+	GetClientID() string      // GetClientID возвращает идентификатор клиента OAuth, которому выдан токен, или пустую строку
+	GetScopes() []string      // GetScopes возвращает области доступа клиента OAuth
+	GetRoles() []string       // GetRoles возвращает роли пользователя
+	GetPermissions() []string // GetPermissions возвращает разрешения ролей пользователя
+	GetActor() *Actor         // GetActor возвращает сторону, действующую от имени пользователя, или nil, если токен выдан ему самому
+	GetSessionID() uuid.UUID  // GetSessionID возвращает сессию пользователя, к которой привязан токен без собственной сессии, или uuid.Nil
+}
+
+// Actor - сторона, которой выдан токен для действий от имени пользователя (RFC 8693, claim act).
+type Actor struct {
+	Subject   string    // GUID сотрудника или идентификатор клиента OAuth
+	IsClient  bool      // Действует сам клиент OAuth, а не сотрудник
+	ClientID  string    // Клиент OAuth, через который действует сторона
+	SessionID uuid.UUID // Сессия сотрудника, с которой получен токен, или uuid.Nil, если действует клиент
+}
+
+// TokenOptions - дополнительные параметры, с которыми выпускается Access токен.
+type TokenOptions struct {
+	RequireReauth bool          // Токен помечается как требующий повторной аутентификации
+	UserAgentHash string        // Отпечаток User-Agent клиента, которому выдается токен
+	AMR           []string      // Методы аутентификации пользователя (RFC 8176), например pwd, otp, mfa
+	EmailVerified *bool         // Подтвержден ли email пользователя. Если nil, claim email_verified не добавляется
+	ClientID      string        // Клиент OAuth, которому выдается токен. Если пуст, claim client_id не добавляется
+	ClientSubject bool          // Токен выдается клиенту ClientID от его собственного имени: субъектом становится клиент, а не пользователь
+	Scopes        []string      // Области доступа клиента OAuth. Если пусты, claim scope не добавляется
+	Roles         []string      // Роли пользователя. Если пусты, claim roles не добавляется
+	Permissions   []string      // Разрешения ролей пользователя. Если пусты, claim permissions не добавляется
+	TTL           time.Duration // Время жизни токена. Если 0, используется время жизни менеджера
+	Actor         *Actor        // Сторона, действующая от имени пользователя. Если nil, claim act не добавляется
+	SessionID     uuid.UUID     // Сессия пользователя, при отзыве которой отзывается и токен. Если uuid.Nil, claim sid не добавляется
 }
 
 // AccessTokenManager описывает интерфейс менеджера Access токенов.
 type AccessTokenManager interface {
-	Generate(guid uuid.UUID, id uuid.UUID, ip string) (string, error) // Generate генерирует новый AccessToken для пользователя с добавлением его ip-адреса и айди токена.
-	Parse(raw string) (Claims, error)                                 // Parse парсит AccessToken и возвращает его Claims
+	Generate(guid uuid.UUID, id uuid.UUID, ip string, opts TokenOptions) (string, error) // Generate генерирует новый AccessToken для пользователя с добавлением его ip-адреса и айди токена.
+	Parse(raw string) (Claims, error)                                                    // Parse парсит AccessToken и возвращает его Claims
+	ParseExpired(raw string) (Claims, error)                                             // ParseExpired парсит AccessToken, не отклоняя просроченный, например для его обмена на новый по Refresh токену
+	TTL() time.Duration                                                                  // TTL возвращает время жизни Access токенов по умолчанию
 }
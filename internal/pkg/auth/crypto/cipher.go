@@ -0,0 +1,83 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+)
+
+// KeyLength - длина ключа шифрования в байтах (AES-256)
+const KeyLength = 32
+
+var (
+	ErrInvalidKey        = errors.New("encryption key must be 32 bytes long")
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// Cipher шифрует небольшие секреты (например, TOTP секреты) для хранения в базе данных с помощью AES-256-GCM.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher создает Cipher с ключом длиной KeyLength байт.
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != KeyLength {
+		return nil, ErrInvalidKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt шифрует plain со случайным nonce и возвращает base64 строку nonce и шифртекста.
+// associatedData не шифруется, но должна совпадать при расшифровке - так шифртекст
+// привязывается к владельцу и не может быть подставлен другому пользователю.
+func (c *Cipher) Encrypt(plain, associatedData []byte) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, plain, associatedData)
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt расшифровывает строку, полученную от Encrypt.
+// Возвращает ErrInvalidCiphertext, если данные повреждены или associatedData не совпадает.
+func (c *Cipher) Decrypt(encoded string, associatedData []byte) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+
+	plain, err := c.aead.Open(nil, nonce, ciphertext, associatedData)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+
+	return plain, nil
+}
+
+// HashToken хеширует случайный токен высокой энтропии с помощью SHA-256 и возвращает хеш в hex.
+// В отличие от HashBytes, хеш детерминирован и может использоваться для поиска токена в базе данных.
+// Не подходит для паролей и других секретов с низкой энтропией.
+func HashToken(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
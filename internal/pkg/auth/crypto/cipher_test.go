@@ -0,0 +1,60 @@
+package crypto_test
+
+import (
+	"bytes"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCipher(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, crypto.KeyLength)
+	c, err := crypto.NewCipher(key)
+	require.NoError(t, err)
+
+	t.Run("Round Trip", func(t *testing.T) {
+		encrypted, err := c.Encrypt([]byte("secret"), []byte("owner"))
+		require.NoError(t, err)
+
+		plain, err := c.Decrypt(encrypted, []byte("owner"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("secret"), plain)
+	})
+
+	t.Run("Random Nonce", func(t *testing.T) {
+		first, err := c.Encrypt([]byte("secret"), nil)
+		require.NoError(t, err)
+		second, err := c.Encrypt([]byte("secret"), nil)
+		require.NoError(t, err)
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("Wrong Associated Data", func(t *testing.T) {
+		encrypted, err := c.Encrypt([]byte("secret"), []byte("owner"))
+		require.NoError(t, err)
+
+		_, err = c.Decrypt(encrypted, []byte("other"))
+		assert.ErrorIs(t, err, crypto.ErrInvalidCiphertext)
+	})
+
+	t.Run("Corrupted", func(t *testing.T) {
+		_, err := c.Decrypt("not base64!", nil)
+		assert.ErrorIs(t, err, crypto.ErrInvalidCiphertext)
+
+		_, err = c.Decrypt("AAAA", nil)
+		assert.ErrorIs(t, err, crypto.ErrInvalidCiphertext)
+	})
+
+	t.Run("Invalid Key", func(t *testing.T) {
+		_, err := crypto.NewCipher([]byte("short"))
+		assert.ErrorIs(t, err, crypto.ErrInvalidKey)
+	})
+}
+
+func TestHashToken(t *testing.T) {
+	first := crypto.HashToken([]byte("token"))
+	assert.Len(t, first, 64)
+	assert.Equal(t, first, crypto.HashToken([]byte("token")))
+	assert.NotEqual(t, first, crypto.HashToken([]byte("other")))
+}
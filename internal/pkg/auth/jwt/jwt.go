@@ -5,19 +5,56 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/maksemen2/medods-task/internal/pkg/auth"
+	"strings"
 	"time"
 )
 
+// subjectTypeClient - значение claim sub_type токенов, выданных клиенту OAuth от его собственного имени.
+const subjectTypeClient = "client"
+
 // jwtClaims имплементирует auth.Claims, payload jwt - Access токенов.
+// Субъект токена (sub) - GUID пользователя, а для токенов клиента OAuth, выданных от его
+// собственного имени, - идентификатор клиента. Такие токены помечаются claim sub_type.
 type jwtClaims struct {
-	jwt.RegisteredClaims           // Встроенные зарегистрированные поля, будут использоваться exp, ID
-	GUID                 uuid.UUID `json:"sub"`
+	jwt.RegisteredClaims           // Встроенные зарегистрированные поля, будут использоваться sub, exp, iat, ID
+	SubjectType          string    `json:"sub_type,omitempty"` // Тип субъекта, не являющегося пользователем
 	IP                   string    `json:"ip"`
+	Reauth               bool      `json:"reauth,omitempty"`         // Токен выпущен по политике step_up и требует повторной аутентификации
+	UserAgentHash        string    `json:"uah,omitempty"`            // Отпечаток User-Agent клиента
+	AMR                  []string  `json:"amr,omitempty"`            // Методы аутентификации пользователя (RFC 8176)
+	EmailVerified        *bool     `json:"email_verified,omitempty"` // Подтвержден ли email пользователя
+	ClientID             string    `json:"client_id,omitempty"`      // Клиент OAuth, которому выдан токен (RFC 9068)
+	Scope                string    `json:"scope,omitempty"`          // Области доступа клиента OAuth через пробел (RFC 9068)
+	Roles                []string  `json:"roles,omitempty"`          // Роли пользователя (RFC 9068, раздел 2.2.3.1)
+	Permissions          []string  `json:"permissions,omitempty"`    // Разрешения ролей пользователя (RFC 9068, раздел 2.2.3.1)
+	Act                  *actClaim `json:"act,omitempty"`            // Сторона, действующая от имени пользователя (RFC 8693)
+	SessionID            string    `json:"sid,omitempty"`            // Сессия пользователя, к которой привязан токен, полученный обменом
+	guid                 uuid.UUID // GUID пользователя, разобранный из sub при проверке токена
+	sessionID            uuid.UUID // Сессия, разобранная из sid при проверке токена
+}
+
+// actClaim - claim act токенов, полученных обменом (RFC 8693, раздел 4.1).
+type actClaim struct {
+	Subject     string    `json:"sub"`
+	SubjectType string    `json:"sub_type,omitempty"`
+	ClientID    string    `json:"client_id,omitempty"`
+	SessionID   string    `json:"sid,omitempty"` // Сессия сотрудника, с которой получен токен
+	sessionID   uuid.UUID // Сессия, разобранная из sid при проверке токена
 }
 
-// GetGUID - геттер для ID пользователя
+// GetGUID - геттер для ID пользователя. Для токенов клиента возвращает uuid.Nil
 func (c *jwtClaims) GetGUID() uuid.UUID {
-	return c.GUID
+	return c.guid
+}
+
+// GetSubjectID - геттер для субъекта токена
+func (c *jwtClaims) GetSubjectID() string {
+	return c.Subject
+}
+
+// IsClientToken - геттер для признака токена, выданного клиенту от его собственного имени
+func (c *jwtClaims) IsClientToken() bool {
+	return c.SubjectType == subjectTypeClient
 }
 
 // GetIP - геттер для айпи пользователя
@@ -25,6 +62,72 @@ func (c *jwtClaims) GetIP() string {
 	return c.IP
 }
 
+// RequiresReauth - геттер для признака необходимости повторной аутентификации
+func (c *jwtClaims) RequiresReauth() bool {
+	return c.Reauth
+}
+
+// GetIssueTime - геттер для времени выпуска токена
+func (c *jwtClaims) GetIssueTime() time.Time {
+	if c.IssuedAt == nil {
+		return time.Time{}
+	}
+	return c.IssuedAt.Time
+}
+
+// GetUserAgentHash - геттер для отпечатка User-Agent
+func (c *jwtClaims) GetUserAgentHash() string {
+	return c.UserAgentHash
+}
+
+// IsEmailVerified - геттер для признака подтвержденного email
+func (c *jwtClaims) IsEmailVerified() bool {
+	return c.EmailVerified != nil && *c.EmailVerified
+}
+
+// GetClientID - геттер для идентификатора клиента OAuth
+func (c *jwtClaims) GetClientID() string {
+	return c.ClientID
+}
+
+// GetScopes - геттер для областей доступа
+func (c *jwtClaims) GetScopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// GetRoles - геттер для ролей пользователя
+func (c *jwtClaims) GetRoles() []string {
+	return c.Roles
+}
+
+// GetPermissions - геттер для разрешений ролей пользователя
+func (c *jwtClaims) GetPermissions() []string {
+	return c.Permissions
+}
+
+// GetActor - геттер для стороны, действующей от имени пользователя
+func (c *jwtClaims) GetActor() *auth.Actor {
+	if c.Act == nil {
+		return nil
+	}
+	return &auth.Actor{
+		Subject:   c.Act.Subject,
+		IsClient:  c.Act.SubjectType == subjectTypeClient,
+		ClientID:  c.Act.ClientID,
+		SessionID: c.Act.sessionID,
+	}
+}
+
+// GetSessionID - геттер для сессии, к которой привязан токен
+func (c *jwtClaims) GetSessionID() uuid.UUID {
+	return c.sessionID
+}
+
+// GetAMR - геттер для методов аутентификации
+func (c *jwtClaims) GetAMR() []string {
+	return c.AMR
+}
+
 // GetJTI - геттер для ID токена
 func (c *jwtClaims) GetJTI() uuid.UUID {
 	// Мы можем быть уверены, что ID спарсится, потому что всегда при создании токена мы кладем
@@ -49,35 +152,85 @@ func NewManager(signingKey []byte, tokenTTL time.Duration) auth.AccessTokenManag
 	}
 }
 
-// Generate генерирует новый Access Token. Принимает GUID пользователя, ID токена и IP-адрес.
+// Generate генерирует новый Access Token. Принимает GUID пользователя, ID токена, IP-адрес
+// и дополнительные параметры токена.
 // Токен подписывается методом jwt.SigningMethodHS512 (SHA512) и секретным ключом.
 // Возвращает строку с токеном.
-func (m *JWTTokenManager) Generate(guid uuid.UUID, id uuid.UUID, ip string) (string, error) {
+func (m *JWTTokenManager) Generate(guid uuid.UUID, id uuid.UUID, ip string, opts auth.TokenOptions) (string, error) {
 	currentTime := time.Now()
 
+	ttl := m.TokenTTL
+	if opts.TTL > 0 {
+		ttl = opts.TTL
+	}
+
+	subject, subjectType := guid.String(), ""
+	if opts.ClientSubject {
+		subject, subjectType = opts.ClientID, subjectTypeClient
+	}
+
 	claims := &jwtClaims{
-		GUID: guid,
-		IP:   ip,
+		SubjectType:   subjectType,
+		IP:            ip,
+		Reauth:        opts.RequireReauth,
+		UserAgentHash: opts.UserAgentHash,
+		AMR:           opts.AMR,
+		EmailVerified: opts.EmailVerified,
+		ClientID:      opts.ClientID,
+		Scope:         strings.Join(opts.Scopes, " "),
+		Roles:         opts.Roles,
+		Permissions:   opts.Permissions,
 		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
 			ID:        id.String(),
-			ExpiresAt: jwt.NewNumericDate(currentTime.Add(m.TokenTTL)),
+			ExpiresAt: jwt.NewNumericDate(currentTime.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(currentTime),
 		},
 	}
 
+	if opts.SessionID != uuid.Nil {
+		claims.SessionID = opts.SessionID.String()
+	}
+
+	if opts.Actor != nil {
+		claims.Act = &actClaim{Subject: opts.Actor.Subject, ClientID: opts.Actor.ClientID}
+		if opts.Actor.IsClient {
+			claims.Act.SubjectType = subjectTypeClient
+		}
+		if opts.Actor.SessionID != uuid.Nil {
+			claims.Act.SessionID = opts.Actor.SessionID.String()
+		}
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
 
 	return token.SignedString(m.SigningKey)
 }
 
+// TTL возвращает время жизни Access токенов, выпускаемых без TokenOptions.TTL.
+func (m *JWTTokenManager) TTL() time.Duration {
+	return m.TokenTTL
+}
+
 // Parse парсит Access токен и возвращает его Claims.
 // Возвращает ошибку, если токен невалиден или просрочен.
 func (m *JWTTokenManager) Parse(raw string) (auth.Claims, error) {
+	return m.parse(raw)
+}
+
+// ParseExpired парсит Access токен так же, как Parse, но не отклоняет просроченный токен.
+// Подпись и содержимое токена проверяются.
+func (m *JWTTokenManager) ParseExpired(raw string) (auth.Claims, error) {
+	return m.parse(raw, jwt.WithoutClaimsValidation())
+}
+
+func (m *JWTTokenManager) parse(raw string, opts ...jwt.ParserOption) (auth.Claims, error) {
 	token, err := jwt.ParseWithClaims(raw, &jwtClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, auth.ErrInvalidSignature
 		}
 		return m.SigningKey, nil
-	})
+	}, opts...)
 
 	if err != nil {
 		switch {
@@ -85,8 +238,8 @@ func (m *JWTTokenManager) Parse(raw string) (auth.Claims, error) {
 			return nil, auth.ErrTokenExpired
 		case errors.Is(err, jwt.ErrSignatureInvalid):
 			return nil, auth.ErrInvalidSignature
-		case errors.Is(err, jwt.ErrTokenExpired):
-			return nil, auth.ErrTokenExpired
+		default:
+			return nil, auth.ErrInvalidToken
 		}
 	}
 
@@ -99,5 +252,41 @@ func (m *JWTTokenManager) Parse(raw string) (auth.Claims, error) {
 		return nil, auth.ErrInvalidToken
 	}
 
+	switch claims.SubjectType {
+	case "":
+		guid, err := uuid.Parse(claims.Subject)
+		if err != nil {
+			return nil, auth.ErrInvalidToken
+		}
+		claims.guid = guid
+	case subjectTypeClient:
+		if claims.Subject == "" || claims.Subject != claims.ClientID {
+			return nil, auth.ErrInvalidToken
+		}
+	default:
+		return nil, auth.ErrInvalidToken
+	}
+
+	if claims.Act != nil && (claims.Act.Subject == "" || claims.Act.SubjectType != "" && claims.Act.SubjectType != subjectTypeClient) {
+		return nil, auth.ErrInvalidToken
+	}
+
+	if claims.sessionID, err = parseSessionID(claims.SessionID); err != nil {
+		return nil, auth.ErrInvalidToken
+	}
+	if claims.Act != nil {
+		if claims.Act.sessionID, err = parseSessionID(claims.Act.SessionID); err != nil {
+			return nil, auth.ErrInvalidToken
+		}
+	}
+
 	return claims, nil
 }
+
+// parseSessionID разбирает claim sid. Если claim отсутствует, возвращает uuid.Nil.
+func parseSessionID(raw string) (uuid.UUID, error) {
+	if raw == "" {
+		return uuid.Nil, nil
+	}
+	return uuid.Parse(raw)
+}
@@ -1,10 +1,13 @@
 package jwt_test
 
 import (
+	"encoding/base64"
+	jwtlib "github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/maksemen2/medods-task/internal/pkg/auth"
 	"github.com/maksemen2/medods-task/internal/pkg/auth/jwt"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 	"time"
 )
@@ -17,7 +20,7 @@ func TestJWTTokenManager_GenerateAndParse(t *testing.T) {
 		guid := uuid.New()
 		ip := "127.0.0.1"
 
-		token, err := manager.Generate(guid, uuid.New(), ip)
+		token, err := manager.Generate(guid, uuid.New(), ip, auth.TokenOptions{})
 		assert.NoError(t, err)
 		assert.NotEmpty(t, token)
 
@@ -28,12 +31,205 @@ func TestJWTTokenManager_GenerateAndParse(t *testing.T) {
 
 		assert.Equal(t, claims.GetGUID(), guid)
 		assert.Equal(t, claims.GetIP(), ip)
+		assert.False(t, claims.RequiresReauth())
+		assert.WithinDuration(t, time.Now(), claims.GetIssueTime(), 2*time.Second)
+	})
+
+	t.Run("Token Options", func(t *testing.T) {
+		manager := jwt.NewManager([]byte("very_secret_key"), 10*time.Minute)
+
+		token, err := manager.Generate(uuid.New(), uuid.New(), "127.0.0.1", auth.TokenOptions{RequireReauth: true, UserAgentHash: "hash", AMR: []string{"pwd", "otp", "mfa"}})
+		assert.NoError(t, err)
+
+		claims, err := manager.Parse(token)
+		assert.NoError(t, err)
+		assert.True(t, claims.RequiresReauth())
+		assert.Equal(t, "hash", claims.GetUserAgentHash())
+		assert.Equal(t, []string{"pwd", "otp", "mfa"}, claims.GetAMR())
+		assert.False(t, claims.IsEmailVerified())
+	})
+
+	t.Run("Email Verified Claim", func(t *testing.T) {
+		manager := jwt.NewManager([]byte("very_secret_key"), 10*time.Minute)
+
+		verified := true
+		token, err := manager.Generate(uuid.New(), uuid.New(), "127.0.0.1", auth.TokenOptions{EmailVerified: &verified})
+		assert.NoError(t, err)
+
+		claims, err := manager.Parse(token)
+		assert.NoError(t, err)
+		assert.True(t, claims.IsEmailVerified())
+
+		payload, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[1])
+		assert.NoError(t, err)
+		assert.Contains(t, string(payload), `"email_verified":true`)
+	})
+
+	t.Run("Client ID Claim", func(t *testing.T) {
+		manager := jwt.NewManager([]byte("very_secret_key"), 10*time.Minute)
+		assert.Equal(t, 10*time.Minute, manager.TTL())
+
+		token, err := manager.Generate(uuid.New(), uuid.New(), "127.0.0.1", auth.TokenOptions{ClientID: "spa"})
+		assert.NoError(t, err)
+
+		claims, err := manager.Parse(token)
+		assert.NoError(t, err)
+		assert.Equal(t, "spa", claims.GetClientID())
+	})
+
+	t.Run("Client Scopes And TTL", func(t *testing.T) {
+		manager := jwt.NewManager([]byte("very_secret_key"), 10*time.Minute)
+
+		token, err := manager.Generate(uuid.New(), uuid.New(), "127.0.0.1", auth.TokenOptions{
+			ClientID: "spa",
+			Scopes:   []string{"read", "write"},
+		})
+		assert.NoError(t, err)
+
+		claims, err := manager.Parse(token)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"read", "write"}, claims.GetScopes())
+
+		// Время жизни токена клиента переопределяет время жизни менеджера
+		token, err = manager.Generate(uuid.New(), uuid.New(), "127.0.0.1", auth.TokenOptions{TTL: time.Millisecond})
+		assert.NoError(t, err)
+
+		time.Sleep(2 * time.Millisecond)
+
+		_, err = manager.Parse(token)
+		assert.ErrorIs(t, err, auth.ErrTokenExpired)
+	})
+
+	t.Run("Roles And Permissions", func(t *testing.T) {
+		manager := jwt.NewManager([]byte("very_secret_key"), 10*time.Minute)
+
+		token, err := manager.Generate(uuid.New(), uuid.New(), "127.0.0.1", auth.TokenOptions{})
+		assert.NoError(t, err)
+
+		claims, err := manager.Parse(token)
+		assert.NoError(t, err)
+		assert.Empty(t, claims.GetRoles())
+		assert.Empty(t, claims.GetPermissions())
+
+		token, err = manager.Generate(uuid.New(), uuid.New(), "127.0.0.1", auth.TokenOptions{
+			Roles:       []string{"admin", "support"},
+			Permissions: []string{"orders:read", "users:write"},
+		})
+		assert.NoError(t, err)
+
+		claims, err = manager.Parse(token)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"admin", "support"}, claims.GetRoles())
+		assert.Equal(t, []string{"orders:read", "users:write"}, claims.GetPermissions())
+		assert.Empty(t, claims.GetScopes())
+	})
+
+	t.Run("Client Subject", func(t *testing.T) {
+		manager := jwt.NewManager([]byte("very_secret_key"), 10*time.Minute)
+
+		token, err := manager.Generate(uuid.Nil, uuid.New(), "127.0.0.1", auth.TokenOptions{
+			ClientID:      "backend",
+			ClientSubject: true,
+			Scopes:        []string{"jobs"},
+		})
+		assert.NoError(t, err)
+
+		claims, err := manager.Parse(token)
+		assert.NoError(t, err)
+		assert.True(t, claims.IsClientToken())
+		assert.Equal(t, "backend", claims.GetSubjectID())
+		assert.Equal(t, uuid.Nil, claims.GetGUID())
+
+		guid := uuid.New()
+		token, err = manager.Generate(guid, uuid.New(), "127.0.0.1", auth.TokenOptions{ClientID: "backend"})
+		assert.NoError(t, err)
+
+		claims, err = manager.Parse(token)
+		assert.NoError(t, err)
+		assert.False(t, claims.IsClientToken())
+		assert.Equal(t, guid.String(), claims.GetSubjectID())
+	})
+
+	t.Run("Subject Is Not GUID", func(t *testing.T) {
+		signingKey := []byte("very_secret_key")
+		manager := jwt.NewManager(signingKey, 10*time.Minute)
+
+		// Токен пользователя, субъект которого не является GUID, не принимается
+		token, err := jwtlib.NewWithClaims(jwtlib.SigningMethodHS512, jwtlib.MapClaims{
+			"sub":       "backend",
+			"client_id": "backend",
+			"jti":       uuid.NewString(),
+			"exp":       time.Now().Add(time.Minute).Unix(),
+		}).SignedString(signingKey)
+		assert.NoError(t, err)
+
+		_, err = manager.Parse(token)
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+	})
+
+	t.Run("Actor Claim", func(t *testing.T) {
+		signingKey := []byte("very_secret_key")
+		manager := jwt.NewManager(signingKey, 10*time.Minute)
+
+		token, err := manager.Generate(uuid.New(), uuid.New(), "127.0.0.1", auth.TokenOptions{ClientID: "support"})
+		assert.NoError(t, err)
+
+		claims, err := manager.Parse(token)
+		assert.NoError(t, err)
+		assert.Nil(t, claims.GetActor())
+
+		token, err = manager.Generate(uuid.New(), uuid.New(), "127.0.0.1", auth.TokenOptions{
+			ClientID: "support",
+			Actor:    &auth.Actor{Subject: "support", IsClient: true, ClientID: "support"},
+		})
+		assert.NoError(t, err)
+
+		claims, err = manager.Parse(token)
+		assert.NoError(t, err)
+		assert.Equal(t, &auth.Actor{Subject: "support", IsClient: true, ClientID: "support"}, claims.GetActor())
+
+		agent, agentSession, session := uuid.NewString(), uuid.New(), uuid.New()
+		token, err = manager.Generate(uuid.New(), uuid.New(), "127.0.0.1", auth.TokenOptions{
+			ClientID:  "support",
+			Actor:     &auth.Actor{Subject: agent, ClientID: "support", SessionID: agentSession},
+			SessionID: session,
+		})
+		assert.NoError(t, err)
+
+		claims, err = manager.Parse(token)
+		assert.NoError(t, err)
+		assert.Equal(t, &auth.Actor{Subject: agent, ClientID: "support", SessionID: agentSession}, claims.GetActor())
+		assert.Equal(t, session, claims.GetSessionID())
+
+		// Claim sid должен быть идентификатором сессии
+		token, err = jwtlib.NewWithClaims(jwtlib.SigningMethodHS512, jwtlib.MapClaims{
+			"sub": uuid.NewString(),
+			"act": map[string]any{"sub": agent, "sid": "session"},
+			"jti": uuid.NewString(),
+			"exp": time.Now().Add(time.Minute).Unix(),
+		}).SignedString(signingKey)
+		assert.NoError(t, err)
+
+		_, err = manager.Parse(token)
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+
+		// Claim act без субъекта не принимается
+		token, err = jwtlib.NewWithClaims(jwtlib.SigningMethodHS512, jwtlib.MapClaims{
+			"sub": uuid.NewString(),
+			"act": map[string]any{"client_id": "support"},
+			"jti": uuid.NewString(),
+			"exp": time.Now().Add(time.Minute).Unix(),
+		}).SignedString(signingKey)
+		assert.NoError(t, err)
+
+		_, err = manager.Parse(token)
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
 	})
 
 	t.Run("Token Expired", func(t *testing.T) {
 		manager := jwt.NewManager([]byte("very_secret_key"), 1*time.Millisecond)
 
-		token, err := manager.Generate(uuid.New(), uuid.New(), "127.0.0.1")
+		token, err := manager.Generate(uuid.New(), uuid.New(), "127.0.0.1", auth.TokenOptions{})
 		assert.NoError(t, err)
 		assert.NotEmpty(t, token)
 
@@ -43,12 +239,20 @@ func TestJWTTokenManager_GenerateAndParse(t *testing.T) {
 		assert.Error(t, err)
 		assert.ErrorIs(t, err, auth.ErrTokenExpired)
 		assert.Empty(t, claims)
+
+		// Просроченный токен можно обменять на новый по Refresh токену
+		claims, err = manager.ParseExpired(token)
+		assert.NoError(t, err)
+		assert.Equal(t, "127.0.0.1", claims.GetIP())
+
+		_, err = jwt.NewManager([]byte("another_key"), time.Minute).ParseExpired(token)
+		assert.ErrorIs(t, err, auth.ErrInvalidSignature)
 	})
 
 	t.Run("Invalid Token", func(t *testing.T) {
 		manager := jwt.NewManager([]byte("very_secret_key"), 10*time.Minute)
 
-		token, err := manager.Generate(uuid.New(), uuid.New(), "127.0.0.1")
+		token, err := manager.Generate(uuid.New(), uuid.New(), "127.0.0.1", auth.TokenOptions{})
 		assert.NoError(t, err)
 		assert.NotEmpty(t, token)
 
@@ -59,4 +263,14 @@ func TestJWTTokenManager_GenerateAndParse(t *testing.T) {
 		assert.ErrorIs(t, err, auth.ErrInvalidToken)
 		assert.Empty(t, claims)
 	})
+
+	t.Run("Malformed Token", func(t *testing.T) {
+		manager := jwt.NewManager([]byte("very_secret_key"), 10*time.Minute)
+
+		for _, token := range []string{"", "invalid", "a.b.c"} {
+			claims, err := manager.Parse(token)
+			assert.ErrorIs(t, err, auth.ErrInvalidToken)
+			assert.Empty(t, claims)
+		}
+	})
 }
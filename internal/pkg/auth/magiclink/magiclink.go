@@ -0,0 +1,100 @@
+package magiclink
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformed        = errors.New("malformed link token")
+	ErrInvalidSignature = errors.New("invalid link token signature")
+	ErrExpired          = errors.New("link token expired")
+)
+
+// payloadLength - длина подписываемых данных: ID токена, GUID пользователя и время истечения.
+const payloadLength = 16 + 16 + 8
+
+// encoding - base64url без выравнивания, чтобы токен можно было передать в query параметре ссылки.
+var encoding = base64.RawURLEncoding
+
+// Claims - данные, которые содержит токен ссылки.
+type Claims struct {
+	ID        uuid.UUID // Уникальный ID токена, по нему отслеживается повторное использование
+	GUID      uuid.UUID // GUID пользователя
+	ExpiresAt time.Time // Время истечения
+}
+
+// Signer выпускает и проверяет подписанные HMAC-SHA256 токены одноразовых ссылок.
+// purpose включается в подпись, чтобы токен, выпущенный для одной цели, нельзя было использовать для другой.
+type Signer struct {
+	key     []byte
+	purpose string
+}
+
+func NewSigner(key []byte, purpose string) *Signer {
+	return &Signer{key: key, purpose: purpose}
+}
+
+func (s *Signer) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(s.purpose))
+	mac.Write([]byte{0})
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// Issue выпускает токен для пользователя guid со сроком жизни ttl.
+func (s *Signer) Issue(guid uuid.UUID, ttl time.Duration) (string, Claims, error) {
+	id, err := uuid.NewRandomFromReader(rand.Reader)
+	if err != nil {
+		return "", Claims{}, err
+	}
+
+	claims := Claims{ID: id, GUID: guid, ExpiresAt: time.Now().Add(ttl).Truncate(time.Second)}
+
+	payload := make([]byte, 0, payloadLength)
+	payload = append(payload, claims.ID[:]...)
+	payload = append(payload, claims.GUID[:]...)
+	payload = binary.BigEndian.AppendUint64(payload, uint64(claims.ExpiresAt.Unix()))
+
+	return encoding.EncodeToString(payload) + "." + encoding.EncodeToString(s.sign(payload)), claims, nil
+}
+
+// Parse проверяет подпись и срок действия токена на момент now и возвращает его данные.
+func (s *Signer) Parse(token string, now time.Time) (Claims, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return Claims{}, ErrMalformed
+	}
+
+	payload, err := encoding.DecodeString(encodedPayload)
+	if err != nil || len(payload) != payloadLength {
+		return Claims{}, ErrMalformed
+	}
+
+	signature, err := encoding.DecodeString(encodedSignature)
+	if err != nil {
+		return Claims{}, ErrMalformed
+	}
+
+	if !hmac.Equal(signature, s.sign(payload)) {
+		return Claims{}, ErrInvalidSignature
+	}
+
+	claims := Claims{ExpiresAt: time.Unix(int64(binary.BigEndian.Uint64(payload[32:])), 0)}
+	copy(claims.ID[:], payload[:16])
+	copy(claims.GUID[:], payload[16:32])
+
+	if !now.Before(claims.ExpiresAt) {
+		return Claims{}, ErrExpired
+	}
+
+	return claims, nil
+}
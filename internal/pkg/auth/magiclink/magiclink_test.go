@@ -0,0 +1,65 @@
+package magiclink
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestSigner(t *testing.T) {
+	signer := NewSigner([]byte("secret"), "login")
+	guid := uuid.New()
+
+	token, issued, err := signer.Issue(guid, 10*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, guid, issued.GUID)
+
+	t.Run("valid", func(t *testing.T) {
+		claims, err := signer.Parse(token, time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, issued.ID, claims.ID)
+		assert.Equal(t, guid, claims.GUID)
+		assert.True(t, issued.ExpiresAt.Equal(claims.ExpiresAt))
+	})
+
+	t.Run("unique ids", func(t *testing.T) {
+		_, other, err := signer.Issue(guid, 10*time.Minute)
+		require.NoError(t, err)
+		assert.NotEqual(t, issued.ID, other.ID)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		_, err := signer.Parse(token, time.Now().Add(11*time.Minute))
+		assert.ErrorIs(t, err, ErrExpired)
+	})
+
+	t.Run("another key", func(t *testing.T) {
+		_, err := NewSigner([]byte("other"), "login").Parse(token, time.Now())
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("another purpose", func(t *testing.T) {
+		_, err := NewSigner([]byte("secret"), "verify").Parse(token, time.Now())
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("tampered payload", func(t *testing.T) {
+		tampered := []byte(token)
+		if tampered[0] == 'A' {
+			tampered[0] = 'B'
+		} else {
+			tampered[0] = 'A'
+		}
+		_, err := signer.Parse(string(tampered), time.Now())
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		for _, value := range []string{"", "abc", "abc.def", "!!!.AAAA"} {
+			_, err := signer.Parse(value, time.Now())
+			assert.ErrorIs(t, err, ErrMalformed, value)
+		}
+	})
+}
@@ -0,0 +1,224 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// CodeChallengeMethodS256 - единственный поддерживаемый метод PKCE (RFC 7636, раздел 4.2).
+// Метод plain не поддерживается, так как не защищает от перехвата кода авторизации.
+const CodeChallengeMethodS256 = "S256"
+
+// Коды ошибок OAuth 2.0 (RFC 6749, разделы 4.1.2.1 и 5.2).
+const (
+	ErrorInvalidRequest          = "invalid_request"
+	ErrorInvalidClient           = "invalid_client"
+	ErrorUnauthorizedClient      = "unauthorized_client"
+	ErrorInvalidScope            = "invalid_scope"
+	ErrorInvalidGrant            = "invalid_grant"
+	ErrorUnsupportedGrantType    = "unsupported_grant_type"
+	ErrorUnsupportedResponseType = "unsupported_response_type"
+	ErrorAccessDenied            = "access_denied"
+	ErrorServerError             = "server_error"
+)
+
+// Коды ошибок опроса эндпоинта /token устройством (RFC 8628, раздел 3.5).
+const (
+	ErrorAuthorizationPending = "authorization_pending"
+	ErrorSlowDown             = "slow_down"
+	ErrorExpiredToken         = "expired_token"
+)
+
+// Типы токенов, участвующих в обмене (RFC 8693, раздел 3).
+const (
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token" // Access токен, выданный сервисом
+	// GUID пользователя, от имени которого действует сотрудник поддержки, без токена самого пользователя
+	TokenTypeUserID = "urn:medods:params:oauth:token-type:user_id"
+)
+
+// Коды ошибок динамической регистрации клиентов (RFC 7591, раздел 3.2.2).
+const (
+	ErrorInvalidRedirectURI    = "invalid_redirect_uri"
+	ErrorInvalidClientMetadata = "invalid_client_metadata"
+)
+
+const (
+	minVerifierLength = 43 // Минимальная длина code_verifier (RFC 7636, раздел 4.1)
+	maxVerifierLength = 128
+	// s256ChallengeLength - длина code_challenge метода S256: SHA-256 хеш в base64url без выравнивания.
+	s256ChallengeLength = 43
+)
+
+// isUnreserved сообщает, относится ли символ к незарезервированным символам URI (RFC 3986, раздел 2.3),
+// из которых состоит code_verifier.
+func isUnreserved(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// ValidCodeVerifier проверяет длину и алфавит code_verifier.
+func ValidCodeVerifier(verifier string) bool {
+	if len(verifier) < minVerifierLength || len(verifier) > maxVerifierLength {
+		return false
+	}
+	for i := 0; i < len(verifier); i++ {
+		if !isUnreserved(verifier[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidCodeChallenge проверяет, что code_challenge является SHA-256 хешем в base64url без выравнивания.
+func ValidCodeChallenge(challenge string) bool {
+	if len(challenge) != s256ChallengeLength {
+		return false
+	}
+	_, err := base64.RawURLEncoding.DecodeString(challenge)
+	return err == nil
+}
+
+// S256Challenge вычисляет code_challenge для code_verifier методом S256.
+func S256Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// VerifyCodeChallenge проверяет, что code_verifier соответствует code_challenge метода S256.
+// Сравнение выполняется за постоянное время.
+func VerifyCodeChallenge(verifier, challenge string) bool {
+	if !ValidCodeVerifier(verifier) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(S256Challenge(verifier)), []byte(challenge)) == 1
+}
+
+// ValidRedirectURI проверяет, что адрес перенаправления является абсолютным URI без фрагмента (RFC 6749, раздел 3.1.2).
+// Допускаются собственные схемы мобильных приложений, например com.example.app:/callback.
+func ValidRedirectURI(raw string) bool {
+	uri, err := url.Parse(raw)
+	if err != nil || !uri.IsAbs() || uri.Fragment != "" || strings.Contains(raw, "#") {
+		return false
+	}
+	if uri.Scheme == "http" || uri.Scheme == "https" {
+		return uri.Host != ""
+	}
+	return true
+}
+
+// RedirectURL добавляет параметры к query адреса перенаправления, сохраняя уже имеющиеся в нем параметры.
+func RedirectURL(redirectURI string, params url.Values) string {
+	uri, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+
+	query := uri.Query()
+	for key, values := range params {
+		for _, value := range values {
+			if value != "" {
+				query.Add(key, value)
+			}
+		}
+	}
+	uri.RawQuery = query.Encode()
+
+	return uri.String()
+}
+
+// isScopeChar сообщает, допустим ли символ в имени области доступа (RFC 6749, раздел 3.3).
+func isScopeChar(c byte) bool {
+	return c == 0x21 || c >= 0x23 && c <= 0x5B || c >= 0x5D && c <= 0x7E
+}
+
+// ValidScope проверяет имя одной области доступа.
+func ValidScope(scope string) bool {
+	if scope == "" {
+		return false
+	}
+	for i := 0; i < len(scope); i++ {
+		if !isScopeChar(scope[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseScope разбирает параметр scope - список областей доступа через пробел - без повторов.
+// Возвращает false, если имя какой-либо области недопустимо.
+func ParseScope(raw string) ([]string, bool) {
+	var scopes []string
+	for _, scope := range strings.Split(raw, " ") {
+		if scope == "" {
+			continue
+		}
+		if !ValidScope(scope) {
+			return nil, false
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, true
+}
+
+// FormatScope объединяет области доступа в значение параметра scope.
+func FormatScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+const (
+	// userCodeAlphabet - алфавит пользовательского кода устройства: согласные латинского алфавита без похожих
+	// друг на друга букв, чтобы код было легко прочитать с экрана и ввести без ошибок (RFC 8628, раздел 6.1).
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength   = 8 // Длина пользовательского кода без разделителя, около 34 бит энтропии
+)
+
+// GenerateUserCode генерирует пользовательский код устройства в нормализованном виде, например BDFHKLMN.
+func GenerateUserCode() (string, error) {
+	random := make([]byte, userCodeLength)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	code := make([]byte, userCodeLength)
+	for i, b := range random {
+		// 256 не делится на 20 нацело, но смещение распределения пренебрежимо мало для кода с ограниченным числом попыток
+		code[i] = userCodeAlphabet[int(b)%len(userCodeAlphabet)]
+	}
+	return string(code), nil
+}
+
+// NormalizeUserCode приводит введенный пользователем код к нормализованному виду:
+// переводит буквы в верхний регистр и удаляет дефисы и пробелы.
+// Возвращает false, если код содержит недопустимые символы или имеет неверную длину.
+func NormalizeUserCode(raw string) (string, bool) {
+	code := make([]byte, 0, userCodeLength)
+	for _, r := range strings.ToUpper(raw) {
+		if r == '-' || r == ' ' {
+			continue
+		}
+		if r > 'Z' || !strings.ContainsRune(userCodeAlphabet, r) {
+			return "", false
+		}
+		code = append(code, byte(r))
+	}
+
+	if len(code) != userCodeLength {
+		return "", false
+	}
+	return string(code), true
+}
+
+// FormatUserCode разделяет нормализованный код дефисом пополам для показа пользователю, например BDFH-KLMN.
+func FormatUserCode(code string) string {
+	if len(code) != userCodeLength {
+		return code
+	}
+	return code[:userCodeLength/2] + "-" + code[userCodeLength/2:]
+}
@@ -0,0 +1,106 @@
+package oauth
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	// Пример из RFC 7636, приложение B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	assert.Equal(t, challenge, S256Challenge(verifier))
+	assert.True(t, ValidCodeChallenge(challenge))
+	assert.True(t, VerifyCodeChallenge(verifier, challenge))
+
+	assert.False(t, VerifyCodeChallenge(verifier+"x", challenge))
+	assert.False(t, VerifyCodeChallenge(verifier, S256Challenge(verifier+"x")))
+}
+
+func TestValidCodeVerifier(t *testing.T) {
+	assert.True(t, ValidCodeVerifier(strings.Repeat("a", 43)))
+	assert.True(t, ValidCodeVerifier(strings.Repeat("A-._~9", 21)))
+	assert.False(t, ValidCodeVerifier(strings.Repeat("a", 42)))
+	assert.False(t, ValidCodeVerifier(strings.Repeat("a", 129)))
+	assert.False(t, ValidCodeVerifier(strings.Repeat("a", 42)+"+"))
+}
+
+func TestValidCodeChallenge(t *testing.T) {
+	assert.False(t, ValidCodeChallenge("short"))
+	assert.False(t, ValidCodeChallenge(strings.Repeat("=", 43)))
+}
+
+func TestValidRedirectURI(t *testing.T) {
+	valid := []string{
+		"https://app.example.com/callback",
+		"http://localhost:3000/callback?tenant=1",
+		"com.example.app:/oauth/callback",
+	}
+	for _, uri := range valid {
+		assert.True(t, ValidRedirectURI(uri), uri)
+	}
+
+	invalid := []string{
+		"",
+		"/callback",
+		"https://app.example.com/callback#fragment",
+		"https:///callback",
+		"://broken",
+	}
+	for _, uri := range invalid {
+		assert.False(t, ValidRedirectURI(uri), uri)
+	}
+}
+
+func TestRedirectURL(t *testing.T) {
+	redirect := RedirectURL("https://app.example.com/callback?tenant=1", url.Values{
+		"code":  {"abc"},
+		"state": {""},
+	})
+
+	uri, err := url.Parse(redirect)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", uri.Query().Get("tenant"))
+	assert.Equal(t, "abc", uri.Query().Get("code"))
+	assert.False(t, uri.Query().Has("state"))
+}
+
+func TestParseScope(t *testing.T) {
+	scopes, ok := ParseScope("  read write read profile:email ")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"read", "write", "profile:email"}, scopes)
+	assert.Equal(t, "read write profile:email", FormatScope(scopes))
+
+	scopes, ok = ParseScope("")
+	assert.True(t, ok)
+	assert.Empty(t, scopes)
+
+	_, ok = ParseScope(`read "write"`)
+	assert.False(t, ok)
+}
+
+func TestUserCode(t *testing.T) {
+	code, err := GenerateUserCode()
+	assert.NoError(t, err)
+
+	normalized, ok := NormalizeUserCode(code)
+	assert.True(t, ok)
+	assert.Equal(t, code, normalized)
+
+	formatted := FormatUserCode(code)
+	assert.Len(t, formatted, 9)
+	assert.Equal(t, "-", formatted[4:5])
+
+	normalized, ok = NormalizeUserCode(" bdfh-klmn ")
+	assert.True(t, ok)
+	assert.Equal(t, "BDFHKLMN", normalized)
+
+	for _, raw := range []string{"", "BDFH-KLM", "BDFH-KLMNP", "ABCD-EFGH", "BDFH-KLM1", "BDFH_KLMN"} {
+		_, ok := NormalizeUserCode(raw)
+		assert.False(t, ok, raw)
+	}
+}
@@ -0,0 +1,116 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	MinLength = 8   // Минимальная длина пароля в символах
+	MaxLength = 128 // Максимальная длина пароля в символах
+
+	// Параметры Argon2id, рекомендованные OWASP
+	argonTime    = 2
+	argonMemory  = 19 * 1024 // КиБ
+	argonThreads = 1
+	argonKeyLen  = 32
+	saltLength   = 16
+)
+
+var (
+	ErrInvalidPassword = errors.New("password does not meet requirements")
+	ErrInvalidHash     = errors.New("invalid password hash format")
+)
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// Validate проверяет, что пароль удовлетворяет требованиям к длине.
+// Возвращает ErrInvalidPassword, если пароль слишком короткий или слишком длинный.
+func Validate(password string) error {
+	length := utf8.RuneCountInString(password)
+	if !utf8.ValidString(password) || length < MinLength || length > MaxLength {
+		return ErrInvalidPassword
+	}
+	return nil
+}
+
+// TooLong сообщает, превышает ли пароль максимальную длину MaxLength.
+// Такой пароль не может совпасть ни с одним сохраненным, поэтому его проверку
+// можно отклонить до вычисления Argon2id.
+func TooLong(password string) bool {
+	return utf8.RuneCountInString(password) > MaxLength
+}
+
+// Hash хеширует пароль с помощью Argon2id со случайной солью.
+// Возвращает хеш в формате PHC: $argon2id$v=19$m=...,t=...,p=...$<соль>$<хеш>
+func Hash(password string) (string, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify проверяет пароль по хешу, полученному от Hash.
+// Параметры Argon2id берутся из хеша, поэтому изменение параметров не ломает проверку старых хешей.
+// Хеши сравниваются за постоянное время.
+func Verify(password, encoded string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, ErrInvalidHash
+	}
+
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, ErrInvalidHash
+	}
+
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(expected) == 0 {
+		return false, ErrInvalidHash
+	}
+
+	key := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(expected)))
+
+	return subtle.ConstantTimeCompare(key, expected) == 1, nil
+}
+
+// VerifyDummy выполняет проверку пароля по заранее вычисленному хешу и всегда возвращает false.
+// Используется, когда пользователь не найден, чтобы время ответа не отличалось от проверки реального пароля.
+func VerifyDummy(password string) bool {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = Hash("dummy-password")
+	})
+
+	_, _ = Verify(password, dummyHash)
+
+	return false
+}
@@ -0,0 +1,62 @@
+package password_test
+
+import (
+	"github.com/maksemen2/medods-task/internal/pkg/auth/password"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, password.Validate("correct horse"))
+	assert.NoError(t, password.Validate(strings.Repeat("п", password.MinLength)))
+	assert.ErrorIs(t, password.Validate("short"), password.ErrInvalidPassword)
+	assert.ErrorIs(t, password.Validate(strings.Repeat("a", password.MaxLength+1)), password.ErrInvalidPassword)
+	assert.ErrorIs(t, password.Validate("bad\xffutf8"), password.ErrInvalidPassword)
+}
+
+func TestTooLong(t *testing.T) {
+	assert.False(t, password.TooLong(strings.Repeat("п", password.MaxLength)))
+	assert.True(t, password.TooLong(strings.Repeat("a", password.MaxLength+1)))
+}
+
+func TestHashAndVerify(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		hash, err := password.Hash("correct horse")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$"))
+
+		ok, err := password.Verify("correct horse", hash)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("Wrong Password", func(t *testing.T) {
+		hash, err := password.Hash("correct horse")
+		require.NoError(t, err)
+
+		ok, err := password.Verify("battery staple", hash)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("Unique Salt", func(t *testing.T) {
+		first, err := password.Hash("correct horse")
+		require.NoError(t, err)
+		second, err := password.Hash("correct horse")
+		require.NoError(t, err)
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("Invalid Hash", func(t *testing.T) {
+		for _, hash := range []string{"", "plain", "$2a$10$bcrypt", "$argon2id$v=19$m=1,t=1,p=1$!!!$abc"} {
+			_, err := password.Verify("correct horse", hash)
+			assert.ErrorIs(t, err, password.ErrInvalidHash, hash)
+		}
+	})
+
+	t.Run("Dummy", func(t *testing.T) {
+		assert.False(t, password.VerifyDummy("dummy-password"))
+	})
+}
@@ -0,0 +1,61 @@
+package recovery
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+const (
+	Count      = 10 // Количество кодов в наборе
+	CodeLength = 10 // Длина кода без разделителя, 50 бит энтропии
+)
+
+// alphabet - 32 символа без легко путаемых 0/O и 1/I, каждый символ кодирует 5 бит
+const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// Generate генерирует набор из Count случайных кодов восстановления в формате XXXXX-XXXXX.
+func Generate() ([]string, error) {
+	codes := make([]string, Count)
+	raw := make([]byte, CodeLength)
+
+	for i := range codes {
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+
+		var code strings.Builder
+		for j, b := range raw {
+			if j == CodeLength/2 {
+				code.WriteByte('-')
+			}
+			// 256 делится на 32 без остатка, поэтому распределение символов равномерно
+			code.WriteByte(alphabet[int(b)%len(alphabet)])
+		}
+		codes[i] = code.String()
+	}
+
+	return codes, nil
+}
+
+// Normalize приводит введенный пользователем код к каноническому виду без разделителя и в верхнем регистре.
+// Пробелы и дефисы игнорируются. Возвращает false, если код не может быть кодом восстановления.
+func Normalize(code string) (string, bool) {
+	var normalized strings.Builder
+
+	for _, r := range strings.ToUpper(code) {
+		switch {
+		case r == '-' || r == ' ':
+			continue
+		case strings.ContainsRune(alphabet, r):
+			normalized.WriteRune(r)
+		default:
+			return "", false
+		}
+	}
+
+	if normalized.Len() != CodeLength {
+		return "", false
+	}
+
+	return normalized.String(), true
+}
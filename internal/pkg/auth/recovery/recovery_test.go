@@ -0,0 +1,52 @@
+package recovery_test
+
+import (
+	"github.com/maksemen2/medods-task/internal/pkg/auth/recovery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	codes, err := recovery.Generate()
+	require.NoError(t, err)
+	require.Len(t, codes, recovery.Count)
+
+	format := regexp.MustCompile(`^[A-HJ-NP-Z2-9]{5}-[A-HJ-NP-Z2-9]{5}$`)
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		assert.Regexp(t, format, code)
+		assert.False(t, seen[code], "duplicate code %s", code)
+		seen[code] = true
+
+		normalized, ok := recovery.Normalize(code)
+		assert.True(t, ok)
+		assert.Len(t, normalized, recovery.CodeLength)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+		ok       bool
+	}{
+		{"canonical", "ABCDE-FGH23", "ABCDEFGH23", true},
+		{"lowercase with spaces", " abcde fgh23 ", "ABCDEFGH23", true},
+		{"without separator", "abcdefgh23", "ABCDEFGH23", true},
+		{"too short", "ABCDE-FGH2", "", false},
+		{"too long", "ABCDE-FGH234", "", false},
+		{"ambiguous characters", "ABCDE-FGH01", "", false},
+		{"totp code", "123456", "", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			normalized, ok := recovery.Normalize(tc.input)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, normalized)
+		})
+	}
+}
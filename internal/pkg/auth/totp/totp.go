@@ -0,0 +1,93 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	SecretLength = 20               // Длина секрета в байтах, рекомендованная RFC 4226 для HMAC-SHA1
+	Digits       = 6                // Количество цифр в коде
+	Period       = 30 * time.Second // Длительность временного шага
+	Skew         = 1                // Количество соседних шагов, коды которых также принимаются
+)
+
+// digitsModulo - 10^Digits
+const digitsModulo = 1_000_000
+
+// encoding - base32 без выравнивания, используемый приложениями-аутентификаторами
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret генерирует новый случайный секрет.
+func GenerateSecret() ([]byte, error) {
+	secret := make([]byte, SecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// EncodeSecret кодирует секрет в base32 для ручного ввода в приложение-аутентификатор.
+func EncodeSecret(secret []byte) string {
+	return encoding.EncodeToString(secret)
+}
+
+// Step возвращает номер временного шага для момента времени t.
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// Code вычисляет код для указанного временного шага по RFC 6238 (HMAC-SHA1).
+func Code(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Динамическое усечение, RFC 4226 раздел 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", Digits, value%digitsModulo)
+}
+
+// Validate проверяет код для момента времени t с допуском Skew шагов в обе стороны,
+// чтобы компенсировать рассинхронизацию часов.
+// Возвращает номер шага, которому соответствует код, для защиты от повторного использования.
+func Validate(secret []byte, code string, t time.Time) (int64, bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	current := Step(t)
+	for step := current - Skew; step <= current+Skew; step++ {
+		if subtle.ConstantTimeCompare([]byte(Code(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// ProvisioningURI формирует URI otpauth://, который приложения-аутентификаторы принимают в виде QR-кода.
+func ProvisioningURI(issuer, account string, secret []byte) string {
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+
+	query := url.Values{}
+	query.Set("secret", EncodeSecret(secret))
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(Digits))
+	query.Set("period", fmt.Sprint(int(Period/time.Second)))
+
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
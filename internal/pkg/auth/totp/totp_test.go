@@ -0,0 +1,74 @@
+package totp_test
+
+import (
+	"github.com/maksemen2/medods-task/internal/pkg/auth/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret - секрет из тестовых векторов RFC 6238 для SHA1
+var rfcSecret = []byte("12345678901234567890")
+
+func TestCode(t *testing.T) {
+	// Тестовые векторы RFC 6238, приложение B, усеченные до 6 цифр
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for unix, expected := range vectors {
+		assert.Equal(t, expected, totp.Code(rfcSecret, totp.Step(time.Unix(unix, 0))), unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	t.Run("Current step", func(t *testing.T) {
+		step, ok := totp.Validate(rfcSecret, "050471", now)
+		assert.True(t, ok)
+		assert.Equal(t, totp.Step(now), step)
+	})
+
+	t.Run("Adjacent step", func(t *testing.T) {
+		previous := totp.Code(rfcSecret, totp.Step(now)-1)
+		step, ok := totp.Validate(rfcSecret, previous, now)
+		assert.True(t, ok)
+		assert.Equal(t, totp.Step(now)-1, step)
+	})
+
+	t.Run("Outside skew", func(t *testing.T) {
+		old := totp.Code(rfcSecret, totp.Step(now)-2)
+		_, ok := totp.Validate(rfcSecret, old, now)
+		assert.False(t, ok)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		_, ok := totp.Validate(rfcSecret, "12345", now)
+		assert.False(t, ok)
+	})
+}
+
+func TestProvisioningURI(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, totp.SecretLength)
+
+	raw := totp.ProvisioningURI("Medods Auth", "user@example.com", secret)
+
+	parsed, err := url.Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", parsed.Scheme)
+	assert.Equal(t, "totp", parsed.Host)
+	assert.Equal(t, "/Medods Auth:user@example.com", parsed.Path)
+	assert.Equal(t, totp.EncodeSecret(secret), parsed.Query().Get("secret"))
+	assert.Equal(t, "Medods Auth", parsed.Query().Get("issuer"))
+	assert.Equal(t, "6", parsed.Query().Get("digits"))
+}
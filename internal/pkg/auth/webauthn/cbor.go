@@ -0,0 +1,174 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// maxCBORDepth - максимальная вложенность CBOR структур. Структуры WebAuthn не бывают глубже нескольких уровней.
+const maxCBORDepth = 8
+
+var errInvalidCBOR = errors.New("invalid cbor")
+
+// decodeCBOR декодирует первый CBOR элемент из data и возвращает его вместе с количеством прочитанных байт.
+// Поддерживается подмножество CBOR, используемое в WebAuthn (CTAP2 canonical CBOR): целые числа,
+// байтовые и текстовые строки, массивы, словари с целыми или строковыми ключами, true, false и null.
+// Элементы неопределенной длины и числа с плавающей точкой не поддерживаются.
+//
+// Целые числа возвращаются как int64, байтовые строки - как []byte, текстовые - как string,
+// массивы - как []any, словари - как map[any]any.
+func decodeCBOR(data []byte) (any, int, error) {
+	d := cborDecoder{data: data}
+	value, err := d.decode(0)
+	if err != nil {
+		return nil, 0, err
+	}
+	return value, d.pos, nil
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+// argument читает заголовок элемента и возвращает его основной тип и аргумент.
+func (d *cborDecoder) argument() (byte, uint64, error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, errInvalidCBOR
+	}
+
+	head := d.data[d.pos]
+	d.pos++
+	major, info := head>>5, head&0x1f
+
+	var size int
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, errInvalidCBOR
+	}
+
+	if len(d.data)-d.pos < size {
+		return 0, 0, errInvalidCBOR
+	}
+
+	raw := d.data[d.pos : d.pos+size]
+	d.pos += size
+
+	switch size {
+	case 1:
+		return major, uint64(raw[0]), nil
+	case 2:
+		return major, uint64(binary.BigEndian.Uint16(raw)), nil
+	case 4:
+		return major, uint64(binary.BigEndian.Uint32(raw)), nil
+	default:
+		return major, binary.BigEndian.Uint64(raw), nil
+	}
+}
+
+// bytes читает n байт содержимого строки.
+func (d *cborDecoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, errInvalidCBOR
+	}
+	raw := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return raw, nil
+}
+
+func (d *cborDecoder) decode(depth int) (any, error) {
+	if depth > maxCBORDepth {
+		return nil, errInvalidCBOR
+	}
+
+	major, arg, err := d.argument()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case 0: // Неотрицательное целое
+		if arg > math.MaxInt64 {
+			return nil, errInvalidCBOR
+		}
+		return int64(arg), nil
+	case 1: // Отрицательное целое -1-arg
+		if arg > math.MaxInt64 {
+			return nil, errInvalidCBOR
+		}
+		return -1 - int64(arg), nil
+	case 2: // Байтовая строка
+		raw, err := d.bytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), raw...), nil
+	case 3: // Текстовая строка
+		raw, err := d.bytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		return string(raw), nil
+	case 4: // Массив
+		// Каждый элемент занимает хотя бы один байт, что ограничивает размер выделяемой памяти
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, errInvalidCBOR
+		}
+		items := make([]any, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case 5: // Словарь
+		if arg > uint64(len(d.data)-d.pos)/2 {
+			return nil, errInvalidCBOR
+		}
+		entries := make(map[any]any, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, errInvalidCBOR
+			}
+			if _, ok := entries[key]; ok {
+				return nil, errInvalidCBOR
+			}
+			value, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			entries[key] = value
+		}
+		return entries, nil
+	case 7: // Простые значения
+		switch arg {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22:
+			return nil, nil
+		}
+	}
+
+	return nil, errInvalidCBOR
+}
@@ -0,0 +1,53 @@
+package webauthn
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    []byte
+		expected any
+	}{
+		{"small uint", []byte{0x17}, int64(23)},
+		{"uint16", []byte{0x19, 0x03, 0xe8}, int64(1000)},
+		{"negative", []byte{0x26}, int64(-7)},
+		{"bytes", []byte{0x43, 1, 2, 3}, []byte{1, 2, 3}},
+		{"text", []byte{0x64, 'n', 'o', 'n', 'e'}, "none"},
+		{"array", []byte{0x82, 0x01, 0xf5}, []any{int64(1), true}},
+		{"map", []byte{0xa2, 0x01, 0x02, 0x61, 'a', 0xf6}, map[any]any{int64(1): int64(2), "a": nil}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			value, n, err := decodeCBOR(tc.input)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, value)
+			assert.Equal(t, len(tc.input), n)
+		})
+	}
+}
+
+func TestDecodeCBOR_Invalid(t *testing.T) {
+	cases := map[string][]byte{
+		"empty":              {},
+		"truncated bytes":    {0x43, 1, 2},
+		"truncated argument": {0x19, 0x03},
+		"indefinite length":  {0x5f},
+		"float":              {0xfa, 0, 0, 0, 0},
+		"duplicate key":      {0xa2, 0x01, 0x01, 0x01, 0x02},
+		"bytes key":          {0xa1, 0x41, 0x00, 0x01},
+		"huge array":         {0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"too deep":           {0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x00},
+	}
+
+	for name, input := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := decodeCBOR(input)
+			assert.ErrorIs(t, err, errInvalidCBOR)
+		})
+	}
+}
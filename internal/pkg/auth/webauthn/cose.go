@@ -0,0 +1,99 @@
+package webauthn
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"math/big"
+)
+
+// Параметры ключей COSE (RFC 9053), поддерживаемые сервисом.
+const (
+	coseLabelKty = 1
+	coseLabelAlg = 3
+	coseLabelCrv = -1
+	coseLabelX   = -2
+	coseLabelY   = -3
+
+	coseKtyOKP = 1
+	coseKtyEC2 = 2
+
+	coseCrvP256    = 1
+	coseCrvEd25519 = 6
+)
+
+// Алгоритмы подписи COSE, поддерживаемые сервисом.
+const (
+	AlgES256 = -7 // ECDSA P-256 с SHA-256
+	AlgEdDSA = -8 // Ed25519
+)
+
+// SupportedAlgorithms - алгоритмы, которые сервис предлагает аутентификатору при регистрации, в порядке предпочтения.
+var SupportedAlgorithms = []int64{AlgES256, AlgEdDSA}
+
+// publicKey - открытый ключ учетных данных, извлеченный из ключа COSE.
+type publicKey struct {
+	ecdsa   *ecdsa.PublicKey
+	ed25519 ed25519.PublicKey
+}
+
+// parsePublicKey разбирает ключ COSE в кодировке CBOR.
+// Возвращает ErrUnsupportedKey для неподдерживаемых типов ключей и алгоритмов.
+func parsePublicKey(cose []byte) (*publicKey, error) {
+	value, n, err := decodeCBOR(cose)
+	if err != nil || n != len(cose) {
+		return nil, ErrInvalidResponse
+	}
+	return publicKeyFromCOSE(value)
+}
+
+func publicKeyFromCOSE(value any) (*publicKey, error) {
+	key, ok := value.(map[any]any)
+	if !ok {
+		return nil, ErrInvalidResponse
+	}
+
+	kty, _ := key[int64(coseLabelKty)].(int64)
+	alg, _ := key[int64(coseLabelAlg)].(int64)
+	crv, _ := key[int64(coseLabelCrv)].(int64)
+	x, _ := key[int64(coseLabelX)].([]byte)
+
+	switch {
+	case kty == coseKtyEC2 && alg == AlgES256 && crv == coseCrvP256:
+		y, _ := key[int64(coseLabelY)].([]byte)
+		if len(x) != 32 || len(y) != 32 {
+			return nil, ErrInvalidResponse
+		}
+
+		// ecdh проверяет, что точка лежит на кривой
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, ErrInvalidResponse
+		}
+
+		return &publicKey{ecdsa: &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}}, nil
+	case kty == coseKtyOKP && alg == AlgEdDSA && crv == coseCrvEd25519:
+		if len(x) != ed25519.PublicKeySize {
+			return nil, ErrInvalidResponse
+		}
+		return &publicKey{ed25519: ed25519.PublicKey(x)}, nil
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+// verify проверяет подпись signature над data.
+// Подписи ES256 передаются аутентификатором в кодировке ASN.1 DER.
+func (k *publicKey) verify(data, signature []byte) bool {
+	if k.ecdsa != nil {
+		digest := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(k.ecdsa, digest[:], signature)
+	}
+	return ed25519.Verify(k.ed25519, data, signature)
+}
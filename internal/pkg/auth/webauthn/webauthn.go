@@ -0,0 +1,404 @@
+package webauthn
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ChallengeLength - длина challenge церемоний WebAuthn в байтах.
+const ChallengeLength = 32
+
+// maxCredentialIDLength - максимальная длина ID учетных данных по спецификации WebAuthn.
+const maxCredentialIDLength = 1023
+
+// Флаги authenticatorData.
+const (
+	flagUserPresent   = 0x01
+	flagUserVerified  = 0x04
+	flagAttestedData  = 0x40
+	flagExtensionData = 0x80
+)
+
+// Типы clientDataJSON.
+const (
+	clientDataTypeCreate = "webauthn.create"
+	clientDataTypeGet    = "webauthn.get"
+)
+
+var (
+	ErrInvalidResponse        = errors.New("invalid webauthn response")
+	ErrUnsupportedKey         = errors.New("unsupported public key algorithm")
+	ErrUnsupportedAttestation = errors.New("unsupported attestation format")
+	ErrChallengeMismatch      = errors.New("challenge mismatch")
+	ErrOriginMismatch         = errors.New("origin mismatch")
+	ErrRPIDMismatch           = errors.New("relying party id mismatch")
+	ErrUserNotVerified        = errors.New("user presence or verification missing")
+	ErrInvalidSignature       = errors.New("invalid assertion signature")
+	ErrSignCountRollback      = errors.New("signature counter did not increase")
+)
+
+// encoding - base64url без выравнивания, которым кодируются бинарные поля WebAuthn в JSON.
+var encoding = base64.RawURLEncoding
+
+// RelyingParty проверяет церемонии регистрации и аутентификации WebAuthn для одного сайта.
+type RelyingParty struct {
+	id      string
+	name    string
+	origins map[string]struct{}
+	idHash  [32]byte
+	timeout time.Duration
+}
+
+// NewRelyingParty создает RelyingParty с идентификатором id (доменом сайта), отображаемым именем name
+// и списком origin, с которых разрешены церемонии. timeout передается браузеру как время ожидания пользователя.
+func NewRelyingParty(id, name string, origins []string, timeout time.Duration) (*RelyingParty, error) {
+	if id == "" || len(origins) == 0 {
+		return nil, errors.New("relying party id and origins are required")
+	}
+
+	allowed := make(map[string]struct{}, len(origins))
+	for _, origin := range origins {
+		allowed[origin] = struct{}{}
+	}
+
+	if name == "" {
+		name = id
+	}
+
+	return &RelyingParty{
+		id:      id,
+		name:    name,
+		origins: allowed,
+		idHash:  sha256.Sum256([]byte(id)),
+		timeout: timeout,
+	}, nil
+}
+
+// NewChallenge генерирует новый случайный challenge.
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, ChallengeLength)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// User - пользователь, для которого регистрируются учетные данные.
+type User struct {
+	ID          []byte // Идентификатор пользователя (user handle), возвращается аутентификатором при входе
+	Name        string // Имя учетной записи, например email
+	DisplayName string // Отображаемое имя
+}
+
+// RelyingPartyEntity - описание сайта в параметрах регистрации.
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserEntity - описание пользователя в параметрах регистрации.
+type UserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// CredentialParameter - допустимый тип учетных данных.
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+// CredentialDescriptor - ссылка на зарегистрированные учетные данные.
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// AuthenticatorSelection - требования к аутентификатору.
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions - параметры navigator.credentials.create() в JSON представлении WebAuthn Level 3.
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout,omitempty"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials,omitempty"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions - параметры navigator.credentials.get() в JSON представлении WebAuthn Level 3.
+type RequestOptions struct {
+	Challenge        string `json:"challenge"`
+	RPID             string `json:"rpId"`
+	Timeout          int64  `json:"timeout,omitempty"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions возвращает параметры регистрации новых учетных данных (passkey) для пользователя.
+// exclude - ID уже зарегистрированных учетных данных пользователя, чтобы аутентификатор не создавал их повторно.
+func (rp *RelyingParty) CreationOptions(challenge []byte, user User, exclude [][]byte) *CreationOptions {
+	options := &CreationOptions{
+		Challenge: encoding.EncodeToString(challenge),
+		RP:        RelyingPartyEntity{ID: rp.id, Name: rp.name},
+		User: UserEntity{
+			ID:          encoding.EncodeToString(user.ID),
+			Name:        user.Name,
+			DisplayName: user.DisplayName,
+		},
+		Timeout: rp.timeout.Milliseconds(),
+		// Учетные данные должны быть обнаруживаемыми, чтобы вход не требовал ввода имени пользователя
+		AuthenticatorSelection: AuthenticatorSelection{ResidentKey: "required", UserVerification: "required"},
+		Attestation:            "none",
+	}
+
+	for _, alg := range SupportedAlgorithms {
+		options.PubKeyCredParams = append(options.PubKeyCredParams, CredentialParameter{Type: "public-key", Alg: alg})
+	}
+
+	for _, id := range exclude {
+		options.ExcludeCredentials = append(options.ExcludeCredentials, CredentialDescriptor{Type: "public-key", ID: encoding.EncodeToString(id)})
+	}
+
+	return options
+}
+
+// RequestOptions возвращает параметры входа по обнаруживаемым учетным данным.
+func (rp *RelyingParty) RequestOptions(challenge []byte) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        encoding.EncodeToString(challenge),
+		RPID:             rp.id,
+		Timeout:          rp.timeout.Milliseconds(),
+		UserVerification: "required",
+	}
+}
+
+// RegistrationResponse - ответ аутентификатора на navigator.credentials.create().
+type RegistrationResponse struct {
+	ClientDataJSON    []byte
+	AttestationObject []byte
+}
+
+// AssertionResponse - ответ аутентификатора на navigator.credentials.get().
+type AssertionResponse struct {
+	CredentialID      []byte
+	ClientDataJSON    []byte
+	AuthenticatorData []byte
+	Signature         []byte
+	UserHandle        []byte
+}
+
+// Credential - проверенные учетные данные, созданные при регистрации.
+type Credential struct {
+	ID        []byte // ID учетных данных
+	PublicKey []byte // Открытый ключ в формате COSE
+	SignCount uint32 // Начальное значение счетчика подписей
+}
+
+// clientData - разобранный clientDataJSON.
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// authenticatorData - разобранный authenticatorData.
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte
+}
+
+// ChallengeFromClientData извлекает challenge из clientDataJSON.
+// Используется для поиска церемонии, к которой относится ответ, до его проверки.
+func ChallengeFromClientData(clientDataJSON []byte) ([]byte, error) {
+	var data clientData
+	if err := json.Unmarshal(clientDataJSON, &data); err != nil {
+		return nil, ErrInvalidResponse
+	}
+
+	challenge, err := encoding.DecodeString(data.Challenge)
+	if err != nil || len(challenge) == 0 {
+		return nil, ErrInvalidResponse
+	}
+
+	return challenge, nil
+}
+
+// verifyClientData проверяет тип церемонии, challenge и origin в clientDataJSON.
+func (rp *RelyingParty) verifyClientData(raw []byte, ceremonyType string, challenge []byte) error {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return ErrInvalidResponse
+	}
+
+	if data.Type != ceremonyType {
+		return ErrInvalidResponse
+	}
+
+	received, err := encoding.DecodeString(data.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(received, challenge) != 1 {
+		return ErrChallengeMismatch
+	}
+
+	if _, ok := rp.origins[data.Origin]; !ok || data.CrossOrigin {
+		return ErrOriginMismatch
+	}
+
+	return nil
+}
+
+// parseAuthenticatorData разбирает authenticatorData и проверяет хеш RP ID и флаги присутствия и верификации пользователя.
+func (rp *RelyingParty) parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	// rpIdHash (32) + flags (1) + signCount (4)
+	if len(raw) < 37 {
+		return nil, ErrInvalidResponse
+	}
+
+	data := &authenticatorData{
+		rpIDHash:  raw[:32],
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+
+	if subtle.ConstantTimeCompare(data.rpIDHash, rp.idHash[:]) != 1 {
+		return nil, ErrRPIDMismatch
+	}
+
+	if data.flags&flagUserPresent == 0 || data.flags&flagUserVerified == 0 {
+		return nil, ErrUserNotVerified
+	}
+
+	rest := raw[37:]
+
+	if data.flags&flagAttestedData != 0 {
+		// aaguid (16) + credentialIdLength (2)
+		if len(rest) < 18 {
+			return nil, ErrInvalidResponse
+		}
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLength == 0 || idLength > maxCredentialIDLength || len(rest) < idLength {
+			return nil, ErrInvalidResponse
+		}
+		data.credentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, ErrInvalidResponse
+		}
+		data.publicKey = rest[:n]
+		rest = rest[n:]
+	}
+
+	if data.flags&flagExtensionData != 0 {
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, ErrInvalidResponse
+		}
+		rest = rest[n:]
+	}
+
+	if len(rest) != 0 {
+		return nil, ErrInvalidResponse
+	}
+
+	return data, nil
+}
+
+// VerifyRegistration проверяет ответ аутентификатора на церемонию регистрации с указанным challenge
+// и возвращает созданные учетные данные.
+// Поддерживается только формат аттестации none: сервис не проверяет происхождение аутентификатора.
+func (rp *RelyingParty) VerifyRegistration(challenge []byte, response RegistrationResponse) (*Credential, error) {
+	if err := rp.verifyClientData(response.ClientDataJSON, clientDataTypeCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	value, n, err := decodeCBOR(response.AttestationObject)
+	if err != nil || n != len(response.AttestationObject) {
+		return nil, ErrInvalidResponse
+	}
+
+	attestation, ok := value.(map[any]any)
+	if !ok {
+		return nil, ErrInvalidResponse
+	}
+
+	format, _ := attestation["fmt"].(string)
+	statement, _ := attestation["attStmt"].(map[any]any)
+	if format != "none" || len(statement) != 0 {
+		return nil, ErrUnsupportedAttestation
+	}
+
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, ErrInvalidResponse
+	}
+
+	authData, err := rp.parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+
+	if authData.credentialID == nil {
+		return nil, ErrInvalidResponse
+	}
+
+	if _, err = parsePublicKey(authData.publicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:        append([]byte(nil), authData.credentialID...),
+		PublicKey: append([]byte(nil), authData.publicKey...),
+		SignCount: authData.signCount,
+	}, nil
+}
+
+// VerifyAssertion проверяет ответ аутентификатора на церемонию входа с указанным challenge
+// с помощью открытого ключа учетных данных в формате COSE и возвращает новое значение счетчика подписей.
+// Если аутентификатор ведет счетчик подписей и он не увеличился, возвращает ErrSignCountRollback:
+// это признак клонированного аутентификатора.
+func (rp *RelyingParty) VerifyAssertion(challenge, publicKeyCOSE []byte, storedSignCount uint32, response AssertionResponse) (uint32, error) {
+	if err := rp.verifyClientData(response.ClientDataJSON, clientDataTypeGet, challenge); err != nil {
+		return 0, err
+	}
+
+	authData, err := rp.parseAuthenticatorData(response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+
+	key, err := parsePublicKey(publicKeyCOSE)
+	if err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(response.ClientDataJSON)
+	signed := append(append([]byte(nil), response.AuthenticatorData...), clientDataHash[:]...)
+	if !key.verify(signed, response.Signature) {
+		return 0, ErrInvalidSignature
+	}
+
+	if (authData.signCount != 0 || storedSignCount != 0) && authData.signCount <= storedSignCount {
+		return 0, ErrSignCountRollback
+	}
+
+	return authData.signCount, nil
+}
@@ -0,0 +1,184 @@
+package webauthn_test
+
+import (
+	"github.com/maksemen2/medods-task/internal/pkg/auth/webauthn"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/webauthn/webauthntest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+const (
+	rpID   = "example.com"
+	origin = "https://example.com"
+)
+
+func newRelyingParty(t *testing.T) *webauthn.RelyingParty {
+	rp, err := webauthn.NewRelyingParty(rpID, "Example", []string{origin}, time.Minute)
+	require.NoError(t, err)
+	return rp
+}
+
+func register(t *testing.T, rp *webauthn.RelyingParty) (*webauthntest.Authenticator, *webauthn.Credential) {
+	authenticator, err := webauthntest.NewAuthenticator()
+	require.NoError(t, err)
+
+	challenge, err := webauthn.NewChallenge()
+	require.NoError(t, err)
+
+	credential, err := rp.VerifyRegistration(challenge, authenticator.Register(rpID, origin, challenge, []byte("user")))
+	require.NoError(t, err)
+	return authenticator, credential
+}
+
+func TestRelyingParty_VerifyRegistration(t *testing.T) {
+	rp := newRelyingParty(t)
+
+	t.Run("Success", func(t *testing.T) {
+		authenticator, credential := register(t, rp)
+		assert.Equal(t, authenticator.CredentialID(), credential.ID)
+		assert.NotEmpty(t, credential.PublicKey)
+		assert.Zero(t, credential.SignCount)
+	})
+
+	t.Run("Challenge mismatch", func(t *testing.T) {
+		authenticator, err := webauthntest.NewAuthenticator()
+		require.NoError(t, err)
+
+		response := authenticator.Register(rpID, origin, []byte("other challenge"), []byte("user"))
+		_, err = rp.VerifyRegistration([]byte("expected challenge"), response)
+		assert.ErrorIs(t, err, webauthn.ErrChallengeMismatch)
+	})
+
+	t.Run("Origin mismatch", func(t *testing.T) {
+		authenticator, err := webauthntest.NewAuthenticator()
+		require.NoError(t, err)
+
+		response := authenticator.Register(rpID, "https://evil.example", []byte("challenge"), []byte("user"))
+		_, err = rp.VerifyRegistration([]byte("challenge"), response)
+		assert.ErrorIs(t, err, webauthn.ErrOriginMismatch)
+	})
+
+	t.Run("RP ID mismatch", func(t *testing.T) {
+		authenticator, err := webauthntest.NewAuthenticator()
+		require.NoError(t, err)
+
+		response := authenticator.Register("evil.example", origin, []byte("challenge"), []byte("user"))
+		_, err = rp.VerifyRegistration([]byte("challenge"), response)
+		assert.ErrorIs(t, err, webauthn.ErrRPIDMismatch)
+	})
+
+	t.Run("Assertion used for registration", func(t *testing.T) {
+		authenticator, _ := register(t, rp)
+
+		assertion := authenticator.Login(rpID, origin, []byte("challenge"))
+		_, err := rp.VerifyRegistration([]byte("challenge"), webauthn.RegistrationResponse{
+			ClientDataJSON:    assertion.ClientDataJSON,
+			AttestationObject: assertion.AuthenticatorData,
+		})
+		assert.ErrorIs(t, err, webauthn.ErrInvalidResponse)
+	})
+
+	t.Run("Malformed attestation object", func(t *testing.T) {
+		authenticator, err := webauthntest.NewAuthenticator()
+		require.NoError(t, err)
+
+		response := authenticator.Register(rpID, origin, []byte("challenge"), []byte("user"))
+		response.AttestationObject = response.AttestationObject[:len(response.AttestationObject)-10]
+		_, err = rp.VerifyRegistration([]byte("challenge"), response)
+		assert.ErrorIs(t, err, webauthn.ErrInvalidResponse)
+	})
+}
+
+func TestRelyingParty_VerifyAssertion(t *testing.T) {
+	rp := newRelyingParty(t)
+
+	t.Run("Success", func(t *testing.T) {
+		authenticator, credential := register(t, rp)
+
+		challenge, err := webauthn.NewChallenge()
+		require.NoError(t, err)
+
+		response := authenticator.Login(rpID, origin, challenge)
+		assert.Equal(t, []byte("user"), response.UserHandle)
+
+		extracted, err := webauthn.ChallengeFromClientData(response.ClientDataJSON)
+		require.NoError(t, err)
+		assert.Equal(t, challenge, extracted)
+
+		signCount, err := rp.VerifyAssertion(challenge, credential.PublicKey, credential.SignCount, response)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(1), signCount)
+
+		// Счетчик увеличивается при каждом входе
+		response = authenticator.Login(rpID, origin, challenge)
+		signCount, err = rp.VerifyAssertion(challenge, credential.PublicKey, signCount, response)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(2), signCount)
+	})
+
+	t.Run("Wrong key", func(t *testing.T) {
+		authenticator, _ := register(t, rp)
+		_, otherCredential := register(t, rp)
+
+		response := authenticator.Login(rpID, origin, []byte("challenge"))
+		_, err := rp.VerifyAssertion([]byte("challenge"), otherCredential.PublicKey, 0, response)
+		assert.ErrorIs(t, err, webauthn.ErrInvalidSignature)
+	})
+
+	t.Run("Tampered client data", func(t *testing.T) {
+		authenticator, credential := register(t, rp)
+
+		response := authenticator.Login(rpID, origin, []byte("challenge"))
+		response.ClientDataJSON = append(response.ClientDataJSON[:len(response.ClientDataJSON)-1], []byte(`,"x":1}`)...)
+		_, err := rp.VerifyAssertion([]byte("challenge"), credential.PublicKey, 0, response)
+		assert.ErrorIs(t, err, webauthn.ErrInvalidSignature)
+	})
+
+	t.Run("Sign count rollback", func(t *testing.T) {
+		authenticator, credential := register(t, rp)
+
+		response := authenticator.Login(rpID, origin, []byte("challenge"))
+		_, err := rp.VerifyAssertion([]byte("challenge"), credential.PublicKey, 5, response)
+		assert.ErrorIs(t, err, webauthn.ErrSignCountRollback)
+	})
+
+	t.Run("User not verified", func(t *testing.T) {
+		authenticator, credential := register(t, rp)
+
+		response := authenticator.Login(rpID, origin, []byte("challenge"))
+		response.AuthenticatorData[32] &^= 0x04
+		_, err := rp.VerifyAssertion([]byte("challenge"), credential.PublicKey, 0, response)
+		assert.ErrorIs(t, err, webauthn.ErrUserNotVerified)
+	})
+
+	t.Run("Registration used for login", func(t *testing.T) {
+		authenticator, credential := register(t, rp)
+
+		registration := authenticator.Register(rpID, origin, []byte("challenge"), []byte("user"))
+		_, err := rp.VerifyAssertion([]byte("challenge"), credential.PublicKey, 0, webauthn.AssertionResponse{
+			ClientDataJSON:    registration.ClientDataJSON,
+			AuthenticatorData: registration.AttestationObject,
+		})
+		assert.ErrorIs(t, err, webauthn.ErrInvalidResponse)
+	})
+}
+
+func TestRelyingParty_Options(t *testing.T) {
+	rp := newRelyingParty(t)
+
+	creation := rp.CreationOptions([]byte{1, 2, 3}, webauthn.User{ID: []byte("user"), Name: "user@example.com"}, [][]byte{{9}})
+	assert.Equal(t, "AQID", creation.Challenge)
+	assert.Equal(t, rpID, creation.RP.ID)
+	assert.Equal(t, "dXNlcg", creation.User.ID)
+	assert.Equal(t, "required", creation.AuthenticatorSelection.ResidentKey)
+	assert.Equal(t, "none", creation.Attestation)
+	assert.Equal(t, []webauthn.CredentialDescriptor{{Type: "public-key", ID: "CQ"}}, creation.ExcludeCredentials)
+	assert.Equal(t, int64(60000), creation.Timeout)
+
+	request := rp.RequestOptions([]byte{1, 2, 3})
+	assert.Equal(t, "AQID", request.Challenge)
+	assert.Equal(t, rpID, request.RPID)
+	assert.Equal(t, "required", request.UserVerification)
+}
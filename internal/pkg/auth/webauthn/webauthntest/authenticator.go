@@ -0,0 +1,128 @@
+// Package webauthntest содержит программный аутентификатор WebAuthn для тестов,
+// который позволяет проходить церемонии регистрации и входа без аппаратного ключа.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/webauthn"
+)
+
+// Флаги authenticatorData, которые выставляет аутентификатор.
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+)
+
+// Authenticator - программный аутентификатор с одной парой ключей ES256.
+// Счетчик подписей увеличивается при каждом входе.
+type Authenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+// NewAuthenticator создает аутентификатор с новыми ключом и ID учетных данных.
+func NewAuthenticator() (*Authenticator, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialID := make([]byte, 16)
+	if _, err = rand.Read(credentialID); err != nil {
+		return nil, err
+	}
+
+	return &Authenticator{key: key, credentialID: credentialID}, nil
+}
+
+// CredentialID возвращает ID учетных данных аутентификатора.
+func (a *Authenticator) CredentialID() []byte {
+	return a.credentialID
+}
+
+// clientDataJSON формирует clientDataJSON так же, как это делает браузер.
+func clientDataJSON(ceremonyType, origin string, challenge []byte) []byte {
+	raw, _ := json.Marshal(map[string]any{
+		"type":        ceremonyType,
+		"challenge":   base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":      origin,
+		"crossOrigin": false,
+	})
+	return raw
+}
+
+// authenticatorData формирует authenticatorData без данных учетных данных.
+func (a *Authenticator) authenticatorData(rpID string, flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, a.signCount)
+}
+
+// publicKeyCOSE возвращает открытый ключ аутентификатора в формате COSE.
+func (a *Authenticator) publicKeyCOSE() []byte {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	a.key.PublicKey.X.FillBytes(x)
+	a.key.PublicKey.Y.FillBytes(y)
+
+	return encodeCBOR(map[any]any{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: x,
+		-3: y,
+	})
+}
+
+// Register проходит церемонию регистрации для сайта rpID на странице origin
+// и запоминает userHandle, который вернется при входе.
+func (a *Authenticator) Register(rpID, origin string, challenge, userHandle []byte) webauthn.RegistrationResponse {
+	a.userHandle = userHandle
+
+	authData := a.authenticatorData(rpID, flagUserPresent|flagUserVerified|flagAttestedData)
+	authData = append(authData, make([]byte, 16)...) // aaguid
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialID)))
+	authData = append(authData, a.credentialID...)
+	authData = append(authData, a.publicKeyCOSE()...)
+
+	return webauthn.RegistrationResponse{
+		ClientDataJSON: clientDataJSON("webauthn.create", origin, challenge),
+		AttestationObject: encodeCBOR(map[any]any{
+			"fmt":      "none",
+			"attStmt":  map[any]any{},
+			"authData": authData,
+		}),
+	}
+}
+
+// Login проходит церемонию входа для сайта rpID на странице origin.
+func (a *Authenticator) Login(rpID, origin string, challenge []byte) webauthn.AssertionResponse {
+	a.signCount++
+
+	authData := a.authenticatorData(rpID, flagUserPresent|flagUserVerified)
+	clientData := clientDataJSON("webauthn.get", origin, challenge)
+	clientDataHash := sha256.Sum256(clientData)
+
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		panic("webauthntest: signing assertion: " + err.Error())
+	}
+
+	return webauthn.AssertionResponse{
+		CredentialID:      a.credentialID,
+		ClientDataJSON:    clientData,
+		AuthenticatorData: authData,
+		Signature:         signature,
+		UserHandle:        a.userHandle,
+	}
+}
@@ -8,7 +8,8 @@ import (
 // Коды ошибок PostgreSQL.
 // См. https://www.postgresql.org/docs/current/errcodes-appendix.html
 const (
-	PGUniqueViolationCode = "23505" // Уникальное ограничение нарушено
+	PGUniqueViolationCode     = "23505" // Уникальное ограничение нарушено
+	PGForeignKeyViolationCode = "23503" // Ограничение внешнего ключа нарушено
 )
 
 func NewPostgresDB(dsn string, maxOpenConns, maxIdleConns int) (*sqlx.DB, error) {
@@ -32,3 +33,13 @@ func IsPGError(err error, code string) bool {
 
 	return false
 }
+
+// PGConstraint - возвращает имя ограничения, нарушение которого вызвало ошибку PostgreSQL.
+// Для остальных ошибок возвращает пустую строку.
+func PGConstraint(err error) string {
+	if pqErr, ok := err.(*pq.Error); ok {
+		return pqErr.Constraint
+	}
+
+	return ""
+}
@@ -0,0 +1,40 @@
+package geoip
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	ErrInvalidIP        = errors.New("invalid ip address")
+	ErrLocationNotFound = errors.New("location not found")
+)
+
+// earthRadiusKM - средний радиус Земли в километрах.
+const earthRadiusKM = 6371.0
+
+// Location - географическое положение IP-адреса.
+type Location struct {
+	Country   string  // ISO-код страны
+	City      string  // Название города на английском языке
+	Latitude  float64 // Широта
+	Longitude float64 // Долгота
+}
+
+// Locator описывает интерфейс определения географического положения IP-адреса.
+type Locator interface {
+	Lookup(ip string) (*Location, error) // Lookup возвращает положение IP-адреса или ErrLocationNotFound
+}
+
+// Distance возвращает расстояние между двумя точками на поверхности Земли в километрах.
+// Расстояние считается по формуле гаверсинусов.
+func Distance(from, to *Location) float64 {
+	lat1 := from.Latitude * math.Pi / 180
+	lat2 := to.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLon := (to.Longitude - from.Longitude) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusKM * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,32 @@
+package geoip_test
+
+import (
+	"github.com/maksemen2/medods-task/internal/pkg/geoip"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	t.Run("Same Point", func(t *testing.T) {
+		moscow := &geoip.Location{Latitude: 55.7558, Longitude: 37.6173}
+
+		assert.InDelta(t, 0, geoip.Distance(moscow, moscow), 0.001)
+	})
+
+	t.Run("Moscow To New York", func(t *testing.T) {
+		moscow := &geoip.Location{Latitude: 55.7558, Longitude: 37.6173}
+		newYork := &geoip.Location{Latitude: 40.7128, Longitude: -74.0060}
+
+		// Расстояние по большому кругу - около 7510 км
+		assert.InDelta(t, 7510, geoip.Distance(moscow, newYork), 20)
+		assert.InDelta(t, geoip.Distance(moscow, newYork), geoip.Distance(newYork, moscow), 0.001)
+	})
+}
+
+func TestNewMMDBLocator(t *testing.T) {
+	t.Run("Missing File", func(t *testing.T) {
+		locator, err := geoip.NewMMDBLocator("does-not-exist.mmdb")
+		assert.Error(t, err)
+		assert.Nil(t, locator)
+	})
+}
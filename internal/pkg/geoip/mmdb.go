@@ -0,0 +1,67 @@
+package geoip
+
+import (
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// mmdbRecord - подмножество полей записи баз GeoIP2/GeoLite2 City, необходимое сервису.
+type mmdbRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Location struct {
+		Latitude  float64 `maxminddb:"latitude"`
+		Longitude float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+// MMDBLocator имплементирует Locator поверх локального файла в формате MaxMind DB (.mmdb).
+type MMDBLocator struct {
+	reader *maxminddb.Reader
+}
+
+// NewMMDBLocator - конструктор MMDBLocator.
+// Открывает файл базы по указанному пути. Файл должен быть закрыт вызовом Close.
+func NewMMDBLocator(path string) (*MMDBLocator, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MMDBLocator{reader: reader}, nil
+}
+
+// Lookup возвращает положение IP-адреса.
+// Если адрес невалиден, возвращает ErrInvalidIP, если адреса нет в базе - ErrLocationNotFound.
+func (l *MMDBLocator) Lookup(ip string) (*Location, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil, ErrInvalidIP
+	}
+
+	var record mmdbRecord
+	_, ok, err := l.reader.LookupNetwork(parsed, &record)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrLocationNotFound
+	}
+
+	return &Location{
+		Country:   record.Country.ISOCode,
+		City:      record.City.Names["en"],
+		Latitude:  record.Location.Latitude,
+		Longitude: record.Location.Longitude,
+	}, nil
+}
+
+// Close закрывает файл базы.
+func (l *MMDBLocator) Close() error {
+	return l.reader.Close()
+}
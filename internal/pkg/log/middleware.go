@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/maksemen2/medods-task/internal/pkg/requestid"
 	"go.uber.org/zap"
 )
 
@@ -19,6 +20,7 @@ func NewMiddleware(logger *zap.Logger) gin.HandlerFunc {
 			zap.String("path", c.Request.URL.Path),
 			zap.Int("status", c.Writer.Status()),
 			zap.Duration("duration", time.Since(start)),
+			zap.String("request_id", requestid.FromContext(c.Request.Context())),
 		)
 	}
 }
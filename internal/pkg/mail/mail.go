@@ -0,0 +1,66 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"os"
+	"sync"
+	"time"
+)
+
+// Message - письмо пользователю.
+type Message struct {
+	To      string // Адрес получателя
+	Subject string // Тема письма
+	Body    string // Текст письма
+}
+
+// Sender описывает интерфейс отправки писем.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error // Send отправляет письмо
+}
+
+// LogSender - реализация Sender, которая вместо отправки пишет письма в лог.
+// Предназначена для локального запуска: письма содержат одноразовые ссылки, поэтому тело пишется на уровне debug.
+type LogSender struct {
+	logger *zap.Logger
+}
+
+func NewLogSender(logger *zap.Logger) *LogSender {
+	return &LogSender{logger: logger}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	s.logger.Info("Sending email", zap.String("to", msg.To), zap.String("subject", msg.Subject))
+	s.logger.Debug("Email body", zap.String("to", msg.To), zap.String("body", msg.Body))
+	return nil
+}
+
+// FileSender - реализация Sender, которая дописывает письма в текстовый файл.
+// Позволяет в тестах и при локальном запуске прочитать отправленные ссылки.
+type FileSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileSender(path string) *FileSender {
+	return &FileSender{path: path}
+}
+
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(file, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().UTC().Format(time.RFC1123Z), msg.To, msg.Subject, msg.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,31 @@
+package mail
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileSender_Send(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.txt")
+	sender := NewFileSender(path)
+
+	require.NoError(t, sender.Send(context.Background(), Message{To: "a@example.com", Subject: "First", Body: "first body"}))
+	require.NoError(t, sender.Send(context.Background(), Message{To: "b@example.com", Subject: "Second", Body: "second body"}))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	text := string(content)
+	assert.Contains(t, text, "To: a@example.com\nSubject: First\n\nfirst body")
+	assert.Contains(t, text, "To: b@example.com\nSubject: Second\n\nsecond body")
+	assert.Less(t, strings.Index(text, "first body"), strings.Index(text, "second body"))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
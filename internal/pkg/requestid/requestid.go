@@ -0,0 +1,62 @@
+package requestid
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Header - заголовок, в котором передается ID запроса.
+const Header = "X-Request-ID"
+
+// maxLength - максимальная длина ID запроса, принимаемого от клиента.
+const maxLength = 128
+
+type contextKey struct{}
+
+// WithRequestID возвращает копию контекста с указанным ID запроса.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext возвращает ID запроса из контекста или пустую строку, если его нет.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// valid сообщает, можно ли принять ID запроса от клиента. ID попадает в логи и неизменяемый журнал аудита,
+// поэтому допускаются только строки до maxLength символов из латинских букв, цифр и символов "-", "_", ".", ":".
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// NewMiddleware - мидлварь для Gin, которая присваивает каждому запросу ID.
+// ID берется из заголовка Header, если клиент передал допустимый ID, иначе генерируется.
+// ID кладется в контекст запроса и возвращается клиенту в заголовке ответа.
+func NewMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(Header)
+		if !valid(id) {
+			id = uuid.NewString()
+		}
+
+		c.Request = c.Request.WithContext(WithRequestID(c.Request.Context(), id))
+		c.Header(Header, id)
+
+		c.Next()
+	}
+}
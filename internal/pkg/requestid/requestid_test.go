@@ -0,0 +1,58 @@
+package requestid_test
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/pkg/requestid"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(requestid.NewMiddleware())
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, requestid.FromContext(c.Request.Context()))
+	})
+
+	t.Run("Generated", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		router.ServeHTTP(w, req)
+
+		assert.NotEmpty(t, w.Body.String())
+		assert.Equal(t, w.Body.String(), w.Header().Get(requestid.Header))
+	})
+
+	t.Run("Provided By Client", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set(requestid.Header, "client-request-id")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, "client-request-id", w.Body.String())
+		assert.Equal(t, "client-request-id", w.Header().Get(requestid.Header))
+	})
+
+	for name, id := range map[string]string{
+		"Too Long":          strings.Repeat("a", 129),
+		"Control Character": "id\nforged log line",
+		"Non ASCII":         "запрос",
+		"Spaces":            "client request id",
+	} {
+		t.Run("Regenerated "+name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Header.Set(requestid.Header, id)
+			router.ServeHTTP(w, req)
+
+			assert.NoError(t, uuid.Validate(w.Body.String()))
+			assert.Equal(t, w.Body.String(), w.Header().Get(requestid.Header))
+		})
+	}
+}
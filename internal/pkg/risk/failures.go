@@ -0,0 +1,71 @@
+package risk
+
+import (
+	"sync"
+	"time"
+)
+
+// FailureTracker описывает интерфейс учета неудачных попыток аутентификации.
+type FailureTracker interface {
+	RecordFailure(key string) // RecordFailure фиксирует неудачную попытку по ключу (например, IP-адресу)
+	Failures(key string) int  // Failures возвращает количество неудачных попыток по ключу за окно наблюдения
+}
+
+// MemoryFailureTracker имплементирует FailureTracker в памяти процесса со скользящим окном.
+type MemoryFailureTracker struct {
+	mu        sync.Mutex
+	window    time.Duration
+	failures  map[string][]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryFailureTracker - конструктор MemoryFailureTracker.
+// Принимает длительность окна, за которое учитываются неудачные попытки.
+func NewMemoryFailureTracker(window time.Duration) *MemoryFailureTracker {
+	return &MemoryFailureTracker{
+		window:   window,
+		failures: map[string][]time.Time{},
+		now:      time.Now,
+	}
+}
+
+func (t *MemoryFailureTracker) RecordFailure(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.failures[key] = append(t.prune(t.failures[key], now), now)
+
+	// Периодически удаляем ключи, по которым давно не было неудачных попыток,
+	// чтобы память не росла неограниченно
+	if now.Sub(t.lastSweep) >= t.window {
+		for k, attempts := range t.failures {
+			if attempts = t.prune(attempts, now); len(attempts) == 0 {
+				delete(t.failures, k)
+			} else {
+				t.failures[k] = attempts
+			}
+		}
+		t.lastSweep = now
+	}
+}
+
+func (t *MemoryFailureTracker) Failures(key string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.prune(t.failures[key], t.now()))
+}
+
+// prune возвращает попытки, попадающие в окно наблюдения.
+// Попытки хранятся в порядке возрастания времени.
+func (t *MemoryFailureTracker) prune(attempts []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-t.window)
+	for i, at := range attempts {
+		if at.After(cutoff) {
+			return attempts[i:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package risk_test
+
+import (
+	"github.com/maksemen2/medods-task/internal/pkg/risk"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMemoryFailureTracker(t *testing.T) {
+	t.Run("Counts Failures By Key", func(t *testing.T) {
+		tracker := risk.NewMemoryFailureTracker(time.Minute)
+
+		tracker.RecordFailure("127.0.0.1")
+		tracker.RecordFailure("127.0.0.1")
+		tracker.RecordFailure("10.0.0.1")
+
+		assert.Equal(t, 2, tracker.Failures("127.0.0.1"))
+		assert.Equal(t, 1, tracker.Failures("10.0.0.1"))
+		assert.Zero(t, tracker.Failures("192.168.0.1"))
+	})
+
+	t.Run("Window Expired", func(t *testing.T) {
+		tracker := risk.NewMemoryFailureTracker(10 * time.Millisecond)
+
+		tracker.RecordFailure("127.0.0.1")
+		time.Sleep(20 * time.Millisecond)
+
+		assert.Zero(t, tracker.Failures("127.0.0.1"))
+	})
+}
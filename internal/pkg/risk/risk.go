@@ -0,0 +1,69 @@
+package risk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+)
+
+// Signal - сигнал риска, учитываемый при оценке операции.
+type Signal string
+
+const (
+	SignalIPChange       Signal = "ip_change"      // Операция выполняется с IP-адреса, отличного от предыдущего в сессии
+	SignalNewUserAgent   Signal = "new_user_agent" // Операция выполняется с User-Agent, отличного от предыдущего в сессии
+	SignalGeoVelocity    Signal = "geo_velocity"   // Перемещение с момента предыдущей операции невозможно за прошедшее время
+	SignalFailureRate    Signal = "failure_rate"   // С IP-адреса недавно было много неудачных попыток
+	SignalTimeSinceUsage Signal = "time_since_use" // Сессия долго не использовалась
+)
+
+// Action - решение, принятое по результатам оценки риска.
+type Action string
+
+const (
+	ActionAllow  Action = "allow"  // Операция разрешается
+	ActionNotify Action = "notify" // Операция разрешается, пользователь уведомляется
+	ActionDeny   Action = "deny"   // Операция запрещается
+)
+
+// Operation - тип оцениваемой операции.
+type Operation string
+
+const (
+	OperationLogin   Operation = "login"   // Выдача новой пары токенов
+	OperationRefresh Operation = "refresh" // Обновление пары токенов
+)
+
+// Input - входные данные для оценки риска операции.
+// Поля, относящиеся к предыдущей операции сессии, заполняются только при обновлении токенов.
+type Input struct {
+	Operation        Operation
+	IPChanged        bool          // IP-адрес изменился с предыдущей операции сессии
+	UserAgentChanged bool          // User-Agent изменился с предыдущей операции сессии
+	ImpossibleTravel bool          // Обнаружено невозможное перемещение
+	RecentFailures   int           // Количество недавних неудачных попыток с IP-адреса
+	SinceLastUse     time.Duration // Время с предыдущей операции сессии
+}
+
+// Assessment - результат оценки риска.
+type Assessment struct {
+	Score   float64            // Итоговая оценка риска
+	Action  Action             // Принятое решение
+	Signals map[Signal]float64 // Сработавшие сигналы и их вклад в оценку
+}
+
+// Engine описывает интерфейс движка оценки риска.
+type Engine interface {
+	Evaluate(ctx context.Context, input Input) Assessment // Evaluate оценивает риск операции и принимает решение
+}
+
+// UserAgentFingerprint возвращает короткий отпечаток User-Agent, пригодный для хранения в Access токене.
+// Для пустого User-Agent возвращает пустую строку.
+func UserAgentFingerprint(userAgent string) string {
+	if userAgent == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(userAgent))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
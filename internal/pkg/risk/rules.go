@@ -0,0 +1,162 @@
+package risk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+var ErrInvalidRules = errors.New("invalid risk rules")
+
+// Duration - time.Duration, которая читается из JSON в формате time.ParseDuration (например, "720h").
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(raw)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Rules - настраиваемые правила оценки риска.
+// Каждый сработавший сигнал добавляет к оценке свой вес, умноженный на силу сигнала от 0 до 1.
+// Решение принимается сравнением итоговой оценки с порогами. Нулевой порог отключает соответствующее решение.
+type Rules struct {
+	Weights          map[Signal]float64 `json:"weights"`           // Веса сигналов
+	FailureThreshold int                `json:"failure_threshold"` // Количество неудачных попыток, при котором failure_rate срабатывает в полную силу
+	IdleThreshold    Duration           `json:"idle_threshold"`    // Время простоя сессии, начиная с которого срабатывает time_since_use
+	NotifyThreshold  float64            `json:"notify_threshold"`  // Оценка, начиная с которой пользователь уведомляется
+	DenyThreshold    float64            `json:"deny_threshold"`    // Оценка, начиная с которой операция запрещается
+}
+
+// DefaultRules возвращает правила по умолчанию.
+// По отдельности ни один сигнал не приводит к запрету операции.
+func DefaultRules() Rules {
+	return Rules{
+		Weights: map[Signal]float64{
+			SignalIPChange:       20,
+			SignalNewUserAgent:   20,
+			SignalGeoVelocity:    50,
+			SignalFailureRate:    30,
+			SignalTimeSinceUsage: 10,
+		},
+		FailureThreshold: 10,
+		IdleThreshold:    Duration(30 * 24 * time.Hour),
+		NotifyThreshold:  40,
+		DenyThreshold:    90,
+	}
+}
+
+// Validate проверяет правила на корректность.
+func (r Rules) Validate() error {
+	for signal, weight := range r.Weights {
+		if weight < 0 {
+			return fmt.Errorf("%w: negative weight for signal %s", ErrInvalidRules, signal)
+		}
+	}
+
+	if r.FailureThreshold < 0 || r.IdleThreshold < 0 || r.NotifyThreshold < 0 || r.DenyThreshold < 0 {
+		return fmt.Errorf("%w: thresholds must not be negative", ErrInvalidRules)
+	}
+
+	if r.NotifyThreshold > 0 && r.DenyThreshold > 0 && r.NotifyThreshold > r.DenyThreshold {
+		return fmt.Errorf("%w: notify threshold is greater than deny threshold", ErrInvalidRules)
+	}
+
+	return nil
+}
+
+// LoadRules читает правила из JSON файла.
+// Поля, отсутствующие в файле, берутся из DefaultRules.
+func LoadRules(path string) (Rules, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Rules{}, err
+	}
+
+	rules := DefaultRules()
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return Rules{}, fmt.Errorf("%w: %v", ErrInvalidRules, err)
+	}
+
+	if err := rules.Validate(); err != nil {
+		return Rules{}, err
+	}
+
+	return rules, nil
+}
+
+// RuleEngine имплементирует Engine на основе взвешенных сигналов и порогов.
+// Правила могут быть заменены во время работы без перезапуска сервиса.
+type RuleEngine struct {
+	rules atomic.Pointer[Rules]
+}
+
+// NewRuleEngine - конструктор RuleEngine.
+func NewRuleEngine(rules Rules) *RuleEngine {
+	e := &RuleEngine{}
+	e.SetRules(rules)
+	return e
+}
+
+// SetRules атомарно заменяет правила движка.
+func (e *RuleEngine) SetRules(rules Rules) {
+	e.rules.Store(&rules)
+}
+
+// Evaluate оценивает риск операции по текущим правилам.
+func (e *RuleEngine) Evaluate(ctx context.Context, input Input) Assessment {
+	rules := e.rules.Load()
+
+	strengths := map[Signal]float64{}
+	if input.IPChanged {
+		strengths[SignalIPChange] = 1
+	}
+	if input.UserAgentChanged {
+		strengths[SignalNewUserAgent] = 1
+	}
+	if input.ImpossibleTravel {
+		strengths[SignalGeoVelocity] = 1
+	}
+	if input.RecentFailures > 0 && rules.FailureThreshold > 0 {
+		strengths[SignalFailureRate] = min(float64(input.RecentFailures)/float64(rules.FailureThreshold), 1)
+	}
+	if rules.IdleThreshold > 0 && input.SinceLastUse >= time.Duration(rules.IdleThreshold) {
+		strengths[SignalTimeSinceUsage] = 1
+	}
+
+	assessment := Assessment{Action: ActionAllow, Signals: map[Signal]float64{}}
+	for signal, strength := range strengths {
+		contribution := rules.Weights[signal] * strength
+		if contribution == 0 {
+			continue
+		}
+		assessment.Signals[signal] = contribution
+		assessment.Score += contribution
+	}
+
+	switch {
+	case rules.DenyThreshold > 0 && assessment.Score >= rules.DenyThreshold:
+		assessment.Action = ActionDeny
+	case rules.NotifyThreshold > 0 && assessment.Score >= rules.NotifyThreshold:
+		assessment.Action = ActionNotify
+	}
+
+	return assessment
+}
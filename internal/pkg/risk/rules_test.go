@@ -0,0 +1,110 @@
+package risk_test
+
+import (
+	"context"
+	"github.com/maksemen2/medods-task/internal/pkg/risk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRuleEngine_Evaluate(t *testing.T) {
+	engine := risk.NewRuleEngine(risk.DefaultRules())
+
+	t.Run("Allow", func(t *testing.T) {
+		assessment := engine.Evaluate(context.Background(), risk.Input{Operation: risk.OperationRefresh})
+
+		assert.Equal(t, risk.ActionAllow, assessment.Action)
+		assert.Zero(t, assessment.Score)
+		assert.Empty(t, assessment.Signals)
+	})
+
+	t.Run("Notify", func(t *testing.T) {
+		assessment := engine.Evaluate(context.Background(), risk.Input{
+			Operation:        risk.OperationRefresh,
+			IPChanged:        true,
+			UserAgentChanged: true,
+		})
+
+		assert.Equal(t, risk.ActionNotify, assessment.Action)
+		assert.Equal(t, 40.0, assessment.Score)
+		assert.Equal(t, map[risk.Signal]float64{risk.SignalIPChange: 20, risk.SignalNewUserAgent: 20}, assessment.Signals)
+	})
+
+	t.Run("Deny", func(t *testing.T) {
+		assessment := engine.Evaluate(context.Background(), risk.Input{
+			Operation:        risk.OperationRefresh,
+			IPChanged:        true,
+			UserAgentChanged: true,
+			ImpossibleTravel: true,
+		})
+
+		assert.Equal(t, risk.ActionDeny, assessment.Action)
+		assert.Equal(t, 90.0, assessment.Score)
+	})
+
+	t.Run("Partial Failure Rate", func(t *testing.T) {
+		assessment := engine.Evaluate(context.Background(), risk.Input{
+			Operation:      risk.OperationLogin,
+			RecentFailures: 5,
+			SinceLastUse:   31 * 24 * time.Hour,
+		})
+
+		assert.Equal(t, risk.ActionAllow, assessment.Action)
+		assert.Equal(t, 15.0, assessment.Signals[risk.SignalFailureRate])
+		assert.Equal(t, 10.0, assessment.Signals[risk.SignalTimeSinceUsage])
+	})
+
+	t.Run("Set Rules", func(t *testing.T) {
+		engine := risk.NewRuleEngine(risk.DefaultRules())
+		rules := risk.DefaultRules()
+		rules.DenyThreshold = 20
+		engine.SetRules(rules)
+
+		assessment := engine.Evaluate(context.Background(), risk.Input{IPChanged: true})
+		assert.Equal(t, risk.ActionDeny, assessment.Action)
+	})
+}
+
+func TestLoadRules(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rules.json")
+		err := os.WriteFile(path, []byte(`{"weights": {"ip_change": 50}, "idle_threshold": "24h", "deny_threshold": 50}`), 0o600)
+		require.NoError(t, err)
+
+		rules, err := risk.LoadRules(path)
+		assert.NoError(t, err)
+		assert.Equal(t, 50.0, rules.Weights[risk.SignalIPChange])
+		assert.Equal(t, risk.Duration(24*time.Hour), rules.IdleThreshold)
+		assert.Equal(t, 50.0, rules.DenyThreshold)
+		// Не указанные в файле поля берутся из правил по умолчанию
+		assert.Equal(t, risk.DefaultRules().NotifyThreshold, rules.NotifyThreshold)
+	})
+
+	t.Run("Invalid Thresholds", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rules.json")
+		err := os.WriteFile(path, []byte(`{"notify_threshold": 100, "deny_threshold": 50}`), 0o600)
+		require.NoError(t, err)
+
+		_, err = risk.LoadRules(path)
+		assert.ErrorIs(t, err, risk.ErrInvalidRules)
+	})
+
+	t.Run("Invalid Json", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rules.json")
+		err := os.WriteFile(path, []byte(`{"idle_threshold": "forever"}`), 0o600)
+		require.NoError(t, err)
+
+		_, err = risk.LoadRules(path)
+		assert.ErrorIs(t, err, risk.ErrInvalidRules)
+	})
+}
+
+func TestUserAgentFingerprint(t *testing.T) {
+	assert.Empty(t, risk.UserAgentFingerprint(""))
+	assert.Equal(t, risk.UserAgentFingerprint("curl/8.0"), risk.UserAgentFingerprint("curl/8.0"))
+	assert.NotEqual(t, risk.UserAgentFingerprint("curl/8.0"), risk.UserAgentFingerprint("curl/8.1"))
+}
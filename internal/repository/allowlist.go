@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/uuid"
+)
+
+// IAllowlistRepo - интерфейс для работы со списком GUID, для которых разрешено
+// автоматическое создание пользователей в режиме domain.ProvisioningModeAllowlist.
+type IAllowlistRepo interface {
+	// Add добавляет GUID в список разрешенных. Уже присутствующие GUID пропускаются.
+	// Возвращает количество действительно добавленных записей.
+	Add(ctx context.Context, guids []uuid.UUID) (int, error)
+	// Remove удаляет GUID из списка разрешенных.
+	Remove(ctx context.Context, guid uuid.UUID) error
+	// Contains проверяет, присутствует ли GUID в списке разрешенных.
+	Contains(ctx context.Context, guid uuid.UUID) (bool, error)
+}
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+	"github.com/maksemen2/medods-task/internal/domain"
+)
+
+// IAuditRepo - интерфейс для работы с журналом аудита событий аутентификации.
+// Журнал допускает только добавление записей.
+type IAuditRepo interface {
+	Create(ctx context.Context, event *domain.AuthEvent) error                       // Create добавляет событие в журнал аудита
+	List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuthEvent, error) // List возвращает события по фильтру, от новых к старым
+}
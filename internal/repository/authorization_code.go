@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"time"
+)
+
+// IAuthorizationCodeRepo - интерфейс для работы с кодами авторизации OAuth в базе данных
+type IAuthorizationCodeRepo interface {
+	// Create сохраняет новый код авторизации
+	Create(ctx context.Context, code *domain.AuthorizationCode) error
+	// Consume атомарно удаляет код по хешу и возвращает его.
+	// Возвращает domain.ErrInvalidGrant, если код не найден или истек.
+	Consume(ctx context.Context, codeHash string, now time.Time) (*domain.AuthorizationCode, error)
+}
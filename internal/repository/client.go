@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"github.com/maksemen2/medods-task/internal/domain"
+)
+
+// IClientRepo - интерфейс для работы с реестром клиентов OAuth в базе данных
+type IClientRepo interface {
+	// Create сохраняет нового клиента
+	Create(ctx context.Context, client *domain.OAuthClient) error
+	// Get возвращает клиента по идентификатору.
+	// Возвращает domain.ErrClientNotFound, если клиент не найден.
+	Get(ctx context.Context, clientID string) (*domain.OAuthClient, error)
+	// List возвращает всех клиентов в порядке регистрации
+	List(ctx context.Context) ([]domain.OAuthClient, error)
+	// Update заменяет параметры клиента и хеш токена доступа к регистрации, кроме секрета.
+	// Возвращает domain.ErrClientNotFound, если клиент не найден.
+	Update(ctx context.Context, client *domain.OAuthClient) error
+	// UpdateSecret заменяет хеш секрета клиента.
+	// Возвращает domain.ErrClientNotFound, если клиент не найден.
+	UpdateSecret(ctx context.Context, clientID, secretHash string) error
+	// Delete удаляет клиента вместе с его неиспользованными кодами авторизации.
+	// Возвращает domain.ErrClientNotFound, если клиент не найден.
+	Delete(ctx context.Context, clientID string) error
+}
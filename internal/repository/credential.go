@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/uuid"
+)
+
+// ICredentialRepo - интерфейс для работы с учетными данными пользователей в базе данных
+type ICredentialRepo interface {
+	// GetByEmail возвращает guid пользователя и хеш его пароля по email
+	GetByEmail(ctx context.Context, email string) (uuid.UUID, string, error)
+	// GetPasswordHash возвращает хеш пароля пользователя по его guid
+	GetPasswordHash(ctx context.Context, userID uuid.UUID) (string, error)
+	// SetPassword сохраняет хеш пароля пользователя, заменяя предыдущий
+	SetPassword(ctx context.Context, userID uuid.UUID, passwordHash string) error
+}
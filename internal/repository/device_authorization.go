@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"time"
+)
+
+// IDeviceAuthorizationRepo - интерфейс для работы с запросами авторизации устройств OAuth в базе данных
+type IDeviceAuthorizationRepo interface {
+	// Create сохраняет новый запрос авторизации устройства
+	Create(ctx context.Context, authorization *domain.DeviceAuthorization) error
+	// GetPendingByUserCode возвращает ожидающий подтверждения запрос по хешу пользовательского кода.
+	// Возвращает domain.ErrInvalidUserCode, если запрос не найден, истек или уже подтвержден.
+	GetPendingByUserCode(ctx context.Context, userCodeHash string, now time.Time) (*domain.DeviceAuthorization, error)
+	// Complete переводит ожидающий запрос в состояние status от имени пользователя userID.
+	// Возвращает domain.ErrInvalidUserCode, если запрос не найден, истек или уже подтвержден.
+	Complete(ctx context.Context, userCodeHash string, status domain.DeviceAuthorizationStatus, userID uuid.UUID, methods []domain.AuthMethod, now time.Time) error
+	// Poll отмечает опрос запроса устройством и возвращает запрос со временем предыдущего опроса.
+	// Возвращает domain.ErrInvalidGrant, если запрос не найден.
+	Poll(ctx context.Context, deviceCodeHash string, now time.Time) (*domain.DeviceAuthorization, error)
+	// SlowDown увеличивает минимальный интервал опроса запроса
+	SlowDown(ctx context.Context, deviceCodeHash string, interval time.Duration) error
+	// Consume атомарно удаляет подтвержденный или отклоненный запрос по хешу кода устройства и возвращает его.
+	// Возвращает domain.ErrInvalidGrant, если запрос не найден или еще ожидает подтверждения.
+	Consume(ctx context.Context, deviceCodeHash string) (*domain.DeviceAuthorization, error)
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"time"
+)
+
+// IEmailVerificationRepo - интерфейс для работы с токенами подтверждения email в базе данных
+type IEmailVerificationRepo interface {
+	// Create сохраняет новый токен подтверждения email
+	Create(ctx context.Context, verification *domain.EmailVerification) error
+	// LastCreatedAt возвращает время выдачи последнего токена пользователя или нулевое время, если токенов нет
+	LastCreatedAt(ctx context.Context, userID uuid.UUID) (time.Time, error)
+	// Consume атомарно удаляет токен по хешу и отмечает email пользователя подтвержденным.
+	// Возвращает guid пользователя или domain.ErrInvalidVerificationToken, если токен не найден, истек
+	// или email пользователя изменился после его выдачи. Остальные токены пользователя также удаляются.
+	Consume(ctx context.Context, tokenHash string, now time.Time) (uuid.UUID, error)
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"time"
+)
+
+// IMagicLinkRepo - интерфейс для учета использованных ссылок для входа в базе данных
+type IMagicLinkRepo interface {
+	// Consume атомарно отмечает ссылку с указанным ID как использованную.
+	// Возвращает false, если ссылка уже была использована.
+	// expiresAt - время истечения ссылки, после которого запись можно удалить.
+	Consume(ctx context.Context, id, userID uuid.UUID, expiresAt time.Time) (bool, error)
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"time"
+)
+
+// IMFAChallengeRepo - интерфейс для работы с MFA-челленджами в базе данных
+type IMFAChallengeRepo interface {
+	Create(ctx context.Context, challenge *domain.MFAChallenge) error                                       // Create сохраняет новый челлендж
+	GetByTokenHash(ctx context.Context, tokenHash string, notAfter time.Time) (*domain.MFAChallenge, error) // GetByTokenHash возвращает не истекший к моменту notAfter челлендж по хешу токена
+	IncrementAttempts(ctx context.Context, id uuid.UUID) (int, error)                                       // IncrementAttempts увеличивает счетчик неудачных попыток и возвращает его новое значение
+	Delete(ctx context.Context, id uuid.UUID) error                                                         // Delete удаляет челлендж
+}
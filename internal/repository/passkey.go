@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+)
+
+// IPasskeyRepo - интерфейс для работы с passkey пользователей в базе данных
+type IPasskeyRepo interface {
+	// Create сохраняет новый passkey
+	Create(ctx context.Context, passkey *domain.Passkey) error
+	// Get возвращает passkey по ID учетных данных
+	Get(ctx context.Context, credentialID []byte) (*domain.Passkey, error)
+	// ListCredentialIDs возвращает ID учетных данных всех passkey пользователя
+	ListCredentialIDs(ctx context.Context, userID uuid.UUID) ([][]byte, error)
+	// UpdateSignCount атомарно сохраняет новое значение счетчика подписей и время использования passkey.
+	// Возвращает false, если счетчик был изменен параллельным входом.
+	UpdateSignCount(ctx context.Context, credentialID []byte, signCount uint32) (bool, error)
+}
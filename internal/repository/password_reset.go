@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"time"
+)
+
+// IPasswordResetRepo - интерфейс для работы с токенами сброса пароля в базе данных
+type IPasswordResetRepo interface {
+	// Create сохраняет новый токен сброса пароля
+	Create(ctx context.Context, reset *domain.PasswordReset) error
+	// Reset атомарно удаляет токен по хешу, устанавливает пользователю новый хеш пароля
+	// и удаляет все его Refresh токены и остальные токены сброса пароля.
+	// Возвращает guid пользователя и количество отозванных Refresh токенов
+	// или domain.ErrInvalidResetToken, если токен не найден или истек.
+	Reset(ctx context.Context, tokenHash, passwordHash string, now time.Time) (uuid.UUID, int, error)
+}
@@ -0,0 +1,90 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+)
+
+// PostgresqlAllowlistRepo - имплементация интерфейса repository.IAllowlistRepo.
+// Позволяет взаимодействовать со списком разрешенных GUID в Postgresql
+type PostgresqlAllowlistRepo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+// Add добавляет GUID в список разрешенных одним запросом.
+// Уже присутствующие GUID пропускаются. Возвращает количество добавленных записей.
+func (r *PostgresqlAllowlistRepo) Add(ctx context.Context, guids []uuid.UUID) (int, error) {
+	if len(guids) == 0 {
+		return 0, nil
+	}
+
+	raw := make([]string, len(guids))
+	for i, guid := range guids {
+		raw[i] = guid.String()
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		"INSERT INTO provisioning_allowlist (guid) SELECT unnest($1::uuid[]) ON CONFLICT (guid) DO NOTHING",
+		pq.Array(raw))
+	if err != nil {
+		r.logger.Error("Error inserting allowlist entries", zap.Error(err))
+		return 0, err
+	}
+
+	added, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Error getting rows affected", zap.Error(err))
+		return 0, err
+	}
+
+	return int(added), nil
+}
+
+// Remove удаляет GUID из списка разрешенных.
+// Если GUID отсутствует в списке, возвращает ошибку domain.ErrAllowlistEntryNotFound.
+func (r *PostgresqlAllowlistRepo) Remove(ctx context.Context, guid uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM provisioning_allowlist WHERE guid = $1", guid)
+	if err != nil {
+		r.logger.Error("Error deleting allowlist entry", zap.Error(err))
+		return err
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Error getting rows affected", zap.Error(err))
+		return err
+	}
+
+	if removed == 0 {
+		return domain.ErrAllowlistEntryNotFound
+	}
+
+	return nil
+}
+
+// Contains проверяет, присутствует ли GUID в списке разрешенных.
+func (r *PostgresqlAllowlistRepo) Contains(ctx context.Context, guid uuid.UUID) (bool, error) {
+	var exists bool
+
+	err := r.db.GetContext(ctx, &exists, "SELECT EXISTS(SELECT 1 FROM provisioning_allowlist WHERE guid = $1)", guid)
+	if err != nil {
+		r.logger.Error("Error querying allowlist", zap.Error(err))
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// NewPostgresqlAllowlistRepo - конструктор для создания нового экземпляра PostgresqlAllowlistRepo.
+func NewPostgresqlAllowlistRepo(db *sqlx.DB, logger *zap.Logger) repository.IAllowlistRepo {
+	return &PostgresqlAllowlistRepo{
+		db:     db,
+		logger: logger,
+	}
+}
@@ -0,0 +1,106 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+)
+
+func getMockAllowlistRepo(t *testing.T) (repository.IAllowlistRepo, sqlmock.Sqlmock, func()) {
+	logger := zap.NewNop()
+
+	mockDB, mock, err := sqlmock.New()
+
+	require.NoError(t, err, "creating mock db")
+
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	repo := NewPostgresqlAllowlistRepo(sqlxDB, logger)
+
+	cleanup := func() {
+		mockDB.Close()
+	}
+
+	return repo, mock, cleanup
+}
+
+func TestPostgresqlAllowlistRepo_Add(t *testing.T) {
+	repo, mock, cleanup := getMockAllowlistRepo(t)
+	defer cleanup()
+
+	t.Run("Success", func(t *testing.T) {
+		guids := []uuid.UUID{uuid.New(), uuid.New()}
+
+		mock.ExpectExec("INSERT INTO provisioning_allowlist").
+			WithArgs(sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		added, err := repo.Add(context.Background(), guids)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, added)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		added, err := repo.Add(context.Background(), nil)
+		assert.NoError(t, err)
+		assert.Zero(t, added)
+	})
+
+	t.Run("DB error", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO provisioning_allowlist").
+			WithArgs(sqlmock.AnyArg()).
+			WillReturnError(sql.ErrConnDone)
+
+		_, err := repo.Add(context.Background(), []uuid.UUID{uuid.New()})
+		assert.Error(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlAllowlistRepo_Remove(t *testing.T) {
+	repo, mock, cleanup := getMockAllowlistRepo(t)
+	defer cleanup()
+
+	t.Run("Success", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectExec("DELETE FROM provisioning_allowlist").
+			WithArgs(guid).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Remove(context.Background(), guid)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectExec("DELETE FROM provisioning_allowlist").
+			WithArgs(guid).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Remove(context.Background(), guid)
+		assert.ErrorIs(t, err, domain.ErrAllowlistEntryNotFound)
+	})
+}
+
+func TestPostgresqlAllowlistRepo_Contains(t *testing.T) {
+	repo, mock, cleanup := getMockAllowlistRepo(t)
+	defer cleanup()
+
+	guid := uuid.New()
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs(guid).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	allowed, err := repo.Contains(context.Background(), guid)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+}
@@ -0,0 +1,145 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+// PostgresqlAuditRepo - имплементация интерфейса repository.IAuditRepo.
+// Позволяет записывать события аутентификации в таблицу auth_events в Postgresql
+type PostgresqlAuditRepo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+// nullUUID преобразует uuid.Nil в NULL.
+func nullUUID(id uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{UUID: id, Valid: id != uuid.Nil}
+}
+
+// Create добавляет событие в журнал аудита.
+// Нулевые GUID и JTI сохраняются как NULL, дополнительные данные - как JSONB.
+func (r *PostgresqlAuditRepo) Create(ctx context.Context, event *domain.AuthEvent) error {
+	var details []byte
+	if len(event.Details) > 0 {
+		var err error
+		details, err = json.Marshal(event.Details)
+		if err != nil {
+			r.logger.Error("error marshalling audit event details", zap.Error(err))
+			return err
+		}
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO auth_events (id, type, user_id, jti, ip, user_agent, request_id, reason, details, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+		event.ID, event.Type, nullUUID(event.GUID), nullUUID(event.JTI), event.IP, event.UserAgent, event.RequestID, event.Reason, details, event.CreatedAt,
+	)
+	if err != nil {
+		r.logger.Error("error creating audit event", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// auditEventRow - строка таблицы auth_events.
+type auditEventRow struct {
+	ID        uuid.UUID     `db:"id"`
+	Type      string        `db:"type"`
+	UserID    uuid.NullUUID `db:"user_id"`
+	JTI       uuid.NullUUID `db:"jti"`
+	IP        string        `db:"ip"`
+	UserAgent string        `db:"user_agent"`
+	RequestID string        `db:"request_id"`
+	Reason    string        `db:"reason"`
+	Details   []byte        `db:"details"`
+	CreatedAt time.Time     `db:"created_at"`
+}
+
+// List возвращает события журнала аудита, удовлетворяющие фильтру.
+// События упорядочены от новых к старым по времени создания и ID, что позволяет
+// использовать domain.AuditCursor для постраничной выборки.
+func (r *PostgresqlAuditRepo) List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuthEvent, error) {
+	var conditions []string
+	var args []any
+
+	addCondition := func(condition string, values ...any) {
+		for _, value := range values {
+			args = append(args, value)
+			condition = strings.Replace(condition, "?", fmt.Sprintf("$%d", len(args)), 1)
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if filter.GUID != uuid.Nil {
+		addCondition("user_id = ?", filter.GUID)
+	}
+	if filter.Type != "" {
+		addCondition("type = ?", filter.Type)
+	}
+	if filter.IP != "" {
+		addCondition("ip = ?", filter.IP)
+	}
+	if !filter.From.IsZero() {
+		addCondition("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("created_at < ?", filter.To)
+	}
+	if filter.After != nil {
+		addCondition("(created_at, id) < (?, ?)", filter.After.CreatedAt, filter.After.ID)
+	}
+
+	query := "SELECT id, type, user_id, jti, ip, user_agent, request_id, reason, details, created_at FROM auth_events"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args))
+
+	var rows []auditEventRow
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		r.logger.Error("error listing audit events", zap.Error(err))
+		return nil, err
+	}
+
+	events := make([]domain.AuthEvent, 0, len(rows))
+	for _, row := range rows {
+		event := domain.AuthEvent{
+			ID:        row.ID,
+			Type:      domain.AuthEventType(row.Type),
+			GUID:      row.UserID.UUID,
+			JTI:       row.JTI.UUID,
+			IP:        row.IP,
+			UserAgent: row.UserAgent,
+			RequestID: row.RequestID,
+			Reason:    row.Reason,
+			CreatedAt: row.CreatedAt,
+		}
+		if len(row.Details) > 0 {
+			if err := json.Unmarshal(row.Details, &event.Details); err != nil {
+				r.logger.Error("error unmarshalling audit event details", zap.Error(err))
+				return nil, err
+			}
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// NewPostgresqlAuditRepo - конструктор для создания нового экземпляра PostgresqlAuditRepo.
+func NewPostgresqlAuditRepo(db *sqlx.DB, logger *zap.Logger) repository.IAuditRepo {
+	return &PostgresqlAuditRepo{
+		db:     db,
+		logger: logger,
+	}
+}
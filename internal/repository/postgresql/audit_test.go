@@ -0,0 +1,140 @@
+package postgresqlrepo_test
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"github.com/maksemen2/medods-task/internal/repository/postgresql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func getMockAuditRepo(t *testing.T) (repository.IAuditRepo, sqlmock.Sqlmock, func()) {
+	logger := zap.NewNop()
+
+	mockDB, mock, err := sqlmock.New()
+
+	require.NoError(t, err, "creating mock db")
+
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	repo := postgresqlrepo.NewPostgresqlAuditRepo(sqlxDB, logger)
+
+	cleanup := func() {
+		mockDB.Close()
+	}
+
+	return repo, mock, cleanup
+}
+
+func TestPostgresqlAuditRepo_Create(t *testing.T) {
+	repo, mock, cleanup := getMockAuditRepo(t)
+	defer cleanup()
+
+	t.Run("Success", func(t *testing.T) {
+		event := &domain.AuthEvent{
+			ID:        uuid.New(),
+			Type:      domain.AuthEventTokenIssued,
+			GUID:      uuid.New(),
+			JTI:       uuid.New(),
+			IP:        "127.0.0.1",
+			UserAgent: "curl/8.0",
+			RequestID: "request-id",
+			Details:   map[string]any{"country": "RU"},
+			CreatedAt: time.Now(),
+		}
+
+		mock.ExpectExec("INSERT INTO auth_events").
+			WithArgs(event.ID, event.Type, uuid.NullUUID{UUID: event.GUID, Valid: true}, uuid.NullUUID{UUID: event.JTI, Valid: true},
+				event.IP, event.UserAgent, event.RequestID, "", []byte(`{"country":"RU"}`), event.CreatedAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err := repo.Create(context.Background(), event)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Unknown User", func(t *testing.T) {
+		event := &domain.AuthEvent{
+			ID:        uuid.New(),
+			Type:      domain.AuthEventRefreshFailed,
+			IP:        "127.0.0.1",
+			Reason:    domain.FailureReasonBadAccessToken,
+			CreatedAt: time.Now(),
+		}
+
+		mock.ExpectExec("INSERT INTO auth_events").
+			WithArgs(event.ID, event.Type, uuid.NullUUID{}, uuid.NullUUID{},
+				event.IP, "", "", event.Reason, []byte(nil), event.CreatedAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err := repo.Create(context.Background(), event)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Database Error", func(t *testing.T) {
+		event := &domain.AuthEvent{ID: uuid.New(), Type: domain.AuthEventTokenIssued, CreatedAt: time.Now()}
+
+		mock.ExpectExec("INSERT INTO auth_events").
+			WillReturnError(errors.New("connection refused"))
+
+		err := repo.Create(context.Background(), event)
+		assert.Error(t, err)
+	})
+}
+
+func TestPostgresqlAuditRepo_List(t *testing.T) {
+	repo, mock, cleanup := getMockAuditRepo(t)
+	defer cleanup()
+
+	columns := []string{"id", "type", "user_id", "jti", "ip", "user_agent", "request_id", "reason", "details", "created_at"}
+
+	t.Run("Success", func(t *testing.T) {
+		guid := uuid.New()
+		id := uuid.New()
+		createdAt := time.Now()
+
+		mock.ExpectQuery(`SELECT (.+) FROM auth_events ORDER BY created_at DESC, id DESC LIMIT \$1`).
+			WithArgs(10).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(id, "token_issued", guid, nil, "127.0.0.1", "curl/8.0", "request-id", "", []byte(`{"country":"RU"}`), createdAt))
+
+		events, err := repo.List(context.Background(), domain.AuditFilter{Limit: 10})
+		assert.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, id, events[0].ID)
+		assert.Equal(t, domain.AuthEventTokenIssued, events[0].Type)
+		assert.Equal(t, guid, events[0].GUID)
+		assert.Equal(t, uuid.Nil, events[0].JTI)
+		assert.Equal(t, map[string]any{"country": "RU"}, events[0].Details)
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		guid := uuid.New()
+		from := time.Now().Add(-time.Hour)
+		to := time.Now()
+		cursor := &domain.AuditCursor{CreatedAt: time.Now(), ID: uuid.New()}
+
+		mock.ExpectQuery(`SELECT (.+) FROM auth_events WHERE user_id = \$1 AND type = \$2 AND ip = \$3 AND created_at >= \$4 AND created_at < \$5 AND \(created_at, id\) < \(\$6, \$7\) ORDER BY created_at DESC, id DESC LIMIT \$8`).
+			WithArgs(guid, domain.AuthEventRefreshFailed, "127.0.0.1", from, to, cursor.CreatedAt, cursor.ID, 50).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		events, err := repo.List(context.Background(), domain.AuditFilter{
+			GUID:  guid,
+			Type:  domain.AuthEventRefreshFailed,
+			IP:    "127.0.0.1",
+			From:  from,
+			To:    to,
+			After: cursor,
+			Limit: 50,
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, events)
+	})
+}
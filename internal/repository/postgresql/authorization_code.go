@@ -0,0 +1,98 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	"time"
+)
+
+// PostgresqlAuthorizationCodeRepo - имплементация интерфейса repository.IAuthorizationCodeRepo.
+// Позволяет взаимодействовать с кодами авторизации OAuth в Postgresql
+type PostgresqlAuthorizationCodeRepo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+// authorizationCodeRow - строка таблицы authorization_codes.
+type authorizationCodeRow struct {
+	CodeHash      string         `db:"code_hash"`
+	ClientID      string         `db:"client_id"`
+	UserID        uuid.UUID      `db:"user_id"`
+	RedirectURI   string         `db:"redirect_uri"`
+	CodeChallenge string         `db:"code_challenge"`
+	AuthMethods   pq.StringArray `db:"auth_methods"`
+	Scopes        pq.StringArray `db:"scopes"`
+	ExpiresAt     time.Time      `db:"expires_at"`
+}
+
+// Create сохраняет новый код авторизации.
+func (r *PostgresqlAuthorizationCodeRepo) Create(ctx context.Context, code *domain.AuthorizationCode) error {
+	methods := make([]string, len(code.AuthMethods))
+	for i, method := range code.AuthMethods {
+		methods[i] = string(method)
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO authorization_codes (code_hash, client_id, user_id, redirect_uri, code_challenge, auth_methods, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		code.CodeHash, code.ClientID, code.UserID, code.RedirectURI, code.CodeChallenge, pq.Array(methods), pq.Array(code.Scopes), code.ExpiresAt)
+	if err != nil {
+		r.logger.Error("Error inserting authorization code", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Consume удаляет код по хешу и возвращает его. Код удаляется одним запросом,
+// поэтому параллельные запросы не могут обменять его дважды.
+// Истекший код удаляется так же, как действующий, но возвращается domain.ErrInvalidGrant.
+func (r *PostgresqlAuthorizationCodeRepo) Consume(ctx context.Context, codeHash string, now time.Time) (*domain.AuthorizationCode, error) {
+	var row authorizationCodeRow
+
+	err := r.db.GetContext(ctx, &row,
+		`DELETE FROM authorization_codes WHERE code_hash = $1
+		RETURNING code_hash, client_id, user_id, redirect_uri, code_challenge, auth_methods, scopes, expires_at`,
+		codeHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrInvalidGrant
+		}
+		r.logger.Error("Error consuming authorization code", zap.Error(err))
+		return nil, err
+	}
+
+	if !row.ExpiresAt.After(now) {
+		return nil, domain.ErrInvalidGrant
+	}
+
+	methods := make([]domain.AuthMethod, len(row.AuthMethods))
+	for i, method := range row.AuthMethods {
+		methods[i] = domain.AuthMethod(method)
+	}
+
+	return &domain.AuthorizationCode{
+		CodeHash:      row.CodeHash,
+		ClientID:      row.ClientID,
+		UserID:        row.UserID,
+		RedirectURI:   row.RedirectURI,
+		CodeChallenge: row.CodeChallenge,
+		AuthMethods:   methods,
+		Scopes:        row.Scopes,
+		ExpiresAt:     row.ExpiresAt,
+	}, nil
+}
+
+// NewPostgresqlAuthorizationCodeRepo - конструктор для создания нового экземпляра PostgresqlAuthorizationCodeRepo.
+func NewPostgresqlAuthorizationCodeRepo(db *sqlx.DB, logger *zap.Logger) repository.IAuthorizationCodeRepo {
+	return &PostgresqlAuthorizationCodeRepo{
+		db:     db,
+		logger: logger,
+	}
+}
@@ -0,0 +1,101 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func getMockAuthorizationCodeRepo(t *testing.T) (repository.IAuthorizationCodeRepo, sqlmock.Sqlmock, func()) {
+	logger := zap.NewNop()
+
+	mockDB, mock, err := sqlmock.New()
+
+	require.NoError(t, err, "creating mock db")
+
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	repo := NewPostgresqlAuthorizationCodeRepo(sqlxDB, logger)
+
+	cleanup := func() {
+		mockDB.Close()
+	}
+
+	return repo, mock, cleanup
+}
+
+func TestPostgresqlAuthorizationCodeRepo_Create(t *testing.T) {
+	repo, mock, cleanup := getMockAuthorizationCodeRepo(t)
+	defer cleanup()
+
+	code := &domain.AuthorizationCode{
+		CodeHash:      "hash",
+		ClientID:      "spa",
+		UserID:        uuid.New(),
+		RedirectURI:   "https://app.example.com/callback",
+		CodeChallenge: "challenge",
+		AuthMethods:   []domain.AuthMethod{domain.AuthMethodPassword, domain.AuthMethodTOTP},
+		Scopes:        []string{"profile"},
+		ExpiresAt:     time.Now().Add(time.Minute),
+	}
+
+	mock.ExpectExec("INSERT INTO authorization_codes").
+		WithArgs("hash", "spa", code.UserID, code.RedirectURI, "challenge", `{"password","totp"}`, `{"profile"}`, code.ExpiresAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.Create(context.Background(), code))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlAuthorizationCodeRepo_Consume(t *testing.T) {
+	repo, mock, cleanup := getMockAuthorizationCodeRepo(t)
+	defer cleanup()
+
+	now := time.Now()
+	guid := uuid.New()
+	columns := []string{"code_hash", "client_id", "user_id", "redirect_uri", "code_challenge", "auth_methods", "scopes", "expires_at"}
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectQuery("DELETE FROM authorization_codes WHERE code_hash").
+			WithArgs("hash").
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow("hash", "spa", guid, "https://app.example.com/callback", "challenge", `{password,totp}`, `{profile}`, now.Add(time.Minute)))
+
+		code, err := repo.Consume(context.Background(), "hash", now)
+		require.NoError(t, err)
+		assert.Equal(t, guid, code.UserID)
+		assert.Equal(t, "spa", code.ClientID)
+		assert.Equal(t, []domain.AuthMethod{domain.AuthMethodPassword, domain.AuthMethodTOTP}, code.AuthMethods)
+		assert.Equal(t, []string{"profile"}, code.Scopes)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mock.ExpectQuery("DELETE FROM authorization_codes WHERE code_hash").
+			WithArgs("hash").
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.Consume(context.Background(), "hash", now)
+		assert.ErrorIs(t, err, domain.ErrInvalidGrant)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		mock.ExpectQuery("DELETE FROM authorization_codes WHERE code_hash").
+			WithArgs("hash").
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow("hash", "spa", guid, "https://app.example.com/callback", "challenge", `{password}`, `{}`, now.Add(-time.Second)))
+
+		_, err := repo.Consume(context.Background(), "hash", now)
+		assert.ErrorIs(t, err, domain.ErrInvalidGrant)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
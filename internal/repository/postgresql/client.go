@@ -0,0 +1,177 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	"time"
+)
+
+const clientColumns = `id, name, secret_hash, grant_types, redirect_uris, scopes, access_ttl_seconds, refresh_ttl_seconds, registration_token_hash, created_at, updated_at`
+
+// PostgresqlClientRepo - имплементация интерфейса repository.IClientRepo.
+// Позволяет взаимодействовать с реестром клиентов OAuth в Postgresql
+type PostgresqlClientRepo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+// clientRow - строка таблицы oauth_clients.
+type clientRow struct {
+	ID                string         `db:"id"`
+	Name              string         `db:"name"`
+	SecretHash        string         `db:"secret_hash"`
+	GrantTypes        pq.StringArray `db:"grant_types"`
+	RedirectURIs      pq.StringArray `db:"redirect_uris"`
+	Scopes            pq.StringArray `db:"scopes"`
+	AccessTTLSeconds  int            `db:"access_ttl_seconds"`
+	RefreshTTLSeconds int            `db:"refresh_ttl_seconds"`
+	RegistrationHash  string         `db:"registration_token_hash"`
+	CreatedAt         time.Time      `db:"created_at"`
+	UpdatedAt         time.Time      `db:"updated_at"`
+}
+
+// toDomain преобразует строку таблицы в доменную модель.
+func (row *clientRow) toDomain() domain.OAuthClient {
+	grantTypes := make([]domain.GrantType, len(row.GrantTypes))
+	for i, grantType := range row.GrantTypes {
+		grantTypes[i] = domain.GrantType(grantType)
+	}
+
+	return domain.OAuthClient{
+		ID:           row.ID,
+		Name:         row.Name,
+		SecretHash:   row.SecretHash,
+		GrantTypes:   grantTypes,
+		RedirectURIs: row.RedirectURIs,
+		Scopes:       row.Scopes,
+		AccessTTL:    time.Duration(row.AccessTTLSeconds) * time.Second,
+		RefreshTTL:   time.Duration(row.RefreshTTLSeconds) * time.Second,
+
+		RegistrationTokenHash: row.RegistrationHash,
+		CreatedAt:             row.CreatedAt,
+		UpdatedAt:             row.UpdatedAt,
+	}
+}
+
+// grantTypesArray преобразует способы получения токенов в массив Postgresql.
+func grantTypesArray(grantTypes []domain.GrantType) pq.StringArray {
+	raw := make([]string, len(grantTypes))
+	for i, grantType := range grantTypes {
+		raw[i] = string(grantType)
+	}
+	return pq.StringArray(raw)
+}
+
+// Create сохраняет нового клиента.
+func (r *PostgresqlClientRepo) Create(ctx context.Context, client *domain.OAuthClient) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO oauth_clients (id, name, secret_hash, grant_types, redirect_uris, scopes, access_ttl_seconds, refresh_ttl_seconds, registration_token_hash, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		client.ID, client.Name, client.SecretHash, grantTypesArray(client.GrantTypes), pq.Array(client.RedirectURIs), pq.Array(client.Scopes),
+		int(client.AccessTTL.Seconds()), int(client.RefreshTTL.Seconds()), client.RegistrationTokenHash, client.CreatedAt, client.UpdatedAt)
+	if err != nil {
+		r.logger.Error("Error inserting oauth client", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Get возвращает клиента по идентификатору.
+func (r *PostgresqlClientRepo) Get(ctx context.Context, clientID string) (*domain.OAuthClient, error) {
+	var row clientRow
+
+	err := r.db.GetContext(ctx, &row, `SELECT `+clientColumns+` FROM oauth_clients WHERE id = $1`, clientID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrClientNotFound
+		}
+		r.logger.Error("Error getting oauth client", zap.Error(err))
+		return nil, err
+	}
+
+	client := row.toDomain()
+	return &client, nil
+}
+
+// List возвращает всех клиентов в порядке регистрации.
+func (r *PostgresqlClientRepo) List(ctx context.Context) ([]domain.OAuthClient, error) {
+	var rows []clientRow
+
+	err := r.db.SelectContext(ctx, &rows, `SELECT `+clientColumns+` FROM oauth_clients ORDER BY created_at, id`)
+	if err != nil {
+		r.logger.Error("Error listing oauth clients", zap.Error(err))
+		return nil, err
+	}
+
+	clients := make([]domain.OAuthClient, len(rows))
+	for i := range rows {
+		clients[i] = rows[i].toDomain()
+	}
+	return clients, nil
+}
+
+// Update заменяет параметры клиента и хеш токена доступа к регистрации. Секрет и время регистрации не меняются.
+func (r *PostgresqlClientRepo) Update(ctx context.Context, client *domain.OAuthClient) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE oauth_clients SET name = $2, grant_types = $3, redirect_uris = $4, scopes = $5,
+		access_ttl_seconds = $6, refresh_ttl_seconds = $7, registration_token_hash = $8, updated_at = $9 WHERE id = $1`,
+		client.ID, client.Name, grantTypesArray(client.GrantTypes), pq.Array(client.RedirectURIs), pq.Array(client.Scopes),
+		int(client.AccessTTL.Seconds()), int(client.RefreshTTL.Seconds()), client.RegistrationTokenHash, client.UpdatedAt)
+	if err != nil {
+		r.logger.Error("Error updating oauth client", zap.Error(err))
+		return err
+	}
+
+	return r.checkAffected(result)
+}
+
+// UpdateSecret заменяет хеш секрета клиента.
+func (r *PostgresqlClientRepo) UpdateSecret(ctx context.Context, clientID, secretHash string) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE oauth_clients SET secret_hash = $2, updated_at = now() WHERE id = $1`, clientID, secretHash)
+	if err != nil {
+		r.logger.Error("Error updating oauth client secret", zap.Error(err))
+		return err
+	}
+
+	return r.checkAffected(result)
+}
+
+// Delete удаляет клиента. Коды авторизации клиента удаляются каскадно.
+func (r *PostgresqlClientRepo) Delete(ctx context.Context, clientID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM oauth_clients WHERE id = $1`, clientID)
+	if err != nil {
+		r.logger.Error("Error deleting oauth client", zap.Error(err))
+		return err
+	}
+
+	return r.checkAffected(result)
+}
+
+// checkAffected возвращает domain.ErrClientNotFound, если запрос не затронул ни одной строки.
+func (r *PostgresqlClientRepo) checkAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Error getting rows affected", zap.Error(err))
+		return err
+	}
+
+	if affected == 0 {
+		return domain.ErrClientNotFound
+	}
+	return nil
+}
+
+// NewPostgresqlClientRepo - конструктор для создания нового экземпляра PostgresqlClientRepo.
+func NewPostgresqlClientRepo(db *sqlx.DB, logger *zap.Logger) repository.IClientRepo {
+	return &PostgresqlClientRepo{
+		db:     db,
+		logger: logger,
+	}
+}
@@ -0,0 +1,109 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func getMockClientRepo(t *testing.T) (repository.IClientRepo, sqlmock.Sqlmock, func()) {
+	logger := zap.NewNop()
+
+	mockDB, mock, err := sqlmock.New()
+
+	require.NoError(t, err, "creating mock db")
+
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	repo := NewPostgresqlClientRepo(sqlxDB, logger)
+
+	cleanup := func() {
+		mockDB.Close()
+	}
+
+	return repo, mock, cleanup
+}
+
+func TestPostgresqlClientRepo_Create(t *testing.T) {
+	repo, mock, cleanup := getMockClientRepo(t)
+	defer cleanup()
+
+	now := time.Now()
+	client := &domain.OAuthClient{
+		ID:           "spa",
+		Name:         "SPA",
+		GrantTypes:   []domain.GrantType{domain.GrantTypeAuthorizationCode, domain.GrantTypeRefreshToken},
+		RedirectURIs: []string{"https://app.example.com/callback"},
+		Scopes:       []string{"profile"},
+		AccessTTL:    5 * time.Minute,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	mock.ExpectExec("INSERT INTO oauth_clients").
+		WithArgs("spa", "SPA", "", `{"authorization_code","refresh_token"}`, `{"https://app.example.com/callback"}`, `{"profile"}`, 300, 0, "", now, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.Create(context.Background(), client))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlClientRepo_Get(t *testing.T) {
+	repo, mock, cleanup := getMockClientRepo(t)
+	defer cleanup()
+
+	now := time.Now()
+	columns := []string{"id", "name", "secret_hash", "grant_types", "redirect_uris", "scopes", "access_ttl_seconds", "refresh_ttl_seconds", "registration_token_hash", "created_at", "updated_at"}
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM oauth_clients WHERE id").
+			WithArgs("spa").
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow("spa", "SPA", "hash", `{authorization_code}`, `{https://app.example.com/callback}`, `{}`, 300, 3600, "registration", now, now))
+
+		client, err := repo.Get(context.Background(), "spa")
+		require.NoError(t, err)
+		assert.Equal(t, []domain.GrantType{domain.GrantTypeAuthorizationCode}, client.GrantTypes)
+		assert.Equal(t, []string{"https://app.example.com/callback"}, client.RedirectURIs)
+		assert.Equal(t, 5*time.Minute, client.AccessTTL)
+		assert.Equal(t, time.Hour, client.RefreshTTL)
+		assert.True(t, client.Confidential())
+		assert.Equal(t, "registration", client.RegistrationTokenHash)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM oauth_clients WHERE id").
+			WithArgs("spa").
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.Get(context.Background(), "spa")
+		assert.ErrorIs(t, err, domain.ErrClientNotFound)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlClientRepo_Delete(t *testing.T) {
+	repo, mock, cleanup := getMockClientRepo(t)
+	defer cleanup()
+
+	mock.ExpectExec("DELETE FROM oauth_clients WHERE id").
+		WithArgs("spa").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.Delete(context.Background(), "spa"))
+
+	mock.ExpectExec("DELETE FROM oauth_clients WHERE id").
+		WithArgs("other").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.Delete(context.Background(), "other"), domain.ErrClientNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
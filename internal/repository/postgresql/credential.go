@@ -0,0 +1,80 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	"time"
+)
+
+// PostgresqlCredentialRepo - имплементация интерфейса repository.ICredentialRepo.
+// Позволяет взаимодействовать с учетными данными пользователей в Postgresql
+type PostgresqlCredentialRepo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+// GetByEmail возвращает guid пользователя и хеш его пароля по email.
+// Если пользователь не найден или у него не задан пароль, возвращает ошибку domain.ErrCredentialsNotFound.
+func (r *PostgresqlCredentialRepo) GetByEmail(ctx context.Context, email string) (uuid.UUID, string, error) {
+	var row struct {
+		UserID       uuid.UUID `db:"user_id"`
+		PasswordHash string    `db:"password_hash"`
+	}
+
+	err := r.db.GetContext(ctx, &row,
+		"SELECT c.user_id, c.password_hash FROM credentials c JOIN users u ON u.guid = c.user_id WHERE u.email = $1", email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, "", domain.ErrCredentialsNotFound
+		}
+		r.logger.Error("Error querying credentials by email", zap.Error(err))
+		return uuid.Nil, "", err
+	}
+
+	return row.UserID, row.PasswordHash, nil
+}
+
+// GetPasswordHash возвращает хеш пароля пользователя по его guid.
+// Если у пользователя не задан пароль, возвращает ошибку domain.ErrCredentialsNotFound.
+func (r *PostgresqlCredentialRepo) GetPasswordHash(ctx context.Context, userID uuid.UUID) (string, error) {
+	var hash string
+
+	err := r.db.GetContext(ctx, &hash, "SELECT password_hash FROM credentials WHERE user_id = $1", userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", domain.ErrCredentialsNotFound
+		}
+		r.logger.Error("Error querying credentials", zap.Error(err))
+		return "", err
+	}
+
+	return hash, nil
+}
+
+// SetPassword сохраняет хеш пароля пользователя, заменяя предыдущий.
+func (r *PostgresqlCredentialRepo) SetPassword(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO credentials (user_id, password_hash, updated_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET password_hash = EXCLUDED.password_hash, updated_at = EXCLUDED.updated_at`,
+		userID, passwordHash, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("Error saving credentials", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// NewPostgresqlCredentialRepo - конструктор для создания нового экземпляра PostgresqlCredentialRepo.
+func NewPostgresqlCredentialRepo(db *sqlx.DB, logger *zap.Logger) repository.ICredentialRepo {
+	return &PostgresqlCredentialRepo{
+		db:     db,
+		logger: logger,
+	}
+}
@@ -0,0 +1,99 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+)
+
+func getMockCredentialRepo(t *testing.T) (repository.ICredentialRepo, sqlmock.Sqlmock, func()) {
+	logger := zap.NewNop()
+
+	mockDB, mock, err := sqlmock.New()
+
+	require.NoError(t, err, "creating mock db")
+
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	repo := NewPostgresqlCredentialRepo(sqlxDB, logger)
+
+	cleanup := func() {
+		mockDB.Close()
+	}
+
+	return repo, mock, cleanup
+}
+
+func TestPostgresqlCredentialRepo_GetByEmail(t *testing.T) {
+	repo, mock, cleanup := getMockCredentialRepo(t)
+	defer cleanup()
+
+	t.Run("Success", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectQuery("SELECT c.user_id, c.password_hash FROM credentials").
+			WithArgs("user@example.com").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "password_hash"}).AddRow(guid, "hash"))
+
+		userID, hash, err := repo.GetByEmail(context.Background(), "user@example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, guid, userID)
+		assert.Equal(t, "hash", hash)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT c.user_id, c.password_hash FROM credentials").
+			WithArgs("user@example.com").
+			WillReturnError(sql.ErrNoRows)
+
+		_, _, err := repo.GetByEmail(context.Background(), "user@example.com")
+		assert.ErrorIs(t, err, domain.ErrCredentialsNotFound)
+	})
+}
+
+func TestPostgresqlCredentialRepo_GetPasswordHash(t *testing.T) {
+	repo, mock, cleanup := getMockCredentialRepo(t)
+	defer cleanup()
+
+	t.Run("Success", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectQuery("SELECT password_hash FROM credentials").
+			WithArgs(guid).
+			WillReturnRows(sqlmock.NewRows([]string{"password_hash"}).AddRow("hash"))
+
+		hash, err := repo.GetPasswordHash(context.Background(), guid)
+		assert.NoError(t, err)
+		assert.Equal(t, "hash", hash)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectQuery("SELECT password_hash FROM credentials").
+			WithArgs(guid).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.GetPasswordHash(context.Background(), guid)
+		assert.ErrorIs(t, err, domain.ErrCredentialsNotFound)
+	})
+}
+
+func TestPostgresqlCredentialRepo_SetPassword(t *testing.T) {
+	repo, mock, cleanup := getMockCredentialRepo(t)
+	defer cleanup()
+
+	guid := uuid.New()
+	mock.ExpectExec("INSERT INTO credentials").
+		WithArgs(guid, "hash", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := repo.SetPassword(context.Background(), guid, "hash")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
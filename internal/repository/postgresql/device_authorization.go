@@ -0,0 +1,183 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	"time"
+)
+
+// PostgresqlDeviceAuthorizationRepo - имплементация интерфейса repository.IDeviceAuthorizationRepo.
+// Позволяет взаимодействовать с запросами авторизации устройств OAuth в Postgresql
+type PostgresqlDeviceAuthorizationRepo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+const deviceAuthorizationColumns = `device_code_hash, user_code_hash, client_id, scopes, status, user_id, auth_methods, interval_seconds, last_polled_at, expires_at`
+
+// deviceAuthorizationRow - строка таблицы device_authorizations.
+type deviceAuthorizationRow struct {
+	DeviceCodeHash  string         `db:"device_code_hash"`
+	UserCodeHash    string         `db:"user_code_hash"`
+	ClientID        string         `db:"client_id"`
+	Scopes          pq.StringArray `db:"scopes"`
+	Status          string         `db:"status"`
+	UserID          uuid.NullUUID  `db:"user_id"`
+	AuthMethods     pq.StringArray `db:"auth_methods"`
+	IntervalSeconds int            `db:"interval_seconds"`
+	LastPolledAt    sql.NullTime   `db:"last_polled_at"`
+	ExpiresAt       time.Time      `db:"expires_at"`
+}
+
+func (row *deviceAuthorizationRow) toDomain() *domain.DeviceAuthorization {
+	methods := make([]domain.AuthMethod, len(row.AuthMethods))
+	for i, method := range row.AuthMethods {
+		methods[i] = domain.AuthMethod(method)
+	}
+
+	return &domain.DeviceAuthorization{
+		DeviceCodeHash: row.DeviceCodeHash,
+		UserCodeHash:   row.UserCodeHash,
+		ClientID:       row.ClientID,
+		Scopes:         row.Scopes,
+		Status:         domain.DeviceAuthorizationStatus(row.Status),
+		UserID:         row.UserID.UUID,
+		AuthMethods:    methods,
+		Interval:       time.Duration(row.IntervalSeconds) * time.Second,
+		LastPolledAt:   row.LastPolledAt.Time,
+		ExpiresAt:      row.ExpiresAt,
+	}
+}
+
+// Create сохраняет новый запрос авторизации устройства.
+func (r *PostgresqlDeviceAuthorizationRepo) Create(ctx context.Context, authorization *domain.DeviceAuthorization) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO device_authorizations (device_code_hash, user_code_hash, client_id, scopes, status, interval_seconds, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		authorization.DeviceCodeHash, authorization.UserCodeHash, authorization.ClientID, pq.Array(authorization.Scopes),
+		string(domain.DeviceAuthorizationPending), int(authorization.Interval.Seconds()), authorization.ExpiresAt)
+	if err != nil {
+		r.logger.Error("Error inserting device authorization", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetPendingByUserCode возвращает ожидающий подтверждения запрос по хешу пользовательского кода.
+func (r *PostgresqlDeviceAuthorizationRepo) GetPendingByUserCode(ctx context.Context, userCodeHash string, now time.Time) (*domain.DeviceAuthorization, error) {
+	var row deviceAuthorizationRow
+
+	err := r.db.GetContext(ctx, &row,
+		`SELECT `+deviceAuthorizationColumns+` FROM device_authorizations
+		WHERE user_code_hash = $1 AND status = $2 AND expires_at > $3`,
+		userCodeHash, string(domain.DeviceAuthorizationPending), now)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrInvalidUserCode
+		}
+		r.logger.Error("Error getting device authorization", zap.Error(err))
+		return nil, err
+	}
+
+	return row.toDomain(), nil
+}
+
+// Complete переводит ожидающий запрос в состояние status. Состояние меняется одним запросом,
+// поэтому параллельные подтверждения одного кода не перезаписывают друг друга.
+func (r *PostgresqlDeviceAuthorizationRepo) Complete(ctx context.Context, userCodeHash string, status domain.DeviceAuthorizationStatus, userID uuid.UUID, methods []domain.AuthMethod, now time.Time) error {
+	rawMethods := make([]string, len(methods))
+	for i, method := range methods {
+		rawMethods[i] = string(method)
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE device_authorizations SET status = $1, user_id = $2, auth_methods = $3
+		WHERE user_code_hash = $4 AND status = $5 AND expires_at > $6`,
+		string(status), nullUUID(userID), pq.Array(rawMethods),
+		userCodeHash, string(domain.DeviceAuthorizationPending), now)
+	if err != nil {
+		r.logger.Error("Error completing device authorization", zap.Error(err))
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Error getting affected rows", zap.Error(err))
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrInvalidUserCode
+	}
+	return nil
+}
+
+// Poll отмечает опрос запроса устройством. Предыдущее время опроса читается с блокировкой строки,
+// поэтому из параллельных опросов слишком частым считается каждый, кроме первого.
+func (r *PostgresqlDeviceAuthorizationRepo) Poll(ctx context.Context, deviceCodeHash string, now time.Time) (*domain.DeviceAuthorization, error) {
+	var row deviceAuthorizationRow
+
+	err := r.db.GetContext(ctx, &row,
+		`WITH previous AS (
+			SELECT last_polled_at FROM device_authorizations WHERE device_code_hash = $1 FOR UPDATE
+		)
+		UPDATE device_authorizations AS d SET last_polled_at = $2 FROM previous WHERE d.device_code_hash = $1
+		RETURNING d.device_code_hash, d.user_code_hash, d.client_id, d.scopes, d.status, d.user_id, d.auth_methods,
+			d.interval_seconds, previous.last_polled_at, d.expires_at`,
+		deviceCodeHash, now)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrInvalidGrant
+		}
+		r.logger.Error("Error polling device authorization", zap.Error(err))
+		return nil, err
+	}
+
+	return row.toDomain(), nil
+}
+
+// SlowDown устанавливает новый минимальный интервал опроса запроса.
+func (r *PostgresqlDeviceAuthorizationRepo) SlowDown(ctx context.Context, deviceCodeHash string, interval time.Duration) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE device_authorizations SET interval_seconds = $1 WHERE device_code_hash = $2`,
+		int(interval.Seconds()), deviceCodeHash)
+	if err != nil {
+		r.logger.Error("Error updating device authorization interval", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Consume удаляет подтвержденный или отклоненный запрос по хешу кода устройства и возвращает его.
+// Запрос удаляется одним запросом, поэтому параллельные опросы не могут обменять его дважды.
+func (r *PostgresqlDeviceAuthorizationRepo) Consume(ctx context.Context, deviceCodeHash string) (*domain.DeviceAuthorization, error) {
+	var row deviceAuthorizationRow
+
+	err := r.db.GetContext(ctx, &row,
+		`DELETE FROM device_authorizations WHERE device_code_hash = $1 AND status <> $2
+		RETURNING `+deviceAuthorizationColumns,
+		deviceCodeHash, string(domain.DeviceAuthorizationPending))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrInvalidGrant
+		}
+		r.logger.Error("Error consuming device authorization", zap.Error(err))
+		return nil, err
+	}
+
+	return row.toDomain(), nil
+}
+
+// NewPostgresqlDeviceAuthorizationRepo - конструктор для создания нового экземпляра PostgresqlDeviceAuthorizationRepo.
+func NewPostgresqlDeviceAuthorizationRepo(db *sqlx.DB, logger *zap.Logger) repository.IDeviceAuthorizationRepo {
+	return &PostgresqlDeviceAuthorizationRepo{
+		db:     db,
+		logger: logger,
+	}
+}
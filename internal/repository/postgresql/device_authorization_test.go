@@ -0,0 +1,193 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func getMockDeviceAuthorizationRepo(t *testing.T) (repository.IDeviceAuthorizationRepo, sqlmock.Sqlmock, func()) {
+	logger := zap.NewNop()
+
+	mockDB, mock, err := sqlmock.New()
+
+	require.NoError(t, err, "creating mock db")
+
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	repo := NewPostgresqlDeviceAuthorizationRepo(sqlxDB, logger)
+
+	cleanup := func() {
+		mockDB.Close()
+	}
+
+	return repo, mock, cleanup
+}
+
+var deviceAuthorizationColumnNames = []string{"device_code_hash", "user_code_hash", "client_id", "scopes", "status", "user_id",
+	"auth_methods", "interval_seconds", "last_polled_at", "expires_at"}
+
+func TestPostgresqlDeviceAuthorizationRepo_Create(t *testing.T) {
+	repo, mock, cleanup := getMockDeviceAuthorizationRepo(t)
+	defer cleanup()
+
+	authorization := &domain.DeviceAuthorization{
+		DeviceCodeHash: "device",
+		UserCodeHash:   "user",
+		ClientID:       "cli",
+		Scopes:         []string{"profile"},
+		Interval:       5 * time.Second,
+		ExpiresAt:      time.Now().Add(10 * time.Minute),
+	}
+
+	mock.ExpectExec("INSERT INTO device_authorizations").
+		WithArgs("device", "user", "cli", `{"profile"}`, "pending", 5, authorization.ExpiresAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.Create(context.Background(), authorization))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlDeviceAuthorizationRepo_GetPendingByUserCode(t *testing.T) {
+	repo, mock, cleanup := getMockDeviceAuthorizationRepo(t)
+	defer cleanup()
+
+	now := time.Now()
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM device_authorizations WHERE user_code_hash").
+			WithArgs("user", "pending", now).
+			WillReturnRows(sqlmock.NewRows(deviceAuthorizationColumnNames).
+				AddRow("device", "user", "cli", `{profile}`, "pending", nil, `{}`, 5, nil, now.Add(time.Minute)))
+
+		authorization, err := repo.GetPendingByUserCode(context.Background(), "user", now)
+		require.NoError(t, err)
+		assert.Equal(t, "cli", authorization.ClientID)
+		assert.Equal(t, domain.DeviceAuthorizationPending, authorization.Status)
+		assert.Equal(t, uuid.Nil, authorization.UserID)
+		assert.True(t, authorization.LastPolledAt.IsZero())
+		assert.Equal(t, 5*time.Second, authorization.Interval)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM device_authorizations WHERE user_code_hash").
+			WithArgs("user", "pending", now).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.GetPendingByUserCode(context.Background(), "user", now)
+		assert.ErrorIs(t, err, domain.ErrInvalidUserCode)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlDeviceAuthorizationRepo_Complete(t *testing.T) {
+	repo, mock, cleanup := getMockDeviceAuthorizationRepo(t)
+	defer cleanup()
+
+	now := time.Now()
+	guid := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectExec("UPDATE device_authorizations SET status").
+			WithArgs("approved", uuid.NullUUID{UUID: guid, Valid: true}, `{"password"}`, "user", "pending", now).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Complete(context.Background(), "user", domain.DeviceAuthorizationApproved, guid, []domain.AuthMethod{domain.AuthMethodPassword}, now)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Already completed", func(t *testing.T) {
+		mock.ExpectExec("UPDATE device_authorizations SET status").
+			WithArgs("denied", uuid.NullUUID{}, `{}`, "user", "pending", now).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Complete(context.Background(), "user", domain.DeviceAuthorizationDenied, uuid.Nil, nil, now)
+		assert.ErrorIs(t, err, domain.ErrInvalidUserCode)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlDeviceAuthorizationRepo_Poll(t *testing.T) {
+	repo, mock, cleanup := getMockDeviceAuthorizationRepo(t)
+	defer cleanup()
+
+	now := time.Now()
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectQuery("UPDATE device_authorizations AS d SET last_polled_at").
+			WithArgs("device", now).
+			WillReturnRows(sqlmock.NewRows(deviceAuthorizationColumnNames).
+				AddRow("device", "user", "cli", `{}`, "pending", nil, `{}`, 5, now.Add(-time.Second), now.Add(time.Minute)))
+
+		authorization, err := repo.Poll(context.Background(), "device", now)
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(-time.Second), authorization.LastPolledAt)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mock.ExpectQuery("UPDATE device_authorizations AS d SET last_polled_at").
+			WithArgs("device", now).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.Poll(context.Background(), "device", now)
+		assert.ErrorIs(t, err, domain.ErrInvalidGrant)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlDeviceAuthorizationRepo_SlowDown(t *testing.T) {
+	repo, mock, cleanup := getMockDeviceAuthorizationRepo(t)
+	defer cleanup()
+
+	mock.ExpectExec("UPDATE device_authorizations SET interval_seconds").
+		WithArgs(10, "device").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.SlowDown(context.Background(), "device", 10*time.Second))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlDeviceAuthorizationRepo_Consume(t *testing.T) {
+	repo, mock, cleanup := getMockDeviceAuthorizationRepo(t)
+	defer cleanup()
+
+	now := time.Now()
+	guid := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectQuery("DELETE FROM device_authorizations WHERE device_code_hash").
+			WithArgs("device", "pending").
+			WillReturnRows(sqlmock.NewRows(deviceAuthorizationColumnNames).
+				AddRow("device", "user", "cli", `{profile}`, "approved", guid, `{password,totp}`, 5, now, now.Add(time.Minute)))
+
+		authorization, err := repo.Consume(context.Background(), "device")
+		require.NoError(t, err)
+		assert.Equal(t, guid, authorization.UserID)
+		assert.Equal(t, domain.DeviceAuthorizationApproved, authorization.Status)
+		assert.Equal(t, []domain.AuthMethod{domain.AuthMethodPassword, domain.AuthMethodTOTP}, authorization.AuthMethods)
+		assert.Equal(t, []string{"profile"}, authorization.Scopes)
+	})
+
+	t.Run("Pending or used", func(t *testing.T) {
+		mock.ExpectQuery("DELETE FROM device_authorizations WHERE device_code_hash").
+			WithArgs("device", "pending").
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.Consume(context.Background(), "device")
+		assert.ErrorIs(t, err, domain.ErrInvalidGrant)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
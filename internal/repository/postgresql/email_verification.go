@@ -0,0 +1,121 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/database"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	"time"
+)
+
+// PostgresqlEmailVerificationRepo - имплементация интерфейса repository.IEmailVerificationRepo.
+// Позволяет взаимодействовать с токенами подтверждения email в Postgresql
+type PostgresqlEmailVerificationRepo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+// Create сохраняет новый токен подтверждения email.
+func (r *PostgresqlEmailVerificationRepo) Create(ctx context.Context, verification *domain.EmailVerification) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO email_verifications (id, user_id, email, token_hash, expires_at, created_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		verification.ID, verification.UserID, verification.Email, verification.TokenHash, verification.ExpiresAt, verification.CreatedAt)
+	if err != nil {
+		r.logger.Error("Error inserting email verification", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// LastCreatedAt возвращает время выдачи последнего токена пользователя или нулевое время, если токенов нет.
+func (r *PostgresqlEmailVerificationRepo) LastCreatedAt(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	var createdAt sql.NullTime
+
+	err := r.db.GetContext(ctx, &createdAt, "SELECT MAX(created_at) FROM email_verifications WHERE user_id = $1", userID)
+	if err != nil {
+		r.logger.Error("Error querying email verifications", zap.Error(err))
+		return time.Time{}, err
+	}
+
+	return createdAt.Time, nil
+}
+
+// Consume в одной транзакции удаляет токен по хешу и отмечает email пользователя подтвержденным.
+// Email подтверждается, только если он совпадает с адресом, на который был отправлен токен.
+// После подтверждения остальные токены пользователя удаляются.
+// Возвращает domain.ErrInvalidVerificationToken, если токен не найден, истек или email изменился.
+func (r *PostgresqlEmailVerificationRepo) Consume(ctx context.Context, tokenHash string, now time.Time) (uuid.UUID, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error("error starting transaction", zap.Error(err))
+		return uuid.Nil, err
+	}
+	defer database.TxRollback(tx, r.logger)
+
+	var row struct {
+		UserID    uuid.UUID `db:"user_id"`
+		Email     string    `db:"email"`
+		ExpiresAt time.Time `db:"expires_at"`
+	}
+
+	err = tx.GetContext(ctx, &row, "DELETE FROM email_verifications WHERE token_hash = $1 RETURNING user_id, email, expires_at", tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, domain.ErrInvalidVerificationToken
+		}
+		r.logger.Error("Error consuming email verification", zap.Error(err))
+		return uuid.Nil, err
+	}
+
+	// Истекший токен удаляется так же, как использованный, но email не подтверждается
+	if !row.ExpiresAt.After(now) {
+		if err = tx.Commit(); err != nil {
+			r.logger.Error("error committing transaction", zap.Error(err))
+			return uuid.Nil, err
+		}
+		return uuid.Nil, domain.ErrInvalidVerificationToken
+	}
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE users SET email_verified_at = COALESCE(email_verified_at, $3) WHERE guid = $1 AND email = $2",
+		row.UserID, row.Email, now.UTC())
+	if err != nil {
+		r.logger.Error("Error marking email verified", zap.Error(err))
+		return uuid.Nil, err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Error getting rows affected", zap.Error(err))
+		return uuid.Nil, err
+	}
+
+	if updated == 0 {
+		return uuid.Nil, domain.ErrInvalidVerificationToken
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM email_verifications WHERE user_id = $1", row.UserID); err != nil {
+		r.logger.Error("Error deleting email verifications", zap.Error(err))
+		return uuid.Nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.Error("error committing transaction", zap.Error(err))
+		return uuid.Nil, err
+	}
+
+	return row.UserID, nil
+}
+
+// NewPostgresqlEmailVerificationRepo - конструктор для создания нового экземпляра PostgresqlEmailVerificationRepo.
+func NewPostgresqlEmailVerificationRepo(db *sqlx.DB, logger *zap.Logger) repository.IEmailVerificationRepo {
+	return &PostgresqlEmailVerificationRepo{
+		db:     db,
+		logger: logger,
+	}
+}
@@ -0,0 +1,151 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func getMockEmailVerificationRepo(t *testing.T) (repository.IEmailVerificationRepo, sqlmock.Sqlmock, func()) {
+	logger := zap.NewNop()
+
+	mockDB, mock, err := sqlmock.New()
+
+	require.NoError(t, err, "creating mock db")
+
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	repo := NewPostgresqlEmailVerificationRepo(sqlxDB, logger)
+
+	cleanup := func() {
+		mockDB.Close()
+	}
+
+	return repo, mock, cleanup
+}
+
+func TestPostgresqlEmailVerificationRepo_Create(t *testing.T) {
+	repo, mock, cleanup := getMockEmailVerificationRepo(t)
+	defer cleanup()
+
+	now := time.Now()
+	verification := &domain.EmailVerification{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		Email:     "user@example.com",
+		TokenHash: "hash",
+		ExpiresAt: now.Add(time.Hour),
+		CreatedAt: now,
+	}
+
+	mock.ExpectExec("INSERT INTO email_verifications").
+		WithArgs(verification.ID, verification.UserID, "user@example.com", "hash", verification.ExpiresAt, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.Create(context.Background(), verification))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlEmailVerificationRepo_LastCreatedAt(t *testing.T) {
+	repo, mock, cleanup := getMockEmailVerificationRepo(t)
+	defer cleanup()
+
+	guid := uuid.New()
+
+	t.Run("Has tokens", func(t *testing.T) {
+		createdAt := time.Now().Add(-time.Minute)
+		mock.ExpectQuery("SELECT MAX\\(created_at\\) FROM email_verifications").
+			WithArgs(guid).
+			WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(createdAt))
+
+		last, err := repo.LastCreatedAt(context.Background(), guid)
+		require.NoError(t, err)
+		assert.True(t, createdAt.Equal(last))
+	})
+
+	t.Run("No tokens", func(t *testing.T) {
+		mock.ExpectQuery("SELECT MAX\\(created_at\\) FROM email_verifications").
+			WithArgs(guid).
+			WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+
+		last, err := repo.LastCreatedAt(context.Background(), guid)
+		require.NoError(t, err)
+		assert.True(t, last.IsZero())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlEmailVerificationRepo_Consume(t *testing.T) {
+	repo, mock, cleanup := getMockEmailVerificationRepo(t)
+	defer cleanup()
+
+	now := time.Now()
+	guid := uuid.New()
+	columns := []string{"user_id", "email", "expires_at"}
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM email_verifications WHERE token_hash").
+			WithArgs("hash").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(guid, "user@example.com", now.Add(time.Hour)))
+		mock.ExpectExec("UPDATE users SET email_verified_at").
+			WithArgs(guid, "user@example.com", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM email_verifications WHERE user_id").
+			WithArgs(guid).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		userID, err := repo.Consume(context.Background(), "hash", now)
+		require.NoError(t, err)
+		assert.Equal(t, guid, userID)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM email_verifications WHERE token_hash").
+			WithArgs("hash").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, err := repo.Consume(context.Background(), "hash", now)
+		assert.ErrorIs(t, err, domain.ErrInvalidVerificationToken)
+	})
+
+	t.Run("Expired token is deleted", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM email_verifications WHERE token_hash").
+			WithArgs("hash").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(guid, "user@example.com", now.Add(-time.Second)))
+		mock.ExpectCommit()
+
+		_, err := repo.Consume(context.Background(), "hash", now)
+		assert.ErrorIs(t, err, domain.ErrInvalidVerificationToken)
+	})
+
+	t.Run("Email changed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM email_verifications WHERE token_hash").
+			WithArgs("hash").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(guid, "old@example.com", now.Add(time.Hour)))
+		mock.ExpectExec("UPDATE users SET email_verified_at").
+			WithArgs(guid, "old@example.com", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		_, err := repo.Consume(context.Background(), "hash", now)
+		assert.ErrorIs(t, err, domain.ErrInvalidVerificationToken)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
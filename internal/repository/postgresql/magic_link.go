@@ -0,0 +1,45 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	"time"
+)
+
+// PostgresqlMagicLinkRepo - имплементация интерфейса repository.IMagicLinkRepo.
+// Позволяет учитывать использованные ссылки для входа в Postgresql
+type PostgresqlMagicLinkRepo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+// Consume сохраняет ID использованной ссылки. Повторная вставка того же ID не выполняется,
+// поэтому из двух параллельных запросов с одной ссылкой успешен только один.
+func (r *PostgresqlMagicLinkRepo) Consume(ctx context.Context, id, userID uuid.UUID, expiresAt time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		"INSERT INTO consumed_magic_links (id, user_id, consumed_at, expires_at) VALUES ($1, $2, $3, $4) ON CONFLICT (id) DO NOTHING",
+		id, userID, time.Now().UTC(), expiresAt)
+	if err != nil {
+		r.logger.Error("Error consuming magic link", zap.Error(err))
+		return false, err
+	}
+
+	consumed, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Error getting rows affected", zap.Error(err))
+		return false, err
+	}
+
+	return consumed > 0, nil
+}
+
+// NewPostgresqlMagicLinkRepo - конструктор для создания нового экземпляра PostgresqlMagicLinkRepo.
+func NewPostgresqlMagicLinkRepo(db *sqlx.DB, logger *zap.Logger) repository.IMagicLinkRepo {
+	return &PostgresqlMagicLinkRepo{
+		db:     db,
+		logger: logger,
+	}
+}
@@ -0,0 +1,62 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func getMockMagicLinkRepo(t *testing.T) (repository.IMagicLinkRepo, sqlmock.Sqlmock, func()) {
+	logger := zap.NewNop()
+
+	mockDB, mock, err := sqlmock.New()
+
+	require.NoError(t, err, "creating mock db")
+
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	repo := NewPostgresqlMagicLinkRepo(sqlxDB, logger)
+
+	cleanup := func() {
+		mockDB.Close()
+	}
+
+	return repo, mock, cleanup
+}
+
+func TestPostgresqlMagicLinkRepo_Consume(t *testing.T) {
+	repo, mock, cleanup := getMockMagicLinkRepo(t)
+	defer cleanup()
+
+	id, guid := uuid.New(), uuid.New()
+	expiresAt := time.Now().Add(time.Minute)
+
+	t.Run("First use", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO consumed_magic_links").
+			WithArgs(id, guid, sqlmock.AnyArg(), expiresAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		consumed, err := repo.Consume(context.Background(), id, guid, expiresAt)
+		require.NoError(t, err)
+		assert.True(t, consumed)
+	})
+
+	t.Run("Replay", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO consumed_magic_links").
+			WithArgs(id, guid, sqlmock.AnyArg(), expiresAt).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		consumed, err := repo.Consume(context.Background(), id, guid, expiresAt)
+		require.NoError(t, err)
+		assert.False(t, consumed)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
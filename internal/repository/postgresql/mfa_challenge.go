@@ -0,0 +1,116 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	"time"
+)
+
+// PostgresqlMFAChallengeRepo - имплементация интерфейса repository.IMFAChallengeRepo.
+// Позволяет взаимодействовать с MFA-челленджами в Postgresql
+type PostgresqlMFAChallengeRepo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+// mfaChallengeRow - строка таблицы mfa_challenges
+type mfaChallengeRow struct {
+	ID        uuid.UUID `db:"id"`
+	TokenHash string    `db:"token_hash"`
+	UserID    uuid.UUID `db:"user_id"`
+	Method    string    `db:"method"`
+	Attempts  int       `db:"attempts"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+// Create сохраняет новый челлендж.
+func (r *PostgresqlMFAChallengeRepo) Create(ctx context.Context, challenge *domain.MFAChallenge) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO mfa_challenges (id, token_hash, user_id, method, attempts, expires_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		challenge.ID, challenge.TokenHash, challenge.UserID, string(challenge.Method), challenge.Attempts, challenge.ExpiresAt)
+	if err != nil {
+		r.logger.Error("Error inserting mfa challenge", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetByTokenHash возвращает не истекший к моменту notAfter челлендж по хешу токена.
+// Если челлендж не найден или истек, возвращает ошибку domain.ErrMFAChallengeNotFound.
+func (r *PostgresqlMFAChallengeRepo) GetByTokenHash(ctx context.Context, tokenHash string, notAfter time.Time) (*domain.MFAChallenge, error) {
+	var row mfaChallengeRow
+
+	err := r.db.GetContext(ctx, &row,
+		"SELECT id, token_hash, user_id, method, attempts, expires_at FROM mfa_challenges WHERE token_hash = $1 AND expires_at > $2",
+		tokenHash, notAfter)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrMFAChallengeNotFound
+		}
+		r.logger.Error("Error querying mfa challenge", zap.Error(err))
+		return nil, err
+	}
+
+	return &domain.MFAChallenge{
+		ID:        row.ID,
+		TokenHash: row.TokenHash,
+		UserID:    row.UserID,
+		Method:    domain.AuthMethod(row.Method),
+		Attempts:  row.Attempts,
+		ExpiresAt: row.ExpiresAt,
+	}, nil
+}
+
+// IncrementAttempts увеличивает счетчик неудачных попыток и возвращает его новое значение.
+// Если челлендж не найден, возвращает ошибку domain.ErrMFAChallengeNotFound.
+func (r *PostgresqlMFAChallengeRepo) IncrementAttempts(ctx context.Context, id uuid.UUID) (int, error) {
+	var attempts int
+
+	err := r.db.GetContext(ctx, &attempts, "UPDATE mfa_challenges SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts", id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, domain.ErrMFAChallengeNotFound
+		}
+		r.logger.Error("Error updating mfa challenge attempts", zap.Error(err))
+		return 0, err
+	}
+
+	return attempts, nil
+}
+
+// Delete удаляет челлендж.
+// Если челлендж уже удален, возвращает ошибку domain.ErrMFAChallengeNotFound -
+// так параллельные запросы не могут использовать один челлендж дважды.
+func (r *PostgresqlMFAChallengeRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM mfa_challenges WHERE id = $1", id)
+	if err != nil {
+		r.logger.Error("Error deleting mfa challenge", zap.Error(err))
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Error getting rows affected", zap.Error(err))
+		return err
+	}
+
+	if deleted == 0 {
+		return domain.ErrMFAChallengeNotFound
+	}
+
+	return nil
+}
+
+// NewPostgresqlMFAChallengeRepo - конструктор для создания нового экземпляра PostgresqlMFAChallengeRepo.
+func NewPostgresqlMFAChallengeRepo(db *sqlx.DB, logger *zap.Logger) repository.IMFAChallengeRepo {
+	return &PostgresqlMFAChallengeRepo{
+		db:     db,
+		logger: logger,
+	}
+}
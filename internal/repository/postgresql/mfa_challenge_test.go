@@ -0,0 +1,142 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func getMockMFAChallengeRepo(t *testing.T) (repository.IMFAChallengeRepo, sqlmock.Sqlmock, func()) {
+	logger := zap.NewNop()
+
+	mockDB, mock, err := sqlmock.New()
+
+	require.NoError(t, err, "creating mock db")
+
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	repo := NewPostgresqlMFAChallengeRepo(sqlxDB, logger)
+
+	cleanup := func() {
+		mockDB.Close()
+	}
+
+	return repo, mock, cleanup
+}
+
+func TestPostgresqlMFAChallengeRepo_Create(t *testing.T) {
+	repo, mock, cleanup := getMockMFAChallengeRepo(t)
+	defer cleanup()
+
+	challenge := &domain.MFAChallenge{
+		ID:        uuid.New(),
+		TokenHash: "hash",
+		UserID:    uuid.New(),
+		Method:    domain.AuthMethodPassword,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	mock.ExpectExec("INSERT INTO mfa_challenges").
+		WithArgs(challenge.ID, "hash", challenge.UserID, "password", 0, challenge.ExpiresAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.Create(context.Background(), challenge)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlMFAChallengeRepo_GetByTokenHash(t *testing.T) {
+	repo, mock, cleanup := getMockMFAChallengeRepo(t)
+	defer cleanup()
+
+	now := time.Now()
+
+	t.Run("Success", func(t *testing.T) {
+		id, guid := uuid.New(), uuid.New()
+		expiresAt := now.Add(time.Minute)
+		mock.ExpectQuery("SELECT id, token_hash, user_id, method, attempts, expires_at FROM mfa_challenges").
+			WithArgs("hash", now).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "token_hash", "user_id", "method", "attempts", "expires_at"}).
+				AddRow(id, "hash", guid, "guid", 2, expiresAt))
+
+		challenge, err := repo.GetByTokenHash(context.Background(), "hash", now)
+		require.NoError(t, err)
+		assert.Equal(t, id, challenge.ID)
+		assert.Equal(t, guid, challenge.UserID)
+		assert.Equal(t, domain.AuthMethodGUID, challenge.Method)
+		assert.Equal(t, 2, challenge.Attempts)
+	})
+
+	t.Run("Not found or expired", func(t *testing.T) {
+		mock.ExpectQuery("SELECT id, token_hash, user_id, method, attempts, expires_at FROM mfa_challenges").
+			WithArgs("hash", now).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.GetByTokenHash(context.Background(), "hash", now)
+		assert.ErrorIs(t, err, domain.ErrMFAChallengeNotFound)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlMFAChallengeRepo_IncrementAttempts(t *testing.T) {
+	repo, mock, cleanup := getMockMFAChallengeRepo(t)
+	defer cleanup()
+
+	t.Run("Success", func(t *testing.T) {
+		id := uuid.New()
+		mock.ExpectQuery("UPDATE mfa_challenges SET attempts").
+			WithArgs(id).
+			WillReturnRows(sqlmock.NewRows([]string{"attempts"}).AddRow(3))
+
+		attempts, err := repo.IncrementAttempts(context.Background(), id)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		id := uuid.New()
+		mock.ExpectQuery("UPDATE mfa_challenges SET attempts").
+			WithArgs(id).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.IncrementAttempts(context.Background(), id)
+		assert.ErrorIs(t, err, domain.ErrMFAChallengeNotFound)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlMFAChallengeRepo_Delete(t *testing.T) {
+	repo, mock, cleanup := getMockMFAChallengeRepo(t)
+	defer cleanup()
+
+	t.Run("Success", func(t *testing.T) {
+		id := uuid.New()
+		mock.ExpectExec("DELETE FROM mfa_challenges").
+			WithArgs(id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.Delete(context.Background(), id))
+	})
+
+	t.Run("Already deleted", func(t *testing.T) {
+		id := uuid.New()
+		mock.ExpectExec("DELETE FROM mfa_challenges").
+			WithArgs(id).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.ErrorIs(t, repo.Delete(context.Background(), id), domain.ErrMFAChallengeNotFound)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,108 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/database"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	"time"
+)
+
+// PostgresqlPasskeyRepo - имплементация интерфейса repository.IPasskeyRepo.
+// Позволяет взаимодействовать с passkey в Postgresql
+type PostgresqlPasskeyRepo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+// Create сохраняет новый passkey.
+// Если passkey с таким ID учетных данных уже зарегистрирован, возвращает ошибку domain.ErrPasskeyExists.
+func (r *PostgresqlPasskeyRepo) Create(ctx context.Context, passkey *domain.Passkey) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO passkeys (credential_id, user_id, public_key, sign_count, created_at) VALUES ($1, $2, $3, $4, $5)",
+		passkey.CredentialID, passkey.UserID, passkey.PublicKey, int64(passkey.SignCount), passkey.CreatedAt)
+	if err != nil {
+		if database.IsPGError(err, database.PGUniqueViolationCode) {
+			return domain.ErrPasskeyExists
+		}
+		r.logger.Error("Error inserting passkey", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Get возвращает passkey по ID учетных данных.
+// Если passkey не найден, возвращает ошибку domain.ErrPasskeyNotFound.
+func (r *PostgresqlPasskeyRepo) Get(ctx context.Context, credentialID []byte) (*domain.Passkey, error) {
+	var row struct {
+		UserID    uuid.UUID `db:"user_id"`
+		PublicKey []byte    `db:"public_key"`
+		SignCount int64     `db:"sign_count"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+
+	err := r.db.GetContext(ctx, &row,
+		"SELECT user_id, public_key, sign_count, created_at FROM passkeys WHERE credential_id = $1", credentialID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrPasskeyNotFound
+		}
+		r.logger.Error("Error querying passkey", zap.Error(err))
+		return nil, err
+	}
+
+	return &domain.Passkey{
+		CredentialID: credentialID,
+		UserID:       row.UserID,
+		PublicKey:    row.PublicKey,
+		SignCount:    uint32(row.SignCount),
+		CreatedAt:    row.CreatedAt,
+	}, nil
+}
+
+// ListCredentialIDs возвращает ID учетных данных всех passkey пользователя.
+func (r *PostgresqlPasskeyRepo) ListCredentialIDs(ctx context.Context, userID uuid.UUID) ([][]byte, error) {
+	var ids [][]byte
+
+	if err := r.db.SelectContext(ctx, &ids, "SELECT credential_id FROM passkeys WHERE user_id = $1", userID); err != nil {
+		r.logger.Error("Error querying passkeys", zap.Error(err))
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// UpdateSignCount сохраняет новое значение счетчика подписей, только если оно больше сохраненного.
+// Аутентификаторы, не ведущие счетчик, всегда передают 0 - для них обновляется только время использования.
+// Возвращает false, если счетчик уже был увеличен параллельным входом.
+func (r *PostgresqlPasskeyRepo) UpdateSignCount(ctx context.Context, credentialID []byte, signCount uint32) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE passkeys SET sign_count = $2, last_used_at = $3
+		WHERE credential_id = $1 AND (sign_count < $2 OR (sign_count = 0 AND $2 = 0))`,
+		credentialID, int64(signCount), time.Now().UTC())
+	if err != nil {
+		r.logger.Error("Error updating passkey sign count", zap.Error(err))
+		return false, err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Error getting rows affected", zap.Error(err))
+		return false, err
+	}
+
+	return updated > 0, nil
+}
+
+// NewPostgresqlPasskeyRepo - конструктор для создания нового экземпляра PostgresqlPasskeyRepo.
+func NewPostgresqlPasskeyRepo(db *sqlx.DB, logger *zap.Logger) repository.IPasskeyRepo {
+	return &PostgresqlPasskeyRepo{
+		db:     db,
+		logger: logger,
+	}
+}
@@ -0,0 +1,143 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/database"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func getMockPasskeyRepo(t *testing.T) (repository.IPasskeyRepo, sqlmock.Sqlmock, func()) {
+	logger := zap.NewNop()
+
+	mockDB, mock, err := sqlmock.New()
+
+	require.NoError(t, err, "creating mock db")
+
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	repo := NewPostgresqlPasskeyRepo(sqlxDB, logger)
+
+	cleanup := func() {
+		mockDB.Close()
+	}
+
+	return repo, mock, cleanup
+}
+
+func TestPostgresqlPasskeyRepo_Create(t *testing.T) {
+	repo, mock, cleanup := getMockPasskeyRepo(t)
+	defer cleanup()
+
+	passkey := &domain.Passkey{
+		CredentialID: []byte("credential"),
+		UserID:       uuid.New(),
+		PublicKey:    []byte("key"),
+		SignCount:    3,
+		CreatedAt:    time.Now(),
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO passkeys").
+			WithArgs(passkey.CredentialID, passkey.UserID, passkey.PublicKey, int64(3), passkey.CreatedAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.Create(context.Background(), passkey))
+	})
+
+	t.Run("Already registered", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO passkeys").
+			WillReturnError(&pq.Error{Code: database.PGUniqueViolationCode})
+
+		assert.ErrorIs(t, repo.Create(context.Background(), passkey), domain.ErrPasskeyExists)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlPasskeyRepo_Get(t *testing.T) {
+	repo, mock, cleanup := getMockPasskeyRepo(t)
+	defer cleanup()
+
+	credentialID := []byte("credential")
+
+	t.Run("Success", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectQuery("SELECT user_id, public_key, sign_count, created_at FROM passkeys").
+			WithArgs(credentialID).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "public_key", "sign_count", "created_at"}).
+				AddRow(guid, []byte("key"), int64(7), time.Now()))
+
+		passkey, err := repo.Get(context.Background(), credentialID)
+		require.NoError(t, err)
+		assert.Equal(t, guid, passkey.UserID)
+		assert.Equal(t, []byte("key"), passkey.PublicKey)
+		assert.Equal(t, uint32(7), passkey.SignCount)
+		assert.Equal(t, credentialID, passkey.CredentialID)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT user_id, public_key, sign_count, created_at FROM passkeys").
+			WithArgs(credentialID).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.Get(context.Background(), credentialID)
+		assert.ErrorIs(t, err, domain.ErrPasskeyNotFound)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlPasskeyRepo_ListCredentialIDs(t *testing.T) {
+	repo, mock, cleanup := getMockPasskeyRepo(t)
+	defer cleanup()
+
+	guid := uuid.New()
+	mock.ExpectQuery("SELECT credential_id FROM passkeys").
+		WithArgs(guid).
+		WillReturnRows(sqlmock.NewRows([]string{"credential_id"}).AddRow([]byte("a")).AddRow([]byte("b")))
+
+	ids, err := repo.ListCredentialIDs(context.Background(), guid)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("a"), []byte("b")}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlPasskeyRepo_UpdateSignCount(t *testing.T) {
+	repo, mock, cleanup := getMockPasskeyRepo(t)
+	defer cleanup()
+
+	credentialID := []byte("credential")
+
+	t.Run("Updated", func(t *testing.T) {
+		mock.ExpectExec("UPDATE passkeys SET sign_count").
+			WithArgs(credentialID, int64(8), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		updated, err := repo.UpdateSignCount(context.Background(), credentialID, 8)
+		require.NoError(t, err)
+		assert.True(t, updated)
+	})
+
+	t.Run("Counter did not grow", func(t *testing.T) {
+		mock.ExpectExec("UPDATE passkeys SET sign_count").
+			WithArgs(credentialID, int64(8), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		updated, err := repo.UpdateSignCount(context.Background(), credentialID, 8)
+		require.NoError(t, err)
+		assert.False(t, updated)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,109 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/database"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	"time"
+)
+
+// PostgresqlPasswordResetRepo - имплементация интерфейса repository.IPasswordResetRepo.
+// Позволяет взаимодействовать с токенами сброса пароля в Postgresql
+type PostgresqlPasswordResetRepo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+// Create сохраняет новый токен сброса пароля.
+func (r *PostgresqlPasswordResetRepo) Create(ctx context.Context, reset *domain.PasswordReset) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO password_resets (id, user_id, token_hash, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)",
+		reset.ID, reset.UserID, reset.TokenHash, reset.ExpiresAt, reset.CreatedAt)
+	if err != nil {
+		r.logger.Error("Error inserting password reset", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Reset в одной транзакции удаляет токен по хешу, сохраняет новый хеш пароля пользователя,
+// удаляет все его Refresh токены и остальные токены сброса пароля.
+// Возвращает domain.ErrInvalidResetToken, если токен не найден или истек.
+func (r *PostgresqlPasswordResetRepo) Reset(ctx context.Context, tokenHash, passwordHash string, now time.Time) (uuid.UUID, int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error("error starting transaction", zap.Error(err))
+		return uuid.Nil, 0, err
+	}
+	defer database.TxRollback(tx, r.logger)
+
+	var row struct {
+		UserID    uuid.UUID `db:"user_id"`
+		ExpiresAt time.Time `db:"expires_at"`
+	}
+
+	err = tx.GetContext(ctx, &row, "DELETE FROM password_resets WHERE token_hash = $1 RETURNING user_id, expires_at", tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, 0, domain.ErrInvalidResetToken
+		}
+		r.logger.Error("Error consuming password reset", zap.Error(err))
+		return uuid.Nil, 0, err
+	}
+
+	// Истекший токен удаляется так же, как использованный, но пароль не меняется
+	if !row.ExpiresAt.After(now) {
+		if err = tx.Commit(); err != nil {
+			r.logger.Error("error committing transaction", zap.Error(err))
+			return uuid.Nil, 0, err
+		}
+		return uuid.Nil, 0, domain.ErrInvalidResetToken
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO credentials (user_id, password_hash, updated_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET password_hash = EXCLUDED.password_hash, updated_at = EXCLUDED.updated_at`,
+		row.UserID, passwordHash, now.UTC())
+	if err != nil {
+		r.logger.Error("Error setting password", zap.Error(err))
+		return uuid.Nil, 0, err
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM tokens WHERE user_id = $1", row.UserID)
+	if err != nil {
+		r.logger.Error("Error revoking user tokens", zap.Error(err))
+		return uuid.Nil, 0, err
+	}
+
+	revoked, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Error getting rows affected", zap.Error(err))
+		return uuid.Nil, 0, err
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM password_resets WHERE user_id = $1", row.UserID); err != nil {
+		r.logger.Error("Error deleting password resets", zap.Error(err))
+		return uuid.Nil, 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.Error("error committing transaction", zap.Error(err))
+		return uuid.Nil, 0, err
+	}
+
+	return row.UserID, int(revoked), nil
+}
+
+// NewPostgresqlPasswordResetRepo - конструктор для создания нового экземпляра PostgresqlPasswordResetRepo.
+func NewPostgresqlPasswordResetRepo(db *sqlx.DB, logger *zap.Logger) repository.IPasswordResetRepo {
+	return &PostgresqlPasswordResetRepo{
+		db:     db,
+		logger: logger,
+	}
+}
@@ -0,0 +1,110 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func getMockPasswordResetRepo(t *testing.T) (repository.IPasswordResetRepo, sqlmock.Sqlmock, func()) {
+	logger := zap.NewNop()
+
+	mockDB, mock, err := sqlmock.New()
+
+	require.NoError(t, err, "creating mock db")
+
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	repo := NewPostgresqlPasswordResetRepo(sqlxDB, logger)
+
+	cleanup := func() {
+		mockDB.Close()
+	}
+
+	return repo, mock, cleanup
+}
+
+func TestPostgresqlPasswordResetRepo_Create(t *testing.T) {
+	repo, mock, cleanup := getMockPasswordResetRepo(t)
+	defer cleanup()
+
+	now := time.Now()
+	reset := &domain.PasswordReset{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		TokenHash: "hash",
+		ExpiresAt: now.Add(time.Hour),
+		CreatedAt: now,
+	}
+
+	mock.ExpectExec("INSERT INTO password_resets").
+		WithArgs(reset.ID, reset.UserID, "hash", reset.ExpiresAt, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.Create(context.Background(), reset))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlPasswordResetRepo_Reset(t *testing.T) {
+	repo, mock, cleanup := getMockPasswordResetRepo(t)
+	defer cleanup()
+
+	now := time.Now()
+	guid := uuid.New()
+	columns := []string{"user_id", "expires_at"}
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM password_resets WHERE token_hash").
+			WithArgs("hash").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(guid, now.Add(time.Hour)))
+		mock.ExpectExec("INSERT INTO credentials").
+			WithArgs(guid, "password_hash", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM tokens WHERE user_id").
+			WithArgs(guid).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec("DELETE FROM password_resets WHERE user_id").
+			WithArgs(guid).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		userID, revoked, err := repo.Reset(context.Background(), "hash", "password_hash", now)
+		require.NoError(t, err)
+		assert.Equal(t, guid, userID)
+		assert.Equal(t, 3, revoked)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM password_resets WHERE token_hash").
+			WithArgs("hash").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, _, err := repo.Reset(context.Background(), "hash", "password_hash", now)
+		assert.ErrorIs(t, err, domain.ErrInvalidResetToken)
+	})
+
+	t.Run("Expired token is deleted", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM password_resets WHERE token_hash").
+			WithArgs("hash").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(guid, now.Add(-time.Second)))
+		mock.ExpectCommit()
+
+		_, _, err := repo.Reset(context.Background(), "hash", "password_hash", now)
+		assert.ErrorIs(t, err, domain.ErrInvalidResetToken)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,98 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/database"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	"time"
+)
+
+// PostgresqlRecoveryCodeRepo - имплементация интерфейса repository.IRecoveryCodeRepo.
+// Позволяет взаимодействовать с кодами восстановления в Postgresql
+type PostgresqlRecoveryCodeRepo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+// Replace удаляет все коды восстановления пользователя, включая использованные, и сохраняет новый набор.
+func (r *PostgresqlRecoveryCodeRepo) Replace(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error("error starting transaction", zap.Error(err))
+		return err
+	}
+	defer database.TxRollback(tx, r.logger)
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		r.logger.Error("Error deleting recovery codes", zap.Error(err))
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, hash := range codeHashes {
+		_, err = tx.ExecContext(ctx, "INSERT INTO recovery_codes (id, user_id, code_hash, created_at) VALUES ($1, $2, $3, $4)",
+			uuid.New(), userID, hash, now)
+		if err != nil {
+			r.logger.Error("Error inserting recovery code", zap.Error(err))
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("error committing transaction", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// ListUnused возвращает неиспользованные коды восстановления пользователя.
+func (r *PostgresqlRecoveryCodeRepo) ListUnused(ctx context.Context, userID uuid.UUID) ([]domain.RecoveryCode, error) {
+	var rows []struct {
+		ID       uuid.UUID `db:"id"`
+		CodeHash string    `db:"code_hash"`
+	}
+
+	err := r.db.SelectContext(ctx, &rows, "SELECT id, code_hash FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL", userID)
+	if err != nil {
+		r.logger.Error("Error querying recovery codes", zap.Error(err))
+		return nil, err
+	}
+
+	codes := make([]domain.RecoveryCode, 0, len(rows))
+	for _, row := range rows {
+		codes = append(codes, domain.RecoveryCode{ID: row.ID, UserID: userID, CodeHash: row.CodeHash})
+	}
+
+	return codes, nil
+}
+
+// MarkUsed отмечает код как использованный.
+// Возвращает false, если код уже использован или удален - так параллельные запросы не могут использовать один код дважды.
+func (r *PostgresqlRecoveryCodeRepo) MarkUsed(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "UPDATE recovery_codes SET used_at = $2 WHERE id = $1 AND used_at IS NULL", id, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("Error marking recovery code as used", zap.Error(err))
+		return false, err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Error getting rows affected", zap.Error(err))
+		return false, err
+	}
+
+	return updated > 0, nil
+}
+
+// NewPostgresqlRecoveryCodeRepo - конструктор для создания нового экземпляра PostgresqlRecoveryCodeRepo.
+func NewPostgresqlRecoveryCodeRepo(db *sqlx.DB, logger *zap.Logger) repository.IRecoveryCodeRepo {
+	return &PostgresqlRecoveryCodeRepo{
+		db:     db,
+		logger: logger,
+	}
+}
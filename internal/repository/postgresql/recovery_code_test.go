@@ -0,0 +1,117 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+)
+
+func getMockRecoveryCodeRepo(t *testing.T) (repository.IRecoveryCodeRepo, sqlmock.Sqlmock, func()) {
+	logger := zap.NewNop()
+
+	mockDB, mock, err := sqlmock.New()
+
+	require.NoError(t, err, "creating mock db")
+
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	repo := NewPostgresqlRecoveryCodeRepo(sqlxDB, logger)
+
+	cleanup := func() {
+		mockDB.Close()
+	}
+
+	return repo, mock, cleanup
+}
+
+func TestPostgresqlRecoveryCodeRepo_Replace(t *testing.T) {
+	repo, mock, cleanup := getMockRecoveryCodeRepo(t)
+	defer cleanup()
+
+	t.Run("Success", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM recovery_codes").
+			WithArgs(guid).
+			WillReturnResult(sqlmock.NewResult(0, 10))
+		mock.ExpectExec("INSERT INTO recovery_codes").
+			WithArgs(sqlmock.AnyArg(), guid, "hash1", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO recovery_codes").
+			WithArgs(sqlmock.AnyArg(), guid, "hash2", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.Replace(context.Background(), guid, []string{"hash1", "hash2"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Insert error rolls back", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM recovery_codes").
+			WithArgs(guid).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO recovery_codes").
+			WithArgs(sqlmock.AnyArg(), guid, "hash1", sqlmock.AnyArg()).
+			WillReturnError(errors.New("db error"))
+		mock.ExpectRollback()
+
+		err := repo.Replace(context.Background(), guid, []string{"hash1"})
+		assert.Error(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlRecoveryCodeRepo_ListUnused(t *testing.T) {
+	repo, mock, cleanup := getMockRecoveryCodeRepo(t)
+	defer cleanup()
+
+	guid, id := uuid.New(), uuid.New()
+	mock.ExpectQuery("SELECT id, code_hash FROM recovery_codes").
+		WithArgs(guid).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code_hash"}).AddRow(id, "hash"))
+
+	codes, err := repo.ListUnused(context.Background(), guid)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.RecoveryCode{{ID: id, UserID: guid, CodeHash: "hash"}}, codes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlRecoveryCodeRepo_MarkUsed(t *testing.T) {
+	repo, mock, cleanup := getMockRecoveryCodeRepo(t)
+	defer cleanup()
+
+	t.Run("Unused", func(t *testing.T) {
+		id := uuid.New()
+		mock.ExpectExec("UPDATE recovery_codes SET used_at").
+			WithArgs(id, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		used, err := repo.MarkUsed(context.Background(), id)
+		assert.NoError(t, err)
+		assert.True(t, used)
+	})
+
+	t.Run("Already used", func(t *testing.T) {
+		id := uuid.New()
+		mock.ExpectExec("UPDATE recovery_codes SET used_at").
+			WithArgs(id, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		used, err := repo.MarkUsed(context.Background(), id)
+		assert.NoError(t, err)
+		assert.False(t, used)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,165 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/database"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+)
+
+// userRolesRoleConstraint - имя внешнего ключа user_roles на таблицу roles
+const userRolesRoleConstraint = "user_roles_role_fkey"
+
+// roleSelect - выборка ролей с их разрешениями, собранными в массив
+const roleSelect = `SELECT r.name, COALESCE(array_agg(p.permission ORDER BY p.permission) FILTER (WHERE p.permission IS NOT NULL), '{}') AS permissions
+	FROM roles r LEFT JOIN role_permissions p ON p.role = r.name`
+
+// PostgresqlRoleRepo - имплементация интерфейса repository.IRoleRepo.
+// Позволяет взаимодействовать с ролями пользователей в Postgresql
+type PostgresqlRoleRepo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+// roleRow - роль с агрегированными разрешениями.
+type roleRow struct {
+	Name        string         `db:"name"`
+	Permissions pq.StringArray `db:"permissions"`
+}
+
+// toRoles преобразует строки выборки в доменные модели.
+func toRoles(rows []roleRow) []domain.Role {
+	roles := make([]domain.Role, len(rows))
+	for i, row := range rows {
+		roles[i] = domain.Role{Name: row.Name, Permissions: row.Permissions}
+	}
+	return roles
+}
+
+// Save создает роль, если ее нет, и заменяет ее разрешения в одной транзакции.
+func (r *PostgresqlRoleRepo) Save(ctx context.Context, role *domain.Role) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error("error starting transaction", zap.Error(err))
+		return err
+	}
+	defer database.TxRollback(tx, r.logger)
+
+	if _, err = tx.ExecContext(ctx, "INSERT INTO roles (name) VALUES ($1) ON CONFLICT (name) DO NOTHING", role.Name); err != nil {
+		r.logger.Error("Error inserting role", zap.Error(err))
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM role_permissions WHERE role = $1", role.Name); err != nil {
+		r.logger.Error("Error deleting role permissions", zap.Error(err))
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO role_permissions (role, permission) SELECT $1, unnest($2::text[])",
+		role.Name, pq.Array(role.Permissions))
+	if err != nil {
+		r.logger.Error("Error inserting role permissions", zap.Error(err))
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.Error("error committing transaction", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// List возвращает все роли с их разрешениями в порядке названий.
+func (r *PostgresqlRoleRepo) List(ctx context.Context) ([]domain.Role, error) {
+	var rows []roleRow
+
+	err := r.db.SelectContext(ctx, &rows, roleSelect+` GROUP BY r.name ORDER BY r.name`)
+	if err != nil {
+		r.logger.Error("Error listing roles", zap.Error(err))
+		return nil, err
+	}
+
+	return toRoles(rows), nil
+}
+
+// Delete удаляет роль. Разрешения роли и ее назначения пользователям удаляются каскадно.
+func (r *PostgresqlRoleRepo) Delete(ctx context.Context, name string) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM roles WHERE name = $1", name)
+	if err != nil {
+		r.logger.Error("Error deleting role", zap.Error(err))
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Error getting rows affected", zap.Error(err))
+		return err
+	}
+
+	if deleted == 0 {
+		return domain.ErrRoleNotFound
+	}
+	return nil
+}
+
+// GetUserRoles возвращает роли пользователя с их разрешениями в порядке названий.
+func (r *PostgresqlRoleRepo) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]domain.Role, error) {
+	var rows []roleRow
+
+	err := r.db.SelectContext(ctx, &rows,
+		roleSelect+` JOIN user_roles ur ON ur.role = r.name WHERE ur.user_id = $1 GROUP BY r.name ORDER BY r.name`, userID)
+	if err != nil {
+		r.logger.Error("Error getting user roles", zap.Error(err))
+		return nil, err
+	}
+
+	return toRoles(rows), nil
+}
+
+// SetUserRoles заменяет роли пользователя в одной транзакции.
+// Несуществующие роли и пользователь определяются по нарушению внешних ключей user_roles.
+func (r *PostgresqlRoleRepo) SetUserRoles(ctx context.Context, userID uuid.UUID, roles []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error("error starting transaction", zap.Error(err))
+		return err
+	}
+	defer database.TxRollback(tx, r.logger)
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM user_roles WHERE user_id = $1", userID); err != nil {
+		r.logger.Error("Error deleting user roles", zap.Error(err))
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO user_roles (user_id, role) SELECT $1, unnest($2::text[])", userID, pq.Array(roles))
+	if err != nil {
+		if database.IsPGError(err, database.PGForeignKeyViolationCode) {
+			if database.PGConstraint(err) == userRolesRoleConstraint {
+				return domain.ErrRoleNotFound
+			}
+			return domain.ErrUserNotFound
+		}
+		r.logger.Error("Error inserting user roles", zap.Error(err))
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.Error("error committing transaction", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// NewPostgresqlRoleRepo - конструктор для создания нового экземпляра PostgresqlRoleRepo.
+func NewPostgresqlRoleRepo(db *sqlx.DB, logger *zap.Logger) repository.IRoleRepo {
+	return &PostgresqlRoleRepo{
+		db:     db,
+		logger: logger,
+	}
+}
@@ -0,0 +1,183 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/database"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+)
+
+func getMockRoleRepo(t *testing.T) (repository.IRoleRepo, sqlmock.Sqlmock, func()) {
+	logger := zap.NewNop()
+
+	mockDB, mock, err := sqlmock.New()
+
+	require.NoError(t, err, "creating mock db")
+
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	repo := NewPostgresqlRoleRepo(sqlxDB, logger)
+
+	cleanup := func() {
+		mockDB.Close()
+	}
+
+	return repo, mock, cleanup
+}
+
+func TestPostgresqlRoleRepo_Save(t *testing.T) {
+	repo, mock, cleanup := getMockRoleRepo(t)
+	defer cleanup()
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO roles").
+			WithArgs("support").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM role_permissions").
+			WithArgs("support").
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec("INSERT INTO role_permissions").
+			WithArgs("support", pq.Array([]string{"users:read", "orders:read"})).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		err := repo.Save(context.Background(), &domain.Role{Name: "support", Permissions: []string{"users:read", "orders:read"}})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Insert error rolls back", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO roles").
+			WithArgs("support").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE FROM role_permissions").
+			WithArgs("support").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO role_permissions").
+			WithArgs("support", pq.Array([]string{"users:read"})).
+			WillReturnError(errors.New("db error"))
+		mock.ExpectRollback()
+
+		err := repo.Save(context.Background(), &domain.Role{Name: "support", Permissions: []string{"users:read"}})
+		assert.Error(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlRoleRepo_List(t *testing.T) {
+	repo, mock, cleanup := getMockRoleRepo(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT (.+) FROM roles r LEFT JOIN role_permissions p (.+) GROUP BY r.name ORDER BY r.name").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "permissions"}).
+			AddRow("admin", `{users:read,users:write}`).
+			AddRow("guest", `{}`))
+
+	roles, err := repo.List(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Role{
+		{Name: "admin", Permissions: []string{"users:read", "users:write"}},
+		{Name: "guest", Permissions: []string{}},
+	}, roles)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlRoleRepo_Delete(t *testing.T) {
+	repo, mock, cleanup := getMockRoleRepo(t)
+	defer cleanup()
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM roles WHERE name").
+			WithArgs("support").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.Delete(context.Background(), "support"))
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM roles WHERE name").
+			WithArgs("support").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.ErrorIs(t, repo.Delete(context.Background(), "support"), domain.ErrRoleNotFound)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlRoleRepo_GetUserRoles(t *testing.T) {
+	repo, mock, cleanup := getMockRoleRepo(t)
+	defer cleanup()
+
+	guid := uuid.New()
+	mock.ExpectQuery("SELECT (.+) FROM roles r (.+) JOIN user_roles ur (.+) WHERE ur.user_id").
+		WithArgs(guid).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "permissions"}).
+			AddRow("support", `{users:read}`))
+
+	roles, err := repo.GetUserRoles(context.Background(), guid)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Role{{Name: "support", Permissions: []string{"users:read"}}}, roles)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlRoleRepo_SetUserRoles(t *testing.T) {
+	repo, mock, cleanup := getMockRoleRepo(t)
+	defer cleanup()
+
+	guid := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM user_roles").
+			WithArgs(guid).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO user_roles").
+			WithArgs(guid, pq.Array([]string{"admin", "support"})).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.SetUserRoles(context.Background(), guid, []string{"admin", "support"}))
+	})
+
+	t.Run("Role not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM user_roles").
+			WithArgs(guid).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO user_roles").
+			WithArgs(guid, pq.Array([]string{"unknown"})).
+			WillReturnError(&pq.Error{Code: database.PGForeignKeyViolationCode, Constraint: "user_roles_role_fkey"})
+		mock.ExpectRollback()
+
+		err := repo.SetUserRoles(context.Background(), guid, []string{"unknown"})
+		assert.ErrorIs(t, err, domain.ErrRoleNotFound)
+	})
+
+	t.Run("User not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM user_roles").
+			WithArgs(guid).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO user_roles").
+			WithArgs(guid, pq.Array([]string{"admin"})).
+			WillReturnError(&pq.Error{Code: database.PGForeignKeyViolationCode, Constraint: "user_roles_user_id_fkey"})
+		mock.ExpectRollback()
+
+		err := repo.SetUserRoles(context.Background(), guid, []string{"admin"})
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -35,20 +35,22 @@ func (r *PostgresqlTokenRepo) Create(ctx context.Context, id, jti, userID uuid.U
 
 // GetToken получает Refresh - токен из базы данных по userID и jti.
 // Так же принимает notAfter - время, до которого токен должен быть действителен.
+// Время последнего использования сессии - время выпуска токена: при входе или предыдущем обновлении токенов.
 // Если токен не найден или просрочен, возвращает ошибку domain.ErrTokenNotFound.
-func (r *PostgresqlTokenRepo) GetToken(ctx context.Context, userID, jti uuid.UUID, notAfter time.Time) (uuid.UUID, string, error) {
+func (r *PostgresqlTokenRepo) GetToken(ctx context.Context, userID, jti uuid.UUID, notAfter time.Time) (uuid.UUID, string, time.Time, error) {
 	var tokenID uuid.UUID
 	var token string
+	var lastUsedAt time.Time
 
-	err := r.db.QueryRowxContext(ctx, "SELECT id, token FROM tokens WHERE user_id = $1 AND jti = $2 AND expires_at >= $3", userID, jti, notAfter).Scan(&tokenID, &token)
+	err := r.db.QueryRowxContext(ctx, "SELECT id, token, last_used_at FROM tokens WHERE user_id = $1 AND jti = $2 AND expires_at >= $3", userID, jti, notAfter).Scan(&tokenID, &token, &lastUsedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return uuid.Nil, "", domain.ErrTokenNotFound
+			return uuid.Nil, "", time.Time{}, domain.ErrTokenNotFound
 		}
-		return uuid.Nil, "", err
+		return uuid.Nil, "", time.Time{}, err
 	}
 
-	return tokenID, token, nil
+	return tokenID, token, lastUsedAt, nil
 }
 
 // RotateToken - обновляет токен в базе данных.
@@ -86,6 +88,47 @@ func (r *PostgresqlTokenRepo) RotateToken(ctx context.Context, oldID, id, jti, u
 	return nil
 }
 
+// RevokeUserTokens удаляет все Refresh - токены пользователя, кроме токена с exceptJTI.
+// Чтобы удалить все токены пользователя, в exceptJTI передается uuid.Nil.
+// Возвращает количество удаленных токенов.
+func (r *PostgresqlTokenRepo) RevokeUserTokens(ctx context.Context, userID, exceptJTI uuid.UUID) (int, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM tokens WHERE user_id = $1 AND jti <> $2", userID, exceptJTI)
+	if err != nil {
+		r.logger.Error("error revoking user tokens", zap.Error(err))
+		return 0, err
+	}
+
+	revoked, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("error getting rows affected", zap.Error(err))
+		return 0, err
+	}
+
+	return int(revoked), nil
+}
+
+// RevokeToken удаляет Refresh - токен пользователя userID с указанным jti.
+// Если токен не найден, возвращает ошибку domain.ErrTokenNotFound.
+func (r *PostgresqlTokenRepo) RevokeToken(ctx context.Context, userID, jti uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM tokens WHERE user_id = $1 AND jti = $2", userID, jti)
+	if err != nil {
+		r.logger.Error("error revoking token", zap.Error(err))
+		return err
+	}
+
+	revoked, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("error getting rows affected", zap.Error(err))
+		return err
+	}
+
+	if revoked == 0 {
+		return domain.ErrTokenNotFound
+	}
+
+	return nil
+}
+
 // NewPostgresqlTokenRepo - конструктор для создания нового экземпляра PostgresqlTokenRepo.
 func NewPostgresqlTokenRepo(db *sqlx.DB, logger *zap.Logger) repository.ITokenRepo {
 	return &PostgresqlTokenRepo{
@@ -80,16 +80,18 @@ func TestPostgresqlTokenRepo_GetToken(t *testing.T) {
 		notAfter := time.Now()
 		expectedID := uuid.New()
 		expectedToken := "test_token"
+		expectedLastUse := notAfter.Add(-time.Hour)
 
-		mock.ExpectQuery("SELECT id, token FROM tokens").
+		mock.ExpectQuery("SELECT id, token, last_used_at FROM tokens").
 			WithArgs(userID, jti, notAfter).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "token"}).
-				AddRow(expectedID, expectedToken))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "token", "last_used_at"}).
+				AddRow(expectedID, expectedToken, expectedLastUse))
 
-		tokenID, token, err := repo.GetToken(context.Background(), userID, jti, notAfter)
+		tokenID, token, lastUsedAt, err := repo.GetToken(context.Background(), userID, jti, notAfter)
 		assert.NoError(t, err)
 		assert.Equal(t, expectedToken, token)
 		assert.Equal(t, expectedID, tokenID)
+		assert.Equal(t, expectedLastUse, lastUsedAt)
 	})
 
 	t.Run("Token not found", func(t *testing.T) {
@@ -97,11 +99,11 @@ func TestPostgresqlTokenRepo_GetToken(t *testing.T) {
 		jti := uuid.New()
 		notAfter := time.Now()
 
-		mock.ExpectQuery("SELECT id, token FROM tokens").
+		mock.ExpectQuery("SELECT id, token, last_used_at FROM tokens").
 			WithArgs(userID, jti, notAfter).
 			WillReturnError(sql.ErrNoRows)
 
-		tokenID, token, err := repo.GetToken(context.Background(), userID, jti, notAfter)
+		tokenID, token, _, err := repo.GetToken(context.Background(), userID, jti, notAfter)
 		assert.Error(t, err)
 		assert.Empty(t, token)
 		assert.Equal(t, uuid.Nil, tokenID)
@@ -157,3 +159,62 @@ func TestPostgresqlTokenRepo_RotateToken(t *testing.T) {
 		assert.ErrorIs(t, err, domain.ErrTokenExists)
 	})
 }
+
+func TestPostgresqlTokenRepo_RevokeUserTokens(t *testing.T) {
+	repo, mock, cleanup := getMockTokenRepo(t)
+	defer cleanup()
+
+	t.Run("Success", func(t *testing.T) {
+		userID := uuid.New()
+		exceptJTI := uuid.New()
+
+		mock.ExpectExec("DELETE FROM tokens").
+			WithArgs(userID, exceptJTI).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		revoked, err := repo.RevokeUserTokens(context.Background(), userID, exceptJTI)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, revoked)
+	})
+
+	t.Run("DB error", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM tokens").
+			WillReturnError(sql.ErrConnDone)
+
+		_, err := repo.RevokeUserTokens(context.Background(), uuid.New(), uuid.Nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestPostgresqlTokenRepo_RevokeToken(t *testing.T) {
+	repo, mock, cleanup := getMockTokenRepo(t)
+	defer cleanup()
+
+	t.Run("Success", func(t *testing.T) {
+		userID := uuid.New()
+		jti := uuid.New()
+
+		mock.ExpectExec("DELETE FROM tokens").
+			WithArgs(userID, jti).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.RevokeToken(context.Background(), userID, jti)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM tokens").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.RevokeToken(context.Background(), uuid.New(), uuid.New())
+		assert.ErrorIs(t, err, domain.ErrTokenNotFound)
+	})
+
+	t.Run("DB error", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM tokens").
+			WillReturnError(sql.ErrConnDone)
+
+		err := repo.RevokeToken(context.Background(), uuid.New(), uuid.New())
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,124 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	"time"
+)
+
+// PostgresqlTOTPRepo - имплементация интерфейса repository.ITOTPRepo.
+// Позволяет взаимодействовать с подключениями TOTP в Postgresql
+type PostgresqlTOTPRepo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+// Save сохраняет зашифрованный секрет неподтвержденного подключения, заменяя предыдущий неподтвержденный.
+// Если подключение уже подтверждено, возвращает ошибку domain.ErrTOTPAlreadyEnabled.
+func (r *PostgresqlTOTPRepo) Save(ctx context.Context, userID uuid.UUID, encryptedSecret string) error {
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO totp_enrollments (user_id, secret, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, created_at = EXCLUDED.created_at
+		WHERE totp_enrollments.confirmed_at IS NULL`,
+		userID, encryptedSecret, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("Error saving totp enrollment", zap.Error(err))
+		return err
+	}
+
+	saved, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Error getting rows affected", zap.Error(err))
+		return err
+	}
+
+	if saved == 0 {
+		return domain.ErrTOTPAlreadyEnabled
+	}
+
+	return nil
+}
+
+// Get возвращает подключение TOTP пользователя.
+// Если пользователь не начинал подключение, возвращает ошибку domain.ErrTOTPNotEnrolled.
+func (r *PostgresqlTOTPRepo) Get(ctx context.Context, userID uuid.UUID) (*domain.TOTPEnrollment, error) {
+	var row struct {
+		Secret       string       `db:"secret"`
+		ConfirmedAt  sql.NullTime `db:"confirmed_at"`
+		LastUsedStep int64        `db:"last_used_step"`
+	}
+
+	err := r.db.GetContext(ctx, &row, "SELECT secret, confirmed_at, last_used_step FROM totp_enrollments WHERE user_id = $1", userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrTOTPNotEnrolled
+		}
+		r.logger.Error("Error querying totp enrollment", zap.Error(err))
+		return nil, err
+	}
+
+	return &domain.TOTPEnrollment{
+		UserID:          userID,
+		EncryptedSecret: row.Secret,
+		Confirmed:       row.ConfirmedAt.Valid,
+		LastUsedStep:    row.LastUsedStep,
+	}, nil
+}
+
+// Confirm подтверждает подключение и запоминает использованный при подтверждении временной шаг.
+// Если подключение не найдено или уже подтверждено, возвращает ошибку domain.ErrTOTPNotEnrolled.
+func (r *PostgresqlTOTPRepo) Confirm(ctx context.Context, userID uuid.UUID, step int64) error {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE totp_enrollments SET confirmed_at = $2, last_used_step = $3 WHERE user_id = $1 AND confirmed_at IS NULL",
+		userID, time.Now().UTC(), step)
+	if err != nil {
+		r.logger.Error("Error confirming totp enrollment", zap.Error(err))
+		return err
+	}
+
+	confirmed, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Error getting rows affected", zap.Error(err))
+		return err
+	}
+
+	if confirmed == 0 {
+		return domain.ErrTOTPNotEnrolled
+	}
+
+	return nil
+}
+
+// UseStep атомарно отмечает временной шаг как использованный.
+// Возвращает false, если этот или более поздний шаг уже был использован.
+func (r *PostgresqlTOTPRepo) UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE totp_enrollments SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2",
+		userID, step)
+	if err != nil {
+		r.logger.Error("Error updating totp last used step", zap.Error(err))
+		return false, err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Error getting rows affected", zap.Error(err))
+		return false, err
+	}
+
+	return updated > 0, nil
+}
+
+// NewPostgresqlTOTPRepo - конструктор для создания нового экземпляра PostgresqlTOTPRepo.
+func NewPostgresqlTOTPRepo(db *sqlx.DB, logger *zap.Logger) repository.ITOTPRepo {
+	return &PostgresqlTOTPRepo{
+		db:     db,
+		logger: logger,
+	}
+}
@@ -0,0 +1,159 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func getMockTOTPRepo(t *testing.T) (repository.ITOTPRepo, sqlmock.Sqlmock, func()) {
+	logger := zap.NewNop()
+
+	mockDB, mock, err := sqlmock.New()
+
+	require.NoError(t, err, "creating mock db")
+
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	repo := NewPostgresqlTOTPRepo(sqlxDB, logger)
+
+	cleanup := func() {
+		mockDB.Close()
+	}
+
+	return repo, mock, cleanup
+}
+
+func TestPostgresqlTOTPRepo_Save(t *testing.T) {
+	repo, mock, cleanup := getMockTOTPRepo(t)
+	defer cleanup()
+
+	t.Run("Success", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectExec("INSERT INTO totp_enrollments").
+			WithArgs(guid, "secret", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Save(context.Background(), guid, "secret")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Already confirmed", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectExec("INSERT INTO totp_enrollments").
+			WithArgs(guid, "secret", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Save(context.Background(), guid, "secret")
+		assert.ErrorIs(t, err, domain.ErrTOTPAlreadyEnabled)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlTOTPRepo_Get(t *testing.T) {
+	repo, mock, cleanup := getMockTOTPRepo(t)
+	defer cleanup()
+
+	t.Run("Confirmed", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectQuery("SELECT secret, confirmed_at, last_used_step FROM totp_enrollments").
+			WithArgs(guid).
+			WillReturnRows(sqlmock.NewRows([]string{"secret", "confirmed_at", "last_used_step"}).AddRow("secret", time.Now(), 42))
+
+		enrollment, err := repo.Get(context.Background(), guid)
+		require.NoError(t, err)
+		assert.Equal(t, guid, enrollment.UserID)
+		assert.Equal(t, "secret", enrollment.EncryptedSecret)
+		assert.True(t, enrollment.Confirmed)
+		assert.Equal(t, int64(42), enrollment.LastUsedStep)
+	})
+
+	t.Run("Unconfirmed", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectQuery("SELECT secret, confirmed_at, last_used_step FROM totp_enrollments").
+			WithArgs(guid).
+			WillReturnRows(sqlmock.NewRows([]string{"secret", "confirmed_at", "last_used_step"}).AddRow("secret", nil, 0))
+
+		enrollment, err := repo.Get(context.Background(), guid)
+		require.NoError(t, err)
+		assert.False(t, enrollment.Confirmed)
+	})
+
+	t.Run("Not enrolled", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectQuery("SELECT secret, confirmed_at, last_used_step FROM totp_enrollments").
+			WithArgs(guid).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.Get(context.Background(), guid)
+		assert.ErrorIs(t, err, domain.ErrTOTPNotEnrolled)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlTOTPRepo_Confirm(t *testing.T) {
+	repo, mock, cleanup := getMockTOTPRepo(t)
+	defer cleanup()
+
+	t.Run("Success", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectExec("UPDATE totp_enrollments SET confirmed_at").
+			WithArgs(guid, sqlmock.AnyArg(), int64(100)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Confirm(context.Background(), guid, 100)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Not pending", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectExec("UPDATE totp_enrollments SET confirmed_at").
+			WithArgs(guid, sqlmock.AnyArg(), int64(100)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Confirm(context.Background(), guid, 100)
+		assert.ErrorIs(t, err, domain.ErrTOTPNotEnrolled)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlTOTPRepo_UseStep(t *testing.T) {
+	repo, mock, cleanup := getMockTOTPRepo(t)
+	defer cleanup()
+
+	t.Run("Fresh step", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectExec("UPDATE totp_enrollments SET last_used_step").
+			WithArgs(guid, int64(101)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		used, err := repo.UseStep(context.Background(), guid, 101)
+		assert.NoError(t, err)
+		assert.True(t, used)
+	})
+
+	t.Run("Replayed step", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectExec("UPDATE totp_enrollments SET last_used_step").
+			WithArgs(guid, int64(101)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		used, err := repo.UseStep(context.Background(), guid, 101)
+		assert.NoError(t, err)
+		assert.False(t, used)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
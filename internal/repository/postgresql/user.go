@@ -19,24 +19,84 @@ type PostgresqlUserRepo struct {
 	logger *zap.Logger
 }
 
-// Create создает нового пользователя с указанными guid и email.
+// usersEmailConstraint - имя ограничения уникальности email в таблице users
+const usersEmailConstraint = "users_email_key"
+
+// Create создает нового пользователя.
+// Если passwordHash не пуст, в той же транзакции сохраняется хеш пароля пользователя.
 // Если пользователь с таким guid уже существует, возвращает ошибку domain.ErrUserExists.
-func (r *PostgresqlUserRepo) Create(ctx context.Context, guid uuid.UUID, email string) error {
-	_, err := r.db.ExecContext(ctx, "INSERT INTO users (guid, email) VALUES ($1, $2)", guid, email)
+// Если email уже занят другим пользователем, возвращает ошибку domain.ErrEmailTaken.
+func (r *PostgresqlUserRepo) Create(ctx context.Context, user *domain.User, passwordHash string) error {
+	// Пустой email сохраняется как NULL, чтобы не нарушать ограничение уникальности
+	email := sql.NullString{String: user.Email, Valid: user.Email != ""}
+
+	if passwordHash == "" {
+		_, err := r.db.ExecContext(ctx, "INSERT INTO users (guid, email, display_name, created_at) VALUES ($1, $2, $3, $4)",
+			user.GUID, email, user.DisplayName, user.CreatedAt)
+		return r.mapCreateError(err)
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		if database.IsPGError(err, database.PGUniqueViolationCode) {
-			return domain.ErrUserExists
-		}
-		r.logger.Error("Error inserting user", zap.Error(err))
+		r.logger.Error("error starting transaction", zap.Error(err))
 		return err
 	}
+	defer database.TxRollback(tx, r.logger)
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO users (guid, email, display_name, created_at) VALUES ($1, $2, $3, $4)",
+		user.GUID, email, user.DisplayName, user.CreatedAt)
+	if err != nil {
+		return r.mapCreateError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO credentials (user_id, password_hash, updated_at) VALUES ($1, $2, $3)",
+		user.GUID, passwordHash, user.CreatedAt)
+	if err != nil {
+		r.logger.Error("Error inserting credentials", zap.Error(err))
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("error committing transaction", zap.Error(err))
+		return err
+	}
+
 	return nil
 }
 
+// mapCreateError преобразует ошибку вставки пользователя в доменную ошибку.
+func (r *PostgresqlUserRepo) mapCreateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if database.IsPGError(err, database.PGUniqueViolationCode) {
+		if database.PGConstraint(err) == usersEmailConstraint {
+			return domain.ErrEmailTaken
+		}
+		return domain.ErrUserExists
+	}
+	r.logger.Error("Error inserting user", zap.Error(err))
+	return err
+}
+
+// Exists проверяет, зарегистрирован ли пользователь с указанным guid.
+func (r *PostgresqlUserRepo) Exists(ctx context.Context, guid uuid.UUID) (bool, error) {
+	var exists bool
+
+	err := r.db.GetContext(ctx, &exists, "SELECT EXISTS(SELECT 1 FROM users WHERE guid = $1)", guid)
+	if err != nil {
+		r.logger.Error("Error checking user existence", zap.Error(err))
+		return false, err
+	}
+
+	return exists, nil
+}
+
 // GetEmail возвращает email пользователя по его guid.
+// Для пользователей без email возвращает пустую строку.
 // Если пользователь не найден, возвращает ошибку domain.ErrUserNotFound.
 func (r *PostgresqlUserRepo) GetEmail(ctx context.Context, guid uuid.UUID) (string, error) {
-	var email string
+	var email sql.NullString
 
 	err := r.db.GetContext(ctx, &email, "SELECT email FROM users WHERE guid = $1", guid)
 	if err != nil {
@@ -47,7 +107,84 @@ func (r *PostgresqlUserRepo) GetEmail(ctx context.Context, guid uuid.UUID) (stri
 		return "", err
 	}
 
-	return email, nil
+	return email.String, nil
+}
+
+// IsEmailVerified сообщает, подтвержден ли email пользователя.
+// Если пользователь не найден, возвращает ошибку domain.ErrUserNotFound.
+func (r *PostgresqlUserRepo) IsEmailVerified(ctx context.Context, guid uuid.UUID) (bool, error) {
+	var verified bool
+
+	err := r.db.GetContext(ctx, &verified, "SELECT email_verified_at IS NOT NULL FROM users WHERE guid = $1", guid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, domain.ErrUserNotFound
+		}
+		r.logger.Error("Error querying user", zap.Error(err))
+		return false, err
+	}
+
+	return verified, nil
+}
+
+// GetGUIDByEmail возвращает guid пользователя по email.
+// Если пользователь не найден, возвращает ошибку domain.ErrUserNotFound.
+func (r *PostgresqlUserRepo) GetGUIDByEmail(ctx context.Context, email string) (uuid.UUID, error) {
+	var guid uuid.UUID
+
+	err := r.db.GetContext(ctx, &guid, "SELECT guid FROM users WHERE email = $1", email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, domain.ErrUserNotFound
+		}
+		r.logger.Error("Error querying user", zap.Error(err))
+		return uuid.Nil, err
+	}
+
+	return guid, nil
+}
+
+// GetIPChangePolicy возвращает персональную политику смены IP пользователя по его guid.
+// Если политика не переопределена, возвращает пустую строку.
+// Если пользователь не найден, возвращает ошибку domain.ErrUserNotFound.
+func (r *PostgresqlUserRepo) GetIPChangePolicy(ctx context.Context, guid uuid.UUID) (domain.IPChangePolicy, error) {
+	var policy sql.NullString
+
+	err := r.db.GetContext(ctx, &policy, "SELECT ip_change_policy FROM users WHERE guid = $1", guid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", domain.ErrUserNotFound
+		}
+		r.logger.Error("Error querying user ip change policy", zap.Error(err))
+		return "", err
+	}
+
+	return domain.IPChangePolicy(policy.String), nil
+}
+
+// SetIPChangePolicy сохраняет персональную политику смены IP пользователя.
+// Пустая политика сохраняется как NULL, то есть к пользователю применяется глобальная политика.
+// Если пользователь не найден, возвращает ошибку domain.ErrUserNotFound.
+func (r *PostgresqlUserRepo) SetIPChangePolicy(ctx context.Context, guid uuid.UUID, policy domain.IPChangePolicy) error {
+	value := sql.NullString{String: string(policy), Valid: policy != ""}
+
+	result, err := r.db.ExecContext(ctx, "UPDATE users SET ip_change_policy = $1 WHERE guid = $2", value, guid)
+	if err != nil {
+		r.logger.Error("Error updating user ip change policy", zap.Error(err))
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Error getting rows affected", zap.Error(err))
+		return err
+	}
+
+	if updated == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
 }
 
 // NewPostgresqlUserRepo - конструктор для создания нового экземпляра PostgresqlUserRepo.
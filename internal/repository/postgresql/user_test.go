@@ -14,6 +14,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"testing"
+	"time"
 )
 
 func getMockUserRepo(t *testing.T) (repository.IUserRepo, sqlmock.Sqlmock, func()) {
@@ -38,31 +39,122 @@ func TestPostgresqlUserRepo_Create(t *testing.T) {
 	repo, mock, cleanup := getMockUserRepo(t)
 	defer cleanup()
 
+	newUser := func() *domain.User {
+		return &domain.User{GUID: uuid.New(), Email: "test@test.ru", DisplayName: "Test", CreatedAt: time.Now()}
+	}
+
 	t.Run("Success", func(t *testing.T) {
-		guid := uuid.New()
-		email := "test@test.ru"
+		user := newUser()
+
+		mock.ExpectExec("INSERT INTO users").
+			WithArgs(user.GUID, user.Email, user.DisplayName, user.CreatedAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err := repo.Create(context.Background(), user, "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Without email", func(t *testing.T) {
+		user := newUser()
+		user.Email = ""
+
+		mock.ExpectExec("INSERT INTO users").
+			WithArgs(user.GUID, nil, user.DisplayName, user.CreatedAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err := repo.Create(context.Background(), user, "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("With password", func(t *testing.T) {
+		user := newUser()
 
+		mock.ExpectBegin()
 		mock.ExpectExec("INSERT INTO users").
-			WithArgs(guid, email).
+			WithArgs(user.GUID, user.Email, user.DisplayName, user.CreatedAt).
 			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO credentials").
+			WithArgs(user.GUID, "hash", user.CreatedAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
 
-		err := repo.Create(context.Background(), guid, email)
+		err := repo.Create(context.Background(), user, "hash")
 		assert.NoError(t, err)
 	})
 
+	t.Run("With password email taken", func(t *testing.T) {
+		user := newUser()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO users").
+			WithArgs(user.GUID, user.Email, user.DisplayName, user.CreatedAt).
+			WillReturnError(&pq.Error{Code: database.PGUniqueViolationCode, Constraint: "users_email_key"})
+		mock.ExpectRollback()
+
+		err := repo.Create(context.Background(), user, "hash")
+		assert.ErrorIs(t, err, domain.ErrEmailTaken)
+	})
+
 	t.Run("User exists", func(t *testing.T) {
-		guid := uuid.New()
-		email := "test@test.ru"
+		user := newUser()
 
 		mock.ExpectExec("INSERT INTO users").
-			WithArgs(guid, email).
-			WillReturnError(&pq.Error{Code: database.PGUniqueViolationCode})
+			WithArgs(user.GUID, user.Email, user.DisplayName, user.CreatedAt).
+			WillReturnError(&pq.Error{Code: database.PGUniqueViolationCode, Constraint: "users_pkey"})
 
-		err := repo.Create(context.Background(), guid, email)
+		err := repo.Create(context.Background(), user, "")
 		assert.Error(t, err)
 		assert.ErrorIs(t, err, domain.ErrUserExists)
 	})
 
+	t.Run("Email taken", func(t *testing.T) {
+		user := newUser()
+
+		mock.ExpectExec("INSERT INTO users").
+			WithArgs(user.GUID, user.Email, user.DisplayName, user.CreatedAt).
+			WillReturnError(&pq.Error{Code: database.PGUniqueViolationCode, Constraint: "users_email_key"})
+
+		err := repo.Create(context.Background(), user, "")
+		assert.ErrorIs(t, err, domain.ErrEmailTaken)
+	})
+
+}
+
+func TestPostgresqlUserRepo_Exists(t *testing.T) {
+	repo, mock, cleanup := getMockUserRepo(t)
+	defer cleanup()
+
+	t.Run("Exists", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(guid).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+		exists, err := repo.Exists(context.Background(), guid)
+		assert.NoError(t, err)
+		assert.True(t, exists)
+	})
+
+	t.Run("Not exists", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(guid).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		exists, err := repo.Exists(context.Background(), guid)
+		assert.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("DB error", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(guid).
+			WillReturnError(sql.ErrConnDone)
+
+		_, err := repo.Exists(context.Background(), guid)
+		assert.Error(t, err)
+	})
 }
 
 func TestPostgresqlUserRepo_GetEmail(t *testing.T) {
@@ -93,3 +185,135 @@ func TestPostgresqlUserRepo_GetEmail(t *testing.T) {
 		assert.Empty(t, email)
 	})
 }
+
+func TestPostgresqlUserRepo_IsEmailVerified(t *testing.T) {
+	repo, mock, cleanup := getMockUserRepo(t)
+	defer cleanup()
+
+	guid := uuid.New()
+
+	t.Run("Verified", func(t *testing.T) {
+		mock.ExpectQuery("SELECT email_verified_at IS NOT NULL FROM users").
+			WithArgs(guid).
+			WillReturnRows(sqlmock.NewRows([]string{"verified"}).AddRow(true))
+
+		verified, err := repo.IsEmailVerified(context.Background(), guid)
+		assert.NoError(t, err)
+		assert.True(t, verified)
+	})
+
+	t.Run("User not found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT email_verified_at IS NOT NULL FROM users").
+			WithArgs(guid).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.IsEmailVerified(context.Background(), guid)
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlUserRepo_GetGUIDByEmail(t *testing.T) {
+	repo, mock, cleanup := getMockUserRepo(t)
+	defer cleanup()
+
+	t.Run("Success", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectQuery("SELECT guid FROM users").
+			WithArgs("test@test.ru").
+			WillReturnRows(sqlmock.NewRows([]string{"guid"}).AddRow(guid))
+
+		found, err := repo.GetGUIDByEmail(context.Background(), "test@test.ru")
+		assert.NoError(t, err)
+		assert.Equal(t, guid, found)
+	})
+
+	t.Run("User not found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT guid FROM users").
+			WithArgs("test@test.ru").
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.GetGUIDByEmail(context.Background(), "test@test.ru")
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlUserRepo_GetIPChangePolicy(t *testing.T) {
+	repo, mock, cleanup := getMockUserRepo(t)
+	defer cleanup()
+
+	t.Run("Success", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectQuery("SELECT ip_change_policy FROM users").
+			WithArgs(guid).
+			WillReturnRows(sqlmock.NewRows([]string{"ip_change_policy"}).
+				AddRow("deny"))
+
+		policy, err := repo.GetIPChangePolicy(context.Background(), guid)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.IPChangePolicyDeny, policy)
+	})
+
+	t.Run("Not overridden", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectQuery("SELECT ip_change_policy FROM users").
+			WithArgs(guid).
+			WillReturnRows(sqlmock.NewRows([]string{"ip_change_policy"}).
+				AddRow(nil))
+
+		policy, err := repo.GetIPChangePolicy(context.Background(), guid)
+		assert.NoError(t, err)
+		assert.Empty(t, policy)
+	})
+
+	t.Run("User not found", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectQuery("SELECT ip_change_policy FROM users").
+			WithArgs(guid).
+			WillReturnError(sql.ErrNoRows)
+
+		policy, err := repo.GetIPChangePolicy(context.Background(), guid)
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+		assert.Empty(t, policy)
+	})
+}
+
+func TestPostgresqlUserRepo_SetIPChangePolicy(t *testing.T) {
+	repo, mock, cleanup := getMockUserRepo(t)
+	defer cleanup()
+
+	t.Run("Success", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectExec("UPDATE users SET ip_change_policy").
+			WithArgs(sql.NullString{String: "deny", Valid: true}, guid).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.SetIPChangePolicy(context.Background(), guid, domain.IPChangePolicyDeny)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectExec("UPDATE users SET ip_change_policy").
+			WithArgs(sql.NullString{}, guid).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.SetIPChangePolicy(context.Background(), guid, "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("User not found", func(t *testing.T) {
+		guid := uuid.New()
+		mock.ExpectExec("UPDATE users SET ip_change_policy").
+			WithArgs(sql.NullString{String: "notify", Valid: true}, guid).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.SetIPChangePolicy(context.Background(), guid, domain.IPChangePolicyNotify)
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
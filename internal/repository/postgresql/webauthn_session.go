@@ -0,0 +1,76 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	"time"
+)
+
+// PostgresqlWebAuthnSessionRepo - имплементация интерфейса repository.IWebAuthnSessionRepo.
+// Позволяет взаимодействовать с церемониями WebAuthn в Postgresql
+type PostgresqlWebAuthnSessionRepo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+// Create сохраняет новую церемонию. Для церемонии входа user_id сохраняется как NULL.
+func (r *PostgresqlWebAuthnSessionRepo) Create(ctx context.Context, session *domain.WebAuthnSession) error {
+	userID := uuid.NullUUID{UUID: session.UserID, Valid: session.UserID != uuid.Nil}
+
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO webauthn_sessions (id, challenge_hash, ceremony, user_id, expires_at) VALUES ($1, $2, $3, $4, $5)",
+		session.ID, session.ChallengeHash, string(session.Ceremony), userID, session.ExpiresAt)
+	if err != nil {
+		r.logger.Error("Error inserting webauthn session", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Consume удаляет церемонию по хешу challenge и возвращает ее.
+// Истекшая церемония тоже удаляется, но вместо нее возвращается ошибка domain.ErrWebAuthnCeremonyNotFound,
+// как и для отсутствующей.
+func (r *PostgresqlWebAuthnSessionRepo) Consume(ctx context.Context, challengeHash string, ceremony domain.WebAuthnCeremony, notAfter time.Time) (*domain.WebAuthnSession, error) {
+	var row struct {
+		ID        uuid.UUID     `db:"id"`
+		UserID    uuid.NullUUID `db:"user_id"`
+		ExpiresAt time.Time     `db:"expires_at"`
+	}
+
+	err := r.db.GetContext(ctx, &row,
+		"DELETE FROM webauthn_sessions WHERE challenge_hash = $1 AND ceremony = $2 RETURNING id, user_id, expires_at",
+		challengeHash, string(ceremony))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrWebAuthnCeremonyNotFound
+		}
+		r.logger.Error("Error consuming webauthn session", zap.Error(err))
+		return nil, err
+	}
+
+	if !row.ExpiresAt.After(notAfter) {
+		return nil, domain.ErrWebAuthnCeremonyNotFound
+	}
+
+	return &domain.WebAuthnSession{
+		ID:            row.ID,
+		ChallengeHash: challengeHash,
+		Ceremony:      ceremony,
+		UserID:        row.UserID.UUID,
+		ExpiresAt:     row.ExpiresAt,
+	}, nil
+}
+
+// NewPostgresqlWebAuthnSessionRepo - конструктор для создания нового экземпляра PostgresqlWebAuthnSessionRepo.
+func NewPostgresqlWebAuthnSessionRepo(db *sqlx.DB, logger *zap.Logger) repository.IWebAuthnSessionRepo {
+	return &PostgresqlWebAuthnSessionRepo{
+		db:     db,
+		logger: logger,
+	}
+}
@@ -0,0 +1,126 @@
+package postgresqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func getMockWebAuthnSessionRepo(t *testing.T) (repository.IWebAuthnSessionRepo, sqlmock.Sqlmock, func()) {
+	logger := zap.NewNop()
+
+	mockDB, mock, err := sqlmock.New()
+
+	require.NoError(t, err, "creating mock db")
+
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	repo := NewPostgresqlWebAuthnSessionRepo(sqlxDB, logger)
+
+	cleanup := func() {
+		mockDB.Close()
+	}
+
+	return repo, mock, cleanup
+}
+
+func TestPostgresqlWebAuthnSessionRepo_Create(t *testing.T) {
+	repo, mock, cleanup := getMockWebAuthnSessionRepo(t)
+	defer cleanup()
+
+	expiresAt := time.Now().Add(time.Minute)
+
+	t.Run("Registration", func(t *testing.T) {
+		session := &domain.WebAuthnSession{
+			ID:            uuid.New(),
+			ChallengeHash: "hash",
+			Ceremony:      domain.WebAuthnCeremonyRegistration,
+			UserID:        uuid.New(),
+			ExpiresAt:     expiresAt,
+		}
+
+		mock.ExpectExec("INSERT INTO webauthn_sessions").
+			WithArgs(session.ID, "hash", "registration", uuid.NullUUID{UUID: session.UserID, Valid: true}, expiresAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.Create(context.Background(), session))
+	})
+
+	t.Run("Login without user", func(t *testing.T) {
+		session := &domain.WebAuthnSession{
+			ID:            uuid.New(),
+			ChallengeHash: "hash",
+			Ceremony:      domain.WebAuthnCeremonyLogin,
+			ExpiresAt:     expiresAt,
+		}
+
+		mock.ExpectExec("INSERT INTO webauthn_sessions").
+			WithArgs(session.ID, "hash", "login", uuid.NullUUID{}, expiresAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.Create(context.Background(), session))
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlWebAuthnSessionRepo_Consume(t *testing.T) {
+	repo, mock, cleanup := getMockWebAuthnSessionRepo(t)
+	defer cleanup()
+
+	now := time.Now()
+
+	t.Run("Success", func(t *testing.T) {
+		id, guid := uuid.New(), uuid.New()
+		mock.ExpectQuery("DELETE FROM webauthn_sessions").
+			WithArgs("hash", "registration").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expires_at"}).
+				AddRow(id, guid, now.Add(time.Minute)))
+
+		session, err := repo.Consume(context.Background(), "hash", domain.WebAuthnCeremonyRegistration, now)
+		require.NoError(t, err)
+		assert.Equal(t, id, session.ID)
+		assert.Equal(t, guid, session.UserID)
+	})
+
+	t.Run("Login without user", func(t *testing.T) {
+		mock.ExpectQuery("DELETE FROM webauthn_sessions").
+			WithArgs("hash", "login").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expires_at"}).
+				AddRow(uuid.New(), nil, now.Add(time.Minute)))
+
+		session, err := repo.Consume(context.Background(), "hash", domain.WebAuthnCeremonyLogin, now)
+		require.NoError(t, err)
+		assert.Equal(t, uuid.Nil, session.UserID)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		mock.ExpectQuery("DELETE FROM webauthn_sessions").
+			WithArgs("hash", "login").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expires_at"}).
+				AddRow(uuid.New(), nil, now.Add(-time.Second)))
+
+		_, err := repo.Consume(context.Background(), "hash", domain.WebAuthnCeremonyLogin, now)
+		assert.ErrorIs(t, err, domain.ErrWebAuthnCeremonyNotFound)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mock.ExpectQuery("DELETE FROM webauthn_sessions").
+			WithArgs("hash", "login").
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.Consume(context.Background(), "hash", domain.WebAuthnCeremonyLogin, now)
+		assert.ErrorIs(t, err, domain.ErrWebAuthnCeremonyNotFound)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
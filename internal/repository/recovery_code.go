@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+)
+
+// IRecoveryCodeRepo - интерфейс для работы с кодами восстановления пользователей в базе данных
+type IRecoveryCodeRepo interface {
+	// Replace атомарно заменяет все коды восстановления пользователя новым набором хешей
+	Replace(ctx context.Context, userID uuid.UUID, codeHashes []string) error
+	// ListUnused возвращает неиспользованные коды восстановления пользователя
+	ListUnused(ctx context.Context, userID uuid.UUID) ([]domain.RecoveryCode, error)
+	// MarkUsed атомарно отмечает код как использованный.
+	// Возвращает false, если код уже был использован.
+	MarkUsed(ctx context.Context, id uuid.UUID) (bool, error)
+}
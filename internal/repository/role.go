@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+)
+
+// IRoleRepo - интерфейс для работы с ролями пользователей и их разрешениями в базе данных
+type IRoleRepo interface {
+	// Save создает роль или атомарно заменяет разрешения существующей роли
+	Save(ctx context.Context, role *domain.Role) error
+	// List возвращает все роли с их разрешениями в порядке названий
+	List(ctx context.Context) ([]domain.Role, error)
+	// Delete удаляет роль, снимая ее со всех пользователей.
+	// Возвращает domain.ErrRoleNotFound, если роль не найдена.
+	Delete(ctx context.Context, name string) error
+	// GetUserRoles возвращает роли пользователя с их разрешениями в порядке названий
+	GetUserRoles(ctx context.Context, userID uuid.UUID) ([]domain.Role, error)
+	// SetUserRoles атомарно заменяет роли пользователя.
+	// Возвращает domain.ErrRoleNotFound, если какая-либо из ролей не найдена, и domain.ErrUserNotFound, если не найден пользователь.
+	SetUserRoles(ctx context.Context, userID uuid.UUID, roles []string) error
+}
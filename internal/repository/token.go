@@ -8,7 +8,9 @@ import (
 
 // ITokenRepo - интерфейс для работы с сущностями Refresh токенов в базе данных
 type ITokenRepo interface {
-	Create(ctx context.Context, id, jti, userID uuid.UUID, token string, expiresAt time.Time) error             // Create создает новый Refresh - токен
-	GetToken(ctx context.Context, userID, jti uuid.UUID, notAfter time.Time) (uuid.UUID, string, error)         // GetToken получает Refresh - токен из базы данных. Возвращает айди токена и токен.
-	RotateToken(ctx context.Context, oldID, id, jti, userID uuid.UUID, token string, expiresAt time.Time) error // RotateToken производит ротацию токена, т.е. удаление старого и создание нового.
+	Create(ctx context.Context, id, jti, userID uuid.UUID, token string, expiresAt time.Time) error                // Create создает новый Refresh - токен
+	GetToken(ctx context.Context, userID, jti uuid.UUID, notAfter time.Time) (uuid.UUID, string, time.Time, error) // GetToken получает Refresh - токен из базы данных. Возвращает айди токена, токен и время последнего использования сессии.
+	RotateToken(ctx context.Context, oldID, id, jti, userID uuid.UUID, token string, expiresAt time.Time) error    // RotateToken производит ротацию токена, т.е. удаление старого и создание нового.
+	RevokeUserTokens(ctx context.Context, userID, exceptJTI uuid.UUID) (int, error)                                // RevokeUserTokens удаляет все Refresh - токены пользователя, кроме токена с exceptJTI. Возвращает количество удаленных токенов.
+	RevokeToken(ctx context.Context, userID, jti uuid.UUID) error                                                  // RevokeToken удаляет Refresh - токен пользователя с указанным jti.
 }
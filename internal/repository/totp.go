@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+)
+
+// ITOTPRepo - интерфейс для работы с подключениями TOTP пользователей в базе данных
+type ITOTPRepo interface {
+	// Save сохраняет зашифрованный секрет неподтвержденного подключения, заменяя предыдущий неподтвержденный.
+	Save(ctx context.Context, userID uuid.UUID, encryptedSecret string) error
+	// Get возвращает подключение TOTP пользователя
+	Get(ctx context.Context, userID uuid.UUID) (*domain.TOTPEnrollment, error)
+	// Confirm подтверждает подключение и запоминает использованный при подтверждении временной шаг
+	Confirm(ctx context.Context, userID uuid.UUID, step int64) error
+	// UseStep атомарно отмечает временной шаг как использованный.
+	// Возвращает false, если этот или более поздний шаг уже был использован.
+	UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+}
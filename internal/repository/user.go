@@ -3,10 +3,27 @@ package repository
 import (
 	"context"
 	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
 )
 
 // IUserRepo - интерфейс для работы с сущностями пользователей в базе данных
 type IUserRepo interface {
-	Create(ctx context.Context, guid uuid.UUID, email string) error // Create создает нового пользователя с указанными guid и email
-	GetEmail(ctx context.Context, guid uuid.UUID) (string, error)   // GetEmail возвращает email пользователя по его guid
+	// Create создает нового пользователя. Если passwordHash не пуст,
+	// в той же транзакции сохраняется хеш пароля пользователя.
+	Create(ctx context.Context, user *domain.User, passwordHash string) error
+	Exists(ctx context.Context, guid uuid.UUID) (bool, error)     // Exists проверяет, зарегистрирован ли пользователь с указанным guid
+	GetEmail(ctx context.Context, guid uuid.UUID) (string, error) // GetEmail возвращает email пользователя по его guid
+	// IsEmailVerified сообщает, подтвержден ли email пользователя.
+	// Если пользователь не найден, возвращает ошибку domain.ErrUserNotFound.
+	IsEmailVerified(ctx context.Context, guid uuid.UUID) (bool, error)
+	// GetGUIDByEmail возвращает guid пользователя по email.
+	// Если пользователь не найден, возвращает ошибку domain.ErrUserNotFound.
+	GetGUIDByEmail(ctx context.Context, email string) (uuid.UUID, error)
+	// GetIPChangePolicy возвращает персональную политику смены IP пользователя.
+	// Если политика для пользователя не переопределена, возвращает пустую строку.
+	GetIPChangePolicy(ctx context.Context, guid uuid.UUID) (domain.IPChangePolicy, error)
+	// SetIPChangePolicy переопределяет политику смены IP пользователя.
+	// Пустая политика сбрасывает переопределение к глобальной политике.
+	// Если пользователь не найден, возвращает ошибку domain.ErrUserNotFound.
+	SetIPChangePolicy(ctx context.Context, guid uuid.UUID, policy domain.IPChangePolicy) error
 }
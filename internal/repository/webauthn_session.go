@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"time"
+)
+
+// IWebAuthnSessionRepo - интерфейс для работы с церемониями WebAuthn в базе данных
+type IWebAuthnSessionRepo interface {
+	// Create сохраняет новую церемонию
+	Create(ctx context.Context, session *domain.WebAuthnSession) error
+	// Consume атомарно удаляет и возвращает не истекшую к моменту notAfter церемонию по хешу challenge.
+	// Каждая церемония может быть использована только один раз.
+	Consume(ctx context.Context, challengeHash string, ceremony domain.WebAuthnCeremony, notAfter time.Time) (*domain.WebAuthnSession, error)
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/geoip"
+	"github.com/maksemen2/medods-task/internal/pkg/requestid"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+// audit записывает событие в журнал аудита, если он настроен.
+// ID события, ID запроса и время заполняются автоматически.
+// Ошибка записи не прерывает операцию, но логируется.
+func (s *AuthServiceImpl) audit(ctx context.Context, event domain.AuthEvent) {
+	if s.auditRepo == nil {
+		return
+	}
+
+	event.ID = uuid.New()
+	event.RequestID = requestid.FromContext(ctx)
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+
+	// Событие должно попасть в журнал, даже если клиент уже отменил запрос
+	if err := s.auditRepo.Create(context.WithoutCancel(ctx), &event); err != nil {
+		s.logger.Error("Error writing audit event",
+			zap.String("type", string(event.Type)), zap.String("guid", event.GUID.String()), zap.Error(err))
+	}
+}
+
+// auditRefreshFailure записывает в журнал аудита неудачную попытку обновления токенов.
+func (s *AuthServiceImpl) auditRefreshFailure(ctx context.Context, guid, jti uuid.UUID, ip, userAgent, reason string) {
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventRefreshFailed,
+		GUID:      guid,
+		JTI:       jti,
+		IP:        ip,
+		UserAgent: userAgent,
+		Reason:    reason,
+	})
+}
+
+// locationDetails возвращает данные о положении IP-адреса для журнала аудита.
+func locationDetails(location *geoip.Location) map[string]any {
+	if location == nil {
+		return nil
+	}
+	return map[string]any{"country": location.Country, "city": location.City}
+}
+
+// riskEventDetails возвращает данные события риска для журнала аудита.
+func riskEventDetails(event domain.RiskEvent) map[string]any {
+	details := map[string]any{"risk_event": event.Type}
+	if event.PrevIP != "" {
+		details["prev_ip"] = event.PrevIP
+	}
+	if event.PrevLocation != nil {
+		details["prev_location"] = event.PrevLocation
+	}
+	if event.Location != nil {
+		details["location"] = event.Location
+	}
+	if event.DistanceKM > 0 {
+		details["distance_km"] = event.DistanceKM
+		details["elapsed_seconds"] = event.Elapsed.Seconds()
+	}
+	if len(event.Signals) > 0 {
+		details["score"] = event.Score
+		details["signals"] = event.Signals
+	}
+	return details
+}
+
+// withAuthMethods добавляет способы аутентификации в детали события аудита,
+// например "password" или "password+totp".
+func withAuthMethods(details map[string]any, methods []domain.AuthMethod) map[string]any {
+	if details == nil {
+		details = make(map[string]any, 1)
+	}
+	names := make([]string, len(methods))
+	for i, method := range methods {
+		names[i] = string(method)
+	}
+	details["method"] = strings.Join(names, "+")
+	return details
+}
+
+// withClientID добавляет в детали события идентификатор клиента OAuth, если он не пуст.
+func withClientID(details map[string]any, clientID string) map[string]any {
+	if clientID == "" {
+		return details
+	}
+	if details == nil {
+		details = make(map[string]any, 1)
+	}
+	details["client_id"] = clientID
+	return details
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultAuditPageSize = 100  // Размер страницы журнала аудита по умолчанию
+	MaxAuditPageSize     = 1000 // Максимальный размер страницы журнала аудита
+)
+
+// IAuditService - интерфейс для чтения журнала аудита событий аутентификации.
+type IAuditService interface {
+	// ListEvents возвращает страницу событий по фильтру, начиная с позиции cursor.
+	// Пустой cursor означает первую страницу.
+	ListEvents(ctx context.Context, filter domain.AuditFilter, cursor string) (*domain.AuditPage, error)
+	// ExportEvents последовательно передает в fn все события по фильтру, от новых к старым.
+	// Выгрузка прерывается при первой ошибке fn.
+	ExportEvents(ctx context.Context, filter domain.AuditFilter, fn func(event domain.AuthEvent) error) error
+}
+
+type AuditServiceImpl struct {
+	auditRepo repository.IAuditRepo
+	logger    *zap.Logger
+}
+
+func NewAuditServiceImpl(auditRepo repository.IAuditRepo, logger *zap.Logger) IAuditService {
+	return &AuditServiceImpl{
+		auditRepo: auditRepo,
+		logger:    logger,
+	}
+}
+
+// encodeAuditCursor кодирует позицию последнего события страницы в непрозрачную строку.
+func encodeAuditCursor(event domain.AuthEvent) string {
+	raw := event.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + event.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeAuditCursor декодирует курсор, полученный от encodeAuditCursor.
+// Возвращает domain.ErrInvalidCursor, если курсор поврежден.
+func decodeAuditCursor(cursor string) (*domain.AuditCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, domain.ErrInvalidCursor
+	}
+
+	createdAtRaw, idRaw, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, domain.ErrInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, createdAtRaw)
+	if err != nil {
+		return nil, domain.ErrInvalidCursor
+	}
+
+	id, err := uuid.Parse(idRaw)
+	if err != nil {
+		return nil, domain.ErrInvalidCursor
+	}
+
+	return &domain.AuditCursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+// validateAuditFilter проверяет корректность фильтра.
+func validateAuditFilter(filter domain.AuditFilter) error {
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return domain.ErrInvalidFilter
+	}
+	if filter.Limit < 0 {
+		return domain.ErrInvalidFilter
+	}
+	return nil
+}
+
+// ListEvents возвращает страницу событий журнала аудита.
+// Если лимит не задан, используется DefaultAuditPageSize, лимит больше MaxAuditPageSize уменьшается до него.
+// Возвращает domain.ErrInvalidCursor для поврежденного курсора и domain.ErrInvalidFilter для некорректного фильтра.
+func (s *AuditServiceImpl) ListEvents(ctx context.Context, filter domain.AuditFilter, cursor string) (*domain.AuditPage, error) {
+	if err := validateAuditFilter(filter); err != nil {
+		return nil, err
+	}
+
+	if cursor != "" {
+		after, err := decodeAuditCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = after
+	}
+
+	switch {
+	case filter.Limit == 0:
+		filter.Limit = DefaultAuditPageSize
+	case filter.Limit > MaxAuditPageSize:
+		filter.Limit = MaxAuditPageSize
+	}
+
+	events, err := s.auditRepo.List(ctx, filter)
+	if err != nil {
+		return nil, domain.ErrUnexpected
+	}
+
+	page := &domain.AuditPage{Events: events}
+	if len(events) == filter.Limit {
+		page.NextCursor = encodeAuditCursor(events[len(events)-1])
+	}
+
+	return page, nil
+}
+
+// ExportEvents выгружает все события по фильтру страницами размера MaxAuditPageSize.
+// Лимит и курсор фильтра игнорируются.
+func (s *AuditServiceImpl) ExportEvents(ctx context.Context, filter domain.AuditFilter, fn func(event domain.AuthEvent) error) error {
+	if err := validateAuditFilter(filter); err != nil {
+		return err
+	}
+
+	filter.Limit = MaxAuditPageSize
+	filter.After = nil
+
+	for {
+		events, err := s.auditRepo.List(ctx, filter)
+		if err != nil {
+			return domain.ErrUnexpected
+		}
+
+		for _, event := range events {
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+
+		if len(events) < filter.Limit {
+			return nil
+		}
+
+		last := events[len(events)-1]
+		filter.After = &domain.AuditCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+}
@@ -0,0 +1,126 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	mock_repository "github.com/maksemen2/medods-task/internal/repository/mocks"
+	"github.com/maksemen2/medods-task/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func makeAuditEvents(n int) []domain.AuthEvent {
+	events := make([]domain.AuthEvent, n)
+	now := time.Now()
+	for i := range events {
+		events[i] = domain.AuthEvent{ID: uuid.New(), Type: domain.AuthEventTokenIssued, CreatedAt: now.Add(-time.Duration(i) * time.Second)}
+	}
+	return events
+}
+
+func TestAuditService_ListEvents(t *testing.T) {
+	t.Run("pagination", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		auditRepo := mock_repository.NewMockIAuditRepo(ctrl)
+		svc := service.NewAuditServiceImpl(auditRepo, zap.NewNop())
+
+		firstPage := makeAuditEvents(2)
+		last := firstPage[1]
+
+		auditRepo.EXPECT().List(gomock.Any(), domain.AuditFilter{Limit: 2}).Return(firstPage, nil)
+		page, err := svc.ListEvents(context.Background(), domain.AuditFilter{Limit: 2}, "")
+		require.NoError(t, err)
+		assert.Len(t, page.Events, 2)
+		require.NotEmpty(t, page.NextCursor)
+
+		auditRepo.EXPECT().List(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, filter domain.AuditFilter) ([]domain.AuthEvent, error) {
+			require.NotNil(t, filter.After)
+			assert.Equal(t, last.ID, filter.After.ID)
+			assert.True(t, last.CreatedAt.Equal(filter.After.CreatedAt))
+			return makeAuditEvents(1), nil
+		})
+		page, err = svc.ListEvents(context.Background(), domain.AuditFilter{Limit: 2}, page.NextCursor)
+		require.NoError(t, err)
+		assert.Len(t, page.Events, 1)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("default and max limit", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		auditRepo := mock_repository.NewMockIAuditRepo(ctrl)
+		svc := service.NewAuditServiceImpl(auditRepo, zap.NewNop())
+
+		auditRepo.EXPECT().List(gomock.Any(), domain.AuditFilter{Limit: service.DefaultAuditPageSize}).Return(nil, nil)
+		auditRepo.EXPECT().List(gomock.Any(), domain.AuditFilter{Limit: service.MaxAuditPageSize}).Return(nil, nil)
+
+		_, err := svc.ListEvents(context.Background(), domain.AuditFilter{}, "")
+		assert.NoError(t, err)
+		_, err = svc.ListEvents(context.Background(), domain.AuditFilter{Limit: 100000}, "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		svc := service.NewAuditServiceImpl(nil, zap.NewNop())
+
+		_, err := svc.ListEvents(context.Background(), domain.AuditFilter{}, "not-a-cursor")
+		assert.ErrorIs(t, err, domain.ErrInvalidCursor)
+	})
+
+	t.Run("invalid time range", func(t *testing.T) {
+		svc := service.NewAuditServiceImpl(nil, zap.NewNop())
+
+		now := time.Now()
+		_, err := svc.ListEvents(context.Background(), domain.AuditFilter{From: now, To: now.Add(-time.Hour)}, "")
+		assert.ErrorIs(t, err, domain.ErrInvalidFilter)
+	})
+}
+
+func TestAuditService_ExportEvents(t *testing.T) {
+	t.Run("all pages", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		auditRepo := mock_repository.NewMockIAuditRepo(ctrl)
+		svc := service.NewAuditServiceImpl(auditRepo, zap.NewNop())
+
+		gomock.InOrder(
+			auditRepo.EXPECT().List(gomock.Any(), gomock.Any()).Return(makeAuditEvents(service.MaxAuditPageSize), nil),
+			auditRepo.EXPECT().List(gomock.Any(), gomock.Any()).Return(makeAuditEvents(3), nil),
+		)
+
+		count := 0
+		err := svc.ExportEvents(context.Background(), domain.AuditFilter{}, func(event domain.AuthEvent) error {
+			count++
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, service.MaxAuditPageSize+3, count)
+	})
+
+	t.Run("writer error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		auditRepo := mock_repository.NewMockIAuditRepo(ctrl)
+		svc := service.NewAuditServiceImpl(auditRepo, zap.NewNop())
+
+		auditRepo.EXPECT().List(gomock.Any(), gomock.Any()).Return(makeAuditEvents(3), nil)
+
+		writeErr := errors.New("broken pipe")
+		err := svc.ExportEvents(context.Background(), domain.AuditFilter{}, func(event domain.AuthEvent) error {
+			return writeErr
+		})
+		assert.ErrorIs(t, err, writeErr)
+	})
+}
@@ -4,61 +4,577 @@ import (
 	"context"
 	"encoding/base64"
 	"errors"
-	"github.com/brianvoe/gofakeit/v7"
 	"github.com/google/uuid"
 	"github.com/maksemen2/medods-task/internal/domain"
 	"github.com/maksemen2/medods-task/internal/pkg/auth"
 	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/magiclink"
 	"github.com/maksemen2/medods-task/internal/pkg/auth/refresh"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/webauthn"
+	"github.com/maksemen2/medods-task/internal/pkg/geoip"
+	"github.com/maksemen2/medods-task/internal/pkg/mail"
+	"github.com/maksemen2/medods-task/internal/pkg/risk"
 	"github.com/maksemen2/medods-task/internal/repository"
 	"go.uber.org/zap"
+	"slices"
 	"time"
 )
 
 // IAuthService - интерфейс для работы с аутентификацией пользователей.
 type IAuthService interface {
-	AuthenticateUser(ctx context.Context, guid uuid.UUID, ip string) (*domain.UserAuth, error)
-	RefreshToken(ctx context.Context, accessToken, refreshToken, ip string) (*domain.UserAuth, error)
+	AuthenticateUser(ctx context.Context, guid uuid.UUID, ip, userAgent string) (*domain.UserAuth, error)
+	RefreshToken(ctx context.Context, accessToken, refreshToken, ip, userAgent string) (*domain.UserAuth, error)
+	Login(ctx context.Context, email, password, ip, userAgent string) (*domain.UserAuth, error)
+	ChangePassword(ctx context.Context, accessToken, currentPassword, newPassword, ip, userAgent string) error
+	VerifyMFA(ctx context.Context, mfaToken, code, ip, userAgent string) (*domain.UserAuth, error)
+	EnrollTOTP(ctx context.Context, accessToken string) (*domain.TOTPSetup, error)
+	ConfirmTOTP(ctx context.Context, accessToken, code, ip, userAgent string) error
+	GenerateRecoveryCodes(ctx context.Context, accessToken, ip, userAgent string) ([]string, error)
+	VerifyMFARecovery(ctx context.Context, mfaToken, recoveryCode, ip, userAgent string) (*domain.UserAuth, error)
+	BeginPasskeyRegistration(ctx context.Context, accessToken string) (*webauthn.CreationOptions, error)
+	FinishPasskeyRegistration(ctx context.Context, accessToken string, response webauthn.RegistrationResponse, ip, userAgent string) (*domain.Passkey, error)
+	BeginPasskeyLogin(ctx context.Context) (*webauthn.RequestOptions, error)
+	FinishPasskeyLogin(ctx context.Context, response webauthn.AssertionResponse, ip, userAgent string) (*domain.UserAuth, error)
+	RequestMagicLink(ctx context.Context, email, ip, userAgent string) error
+	VerifyMagicLink(ctx context.Context, token, ip, userAgent string) (*domain.UserAuth, error)
+	RequestPasswordReset(ctx context.Context, email, ip, userAgent string) error
+	ResetPassword(ctx context.Context, token, newPassword, ip, userAgent string) error
+	ValidateAuthorizationRequest(ctx context.Context, req domain.AuthorizationRequest) (*domain.OAuthClient, error)
+	AuthorizeWithPassword(ctx context.Context, req domain.AuthorizationRequest, email, password, ip, userAgent string) (*domain.AuthorizationGrant, error)
+	AuthorizeWithMFA(ctx context.Context, req domain.AuthorizationRequest, mfaToken, code, ip, userAgent string) (*domain.AuthorizationGrant, error)
+	ExchangeAuthorizationCode(ctx context.Context, creds domain.ClientCredentials, code, redirectURI, codeVerifier, ip, userAgent string) (*domain.UserAuth, error)
+	ExchangeRefreshToken(ctx context.Context, creds domain.ClientCredentials, accessToken, refreshToken, ip, userAgent string) (*domain.UserAuth, error)
+	ExchangeClientCredentials(ctx context.Context, creds domain.ClientCredentials, scope, ip, userAgent string) (*domain.UserAuth, error)
+	StartDeviceAuthorization(ctx context.Context, creds domain.ClientCredentials, scope, ip, userAgent string) (*domain.DeviceCode, error)
+	LookupDeviceAuthorization(ctx context.Context, userCode, ip, userAgent string) (*domain.OAuthClient, error)
+	ApproveDeviceWithPassword(ctx context.Context, userCode, email, password, ip, userAgent string) (*domain.AuthorizationGrant, error)
+	ApproveDeviceWithMFA(ctx context.Context, userCode, mfaToken, code, ip, userAgent string) error
+	DenyDeviceAuthorization(ctx context.Context, userCode, ip, userAgent string) error
+	ExchangeDeviceCode(ctx context.Context, creds domain.ClientCredentials, deviceCode, ip, userAgent string) (*domain.UserAuth, error)
+	ExchangeToken(ctx context.Context, creds domain.ClientCredentials, req domain.TokenExchangeRequest, ip, userAgent string) (*domain.UserAuth, error)
+	VerifyToken(ctx context.Context, accessToken string) (*domain.VerifiedToken, error)
+	RevokeToken(ctx context.Context, accessToken string, allSessions bool, ip, userAgent string) (int, error)
 }
 
 type AuthServiceImpl struct {
-	userRepo     repository.IUserRepo
-	tokenRepo    repository.ITokenRepo
-	tokenManager auth.AccessTokenManager
-	logger       *zap.Logger
-	refreshTTL   time.Duration
+	userRepo       repository.IUserRepo
+	tokenRepo      repository.ITokenRepo
+	tokenManager   auth.AccessTokenManager
+	logger         *zap.Logger
+	refreshTTL     time.Duration
+	ipChangePolicy domain.IPChangePolicy
+	notifier       INotifier
+	riskHandlers   []RiskEventHandler
+	geoLocator     geoip.Locator
+	maxTravelSpeed float64 // Максимальная правдоподобная скорость перемещения между операциями в км/ч
+	riskEngine     risk.Engine
+	failures       risk.FailureTracker
+	auditRepo      repository.IAuditRepo
+	// Режим создания пользователей при аутентификации по незарегистрированному GUID
+	provisioningMode domain.ProvisioningMode
+	allowlistRepo    repository.IAllowlistRepo
+	credentialRepo   repository.ICredentialRepo
+	// Второй фактор аутентификации
+	totpRepo        repository.ITOTPRepo
+	challengeRepo   repository.IMFAChallengeRepo
+	secretCipher    *crypto.Cipher
+	mfaIssuer       string
+	mfaChallengeTTL time.Duration
+	recoveryRepo    repository.IRecoveryCodeRepo
+	// Вход по passkey (WebAuthn)
+	relyingParty        *webauthn.RelyingParty
+	passkeyRepo         repository.IPasskeyRepo
+	webAuthnSessionRepo repository.IWebAuthnSessionRepo
+	webAuthnCeremonyTTL time.Duration
+	// Вход по ссылке из письма
+	magicLinkSigner *magiclink.Signer
+	magicLinkRepo   repository.IMagicLinkRepo
+	mailSender      mail.Sender
+	magicLinkURL    string
+	magicLinkTTL    time.Duration
+	// Сброс пароля по ссылке из письма
+	passwordResetRepo repository.IPasswordResetRepo
+	passwordResetURL  string
+	passwordResetTTL  time.Duration
+	// Сервер авторизации OAuth 2.0
+	clientRepo   repository.IClientRepo
+	authCodeRepo repository.IAuthorizationCodeRepo
+	authCodeTTL  time.Duration
+	// Авторизация устройств без браузера (RFC 8628)
+	deviceRepo            repository.IDeviceAuthorizationRepo
+	deviceVerificationURL string
+	deviceCodeTTL         time.Duration
+	devicePollInterval    time.Duration
+	// Обмен токенов (RFC 8693)
+	tokenExchangeTTL time.Duration
+	// Роли пользователей и их разрешения
+	roleRepo repository.IRoleRepo
+	// Результаты проверки отзыва токенов для прокси (forward auth)
+	verifyCache *revocationCache
+	// Подтверждение email
+	emailVerifiedClaim   bool // Добавлять в Access токены claim email_verified
+	requireVerifiedEmail bool // Выдавать токены только пользователям с подтвержденным email
 }
 
-func NewAuthServiceImpl(userRepo repository.IUserRepo, tokenRepo repository.ITokenRepo, tokenManager auth.AccessTokenManager, logger *zap.Logger, refreshTTL time.Duration) IAuthService {
-	return &AuthServiceImpl{
-		userRepo:     userRepo,
-		tokenRepo:    tokenRepo,
-		tokenManager: tokenManager,
-		logger:       logger,
-		refreshTTL:   refreshTTL,
+const (
+	// defaultMaxTravelSpeed - скорость перемещения по умолчанию, выше которой перемещение считается невозможным.
+	// Примерно соответствует крейсерской скорости пассажирского самолета.
+	defaultMaxTravelSpeed = 900.0
+	// minTravelDistanceKM - минимальное расстояние между операциями, начиная с которого проверяется скорость.
+	// Защищает от ложных срабатываний из-за неточности GeoIP баз на коротких расстояниях.
+	minTravelDistanceKM = 100.0
+)
+
+// AuthServiceOption - функциональная опция для настройки AuthServiceImpl.
+type AuthServiceOption func(s *AuthServiceImpl)
+
+// WithIPChangePolicy задает политику по умолчанию при обновлении токенов с другого IP-адреса.
+// Если опция не передана, используется domain.IPChangePolicyNotify.
+func WithIPChangePolicy(policy domain.IPChangePolicy) AuthServiceOption {
+	return func(s *AuthServiceImpl) {
+		s.ipChangePolicy = policy
+	}
+}
+
+// WithNotifier задает сервис отправки уведомлений пользователям.
+// Если опция не передана, используется LogNotifier.
+func WithNotifier(notifier INotifier) AuthServiceOption {
+	return func(s *AuthServiceImpl) {
+		s.notifier = notifier
+	}
+}
+
+// WithRiskEventHandlers добавляет подписчиков на события риска.
+func WithRiskEventHandlers(handlers ...RiskEventHandler) AuthServiceOption {
+	return func(s *AuthServiceImpl) {
+		s.riskHandlers = append(s.riskHandlers, handlers...)
+	}
+}
+
+// WithGeoLocator включает GeoIP-обогащение операций и обнаружение невозможных перемещений.
+// maxTravelSpeed - скорость в км/ч, выше которой перемещение между двумя операциями сессии
+// считается невозможным. Если передано неположительное значение, используется defaultMaxTravelSpeed.
+func WithGeoLocator(locator geoip.Locator, maxTravelSpeed float64) AuthServiceOption {
+	return func(s *AuthServiceImpl) {
+		s.geoLocator = locator
+		if maxTravelSpeed > 0 {
+			s.maxTravelSpeed = maxTravelSpeed
+		}
+	}
+}
+
+// WithRiskEngine включает оценку риска операций выдачи и обновления токенов.
+// failures используется для учета неудачных попыток обновления токенов с IP-адреса.
+func WithRiskEngine(engine risk.Engine, failures risk.FailureTracker) AuthServiceOption {
+	return func(s *AuthServiceImpl) {
+		s.riskEngine = engine
+		s.failures = failures
+	}
+}
+
+// WithAuditRepo включает запись событий аутентификации в журнал аудита.
+func WithAuditRepo(auditRepo repository.IAuditRepo) AuthServiceOption {
+	return func(s *AuthServiceImpl) {
+		s.auditRepo = auditRepo
+	}
+}
+
+// WithProvisioning задает режим создания пользователей при аутентификации по незарегистрированному GUID.
+// allowlistRepo используется в режиме domain.ProvisioningModeAllowlist.
+// Если опция не передана, используется domain.ProvisioningModeAuto.
+func WithProvisioning(mode domain.ProvisioningMode, allowlistRepo repository.IAllowlistRepo) AuthServiceOption {
+	return func(s *AuthServiceImpl) {
+		s.provisioningMode = mode
+		s.allowlistRepo = allowlistRepo
+	}
+}
+
+// WithCredentialRepo задает хранилище учетных данных для входа по email и паролю.
+func WithCredentialRepo(credentialRepo repository.ICredentialRepo) AuthServiceOption {
+	return func(s *AuthServiceImpl) {
+		s.credentialRepo = credentialRepo
+	}
+}
+
+// WithTOTP включает второй фактор аутентификации TOTP.
+// cipher шифрует секреты TOTP в базе данных, issuer отображается в приложении-аутентификаторе.
+// Если challengeTTL неположителен, используется defaultMFAChallengeTTL.
+func WithTOTP(totpRepo repository.ITOTPRepo, challengeRepo repository.IMFAChallengeRepo, cipher *crypto.Cipher, issuer string, challengeTTL time.Duration) AuthServiceOption {
+	return func(s *AuthServiceImpl) {
+		s.totpRepo = totpRepo
+		s.challengeRepo = challengeRepo
+		s.secretCipher = cipher
+		s.mfaIssuer = issuer
+		if challengeTTL > 0 {
+			s.mfaChallengeTTL = challengeTTL
+		}
+	}
+}
+
+// WithRecoveryCodes включает коды восстановления, которые можно использовать вместо второго фактора.
+// Имеет смысл только вместе с WithTOTP.
+func WithRecoveryCodes(recoveryRepo repository.IRecoveryCodeRepo) AuthServiceOption {
+	return func(s *AuthServiceImpl) {
+		s.recoveryRepo = recoveryRepo
+	}
+}
+
+// WithWebAuthn включает регистрацию passkey и вход по ним.
+// Если ceremonyTTL неположителен, используется defaultWebAuthnCeremonyTTL.
+func WithWebAuthn(rp *webauthn.RelyingParty, passkeyRepo repository.IPasskeyRepo, sessionRepo repository.IWebAuthnSessionRepo, ceremonyTTL time.Duration) AuthServiceOption {
+	return func(s *AuthServiceImpl) {
+		s.relyingParty = rp
+		s.passkeyRepo = passkeyRepo
+		s.webAuthnSessionRepo = sessionRepo
+		if ceremonyTTL > 0 {
+			s.webAuthnCeremonyTTL = ceremonyTTL
+		}
 	}
 }
 
-// Функция - заглушка, которая отправляет уведомление пользователю об обновлении токена с другого айпи - адреса.
-// Вероятно, для этого лучше использовать отдельный сервис, но, так как это просто заглушка,
-// я сделал это в виде метода сервиса аутентификации.
-func (s *AuthServiceImpl) notifyUser(guid uuid.UUID, oldIP, newIP string) error {
-	// Логика получения почты пользователя и отправки уведомления
+// WithMagicLink включает вход по одноразовой ссылке из письма.
+// verifyURL - адрес страницы или эндпоинта подтверждения, к которому добавляется параметр token.
+// Если ttl неположителен, используется defaultMagicLinkTTL.
+func WithMagicLink(signer *magiclink.Signer, linkRepo repository.IMagicLinkRepo, sender mail.Sender, verifyURL string, ttl time.Duration) AuthServiceOption {
+	return func(s *AuthServiceImpl) {
+		s.magicLinkSigner = signer
+		s.magicLinkRepo = linkRepo
+		s.mailSender = sender
+		s.magicLinkURL = verifyURL
+		if ttl > 0 {
+			s.magicLinkTTL = ttl
+		}
+	}
+}
+
+// WithPasswordReset включает сброс пароля по одноразовой ссылке из письма.
+// resetURL - адрес страницы сброса пароля, к которому добавляется параметр token.
+// Если ttl неположителен, используется defaultPasswordResetTTL.
+func WithPasswordReset(resetRepo repository.IPasswordResetRepo, sender mail.Sender, resetURL string, ttl time.Duration) AuthServiceOption {
+	return func(s *AuthServiceImpl) {
+		s.passwordResetRepo = resetRepo
+		s.mailSender = sender
+		s.passwordResetURL = resetURL
+		if ttl > 0 {
+			s.passwordResetTTL = ttl
+		}
+	}
+}
+
+// WithEmailVerifiedClaim добавляет в Access токены claim email_verified.
+// Если required, токены при аутентификации по GUID и по ссылке из письма выдаются только пользователям
+// с подтвержденным email, остальным возвращается ошибка domain.ErrEmailNotVerified.
+func WithEmailVerifiedClaim(required bool) AuthServiceOption {
+	return func(s *AuthServiceImpl) {
+		s.emailVerifiedClaim = true
+		s.requireVerifiedEmail = required
+	}
+}
+
+func NewAuthServiceImpl(userRepo repository.IUserRepo, tokenRepo repository.ITokenRepo, tokenManager auth.AccessTokenManager, logger *zap.Logger, refreshTTL time.Duration, opts ...AuthServiceOption) IAuthService {
+	s := &AuthServiceImpl{
+		userRepo:            userRepo,
+		tokenRepo:           tokenRepo,
+		tokenManager:        tokenManager,
+		logger:              logger,
+		refreshTTL:          refreshTTL,
+		ipChangePolicy:      domain.IPChangePolicyNotify,
+		provisioningMode:    domain.ProvisioningModeAuto,
+		notifier:            NewLogNotifier(logger),
+		maxTravelSpeed:      defaultMaxTravelSpeed,
+		mfaChallengeTTL:     defaultMFAChallengeTTL,
+		webAuthnCeremonyTTL: defaultWebAuthnCeremonyTTL,
+		magicLinkTTL:        defaultMagicLinkTTL,
+		passwordResetTTL:    defaultPasswordResetTTL,
+		authCodeTTL:         defaultAuthorizationCodeTTL,
+		deviceCodeTTL:       defaultDeviceCodeTTL,
+		devicePollInterval:  defaultDevicePollInterval,
+		tokenExchangeTTL:    defaultTokenExchangeTTL,
+		verifyCache:         newRevocationCache(defaultVerifyCacheTTL),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// notifyIPChange асинхронно отправляет пользователю уведомление об обновлении токенов с другого IP-адреса.
+func (s *AuthServiceImpl) notifyIPChange(ctx context.Context, guid uuid.UUID, oldIP, newIP string) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := s.notifier.NotifyIPChange(ctx, guid, oldIP, newIP); err != nil {
+			s.logger.Error("Error notifying user about ip change", zap.String("guid", guid.String()), zap.Error(err))
+		}
+	}()
+}
+
+// raiseRiskEvent логирует событие риска, асинхронно уведомляет о нем пользователя
+// и передает его всем подписчикам.
+func (s *AuthServiceImpl) raiseRiskEvent(ctx context.Context, event domain.RiskEvent) {
+	s.logger.Warn("Risk event detected",
+		zap.String("type", string(event.Type)),
+		zap.String("guid", event.GUID.String()),
+		zap.String("jti", event.JTI.String()),
+		zap.String("prev_ip", event.PrevIP),
+		zap.String("ip", event.IP),
+		zap.Float64("distance_km", event.DistanceKM),
+		zap.Duration("elapsed", event.Elapsed),
+	)
+
+	notifyCtx := context.WithoutCancel(ctx)
+	go func() {
+		if err := s.notifier.NotifyRiskEvent(notifyCtx, event); err != nil {
+			s.logger.Error("Error notifying user about risk event", zap.String("guid", event.GUID.String()), zap.Error(err))
+		}
+	}()
+
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventRiskDetected,
+		GUID:      event.GUID,
+		JTI:       event.JTI,
+		IP:        event.IP,
+		UserAgent: event.UserAgent,
+		Details:   riskEventDetails(event),
+	})
+
+	for _, handler := range s.riskHandlers {
+		handler.HandleRiskEvent(ctx, event)
+	}
+}
+
+// locate возвращает географическое положение IP-адреса.
+// Если GeoIP не настроен или адрес не найден в базе, возвращает nil.
+func (s *AuthServiceImpl) locate(ip string) *geoip.Location {
+	if s.geoLocator == nil {
+		return nil
+	}
+
+	location, err := s.geoLocator.Lookup(ip)
+	if err != nil {
+		if !errors.Is(err, geoip.ErrLocationNotFound) && !errors.Is(err, geoip.ErrInvalidIP) {
+			s.logger.Error("Error looking up ip location", zap.String("ip", ip), zap.Error(err))
+		}
+		return nil
+	}
+
+	return location
+}
+
+// toGeoLocation преобразует положение GeoIP в доменную модель.
+func toGeoLocation(location *geoip.Location) *domain.GeoLocation {
+	if location == nil {
+		return nil
+	}
+	return &domain.GeoLocation{Country: location.Country, City: location.City}
+}
+
+// operationFields возвращает поля лога операции выдачи токенов, обогащенные положением IP-адреса.
+func operationFields(guid, jti uuid.UUID, ip string, location *geoip.Location) []zap.Field {
+	fields := []zap.Field{zap.String("guid", guid.String()), zap.String("jti", jti.String()), zap.String("ip", ip)}
+	if location != nil {
+		fields = append(fields, zap.String("country", location.Country), zap.String("city", location.City))
+	}
+	return fields
+}
+
+// detectImpossibleTravel проверяет, могла ли сессия переместиться из места выпуска Access токена
+// в место текущей операции за прошедшее время. Если нет - возвращает событие
+// domain.RiskEventImpossibleTravel без заполненных GUID и JTI, иначе nil.
+func (s *AuthServiceImpl) detectImpossibleTravel(prevIP string, issuedAt time.Time, ip string, location *geoip.Location, now time.Time) *domain.RiskEvent {
+	if issuedAt.IsZero() {
+		return nil
+	}
+
+	prevLocation := s.locate(prevIP)
+	if prevLocation == nil {
+		return nil
+	}
+
+	distance := geoip.Distance(prevLocation, location)
+	if distance < minTravelDistanceKM {
+		return nil
+	}
+
+	elapsed := now.Sub(issuedAt)
+	if elapsed > 0 && distance/elapsed.Hours() <= s.maxTravelSpeed {
+		return nil
+	}
+
+	return &domain.RiskEvent{
+		Type:         domain.RiskEventImpossibleTravel,
+		PrevIP:       prevIP,
+		IP:           ip,
+		PrevLocation: toGeoLocation(prevLocation),
+		Location:     toGeoLocation(location),
+		DistanceKM:   distance,
+		Elapsed:      elapsed,
+		OccurredAt:   now,
+	}
+}
+
+// recordFailure фиксирует неудачную попытку обновления токенов с IP-адреса.
+func (s *AuthServiceImpl) recordFailure(ip string) {
+	if s.failures != nil {
+		s.failures.RecordFailure(ip)
+	}
+}
+
+// recentFailures возвращает количество недавних неудачных попыток с IP-адреса.
+func (s *AuthServiceImpl) recentFailures(ip string) int {
+	if s.failures == nil {
+		return 0
+	}
+	return s.failures.Failures(ip)
+}
+
+// assessRisk оценивает риск операции и применяет принятое решение.
+// Решение и сработавшие сигналы логируются и записываются в журнал аудита. При решении notify или deny
+// возбуждается событие риска. Если операция запрещена, возвращает ошибку domain.ErrRiskDenied.
+func (s *AuthServiceImpl) assessRisk(ctx context.Context, guid, jti uuid.UUID, ip, userAgent string, input risk.Input) error {
+	assessment := s.riskEngine.Evaluate(ctx, input)
+
+	signals := make(map[string]float64, len(assessment.Signals))
+	for signal, contribution := range assessment.Signals {
+		signals[string(signal)] = contribution
+	}
+
+	s.logger.Info("Risk decision",
+		zap.String("operation", string(input.Operation)),
+		zap.String("guid", guid.String()),
+		zap.String("jti", jti.String()),
+		zap.String("ip", ip),
+		zap.Float64("score", assessment.Score),
+		zap.String("action", string(assessment.Action)),
+		zap.Any("signals", signals),
+	)
+
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventRiskDecision,
+		GUID:      guid,
+		JTI:       jti,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details: map[string]any{
+			"operation": input.Operation,
+			"score":     assessment.Score,
+			"action":    assessment.Action,
+			"signals":   signals,
+		},
+	})
+
+	event := domain.RiskEvent{
+		GUID:       guid,
+		JTI:        jti,
+		IP:         ip,
+		UserAgent:  userAgent,
+		Score:      assessment.Score,
+		Signals:    signals,
+		OccurredAt: time.Now(),
+	}
+
+	switch assessment.Action {
+	case risk.ActionNotify:
+		event.Type = domain.RiskEventElevatedRisk
+		s.raiseRiskEvent(ctx, event)
+	case risk.ActionDeny:
+		event.Type = domain.RiskEventRiskDenied
+		s.raiseRiskEvent(ctx, event)
+		return domain.ErrRiskDenied
+	}
+
 	return nil
 }
 
-// AuthenticateUser - аутентификация пользователя по guid.
-// Возвращает доменную модель domain.UserAuth.
-func (s *AuthServiceImpl) AuthenticateUser(ctx context.Context, guid uuid.UUID, ip string) (*domain.UserAuth, error) {
-	err := s.userRepo.Create(ctx, guid, gofakeit.Email()) // Используется моковая почта
-	// Если пользователь уже существует - для нас это не проблема, просто идем дальше
-	if err != nil && !errors.Is(err, domain.ErrUserExists) {
+// resolveIPChangePolicy возвращает политику смены IP для пользователя.
+// Персональная политика пользователя имеет приоритет над политикой по умолчанию.
+func (s *AuthServiceImpl) resolveIPChangePolicy(ctx context.Context, guid uuid.UUID) (domain.IPChangePolicy, error) {
+	policy, err := s.userRepo.GetIPChangePolicy(ctx, guid)
+	if err != nil {
+		return "", err
+	}
+
+	if policy == "" {
+		return s.ipChangePolicy, nil
+	}
+
+	return policy, nil
+}
+
+// authMethodsAMR возвращает значение claim amr для пройденных пользователем способов аутентификации.
+// Если пройдено несколько факторов или способ сам по себе многофакторный, добавляется значение domain.AMRMFA.
+func authMethodsAMR(methods []domain.AuthMethod) []string {
+	var amr []string
+	multiFactor := len(methods) > 1
+	for _, method := range methods {
+		if value := method.AMR(); value != "" {
+			amr = append(amr, value)
+		}
+		multiFactor = multiFactor || method.MultiFactor()
+	}
+	if multiFactor {
+		amr = append(amr, domain.AMRMFA)
+	}
+	return amr
+}
+
+// emailVerified возвращает значение claim email_verified для пользователя guid
+// или nil, если claim не включен.
+func (s *AuthServiceImpl) emailVerified(ctx context.Context, guid uuid.UUID) (*bool, error) {
+	if !s.emailVerifiedClaim {
+		return nil, nil
+	}
+
+	verified, err := s.userRepo.IsEmailVerified(ctx, guid)
+	if err != nil {
+		s.logger.Error("Error checking email verification", zap.String("guid", guid.String()), zap.Error(err))
 		return nil, domain.ErrUnexpected
 	}
 
-	jti := uuid.New()
+	return &verified, nil
+}
 
-	accessToken, err := s.tokenManager.Generate(guid, jti, ip)
+// issueTokens выдает новую пару токенов пользователю и сохраняет хеш Refresh токена.
+// methods - пройденные пользователем способы аутентификации, они записываются в claim amr и журнал аудита.
+func (s *AuthServiceImpl) issueTokens(ctx context.Context, guid, jti uuid.UUID, ip, userAgent string, methods ...domain.AuthMethod) (*domain.UserAuth, error) {
+	return s.issueClientTokens(ctx, nil, nil, guid, jti, ip, userAgent, methods)
+}
+
+// issueClientTokens выдает новую пару токенов, как issueTokens, для клиента OAuth client с областями доступа scopes.
+// Идентификатор клиента записывается в claim client_id и журнал аудита, а время жизни токенов берется из настроек клиента.
+// Если client равен nil, токены выдаются без клиента.
+func (s *AuthServiceImpl) issueClientTokens(ctx context.Context, client *domain.OAuthClient, scopes []string, guid, jti uuid.UUID, ip, userAgent string, methods []domain.AuthMethod) (*domain.UserAuth, error) {
+	emailVerified, err := s.emailVerified(ctx, guid)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenOpts := auth.TokenOptions{
+		UserAgentHash: risk.UserAgentFingerprint(userAgent),
+		AMR:           authMethodsAMR(methods),
+		EmailVerified: emailVerified,
+	}
+
+	var clientID string
+	refreshTTL := s.refreshTTL
+	if client != nil {
+		clientID = client.ID
+		tokenOpts.ClientID = client.ID
+		tokenOpts.Scopes = scopes
+		tokenOpts.TTL = client.AccessTTL
+		if client.RefreshTTL > 0 {
+			refreshTTL = client.RefreshTTL
+		}
+	} else {
+		// Токены, выданные пользователю напрямую, несут его роли и их разрешения
+		tokenOpts.Roles, tokenOpts.Permissions, err = s.userAccess(ctx, guid)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	accessToken, err := s.tokenManager.Generate(guid, jti, ip, tokenOpts)
 
 	if err != nil {
 		s.logger.Error("Error generating token", zap.Error(err))
@@ -77,7 +593,7 @@ func (s *AuthServiceImpl) AuthenticateUser(ctx context.Context, guid uuid.UUID,
 		return nil, domain.ErrUnexpected
 	}
 
-	err = s.tokenRepo.Create(ctx, uuid.New(), jti, guid, refreshTokenHash, time.Now().Add(s.refreshTTL)) // Записываем обязательно хешированный токен
+	err = s.tokenRepo.Create(ctx, uuid.New(), jti, guid, refreshTokenHash, time.Now().Add(refreshTTL)) // Записываем обязательно хешированный токен
 
 	if err != nil {
 		if errors.Is(err, domain.ErrTokenExists) {
@@ -88,35 +604,106 @@ func (s *AuthServiceImpl) AuthenticateUser(ctx context.Context, guid uuid.UUID,
 		return nil, domain.ErrUnexpected
 	}
 
+	location := s.locate(ip)
+	s.logger.Info("User authenticated", operationFields(guid, jti, ip, location)...)
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventTokenIssued,
+		GUID:      guid,
+		JTI:       jti,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details:   withClientID(withAuthMethods(locationDetails(location), methods), clientID),
+	})
+
 	return &domain.UserAuth{
 		AccessToken:  accessToken,
 		RefreshToken: base64.URLEncoding.EncodeToString(refreshToken),
+		Scopes:       tokenOpts.Scopes,
 	}, nil
 }
 
+// AuthenticateUser - аутентификация пользователя по guid.
+// Незарегистрированный пользователь создается в соответствии с режимом создания пользователей,
+// если режим этого не позволяет, возвращается ошибка domain.ErrUserNotFound.
+// Если требуется подтвержденный email, а email пользователя не подтвержден, возвращается ошибка domain.ErrEmailNotVerified.
+// Если у пользователя подключен второй фактор, вместо пары токенов возвращается MFA-челлендж.
+// Возвращает доменную модель domain.UserAuth.
+func (s *AuthServiceImpl) AuthenticateUser(ctx context.Context, guid uuid.UUID, ip, userAgent string) (*domain.UserAuth, error) {
+	return s.authenticate(ctx, guid, ip, userAgent, domain.AuthMethodGUID)
+}
+
+// authenticate выдает пару токенов или MFA-челлендж пользователю guid, прошедшему первый фактор method.
+// Общий путь для аутентификации по GUID и способов входа, которые устанавливают GUID пользователя сами.
+func (s *AuthServiceImpl) authenticate(ctx context.Context, guid uuid.UUID, ip, userAgent string, method domain.AuthMethod) (*domain.UserAuth, error) {
+	if err := s.provisionUser(ctx, guid, ip, userAgent); err != nil {
+		return nil, err
+	}
+
+	if s.requireVerifiedEmail {
+		verified, err := s.emailVerified(ctx, guid)
+		if err != nil {
+			return nil, err
+		}
+		if !*verified {
+			s.logger.Debug("Authentication with unverified email", zap.String("guid", guid.String()))
+			s.audit(ctx, domain.AuthEvent{
+				Type:      domain.AuthEventLoginFailed,
+				GUID:      guid,
+				IP:        ip,
+				UserAgent: userAgent,
+				Reason:    domain.FailureReasonEmailUnverified,
+			})
+			return nil, domain.ErrEmailNotVerified
+		}
+	}
+
+	jti := uuid.New()
+
+	if s.riskEngine != nil {
+		input := risk.Input{Operation: risk.OperationLogin, RecentFailures: s.recentFailures(ip)}
+		if err := s.assessRisk(ctx, guid, jti, ip, userAgent, input); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.completeFirstFactor(ctx, guid, jti, ip, userAgent, method)
+}
+
 // RefreshToken обновляет токены пользователя.
-// Проверяет валидность Access токена и Refresh токена.
+// Проверяет валидность Access токена и Refresh токена. Access токен может быть просрочен.
 // Если токены валидны, генерирует новые токены и обновляет Refresh - токен в базе данных.
-func (s *AuthServiceImpl) RefreshToken(ctx context.Context, accessToken, refreshToken, ip string) (*domain.UserAuth, error) {
-	claims, err := s.tokenManager.Parse(accessToken)
+// Если IP-адрес отличается от указанного в Access токене, применяется политика смены IP:
+// при domain.IPChangePolicyDeny возвращается ошибка domain.ErrIPChangeDenied,
+// при domain.IPChangePolicyStepUp новый Access токен помечается как требующий повторной аутентификации.
+// Если настроен движок оценки риска, операция может быть запрещена с ошибкой domain.ErrRiskDenied.
+func (s *AuthServiceImpl) RefreshToken(ctx context.Context, accessToken, refreshToken, ip, userAgent string) (*domain.UserAuth, error) {
+	// Срок действия сессии определяет Refresh токен: просроченный Access токен лишь указывает на нее
+	claims, err := s.tokenManager.ParseExpired(accessToken)
 	if err != nil {
 		s.logger.Debug("Bad token provided", zap.Error(err))
+		s.recordFailure(ip)
+		s.auditRefreshFailure(ctx, uuid.Nil, uuid.Nil, ip, userAgent, domain.FailureReasonBadAccessToken)
 		return nil, domain.ErrInvalidAccessToken
 	}
 
+	guid := claims.GetGUID()
+	jti := claims.GetJTI()
+
 	refreshTokenBytes, err := base64.URLEncoding.DecodeString(refreshToken)
 	if err != nil {
 		s.logger.Debug("Bad refresh token provided", zap.Error(err))
+		s.recordFailure(ip)
+		s.auditRefreshFailure(ctx, guid, jti, ip, userAgent, domain.FailureReasonBadRefreshToken)
 		return nil, domain.ErrInvalidRefreshToken
 	}
 
-	guid := claims.GetGUID()
-	jti := claims.GetJTI()
 	currentTime := time.Now()
-	storedTokenID, storedTokenHash, err := s.tokenRepo.GetToken(ctx, guid, jti, currentTime)
+	storedTokenID, storedTokenHash, lastUsedAt, err := s.tokenRepo.GetToken(ctx, guid, jti, currentTime)
 	if err != nil {
 		if errors.Is(err, domain.ErrTokenNotFound) {
 			s.logger.Debug("Token not found", zap.String("guid", guid.String()), zap.String("jti", jti.String()))
+			s.recordFailure(ip)
+			s.auditRefreshFailure(ctx, guid, jti, ip, userAgent, domain.FailureReasonTokenNotFound)
 			return nil, err
 		}
 		return nil, domain.ErrUnexpected
@@ -124,11 +711,119 @@ func (s *AuthServiceImpl) RefreshToken(ctx context.Context, accessToken, refresh
 
 	if !crypto.CompareHashAndBytes(refreshTokenBytes, storedTokenHash) {
 		s.logger.Debug("Invalid refresh token provided", zap.String("guid", guid.String()), zap.String("jti", jti.String()))
+		s.recordFailure(ip)
+		s.auditRefreshFailure(ctx, guid, jti, ip, userAgent, domain.FailureReasonBadRefreshToken)
 		return nil, domain.ErrInvalidRefreshToken
 	}
 
+	// Признак повторной аутентификации сохраняется при ротации, иначе его можно было бы
+	// снять, повторно обновив токены с нового IP-адреса. Методы аутентификации сессии также не меняются.
+	// Признак подтверждения email, напротив, перечитывается, чтобы подтверждение отражалось в токенах без повторного входа
+	emailVerified, err := s.emailVerified(ctx, guid)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenOpts := auth.TokenOptions{
+		RequireReauth: claims.RequiresReauth(),
+		UserAgentHash: risk.UserAgentFingerprint(userAgent),
+		AMR:           claims.GetAMR(),
+		EmailVerified: emailVerified,
+		ClientID:      claims.GetClientID(),
+	}
+
+	// Токены клиента OAuth обновляются с текущими настройками клиента: временем жизни
+	// и областями доступа, которые по-прежнему ему разрешены
+	refreshTTL := s.refreshTTL
+	if tokenOpts.ClientID != "" {
+		// Без хранилища клиентов OAuth отключен, и токены клиентов, как и в VerifyToken, считаются отозванными
+		if s.clientRepo == nil {
+			s.logger.Debug("Token of oauth client while oauth is disabled", zap.String("client_id", tokenOpts.ClientID))
+			s.auditRefreshFailure(ctx, guid, jti, ip, userAgent, domain.FailureReasonUnknownClient)
+			return nil, domain.ErrInvalidRefreshToken
+		}
+
+		client, err := s.clientRepo.Get(ctx, tokenOpts.ClientID)
+		if err != nil {
+			if errors.Is(err, domain.ErrClientNotFound) {
+				s.logger.Debug("Token of deleted oauth client", zap.String("client_id", tokenOpts.ClientID))
+				s.auditRefreshFailure(ctx, guid, jti, ip, userAgent, domain.FailureReasonUnknownClient)
+				return nil, domain.ErrInvalidRefreshToken
+			}
+			return nil, domain.ErrUnexpected
+		}
+
+		tokenOpts.Scopes = slices.DeleteFunc(claims.GetScopes(), func(scope string) bool {
+			return !slices.Contains(client.Scopes, scope)
+		})
+		tokenOpts.TTL = client.AccessTTL
+		if client.RefreshTTL > 0 {
+			refreshTTL = client.RefreshTTL
+		}
+	} else {
+		// Роли перечитываются, чтобы их изменение отражалось в токенах без повторного входа
+		tokenOpts.Roles, tokenOpts.Permissions, err = s.userAccess(ctx, guid)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	oldIP := claims.GetIP()
+	issuedAt := claims.GetIssueTime()
+	location := s.locate(ip)
+	impossibleTravel := false
+	if location != nil && oldIP != ip {
+		if event := s.detectImpossibleTravel(oldIP, issuedAt, ip, location, currentTime); event != nil {
+			event.GUID, event.JTI, event.UserAgent = guid, jti, userAgent
+			s.raiseRiskEvent(ctx, *event)
+			impossibleTravel = true
+		}
+	}
+
+	if s.riskEngine != nil {
+		input := risk.Input{
+			Operation:        risk.OperationRefresh,
+			IPChanged:        oldIP != ip,
+			UserAgentChanged: claims.GetUserAgentHash() != tokenOpts.UserAgentHash,
+			ImpossibleTravel: impossibleTravel,
+			RecentFailures:   s.recentFailures(ip),
+		}
+		if !lastUsedAt.IsZero() {
+			input.SinceLastUse = currentTime.Sub(lastUsedAt)
+		}
+		if err := s.assessRisk(ctx, guid, jti, ip, userAgent, input); err != nil {
+			return nil, err
+		}
+	}
+
+	if oldIP != ip {
+		policy, err := s.resolveIPChangePolicy(ctx, guid)
+		if err != nil {
+			s.logger.Error("Error resolving ip change policy", zap.String("guid", guid.String()), zap.Error(err))
+			return nil, domain.ErrUnexpected
+		}
+
+		s.notifyIPChange(ctx, guid, oldIP, ip)
+		s.audit(ctx, domain.AuthEvent{
+			Type:      domain.AuthEventIPChanged,
+			GUID:      guid,
+			JTI:       jti,
+			IP:        ip,
+			UserAgent: userAgent,
+			Details:   map[string]any{"old_ip": oldIP, "policy": policy},
+		})
+
+		switch policy {
+		case domain.IPChangePolicyDeny:
+			s.logger.Debug("Token refresh from another ip denied", zap.String("guid", guid.String()), zap.String("jti", jti.String()))
+			return nil, domain.ErrIPChangeDenied
+		case domain.IPChangePolicyStepUp:
+			tokenOpts.RequireReauth = true
+		}
+	}
+
 	newJTI := uuid.New()
-	newAccessToken, err := s.tokenManager.Generate(guid, newJTI, ip)
+	newAccessToken, err := s.tokenManager.Generate(guid, newJTI, ip, tokenOpts)
 	if err != nil {
 		s.logger.Error("Error generating new token", zap.Error(err))
 		return nil, domain.ErrUnexpected
@@ -146,18 +841,40 @@ func (s *AuthServiceImpl) RefreshToken(ctx context.Context, accessToken, refresh
 		return nil, domain.ErrUnexpected
 	}
 
-	expirationTime := currentTime.Add(s.refreshTTL)
+	expirationTime := currentTime.Add(refreshTTL)
 	err = s.tokenRepo.RotateToken(ctx, storedTokenID, uuid.New(), newJTI, guid, hashedRefreshToken, expirationTime)
 	if err != nil {
 		return nil, domain.ErrUnexpected
 	}
 
-	if oldIP := claims.GetIP(); oldIP != ip {
-		go s.notifyUser(guid, oldIP, ip)
+	// Ротация отзывает предыдущий Refresh токен сессии
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventTokenRevoked,
+		GUID:      guid,
+		JTI:       jti,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details:   map[string]any{"reason": "rotated", "new_jti": newJTI},
+	})
+
+	s.logger.Info("Tokens refreshed", operationFields(guid, newJTI, ip, location)...)
+	refreshDetails := locationDetails(location)
+	if refreshDetails == nil {
+		refreshDetails = map[string]any{}
 	}
+	refreshDetails["prev_jti"] = jti
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventTokenRefreshed,
+		GUID:      guid,
+		JTI:       newJTI,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details:   refreshDetails,
+	})
 
 	return &domain.UserAuth{
 		AccessToken:  newAccessToken,
 		RefreshToken: base64.URLEncoding.EncodeToString(newRefreshToken),
+		Scopes:       tokenOpts.Scopes,
 	}, nil
 }
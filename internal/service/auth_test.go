@@ -9,11 +9,17 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth"
 	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
 	mock_auth "github.com/maksemen2/medods-task/internal/pkg/auth/mocks"
 	"github.com/maksemen2/medods-task/internal/pkg/auth/refresh"
+	"github.com/maksemen2/medods-task/internal/pkg/geoip"
+	mock_geoip "github.com/maksemen2/medods-task/internal/pkg/geoip/mocks"
+	"github.com/maksemen2/medods-task/internal/pkg/requestid"
+	"github.com/maksemen2/medods-task/internal/pkg/risk"
 	mock_repository "github.com/maksemen2/medods-task/internal/repository/mocks"
 	"github.com/maksemen2/medods-task/internal/service"
+	mock_service "github.com/maksemen2/medods-task/internal/service/mocks"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 	"go.uber.org/zap"
@@ -33,8 +39,8 @@ func TestAuthService_AuthenticateUser(t *testing.T) {
 		guid := uuid.New()
 		expectedAccessToken := "test_access"
 
-		userRepo.EXPECT().Create(gomock.Any(), guid, gomock.Any()).Return(nil)
-		tokenManager.EXPECT().Generate(guid, gomock.Any(), gomock.Any()).Return(expectedAccessToken, nil)
+		userRepo.EXPECT().Exists(gomock.Any(), guid).Return(true, nil)
+		tokenManager.EXPECT().Generate(guid, gomock.Any(), gomock.Any(), auth.TokenOptions{}).Return(expectedAccessToken, nil)
 		tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), guid, gomock.Any(), gomock.Any()).DoAndReturn(
 			func(ctx context.Context, tokenID, tokenJTI uuid.UUID, userID uuid.UUID, refreshTokenHash string, expiresAt time.Time) error {
 				assert.NotEmpty(t, refreshTokenHash)
@@ -42,7 +48,7 @@ func TestAuthService_AuthenticateUser(t *testing.T) {
 			},
 		)
 
-		result, err := svc.AuthenticateUser(context.Background(), guid, "127.0.0.1")
+		result, err := svc.AuthenticateUser(context.Background(), guid, "127.0.0.1", "")
 		assert.NoError(t, err)
 		assert.Equal(t, expectedAccessToken, result.AccessToken)
 
@@ -50,6 +56,83 @@ func TestAuthService_AuthenticateUser(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Len(t, decoded, refresh.TokenLength)
 	})
+
+	t.Run("risk denied", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		tokenRepo := mock_repository.NewMockITokenRepo(ctrl)
+		tokenManager := mock_auth.NewMockAccessTokenManager(ctrl)
+		logger := zap.NewNop()
+
+		rules := risk.DefaultRules()
+		rules.FailureThreshold = 2
+		rules.DenyThreshold = rules.Weights[risk.SignalFailureRate]
+		failures := risk.NewMemoryFailureTracker(time.Minute)
+		failures.RecordFailure("127.0.0.1")
+		failures.RecordFailure("127.0.0.1")
+
+		svc := service.NewAuthServiceImpl(userRepo, tokenRepo, tokenManager, logger, time.Hour,
+			service.WithRiskEngine(risk.NewRuleEngine(rules), failures))
+
+		userRepo.EXPECT().Exists(gomock.Any(), gomock.Any()).Return(true, nil)
+
+		_, err := svc.AuthenticateUser(context.Background(), uuid.New(), "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrRiskDenied)
+	})
+
+	t.Run("unknown user is provisioned by default", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		tokenRepo := mock_repository.NewMockITokenRepo(ctrl)
+		tokenManager := mock_auth.NewMockAccessTokenManager(ctrl)
+		svc := service.NewAuthServiceImpl(userRepo, tokenRepo, tokenManager, zap.NewNop(), time.Hour)
+
+		guid := uuid.New()
+		userRepo.EXPECT().Exists(gomock.Any(), guid).Return(false, nil)
+		userRepo.EXPECT().Create(gomock.Any(), gomock.Any(), "").Return(nil)
+		tokenManager.EXPECT().Generate(guid, gomock.Any(), gomock.Any(), gomock.Any()).Return("access", nil)
+		tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(nil)
+
+		result, err := svc.AuthenticateUser(context.Background(), guid, "127.0.0.1", "")
+		assert.NoError(t, err)
+		assert.Equal(t, "access", result.AccessToken)
+	})
+
+	t.Run("audited", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		tokenRepo := mock_repository.NewMockITokenRepo(ctrl)
+		auditRepo := mock_repository.NewMockIAuditRepo(ctrl)
+		tokenManager := mock_auth.NewMockAccessTokenManager(ctrl)
+		logger := zap.NewNop()
+		svc := service.NewAuthServiceImpl(userRepo, tokenRepo, tokenManager, logger, time.Hour,
+			service.WithAuditRepo(auditRepo))
+
+		guid := uuid.New()
+		ctx := requestid.WithRequestID(context.Background(), "request-id")
+
+		userRepo.EXPECT().Exists(gomock.Any(), guid).Return(true, nil)
+		tokenManager.EXPECT().Generate(guid, gomock.Any(), "127.0.0.1", gomock.Any()).Return("access", nil)
+		tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(nil)
+		auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.AuthEvent) error {
+			assert.Equal(t, domain.AuthEventTokenIssued, event.Type)
+			assert.Equal(t, guid, event.GUID)
+			assert.NotEqual(t, uuid.Nil, event.JTI)
+			assert.Equal(t, "127.0.0.1", event.IP)
+			assert.Equal(t, "curl/8.0", event.UserAgent)
+			assert.Equal(t, "request-id", event.RequestID)
+			return nil
+		})
+
+		_, err := svc.AuthenticateUser(ctx, guid, "127.0.0.1", "curl/8.0")
+		assert.NoError(t, err)
+	})
 }
 
 func TestAuthService_RefreshToken(t *testing.T) {
@@ -57,11 +140,12 @@ func TestAuthService_RefreshToken(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
 		tokenRepo := mock_repository.NewMockITokenRepo(ctrl)
 		tokenManager := mock_auth.NewMockAccessTokenManager(ctrl)
 		claims := mock_auth.NewMockClaims(ctrl)
 		logger := zap.NewNop()
-		svc := service.NewAuthServiceImpl(nil, tokenRepo, tokenManager, logger, time.Hour)
+		svc := service.NewAuthServiceImpl(userRepo, tokenRepo, tokenManager, logger, time.Hour)
 
 		oldJTI := uuid.New()
 		guid := uuid.New()
@@ -71,14 +155,19 @@ func TestAuthService_RefreshToken(t *testing.T) {
 		assert.NoError(t, err)
 		storedTokenID := uuid.New()
 
-		tokenManager.EXPECT().Parse("valid_access").Return(claims, nil)
+		tokenManager.EXPECT().ParseExpired("valid_access").Return(claims, nil)
 		claims.EXPECT().GetGUID().Return(guid)
 		claims.EXPECT().GetJTI().Return(oldJTI)
 		claims.EXPECT().GetIP().Return("old_ip")
-		tokenRepo.EXPECT().GetToken(gomock.Any(), guid, oldJTI, gomock.Any()).Return(storedTokenID, hashedOldRefresh, nil)
+		claims.EXPECT().RequiresReauth().Return(false)
+		claims.EXPECT().GetAMR().Return(nil)
+		claims.EXPECT().GetClientID().Return("")
+		claims.EXPECT().GetIssueTime().Return(time.Now())
+		tokenRepo.EXPECT().GetToken(gomock.Any(), guid, oldJTI, gomock.Any()).Return(storedTokenID, hashedOldRefresh, time.Time{}, nil)
+		userRepo.EXPECT().GetIPChangePolicy(gomock.Any(), guid).Return(domain.IPChangePolicy(""), nil)
 
 		newAccessToken := "new_access"
-		tokenManager.EXPECT().Generate(guid, gomock.Any(), "new_ip").Return(newAccessToken, nil)
+		tokenManager.EXPECT().Generate(guid, gomock.Any(), "new_ip", auth.TokenOptions{}).Return(newAccessToken, nil)
 		tokenRepo.EXPECT().RotateToken(
 			gomock.Any(), storedTokenID, gomock.Any(), gomock.Any(), guid, gomock.Any(), gomock.Any(),
 		).DoAndReturn(func(ctx context.Context, tokenID, newTokenID, newJTI uuid.UUID, userID uuid.UUID, hashedRefreshToken string, expiresAt time.Time) error {
@@ -87,7 +176,7 @@ func TestAuthService_RefreshToken(t *testing.T) {
 			return nil
 		})
 
-		result, err := svc.RefreshToken(context.Background(), "valid_access", oldRefreshB64, "new_ip")
+		result, err := svc.RefreshToken(context.Background(), "valid_access", oldRefreshB64, "new_ip", "")
 		assert.NoError(t, err)
 		assert.Equal(t, newAccessToken, result.AccessToken)
 		decoded, err := base64.URLEncoding.DecodeString(result.RefreshToken)
@@ -95,17 +184,106 @@ func TestAuthService_RefreshToken(t *testing.T) {
 		assert.Len(t, decoded, refresh.TokenLength)
 	})
 
+	t.Run("rotation revokes previous token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		tokenRepo := mock_repository.NewMockITokenRepo(ctrl)
+		auditRepo := mock_repository.NewMockIAuditRepo(ctrl)
+		tokenManager := mock_auth.NewMockAccessTokenManager(ctrl)
+		claims := mock_auth.NewMockClaims(ctrl)
+		svc := service.NewAuthServiceImpl(userRepo, tokenRepo, tokenManager, zap.NewNop(), time.Hour, service.WithAuditRepo(auditRepo))
+
+		oldJTI := uuid.New()
+		guid := uuid.New()
+		oldRefresh := []byte("old_refresh")
+		hashedOldRefresh, err := crypto.HashBytes(oldRefresh)
+		assert.NoError(t, err)
+
+		tokenManager.EXPECT().ParseExpired("valid_access").Return(claims, nil)
+		claims.EXPECT().GetGUID().Return(guid)
+		claims.EXPECT().GetJTI().Return(oldJTI)
+		claims.EXPECT().GetIP().Return("ip")
+		claims.EXPECT().RequiresReauth().Return(false)
+		claims.EXPECT().GetAMR().Return(nil)
+		claims.EXPECT().GetClientID().Return("")
+		claims.EXPECT().GetIssueTime().Return(time.Now())
+		tokenRepo.EXPECT().GetToken(gomock.Any(), guid, oldJTI, gomock.Any()).Return(uuid.New(), hashedOldRefresh, time.Time{}, nil)
+		tokenManager.EXPECT().Generate(guid, gomock.Any(), "ip", auth.TokenOptions{}).Return("new_access", nil)
+		tokenRepo.EXPECT().RotateToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(nil)
+
+		var events []domain.AuthEvent
+		auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.AuthEvent) error {
+			events = append(events, *event)
+			return nil
+		}).Times(2)
+
+		_, err = svc.RefreshToken(context.Background(), "valid_access", base64.URLEncoding.EncodeToString(oldRefresh), "ip", "")
+		assert.NoError(t, err)
+
+		if assert.Len(t, events, 2) {
+			assert.Equal(t, domain.AuthEventTokenRevoked, events[0].Type)
+			assert.Equal(t, oldJTI, events[0].JTI)
+			assert.Equal(t, "rotated", events[0].Details["reason"])
+			assert.Equal(t, time.UTC, events[0].CreatedAt.Location(), "audit timestamps are stored in UTC")
+			assert.Equal(t, domain.AuthEventTokenRefreshed, events[1].Type)
+			assert.Equal(t, events[1].JTI, events[0].Details["new_jti"])
+		}
+	})
+
+	t.Run("client token while oauth disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		tokenRepo := mock_repository.NewMockITokenRepo(ctrl)
+		auditRepo := mock_repository.NewMockIAuditRepo(ctrl)
+		tokenManager := mock_auth.NewMockAccessTokenManager(ctrl)
+		claims := mock_auth.NewMockClaims(ctrl)
+		svc := service.NewAuthServiceImpl(userRepo, tokenRepo, tokenManager, zap.NewNop(), time.Hour, service.WithAuditRepo(auditRepo))
+
+		guid := uuid.New()
+		oldRefresh := []byte("old_refresh")
+		hashedOldRefresh, err := crypto.HashBytes(oldRefresh)
+		assert.NoError(t, err)
+
+		tokenManager.EXPECT().ParseExpired("valid_access").Return(claims, nil)
+		claims.EXPECT().GetGUID().Return(guid)
+		claims.EXPECT().GetJTI().Return(uuid.New())
+		claims.EXPECT().RequiresReauth().Return(false)
+		claims.EXPECT().GetAMR().Return(nil)
+		claims.EXPECT().GetClientID().Return("spa")
+		tokenRepo.EXPECT().GetToken(gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(uuid.New(), hashedOldRefresh, time.Time{}, nil)
+		auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.AuthEvent) error {
+			assert.Equal(t, domain.AuthEventRefreshFailed, event.Type)
+			assert.Equal(t, domain.FailureReasonUnknownClient, event.Reason)
+			return nil
+		})
+
+		// Без хранилища клиентов токены клиентов OAuth не обновляются
+		_, err = svc.RefreshToken(context.Background(), "valid_access", base64.URLEncoding.EncodeToString(oldRefresh), "ip", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidRefreshToken)
+	})
+
 	t.Run("invalid access token", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		tokenManager := mock_auth.NewMockAccessTokenManager(ctrl)
+		auditRepo := mock_repository.NewMockIAuditRepo(ctrl)
 		logger := zap.NewNop()
-		svc := service.NewAuthServiceImpl(nil, nil, tokenManager, logger, time.Hour)
+		svc := service.NewAuthServiceImpl(nil, nil, tokenManager, logger, time.Hour, service.WithAuditRepo(auditRepo))
 
-		tokenManager.EXPECT().Parse("invalid").Return(nil, errors.New("invalid"))
+		tokenManager.EXPECT().ParseExpired("invalid").Return(nil, errors.New("invalid"))
+		auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.AuthEvent) error {
+			assert.Equal(t, domain.AuthEventRefreshFailed, event.Type)
+			assert.Equal(t, domain.FailureReasonBadAccessToken, event.Reason)
+			assert.Equal(t, uuid.Nil, event.GUID)
+			return nil
+		})
 
-		_, err := svc.RefreshToken(context.Background(), "invalid", "refresh", "ip")
+		_, err := svc.RefreshToken(context.Background(), "invalid", "refresh", "ip", "")
 		assert.ErrorIs(t, err, domain.ErrInvalidAccessToken)
 	})
 
@@ -119,17 +297,18 @@ func TestAuthService_RefreshToken(t *testing.T) {
 		logger := zap.NewNop()
 		svc := service.NewAuthServiceImpl(nil, tokenRepo, tokenManager, logger, time.Hour)
 
-		tokenManager.EXPECT().Parse("valid").Return(claims, nil)
+		tokenManager.EXPECT().ParseExpired("valid").Return(claims, nil)
 		claims.EXPECT().GetGUID().Return(uuid.New())
 		claims.EXPECT().GetJTI().Return(uuid.New())
 		tokenRepo.EXPECT().GetToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
-			Return(uuid.Nil, "wrong_hash", nil)
+			Return(uuid.Nil, "wrong_hash", time.Time{}, nil)
 
 		_, err := svc.RefreshToken(
 			context.Background(),
 			"valid",
 			base64.URLEncoding.EncodeToString([]byte("refresh")),
 			"ip",
+			"",
 		)
 		assert.ErrorIs(t, err, domain.ErrInvalidRefreshToken)
 	})
@@ -144,18 +323,294 @@ func TestAuthService_RefreshToken(t *testing.T) {
 		logger := zap.NewNop()
 		svc := service.NewAuthServiceImpl(nil, tokenRepo, tokenManager, logger, time.Hour)
 
-		tokenManager.EXPECT().Parse("valid").Return(claims, nil)
+		tokenManager.EXPECT().ParseExpired("valid").Return(claims, nil)
 		claims.EXPECT().GetGUID().Return(uuid.New())
 		claims.EXPECT().GetJTI().Return(uuid.New())
 		tokenRepo.EXPECT().GetToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
-			Return(uuid.Nil, "", domain.ErrTokenNotFound)
+			Return(uuid.Nil, "", time.Time{}, domain.ErrTokenNotFound)
 
 		_, err := svc.RefreshToken(
 			context.Background(),
 			"valid",
 			base64.URLEncoding.EncodeToString([]byte("refresh")),
 			"ip",
+			"",
 		)
 		assert.ErrorIs(t, err, domain.ErrTokenNotFound)
 	})
+
+	t.Run("ip change denied", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		tokenRepo := mock_repository.NewMockITokenRepo(ctrl)
+		tokenManager := mock_auth.NewMockAccessTokenManager(ctrl)
+		claims := mock_auth.NewMockClaims(ctrl)
+		logger := zap.NewNop()
+		svc := service.NewAuthServiceImpl(userRepo, tokenRepo, tokenManager, logger, time.Hour)
+
+		guid := uuid.New()
+		oldRefresh := []byte("old_refresh")
+		hashedOldRefresh, err := crypto.HashBytes(oldRefresh)
+		assert.NoError(t, err)
+
+		tokenManager.EXPECT().ParseExpired("valid").Return(claims, nil)
+		claims.EXPECT().GetGUID().Return(guid)
+		claims.EXPECT().GetJTI().Return(uuid.New())
+		claims.EXPECT().GetIP().Return("old_ip")
+		claims.EXPECT().RequiresReauth().Return(false)
+		claims.EXPECT().GetAMR().Return(nil)
+		claims.EXPECT().GetClientID().Return("")
+		claims.EXPECT().GetIssueTime().Return(time.Now())
+		tokenRepo.EXPECT().GetToken(gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(uuid.New(), hashedOldRefresh, time.Time{}, nil)
+		// Персональная политика пользователя имеет приоритет над политикой по умолчанию
+		userRepo.EXPECT().GetIPChangePolicy(gomock.Any(), guid).Return(domain.IPChangePolicyDeny, nil)
+
+		_, err = svc.RefreshToken(
+			context.Background(),
+			"valid",
+			base64.URLEncoding.EncodeToString(oldRefresh),
+			"new_ip",
+			"",
+		)
+		assert.ErrorIs(t, err, domain.ErrIPChangeDenied)
+	})
+
+	t.Run("ip change step up", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		tokenRepo := mock_repository.NewMockITokenRepo(ctrl)
+		tokenManager := mock_auth.NewMockAccessTokenManager(ctrl)
+		claims := mock_auth.NewMockClaims(ctrl)
+		logger := zap.NewNop()
+		svc := service.NewAuthServiceImpl(userRepo, tokenRepo, tokenManager, logger, time.Hour,
+			service.WithIPChangePolicy(domain.IPChangePolicyStepUp))
+
+		guid := uuid.New()
+		oldRefresh := []byte("old_refresh")
+		hashedOldRefresh, err := crypto.HashBytes(oldRefresh)
+		assert.NoError(t, err)
+
+		tokenManager.EXPECT().ParseExpired("valid").Return(claims, nil)
+		claims.EXPECT().GetGUID().Return(guid)
+		claims.EXPECT().GetJTI().Return(uuid.New())
+		claims.EXPECT().GetIP().Return("old_ip")
+		claims.EXPECT().RequiresReauth().Return(false)
+		claims.EXPECT().GetAMR().Return(nil)
+		claims.EXPECT().GetClientID().Return("")
+		claims.EXPECT().GetIssueTime().Return(time.Now())
+		tokenRepo.EXPECT().GetToken(gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(uuid.New(), hashedOldRefresh, time.Time{}, nil)
+		userRepo.EXPECT().GetIPChangePolicy(gomock.Any(), guid).Return(domain.IPChangePolicy(""), nil)
+		tokenManager.EXPECT().Generate(guid, gomock.Any(), "new_ip", auth.TokenOptions{RequireReauth: true}).Return("new_access", nil)
+		tokenRepo.EXPECT().RotateToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(nil)
+
+		result, err := svc.RefreshToken(
+			context.Background(),
+			"valid",
+			base64.URLEncoding.EncodeToString(oldRefresh),
+			"new_ip",
+			"",
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, "new_access", result.AccessToken)
+	})
+
+	t.Run("impossible travel", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		tokenRepo := mock_repository.NewMockITokenRepo(ctrl)
+		tokenManager := mock_auth.NewMockAccessTokenManager(ctrl)
+		claims := mock_auth.NewMockClaims(ctrl)
+		locator := mock_geoip.NewMockLocator(ctrl)
+		riskHandler := mock_service.NewMockRiskEventHandler(ctrl)
+		logger := zap.NewNop()
+		svc := service.NewAuthServiceImpl(userRepo, tokenRepo, tokenManager, logger, time.Hour,
+			service.WithGeoLocator(locator, 0), service.WithRiskEventHandlers(riskHandler))
+
+		guid := uuid.New()
+		jti := uuid.New()
+		oldRefresh := []byte("old_refresh")
+		hashedOldRefresh, err := crypto.HashBytes(oldRefresh)
+		assert.NoError(t, err)
+
+		tokenManager.EXPECT().ParseExpired("valid").Return(claims, nil)
+		claims.EXPECT().GetGUID().Return(guid)
+		claims.EXPECT().GetJTI().Return(jti)
+		claims.EXPECT().GetIP().Return("old_ip")
+		claims.EXPECT().RequiresReauth().Return(false)
+		claims.EXPECT().GetAMR().Return(nil)
+		claims.EXPECT().GetClientID().Return("")
+		claims.EXPECT().GetIssueTime().Return(time.Now().Add(-time.Hour))
+		tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.New(), hashedOldRefresh, time.Time{}, nil)
+		locator.EXPECT().Lookup("new_ip").Return(&geoip.Location{Country: "US", City: "New York", Latitude: 40.7128, Longitude: -74.0060}, nil)
+		locator.EXPECT().Lookup("old_ip").Return(&geoip.Location{Country: "RU", City: "Moscow", Latitude: 55.7558, Longitude: 37.6173}, nil)
+		riskHandler.EXPECT().HandleRiskEvent(gomock.Any(), gomock.Any()).Do(func(ctx context.Context, event domain.RiskEvent) {
+			assert.Equal(t, domain.RiskEventImpossibleTravel, event.Type)
+			assert.Equal(t, guid, event.GUID)
+			assert.Equal(t, jti, event.JTI)
+			assert.Equal(t, "RU", event.PrevLocation.Country)
+			assert.Equal(t, "US", event.Location.Country)
+			assert.Greater(t, event.DistanceKM, 7000.0)
+		})
+		userRepo.EXPECT().GetIPChangePolicy(gomock.Any(), guid).Return(domain.IPChangePolicy(""), nil)
+		tokenManager.EXPECT().Generate(guid, gomock.Any(), "new_ip", auth.TokenOptions{}).Return("new_access", nil)
+		tokenRepo.EXPECT().RotateToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(nil)
+
+		_, err = svc.RefreshToken(
+			context.Background(),
+			"valid",
+			base64.URLEncoding.EncodeToString(oldRefresh),
+			"new_ip",
+			"",
+		)
+		assert.NoError(t, err)
+	})
+
+	t.Run("plausible travel", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		tokenRepo := mock_repository.NewMockITokenRepo(ctrl)
+		tokenManager := mock_auth.NewMockAccessTokenManager(ctrl)
+		claims := mock_auth.NewMockClaims(ctrl)
+		locator := mock_geoip.NewMockLocator(ctrl)
+		riskHandler := mock_service.NewMockRiskEventHandler(ctrl)
+		logger := zap.NewNop()
+		svc := service.NewAuthServiceImpl(userRepo, tokenRepo, tokenManager, logger, time.Hour,
+			service.WithGeoLocator(locator, 0), service.WithRiskEventHandlers(riskHandler))
+
+		guid := uuid.New()
+		oldRefresh := []byte("old_refresh")
+		hashedOldRefresh, err := crypto.HashBytes(oldRefresh)
+		assert.NoError(t, err)
+
+		tokenManager.EXPECT().ParseExpired("valid").Return(claims, nil)
+		claims.EXPECT().GetGUID().Return(guid)
+		claims.EXPECT().GetJTI().Return(uuid.New())
+		claims.EXPECT().GetIP().Return("old_ip")
+		claims.EXPECT().RequiresReauth().Return(false)
+		claims.EXPECT().GetAMR().Return(nil)
+		claims.EXPECT().GetClientID().Return("")
+		// За сутки перелет из Москвы в Нью-Йорк вполне возможен
+		claims.EXPECT().GetIssueTime().Return(time.Now().Add(-24 * time.Hour))
+		tokenRepo.EXPECT().GetToken(gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(uuid.New(), hashedOldRefresh, time.Time{}, nil)
+		locator.EXPECT().Lookup("new_ip").Return(&geoip.Location{Country: "US", City: "New York", Latitude: 40.7128, Longitude: -74.0060}, nil)
+		locator.EXPECT().Lookup("old_ip").Return(&geoip.Location{Country: "RU", City: "Moscow", Latitude: 55.7558, Longitude: 37.6173}, nil)
+		userRepo.EXPECT().GetIPChangePolicy(gomock.Any(), guid).Return(domain.IPChangePolicy(""), nil)
+		tokenManager.EXPECT().Generate(guid, gomock.Any(), "new_ip", auth.TokenOptions{}).Return("new_access", nil)
+		tokenRepo.EXPECT().RotateToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(nil)
+
+		_, err = svc.RefreshToken(
+			context.Background(),
+			"valid",
+			base64.URLEncoding.EncodeToString(oldRefresh),
+			"new_ip",
+			"",
+		)
+		assert.NoError(t, err)
+	})
+
+	t.Run("risk denied", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		tokenRepo := mock_repository.NewMockITokenRepo(ctrl)
+		tokenManager := mock_auth.NewMockAccessTokenManager(ctrl)
+		claims := mock_auth.NewMockClaims(ctrl)
+		riskHandler := mock_service.NewMockRiskEventHandler(ctrl)
+		logger := zap.NewNop()
+
+		rules := risk.DefaultRules()
+		rules.DenyThreshold = rules.Weights[risk.SignalIPChange] + rules.Weights[risk.SignalNewUserAgent]
+		svc := service.NewAuthServiceImpl(userRepo, tokenRepo, tokenManager, logger, time.Hour,
+			service.WithRiskEngine(risk.NewRuleEngine(rules), risk.NewMemoryFailureTracker(time.Minute)),
+			service.WithRiskEventHandlers(riskHandler))
+
+		guid := uuid.New()
+		oldRefresh := []byte("old_refresh")
+		hashedOldRefresh, err := crypto.HashBytes(oldRefresh)
+		assert.NoError(t, err)
+
+		tokenManager.EXPECT().ParseExpired("valid").Return(claims, nil)
+		claims.EXPECT().GetGUID().Return(guid)
+		claims.EXPECT().GetJTI().Return(uuid.New())
+		claims.EXPECT().GetIP().Return("old_ip")
+		claims.EXPECT().RequiresReauth().Return(false)
+		claims.EXPECT().GetAMR().Return(nil)
+		claims.EXPECT().GetClientID().Return("")
+		claims.EXPECT().GetIssueTime().Return(time.Now())
+		claims.EXPECT().GetUserAgentHash().Return(risk.UserAgentFingerprint("old-agent"))
+		tokenRepo.EXPECT().GetToken(gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(uuid.New(), hashedOldRefresh, time.Time{}, nil)
+		riskHandler.EXPECT().HandleRiskEvent(gomock.Any(), gomock.Any()).Do(func(ctx context.Context, event domain.RiskEvent) {
+			assert.Equal(t, domain.RiskEventRiskDenied, event.Type)
+			assert.Equal(t, guid, event.GUID)
+			assert.Contains(t, event.Signals, string(risk.SignalIPChange))
+			assert.Contains(t, event.Signals, string(risk.SignalNewUserAgent))
+		})
+
+		_, err = svc.RefreshToken(
+			context.Background(),
+			"valid",
+			base64.URLEncoding.EncodeToString(oldRefresh),
+			"new_ip",
+			"new-agent",
+		)
+		assert.ErrorIs(t, err, domain.ErrRiskDenied)
+	})
+
+	t.Run("idle session", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		tokenRepo := mock_repository.NewMockITokenRepo(ctrl)
+		tokenManager := mock_auth.NewMockAccessTokenManager(ctrl)
+		claims := mock_auth.NewMockClaims(ctrl)
+		riskHandler := mock_service.NewMockRiskEventHandler(ctrl)
+		logger := zap.NewNop()
+
+		rules := risk.DefaultRules()
+		rules.DenyThreshold = rules.Weights[risk.SignalTimeSinceUsage]
+		svc := service.NewAuthServiceImpl(userRepo, tokenRepo, tokenManager, logger, time.Hour,
+			service.WithRiskEngine(risk.NewRuleEngine(rules), risk.NewMemoryFailureTracker(time.Minute)),
+			service.WithRiskEventHandlers(riskHandler))
+
+		guid := uuid.New()
+		oldRefresh := []byte("old_refresh")
+		hashedOldRefresh, err := crypto.HashBytes(oldRefresh)
+		assert.NoError(t, err)
+
+		// Access токен давно просрочен, время простоя определяет сессия, а не он
+		tokenManager.EXPECT().ParseExpired("expired").Return(claims, nil)
+		claims.EXPECT().GetGUID().Return(guid)
+		claims.EXPECT().GetJTI().Return(uuid.New())
+		claims.EXPECT().GetIP().Return("ip")
+		claims.EXPECT().RequiresReauth().Return(false)
+		claims.EXPECT().GetAMR().Return(nil)
+		claims.EXPECT().GetClientID().Return("")
+		claims.EXPECT().GetIssueTime().Return(time.Now().Add(-31 * 24 * time.Hour))
+		claims.EXPECT().GetUserAgentHash().Return(risk.UserAgentFingerprint("agent"))
+		tokenRepo.EXPECT().GetToken(gomock.Any(), guid, gomock.Any(), gomock.Any()).
+			Return(uuid.New(), hashedOldRefresh, time.Now().Add(-31*24*time.Hour), nil)
+		riskHandler.EXPECT().HandleRiskEvent(gomock.Any(), gomock.Any()).Do(func(ctx context.Context, event domain.RiskEvent) {
+			assert.Equal(t, domain.RiskEventRiskDenied, event.Type)
+			assert.Equal(t, map[string]float64{string(risk.SignalTimeSinceUsage): rules.Weights[risk.SignalTimeSinceUsage]}, event.Signals)
+		})
+
+		_, err = svc.RefreshToken(
+			context.Background(),
+			"expired",
+			base64.URLEncoding.EncodeToString(oldRefresh),
+			"ip",
+			"agent",
+		)
+		assert.ErrorIs(t, err, domain.ErrRiskDenied)
+	})
 }
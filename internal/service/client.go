@@ -0,0 +1,478 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/oauth"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	clientSecretLength = 32  // Длина секрета клиента OAuth и токена доступа к регистрации в байтах
+	maxClientNameLen   = 255 // Максимальная длина названия клиента
+)
+
+// IClientService - интерфейс для управления реестром клиентов OAuth.
+type IClientService interface {
+	// CreateClient регистрирует клиента и возвращает его вместе с секретом.
+	// Секрет выдается только конфиденциальным клиентам и показывается один раз.
+	CreateClient(ctx context.Context, client domain.OAuthClient, confidential bool) (*domain.OAuthClient, string, error)
+	// GetClient возвращает клиента по идентификатору.
+	GetClient(ctx context.Context, clientID string) (*domain.OAuthClient, error)
+	// ListClients возвращает всех зарегистрированных клиентов.
+	ListClients(ctx context.Context) ([]domain.OAuthClient, error)
+	// UpdateClient заменяет параметры клиента, кроме секрета.
+	UpdateClient(ctx context.Context, client domain.OAuthClient) (*domain.OAuthClient, error)
+	// RotateClientSecret выдает клиенту новый секрет взамен старого.
+	RotateClientSecret(ctx context.Context, clientID string) (string, error)
+	// DeleteClient удаляет клиента.
+	DeleteClient(ctx context.Context, clientID string) error
+	// RegisterClient регистрирует клиента по запросу партнера (RFC 7591).
+	RegisterClient(ctx context.Context, initialAccessToken string, client domain.OAuthClient, confidential bool) (*domain.ClientRegistration, error)
+	// GetRegisteredClient возвращает клиента, зарегистрированного партнером (RFC 7592).
+	GetRegisteredClient(ctx context.Context, clientID, registrationToken string) (*domain.ClientRegistration, error)
+	// UpdateRegisteredClient заменяет параметры клиента, зарегистрированного партнером (RFC 7592).
+	UpdateRegisteredClient(ctx context.Context, registrationToken string, client domain.OAuthClient, confidential bool) (*domain.ClientRegistration, error)
+	// DeleteRegisteredClient удаляет клиента, зарегистрированного партнером (RFC 7592).
+	DeleteRegisteredClient(ctx context.Context, clientID, registrationToken string) error
+}
+
+type ClientServiceImpl struct {
+	clientRepo repository.IClientRepo
+	logger     *zap.Logger
+	// Динамическая регистрация клиентов
+	initialTokenHashes []string // SHA-256 хеши токенов, выданных партнерам для регистрации клиентов
+	registrationScopes []string // Области доступа, которые партнеры могут запросить для своих клиентов
+	registrationURL    string   // Адрес эндпоинта регистрации, к которому добавляется client_id
+}
+
+// ClientServiceOption - функциональная опция для настройки ClientServiceImpl.
+type ClientServiceOption func(s *ClientServiceImpl)
+
+// WithRegistration включает динамическую регистрацию клиентов (RFC 7591, RFC 7592).
+// Регистрировать клиентов могут только партнеры, предъявившие один из initialAccessTokens,
+// и только с областями доступа из scopes. registrationURL - адрес эндпоинта регистрации,
+// из которого строится адрес управления регистрацией клиента.
+func WithRegistration(initialAccessTokens, scopes []string, registrationURL string) ClientServiceOption {
+	return func(s *ClientServiceImpl) {
+		s.initialTokenHashes = make([]string, 0, len(initialAccessTokens))
+		for _, token := range initialAccessTokens {
+			if token != "" {
+				s.initialTokenHashes = append(s.initialTokenHashes, crypto.HashToken([]byte(token)))
+			}
+		}
+		s.registrationScopes = scopes
+		s.registrationURL = strings.TrimSuffix(registrationURL, "/")
+	}
+}
+
+func NewClientServiceImpl(clientRepo repository.IClientRepo, logger *zap.Logger, opts ...ClientServiceOption) IClientService {
+	s := &ClientServiceImpl{
+		clientRepo: clientRepo,
+		logger:     logger,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// normalizeClient проверяет параметры клиента и удаляет из них повторы.
+// Возвращает domain.ErrInvalidClientMetadata, если параметры недопустимы.
+func normalizeClient(client *domain.OAuthClient) error {
+	client.Name = strings.TrimSpace(client.Name)
+	if client.Name == "" || len(client.Name) > maxClientNameLen {
+		return domain.ErrInvalidClientMetadata
+	}
+
+	if len(client.GrantTypes) == 0 {
+		return domain.ErrInvalidClientMetadata
+	}
+	grantTypes := make([]domain.GrantType, 0, len(client.GrantTypes))
+	for _, raw := range client.GrantTypes {
+		grantType, err := domain.ParseGrantType(string(raw))
+		if err != nil {
+			return domain.ErrInvalidClientMetadata
+		}
+		if !slices.Contains(grantTypes, grantType) {
+			grantTypes = append(grantTypes, grantType)
+		}
+	}
+	client.GrantTypes = grantTypes
+
+	redirectURIs := make([]string, 0, len(client.RedirectURIs))
+	for _, uri := range client.RedirectURIs {
+		if !oauth.ValidRedirectURI(uri) {
+			return domain.ErrInvalidClientRedirectURIs
+		}
+		if !slices.Contains(redirectURIs, uri) {
+			redirectURIs = append(redirectURIs, uri)
+		}
+	}
+	client.RedirectURIs = redirectURIs
+
+	// Без адреса перенаправления код авторизации некуда вернуть
+	if client.AllowsGrant(domain.GrantTypeAuthorizationCode) && len(client.RedirectURIs) == 0 {
+		return domain.ErrInvalidClientRedirectURIs
+	}
+
+	scopes := make([]string, 0, len(client.Scopes))
+	for _, scope := range client.Scopes {
+		if !oauth.ValidScope(scope) {
+			return domain.ErrInvalidClientMetadata
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	client.Scopes = scopes
+
+	if client.AccessTTL < 0 || client.RefreshTTL < 0 {
+		return domain.ErrInvalidClientMetadata
+	}
+
+	return nil
+}
+
+// generateClientSecret генерирует секрет клиента или токен доступа к регистрации
+// и возвращает его вместе с хешем для хранения.
+func (s *ClientServiceImpl) generateClientSecret() (string, string, error) {
+	secret := make([]byte, clientSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		s.logger.Error("Error generating client secret", zap.Error(err))
+		return "", "", domain.ErrUnexpected
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+	return encoded, crypto.HashToken([]byte(encoded)), nil
+}
+
+// CreateClient регистрирует клиента с новым идентификатором.
+// Возвращает domain.ErrInvalidClientMetadata, если параметры клиента недопустимы,
+// в том числе если client_credentials или обмен токена разрешен публичному клиенту.
+func (s *ClientServiceImpl) CreateClient(ctx context.Context, client domain.OAuthClient, confidential bool) (*domain.OAuthClient, string, error) {
+	client.RegistrationTokenHash = ""
+	return s.createClient(ctx, client, confidential)
+}
+
+// createClient проверяет параметры клиента и сохраняет его с новым идентификатором и секретом.
+func (s *ClientServiceImpl) createClient(ctx context.Context, client domain.OAuthClient, confidential bool) (*domain.OAuthClient, string, error) {
+	if err := normalizeClient(&client); err != nil {
+		return nil, "", err
+	}
+
+	if !confidential && allowsConfidentialOnlyGrant(&client) {
+		return nil, "", domain.ErrInvalidClientMetadata
+	}
+
+	client.ID = uuid.NewString()
+	client.SecretHash = ""
+
+	var secret string
+	if confidential {
+		var err error
+		secret, client.SecretHash, err = s.generateClientSecret()
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	client.CreatedAt = time.Now().UTC()
+	client.UpdatedAt = client.CreatedAt
+
+	if err := s.clientRepo.Create(ctx, &client); err != nil {
+		return nil, "", domain.ErrUnexpected
+	}
+
+	s.logger.Info("OAuth client registered", zap.String("client_id", client.ID), zap.Bool("confidential", confidential))
+
+	return &client, secret, nil
+}
+
+// GetClient возвращает клиента по идентификатору.
+// Возвращает domain.ErrClientNotFound, если клиент не найден.
+func (s *ClientServiceImpl) GetClient(ctx context.Context, clientID string) (*domain.OAuthClient, error) {
+	client, err := s.clientRepo.Get(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, domain.ErrClientNotFound) {
+			return nil, err
+		}
+		return nil, domain.ErrUnexpected
+	}
+	return client, nil
+}
+
+func (s *ClientServiceImpl) ListClients(ctx context.Context) ([]domain.OAuthClient, error) {
+	clients, err := s.clientRepo.List(ctx)
+	if err != nil {
+		return nil, domain.ErrUnexpected
+	}
+	return clients, nil
+}
+
+// UpdateClient заменяет название, способы получения токенов, адреса перенаправления,
+// области доступа и время жизни токенов клиента. Секрет и тип клиента не меняются.
+// Новые настройки применяются к уже выданным токенам при их обновлении.
+// Возвращает domain.ErrClientNotFound, если клиент не найден, и domain.ErrInvalidClientMetadata, если параметры недопустимы.
+func (s *ClientServiceImpl) UpdateClient(ctx context.Context, client domain.OAuthClient) (*domain.OAuthClient, error) {
+	if err := normalizeClient(&client); err != nil {
+		return nil, err
+	}
+
+	current, err := s.GetClient(ctx, client.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	client.SecretHash = current.SecretHash
+	if !client.Confidential() && allowsConfidentialOnlyGrant(&client) {
+		return nil, domain.ErrInvalidClientMetadata
+	}
+	client.RegistrationTokenHash = current.RegistrationTokenHash
+	client.CreatedAt = current.CreatedAt
+	client.UpdatedAt = time.Now().UTC()
+
+	if err := s.clientRepo.Update(ctx, &client); err != nil {
+		if errors.Is(err, domain.ErrClientNotFound) {
+			return nil, err
+		}
+		return nil, domain.ErrUnexpected
+	}
+
+	s.logger.Info("OAuth client updated", zap.String("client_id", client.ID))
+
+	return &client, nil
+}
+
+// RotateClientSecret выдает клиенту новый секрет. Старый секрет перестает действовать сразу.
+// Публичный клиент после этого становится конфиденциальным.
+// Возвращает domain.ErrClientNotFound, если клиент не найден.
+func (s *ClientServiceImpl) RotateClientSecret(ctx context.Context, clientID string) (string, error) {
+	secret, secretHash, err := s.generateClientSecret()
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.clientRepo.UpdateSecret(ctx, clientID, secretHash); err != nil {
+		if errors.Is(err, domain.ErrClientNotFound) {
+			return "", err
+		}
+		return "", domain.ErrUnexpected
+	}
+
+	s.logger.Info("OAuth client secret rotated", zap.String("client_id", clientID))
+
+	return secret, nil
+}
+
+// DeleteClient удаляет клиента вместе с его неиспользованными кодами авторизации.
+// Выданные клиенту Access токены действуют до истечения, но обновить их больше нельзя.
+// Возвращает domain.ErrClientNotFound, если клиент не найден.
+func (s *ClientServiceImpl) DeleteClient(ctx context.Context, clientID string) error {
+	if err := s.clientRepo.Delete(ctx, clientID); err != nil {
+		if errors.Is(err, domain.ErrClientNotFound) {
+			return err
+		}
+		return domain.ErrUnexpected
+	}
+
+	s.logger.Info("OAuth client deleted", zap.String("client_id", clientID))
+
+	return nil
+}
+
+// checkInitialAccessToken проверяет токен, выданный партнеру для регистрации клиентов.
+// Токен сравнивается со всеми настроенными токенами за постоянное время.
+func (s *ClientServiceImpl) checkInitialAccessToken(token string) error {
+	if len(s.initialTokenHashes) == 0 {
+		return domain.ErrRegistrationDisabled
+	}
+
+	hash := []byte(crypto.HashToken([]byte(token)))
+	valid := 0
+	for _, expected := range s.initialTokenHashes {
+		valid |= subtle.ConstantTimeCompare(hash, []byte(expected))
+	}
+
+	if token == "" || valid != 1 {
+		return domain.ErrInvalidRegistrationToken
+	}
+	return nil
+}
+
+// registeredClient возвращает клиента, зарегистрированного партнером, если токен доступа к регистрации подходит.
+// Неизвестный клиент и неверный токен не различаются (RFC 7592, раздел 2).
+// Клиенты, зарегистрированные администратором, через эндпоинт регистрации недоступны.
+func (s *ClientServiceImpl) registeredClient(ctx context.Context, clientID, registrationToken string) (*domain.OAuthClient, error) {
+	if len(s.initialTokenHashes) == 0 {
+		return nil, domain.ErrRegistrationDisabled
+	}
+
+	client, err := s.GetClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, domain.ErrClientNotFound) {
+			return nil, domain.ErrInvalidRegistrationToken
+		}
+		return nil, err
+	}
+
+	hash := crypto.HashToken([]byte(registrationToken))
+	if client.RegistrationTokenHash == "" || subtle.ConstantTimeCompare([]byte(hash), []byte(client.RegistrationTokenHash)) != 1 {
+		s.logger.Debug("Invalid registration access token", zap.String("client_id", clientID))
+		return nil, domain.ErrInvalidRegistrationToken
+	}
+
+	return client, nil
+}
+
+// allowsConfidentialOnlyGrant сообщает, разрешен ли клиенту способ, доступный только конфиденциальным клиентам.
+func allowsConfidentialOnlyGrant(client *domain.OAuthClient) bool {
+	return slices.ContainsFunc(client.GrantTypes, domain.GrantType.ConfidentialOnly)
+}
+
+// checkRegistrationMetadata проверяет, что партнер запросил только разрешенные для регистрации области доступа.
+// Обмен токена позволяет действовать от имени любого пользователя, поэтому партнерам он не разрешается.
+func (s *ClientServiceImpl) checkRegistrationMetadata(client *domain.OAuthClient) error {
+	if client.AllowsGrant(domain.GrantTypeTokenExchange) {
+		return domain.ErrInvalidClientMetadata
+	}
+	for _, scope := range client.Scopes {
+		if !slices.Contains(s.registrationScopes, scope) {
+			return domain.ErrInvalidClientMetadata
+		}
+	}
+	return nil
+}
+
+// registrationURI возвращает адрес управления регистрацией клиента.
+func (s *ClientServiceImpl) registrationURI(clientID string) string {
+	return s.registrationURL + "/" + url.PathEscape(clientID)
+}
+
+// RegisterClient регистрирует клиента по запросу партнера, предъявившего initialAccessToken.
+// Время жизни токенов партнерских клиентов задает только администратор через UpdateClient.
+// Возвращает domain.ErrRegistrationDisabled, если регистрация не настроена, domain.ErrInvalidRegistrationToken,
+// если токен не подошел, и domain.ErrInvalidClientMetadata, если параметры клиента недопустимы.
+func (s *ClientServiceImpl) RegisterClient(ctx context.Context, initialAccessToken string, client domain.OAuthClient, confidential bool) (*domain.ClientRegistration, error) {
+	if err := s.checkInitialAccessToken(initialAccessToken); err != nil {
+		return nil, err
+	}
+
+	client.AccessTTL, client.RefreshTTL = 0, 0
+	if err := s.checkRegistrationMetadata(&client); err != nil {
+		return nil, err
+	}
+
+	registrationToken, registrationHash, err := s.generateClientSecret()
+	if err != nil {
+		return nil, err
+	}
+	client.RegistrationTokenHash = registrationHash
+
+	created, secret, err := s.createClient(ctx, client, confidential)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.ClientRegistration{
+		Client:            *created,
+		Secret:            secret,
+		RegistrationToken: registrationToken,
+		RegistrationURI:   s.registrationURI(created.ID),
+	}, nil
+}
+
+// GetRegisteredClient возвращает клиента, зарегистрированного партнером.
+// Секрет клиента и токен доступа к регистрации не возвращаются, так как хранятся только их хеши.
+// Возвращает domain.ErrInvalidRegistrationToken, если клиент неизвестен или токен не подошел.
+func (s *ClientServiceImpl) GetRegisteredClient(ctx context.Context, clientID, registrationToken string) (*domain.ClientRegistration, error) {
+	client, err := s.registeredClient(ctx, clientID, registrationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.ClientRegistration{
+		Client:          *client,
+		RegistrationURI: s.registrationURI(client.ID),
+	}, nil
+}
+
+// UpdateRegisteredClient заменяет параметры клиента, зарегистрированного партнером, и выдает
+// новый токен доступа к регистрации взамен предъявленного. Тип клиента (confidential), секрет
+// и время жизни токенов не меняются.
+// Возвращает domain.ErrInvalidRegistrationToken, если клиент неизвестен или токен не подошел,
+// и domain.ErrInvalidClientMetadata, если параметры недопустимы или запрошена смена типа клиента.
+func (s *ClientServiceImpl) UpdateRegisteredClient(ctx context.Context, registrationToken string, client domain.OAuthClient, confidential bool) (*domain.ClientRegistration, error) {
+	current, err := s.registeredClient(ctx, client.ID, registrationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := normalizeClient(&client); err != nil {
+		return nil, err
+	}
+	if confidential != current.Confidential() {
+		return nil, domain.ErrInvalidClientMetadata
+	}
+	if !confidential && allowsConfidentialOnlyGrant(&client) {
+		return nil, domain.ErrInvalidClientMetadata
+	}
+	if err := s.checkRegistrationMetadata(&client); err != nil {
+		return nil, err
+	}
+
+	newToken, newHash, err := s.generateClientSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	client.SecretHash = current.SecretHash
+	client.AccessTTL, client.RefreshTTL = current.AccessTTL, current.RefreshTTL
+	client.RegistrationTokenHash = newHash
+	client.CreatedAt = current.CreatedAt
+	client.UpdatedAt = time.Now().UTC()
+
+	if err := s.clientRepo.Update(ctx, &client); err != nil {
+		if errors.Is(err, domain.ErrClientNotFound) {
+			return nil, domain.ErrInvalidRegistrationToken
+		}
+		return nil, domain.ErrUnexpected
+	}
+
+	s.logger.Info("Registered OAuth client updated", zap.String("client_id", client.ID))
+
+	return &domain.ClientRegistration{
+		Client:            client,
+		RegistrationToken: newToken,
+		RegistrationURI:   s.registrationURI(client.ID),
+	}, nil
+}
+
+// DeleteRegisteredClient удаляет клиента, зарегистрированного партнером, как DeleteClient.
+// Возвращает domain.ErrInvalidRegistrationToken, если клиент неизвестен или токен не подошел.
+func (s *ClientServiceImpl) DeleteRegisteredClient(ctx context.Context, clientID, registrationToken string) error {
+	if _, err := s.registeredClient(ctx, clientID, registrationToken); err != nil {
+		return err
+	}
+
+	if err := s.DeleteClient(ctx, clientID); err != nil {
+		if errors.Is(err, domain.ErrClientNotFound) {
+			return domain.ErrInvalidRegistrationToken
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,397 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
+	mock_repository "github.com/maksemen2/medods-task/internal/repository/mocks"
+	"github.com/maksemen2/medods-task/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func newClientService(ctrl *gomock.Controller) (service.IClientService, *mock_repository.MockIClientRepo) {
+	clientRepo := mock_repository.NewMockIClientRepo(ctrl)
+	return service.NewClientServiceImpl(clientRepo, zap.NewNop()), clientRepo
+}
+
+func validClient() domain.OAuthClient {
+	return domain.OAuthClient{
+		Name:         " SPA ",
+		GrantTypes:   []domain.GrantType{domain.GrantTypeAuthorizationCode, domain.GrantTypeRefreshToken, domain.GrantTypeRefreshToken},
+		RedirectURIs: []string{"https://app.example.com/callback"},
+		Scopes:       []string{"profile", "email", "profile"},
+		AccessTTL:    5 * time.Minute,
+	}
+}
+
+func TestClientService_CreateClient(t *testing.T) {
+	t.Run("confidential", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, clientRepo := newClientService(ctrl)
+
+		var stored *domain.OAuthClient
+		clientRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, client *domain.OAuthClient) error {
+			stored = client
+			return nil
+		})
+
+		client, secret, err := svc.CreateClient(context.Background(), validClient(), true)
+		require.NoError(t, err)
+		assert.NotEmpty(t, client.ID)
+		assert.Equal(t, "SPA", client.Name)
+		assert.Equal(t, []domain.GrantType{domain.GrantTypeAuthorizationCode, domain.GrantTypeRefreshToken}, client.GrantTypes)
+		assert.Equal(t, []string{"profile", "email"}, client.Scopes)
+		assert.NotEmpty(t, secret)
+		assert.Equal(t, crypto.HashToken([]byte(secret)), stored.SecretHash)
+		assert.True(t, client.Confidential())
+	})
+
+	t.Run("public", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, clientRepo := newClientService(ctrl)
+
+		clientRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+		client, secret, err := svc.CreateClient(context.Background(), validClient(), false)
+		require.NoError(t, err)
+		assert.Empty(t, secret)
+		assert.False(t, client.Confidential())
+	})
+
+	tests := []struct {
+		name   string
+		modify func(client *domain.OAuthClient)
+	}{
+		{"empty name", func(client *domain.OAuthClient) { client.Name = "  " }},
+		{"no grant types", func(client *domain.OAuthClient) { client.GrantTypes = nil }},
+		{"unknown grant type", func(client *domain.OAuthClient) { client.GrantTypes = []domain.GrantType{"password"} }},
+		{"invalid redirect uri", func(client *domain.OAuthClient) { client.RedirectURIs = []string{"https://app.example.com/#fragment"} }},
+		{"authorization code without redirect uri", func(client *domain.OAuthClient) { client.RedirectURIs = nil }},
+		{"invalid scope", func(client *domain.OAuthClient) { client.Scopes = []string{"bad scope"} }},
+		{"negative ttl", func(client *domain.OAuthClient) { client.RefreshTTL = -time.Second }},
+		{"client credentials for public client", func(client *domain.OAuthClient) {
+			client.GrantTypes = []domain.GrantType{domain.GrantTypeClientCredentials}
+		}},
+		{"token exchange for public client", func(client *domain.OAuthClient) {
+			client.GrantTypes = []domain.GrantType{domain.GrantTypeTokenExchange}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc, _ := newClientService(ctrl)
+
+			client := validClient()
+			tt.modify(&client)
+
+			_, _, err := svc.CreateClient(context.Background(), client, false)
+			assert.ErrorIs(t, err, domain.ErrInvalidClientMetadata)
+		})
+	}
+}
+
+func TestClientService_UpdateClient(t *testing.T) {
+	t.Run("keeps secret", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, clientRepo := newClientService(ctrl)
+		createdAt := time.Now().Add(-time.Hour).UTC()
+
+		clientRepo.EXPECT().Get(gomock.Any(), "client").Return(&domain.OAuthClient{ID: "client", SecretHash: "hash", CreatedAt: createdAt}, nil)
+		clientRepo.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, client *domain.OAuthClient) error {
+			assert.Equal(t, "hash", client.SecretHash)
+			return nil
+		})
+
+		client := validClient()
+		client.ID = "client"
+		client.SecretHash = "other"
+
+		updated, err := svc.UpdateClient(context.Background(), client)
+		require.NoError(t, err)
+		assert.Equal(t, createdAt, updated.CreatedAt)
+		assert.True(t, updated.UpdatedAt.After(createdAt))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, clientRepo := newClientService(ctrl)
+
+		clientRepo.EXPECT().Get(gomock.Any(), "client").Return(nil, domain.ErrClientNotFound)
+
+		client := validClient()
+		client.ID = "client"
+
+		_, err := svc.UpdateClient(context.Background(), client)
+		assert.ErrorIs(t, err, domain.ErrClientNotFound)
+	})
+}
+
+func TestClientService_RotateClientSecret(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc, clientRepo := newClientService(ctrl)
+
+	var storedHash string
+	clientRepo.EXPECT().UpdateSecret(gomock.Any(), "client", gomock.Any()).DoAndReturn(func(ctx context.Context, clientID, secretHash string) error {
+		storedHash = secretHash
+		return nil
+	})
+
+	secret, err := svc.RotateClientSecret(context.Background(), "client")
+	require.NoError(t, err)
+	assert.Equal(t, crypto.HashToken([]byte(secret)), storedHash)
+}
+
+func TestClientService_DeleteClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		repoErr error
+		wantErr error
+	}{
+		{"success", nil, nil},
+		{"not found", domain.ErrClientNotFound, domain.ErrClientNotFound},
+		{"repository error", errors.New("db error"), domain.ErrUnexpected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc, clientRepo := newClientService(ctrl)
+
+			clientRepo.EXPECT().Delete(gomock.Any(), "client").Return(tt.repoErr)
+
+			err := svc.DeleteClient(context.Background(), "client")
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func newRegistrationService(ctrl *gomock.Controller) (service.IClientService, *mock_repository.MockIClientRepo) {
+	clientRepo := mock_repository.NewMockIClientRepo(ctrl)
+	svc := service.NewClientServiceImpl(clientRepo, zap.NewNop(),
+		service.WithRegistration([]string{"partner-a", "partner-b"}, []string{"profile", "email"}, "https://auth.example.com/register"))
+	return svc, clientRepo
+}
+
+func registeredClient(registrationToken string) *domain.OAuthClient {
+	client := validClient()
+	client.ID = "client"
+	client.SecretHash = "hash"
+	client.AccessTTL = 10 * time.Minute
+	client.RegistrationTokenHash = crypto.HashToken([]byte(registrationToken))
+	return &client
+}
+
+func TestClientService_RegisterClient(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, clientRepo := newRegistrationService(ctrl)
+
+		var stored *domain.OAuthClient
+		clientRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, client *domain.OAuthClient) error {
+			stored = client
+			return nil
+		})
+
+		registration, err := svc.RegisterClient(context.Background(), "partner-b", validClient(), true)
+		require.NoError(t, err)
+		assert.NotEmpty(t, registration.Secret)
+		assert.NotEmpty(t, registration.RegistrationToken)
+		assert.Equal(t, crypto.HashToken([]byte(registration.RegistrationToken)), stored.RegistrationTokenHash)
+		assert.Equal(t, "https://auth.example.com/register/"+registration.Client.ID, registration.RegistrationURI)
+		assert.Zero(t, registration.Client.AccessTTL)
+	})
+
+	tests := []struct {
+		name    string
+		opts    []service.ClientServiceOption
+		token   string
+		modify  func(client *domain.OAuthClient)
+		wantErr error
+	}{
+		{
+			name:    "registration disabled",
+			token:   "partner-a",
+			wantErr: domain.ErrRegistrationDisabled,
+		},
+		{
+			name:    "invalid initial access token",
+			opts:    []service.ClientServiceOption{service.WithRegistration([]string{"partner-a"}, nil, "")},
+			token:   "partner-c",
+			wantErr: domain.ErrInvalidRegistrationToken,
+		},
+		{
+			name:    "missing initial access token",
+			opts:    []service.ClientServiceOption{service.WithRegistration([]string{"partner-a"}, nil, "")},
+			wantErr: domain.ErrInvalidRegistrationToken,
+		},
+		{
+			name:    "scope not allowed",
+			opts:    []service.ClientServiceOption{service.WithRegistration([]string{"partner-a"}, []string{"profile"}, "")},
+			token:   "partner-a",
+			wantErr: domain.ErrInvalidClientMetadata,
+		},
+		{
+			name:    "invalid redirect uri",
+			opts:    []service.ClientServiceOption{service.WithRegistration([]string{"partner-a"}, []string{"profile", "email"}, "")},
+			token:   "partner-a",
+			modify:  func(client *domain.OAuthClient) { client.RedirectURIs = []string{"not a uri"} },
+			wantErr: domain.ErrInvalidClientRedirectURIs,
+		},
+		{
+			name:  "token exchange",
+			opts:  []service.ClientServiceOption{service.WithRegistration([]string{"partner-a"}, []string{"profile", "email"}, "")},
+			token: "partner-a",
+			modify: func(client *domain.OAuthClient) {
+				client.GrantTypes = append(client.GrantTypes, domain.GrantTypeTokenExchange)
+			},
+			wantErr: domain.ErrInvalidClientMetadata,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewClientServiceImpl(mock_repository.NewMockIClientRepo(ctrl), zap.NewNop(), tt.opts...)
+
+			client := validClient()
+			if tt.modify != nil {
+				tt.modify(&client)
+			}
+
+			_, err := svc.RegisterClient(context.Background(), tt.token, client, true)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestClientService_RegisteredClientAccess(t *testing.T) {
+	t.Run("get", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, clientRepo := newRegistrationService(ctrl)
+
+		clientRepo.EXPECT().Get(gomock.Any(), "client").Return(registeredClient("registration"), nil)
+
+		registration, err := svc.GetRegisteredClient(context.Background(), "client", "registration")
+		require.NoError(t, err)
+		assert.Equal(t, "client", registration.Client.ID)
+		assert.Empty(t, registration.Secret)
+		assert.Empty(t, registration.RegistrationToken)
+	})
+
+	tests := []struct {
+		name   string
+		client *domain.OAuthClient
+		err    error
+	}{
+		{"wrong token", registeredClient("registration"), nil},
+		{"unknown client", nil, domain.ErrClientNotFound},
+		{"client created by admin", &domain.OAuthClient{ID: "client"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc, clientRepo := newRegistrationService(ctrl)
+
+			clientRepo.EXPECT().Get(gomock.Any(), "client").Return(tt.client, tt.err).Times(3)
+
+			_, err := svc.GetRegisteredClient(context.Background(), "client", "other")
+			assert.ErrorIs(t, err, domain.ErrInvalidRegistrationToken)
+
+			client := validClient()
+			client.ID = "client"
+			_, err = svc.UpdateRegisteredClient(context.Background(), "other", client, true)
+			assert.ErrorIs(t, err, domain.ErrInvalidRegistrationToken)
+
+			err = svc.DeleteRegisteredClient(context.Background(), "client", "other")
+			assert.ErrorIs(t, err, domain.ErrInvalidRegistrationToken)
+		})
+	}
+}
+
+func TestClientService_UpdateRegisteredClient(t *testing.T) {
+	t.Run("rotates registration token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, clientRepo := newRegistrationService(ctrl)
+
+		clientRepo.EXPECT().Get(gomock.Any(), "client").Return(registeredClient("registration"), nil)
+		clientRepo.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, client *domain.OAuthClient) error {
+			assert.Equal(t, "hash", client.SecretHash)
+			assert.Equal(t, 10*time.Minute, client.AccessTTL)
+			return nil
+		})
+
+		client := validClient()
+		client.ID = "client"
+		client.AccessTTL = time.Hour
+
+		registration, err := svc.UpdateRegisteredClient(context.Background(), "registration", client, true)
+		require.NoError(t, err)
+		assert.NotEmpty(t, registration.RegistrationToken)
+		assert.NotEqual(t, "registration", registration.RegistrationToken)
+		assert.Equal(t, crypto.HashToken([]byte(registration.RegistrationToken)), registration.Client.RegistrationTokenHash)
+	})
+
+	t.Run("confidential status change", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, clientRepo := newRegistrationService(ctrl)
+
+		clientRepo.EXPECT().Get(gomock.Any(), "client").Return(registeredClient("registration"), nil)
+
+		client := validClient()
+		client.ID = "client"
+
+		_, err := svc.UpdateRegisteredClient(context.Background(), "registration", client, false)
+		assert.ErrorIs(t, err, domain.ErrInvalidClientMetadata)
+	})
+}
+
+func TestClientService_DeleteRegisteredClient(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc, clientRepo := newRegistrationService(ctrl)
+
+	clientRepo.EXPECT().Get(gomock.Any(), "client").Return(registeredClient("registration"), nil)
+	clientRepo.EXPECT().Delete(gomock.Any(), "client").Return(nil)
+
+	assert.NoError(t, svc.DeleteRegisteredClient(context.Background(), "client", "registration"))
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/oauth"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	"net/url"
+	"time"
+)
+
+const (
+	defaultDeviceCodeTTL      = 10 * time.Minute // Время жизни кодов устройства по умолчанию
+	defaultDevicePollInterval = 5 * time.Second  // Минимальный интервал опроса эндпоинта /token по умолчанию (RFC 8628, раздел 3.2)
+	deviceSlowDownStep        = 5 * time.Second  // Увеличение интервала опроса после ответа slow_down (RFC 8628, раздел 3.5)
+	deviceCodeLength          = 32               // Длина кода устройства в байтах
+)
+
+// WithDeviceAuthorization включает авторизацию устройств без браузера (RFC 8628).
+// Требует включенного сервера авторизации OAuth (WithOAuth). verificationURL - адрес страницы,
+// на которой пользователь вводит код с экрана устройства. Если codeTTL или interval неположительны,
+// используются defaultDeviceCodeTTL и defaultDevicePollInterval.
+func WithDeviceAuthorization(deviceRepo repository.IDeviceAuthorizationRepo, verificationURL string, codeTTL, interval time.Duration) AuthServiceOption {
+	return func(s *AuthServiceImpl) {
+		s.deviceRepo = deviceRepo
+		s.deviceVerificationURL = verificationURL
+		if codeTTL > 0 {
+			s.deviceCodeTTL = codeTTL
+		}
+		if interval > 0 {
+			s.devicePollInterval = interval
+		}
+	}
+}
+
+// StartDeviceAuthorization выдает клиенту код устройства для опроса эндпоинта /token
+// и пользовательский код для страницы подтверждения (RFC 8628, раздел 3.1).
+// scope - запрошенные области доступа через пробел, каждая должна быть разрешена клиенту.
+// Возвращает domain.ErrDeviceFlowDisabled, если авторизация устройств не настроена, domain.ErrUnauthorizedClient,
+// если способ не разрешен клиенту, и domain.ErrInvalidScope, если области доступа недопустимы.
+func (s *AuthServiceImpl) StartDeviceAuthorization(ctx context.Context, creds domain.ClientCredentials, scope, ip, userAgent string) (*domain.DeviceCode, error) {
+	if s.deviceRepo == nil {
+		return nil, domain.ErrDeviceFlowDisabled
+	}
+
+	client, err := s.authenticateClient(ctx, creds, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	if !client.AllowsGrant(domain.GrantTypeDeviceCode) {
+		return nil, domain.ErrUnauthorizedClient
+	}
+
+	scopes, ok := oauth.ParseScope(scope)
+	if !ok || !client.AllowsScopes(scopes) {
+		return nil, domain.ErrInvalidScope
+	}
+
+	deviceCode := make([]byte, deviceCodeLength)
+	if _, err := rand.Read(deviceCode); err != nil {
+		s.logger.Error("Error generating device code", zap.Error(err))
+		return nil, domain.ErrUnexpected
+	}
+
+	userCode, err := oauth.GenerateUserCode()
+	if err != nil {
+		s.logger.Error("Error generating user code", zap.Error(err))
+		return nil, domain.ErrUnexpected
+	}
+
+	authorization := &domain.DeviceAuthorization{
+		DeviceCodeHash: crypto.HashToken(deviceCode),
+		UserCodeHash:   crypto.HashToken([]byte(userCode)),
+		ClientID:       client.ID,
+		Scopes:         scopes,
+		Status:         domain.DeviceAuthorizationPending,
+		Interval:       s.devicePollInterval,
+		ExpiresAt:      time.Now().UTC().Add(s.deviceCodeTTL),
+	}
+
+	if err := s.deviceRepo.Create(ctx, authorization); err != nil {
+		return nil, domain.ErrUnexpected
+	}
+
+	s.logger.Info("Device authorization started", zap.String("client_id", client.ID), zap.String("ip", ip))
+
+	formatted := oauth.FormatUserCode(userCode)
+
+	return &domain.DeviceCode{
+		DeviceCode:              base64.RawURLEncoding.EncodeToString(deviceCode),
+		UserCode:                formatted,
+		VerificationURI:         s.deviceVerificationURL,
+		VerificationURIComplete: oauth.RedirectURL(s.deviceVerificationURL, url.Values{"user_code": {formatted}}),
+		ExpiresIn:               s.deviceCodeTTL,
+		Interval:                s.devicePollInterval,
+	}, nil
+}
+
+// failUserCode учитывает неудачную попытку ввода пользовательского кода.
+// Неудачи учитываются в оценке риска, так как короткий код можно пытаться подобрать.
+func (s *AuthServiceImpl) failUserCode(ctx context.Context, guid uuid.UUID, ip, userAgent string) {
+	s.logger.Debug("Invalid user code", zap.String("ip", ip))
+	s.recordFailure(ip)
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventLoginFailed,
+		GUID:      guid,
+		IP:        ip,
+		UserAgent: userAgent,
+		Reason:    domain.FailureReasonBadUserCode,
+	})
+}
+
+// pendingDeviceAuthorization возвращает ожидающий подтверждения запрос по введенному пользователем коду.
+// Возвращает domain.ErrInvalidUserCode, если код неверен, истек или уже подтвержден.
+func (s *AuthServiceImpl) pendingDeviceAuthorization(ctx context.Context, userCode, ip, userAgent string) (*domain.DeviceAuthorization, error) {
+	if s.deviceRepo == nil {
+		return nil, domain.ErrDeviceFlowDisabled
+	}
+
+	normalized, ok := oauth.NormalizeUserCode(userCode)
+	if !ok {
+		s.failUserCode(ctx, uuid.Nil, ip, userAgent)
+		return nil, domain.ErrInvalidUserCode
+	}
+
+	authorization, err := s.deviceRepo.GetPendingByUserCode(ctx, crypto.HashToken([]byte(normalized)), time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidUserCode) {
+			s.failUserCode(ctx, uuid.Nil, ip, userAgent)
+			return nil, domain.ErrInvalidUserCode
+		}
+		return nil, domain.ErrUnexpected
+	}
+
+	return authorization, nil
+}
+
+// LookupDeviceAuthorization проверяет введенный пользователем код и возвращает клиента, запросившего доступ.
+// Возвращает domain.ErrInvalidUserCode, если код неверен, истек или уже подтвержден.
+func (s *AuthServiceImpl) LookupDeviceAuthorization(ctx context.Context, userCode, ip, userAgent string) (*domain.OAuthClient, error) {
+	authorization, err := s.pendingDeviceAuthorization(ctx, userCode, ip, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := s.oauthClient(ctx, authorization.ClientID)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidClient) {
+			return nil, domain.ErrInvalidUserCode
+		}
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// completeDeviceAuthorization сохраняет решение пользователя по запросу авторизации устройства.
+func (s *AuthServiceImpl) completeDeviceAuthorization(ctx context.Context, authorization *domain.DeviceAuthorization, status domain.DeviceAuthorizationStatus, guid uuid.UUID, methods ...domain.AuthMethod) error {
+	err := s.deviceRepo.Complete(ctx, authorization.UserCodeHash, status, guid, methods, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidUserCode) {
+			return err
+		}
+		return domain.ErrUnexpected
+	}
+
+	s.logger.Info("Device authorization completed", zap.String("client_id", authorization.ClientID),
+		zap.String("status", string(status)), zap.String("guid", guid.String()))
+	return nil
+}
+
+// ApproveDeviceWithPassword аутентифицирует пользователя на странице подтверждения по email и паролю
+// и разрешает устройству доступ. Если у пользователя подключен второй фактор, доступ не разрешается,
+// а возвращается MFA-челлендж, который подтверждается через ApproveDeviceWithMFA.
+// Возвращает domain.ErrInvalidUserCode, если код неверен, истек или уже подтвержден,
+// и domain.ErrInvalidCredentials при неверных email или пароле.
+func (s *AuthServiceImpl) ApproveDeviceWithPassword(ctx context.Context, userCode, email, plainPassword, ip, userAgent string) (*domain.AuthorizationGrant, error) {
+	authorization, err := s.pendingDeviceAuthorization(ctx, userCode, ip, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	guid, _, err := s.verifyPassword(ctx, email, plainPassword, ip, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.mfaEnabled(ctx, guid)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabled {
+		challenge, err := s.issueMFAChallenge(ctx, guid, ip, userAgent, domain.AuthMethodPassword)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.AuthorizationGrant{MFAToken: challenge.MFAToken, MFAExpiresAt: challenge.MFAExpiresAt}, nil
+	}
+
+	if err := s.completeDeviceAuthorization(ctx, authorization, domain.DeviceAuthorizationApproved, guid, domain.AuthMethodPassword); err != nil {
+		return nil, err
+	}
+	return &domain.AuthorizationGrant{}, nil
+}
+
+// ApproveDeviceWithMFA проверяет код TOTP для MFA-челленджа, выданного ApproveDeviceWithPassword,
+// и разрешает устройству доступ.
+// Возвращает domain.ErrInvalidMFAToken, если челлендж не найден или истек, и domain.ErrInvalidMFACode, если код неверен.
+func (s *AuthServiceImpl) ApproveDeviceWithMFA(ctx context.Context, userCode, mfaToken, code, ip, userAgent string) error {
+	authorization, err := s.pendingDeviceAuthorization(ctx, userCode, ip, userAgent)
+	if err != nil {
+		return err
+	}
+
+	challenge, err := s.verifyTOTPChallenge(ctx, mfaToken, code, ip, userAgent)
+	if err != nil {
+		return err
+	}
+
+	return s.completeDeviceAuthorization(ctx, authorization, domain.DeviceAuthorizationApproved, challenge.UserID, challenge.Method, domain.AuthMethodTOTP)
+}
+
+// DenyDeviceAuthorization отклоняет запрос авторизации устройства. Устройство получит access_denied при следующем опросе.
+// Возвращает domain.ErrInvalidUserCode, если код неверен, истек или уже подтвержден.
+func (s *AuthServiceImpl) DenyDeviceAuthorization(ctx context.Context, userCode, ip, userAgent string) error {
+	authorization, err := s.pendingDeviceAuthorization(ctx, userCode, ip, userAgent)
+	if err != nil {
+		return err
+	}
+
+	return s.completeDeviceAuthorization(ctx, authorization, domain.DeviceAuthorizationDenied, uuid.Nil)
+}
+
+// failDeviceCode учитывает неудачную попытку обмена кода устройства.
+func (s *AuthServiceImpl) failDeviceCode(ctx context.Context, clientID, ip, userAgent string) {
+	s.logger.Debug("Invalid device code", zap.String("client_id", clientID))
+	s.recordFailure(ip)
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventLoginFailed,
+		IP:        ip,
+		UserAgent: userAgent,
+		Reason:    domain.FailureReasonBadDeviceCode,
+		Details:   withClientID(nil, clientID),
+	})
+}
+
+// ExchangeDeviceCode обменивает код устройства на пару токенов, когда пользователь разрешил доступ
+// (grant_type=urn:ietf:params:oauth:grant-type:device_code). Устройство опрашивает эндпоинт не чаще интервала,
+// выданного вместе с кодом, каждый слишком частый опрос увеличивает интервал на deviceSlowDownStep.
+// Refresh токен выдается, только если клиенту разрешен grant_type=refresh_token.
+// Пока пользователь не принял решение, возвращается domain.ErrAuthorizationPending, при слишком частом опросе -
+// domain.ErrSlowDown, после истечения кода - domain.ErrExpiredDeviceCode, после отказа - domain.ErrAccessDenied.
+// Возвращает domain.ErrInvalidGrant, если код неверен, уже обменян или выдан другому клиенту.
+func (s *AuthServiceImpl) ExchangeDeviceCode(ctx context.Context, creds domain.ClientCredentials, deviceCode, ip, userAgent string) (*domain.UserAuth, error) {
+	if s.deviceRepo == nil {
+		return nil, domain.ErrUnsupportedGrantType
+	}
+
+	client, err := s.authenticateClient(ctx, creds, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	if !client.AllowsGrant(domain.GrantTypeDeviceCode) {
+		return nil, domain.ErrUnauthorizedClient
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(deviceCode)
+	if err != nil || len(raw) != deviceCodeLength {
+		s.failDeviceCode(ctx, client.ID, ip, userAgent)
+		return nil, domain.ErrInvalidGrant
+	}
+	codeHash := crypto.HashToken(raw)
+
+	now := time.Now().UTC()
+	authorization, err := s.deviceRepo.Poll(ctx, codeHash, now)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidGrant) {
+			s.failDeviceCode(ctx, client.ID, ip, userAgent)
+			return nil, domain.ErrInvalidGrant
+		}
+		return nil, domain.ErrUnexpected
+	}
+
+	if authorization.ClientID != client.ID {
+		s.failDeviceCode(ctx, client.ID, ip, userAgent)
+		return nil, domain.ErrInvalidGrant
+	}
+
+	if !authorization.ExpiresAt.After(now) {
+		return nil, domain.ErrExpiredDeviceCode
+	}
+
+	if !authorization.LastPolledAt.IsZero() && now.Sub(authorization.LastPolledAt) < authorization.Interval {
+		if err := s.deviceRepo.SlowDown(ctx, codeHash, authorization.Interval+deviceSlowDownStep); err != nil {
+			return nil, domain.ErrUnexpected
+		}
+		return nil, domain.ErrSlowDown
+	}
+
+	if authorization.Status == domain.DeviceAuthorizationPending {
+		return nil, domain.ErrAuthorizationPending
+	}
+
+	// Решение пользователя сообщается устройству один раз
+	authorization, err = s.deviceRepo.Consume(ctx, codeHash)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidGrant) {
+			return nil, domain.ErrInvalidGrant
+		}
+		return nil, domain.ErrUnexpected
+	}
+
+	if authorization.Status != domain.DeviceAuthorizationApproved {
+		return nil, domain.ErrAccessDenied
+	}
+
+	userAuth, err := s.issueClientTokens(ctx, client, authorization.Scopes, authorization.UserID, uuid.New(), ip, userAgent, authorization.AuthMethods)
+	if err != nil {
+		return nil, err
+	}
+	userAuth.ExpiresIn = s.clientAccessTTL(client)
+	if !client.AllowsGrant(domain.GrantTypeRefreshToken) {
+		userAuth.RefreshToken = ""
+	}
+
+	return userAuth, nil
+}
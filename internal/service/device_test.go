@@ -0,0 +1,397 @@
+package service_test
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/oauth"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/password"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const testUserCode = "BDFH-KLMN"
+
+// testDeviceClient возвращает публичного клиента, которому разрешены авторизация устройства и обновление токенов.
+func testDeviceClient() *domain.OAuthClient {
+	return &domain.OAuthClient{
+		ID:         testClientID,
+		Name:       "CLI",
+		GrantTypes: []domain.GrantType{domain.GrantTypeDeviceCode, domain.GrantTypeRefreshToken},
+		Scopes:     []string{"profile", "email"},
+	}
+}
+
+func pendingDeviceAuthorization() *domain.DeviceAuthorization {
+	return &domain.DeviceAuthorization{
+		DeviceCodeHash: "device",
+		UserCodeHash:   crypto.HashToken([]byte("BDFHKLMN")),
+		ClientID:       testClientID,
+		Scopes:         []string{"profile"},
+		Status:         domain.DeviceAuthorizationPending,
+		Interval:       5 * time.Second,
+		ExpiresAt:      time.Now().Add(time.Minute),
+	}
+}
+
+func TestAuthService_StartDeviceAuthorization(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testDeviceClient())
+
+		var stored *domain.DeviceAuthorization
+		m.deviceRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, authorization *domain.DeviceAuthorization) error {
+			stored = authorization
+			return nil
+		})
+
+		deviceCode, err := svc.StartDeviceAuthorization(context.Background(), domain.ClientCredentials{ID: testClientID}, "profile", "127.0.0.1", "")
+		require.NoError(t, err)
+		assert.Equal(t, 10*time.Minute, deviceCode.ExpiresIn)
+		assert.Equal(t, 5*time.Second, deviceCode.Interval)
+		assert.Equal(t, testVerificationURI, deviceCode.VerificationURI)
+		assert.Equal(t, testVerificationURI+"?user_code="+deviceCode.UserCode, deviceCode.VerificationURIComplete)
+
+		// В базе хранятся только хеши кодов
+		raw, err := base64.RawURLEncoding.DecodeString(deviceCode.DeviceCode)
+		require.NoError(t, err)
+		assert.Equal(t, crypto.HashToken(raw), stored.DeviceCodeHash)
+		userCode, ok := oauth.NormalizeUserCode(deviceCode.UserCode)
+		require.True(t, ok)
+		assert.Equal(t, crypto.HashToken([]byte(userCode)), stored.UserCodeHash)
+		assert.Equal(t, domain.DeviceAuthorizationPending, stored.Status)
+		assert.Equal(t, []string{"profile"}, stored.Scopes)
+	})
+
+	t.Run("grant not allowed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, _ := newOAuthServiceWithClient(t, ctrl, testOAuthClient())
+
+		_, err := svc.StartDeviceAuthorization(context.Background(), domain.ClientCredentials{ID: testClientID}, "", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrUnauthorizedClient)
+	})
+
+	t.Run("scope not allowed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, _ := newOAuthServiceWithClient(t, ctrl, testDeviceClient())
+
+		_, err := svc.StartDeviceAuthorization(context.Background(), domain.ClientCredentials{ID: testClientID}, "admin", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidScope)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, _ := newPasswordService(ctrl)
+
+		_, err := svc.StartDeviceAuthorization(context.Background(), domain.ClientCredentials{ID: testClientID}, "", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrDeviceFlowDisabled)
+	})
+}
+
+func TestAuthService_LookupDeviceAuthorization(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testDeviceClient())
+
+		m.deviceRepo.EXPECT().GetPendingByUserCode(gomock.Any(), crypto.HashToken([]byte("BDFHKLMN")), gomock.Any()).
+			Return(pendingDeviceAuthorization(), nil)
+
+		client, err := svc.LookupDeviceAuthorization(context.Background(), " bdfh-klmn", "127.0.0.1", "")
+		require.NoError(t, err)
+		assert.Equal(t, "CLI", client.Name)
+	})
+
+	t.Run("malformed code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testDeviceClient())
+
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonBadUserCode)
+
+		_, err := svc.LookupDeviceAuthorization(context.Background(), "ABCD-1234", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidUserCode)
+	})
+
+	t.Run("unknown or used code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testDeviceClient())
+
+		m.deviceRepo.EXPECT().GetPendingByUserCode(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, domain.ErrInvalidUserCode)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonBadUserCode)
+
+		_, err := svc.LookupDeviceAuthorization(context.Background(), testUserCode, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidUserCode)
+	})
+}
+
+func TestAuthService_ApproveDevice(t *testing.T) {
+	hash, err := password.Hash("correct horse")
+	require.NoError(t, err)
+
+	t.Run("password", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testDeviceClient())
+		guid := uuid.New()
+		authorization := pendingDeviceAuthorization()
+
+		m.deviceRepo.EXPECT().GetPendingByUserCode(gomock.Any(), authorization.UserCodeHash, gomock.Any()).Return(authorization, nil)
+		m.credentialRepo.EXPECT().GetByEmail(gomock.Any(), "user@example.com").Return(guid, hash, nil)
+		m.totpRepo.EXPECT().Get(gomock.Any(), guid).Return(nil, domain.ErrTOTPNotEnrolled)
+		m.deviceRepo.EXPECT().Complete(gomock.Any(), authorization.UserCodeHash, domain.DeviceAuthorizationApproved, guid,
+			[]domain.AuthMethod{domain.AuthMethodPassword}, gomock.Any()).Return(nil)
+
+		grant, err := svc.ApproveDeviceWithPassword(context.Background(), testUserCode, "user@example.com", "correct horse", "127.0.0.1", "")
+		require.NoError(t, err)
+		assert.False(t, grant.MFARequired())
+	})
+
+	t.Run("mfa required", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testDeviceClient())
+		guid := uuid.New()
+
+		m.deviceRepo.EXPECT().GetPendingByUserCode(gomock.Any(), gomock.Any(), gomock.Any()).Return(pendingDeviceAuthorization(), nil)
+		m.credentialRepo.EXPECT().GetByEmail(gomock.Any(), "user@example.com").Return(guid, hash, nil)
+		m.totpRepo.EXPECT().Get(gomock.Any(), guid).Return(m.enrollment(t, guid, make([]byte, totp.SecretLength), true), nil)
+		m.challengeRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventMFAChallenge, "")
+
+		grant, err := svc.ApproveDeviceWithPassword(context.Background(), testUserCode, "user@example.com", "correct horse", "127.0.0.1", "")
+		require.NoError(t, err)
+		assert.True(t, grant.MFARequired())
+	})
+
+	t.Run("mfa", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		secret := []byte("12345678901234567890")
+		token := []byte("challenge-token")
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testDeviceClient())
+		authorization := pendingDeviceAuthorization()
+		challenge := &domain.MFAChallenge{
+			ID:        uuid.New(),
+			TokenHash: crypto.HashToken(token),
+			UserID:    uuid.New(),
+			Method:    domain.AuthMethodPassword,
+			ExpiresAt: time.Now().Add(time.Minute),
+		}
+
+		m.deviceRepo.EXPECT().GetPendingByUserCode(gomock.Any(), gomock.Any(), gomock.Any()).Return(authorization, nil)
+		m.challengeRepo.EXPECT().GetByTokenHash(gomock.Any(), challenge.TokenHash, gomock.Any()).Return(challenge, nil)
+		m.totpRepo.EXPECT().Get(gomock.Any(), challenge.UserID).Return(m.enrollment(t, challenge.UserID, secret, true), nil)
+		m.challengeRepo.EXPECT().Delete(gomock.Any(), challenge.ID).Return(nil)
+		m.totpRepo.EXPECT().UseStep(gomock.Any(), challenge.UserID, gomock.Any()).Return(true, nil)
+		m.deviceRepo.EXPECT().Complete(gomock.Any(), authorization.UserCodeHash, domain.DeviceAuthorizationApproved, challenge.UserID,
+			[]domain.AuthMethod{domain.AuthMethodPassword, domain.AuthMethodTOTP}, gomock.Any()).Return(nil)
+
+		code := totp.Code(secret, totp.Step(time.Now()))
+		err := svc.ApproveDeviceWithMFA(context.Background(), testUserCode, base64.RawURLEncoding.EncodeToString(token), code, "127.0.0.1", "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testDeviceClient())
+		authorization := pendingDeviceAuthorization()
+
+		m.deviceRepo.EXPECT().GetPendingByUserCode(gomock.Any(), gomock.Any(), gomock.Any()).Return(authorization, nil)
+		m.deviceRepo.EXPECT().Complete(gomock.Any(), authorization.UserCodeHash, domain.DeviceAuthorizationDenied, uuid.Nil,
+			gomock.Len(0), gomock.Any()).Return(nil)
+
+		assert.NoError(t, svc.DenyDeviceAuthorization(context.Background(), testUserCode, "127.0.0.1", ""))
+	})
+
+	t.Run("completed concurrently", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testDeviceClient())
+
+		m.deviceRepo.EXPECT().GetPendingByUserCode(gomock.Any(), gomock.Any(), gomock.Any()).Return(pendingDeviceAuthorization(), nil)
+		m.deviceRepo.EXPECT().Complete(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(domain.ErrInvalidUserCode)
+
+		err := svc.DenyDeviceAuthorization(context.Background(), testUserCode, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidUserCode)
+	})
+}
+
+func TestAuthService_ExchangeDeviceCode(t *testing.T) {
+	raw := make([]byte, 32)
+	deviceCode := base64.RawURLEncoding.EncodeToString(raw)
+	codeHash := crypto.HashToken(raw)
+	creds := domain.ClientCredentials{ID: testClientID}
+
+	t.Run("approved", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testDeviceClient())
+		guid := uuid.New()
+
+		polled := pendingDeviceAuthorization()
+		polled.Status = domain.DeviceAuthorizationApproved
+		polled.LastPolledAt = time.Now().Add(-10 * time.Second)
+		consumed := *polled
+		consumed.UserID = guid
+		consumed.AuthMethods = []domain.AuthMethod{domain.AuthMethodPassword}
+
+		m.deviceRepo.EXPECT().Poll(gomock.Any(), codeHash, gomock.Any()).Return(polled, nil)
+		m.deviceRepo.EXPECT().Consume(gomock.Any(), codeHash).Return(&consumed, nil)
+		m.tokenManager.EXPECT().Generate(guid, gomock.Any(), "127.0.0.1", gomock.Any()).
+			DoAndReturn(func(guid, jti uuid.UUID, ip string, opts auth.TokenOptions) (string, error) {
+				assert.Equal(t, testClientID, opts.ClientID)
+				assert.Equal(t, []string{"profile"}, opts.Scopes)
+				assert.Equal(t, []string{domain.AMRPassword}, opts.AMR)
+				return "access", nil
+			})
+		m.tokenManager.EXPECT().TTL().Return(15 * time.Minute)
+		m.tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(nil)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventTokenIssued, "")
+
+		result, err := svc.ExchangeDeviceCode(context.Background(), creds, deviceCode, "127.0.0.1", "")
+		require.NoError(t, err)
+		assert.Equal(t, "access", result.AccessToken)
+		assert.NotEmpty(t, result.RefreshToken)
+		assert.Equal(t, 15*time.Minute, result.ExpiresIn)
+	})
+
+	t.Run("pending", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testDeviceClient())
+
+		m.deviceRepo.EXPECT().Poll(gomock.Any(), codeHash, gomock.Any()).Return(pendingDeviceAuthorization(), nil)
+
+		_, err := svc.ExchangeDeviceCode(context.Background(), creds, deviceCode, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrAuthorizationPending)
+	})
+
+	t.Run("polling too fast", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testDeviceClient())
+
+		polled := pendingDeviceAuthorization()
+		polled.LastPolledAt = time.Now().Add(-time.Second)
+
+		m.deviceRepo.EXPECT().Poll(gomock.Any(), codeHash, gomock.Any()).Return(polled, nil)
+		m.deviceRepo.EXPECT().SlowDown(gomock.Any(), codeHash, 10*time.Second).Return(nil)
+
+		_, err := svc.ExchangeDeviceCode(context.Background(), creds, deviceCode, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrSlowDown)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testDeviceClient())
+
+		polled := pendingDeviceAuthorization()
+		polled.ExpiresAt = time.Now().Add(-time.Second)
+
+		m.deviceRepo.EXPECT().Poll(gomock.Any(), codeHash, gomock.Any()).Return(polled, nil)
+
+		_, err := svc.ExchangeDeviceCode(context.Background(), creds, deviceCode, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrExpiredDeviceCode)
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testDeviceClient())
+
+		polled := pendingDeviceAuthorization()
+		polled.Status = domain.DeviceAuthorizationDenied
+
+		m.deviceRepo.EXPECT().Poll(gomock.Any(), codeHash, gomock.Any()).Return(polled, nil)
+		m.deviceRepo.EXPECT().Consume(gomock.Any(), codeHash).Return(polled, nil)
+
+		_, err := svc.ExchangeDeviceCode(context.Background(), creds, deviceCode, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrAccessDenied)
+	})
+
+	t.Run("code of another client", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testDeviceClient())
+
+		polled := pendingDeviceAuthorization()
+		polled.ClientID = "other"
+
+		m.deviceRepo.EXPECT().Poll(gomock.Any(), codeHash, gomock.Any()).Return(polled, nil)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonBadDeviceCode)
+
+		_, err := svc.ExchangeDeviceCode(context.Background(), creds, deviceCode, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidGrant)
+	})
+
+	t.Run("unknown or used code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testDeviceClient())
+
+		m.deviceRepo.EXPECT().Poll(gomock.Any(), codeHash, gomock.Any()).Return(nil, domain.ErrInvalidGrant)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonBadDeviceCode)
+
+		_, err := svc.ExchangeDeviceCode(context.Background(), creds, deviceCode, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidGrant)
+	})
+
+	t.Run("malformed code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testDeviceClient())
+
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonBadDeviceCode)
+
+		_, err := svc.ExchangeDeviceCode(context.Background(), creds, strings.Repeat("a", 10), "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidGrant)
+	})
+
+	t.Run("grant not allowed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, _ := newOAuthServiceWithClient(t, ctrl, testOAuthClient())
+
+		_, err := svc.ExchangeDeviceCode(context.Background(), creds, deviceCode, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrUnauthorizedClient)
+	})
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
+	"github.com/maksemen2/medods-task/internal/pkg/mail"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	"time"
+)
+
+const (
+	defaultEmailVerificationTTL    = 24 * time.Hour // Время жизни токена подтверждения email по умолчанию
+	defaultEmailVerificationResend = time.Minute    // Минимальный интервал между отправками письма подтверждения по умолчанию
+	emailVerificationTokenLength   = 32             // Длина токена подтверждения email в байтах
+)
+
+// WithEmailVerification включает подтверждение email.
+// Письмо со ссылкой verifyURL и одноразовым токеном отправляется через sender при регистрации и по запросу,
+// но не чаще одного раза в resendInterval. Токен действует в течение ttl.
+// Нулевые ttl и resendInterval заменяются значениями по умолчанию.
+func WithEmailVerification(verificationRepo repository.IEmailVerificationRepo, sender mail.Sender, verifyURL string, ttl, resendInterval time.Duration) UserServiceOption {
+	return func(s *UserServiceImpl) {
+		s.verificationRepo = verificationRepo
+		s.mailSender = sender
+		s.verificationURL = verifyURL
+		if ttl > 0 {
+			s.verificationTTL = ttl
+		}
+		if resendInterval > 0 {
+			s.verificationResend = resendInterval
+		}
+	}
+}
+
+// sendVerification выдает новый токен подтверждения email и асинхронно отправляет письмо со ссылкой.
+// В базе данных хранится только хеш токена.
+func (s *UserServiceImpl) sendVerification(ctx context.Context, guid uuid.UUID, email string) error {
+	token := make([]byte, emailVerificationTokenLength)
+	if _, err := rand.Read(token); err != nil {
+		s.logger.Error("Error generating email verification token", zap.Error(err))
+		return domain.ErrUnexpected
+	}
+
+	now := time.Now().UTC()
+	verification := &domain.EmailVerification{
+		ID:        uuid.New(),
+		UserID:    guid,
+		Email:     email,
+		TokenHash: crypto.HashToken(token),
+		ExpiresAt: now.Add(s.verificationTTL),
+		CreatedAt: now,
+	}
+
+	if err := s.verificationRepo.Create(ctx, verification); err != nil {
+		return domain.ErrUnexpected
+	}
+
+	link := linkWithToken(s.verificationURL, base64.RawURLEncoding.EncodeToString(token))
+	sendMailAsync(ctx, s.mailSender, s.logger, guid, mail.Message{
+		To:      email,
+		Subject: "Подтверждение email",
+		Body: "Чтобы подтвердить email, перейдите по ссылке: " + link + "\n\n" +
+			"Ссылка действует до " + verification.ExpiresAt.Format(time.RFC1123) + " и может быть использована один раз.\n" +
+			"Если вы не регистрировались, просто проигнорируйте это письмо.",
+	})
+
+	s.logger.Info("Email verification sent", zap.String("guid", guid.String()))
+
+	return nil
+}
+
+// RequestEmailVerification повторно отправляет письмо подтверждения email.
+// Для незарегистрированного или уже подтвержденного email, а также при повторном запросе раньше,
+// чем через интервал повторной отправки, письмо не отправляется, но ответ не отличается от успешного.
+// Возвращает domain.ErrInvalidEmail, если email некорректен.
+func (s *UserServiceImpl) RequestEmailVerification(ctx context.Context, email string) error {
+	if s.verificationRepo == nil {
+		return domain.ErrEmailVerificationDisabled
+	}
+
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return err
+	}
+
+	guid, err := s.userRepo.GetGUIDByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			s.logger.Debug("Email verification requested for unknown email")
+			return nil
+		}
+		return domain.ErrUnexpected
+	}
+
+	verified, err := s.userRepo.IsEmailVerified(ctx, guid)
+	if err != nil {
+		return domain.ErrUnexpected
+	}
+	if verified {
+		s.logger.Debug("Email verification requested for verified email", zap.String("guid", guid.String()))
+		return nil
+	}
+
+	lastSent, err := s.verificationRepo.LastCreatedAt(ctx, guid)
+	if err != nil {
+		return domain.ErrUnexpected
+	}
+	if time.Since(lastSent) < s.verificationResend {
+		s.logger.Debug("Email verification resend throttled", zap.String("guid", guid.String()))
+		return nil
+	}
+
+	return s.sendVerification(ctx, guid, email)
+}
+
+// VerifyEmail подтверждает email по токену из письма. Токен одноразовый:
+// после подтверждения он и остальные токены пользователя удаляются.
+// Возвращает domain.ErrInvalidVerificationToken, если токен некорректен, истек, уже использован
+// или email пользователя изменился после его выдачи.
+func (s *UserServiceImpl) VerifyEmail(ctx context.Context, token string) error {
+	if s.verificationRepo == nil {
+		return domain.ErrEmailVerificationDisabled
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != emailVerificationTokenLength {
+		return domain.ErrInvalidVerificationToken
+	}
+
+	guid, err := s.verificationRepo.Consume(ctx, crypto.HashToken(raw), time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidVerificationToken) {
+			return domain.ErrInvalidVerificationToken
+		}
+		return domain.ErrUnexpected
+	}
+
+	s.logger.Info("Email verified", zap.String("guid", guid.String()))
+
+	return nil
+}
@@ -0,0 +1,262 @@
+package service_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
+	"github.com/maksemen2/medods-task/internal/pkg/mail"
+	mock_mail "github.com/maksemen2/medods-task/internal/pkg/mail/mocks"
+	mock_repository "github.com/maksemen2/medods-task/internal/repository/mocks"
+	"github.com/maksemen2/medods-task/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+const testVerifyURL = "https://example.com/email/verify"
+
+type emailVerificationMocks struct {
+	userRepo         *mock_repository.MockIUserRepo
+	verificationRepo *mock_repository.MockIEmailVerificationRepo
+	sender           *mock_mail.MockSender
+}
+
+func newEmailVerificationService(ctrl *gomock.Controller) (service.IUserService, emailVerificationMocks) {
+	m := emailVerificationMocks{
+		userRepo:         mock_repository.NewMockIUserRepo(ctrl),
+		verificationRepo: mock_repository.NewMockIEmailVerificationRepo(ctrl),
+		sender:           mock_mail.NewMockSender(ctrl),
+	}
+	svc := service.NewUserServiceImpl(m.userRepo, zap.NewNop(),
+		service.WithEmailVerification(m.verificationRepo, m.sender, testVerifyURL, time.Hour, time.Minute))
+	return svc, m
+}
+
+// expectVerificationMail ожидает сохранение токена подтверждения и отправку письма.
+// Возвращает канал, в который попадет отправленное письмо, и сохраненный токен.
+func expectVerificationMail(t *testing.T, m emailVerificationMocks, guid uuid.UUID, email string) (<-chan mail.Message, *domain.EmailVerification) {
+	stored := &domain.EmailVerification{}
+	m.verificationRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, verification *domain.EmailVerification) error {
+		assert.Equal(t, guid, verification.UserID)
+		assert.Equal(t, email, verification.Email)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), verification.ExpiresAt, time.Minute)
+		*stored = *verification
+		return nil
+	})
+
+	sent := make(chan mail.Message, 1)
+	m.sender.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, msg mail.Message) error {
+		sent <- msg
+		return nil
+	})
+	return sent, stored
+}
+
+// verificationTokenFromMail извлекает токен подтверждения из ссылки в письме.
+func verificationTokenFromMail(t *testing.T, msg mail.Message) string {
+	link, err := url.Parse(linkPattern.FindString(msg.Body))
+	require.NoError(t, err)
+	assert.Equal(t, testVerifyURL, link.Scheme+"://"+link.Host+link.Path)
+	return link.Query().Get("token")
+}
+
+func receiveMail(t *testing.T, sent <-chan mail.Message) mail.Message {
+	select {
+	case msg := <-sent:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("email was not sent")
+		return mail.Message{}
+	}
+}
+
+func TestUserService_RegisterSendsVerification(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc, m := newEmailVerificationService(ctrl)
+
+	var guid uuid.UUID
+	m.userRepo.EXPECT().Create(gomock.Any(), gomock.Any(), "").DoAndReturn(func(ctx context.Context, user *domain.User, passwordHash string) error {
+		guid = user.GUID
+		return nil
+	})
+	m.verificationRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, verification *domain.EmailVerification) error {
+		assert.Equal(t, guid, verification.UserID)
+		assert.Equal(t, "user@example.com", verification.Email)
+		return nil
+	})
+	sent := make(chan mail.Message, 1)
+	m.sender.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, msg mail.Message) error {
+		sent <- msg
+		return nil
+	})
+
+	_, err := svc.Register(context.Background(), "User@Example.com", "", "")
+	require.NoError(t, err)
+
+	msg := receiveMail(t, sent)
+	assert.Equal(t, "user@example.com", msg.To)
+	assert.NotEmpty(t, verificationTokenFromMail(t, msg))
+}
+
+func TestUserService_RequestEmailVerification(t *testing.T) {
+	t.Run("unverified email", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newEmailVerificationService(ctrl)
+		guid := uuid.New()
+
+		m.userRepo.EXPECT().GetGUIDByEmail(gomock.Any(), "user@example.com").Return(guid, nil)
+		m.userRepo.EXPECT().IsEmailVerified(gomock.Any(), guid).Return(false, nil)
+		m.verificationRepo.EXPECT().LastCreatedAt(gomock.Any(), guid).Return(time.Now().Add(-time.Hour), nil)
+		sent, stored := expectVerificationMail(t, m, guid, "user@example.com")
+
+		require.NoError(t, svc.RequestEmailVerification(context.Background(), " User@Example.com "))
+
+		token, err := base64.RawURLEncoding.DecodeString(verificationTokenFromMail(t, receiveMail(t, sent)))
+		require.NoError(t, err)
+		assert.Equal(t, crypto.HashToken(token), stored.TokenHash)
+	})
+
+	t.Run("unknown email", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newEmailVerificationService(ctrl)
+		m.userRepo.EXPECT().GetGUIDByEmail(gomock.Any(), "user@example.com").Return(uuid.Nil, domain.ErrUserNotFound)
+
+		assert.NoError(t, svc.RequestEmailVerification(context.Background(), "user@example.com"))
+	})
+
+	t.Run("already verified", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newEmailVerificationService(ctrl)
+		guid := uuid.New()
+		m.userRepo.EXPECT().GetGUIDByEmail(gomock.Any(), "user@example.com").Return(guid, nil)
+		m.userRepo.EXPECT().IsEmailVerified(gomock.Any(), guid).Return(true, nil)
+
+		assert.NoError(t, svc.RequestEmailVerification(context.Background(), "user@example.com"))
+	})
+
+	t.Run("throttled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newEmailVerificationService(ctrl)
+		guid := uuid.New()
+		m.userRepo.EXPECT().GetGUIDByEmail(gomock.Any(), "user@example.com").Return(guid, nil)
+		m.userRepo.EXPECT().IsEmailVerified(gomock.Any(), guid).Return(false, nil)
+		m.verificationRepo.EXPECT().LastCreatedAt(gomock.Any(), guid).Return(time.Now().Add(-10*time.Second), nil)
+
+		assert.NoError(t, svc.RequestEmailVerification(context.Background(), "user@example.com"))
+	})
+
+	t.Run("invalid email", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, _ := newEmailVerificationService(ctrl)
+		assert.ErrorIs(t, svc.RequestEmailVerification(context.Background(), "user"), domain.ErrInvalidEmail)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc := service.NewUserServiceImpl(mock_repository.NewMockIUserRepo(ctrl), zap.NewNop())
+		assert.ErrorIs(t, svc.RequestEmailVerification(context.Background(), "user@example.com"), domain.ErrEmailVerificationDisabled)
+	})
+}
+
+func TestUserService_VerifyEmail(t *testing.T) {
+	token := make([]byte, 32)
+	encoded := base64.RawURLEncoding.EncodeToString(token)
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newEmailVerificationService(ctrl)
+		m.verificationRepo.EXPECT().Consume(gomock.Any(), crypto.HashToken(token), gomock.Any()).Return(uuid.New(), nil)
+
+		assert.NoError(t, svc.VerifyEmail(context.Background(), encoded))
+	})
+
+	t.Run("used or expired token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newEmailVerificationService(ctrl)
+		m.verificationRepo.EXPECT().Consume(gomock.Any(), crypto.HashToken(token), gomock.Any()).Return(uuid.Nil, domain.ErrInvalidVerificationToken)
+
+		assert.ErrorIs(t, svc.VerifyEmail(context.Background(), encoded), domain.ErrInvalidVerificationToken)
+	})
+
+	t.Run("malformed token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, _ := newEmailVerificationService(ctrl)
+		assert.ErrorIs(t, svc.VerifyEmail(context.Background(), "not a token"), domain.ErrInvalidVerificationToken)
+		assert.ErrorIs(t, svc.VerifyEmail(context.Background(), "c2hvcnQ"), domain.ErrInvalidVerificationToken)
+	})
+}
+
+func TestAuthService_EmailVerifiedClaim(t *testing.T) {
+	verified, unverified := true, false
+
+	t.Run("claim added", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		_, m := newPasswordService(ctrl)
+		svc := service.NewAuthServiceImpl(m.userRepo, m.tokenRepo, m.tokenManager, zap.NewNop(), time.Hour,
+			service.WithEmailVerifiedClaim(false))
+		guid := uuid.New()
+
+		m.userRepo.EXPECT().Exists(gomock.Any(), guid).Return(true, nil)
+		m.userRepo.EXPECT().IsEmailVerified(gomock.Any(), guid).Return(false, nil)
+		m.tokenManager.EXPECT().Generate(guid, gomock.Any(), gomock.Any(), auth.TokenOptions{EmailVerified: &unverified}).Return("access", nil)
+		m.tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(nil)
+
+		_, err := svc.AuthenticateUser(context.Background(), guid, "127.0.0.1", "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("verified email required", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		_, m := newPasswordService(ctrl)
+		svc := service.NewAuthServiceImpl(m.userRepo, m.tokenRepo, m.tokenManager, zap.NewNop(), time.Hour,
+			service.WithAuditRepo(m.auditRepo), service.WithEmailVerifiedClaim(true))
+		guid := uuid.New()
+
+		m.userRepo.EXPECT().Exists(gomock.Any(), guid).Return(true, nil).Times(2)
+		m.userRepo.EXPECT().IsEmailVerified(gomock.Any(), guid).Return(false, nil)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonEmailUnverified)
+
+		_, err := svc.AuthenticateUser(context.Background(), guid, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrEmailNotVerified)
+
+		m.userRepo.EXPECT().IsEmailVerified(gomock.Any(), guid).Return(true, nil).Times(2)
+		m.tokenManager.EXPECT().Generate(guid, gomock.Any(), gomock.Any(), auth.TokenOptions{EmailVerified: &verified}).Return("access", nil)
+		m.tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(nil)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventTokenIssued, "")
+
+		_, err = svc.AuthenticateUser(context.Background(), guid, "127.0.0.1", "")
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/mail"
+	"go.uber.org/zap"
+	"time"
+)
+
+const defaultMagicLinkTTL = 15 * time.Minute // Время жизни ссылки для входа по умолчанию
+
+// RequestMagicLink отправляет на email одноразовую ссылку для входа.
+// Для незарегистрированного email письмо не отправляется, но ответ не отличается от успешного,
+// чтобы по нему нельзя было определить, зарегистрирован ли email.
+// Возвращает domain.ErrInvalidEmail, если email некорректен.
+func (s *AuthServiceImpl) RequestMagicLink(ctx context.Context, email, ip, userAgent string) error {
+	if s.magicLinkSigner == nil {
+		return domain.ErrMagicLinkDisabled
+	}
+
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return err
+	}
+
+	guid, err := s.userRepo.GetGUIDByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			s.logger.Debug("Magic link requested for unknown email")
+			return nil
+		}
+		return domain.ErrUnexpected
+	}
+
+	token, claims, err := s.magicLinkSigner.Issue(guid, s.magicLinkTTL)
+	if err != nil {
+		s.logger.Error("Error issuing magic link", zap.Error(err))
+		return domain.ErrUnexpected
+	}
+
+	sendMailAsync(ctx, s.mailSender, s.logger, guid, mail.Message{
+		To:      email,
+		Subject: "Вход в аккаунт",
+		Body: "Чтобы войти, перейдите по ссылке: " + linkWithToken(s.magicLinkURL, token) + "\n\n" +
+			"Ссылка действует до " + claims.ExpiresAt.UTC().Format(time.RFC1123) + " и может быть использована один раз.\n" +
+			"Если вы не запрашивали вход, просто проигнорируйте это письмо.",
+	})
+
+	s.logger.Info("Magic link sent", zap.String("guid", guid.String()))
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventMagicLinkSent,
+		GUID:      guid,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details:   map[string]any{"link_id": claims.ID.String()},
+	})
+
+	return nil
+}
+
+// failMagicLink учитывает неудачный вход по ссылке по причине reason.
+func (s *AuthServiceImpl) failMagicLink(ctx context.Context, guid uuid.UUID, reason, ip, userAgent string) {
+	s.logger.Debug("Invalid magic link", zap.String("guid", guid.String()), zap.String("reason", reason))
+	s.recordFailure(ip)
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventLoginFailed,
+		GUID:      guid,
+		IP:        ip,
+		UserAgent: userAgent,
+		Reason:    reason,
+	})
+}
+
+// VerifyMagicLink проверяет токен ссылки для входа и выдает пару токенов так же, как AuthenticateUser.
+// Каждую ссылку можно использовать один раз. Если у пользователя подключен второй фактор,
+// вместо пары токенов возвращается MFA-челлендж.
+// Возвращает domain.ErrInvalidMagicLink, если подпись неверна, ссылка просрочена или уже использована.
+func (s *AuthServiceImpl) VerifyMagicLink(ctx context.Context, token, ip, userAgent string) (*domain.UserAuth, error) {
+	if s.magicLinkSigner == nil {
+		return nil, domain.ErrMagicLinkDisabled
+	}
+
+	claims, err := s.magicLinkSigner.Parse(token, time.Now())
+	if err != nil {
+		s.failMagicLink(ctx, uuid.Nil, domain.FailureReasonBadMagicLink, ip, userAgent)
+		return nil, domain.ErrInvalidMagicLink
+	}
+
+	consumed, err := s.magicLinkRepo.Consume(ctx, claims.ID, claims.GUID, claims.ExpiresAt)
+	if err != nil {
+		return nil, domain.ErrUnexpected
+	}
+
+	if !consumed {
+		s.failMagicLink(ctx, claims.GUID, domain.FailureReasonReusedMagicLink, ip, userAgent)
+		return nil, domain.ErrInvalidMagicLink
+	}
+
+	return s.authenticate(ctx, claims.GUID, ip, userAgent, domain.AuthMethodMagicLink)
+}
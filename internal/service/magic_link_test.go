@@ -0,0 +1,185 @@
+package service_test
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/magiclink"
+	"github.com/maksemen2/medods-task/internal/pkg/mail"
+	mock_mail "github.com/maksemen2/medods-task/internal/pkg/mail/mocks"
+	mock_repository "github.com/maksemen2/medods-task/internal/repository/mocks"
+	"github.com/maksemen2/medods-task/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+const testMagicLinkURL = "https://example.com/login/magic/verify"
+
+type magicLinkMocks struct {
+	passwordMocks
+	linkRepo *mock_repository.MockIMagicLinkRepo
+	sender   *mock_mail.MockSender
+	signer   *magiclink.Signer
+}
+
+func newMagicLinkService(ctrl *gomock.Controller) (service.IAuthService, magicLinkMocks) {
+	_, password := newPasswordService(ctrl)
+	m := magicLinkMocks{
+		passwordMocks: password,
+		linkRepo:      mock_repository.NewMockIMagicLinkRepo(ctrl),
+		sender:        mock_mail.NewMockSender(ctrl),
+		signer:        magiclink.NewSigner([]byte("secret"), "login"),
+	}
+	svc := service.NewAuthServiceImpl(m.userRepo, m.tokenRepo, m.tokenManager, zap.NewNop(), time.Hour,
+		service.WithAuditRepo(m.auditRepo),
+		service.WithMagicLink(m.signer, m.linkRepo, m.sender, testMagicLinkURL, 10*time.Minute))
+	return svc, m
+}
+
+var linkPattern = regexp.MustCompile(`https://\S+`)
+
+// tokenFromMail извлекает токен из ссылки в письме.
+func tokenFromMail(t *testing.T, msg mail.Message) string {
+	link, err := url.Parse(linkPattern.FindString(msg.Body))
+	require.NoError(t, err)
+	assert.Equal(t, testMagicLinkURL, link.Scheme+"://"+link.Host+link.Path)
+	return link.Query().Get("token")
+}
+
+func TestAuthService_RequestMagicLink(t *testing.T) {
+	t.Run("known email", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newMagicLinkService(ctrl)
+		guid := uuid.New()
+
+		sent := make(chan mail.Message, 1)
+		m.userRepo.EXPECT().GetGUIDByEmail(gomock.Any(), "user@example.com").Return(guid, nil)
+		m.sender.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, msg mail.Message) error {
+			sent <- msg
+			return nil
+		})
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventMagicLinkSent, "")
+
+		require.NoError(t, svc.RequestMagicLink(context.Background(), " User@Example.com ", "127.0.0.1", ""))
+
+		select {
+		case msg := <-sent:
+			assert.Equal(t, "user@example.com", msg.To)
+			claims, err := m.signer.Parse(tokenFromMail(t, msg), time.Now())
+			require.NoError(t, err)
+			assert.Equal(t, guid, claims.GUID)
+			assert.WithinDuration(t, time.Now().Add(10*time.Minute), claims.ExpiresAt, 2*time.Second)
+		case <-time.After(time.Second):
+			t.Fatal("email was not sent")
+		}
+	})
+
+	t.Run("unknown email looks the same", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newMagicLinkService(ctrl)
+
+		m.userRepo.EXPECT().GetGUIDByEmail(gomock.Any(), "nobody@example.com").Return(uuid.Nil, domain.ErrUserNotFound)
+
+		assert.NoError(t, svc.RequestMagicLink(context.Background(), "nobody@example.com", "127.0.0.1", ""))
+	})
+
+	t.Run("invalid email", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, _ := newMagicLinkService(ctrl)
+
+		assert.ErrorIs(t, svc.RequestMagicLink(context.Background(), "not an email", "127.0.0.1", ""), domain.ErrInvalidEmail)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, _ := newPasswordService(ctrl)
+
+		assert.ErrorIs(t, svc.RequestMagicLink(context.Background(), "user@example.com", "127.0.0.1", ""), domain.ErrMagicLinkDisabled)
+	})
+}
+
+func TestAuthService_VerifyMagicLink(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newMagicLinkService(ctrl)
+		guid := uuid.New()
+		token, claims, err := m.signer.Issue(guid, time.Minute)
+		require.NoError(t, err)
+
+		m.linkRepo.EXPECT().Consume(gomock.Any(), claims.ID, guid, claims.ExpiresAt).Return(true, nil)
+		m.userRepo.EXPECT().Exists(gomock.Any(), guid).Return(true, nil)
+		m.tokenManager.EXPECT().Generate(guid, gomock.Any(), "127.0.0.1", gomock.Any()).
+			DoAndReturn(func(guid, jti uuid.UUID, ip string, opts auth.TokenOptions) (string, error) {
+				assert.Empty(t, opts.AMR)
+				return "access", nil
+			})
+		m.tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(nil)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventTokenIssued, "")
+
+		result, err := svc.VerifyMagicLink(context.Background(), token, "127.0.0.1", "")
+		require.NoError(t, err)
+		assert.Equal(t, "access", result.AccessToken)
+	})
+
+	t.Run("replayed link", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newMagicLinkService(ctrl)
+		guid := uuid.New()
+		token, claims, err := m.signer.Issue(guid, time.Minute)
+		require.NoError(t, err)
+
+		m.linkRepo.EXPECT().Consume(gomock.Any(), claims.ID, guid, claims.ExpiresAt).Return(false, nil)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonReusedMagicLink)
+
+		_, err = svc.VerifyMagicLink(context.Background(), token, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidMagicLink)
+	})
+
+	t.Run("expired link", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newMagicLinkService(ctrl)
+		token, _, err := m.signer.Issue(uuid.New(), -time.Minute)
+		require.NoError(t, err)
+
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonBadMagicLink)
+
+		_, err = svc.VerifyMagicLink(context.Background(), token, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidMagicLink)
+	})
+
+	t.Run("forged link", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newMagicLinkService(ctrl)
+		token, _, err := magiclink.NewSigner([]byte("attacker"), "login").Issue(uuid.New(), time.Minute)
+		require.NoError(t, err)
+
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonBadMagicLink)
+
+		_, err = svc.VerifyMagicLink(context.Background(), token, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidMagicLink)
+	})
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/pkg/mail"
+	"go.uber.org/zap"
+	"net/url"
+)
+
+// linkWithToken возвращает адрес base с токеном в параметре token.
+func linkWithToken(base, token string) string {
+	link, err := url.Parse(base)
+	if err != nil {
+		return base + "?token=" + url.QueryEscape(token)
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return link.String()
+}
+
+// sendMailAsync асинхронно отправляет письмо через sender, ошибки отправки только логируются.
+// Отправка не задерживает ответ, чтобы время ответа не выдавало, зарегистрирован ли email.
+func sendMailAsync(ctx context.Context, sender mail.Sender, logger *zap.Logger, guid uuid.UUID, msg mail.Message) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := sender.Send(ctx, msg); err != nil {
+			logger.Error("Error sending email", zap.String("guid", guid.String()), zap.String("subject", msg.Subject), zap.Error(err))
+		}
+	}()
+}
@@ -0,0 +1,369 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/totp"
+	"go.uber.org/zap"
+	"time"
+)
+
+const (
+	defaultMFAChallengeTTL = 5 * time.Minute // Время жизни MFA-челленджа по умолчанию
+	maxMFAAttempts         = 5               // Количество неудачных проверок второго фактора, после которого челлендж удаляется
+	mfaTokenLength         = 32              // Длина токена MFA-челленджа в байтах
+)
+
+// authorizeSession проверяет Access токен и то, что его сессия не отозвана.
+// Используется эндпоинтами, которые выполняют действия от имени владельца токена.
+// Возвращает domain.ErrInvalidAccessToken, если токен невалиден или сессия отозвана, и domain.ErrReauthRequired,
+// если токен требует повторной аутентификации: иначе похищенным токеном можно было бы, например, подключить свой второй фактор.
+func (s *AuthServiceImpl) authorizeSession(ctx context.Context, accessToken string) (auth.Claims, error) {
+	claims, err := s.sessionClaims(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.RequiresReauth() {
+		s.logger.Debug("Request with token requiring re-authentication",
+			zap.String("guid", claims.GetGUID().String()), zap.String("jti", claims.GetJTI().String()))
+		return nil, domain.ErrReauthRequired
+	}
+
+	return claims, nil
+}
+
+// sessionClaims проверяет Access токен и то, что его сессия не отозвана, не учитывая признак повторной аутентификации.
+// Возвращает domain.ErrInvalidAccessToken, если токен невалиден или сессия отозвана.
+func (s *AuthServiceImpl) sessionClaims(ctx context.Context, accessToken string) (auth.Claims, error) {
+	claims, err := s.tokenManager.Parse(accessToken)
+	if err != nil {
+		s.logger.Debug("Bad token provided", zap.Error(err))
+		return nil, domain.ErrInvalidAccessToken
+	}
+
+	guid := claims.GetGUID()
+	jti := claims.GetJTI()
+
+	// Access токен отозванной сессии не должен позволять выполнять действия от имени пользователя
+	if _, _, _, err = s.tokenRepo.GetToken(ctx, guid, jti, time.Now()); err != nil {
+		if errors.Is(err, domain.ErrTokenNotFound) {
+			s.logger.Debug("Request from revoked session", zap.String("guid", guid.String()), zap.String("jti", jti.String()))
+			return nil, domain.ErrInvalidAccessToken
+		}
+		return nil, domain.ErrUnexpected
+	}
+
+	return claims, nil
+}
+
+// mfaEnabled сообщает, подключен ли у пользователя второй фактор.
+func (s *AuthServiceImpl) mfaEnabled(ctx context.Context, guid uuid.UUID) (bool, error) {
+	if s.totpRepo == nil {
+		return false, nil
+	}
+
+	enrollment, err := s.totpRepo.Get(ctx, guid)
+	if err != nil {
+		if errors.Is(err, domain.ErrTOTPNotEnrolled) {
+			return false, nil
+		}
+		return false, domain.ErrUnexpected
+	}
+
+	return enrollment.Confirmed, nil
+}
+
+// completeFirstFactor завершает аутентификацию первым фактором.
+// Если у пользователя подключен второй фактор, вместо пары токенов выдается MFA-челлендж.
+func (s *AuthServiceImpl) completeFirstFactor(ctx context.Context, guid, jti uuid.UUID, ip, userAgent string, method domain.AuthMethod) (*domain.UserAuth, error) {
+	enabled, err := s.mfaEnabled(ctx, guid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !enabled {
+		return s.issueTokens(ctx, guid, jti, ip, userAgent, method)
+	}
+
+	return s.issueMFAChallenge(ctx, guid, ip, userAgent, method)
+}
+
+// issueMFAChallenge создает MFA-челлендж для пользователя, прошедшего первый фактор method.
+// В базе данных хранится только хеш токена челленджа.
+func (s *AuthServiceImpl) issueMFAChallenge(ctx context.Context, guid uuid.UUID, ip, userAgent string, method domain.AuthMethod) (*domain.UserAuth, error) {
+	token := make([]byte, mfaTokenLength)
+	if _, err := rand.Read(token); err != nil {
+		s.logger.Error("Error generating mfa token", zap.Error(err))
+		return nil, domain.ErrUnexpected
+	}
+
+	challenge := &domain.MFAChallenge{
+		ID:        uuid.New(),
+		TokenHash: crypto.HashToken(token),
+		UserID:    guid,
+		Method:    method,
+		ExpiresAt: time.Now().Add(s.mfaChallengeTTL),
+	}
+
+	if err := s.challengeRepo.Create(ctx, challenge); err != nil {
+		return nil, domain.ErrUnexpected
+	}
+
+	s.logger.Info("MFA challenge issued", zap.String("guid", guid.String()), zap.String("method", string(method)))
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventMFAChallenge,
+		GUID:      guid,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details:   withAuthMethods(nil, []domain.AuthMethod{method}),
+	})
+
+	return &domain.UserAuth{
+		MFAToken:     base64.RawURLEncoding.EncodeToString(token),
+		MFAExpiresAt: challenge.ExpiresAt,
+	}, nil
+}
+
+// decryptTOTPSecret расшифровывает секрет TOTP пользователя.
+// Секрет зашифрован с GUID пользователя в качестве связанных данных.
+func (s *AuthServiceImpl) decryptTOTPSecret(enrollment *domain.TOTPEnrollment) ([]byte, error) {
+	secret, err := s.secretCipher.Decrypt(enrollment.EncryptedSecret, enrollment.UserID[:])
+	if err != nil {
+		s.logger.Error("Error decrypting totp secret", zap.String("guid", enrollment.UserID.String()), zap.Error(err))
+		return nil, domain.ErrUnexpected
+	}
+	return secret, nil
+}
+
+// failMFAChallenge учитывает неудачную проверку второго фактора по причине reason.
+// После maxMFAAttempts неудачных попыток челлендж удаляется, и пользователю придется пройти первый фактор заново.
+func (s *AuthServiceImpl) failMFAChallenge(ctx context.Context, challenge *domain.MFAChallenge, reason, ip, userAgent string) {
+	s.logger.Debug("Invalid second factor", zap.String("guid", challenge.UserID.String()), zap.String("reason", reason))
+	s.recordFailure(ip)
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventMFAFailed,
+		GUID:      challenge.UserID,
+		IP:        ip,
+		UserAgent: userAgent,
+		Reason:    reason,
+	})
+
+	attempts, err := s.challengeRepo.IncrementAttempts(ctx, challenge.ID)
+	if err != nil || attempts < maxMFAAttempts {
+		return
+	}
+
+	if err := s.challengeRepo.Delete(ctx, challenge.ID); err != nil && !errors.Is(err, domain.ErrMFAChallengeNotFound) {
+		s.logger.Error("Error deleting exhausted mfa challenge", zap.Error(err))
+	}
+}
+
+// lookupMFAChallenge возвращает действующий MFA-челлендж по токену, выданному клиенту.
+// Возвращает domain.ErrInvalidMFAToken, если челлендж не найден или истек.
+func (s *AuthServiceImpl) lookupMFAChallenge(ctx context.Context, mfaToken string) (*domain.MFAChallenge, error) {
+	token, err := base64.RawURLEncoding.DecodeString(mfaToken)
+	if err != nil {
+		return nil, domain.ErrInvalidMFAToken
+	}
+
+	challenge, err := s.challengeRepo.GetByTokenHash(ctx, crypto.HashToken(token), time.Now())
+	if err != nil {
+		if errors.Is(err, domain.ErrMFAChallengeNotFound) {
+			return nil, domain.ErrInvalidMFAToken
+		}
+		return nil, domain.ErrUnexpected
+	}
+
+	return challenge, nil
+}
+
+// consumeMFAChallenge удаляет успешно пройденный челлендж.
+// Челлендж удаляется до выдачи токенов, чтобы параллельные запросы не могли использовать его повторно.
+func (s *AuthServiceImpl) consumeMFAChallenge(ctx context.Context, challenge *domain.MFAChallenge) error {
+	if err := s.challengeRepo.Delete(ctx, challenge.ID); err != nil {
+		if errors.Is(err, domain.ErrMFAChallengeNotFound) {
+			return domain.ErrInvalidMFAToken
+		}
+		return domain.ErrUnexpected
+	}
+	return nil
+}
+
+// VerifyMFA проверяет код TOTP для MFA-челленджа и при успехе выдает пару токенов.
+// Access токен содержит в claim amr оба пройденных фактора.
+// Возвращает domain.ErrInvalidMFAToken, если челлендж не найден, истек или уже использован,
+// и domain.ErrInvalidMFACode, если код неверен или уже был использован.
+func (s *AuthServiceImpl) VerifyMFA(ctx context.Context, mfaToken, code, ip, userAgent string) (*domain.UserAuth, error) {
+	challenge, err := s.verifyTOTPChallenge(ctx, mfaToken, code, ip, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, challenge.UserID, uuid.New(), ip, userAgent, challenge.Method, domain.AuthMethodTOTP)
+}
+
+// verifyTOTPChallenge проверяет код TOTP для MFA-челленджа и при успехе удаляет челлендж.
+// Возвращает пройденный челлендж, по которому вызывающий выдает токены или код авторизации.
+func (s *AuthServiceImpl) verifyTOTPChallenge(ctx context.Context, mfaToken, code, ip, userAgent string) (*domain.MFAChallenge, error) {
+	if s.totpRepo == nil {
+		return nil, domain.ErrMFADisabled
+	}
+
+	challenge, err := s.lookupMFAChallenge(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment, err := s.totpRepo.Get(ctx, challenge.UserID)
+	if err != nil {
+		// Второй фактор мог быть отключен после выдачи челленджа
+		if errors.Is(err, domain.ErrTOTPNotEnrolled) {
+			return nil, domain.ErrInvalidMFAToken
+		}
+		return nil, domain.ErrUnexpected
+	}
+
+	secret, err := s.decryptTOTPSecret(enrollment)
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		s.failMFAChallenge(ctx, challenge, domain.FailureReasonBadMFACode, ip, userAgent)
+		return nil, domain.ErrInvalidMFACode
+	}
+
+	if err = s.consumeMFAChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	used, err := s.totpRepo.UseStep(ctx, challenge.UserID, step)
+	if err != nil {
+		return nil, domain.ErrUnexpected
+	}
+
+	if !used {
+		s.logger.Debug("Replayed totp code", zap.String("guid", challenge.UserID.String()))
+		s.failMFAChallenge(ctx, challenge, domain.FailureReasonBadMFACode, ip, userAgent)
+		return nil, domain.ErrInvalidMFACode
+	}
+
+	return challenge, nil
+}
+
+// EnrollTOTP начинает подключение TOTP для владельца Access токена.
+// Возвращает секрет и URI для приложения-аутентификатора. Подключение вступает в силу после ConfirmTOTP.
+// Повторный вызов до подтверждения заменяет секрет.
+// Если TOTP уже подключен, возвращает ошибку domain.ErrTOTPAlreadyEnabled.
+func (s *AuthServiceImpl) EnrollTOTP(ctx context.Context, accessToken string) (*domain.TOTPSetup, error) {
+	if s.totpRepo == nil {
+		return nil, domain.ErrMFADisabled
+	}
+
+	claims, err := s.authorizeSession(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	guid := claims.GetGUID()
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		s.logger.Error("Error generating totp secret", zap.Error(err))
+		return nil, domain.ErrUnexpected
+	}
+
+	encrypted, err := s.secretCipher.Encrypt(secret, guid[:])
+	if err != nil {
+		s.logger.Error("Error encrypting totp secret", zap.Error(err))
+		return nil, domain.ErrUnexpected
+	}
+
+	if err = s.totpRepo.Save(ctx, guid, encrypted); err != nil {
+		if errors.Is(err, domain.ErrTOTPAlreadyEnabled) {
+			return nil, err
+		}
+		return nil, domain.ErrUnexpected
+	}
+
+	// Пользователи без email (созданные автоматически) отображаются в приложении по GUID
+	account, err := s.userRepo.GetEmail(ctx, guid)
+	if err != nil {
+		return nil, domain.ErrUnexpected
+	}
+	if account == "" {
+		account = guid.String()
+	}
+
+	return &domain.TOTPSetup{
+		Secret:          totp.EncodeSecret(secret),
+		ProvisioningURI: totp.ProvisioningURI(s.mfaIssuer, account, secret),
+	}, nil
+}
+
+// ConfirmTOTP подтверждает подключение TOTP кодом из приложения-аутентификатора.
+// После подтверждения выдача токенов владельцу требует второго фактора.
+// Возвращает domain.ErrTOTPNotEnrolled, если подключение не начато, domain.ErrTOTPAlreadyEnabled,
+// если оно уже подтверждено, и domain.ErrInvalidMFACode, если код неверен.
+func (s *AuthServiceImpl) ConfirmTOTP(ctx context.Context, accessToken, code, ip, userAgent string) error {
+	if s.totpRepo == nil {
+		return domain.ErrMFADisabled
+	}
+
+	claims, err := s.authorizeSession(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+
+	guid := claims.GetGUID()
+
+	enrollment, err := s.totpRepo.Get(ctx, guid)
+	if err != nil {
+		if errors.Is(err, domain.ErrTOTPNotEnrolled) {
+			return err
+		}
+		return domain.ErrUnexpected
+	}
+
+	if enrollment.Confirmed {
+		return domain.ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := s.decryptTOTPSecret(enrollment)
+	if err != nil {
+		return err
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		s.recordFailure(ip)
+		return domain.ErrInvalidMFACode
+	}
+
+	if err = s.totpRepo.Confirm(ctx, guid, step); err != nil {
+		// Подключение могло быть подтверждено параллельным запросом
+		if errors.Is(err, domain.ErrTOTPNotEnrolled) {
+			return domain.ErrTOTPAlreadyEnabled
+		}
+		return domain.ErrUnexpected
+	}
+
+	s.logger.Info("TOTP enabled", zap.String("guid", guid.String()))
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventTOTPEnabled,
+		GUID:      guid,
+		JTI:       claims.GetJTI(),
+		IP:        ip,
+		UserAgent: userAgent,
+	})
+
+	return nil
+}
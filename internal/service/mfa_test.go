@@ -0,0 +1,385 @@
+package service_test
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
+	mock_auth "github.com/maksemen2/medods-task/internal/pkg/auth/mocks"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/password"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/totp"
+	mock_repository "github.com/maksemen2/medods-task/internal/repository/mocks"
+	"github.com/maksemen2/medods-task/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+type mfaMocks struct {
+	passwordMocks
+	totpRepo      *mock_repository.MockITOTPRepo
+	challengeRepo *mock_repository.MockIMFAChallengeRepo
+	cipher        *crypto.Cipher
+}
+
+func newMFAService(t *testing.T, ctrl *gomock.Controller) (service.IAuthService, mfaMocks) {
+	cipher, err := crypto.NewCipher(make([]byte, crypto.KeyLength))
+	require.NoError(t, err)
+
+	m := mfaMocks{
+		passwordMocks: passwordMocks{
+			userRepo:       mock_repository.NewMockIUserRepo(ctrl),
+			tokenRepo:      mock_repository.NewMockITokenRepo(ctrl),
+			credentialRepo: mock_repository.NewMockICredentialRepo(ctrl),
+			auditRepo:      mock_repository.NewMockIAuditRepo(ctrl),
+			tokenManager:   mock_auth.NewMockAccessTokenManager(ctrl),
+		},
+		totpRepo:      mock_repository.NewMockITOTPRepo(ctrl),
+		challengeRepo: mock_repository.NewMockIMFAChallengeRepo(ctrl),
+		cipher:        cipher,
+	}
+	svc := service.NewAuthServiceImpl(m.userRepo, m.tokenRepo, m.tokenManager, zap.NewNop(), time.Hour,
+		service.WithCredentialRepo(m.credentialRepo), service.WithAuditRepo(m.auditRepo),
+		service.WithTOTP(m.totpRepo, m.challengeRepo, cipher, "medods-task", time.Minute))
+	return svc, m
+}
+
+// enrollment возвращает подтвержденное подключение TOTP с секретом, зашифрованным так же, как это делает сервис.
+func (m mfaMocks) enrollment(t *testing.T, guid uuid.UUID, secret []byte, confirmed bool) *domain.TOTPEnrollment {
+	encrypted, err := m.cipher.Encrypt(secret, guid[:])
+	require.NoError(t, err)
+	return &domain.TOTPEnrollment{UserID: guid, EncryptedSecret: encrypted, Confirmed: confirmed}
+}
+
+func TestAuthService_LoginWithMFA(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hash, err := password.Hash("correct horse")
+	require.NoError(t, err)
+
+	svc, m := newMFAService(t, ctrl)
+	guid := uuid.New()
+
+	m.credentialRepo.EXPECT().GetByEmail(gomock.Any(), "user@example.com").Return(guid, hash, nil)
+	m.totpRepo.EXPECT().Get(gomock.Any(), guid).Return(m.enrollment(t, guid, make([]byte, totp.SecretLength), true), nil)
+
+	var stored *domain.MFAChallenge
+	m.challengeRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, challenge *domain.MFAChallenge) error {
+		stored = challenge
+		return nil
+	})
+	expectAuditEvent(t, m.auditRepo, domain.AuthEventMFAChallenge, "")
+
+	result, err := svc.Login(context.Background(), "user@example.com", "correct horse", "127.0.0.1", "")
+	require.NoError(t, err)
+
+	assert.True(t, result.MFARequired())
+	assert.Empty(t, result.AccessToken)
+	assert.Empty(t, result.RefreshToken)
+
+	// В базе хранится только хеш токена челленджа
+	raw, err := base64.RawURLEncoding.DecodeString(result.MFAToken)
+	require.NoError(t, err)
+	assert.Equal(t, crypto.HashToken(raw), stored.TokenHash)
+	assert.Equal(t, guid, stored.UserID)
+	assert.Equal(t, domain.AuthMethodPassword, stored.Method)
+}
+
+func TestAuthService_LoginWithUnconfirmedTOTP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hash, err := password.Hash("correct horse")
+	require.NoError(t, err)
+
+	svc, m := newMFAService(t, ctrl)
+	guid := uuid.New()
+
+	m.credentialRepo.EXPECT().GetByEmail(gomock.Any(), "user@example.com").Return(guid, hash, nil)
+	m.totpRepo.EXPECT().Get(gomock.Any(), guid).Return(m.enrollment(t, guid, make([]byte, totp.SecretLength), false), nil)
+	m.tokenManager.EXPECT().Generate(guid, gomock.Any(), "127.0.0.1", gomock.Any()).Return("access", nil)
+	m.tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(nil)
+	expectAuditEvent(t, m.auditRepo, domain.AuthEventTokenIssued, "")
+
+	result, err := svc.Login(context.Background(), "user@example.com", "correct horse", "127.0.0.1", "")
+	require.NoError(t, err)
+	assert.False(t, result.MFARequired())
+	assert.Equal(t, "access", result.AccessToken)
+}
+
+func TestAuthService_VerifyMFA(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	token := []byte("challenge-token")
+	mfaToken := base64.RawURLEncoding.EncodeToString(token)
+
+	setup := func(t *testing.T, ctrl *gomock.Controller) (service.IAuthService, mfaMocks, *domain.MFAChallenge) {
+		svc, m := newMFAService(t, ctrl)
+		challenge := &domain.MFAChallenge{
+			ID:        uuid.New(),
+			TokenHash: crypto.HashToken(token),
+			UserID:    uuid.New(),
+			Method:    domain.AuthMethodPassword,
+			ExpiresAt: time.Now().Add(time.Minute),
+		}
+		m.challengeRepo.EXPECT().GetByTokenHash(gomock.Any(), challenge.TokenHash, gomock.Any()).Return(challenge, nil)
+		m.totpRepo.EXPECT().Get(gomock.Any(), challenge.UserID).Return(m.enrollment(t, challenge.UserID, secret, true), nil)
+		return svc, m, challenge
+	}
+
+	t.Run("success issues tokens with both factors", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, challenge := setup(t, ctrl)
+		code := totp.Code(secret, totp.Step(time.Now()))
+
+		m.challengeRepo.EXPECT().Delete(gomock.Any(), challenge.ID).Return(nil)
+		m.totpRepo.EXPECT().UseStep(gomock.Any(), challenge.UserID, gomock.Any()).Return(true, nil)
+		m.tokenManager.EXPECT().Generate(challenge.UserID, gomock.Any(), "127.0.0.1", gomock.Any()).
+			DoAndReturn(func(guid, jti uuid.UUID, ip string, opts auth.TokenOptions) (string, error) {
+				assert.Equal(t, []string{domain.AMRPassword, domain.AMROTP, domain.AMRMFA}, opts.AMR)
+				return "access", nil
+			})
+		m.tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), challenge.UserID, gomock.Any(), gomock.Any()).Return(nil)
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.AuthEvent) error {
+			assert.Equal(t, domain.AuthEventTokenIssued, event.Type)
+			assert.Equal(t, "password+totp", event.Details["method"])
+			return nil
+		})
+
+		result, err := svc.VerifyMFA(context.Background(), mfaToken, code, "127.0.0.1", "")
+		require.NoError(t, err)
+		assert.Equal(t, "access", result.AccessToken)
+	})
+
+	t.Run("wrong code counts attempt", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, challenge := setup(t, ctrl)
+
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventMFAFailed, domain.FailureReasonBadMFACode)
+		m.challengeRepo.EXPECT().IncrementAttempts(gomock.Any(), challenge.ID).Return(1, nil)
+
+		_, err := svc.VerifyMFA(context.Background(), mfaToken, "000000", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidMFACode)
+	})
+
+	t.Run("too many attempts delete challenge", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, challenge := setup(t, ctrl)
+
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventMFAFailed, domain.FailureReasonBadMFACode)
+		m.challengeRepo.EXPECT().IncrementAttempts(gomock.Any(), challenge.ID).Return(5, nil)
+		m.challengeRepo.EXPECT().Delete(gomock.Any(), challenge.ID).Return(nil)
+
+		_, err := svc.VerifyMFA(context.Background(), mfaToken, "000000", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidMFACode)
+	})
+
+	t.Run("replayed code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, challenge := setup(t, ctrl)
+		code := totp.Code(secret, totp.Step(time.Now()))
+
+		m.challengeRepo.EXPECT().Delete(gomock.Any(), challenge.ID).Return(nil)
+		m.totpRepo.EXPECT().UseStep(gomock.Any(), challenge.UserID, gomock.Any()).Return(false, nil)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventMFAFailed, domain.FailureReasonBadMFACode)
+		m.challengeRepo.EXPECT().IncrementAttempts(gomock.Any(), challenge.ID).Return(0, domain.ErrMFAChallengeNotFound)
+
+		_, err := svc.VerifyMFA(context.Background(), mfaToken, code, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidMFACode)
+	})
+
+	t.Run("challenge used concurrently", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, challenge := setup(t, ctrl)
+		code := totp.Code(secret, totp.Step(time.Now()))
+
+		m.challengeRepo.EXPECT().Delete(gomock.Any(), challenge.ID).Return(domain.ErrMFAChallengeNotFound)
+
+		_, err := svc.VerifyMFA(context.Background(), mfaToken, code, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidMFAToken)
+	})
+
+	t.Run("unknown challenge", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newMFAService(t, ctrl)
+		m.challengeRepo.EXPECT().GetByTokenHash(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, domain.ErrMFAChallengeNotFound)
+
+		_, err := svc.VerifyMFA(context.Background(), mfaToken, "123456", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidMFAToken)
+	})
+
+	t.Run("malformed token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, _ := newMFAService(t, ctrl)
+
+		_, err := svc.VerifyMFA(context.Background(), "not base64!", "123456", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidMFAToken)
+	})
+
+	t.Run("mfa disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, _ := newPasswordService(ctrl)
+
+		_, err := svc.VerifyMFA(context.Background(), mfaToken, "123456", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrMFADisabled)
+	})
+}
+
+func TestAuthService_EnrollAndConfirmTOTP(t *testing.T) {
+	setup := func(t *testing.T, ctrl *gomock.Controller) (service.IAuthService, mfaMocks, uuid.UUID) {
+		svc, m := newMFAService(t, ctrl)
+		claims := mock_auth.NewMockClaims(ctrl)
+		guid, jti := uuid.New(), uuid.New()
+
+		m.tokenManager.EXPECT().Parse("access").Return(claims, nil)
+		claims.EXPECT().GetGUID().Return(guid).AnyTimes()
+		claims.EXPECT().GetJTI().Return(jti).AnyTimes()
+		claims.EXPECT().RequiresReauth().Return(false).AnyTimes()
+		m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.New(), "hash", time.Time{}, nil)
+		return svc, m, guid
+	}
+
+	t.Run("enroll", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, guid := setup(t, ctrl)
+
+		var encrypted string
+		m.totpRepo.EXPECT().Save(gomock.Any(), guid, gomock.Any()).DoAndReturn(func(ctx context.Context, userID uuid.UUID, secret string) error {
+			encrypted = secret
+			return nil
+		})
+		m.userRepo.EXPECT().GetEmail(gomock.Any(), guid).Return("user@example.com", nil)
+
+		enrolled, err := svc.EnrollTOTP(context.Background(), "access")
+		require.NoError(t, err)
+
+		secret, err := m.cipher.Decrypt(encrypted, guid[:])
+		require.NoError(t, err)
+		assert.Equal(t, totp.EncodeSecret(secret), enrolled.Secret)
+		assert.Equal(t, totp.ProvisioningURI("medods-task", "user@example.com", secret), enrolled.ProvisioningURI)
+	})
+
+	t.Run("enroll without email uses guid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, guid := setup(t, ctrl)
+
+		m.totpRepo.EXPECT().Save(gomock.Any(), guid, gomock.Any()).Return(nil)
+		m.userRepo.EXPECT().GetEmail(gomock.Any(), guid).Return("", nil)
+
+		enrolled, err := svc.EnrollTOTP(context.Background(), "access")
+		require.NoError(t, err)
+		assert.Contains(t, enrolled.ProvisioningURI, guid.String())
+	})
+
+	t.Run("enroll when already enabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, guid := setup(t, ctrl)
+
+		m.totpRepo.EXPECT().Save(gomock.Any(), guid, gomock.Any()).Return(domain.ErrTOTPAlreadyEnabled)
+
+		_, err := svc.EnrollTOTP(context.Background(), "access")
+		assert.ErrorIs(t, err, domain.ErrTOTPAlreadyEnabled)
+	})
+
+	t.Run("confirm", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, guid := setup(t, ctrl)
+		secret := []byte("12345678901234567890")
+		step := totp.Step(time.Now())
+
+		m.totpRepo.EXPECT().Get(gomock.Any(), guid).Return(m.enrollment(t, guid, secret, false), nil)
+		m.totpRepo.EXPECT().Confirm(gomock.Any(), guid, step).Return(nil)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventTOTPEnabled, "")
+
+		err := svc.ConfirmTOTP(context.Background(), "access", totp.Code(secret, step), "127.0.0.1", "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("confirm with wrong code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, guid := setup(t, ctrl)
+
+		m.totpRepo.EXPECT().Get(gomock.Any(), guid).Return(m.enrollment(t, guid, []byte("12345678901234567890"), false), nil)
+
+		err := svc.ConfirmTOTP(context.Background(), "access", "000000", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidMFACode)
+	})
+
+	t.Run("confirm when already enabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, guid := setup(t, ctrl)
+
+		m.totpRepo.EXPECT().Get(gomock.Any(), guid).Return(m.enrollment(t, guid, []byte("12345678901234567890"), true), nil)
+
+		err := svc.ConfirmTOTP(context.Background(), "access", "123456", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrTOTPAlreadyEnabled)
+	})
+
+	t.Run("confirm without enrollment", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, guid := setup(t, ctrl)
+
+		m.totpRepo.EXPECT().Get(gomock.Any(), guid).Return(nil, domain.ErrTOTPNotEnrolled)
+
+		err := svc.ConfirmTOTP(context.Background(), "access", "123456", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrTOTPNotEnrolled)
+	})
+
+	t.Run("enroll with token requiring re-authentication", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newMFAService(t, ctrl)
+		m.expectStepUpToken(ctrl, "step-up")
+
+		_, err := svc.EnrollTOTP(context.Background(), "step-up")
+		assert.ErrorIs(t, err, domain.ErrReauthRequired)
+	})
+
+	t.Run("confirm with token requiring re-authentication", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newMFAService(t, ctrl)
+		m.expectStepUpToken(ctrl, "step-up")
+
+		err := svc.ConfirmTOTP(context.Background(), "step-up", "123456", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrReauthRequired)
+	})
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"go.uber.org/zap"
+)
+
+// INotifier - интерфейс для отправки пользователю уведомлений о событиях безопасности.
+type INotifier interface {
+	NotifyIPChange(ctx context.Context, guid uuid.UUID, oldIP, newIP string) error   // NotifyIPChange уведомляет об обновлении токенов с другого IP-адреса
+	NotifyRiskEvent(ctx context.Context, event domain.RiskEvent) error               // NotifyRiskEvent уведомляет о событии риска в сессии пользователя
+	NotifyRecoveryCodeUsed(ctx context.Context, guid uuid.UUID, remaining int) error // NotifyRecoveryCodeUsed уведомляет о входе с кодом восстановления
+}
+
+// RiskEventHandler - интерфейс подписчика на события риска, обнаруженные сервисом аутентификации.
+type RiskEventHandler interface {
+	HandleRiskEvent(ctx context.Context, event domain.RiskEvent)
+}
+
+// LogNotifier - заглушка INotifier, которая вместо отправки уведомлений только логирует их.
+// Вероятно, для реальной отправки лучше использовать отдельный сервис.
+type LogNotifier struct {
+	logger *zap.Logger
+}
+
+func NewLogNotifier(logger *zap.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+func (n *LogNotifier) NotifyIPChange(ctx context.Context, guid uuid.UUID, oldIP, newIP string) error {
+	// Логика получения почты пользователя и отправки уведомления
+	n.logger.Debug("Notifying user about ip change",
+		zap.String("guid", guid.String()), zap.String("old_ip", oldIP), zap.String("new_ip", newIP))
+	return nil
+}
+
+func (n *LogNotifier) NotifyRiskEvent(ctx context.Context, event domain.RiskEvent) error {
+	n.logger.Debug("Notifying user about risk event",
+		zap.String("guid", event.GUID.String()), zap.String("type", string(event.Type)))
+	return nil
+}
+
+func (n *LogNotifier) NotifyRecoveryCodeUsed(ctx context.Context, guid uuid.UUID, remaining int) error {
+	n.logger.Debug("Notifying user about recovery code usage",
+		zap.String("guid", guid.String()), zap.Int("remaining", remaining))
+	return nil
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/oauth"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	"time"
+)
+
+const (
+	defaultAuthorizationCodeTTL = time.Minute // Время жизни кода авторизации по умолчанию
+	authorizationCodeLength     = 32          // Длина кода авторизации в байтах
+)
+
+// WithOAuth включает сервер авторизации OAuth 2.0 с потоком authorization code и PKCE.
+// Клиенты читаются из реестра clientRepo. Если codeTTL неположителен, используется defaultAuthorizationCodeTTL.
+func WithOAuth(clientRepo repository.IClientRepo, codeRepo repository.IAuthorizationCodeRepo, codeTTL time.Duration) AuthServiceOption {
+	return func(s *AuthServiceImpl) {
+		s.clientRepo = clientRepo
+		s.authCodeRepo = codeRepo
+		if codeTTL > 0 {
+			s.authCodeTTL = codeTTL
+		}
+	}
+}
+
+// oauthClient возвращает зарегистрированного клиента OAuth.
+// Возвращает domain.ErrOAuthDisabled, если сервер авторизации не настроен, и domain.ErrInvalidClient, если клиент неизвестен.
+func (s *AuthServiceImpl) oauthClient(ctx context.Context, clientID string) (*domain.OAuthClient, error) {
+	if s.clientRepo == nil || s.authCodeRepo == nil {
+		return nil, domain.ErrOAuthDisabled
+	}
+
+	client, err := s.clientRepo.Get(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, domain.ErrClientNotFound) {
+			return nil, domain.ErrInvalidClient
+		}
+		return nil, domain.ErrUnexpected
+	}
+
+	return client, nil
+}
+
+// authenticateClient аутентифицирует клиента OAuth на эндпоинте /token.
+// Конфиденциальный клиент должен предъявить свой секрет, публичный клиент секрета не имеет.
+// Возвращает domain.ErrInvalidClient, если клиент неизвестен или секрет не подошел.
+func (s *AuthServiceImpl) authenticateClient(ctx context.Context, creds domain.ClientCredentials, ip string) (*domain.OAuthClient, error) {
+	client, err := s.oauthClient(ctx, creds.ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidClient) {
+			s.recordFailure(ip)
+		}
+		return nil, err
+	}
+
+	var authenticated bool
+	if client.Confidential() {
+		hash := crypto.HashToken([]byte(creds.Secret))
+		authenticated = creds.Secret != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(client.SecretHash)) == 1
+	} else {
+		authenticated = creds.Secret == ""
+	}
+
+	if !authenticated {
+		s.logger.Debug("Client authentication failed", zap.String("client_id", creds.ID))
+		s.recordFailure(ip)
+		return nil, domain.ErrInvalidClient
+	}
+
+	return client, nil
+}
+
+// clientAccessTTL возвращает время жизни Access токенов клиента.
+func (s *AuthServiceImpl) clientAccessTTL(client *domain.OAuthClient) time.Duration {
+	if client.AccessTTL > 0 {
+		return client.AccessTTL
+	}
+	return s.tokenManager.TTL()
+}
+
+// ValidateAuthorizationRequest проверяет запрос авторизации и возвращает клиента, запросившего доступ.
+// Ошибки domain.ErrInvalidClient и domain.ErrInvalidRedirectURI означают, что перенаправлять пользователя
+// обратно к клиенту нельзя. Ошибки domain.ErrUnsupportedResponseType, domain.ErrUnauthorizedClient,
+// domain.ErrInvalidOAuthRequest и domain.ErrInvalidScope сообщаются клиенту через redirect_uri.
+func (s *AuthServiceImpl) ValidateAuthorizationRequest(ctx context.Context, req domain.AuthorizationRequest) (*domain.OAuthClient, error) {
+	client, err := s.oauthClient(ctx, req.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !client.AllowsRedirectURI(req.RedirectURI) {
+		return nil, domain.ErrInvalidRedirectURI
+	}
+
+	if req.ResponseType != "code" {
+		return nil, domain.ErrUnsupportedResponseType
+	}
+
+	if !client.AllowsGrant(domain.GrantTypeAuthorizationCode) {
+		return nil, domain.ErrUnauthorizedClient
+	}
+
+	// PKCE обязателен для всех клиентов, поддерживается только метод S256
+	if req.CodeChallengeMethod != oauth.CodeChallengeMethodS256 || !oauth.ValidCodeChallenge(req.CodeChallenge) {
+		return nil, domain.ErrInvalidOAuthRequest
+	}
+
+	scopes, ok := oauth.ParseScope(req.Scope)
+	if !ok || !client.AllowsScopes(scopes) {
+		return nil, domain.ErrInvalidScope
+	}
+
+	return client, nil
+}
+
+// AuthorizeWithPassword аутентифицирует пользователя на странице входа по email и паролю
+// и выдает код авторизации для запроса req.
+// Если у пользователя подключен второй фактор, вместо кода возвращается MFA-челлендж,
+// который подтверждается через AuthorizeWithMFA.
+// Возвращает domain.ErrInvalidCredentials при неверных email или пароле.
+func (s *AuthServiceImpl) AuthorizeWithPassword(ctx context.Context, req domain.AuthorizationRequest, email, plainPassword, ip, userAgent string) (*domain.AuthorizationGrant, error) {
+	if _, err := s.ValidateAuthorizationRequest(ctx, req); err != nil {
+		return nil, err
+	}
+
+	guid, _, err := s.verifyPassword(ctx, email, plainPassword, ip, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.mfaEnabled(ctx, guid)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabled {
+		challenge, err := s.issueMFAChallenge(ctx, guid, ip, userAgent, domain.AuthMethodPassword)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.AuthorizationGrant{MFAToken: challenge.MFAToken, MFAExpiresAt: challenge.MFAExpiresAt}, nil
+	}
+
+	return s.issueAuthorizationCode(ctx, req, guid, domain.AuthMethodPassword)
+}
+
+// AuthorizeWithMFA проверяет код TOTP для MFA-челленджа, выданного AuthorizeWithPassword,
+// и выдает код авторизации для запроса req.
+// Возвращает domain.ErrInvalidMFAToken, если челлендж не найден или истек, и domain.ErrInvalidMFACode, если код неверен.
+func (s *AuthServiceImpl) AuthorizeWithMFA(ctx context.Context, req domain.AuthorizationRequest, mfaToken, code, ip, userAgent string) (*domain.AuthorizationGrant, error) {
+	if _, err := s.ValidateAuthorizationRequest(ctx, req); err != nil {
+		return nil, err
+	}
+
+	challenge, err := s.verifyTOTPChallenge(ctx, mfaToken, code, ip, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueAuthorizationCode(ctx, req, challenge.UserID, challenge.Method, domain.AuthMethodTOTP)
+}
+
+// issueAuthorizationCode выдает одноразовый код авторизации, привязанный к клиенту, redirect_uri и code_challenge запроса.
+// В базе данных хранится только хеш кода.
+func (s *AuthServiceImpl) issueAuthorizationCode(ctx context.Context, req domain.AuthorizationRequest, guid uuid.UUID, methods ...domain.AuthMethod) (*domain.AuthorizationGrant, error) {
+	// Запрос уже проверен ValidateAuthorizationRequest
+	scopes, _ := oauth.ParseScope(req.Scope)
+
+	code := make([]byte, authorizationCodeLength)
+	if _, err := rand.Read(code); err != nil {
+		s.logger.Error("Error generating authorization code", zap.Error(err))
+		return nil, domain.ErrUnexpected
+	}
+
+	authCode := &domain.AuthorizationCode{
+		CodeHash:      crypto.HashToken(code),
+		ClientID:      req.ClientID,
+		UserID:        guid,
+		RedirectURI:   req.RedirectURI,
+		CodeChallenge: req.CodeChallenge,
+		AuthMethods:   methods,
+		Scopes:        scopes,
+		ExpiresAt:     time.Now().UTC().Add(s.authCodeTTL),
+	}
+
+	if err := s.authCodeRepo.Create(ctx, authCode); err != nil {
+		return nil, domain.ErrUnexpected
+	}
+
+	s.logger.Info("Authorization code issued", zap.String("guid", guid.String()), zap.String("client_id", req.ClientID))
+
+	return &domain.AuthorizationGrant{Code: base64.RawURLEncoding.EncodeToString(code)}, nil
+}
+
+// failAuthorizationCode учитывает неудачную попытку обмена кода авторизации.
+func (s *AuthServiceImpl) failAuthorizationCode(ctx context.Context, guid uuid.UUID, clientID, ip, userAgent string) {
+	s.logger.Debug("Invalid authorization code", zap.String("client_id", clientID))
+	s.recordFailure(ip)
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventLoginFailed,
+		GUID:      guid,
+		IP:        ip,
+		UserAgent: userAgent,
+		Reason:    domain.FailureReasonBadAuthCode,
+		Details:   withClientID(nil, clientID),
+	})
+}
+
+// ExchangeAuthorizationCode обменивает код авторизации на пару токенов (grant_type=authorization_code).
+// Код одноразовый и должен быть предъявлен тем же клиентом с тем же redirect_uri,
+// а code_verifier должен соответствовать code_challenge запроса авторизации.
+// Refresh токен выдается, только если клиенту разрешен grant_type=refresh_token.
+// Возвращает domain.ErrInvalidClient, если клиент не прошел аутентификацию, и domain.ErrInvalidGrant, если код не прошел проверку.
+func (s *AuthServiceImpl) ExchangeAuthorizationCode(ctx context.Context, creds domain.ClientCredentials, code, redirectURI, codeVerifier, ip, userAgent string) (*domain.UserAuth, error) {
+	client, err := s.authenticateClient(ctx, creds, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	if !client.AllowsGrant(domain.GrantTypeAuthorizationCode) {
+		return nil, domain.ErrUnauthorizedClient
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(code)
+	if err != nil || len(raw) != authorizationCodeLength {
+		s.failAuthorizationCode(ctx, uuid.Nil, client.ID, ip, userAgent)
+		return nil, domain.ErrInvalidGrant
+	}
+
+	// Код удаляется до проверки PKCE, поэтому перехваченный код нельзя подбирать к разным code_verifier
+	authCode, err := s.authCodeRepo.Consume(ctx, crypto.HashToken(raw), time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidGrant) {
+			s.failAuthorizationCode(ctx, uuid.Nil, client.ID, ip, userAgent)
+			return nil, domain.ErrInvalidGrant
+		}
+		return nil, domain.ErrUnexpected
+	}
+
+	if authCode.ClientID != client.ID || authCode.RedirectURI != redirectURI || !oauth.VerifyCodeChallenge(codeVerifier, authCode.CodeChallenge) {
+		s.failAuthorizationCode(ctx, authCode.UserID, client.ID, ip, userAgent)
+		return nil, domain.ErrInvalidGrant
+	}
+
+	userAuth, err := s.issueClientTokens(ctx, client, authCode.Scopes, authCode.UserID, uuid.New(), ip, userAgent, authCode.AuthMethods)
+	if err != nil {
+		return nil, err
+	}
+	userAuth.ExpiresIn = s.clientAccessTTL(client)
+	if !client.AllowsGrant(domain.GrantTypeRefreshToken) {
+		userAuth.RefreshToken = ""
+	}
+
+	return userAuth, nil
+}
+
+// ExchangeClientCredentials выдает конфиденциальному клиенту Access токен от его собственного имени
+// (grant_type=client_credentials). Субъектом токена становится клиент, Refresh токен не выдается.
+// scope - запрошенные области доступа через пробел, каждая должна быть разрешена клиенту.
+// Если scope пуст, выдаются все разрешенные клиенту области.
+// Возвращает domain.ErrUnauthorizedClient, если клиент публичный или способ ему не разрешен,
+// и domain.ErrInvalidScope, если области доступа недопустимы.
+func (s *AuthServiceImpl) ExchangeClientCredentials(ctx context.Context, creds domain.ClientCredentials, scope, ip, userAgent string) (*domain.UserAuth, error) {
+	client, err := s.authenticateClient(ctx, creds, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	// Публичный клиент не может подтвердить, что запрос исходит от него (RFC 6749, раздел 4.4)
+	if !client.Confidential() || !client.AllowsGrant(domain.GrantTypeClientCredentials) {
+		return nil, domain.ErrUnauthorizedClient
+	}
+
+	scopes, ok := oauth.ParseScope(scope)
+	if !ok || !client.AllowsScopes(scopes) {
+		return nil, domain.ErrInvalidScope
+	}
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+
+	jti := uuid.New()
+	accessToken, err := s.tokenManager.Generate(uuid.Nil, jti, ip, auth.TokenOptions{
+		ClientID:      client.ID,
+		ClientSubject: true,
+		Scopes:        scopes,
+		TTL:           client.AccessTTL,
+	})
+	if err != nil {
+		s.logger.Error("Error generating client token", zap.Error(err))
+		return nil, domain.ErrUnexpected
+	}
+
+	s.logger.Info("Client token issued", zap.String("client_id", client.ID), zap.String("jti", jti.String()), zap.String("ip", ip))
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventTokenIssued,
+		JTI:       jti,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details:   withClientID(map[string]any{"grant_type": string(domain.GrantTypeClientCredentials)}, client.ID),
+	})
+
+	return &domain.UserAuth{
+		AccessToken: accessToken,
+		ExpiresIn:   s.clientAccessTTL(client),
+		Scopes:      scopes,
+	}, nil
+}
+
+// ExchangeRefreshToken обновляет пару токенов клиента OAuth (grant_type=refresh_token) через RefreshToken.
+// Refresh токены привязаны к Access токену, с которым они выданы, поэтому клиент передает оба.
+// Токены должны быть выданы тому же клиенту. Ошибки проверки токенов и отказы политик
+// возвращаются как domain.ErrInvalidGrant.
+func (s *AuthServiceImpl) ExchangeRefreshToken(ctx context.Context, creds domain.ClientCredentials, accessToken, refreshToken, ip, userAgent string) (*domain.UserAuth, error) {
+	client, err := s.authenticateClient(ctx, creds, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	if !client.AllowsGrant(domain.GrantTypeRefreshToken) {
+		return nil, domain.ErrUnauthorizedClient
+	}
+
+	claims, err := s.tokenManager.ParseExpired(accessToken)
+	if err != nil || claims.GetClientID() != client.ID {
+		s.logger.Debug("Refresh grant with token of another client", zap.String("client_id", client.ID))
+		return nil, domain.ErrInvalidGrant
+	}
+
+	userAuth, err := s.RefreshToken(ctx, accessToken, refreshToken, ip, userAgent)
+	if err != nil {
+		if errors.Is(err, domain.ErrUnexpected) {
+			return nil, err
+		}
+		return nil, domain.ErrInvalidGrant
+	}
+	userAuth.ExpiresIn = s.clientAccessTTL(client)
+
+	return userAuth, nil
+}
@@ -0,0 +1,578 @@
+package service_test
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
+	mock_auth "github.com/maksemen2/medods-task/internal/pkg/auth/mocks"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/oauth"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/password"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/totp"
+	mock_repository "github.com/maksemen2/medods-task/internal/repository/mocks"
+	"github.com/maksemen2/medods-task/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+const (
+	testClientID        = "spa"
+	testRedirectURI     = "https://app.example.com/callback"
+	testVerifier        = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	testVerificationURI = "https://auth.example.com/device"
+)
+
+type oauthMocks struct {
+	mfaMocks
+	clientRepo *mock_repository.MockIClientRepo
+	codeRepo   *mock_repository.MockIAuthorizationCodeRepo
+	deviceRepo *mock_repository.MockIDeviceAuthorizationRepo
+}
+
+// testOAuthClient возвращает публичного клиента, которому разрешены код авторизации и обновление токенов.
+func testOAuthClient() *domain.OAuthClient {
+	return &domain.OAuthClient{
+		ID:           testClientID,
+		Name:         "SPA",
+		GrantTypes:   []domain.GrantType{domain.GrantTypeAuthorizationCode, domain.GrantTypeRefreshToken},
+		RedirectURIs: []string{testRedirectURI},
+		Scopes:       []string{"profile", "email"},
+	}
+}
+
+// newOAuthServiceWithClient создает сервис с реестром клиентов, в котором зарегистрирован client,
+// а остальные клиенты неизвестны.
+func newOAuthServiceWithClient(t *testing.T, ctrl *gomock.Controller, client *domain.OAuthClient, opts ...service.AuthServiceOption) (service.IAuthService, oauthMocks) {
+	svc, m := newOAuthService(t, ctrl, opts...)
+	m.clientRepo.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, clientID string) (*domain.OAuthClient, error) {
+		if clientID != client.ID {
+			return nil, domain.ErrClientNotFound
+		}
+		copied := *client
+		return &copied, nil
+	}).AnyTimes()
+	return svc, m
+}
+
+func newOAuthService(t *testing.T, ctrl *gomock.Controller, opts ...service.AuthServiceOption) (service.IAuthService, oauthMocks) {
+	cipher, err := crypto.NewCipher(make([]byte, crypto.KeyLength))
+	require.NoError(t, err)
+
+	m := oauthMocks{
+		mfaMocks: mfaMocks{
+			passwordMocks: passwordMocks{
+				userRepo:       mock_repository.NewMockIUserRepo(ctrl),
+				tokenRepo:      mock_repository.NewMockITokenRepo(ctrl),
+				credentialRepo: mock_repository.NewMockICredentialRepo(ctrl),
+				auditRepo:      mock_repository.NewMockIAuditRepo(ctrl),
+				tokenManager:   mock_auth.NewMockAccessTokenManager(ctrl),
+			},
+			totpRepo:      mock_repository.NewMockITOTPRepo(ctrl),
+			challengeRepo: mock_repository.NewMockIMFAChallengeRepo(ctrl),
+			cipher:        cipher,
+		},
+		clientRepo: mock_repository.NewMockIClientRepo(ctrl),
+		codeRepo:   mock_repository.NewMockIAuthorizationCodeRepo(ctrl),
+		deviceRepo: mock_repository.NewMockIDeviceAuthorizationRepo(ctrl),
+	}
+	opts = append([]service.AuthServiceOption{
+		service.WithCredentialRepo(m.credentialRepo), service.WithAuditRepo(m.auditRepo),
+		service.WithTOTP(m.totpRepo, m.challengeRepo, cipher, "medods-task", time.Minute),
+		service.WithOAuth(m.clientRepo, m.codeRepo, time.Minute),
+		service.WithDeviceAuthorization(m.deviceRepo, testVerificationURI, 10*time.Minute, 5*time.Second),
+	}, opts...)
+	svc := service.NewAuthServiceImpl(m.userRepo, m.tokenRepo, m.tokenManager, zap.NewNop(), time.Hour, opts...)
+	return svc, m
+}
+
+func authorizationRequest() domain.AuthorizationRequest {
+	return domain.AuthorizationRequest{
+		ResponseType:        "code",
+		ClientID:            testClientID,
+		RedirectURI:         testRedirectURI,
+		State:               "xyz",
+		CodeChallenge:       oauth.S256Challenge(testVerifier),
+		CodeChallengeMethod: oauth.CodeChallengeMethodS256,
+		Scope:               "profile",
+	}
+}
+
+func TestAuthService_ValidateAuthorizationRequest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc, _ := newOAuthServiceWithClient(t, ctrl, testOAuthClient())
+
+	client, err := svc.ValidateAuthorizationRequest(context.Background(), authorizationRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "SPA", client.Name)
+
+	tests := []struct {
+		name   string
+		modify func(req *domain.AuthorizationRequest)
+		want   error
+	}{
+		{"unknown client", func(req *domain.AuthorizationRequest) { req.ClientID = "other" }, domain.ErrInvalidClient},
+		{"unregistered redirect uri", func(req *domain.AuthorizationRequest) { req.RedirectURI = "https://evil.example.com/callback" }, domain.ErrInvalidRedirectURI},
+		{"implicit flow", func(req *domain.AuthorizationRequest) { req.ResponseType = "token" }, domain.ErrUnsupportedResponseType},
+		{"missing code challenge", func(req *domain.AuthorizationRequest) { req.CodeChallenge = "" }, domain.ErrInvalidOAuthRequest},
+		{"plain code challenge method", func(req *domain.AuthorizationRequest) { req.CodeChallengeMethod = "plain" }, domain.ErrInvalidOAuthRequest},
+		{"scope not allowed", func(req *domain.AuthorizationRequest) { req.Scope = "profile admin" }, domain.ErrInvalidScope},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := authorizationRequest()
+			tt.modify(&req)
+			_, err := svc.ValidateAuthorizationRequest(context.Background(), req)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+
+	t.Run("oauth disabled", func(t *testing.T) {
+		svc, _ := newPasswordService(ctrl)
+		_, err := svc.ValidateAuthorizationRequest(context.Background(), authorizationRequest())
+		assert.ErrorIs(t, err, domain.ErrOAuthDisabled)
+	})
+}
+
+func TestAuthService_AuthorizeWithPassword(t *testing.T) {
+	hash, err := password.Hash("correct horse")
+	require.NoError(t, err)
+
+	t.Run("issues code bound to request", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testOAuthClient())
+		guid := uuid.New()
+
+		m.credentialRepo.EXPECT().GetByEmail(gomock.Any(), "user@example.com").Return(guid, hash, nil)
+		m.totpRepo.EXPECT().Get(gomock.Any(), guid).Return(nil, domain.ErrTOTPNotEnrolled)
+
+		var stored *domain.AuthorizationCode
+		m.codeRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, code *domain.AuthorizationCode) error {
+			stored = code
+			return nil
+		})
+
+		grant, err := svc.AuthorizeWithPassword(context.Background(), authorizationRequest(), "user@example.com", "correct horse", "127.0.0.1", "")
+		require.NoError(t, err)
+		assert.False(t, grant.MFARequired())
+
+		// В базе хранится только хеш кода
+		raw, err := base64.RawURLEncoding.DecodeString(grant.Code)
+		require.NoError(t, err)
+		assert.Equal(t, crypto.HashToken(raw), stored.CodeHash)
+		assert.Equal(t, guid, stored.UserID)
+		assert.Equal(t, testClientID, stored.ClientID)
+		assert.Equal(t, testRedirectURI, stored.RedirectURI)
+		assert.Equal(t, oauth.S256Challenge(testVerifier), stored.CodeChallenge)
+		assert.Equal(t, []domain.AuthMethod{domain.AuthMethodPassword}, stored.AuthMethods)
+		assert.Equal(t, []string{"profile"}, stored.Scopes)
+	})
+
+	t.Run("mfa required", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testOAuthClient())
+		guid := uuid.New()
+
+		m.credentialRepo.EXPECT().GetByEmail(gomock.Any(), "user@example.com").Return(guid, hash, nil)
+		m.totpRepo.EXPECT().Get(gomock.Any(), guid).Return(m.enrollment(t, guid, make([]byte, totp.SecretLength), true), nil)
+		m.challengeRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventMFAChallenge, "")
+
+		grant, err := svc.AuthorizeWithPassword(context.Background(), authorizationRequest(), "user@example.com", "correct horse", "127.0.0.1", "")
+		require.NoError(t, err)
+		assert.True(t, grant.MFARequired())
+		assert.Empty(t, grant.Code)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testOAuthClient())
+
+		m.credentialRepo.EXPECT().GetByEmail(gomock.Any(), "user@example.com").Return(uuid.New(), hash, nil)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonBadPassword)
+
+		_, err := svc.AuthorizeWithPassword(context.Background(), authorizationRequest(), "user@example.com", "wrong", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	})
+}
+
+func TestAuthService_AuthorizeWithMFA(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	secret := []byte("12345678901234567890")
+	token := []byte("challenge-token")
+
+	svc, m := newOAuthServiceWithClient(t, ctrl, testOAuthClient())
+	challenge := &domain.MFAChallenge{
+		ID:        uuid.New(),
+		TokenHash: crypto.HashToken(token),
+		UserID:    uuid.New(),
+		Method:    domain.AuthMethodPassword,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	m.challengeRepo.EXPECT().GetByTokenHash(gomock.Any(), challenge.TokenHash, gomock.Any()).Return(challenge, nil)
+	m.totpRepo.EXPECT().Get(gomock.Any(), challenge.UserID).Return(m.enrollment(t, challenge.UserID, secret, true), nil)
+	m.challengeRepo.EXPECT().Delete(gomock.Any(), challenge.ID).Return(nil)
+	m.totpRepo.EXPECT().UseStep(gomock.Any(), challenge.UserID, gomock.Any()).Return(true, nil)
+	m.codeRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, code *domain.AuthorizationCode) error {
+		assert.Equal(t, challenge.UserID, code.UserID)
+		assert.Equal(t, []domain.AuthMethod{domain.AuthMethodPassword, domain.AuthMethodTOTP}, code.AuthMethods)
+		return nil
+	})
+
+	code := totp.Code(secret, totp.Step(time.Now()))
+	grant, err := svc.AuthorizeWithMFA(context.Background(), authorizationRequest(),
+		base64.RawURLEncoding.EncodeToString(token), code, "127.0.0.1", "")
+	require.NoError(t, err)
+	assert.NotEmpty(t, grant.Code)
+}
+
+func TestAuthService_ExchangeAuthorizationCode(t *testing.T) {
+	raw := make([]byte, 32)
+	code := base64.RawURLEncoding.EncodeToString(raw)
+
+	storedCode := func() *domain.AuthorizationCode {
+		return &domain.AuthorizationCode{
+			CodeHash:      crypto.HashToken(raw),
+			ClientID:      testClientID,
+			UserID:        uuid.New(),
+			RedirectURI:   testRedirectURI,
+			CodeChallenge: oauth.S256Challenge(testVerifier),
+			AuthMethods:   []domain.AuthMethod{domain.AuthMethodPassword},
+			ExpiresAt:     time.Now().Add(time.Minute),
+		}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testOAuthClient())
+		authCode := storedCode()
+
+		m.codeRepo.EXPECT().Consume(gomock.Any(), authCode.CodeHash, gomock.Any()).Return(authCode, nil)
+		m.tokenManager.EXPECT().Generate(authCode.UserID, gomock.Any(), "127.0.0.1", gomock.Any()).
+			DoAndReturn(func(guid, jti uuid.UUID, ip string, opts auth.TokenOptions) (string, error) {
+				assert.Equal(t, testClientID, opts.ClientID)
+				assert.Equal(t, []string{domain.AMRPassword}, opts.AMR)
+				return "access", nil
+			})
+		m.tokenManager.EXPECT().TTL().Return(15 * time.Minute)
+		m.tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), authCode.UserID, gomock.Any(), gomock.Any()).Return(nil)
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.AuthEvent) error {
+			assert.Equal(t, domain.AuthEventTokenIssued, event.Type)
+			assert.Equal(t, testClientID, event.Details["client_id"])
+			return nil
+		})
+
+		result, err := svc.ExchangeAuthorizationCode(context.Background(), domain.ClientCredentials{ID: testClientID}, code, testRedirectURI, testVerifier, "127.0.0.1", "")
+		require.NoError(t, err)
+		assert.Equal(t, "access", result.AccessToken)
+		assert.NotEmpty(t, result.RefreshToken)
+		assert.Equal(t, 15*time.Minute, result.ExpiresIn)
+	})
+
+	tests := []struct {
+		name        string
+		redirectURI string
+		verifier    string
+	}{
+		{"wrong code verifier", testRedirectURI, "wrong-verifier-wrong-verifier-wrong-verifier"},
+		{"wrong redirect uri", "https://app.example.com/other", testVerifier},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc, m := newOAuthServiceWithClient(t, ctrl, testOAuthClient())
+
+			m.codeRepo.EXPECT().Consume(gomock.Any(), gomock.Any(), gomock.Any()).Return(storedCode(), nil)
+			expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonBadAuthCode)
+
+			_, err := svc.ExchangeAuthorizationCode(context.Background(), domain.ClientCredentials{ID: testClientID}, code, tt.redirectURI, tt.verifier, "127.0.0.1", "")
+			assert.ErrorIs(t, err, domain.ErrInvalidGrant)
+		})
+	}
+
+	t.Run("used or expired code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testOAuthClient())
+
+		m.codeRepo.EXPECT().Consume(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, domain.ErrInvalidGrant)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonBadAuthCode)
+
+		_, err := svc.ExchangeAuthorizationCode(context.Background(), domain.ClientCredentials{ID: testClientID}, code, testRedirectURI, testVerifier, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidGrant)
+	})
+
+	t.Run("unknown client", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, _ := newOAuthServiceWithClient(t, ctrl, testOAuthClient())
+
+		_, err := svc.ExchangeAuthorizationCode(context.Background(), domain.ClientCredentials{ID: "other"}, code, testRedirectURI, testVerifier, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidClient)
+	})
+
+	t.Run("confidential client with own token settings", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		client := testOAuthClient()
+		client.SecretHash = crypto.HashToken([]byte("secret"))
+		client.GrantTypes = []domain.GrantType{domain.GrantTypeAuthorizationCode}
+		client.AccessTTL = 5 * time.Minute
+		client.RefreshTTL = 2 * time.Hour
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, client)
+		authCode := storedCode()
+		authCode.Scopes = []string{"profile"}
+
+		m.codeRepo.EXPECT().Consume(gomock.Any(), authCode.CodeHash, gomock.Any()).Return(authCode, nil)
+		m.tokenManager.EXPECT().Generate(authCode.UserID, gomock.Any(), "127.0.0.1", gomock.Any()).
+			DoAndReturn(func(guid, jti uuid.UUID, ip string, opts auth.TokenOptions) (string, error) {
+				assert.Equal(t, 5*time.Minute, opts.TTL)
+				assert.Equal(t, []string{"profile"}, opts.Scopes)
+				return "access", nil
+			})
+		m.tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), authCode.UserID, gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, id, jti, userID uuid.UUID, hash string, expiresAt time.Time) error {
+				assert.WithinDuration(t, time.Now().Add(2*time.Hour), expiresAt, time.Minute)
+				return nil
+			})
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+		result, err := svc.ExchangeAuthorizationCode(context.Background(), domain.ClientCredentials{ID: testClientID, Secret: "secret"}, code, testRedirectURI, testVerifier, "127.0.0.1", "")
+		require.NoError(t, err)
+		assert.Equal(t, 5*time.Minute, result.ExpiresIn)
+		assert.Equal(t, []string{"profile"}, result.Scopes)
+		assert.Empty(t, result.RefreshToken, "refresh token is issued only with refresh_token grant")
+	})
+
+	authTests := []struct {
+		name       string
+		secretHash string
+		secret     string
+	}{
+		{"confidential client with wrong secret", crypto.HashToken([]byte("secret")), "wrong"},
+		{"confidential client without secret", crypto.HashToken([]byte("secret")), ""},
+		{"public client with secret", "", "secret"},
+	}
+
+	for _, tt := range authTests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			client := testOAuthClient()
+			client.SecretHash = tt.secretHash
+			svc, _ := newOAuthServiceWithClient(t, ctrl, client)
+
+			_, err := svc.ExchangeAuthorizationCode(context.Background(), domain.ClientCredentials{ID: testClientID, Secret: tt.secret}, code, testRedirectURI, testVerifier, "127.0.0.1", "")
+			assert.ErrorIs(t, err, domain.ErrInvalidClient)
+		})
+	}
+
+	t.Run("grant not allowed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		client := testOAuthClient()
+		client.GrantTypes = []domain.GrantType{domain.GrantTypeRefreshToken}
+		svc, _ := newOAuthServiceWithClient(t, ctrl, client)
+
+		_, err := svc.ExchangeAuthorizationCode(context.Background(), domain.ClientCredentials{ID: testClientID}, code, testRedirectURI, testVerifier, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrUnauthorizedClient)
+	})
+}
+
+func TestAuthService_ExchangeRefreshToken(t *testing.T) {
+	t.Run("current client settings applied", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		client := testOAuthClient()
+		client.Scopes = []string{"email"}
+		client.AccessTTL = 5 * time.Minute
+		svc, m := newOAuthServiceWithClient(t, ctrl, client)
+		claims := mock_auth.NewMockClaims(ctrl)
+
+		guid, jti, storedTokenID := uuid.New(), uuid.New(), uuid.New()
+		oldRefresh := []byte("old_refresh")
+		hashedOldRefresh, err := crypto.HashBytes(oldRefresh)
+		require.NoError(t, err)
+
+		m.tokenManager.EXPECT().ParseExpired("access").Return(claims, nil).Times(2)
+		claims.EXPECT().GetClientID().Return(testClientID).Times(2)
+		claims.EXPECT().GetGUID().Return(guid)
+		claims.EXPECT().GetJTI().Return(jti)
+		claims.EXPECT().GetIP().Return("127.0.0.1")
+		claims.EXPECT().GetIssueTime().Return(time.Now())
+		claims.EXPECT().RequiresReauth().Return(false)
+		claims.EXPECT().GetAMR().Return([]string{domain.AMRPassword})
+		claims.EXPECT().GetScopes().Return([]string{"profile", "email"})
+		m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(storedTokenID, hashedOldRefresh, time.Time{}, nil)
+		m.tokenManager.EXPECT().Generate(guid, gomock.Any(), "127.0.0.1", gomock.Any()).
+			DoAndReturn(func(guid, jti uuid.UUID, ip string, opts auth.TokenOptions) (string, error) {
+				assert.Equal(t, []string{"email"}, opts.Scopes, "scopes no longer allowed to the client are dropped")
+				assert.Equal(t, 5*time.Minute, opts.TTL)
+				return "access2", nil
+			})
+		m.tokenRepo.EXPECT().RotateToken(gomock.Any(), storedTokenID, gomock.Any(), gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(nil)
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+
+		result, err := svc.ExchangeRefreshToken(context.Background(), domain.ClientCredentials{ID: testClientID}, "access",
+			base64.URLEncoding.EncodeToString(oldRefresh), "127.0.0.1", "")
+		require.NoError(t, err)
+		assert.Equal(t, "access2", result.AccessToken)
+		assert.Equal(t, []string{"email"}, result.Scopes)
+		assert.Equal(t, 5*time.Minute, result.ExpiresIn)
+	})
+
+	t.Run("grant not allowed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		client := testOAuthClient()
+		client.GrantTypes = []domain.GrantType{domain.GrantTypeAuthorizationCode}
+		svc, _ := newOAuthServiceWithClient(t, ctrl, client)
+
+		_, err := svc.ExchangeRefreshToken(context.Background(), domain.ClientCredentials{ID: testClientID}, "access", "refresh", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrUnauthorizedClient)
+	})
+
+	t.Run("token of another client", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testOAuthClient())
+		claims := mock_auth.NewMockClaims(ctrl)
+
+		m.tokenManager.EXPECT().ParseExpired("access").Return(claims, nil)
+		claims.EXPECT().GetClientID().Return("")
+
+		_, err := svc.ExchangeRefreshToken(context.Background(), domain.ClientCredentials{ID: testClientID}, "access", "refresh", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidGrant)
+	})
+
+	t.Run("invalid access token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testOAuthClient())
+
+		m.tokenManager.EXPECT().ParseExpired("access").Return(nil, auth.ErrInvalidToken)
+
+		_, err := svc.ExchangeRefreshToken(context.Background(), domain.ClientCredentials{ID: testClientID}, "access", "refresh", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidGrant)
+	})
+}
+
+func TestAuthService_ExchangeClientCredentials(t *testing.T) {
+	machineClient := func() *domain.OAuthClient {
+		return &domain.OAuthClient{
+			ID:         "backend",
+			Name:       "Backend jobs",
+			SecretHash: crypto.HashToken([]byte("secret")),
+			GrantTypes: []domain.GrantType{domain.GrantTypeClientCredentials},
+			Scopes:     []string{"jobs", "reports"},
+			AccessTTL:  5 * time.Minute,
+		}
+	}
+	creds := domain.ClientCredentials{ID: "backend", Secret: "secret"}
+
+	scopeTests := []struct {
+		name       string
+		scope      string
+		wantScopes []string
+	}{
+		{"all allowed scopes by default", "", []string{"jobs", "reports"}},
+		{"requested scopes", "reports", []string{"reports"}},
+	}
+
+	for _, tt := range scopeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc, m := newOAuthServiceWithClient(t, ctrl, machineClient())
+
+			m.tokenManager.EXPECT().Generate(uuid.Nil, gomock.Any(), "127.0.0.1", gomock.Any()).
+				DoAndReturn(func(guid, jti uuid.UUID, ip string, opts auth.TokenOptions) (string, error) {
+					assert.Equal(t, "backend", opts.ClientID)
+					assert.True(t, opts.ClientSubject)
+					assert.Equal(t, tt.wantScopes, opts.Scopes)
+					assert.Equal(t, 5*time.Minute, opts.TTL)
+					return "access", nil
+				})
+			m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.AuthEvent) error {
+				assert.Equal(t, domain.AuthEventTokenIssued, event.Type)
+				assert.Equal(t, uuid.Nil, event.GUID)
+				assert.Equal(t, "backend", event.Details["client_id"])
+				assert.Equal(t, "client_credentials", event.Details["grant_type"])
+				return nil
+			})
+
+			result, err := svc.ExchangeClientCredentials(context.Background(), creds, tt.scope, "127.0.0.1", "")
+			require.NoError(t, err)
+			assert.Equal(t, "access", result.AccessToken)
+			assert.Empty(t, result.RefreshToken)
+			assert.Equal(t, tt.wantScopes, result.Scopes)
+			assert.Equal(t, 5*time.Minute, result.ExpiresIn)
+		})
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(client *domain.OAuthClient)
+		creds   domain.ClientCredentials
+		scope   string
+		wantErr error
+	}{
+		{"scope not allowed", func(*domain.OAuthClient) {}, creds, "admin", domain.ErrInvalidScope},
+		{"invalid scope", func(*domain.OAuthClient) {}, creds, "jobs \"", domain.ErrInvalidScope},
+		{"wrong secret", func(*domain.OAuthClient) {}, domain.ClientCredentials{ID: "backend", Secret: "wrong"}, "", domain.ErrInvalidClient},
+		{"grant not allowed", func(client *domain.OAuthClient) {
+			client.GrantTypes = []domain.GrantType{domain.GrantTypeAuthorizationCode}
+		}, creds, "", domain.ErrUnauthorizedClient},
+		{"public client", func(client *domain.OAuthClient) {
+			client.SecretHash = ""
+		}, domain.ClientCredentials{ID: "backend"}, "", domain.ErrUnauthorizedClient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			client := machineClient()
+			tt.modify(client)
+			svc, _ := newOAuthServiceWithClient(t, ctrl, client)
+
+			_, err := svc.ExchangeClientCredentials(context.Background(), tt.creds, tt.scope, "127.0.0.1", "")
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
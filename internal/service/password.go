@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/password"
+	"github.com/maksemen2/medods-task/internal/pkg/risk"
+	"go.uber.org/zap"
+)
+
+// lookupCredentials возвращает guid пользователя и хеш пароля по email.
+// Некорректный email трактуется так же, как отсутствующий пользователь - domain.ErrCredentialsNotFound.
+func (s *AuthServiceImpl) lookupCredentials(ctx context.Context, email string) (uuid.UUID, string, error) {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return uuid.Nil, "", domain.ErrCredentialsNotFound
+	}
+
+	return s.credentialRepo.GetByEmail(ctx, email)
+}
+
+// auditPasswordFailure записывает в журнал аудита неудачную проверку пароля.
+func (s *AuthServiceImpl) auditPasswordFailure(ctx context.Context, eventType domain.AuthEventType, guid, jti uuid.UUID, ip, userAgent, reason string) {
+	s.audit(ctx, domain.AuthEvent{
+		Type:      eventType,
+		GUID:      guid,
+		JTI:       jti,
+		IP:        ip,
+		UserAgent: userAgent,
+		Reason:    reason,
+	})
+}
+
+// Login - аутентификация пользователя по email и паролю.
+// Для неизвестного email, пользователя без пароля и неверного пароля возвращается одна и та же ошибка
+// domain.ErrInvalidCredentials, а пароль проверяется по фиктивному хешу, чтобы время ответа не выдавало,
+// зарегистрирован ли email.
+// Если настроен движок оценки риска, вход может быть запрещен с ошибкой domain.ErrRiskDenied.
+// Если у пользователя подключен второй фактор, вместо пары токенов возвращается MFA-челлендж.
+func (s *AuthServiceImpl) Login(ctx context.Context, email, plainPassword, ip, userAgent string) (*domain.UserAuth, error) {
+	guid, jti, err := s.verifyPassword(ctx, email, plainPassword, ip, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.completeFirstFactor(ctx, guid, jti, ip, userAgent, domain.AuthMethodPassword)
+}
+
+// verifyPassword проверяет email и пароль и возвращает guid пользователя и ID будущей сессии.
+// Общий путь для входа по паролю через POST /login и страницу входа OAuth.
+func (s *AuthServiceImpl) verifyPassword(ctx context.Context, email, plainPassword, ip, userAgent string) (uuid.UUID, uuid.UUID, error) {
+	// Слишком длинный пароль не мог быть установлен при регистрации, и хешировать его не нужно
+	if password.TooLong(plainPassword) {
+		s.logger.Debug("Login with too long password")
+		s.recordFailure(ip)
+		s.auditPasswordFailure(ctx, domain.AuthEventLoginFailed, uuid.Nil, uuid.Nil, ip, userAgent, domain.FailureReasonBadPassword)
+		return uuid.Nil, uuid.Nil, domain.ErrInvalidCredentials
+	}
+
+	guid, hash, err := s.lookupCredentials(ctx, email)
+	if err != nil && !errors.Is(err, domain.ErrCredentialsNotFound) {
+		return uuid.Nil, uuid.Nil, domain.ErrUnexpected
+	}
+	found := err == nil
+
+	jti := uuid.New()
+
+	if s.riskEngine != nil {
+		input := risk.Input{Operation: risk.OperationLogin, RecentFailures: s.recentFailures(ip)}
+		if err := s.assessRisk(ctx, guid, jti, ip, userAgent, input); err != nil {
+			return uuid.Nil, uuid.Nil, err
+		}
+	}
+
+	if !found {
+		password.VerifyDummy(plainPassword)
+		s.logger.Debug("Login with unknown email")
+		s.recordFailure(ip)
+		s.auditPasswordFailure(ctx, domain.AuthEventLoginFailed, uuid.Nil, uuid.Nil, ip, userAgent, domain.FailureReasonUnknownEmail)
+		return uuid.Nil, uuid.Nil, domain.ErrInvalidCredentials
+	}
+
+	ok, err := password.Verify(plainPassword, hash)
+	if err != nil {
+		s.logger.Error("Error verifying password", zap.String("guid", guid.String()), zap.Error(err))
+		return uuid.Nil, uuid.Nil, domain.ErrUnexpected
+	}
+
+	if !ok {
+		s.logger.Debug("Login with invalid password", zap.String("guid", guid.String()))
+		s.recordFailure(ip)
+		s.auditPasswordFailure(ctx, domain.AuthEventLoginFailed, guid, uuid.Nil, ip, userAgent, domain.FailureReasonBadPassword)
+		return uuid.Nil, uuid.Nil, domain.ErrInvalidCredentials
+	}
+
+	return guid, jti, nil
+}
+
+// ChangePassword меняет пароль пользователя, которому принадлежит Access токен.
+// Сессия Access токена должна быть активна, иначе возвращается ошибка domain.ErrInvalidAccessToken.
+// Если текущий пароль неверен или не задан, возвращается ошибка domain.ErrInvalidCredentials,
+// если новый пароль не удовлетворяет требованиям - domain.ErrWeakPassword.
+// После смены пароля все Refresh токены пользователя, кроме токена текущей сессии, отзываются.
+// Уже выданные Access токены других сессий остаются действительными до истечения срока жизни.
+func (s *AuthServiceImpl) ChangePassword(ctx context.Context, accessToken, currentPassword, newPassword, ip, userAgent string) error {
+	claims, err := s.authorizeSession(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+
+	guid := claims.GetGUID()
+	jti := claims.GetJTI()
+
+	if err = password.Validate(newPassword); err != nil {
+		return domain.ErrWeakPassword
+	}
+
+	if password.TooLong(currentPassword) {
+		s.logger.Debug("Password change with too long current password", zap.String("guid", guid.String()))
+		s.recordFailure(ip)
+		s.auditPasswordFailure(ctx, domain.AuthEventPasswordChangeFailed, guid, jti, ip, userAgent, domain.FailureReasonBadPassword)
+		return domain.ErrInvalidCredentials
+	}
+
+	hash, err := s.credentialRepo.GetPasswordHash(ctx, guid)
+	if err != nil && !errors.Is(err, domain.ErrCredentialsNotFound) {
+		return domain.ErrUnexpected
+	}
+
+	if err != nil {
+		password.VerifyDummy(currentPassword)
+		s.recordFailure(ip)
+		s.auditPasswordFailure(ctx, domain.AuthEventPasswordChangeFailed, guid, jti, ip, userAgent, domain.FailureReasonNoPassword)
+		return domain.ErrInvalidCredentials
+	}
+
+	ok, err := password.Verify(currentPassword, hash)
+	if err != nil {
+		s.logger.Error("Error verifying password", zap.String("guid", guid.String()), zap.Error(err))
+		return domain.ErrUnexpected
+	}
+
+	if !ok {
+		s.logger.Debug("Password change with invalid current password", zap.String("guid", guid.String()))
+		s.recordFailure(ip)
+		s.auditPasswordFailure(ctx, domain.AuthEventPasswordChangeFailed, guid, jti, ip, userAgent, domain.FailureReasonBadPassword)
+		return domain.ErrInvalidCredentials
+	}
+
+	newHash, err := password.Hash(newPassword)
+	if err != nil {
+		s.logger.Error("Error hashing password", zap.Error(err))
+		return domain.ErrUnexpected
+	}
+
+	if err = s.credentialRepo.SetPassword(ctx, guid, newHash); err != nil {
+		return domain.ErrUnexpected
+	}
+
+	revoked, err := s.tokenRepo.RevokeUserTokens(ctx, guid, jti)
+	if err != nil {
+		s.logger.Error("Error revoking sessions after password change", zap.String("guid", guid.String()), zap.Error(err))
+		return domain.ErrUnexpected
+	}
+
+	s.logger.Info("Password changed", zap.String("guid", guid.String()), zap.Int("revoked_sessions", revoked))
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventPasswordChanged,
+		GUID:      guid,
+		JTI:       jti,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details:   map[string]any{"revoked_sessions": revoked},
+	})
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/password"
+	"github.com/maksemen2/medods-task/internal/pkg/mail"
+	"go.uber.org/zap"
+	"time"
+)
+
+const (
+	defaultPasswordResetTTL  = time.Hour // Время жизни токена сброса пароля по умолчанию
+	passwordResetTokenLength = 32        // Длина токена сброса пароля в байтах
+)
+
+// RequestPasswordReset отправляет на email одноразовую ссылку для сброса пароля.
+// Для незарегистрированного email письмо не отправляется, но ответ не отличается от успешного,
+// чтобы по нему нельзя было определить, зарегистрирован ли email.
+// Возвращает domain.ErrInvalidEmail, если email некорректен.
+func (s *AuthServiceImpl) RequestPasswordReset(ctx context.Context, email, ip, userAgent string) error {
+	if s.passwordResetRepo == nil {
+		return domain.ErrPasswordResetDisabled
+	}
+
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return err
+	}
+
+	guid, err := s.userRepo.GetGUIDByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			s.logger.Debug("Password reset requested for unknown email")
+			return nil
+		}
+		return domain.ErrUnexpected
+	}
+
+	token := make([]byte, passwordResetTokenLength)
+	if _, err = rand.Read(token); err != nil {
+		s.logger.Error("Error generating password reset token", zap.Error(err))
+		return domain.ErrUnexpected
+	}
+
+	now := time.Now().UTC()
+	reset := &domain.PasswordReset{
+		ID:        uuid.New(),
+		UserID:    guid,
+		TokenHash: crypto.HashToken(token),
+		ExpiresAt: now.Add(s.passwordResetTTL),
+		CreatedAt: now,
+	}
+
+	if err = s.passwordResetRepo.Create(ctx, reset); err != nil {
+		return domain.ErrUnexpected
+	}
+
+	link := linkWithToken(s.passwordResetURL, base64.RawURLEncoding.EncodeToString(token))
+	sendMailAsync(ctx, s.mailSender, s.logger, guid, mail.Message{
+		To:      email,
+		Subject: "Сброс пароля",
+		Body: "Чтобы задать новый пароль, перейдите по ссылке: " + link + "\n\n" +
+			"Ссылка действует до " + reset.ExpiresAt.Format(time.RFC1123) + " и может быть использована один раз.\n" +
+			"Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо, ваш пароль не изменится.",
+	})
+
+	s.logger.Info("Password reset sent", zap.String("guid", guid.String()))
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventPasswordResetSent,
+		GUID:      guid,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details:   map[string]any{"reset_id": reset.ID.String()},
+	})
+
+	return nil
+}
+
+// ResetPassword задает новый пароль по токену из письма. Токен одноразовый:
+// после сброса он и остальные токены сброса пользователя удаляются, а все Refresh токены пользователя отзываются.
+// Уже выданные Access токены остаются действительными до истечения срока жизни.
+// Возвращает domain.ErrWeakPassword, если новый пароль не удовлетворяет требованиям,
+// и domain.ErrInvalidResetToken, если токен некорректен, истек или уже использован.
+func (s *AuthServiceImpl) ResetPassword(ctx context.Context, token, newPassword, ip, userAgent string) error {
+	if s.passwordResetRepo == nil {
+		return domain.ErrPasswordResetDisabled
+	}
+
+	if err := password.Validate(newPassword); err != nil {
+		return domain.ErrWeakPassword
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != passwordResetTokenLength {
+		s.failPasswordReset(ctx, ip, userAgent)
+		return domain.ErrInvalidResetToken
+	}
+
+	newHash, err := password.Hash(newPassword)
+	if err != nil {
+		s.logger.Error("Error hashing password", zap.Error(err))
+		return domain.ErrUnexpected
+	}
+
+	guid, revoked, err := s.passwordResetRepo.Reset(ctx, crypto.HashToken(raw), newHash, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidResetToken) {
+			s.failPasswordReset(ctx, ip, userAgent)
+			return domain.ErrInvalidResetToken
+		}
+		return domain.ErrUnexpected
+	}
+
+	s.logger.Info("Password reset", zap.String("guid", guid.String()), zap.Int("revoked_sessions", revoked))
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventPasswordReset,
+		GUID:      guid,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details:   map[string]any{"revoked_sessions": revoked},
+	})
+
+	return nil
+}
+
+// failPasswordReset учитывает неудачную попытку сброса пароля.
+// Пользователь по неверному токену неизвестен, поэтому событие записывается без guid.
+func (s *AuthServiceImpl) failPasswordReset(ctx context.Context, ip, userAgent string) {
+	s.logger.Debug("Password reset with invalid token")
+	s.recordFailure(ip)
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventPasswordResetFailed,
+		IP:        ip,
+		UserAgent: userAgent,
+		Reason:    domain.FailureReasonBadResetToken,
+	})
+}
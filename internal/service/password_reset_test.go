@@ -0,0 +1,165 @@
+package service_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/password"
+	"github.com/maksemen2/medods-task/internal/pkg/mail"
+	mock_mail "github.com/maksemen2/medods-task/internal/pkg/mail/mocks"
+	mock_repository "github.com/maksemen2/medods-task/internal/repository/mocks"
+	"github.com/maksemen2/medods-task/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+const testResetURL = "https://example.com/password/reset"
+
+type passwordResetMocks struct {
+	passwordMocks
+	resetRepo *mock_repository.MockIPasswordResetRepo
+	sender    *mock_mail.MockSender
+}
+
+func newPasswordResetService(ctrl *gomock.Controller) (service.IAuthService, passwordResetMocks) {
+	_, password := newPasswordService(ctrl)
+	m := passwordResetMocks{
+		passwordMocks: password,
+		resetRepo:     mock_repository.NewMockIPasswordResetRepo(ctrl),
+		sender:        mock_mail.NewMockSender(ctrl),
+	}
+	svc := service.NewAuthServiceImpl(m.userRepo, m.tokenRepo, m.tokenManager, zap.NewNop(), time.Hour,
+		service.WithAuditRepo(m.auditRepo),
+		service.WithPasswordReset(m.resetRepo, m.sender, testResetURL, 30*time.Minute))
+	return svc, m
+}
+
+func TestAuthService_RequestPasswordReset(t *testing.T) {
+	t.Run("known email", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newPasswordResetService(ctrl)
+		guid := uuid.New()
+
+		var stored domain.PasswordReset
+		m.userRepo.EXPECT().GetGUIDByEmail(gomock.Any(), "user@example.com").Return(guid, nil)
+		m.resetRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, reset *domain.PasswordReset) error {
+			assert.Equal(t, guid, reset.UserID)
+			assert.WithinDuration(t, time.Now().Add(30*time.Minute), reset.ExpiresAt, time.Minute)
+			stored = *reset
+			return nil
+		})
+		sent := make(chan mail.Message, 1)
+		m.sender.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, msg mail.Message) error {
+			sent <- msg
+			return nil
+		})
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventPasswordResetSent, "")
+
+		require.NoError(t, svc.RequestPasswordReset(context.Background(), " User@Example.com ", "127.0.0.1", ""))
+
+		msg := receiveMail(t, sent)
+		assert.Equal(t, "user@example.com", msg.To)
+
+		link, err := url.Parse(linkPattern.FindString(msg.Body))
+		require.NoError(t, err)
+		assert.Equal(t, testResetURL, link.Scheme+"://"+link.Host+link.Path)
+
+		token, err := base64.RawURLEncoding.DecodeString(link.Query().Get("token"))
+		require.NoError(t, err)
+		assert.Equal(t, crypto.HashToken(token), stored.TokenHash)
+	})
+
+	t.Run("unknown email", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newPasswordResetService(ctrl)
+		m.userRepo.EXPECT().GetGUIDByEmail(gomock.Any(), "user@example.com").Return(uuid.Nil, domain.ErrUserNotFound)
+
+		assert.NoError(t, svc.RequestPasswordReset(context.Background(), "user@example.com", "127.0.0.1", ""))
+	})
+
+	t.Run("invalid email", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, _ := newPasswordResetService(ctrl)
+		assert.ErrorIs(t, svc.RequestPasswordReset(context.Background(), "user", "127.0.0.1", ""), domain.ErrInvalidEmail)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, _ := newPasswordService(ctrl)
+		assert.ErrorIs(t, svc.RequestPasswordReset(context.Background(), "user@example.com", "127.0.0.1", ""), domain.ErrPasswordResetDisabled)
+	})
+}
+
+func TestAuthService_ResetPassword(t *testing.T) {
+	token := make([]byte, 32)
+	encoded := base64.RawURLEncoding.EncodeToString(token)
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newPasswordResetService(ctrl)
+		guid := uuid.New()
+
+		m.resetRepo.EXPECT().Reset(gomock.Any(), crypto.HashToken(token), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, tokenHash, passwordHash string, now time.Time) (uuid.UUID, int, error) {
+				ok, err := password.Verify("new password", passwordHash)
+				require.NoError(t, err)
+				assert.True(t, ok)
+				return guid, 2, nil
+			})
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.AuthEvent) error {
+			assert.Equal(t, domain.AuthEventPasswordReset, event.Type)
+			assert.Equal(t, guid, event.GUID)
+			assert.Equal(t, 2, event.Details["revoked_sessions"])
+			return nil
+		})
+
+		assert.NoError(t, svc.ResetPassword(context.Background(), encoded, "new password", "127.0.0.1", ""))
+	})
+
+	t.Run("used or expired token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newPasswordResetService(ctrl)
+		m.resetRepo.EXPECT().Reset(gomock.Any(), crypto.HashToken(token), gomock.Any(), gomock.Any()).Return(uuid.Nil, 0, domain.ErrInvalidResetToken)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventPasswordResetFailed, domain.FailureReasonBadResetToken)
+
+		assert.ErrorIs(t, svc.ResetPassword(context.Background(), encoded, "new password", "127.0.0.1", ""), domain.ErrInvalidResetToken)
+	})
+
+	t.Run("malformed token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newPasswordResetService(ctrl)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventPasswordResetFailed, domain.FailureReasonBadResetToken)
+
+		assert.ErrorIs(t, svc.ResetPassword(context.Background(), "not a token", "new password", "127.0.0.1", ""), domain.ErrInvalidResetToken)
+	})
+
+	t.Run("weak password", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, _ := newPasswordResetService(ctrl)
+		assert.ErrorIs(t, svc.ResetPassword(context.Background(), encoded, "short", "127.0.0.1", ""), domain.ErrWeakPassword)
+	})
+}
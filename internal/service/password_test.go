@@ -0,0 +1,266 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	mock_auth "github.com/maksemen2/medods-task/internal/pkg/auth/mocks"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/password"
+	mock_repository "github.com/maksemen2/medods-task/internal/repository/mocks"
+	"github.com/maksemen2/medods-task/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+type passwordMocks struct {
+	userRepo       *mock_repository.MockIUserRepo
+	tokenRepo      *mock_repository.MockITokenRepo
+	credentialRepo *mock_repository.MockICredentialRepo
+	auditRepo      *mock_repository.MockIAuditRepo
+	tokenManager   *mock_auth.MockAccessTokenManager
+}
+
+func newPasswordService(ctrl *gomock.Controller) (service.IAuthService, passwordMocks) {
+	m := passwordMocks{
+		userRepo:       mock_repository.NewMockIUserRepo(ctrl),
+		tokenRepo:      mock_repository.NewMockITokenRepo(ctrl),
+		credentialRepo: mock_repository.NewMockICredentialRepo(ctrl),
+		auditRepo:      mock_repository.NewMockIAuditRepo(ctrl),
+		tokenManager:   mock_auth.NewMockAccessTokenManager(ctrl),
+	}
+	svc := service.NewAuthServiceImpl(m.userRepo, m.tokenRepo, m.tokenManager, zap.NewNop(), time.Hour,
+		service.WithCredentialRepo(m.credentialRepo), service.WithAuditRepo(m.auditRepo))
+	return svc, m
+}
+
+func expectAuditEvent(t *testing.T, auditRepo *mock_repository.MockIAuditRepo, eventType domain.AuthEventType, reason string) {
+	auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.AuthEvent) error {
+		assert.Equal(t, eventType, event.Type)
+		assert.Equal(t, reason, event.Reason)
+		return nil
+	})
+}
+
+func TestAuthService_Login(t *testing.T) {
+	hash, err := password.Hash("correct horse")
+	require.NoError(t, err)
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newPasswordService(ctrl)
+		guid := uuid.New()
+
+		m.credentialRepo.EXPECT().GetByEmail(gomock.Any(), "user@example.com").Return(guid, hash, nil)
+		m.tokenManager.EXPECT().Generate(guid, gomock.Any(), "127.0.0.1", gomock.Any()).Return("access", nil)
+		m.tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(nil)
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.AuthEvent) error {
+			assert.Equal(t, domain.AuthEventTokenIssued, event.Type)
+			assert.Equal(t, "password", event.Details["method"])
+			return nil
+		})
+
+		result, err := svc.Login(context.Background(), " User@Example.com", "correct horse", "127.0.0.1", "")
+		require.NoError(t, err)
+		assert.Equal(t, "access", result.AccessToken)
+	})
+
+	t.Run("unknown email", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newPasswordService(ctrl)
+
+		m.credentialRepo.EXPECT().GetByEmail(gomock.Any(), "user@example.com").Return(uuid.Nil, "", domain.ErrCredentialsNotFound)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonUnknownEmail)
+
+		_, err := svc.Login(context.Background(), "user@example.com", "correct horse", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	})
+
+	t.Run("invalid email", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newPasswordService(ctrl)
+
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonUnknownEmail)
+
+		_, err := svc.Login(context.Background(), "not-an-email", "correct horse", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	})
+
+	t.Run("bad password", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newPasswordService(ctrl)
+
+		m.credentialRepo.EXPECT().GetByEmail(gomock.Any(), gomock.Any()).Return(uuid.New(), hash, nil)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonBadPassword)
+
+		_, err := svc.Login(context.Background(), "user@example.com", "battery staple", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	})
+
+	t.Run("too long password", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newPasswordService(ctrl)
+
+		// Пароль отклоняется до поиска учетных данных и вычисления хеша
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonBadPassword)
+
+		_, err := svc.Login(context.Background(), "user@example.com", strings.Repeat("a", password.MaxLength+1), "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	})
+}
+
+// expectStepUpToken настраивает Access токен действующей сессии, который требует повторной аутентификации.
+func (m passwordMocks) expectStepUpToken(ctrl *gomock.Controller, token string) {
+	claims := mock_auth.NewMockClaims(ctrl)
+	guid, jti := uuid.New(), uuid.New()
+	m.tokenManager.EXPECT().Parse(token).Return(claims, nil)
+	claims.EXPECT().GetGUID().Return(guid).AnyTimes()
+	claims.EXPECT().GetJTI().Return(jti).AnyTimes()
+	claims.EXPECT().RequiresReauth().Return(true)
+	m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.New(), "hash", time.Time{}, nil)
+}
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	hash, err := password.Hash("correct horse")
+	require.NoError(t, err)
+
+	setup := func(ctrl *gomock.Controller) (service.IAuthService, passwordMocks, uuid.UUID, uuid.UUID) {
+		svc, m := newPasswordService(ctrl)
+		claims := mock_auth.NewMockClaims(ctrl)
+		guid, jti := uuid.New(), uuid.New()
+
+		m.tokenManager.EXPECT().Parse("access").Return(claims, nil)
+		claims.EXPECT().GetGUID().Return(guid).AnyTimes()
+		claims.EXPECT().GetJTI().Return(jti).AnyTimes()
+		claims.EXPECT().RequiresReauth().Return(false).AnyTimes()
+		return svc, m, guid, jti
+	}
+
+	t.Run("success revokes other sessions", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, guid, jti := setup(ctrl)
+
+		m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.New(), "hash", time.Time{}, nil)
+		m.credentialRepo.EXPECT().GetPasswordHash(gomock.Any(), guid).Return(hash, nil)
+		m.credentialRepo.EXPECT().SetPassword(gomock.Any(), guid, gomock.Any()).DoAndReturn(func(ctx context.Context, userID uuid.UUID, newHash string) error {
+			ok, err := password.Verify("battery staple", newHash)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			return nil
+		})
+		m.tokenRepo.EXPECT().RevokeUserTokens(gomock.Any(), guid, jti).Return(3, nil)
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.AuthEvent) error {
+			assert.Equal(t, domain.AuthEventPasswordChanged, event.Type)
+			assert.Equal(t, 3, event.Details["revoked_sessions"])
+			return nil
+		})
+
+		err := svc.ChangePassword(context.Background(), "access", "correct horse", "battery staple", "127.0.0.1", "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("wrong current password", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, guid, jti := setup(ctrl)
+
+		m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.New(), "hash", time.Time{}, nil)
+		m.credentialRepo.EXPECT().GetPasswordHash(gomock.Any(), guid).Return(hash, nil)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventPasswordChangeFailed, domain.FailureReasonBadPassword)
+
+		err := svc.ChangePassword(context.Background(), "access", "wrong password", "battery staple", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	})
+
+	t.Run("too long current password", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, guid, jti := setup(ctrl)
+
+		m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.New(), "hash", time.Time{}, nil)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventPasswordChangeFailed, domain.FailureReasonBadPassword)
+
+		err := svc.ChangePassword(context.Background(), "access", strings.Repeat("a", password.MaxLength+1), "battery staple", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	})
+
+	t.Run("no password set", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, guid, jti := setup(ctrl)
+
+		m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.New(), "hash", time.Time{}, nil)
+		m.credentialRepo.EXPECT().GetPasswordHash(gomock.Any(), guid).Return("", domain.ErrCredentialsNotFound)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventPasswordChangeFailed, domain.FailureReasonNoPassword)
+
+		err := svc.ChangePassword(context.Background(), "access", "correct horse", "battery staple", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	})
+
+	t.Run("weak new password", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, guid, jti := setup(ctrl)
+
+		m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.New(), "hash", time.Time{}, nil)
+
+		err := svc.ChangePassword(context.Background(), "access", "correct horse", "short", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrWeakPassword)
+	})
+
+	t.Run("revoked session", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, guid, jti := setup(ctrl)
+
+		m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.Nil, "", time.Time{}, domain.ErrTokenNotFound)
+
+		err := svc.ChangePassword(context.Background(), "access", "correct horse", "battery staple", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidAccessToken)
+	})
+
+	t.Run("token requires re-authentication", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newPasswordService(ctrl)
+		m.expectStepUpToken(ctrl, "step-up")
+
+		err := svc.ChangePassword(context.Background(), "step-up", "correct horse", "battery staple", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrReauthRequired)
+	})
+
+	t.Run("invalid access token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newPasswordService(ctrl)
+
+		m.tokenManager.EXPECT().Parse("bad").Return(nil, domain.ErrInvalidAccessToken)
+
+		err := svc.ChangePassword(context.Background(), "bad", "correct horse", "battery staple", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidAccessToken)
+	})
+}
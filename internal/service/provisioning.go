@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	"time"
+)
+
+// IProvisioningService - интерфейс для управления списком GUID,
+// для которых разрешено автоматическое создание пользователей.
+type IProvisioningService interface {
+	// AllowGUIDs добавляет GUID в список разрешенных и возвращает количество добавленных записей.
+	AllowGUIDs(ctx context.Context, guids []uuid.UUID) (int, error)
+	// DisallowGUID удаляет GUID из списка разрешенных.
+	DisallowGUID(ctx context.Context, guid uuid.UUID) error
+}
+
+type ProvisioningServiceImpl struct {
+	allowlistRepo repository.IAllowlistRepo
+	logger        *zap.Logger
+}
+
+func NewProvisioningServiceImpl(allowlistRepo repository.IAllowlistRepo, logger *zap.Logger) IProvisioningService {
+	return &ProvisioningServiceImpl{
+		allowlistRepo: allowlistRepo,
+		logger:        logger,
+	}
+}
+
+func (s *ProvisioningServiceImpl) AllowGUIDs(ctx context.Context, guids []uuid.UUID) (int, error) {
+	added, err := s.allowlistRepo.Add(ctx, guids)
+	if err != nil {
+		return 0, domain.ErrUnexpected
+	}
+
+	s.logger.Info("GUIDs added to provisioning allowlist", zap.Int("requested", len(guids)), zap.Int("added", added))
+
+	return added, nil
+}
+
+// DisallowGUID удаляет GUID из списка разрешенных.
+// Если GUID отсутствует в списке, возвращает ошибку domain.ErrAllowlistEntryNotFound.
+func (s *ProvisioningServiceImpl) DisallowGUID(ctx context.Context, guid uuid.UUID) error {
+	err := s.allowlistRepo.Remove(ctx, guid)
+	if err != nil {
+		if errors.Is(err, domain.ErrAllowlistEntryNotFound) {
+			return err
+		}
+		return domain.ErrUnexpected
+	}
+
+	s.logger.Info("GUID removed from provisioning allowlist", zap.String("guid", guid.String()))
+
+	return nil
+}
+
+// provisionUser проверяет, что пользователь с указанным guid зарегистрирован,
+// и при необходимости создает его в соответствии с режимом создания пользователей.
+// Возвращает domain.ErrUserNotFound, если режим не позволяет создать пользователя.
+func (s *AuthServiceImpl) provisionUser(ctx context.Context, guid uuid.UUID, ip, userAgent string) error {
+	exists, err := s.userRepo.Exists(ctx, guid)
+	if err != nil {
+		return domain.ErrUnexpected
+	}
+
+	if exists {
+		return nil
+	}
+
+	switch s.provisioningMode {
+	case domain.ProvisioningModeAuto:
+	case domain.ProvisioningModeAllowlist:
+		allowed, err := s.allowlistRepo.Contains(ctx, guid)
+		if err != nil {
+			return domain.ErrUnexpected
+		}
+		if !allowed {
+			s.logger.Debug("Authentication of unknown user not in allowlist", zap.String("guid", guid.String()))
+			return domain.ErrUserNotFound
+		}
+	default:
+		s.logger.Debug("Authentication of unknown user", zap.String("guid", guid.String()))
+		return domain.ErrUserNotFound
+	}
+
+	err = s.userRepo.Create(ctx, &domain.User{GUID: guid, CreatedAt: time.Now().UTC()}, "")
+	// Пользователь мог быть создан параллельным запросом - для нас это не проблема
+	if err != nil && !errors.Is(err, domain.ErrUserExists) {
+		return domain.ErrUnexpected
+	}
+
+	if err == nil {
+		s.logger.Info("User provisioned", zap.String("guid", guid.String()), zap.String("mode", string(s.provisioningMode)))
+		s.audit(ctx, domain.AuthEvent{
+			Type:      domain.AuthEventUserProvisioned,
+			GUID:      guid,
+			IP:        ip,
+			UserAgent: userAgent,
+			Details:   map[string]any{"mode": string(s.provisioningMode)},
+		})
+	}
+
+	return nil
+}
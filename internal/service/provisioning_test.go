@@ -0,0 +1,200 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	mock_auth "github.com/maksemen2/medods-task/internal/pkg/auth/mocks"
+	mock_repository "github.com/maksemen2/medods-task/internal/repository/mocks"
+	"github.com/maksemen2/medods-task/internal/service"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestAuthService_Provisioning(t *testing.T) {
+	type mocks struct {
+		userRepo      *mock_repository.MockIUserRepo
+		tokenRepo     *mock_repository.MockITokenRepo
+		allowlistRepo *mock_repository.MockIAllowlistRepo
+		auditRepo     *mock_repository.MockIAuditRepo
+		tokenManager  *mock_auth.MockAccessTokenManager
+	}
+
+	newService := func(ctrl *gomock.Controller, mode domain.ProvisioningMode) (service.IAuthService, mocks) {
+		m := mocks{
+			userRepo:      mock_repository.NewMockIUserRepo(ctrl),
+			tokenRepo:     mock_repository.NewMockITokenRepo(ctrl),
+			allowlistRepo: mock_repository.NewMockIAllowlistRepo(ctrl),
+			auditRepo:     mock_repository.NewMockIAuditRepo(ctrl),
+			tokenManager:  mock_auth.NewMockAccessTokenManager(ctrl),
+		}
+		svc := service.NewAuthServiceImpl(m.userRepo, m.tokenRepo, m.tokenManager, zap.NewNop(), time.Hour,
+			service.WithProvisioning(mode, m.allowlistRepo), service.WithAuditRepo(m.auditRepo))
+		return svc, m
+	}
+
+	expectTokensIssued := func(m mocks, guid uuid.UUID) {
+		m.tokenManager.EXPECT().Generate(guid, gomock.Any(), gomock.Any(), gomock.Any()).Return("access", nil)
+		m.tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(nil)
+	}
+
+	t.Run("auto creates unknown user", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl, domain.ProvisioningModeAuto)
+		guid := uuid.New()
+
+		m.userRepo.EXPECT().Exists(gomock.Any(), guid).Return(false, nil)
+		m.userRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, user *domain.User, passwordHash string) error {
+			assert.Equal(t, guid, user.GUID)
+			assert.Empty(t, user.Email)
+			return nil
+		})
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.AuthEvent) error {
+			assert.Equal(t, domain.AuthEventUserProvisioned, event.Type)
+			assert.Equal(t, "auto", event.Details["mode"])
+			return nil
+		})
+		expectTokensIssued(m, guid)
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+		_, err := svc.AuthenticateUser(context.Background(), guid, "127.0.0.1", "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("auto tolerates concurrent creation", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl, domain.ProvisioningModeAuto)
+		guid := uuid.New()
+
+		m.userRepo.EXPECT().Exists(gomock.Any(), guid).Return(false, nil)
+		m.userRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).Return(domain.ErrUserExists)
+		expectTokensIssued(m, guid)
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+		_, err := svc.AuthenticateUser(context.Background(), guid, "127.0.0.1", "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("deny rejects unknown user", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl, domain.ProvisioningModeDeny)
+		guid := uuid.New()
+
+		m.userRepo.EXPECT().Exists(gomock.Any(), guid).Return(false, nil)
+
+		_, err := svc.AuthenticateUser(context.Background(), guid, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+
+	t.Run("allowlist creates allowed user", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl, domain.ProvisioningModeAllowlist)
+		guid := uuid.New()
+
+		m.userRepo.EXPECT().Exists(gomock.Any(), guid).Return(false, nil)
+		m.allowlistRepo.EXPECT().Contains(gomock.Any(), guid).Return(true, nil)
+		m.userRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+		expectTokensIssued(m, guid)
+
+		_, err := svc.AuthenticateUser(context.Background(), guid, "127.0.0.1", "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("allowlist rejects unknown user", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl, domain.ProvisioningModeAllowlist)
+		guid := uuid.New()
+
+		m.userRepo.EXPECT().Exists(gomock.Any(), guid).Return(false, nil)
+		m.allowlistRepo.EXPECT().Contains(gomock.Any(), guid).Return(false, nil)
+
+		_, err := svc.AuthenticateUser(context.Background(), guid, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+
+	t.Run("registered user skips provisioning", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl, domain.ProvisioningModeAllowlist)
+		guid := uuid.New()
+
+		m.userRepo.EXPECT().Exists(gomock.Any(), guid).Return(true, nil)
+		expectTokensIssued(m, guid)
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+		_, err := svc.AuthenticateUser(context.Background(), guid, "127.0.0.1", "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl, domain.ProvisioningModeAuto)
+
+		m.userRepo.EXPECT().Exists(gomock.Any(), gomock.Any()).Return(false, errors.New("db error"))
+
+		_, err := svc.AuthenticateUser(context.Background(), uuid.New(), "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrUnexpected)
+	})
+}
+
+func TestProvisioningService(t *testing.T) {
+	t.Run("allow", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		allowlistRepo := mock_repository.NewMockIAllowlistRepo(ctrl)
+		svc := service.NewProvisioningServiceImpl(allowlistRepo, zap.NewNop())
+
+		guids := []uuid.UUID{uuid.New(), uuid.New()}
+		allowlistRepo.EXPECT().Add(gomock.Any(), guids).Return(1, nil)
+
+		added, err := svc.AllowGUIDs(context.Background(), guids)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, added)
+	})
+
+	t.Run("disallow missing", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		allowlistRepo := mock_repository.NewMockIAllowlistRepo(ctrl)
+		svc := service.NewProvisioningServiceImpl(allowlistRepo, zap.NewNop())
+
+		allowlistRepo.EXPECT().Remove(gomock.Any(), gomock.Any()).Return(domain.ErrAllowlistEntryNotFound)
+
+		err := svc.DisallowGUID(context.Background(), uuid.New())
+		assert.ErrorIs(t, err, domain.ErrAllowlistEntryNotFound)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		allowlistRepo := mock_repository.NewMockIAllowlistRepo(ctrl)
+		svc := service.NewProvisioningServiceImpl(allowlistRepo, zap.NewNop())
+
+		allowlistRepo.EXPECT().Remove(gomock.Any(), gomock.Any()).Return(errors.New("db error"))
+
+		err := svc.DisallowGUID(context.Background(), uuid.New())
+		assert.ErrorIs(t, err, domain.ErrUnexpected)
+	})
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/recovery"
+	"go.uber.org/zap"
+)
+
+// notifyRecoveryCodeUsed асинхронно уведомляет пользователя о входе с кодом восстановления.
+func (s *AuthServiceImpl) notifyRecoveryCodeUsed(ctx context.Context, guid uuid.UUID, remaining int) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := s.notifier.NotifyRecoveryCodeUsed(ctx, guid, remaining); err != nil {
+			s.logger.Error("Error notifying user about recovery code usage", zap.String("guid", guid.String()), zap.Error(err))
+		}
+	}()
+}
+
+// GenerateRecoveryCodes генерирует новый набор кодов восстановления для владельца Access токена.
+// Коды возвращаются один раз, в базе данных хранятся только их хеши. Предыдущий набор перестает действовать.
+// Коды доступны только пользователям с подключенным вторым фактором, иначе возвращается domain.ErrTOTPNotEnrolled.
+func (s *AuthServiceImpl) GenerateRecoveryCodes(ctx context.Context, accessToken, ip, userAgent string) ([]string, error) {
+	if s.recoveryRepo == nil {
+		return nil, domain.ErrMFADisabled
+	}
+
+	claims, err := s.authorizeSession(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	guid := claims.GetGUID()
+
+	enabled, err := s.mfaEnabled(ctx, guid)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, domain.ErrTOTPNotEnrolled
+	}
+
+	codes, err := recovery.Generate()
+	if err != nil {
+		s.logger.Error("Error generating recovery codes", zap.Error(err))
+		return nil, domain.ErrUnexpected
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		normalized, _ := recovery.Normalize(code)
+		hash, err := crypto.HashBytes([]byte(normalized))
+		if err != nil {
+			s.logger.Error("Error hashing recovery code", zap.Error(err))
+			return nil, domain.ErrUnexpected
+		}
+		hashes = append(hashes, hash)
+	}
+
+	if err = s.recoveryRepo.Replace(ctx, guid, hashes); err != nil {
+		return nil, domain.ErrUnexpected
+	}
+
+	s.logger.Info("Recovery codes generated", zap.String("guid", guid.String()))
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventRecoveryCodesIssued,
+		GUID:      guid,
+		JTI:       claims.GetJTI(),
+		IP:        ip,
+		UserAgent: userAgent,
+	})
+
+	return codes, nil
+}
+
+// VerifyMFARecovery проверяет код восстановления вместо второго фактора для MFA-челленджа и при успехе выдает пару токенов.
+// Каждый код можно использовать один раз. Об использовании кода пользователь уведомляется.
+// Возвращает domain.ErrInvalidMFAToken, если челлендж не найден, истек или уже использован,
+// и domain.ErrInvalidMFACode, если код неверен или уже был использован.
+func (s *AuthServiceImpl) VerifyMFARecovery(ctx context.Context, mfaToken, code, ip, userAgent string) (*domain.UserAuth, error) {
+	if s.recoveryRepo == nil || s.challengeRepo == nil {
+		return nil, domain.ErrMFADisabled
+	}
+
+	challenge, err := s.lookupMFAChallenge(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized, ok := recovery.Normalize(code)
+	if !ok {
+		s.failMFAChallenge(ctx, challenge, domain.FailureReasonBadRecoveryCode, ip, userAgent)
+		return nil, domain.ErrInvalidMFACode
+	}
+
+	codes, err := s.recoveryRepo.ListUnused(ctx, challenge.UserID)
+	if err != nil {
+		return nil, domain.ErrUnexpected
+	}
+
+	var matched *domain.RecoveryCode
+	for i := range codes {
+		if crypto.CompareHashAndBytes([]byte(normalized), codes[i].CodeHash) {
+			matched = &codes[i]
+			break
+		}
+	}
+
+	if matched == nil {
+		s.failMFAChallenge(ctx, challenge, domain.FailureReasonBadRecoveryCode, ip, userAgent)
+		return nil, domain.ErrInvalidMFACode
+	}
+
+	if err = s.consumeMFAChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	used, err := s.recoveryRepo.MarkUsed(ctx, matched.ID)
+	if err != nil {
+		return nil, domain.ErrUnexpected
+	}
+
+	if !used {
+		s.logger.Debug("Replayed recovery code", zap.String("guid", challenge.UserID.String()))
+		s.failMFAChallenge(ctx, challenge, domain.FailureReasonBadRecoveryCode, ip, userAgent)
+		return nil, domain.ErrInvalidMFACode
+	}
+
+	remaining := len(codes) - 1
+	s.logger.Warn("Recovery code used", zap.String("guid", challenge.UserID.String()), zap.Int("remaining", remaining))
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventRecoveryCodeUsed,
+		GUID:      challenge.UserID,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details:   map[string]any{"remaining": remaining},
+	})
+	s.notifyRecoveryCodeUsed(ctx, challenge.UserID, remaining)
+
+	return s.issueTokens(ctx, challenge.UserID, uuid.New(), ip, userAgent, challenge.Method, domain.AuthMethodRecoveryCode)
+}
@@ -0,0 +1,221 @@
+package service_test
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
+	mock_auth "github.com/maksemen2/medods-task/internal/pkg/auth/mocks"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/recovery"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/totp"
+	mock_repository "github.com/maksemen2/medods-task/internal/repository/mocks"
+	"github.com/maksemen2/medods-task/internal/service"
+	mock_service "github.com/maksemen2/medods-task/internal/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+type recoveryMocks struct {
+	mfaMocks
+	recoveryRepo *mock_repository.MockIRecoveryCodeRepo
+	notifier     *mock_service.MockINotifier
+}
+
+func newRecoveryService(t *testing.T, ctrl *gomock.Controller) (service.IAuthService, recoveryMocks) {
+	_, mfa := newMFAService(t, ctrl)
+	m := recoveryMocks{
+		mfaMocks:     mfa,
+		recoveryRepo: mock_repository.NewMockIRecoveryCodeRepo(ctrl),
+		notifier:     mock_service.NewMockINotifier(ctrl),
+	}
+	svc := service.NewAuthServiceImpl(m.userRepo, m.tokenRepo, m.tokenManager, zap.NewNop(), time.Hour,
+		service.WithAuditRepo(m.auditRepo), service.WithNotifier(m.notifier),
+		service.WithTOTP(m.totpRepo, m.challengeRepo, m.cipher, "medods-task", time.Minute),
+		service.WithRecoveryCodes(m.recoveryRepo))
+	return svc, m
+}
+
+func TestAuthService_GenerateRecoveryCodes(t *testing.T) {
+	setup := func(t *testing.T, ctrl *gomock.Controller) (service.IAuthService, recoveryMocks, uuid.UUID) {
+		svc, m := newRecoveryService(t, ctrl)
+		claims := mock_auth.NewMockClaims(ctrl)
+		guid, jti := uuid.New(), uuid.New()
+
+		m.tokenManager.EXPECT().Parse("access").Return(claims, nil)
+		claims.EXPECT().GetGUID().Return(guid).AnyTimes()
+		claims.EXPECT().GetJTI().Return(jti).AnyTimes()
+		claims.EXPECT().RequiresReauth().Return(false).AnyTimes()
+		m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.New(), "hash", time.Time{}, nil)
+		return svc, m, guid
+	}
+
+	t.Run("success stores hashes only", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, guid := setup(t, ctrl)
+
+		m.totpRepo.EXPECT().Get(gomock.Any(), guid).Return(m.enrollment(t, guid, make([]byte, totp.SecretLength), true), nil)
+
+		var stored []string
+		m.recoveryRepo.EXPECT().Replace(gomock.Any(), guid, gomock.Any()).DoAndReturn(func(ctx context.Context, userID uuid.UUID, hashes []string) error {
+			stored = hashes
+			return nil
+		})
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventRecoveryCodesIssued, "")
+
+		codes, err := svc.GenerateRecoveryCodes(context.Background(), "access", "127.0.0.1", "")
+		require.NoError(t, err)
+		require.Len(t, codes, recovery.Count)
+		require.Len(t, stored, recovery.Count)
+
+		for i, code := range codes {
+			assert.NotContains(t, stored[i], code)
+			normalized, _ := recovery.Normalize(code)
+			assert.True(t, crypto.CompareHashAndBytes([]byte(normalized), stored[i]))
+		}
+	})
+
+	t.Run("mfa not enabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, guid := setup(t, ctrl)
+
+		m.totpRepo.EXPECT().Get(gomock.Any(), guid).Return(m.enrollment(t, guid, make([]byte, totp.SecretLength), false), nil)
+
+		_, err := svc.GenerateRecoveryCodes(context.Background(), "access", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrTOTPNotEnrolled)
+	})
+
+	t.Run("token requires re-authentication", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newRecoveryService(t, ctrl)
+		m.expectStepUpToken(ctrl, "step-up")
+
+		_, err := svc.GenerateRecoveryCodes(context.Background(), "step-up", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrReauthRequired)
+	})
+}
+
+func TestAuthService_VerifyMFARecovery(t *testing.T) {
+	token := []byte("challenge-token")
+	mfaToken := base64.RawURLEncoding.EncodeToString(token)
+
+	hash, err := crypto.HashBytes([]byte("ABCDEFGH23"))
+	require.NoError(t, err)
+	otherHash, err := crypto.HashBytes([]byte("ZZZZZZZZZZ"))
+	require.NoError(t, err)
+
+	setup := func(t *testing.T, ctrl *gomock.Controller) (service.IAuthService, recoveryMocks, *domain.MFAChallenge, []domain.RecoveryCode) {
+		svc, m := newRecoveryService(t, ctrl)
+		challenge := &domain.MFAChallenge{
+			ID:        uuid.New(),
+			TokenHash: crypto.HashToken(token),
+			UserID:    uuid.New(),
+			Method:    domain.AuthMethodPassword,
+			ExpiresAt: time.Now().Add(time.Minute),
+		}
+		codes := []domain.RecoveryCode{
+			{ID: uuid.New(), UserID: challenge.UserID, CodeHash: otherHash},
+			{ID: uuid.New(), UserID: challenge.UserID, CodeHash: hash},
+		}
+		m.challengeRepo.EXPECT().GetByTokenHash(gomock.Any(), challenge.TokenHash, gomock.Any()).Return(challenge, nil)
+		return svc, m, challenge, codes
+	}
+
+	t.Run("success notifies user", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, challenge, codes := setup(t, ctrl)
+
+		m.recoveryRepo.EXPECT().ListUnused(gomock.Any(), challenge.UserID).Return(codes, nil)
+		m.challengeRepo.EXPECT().Delete(gomock.Any(), challenge.ID).Return(nil)
+		m.recoveryRepo.EXPECT().MarkUsed(gomock.Any(), codes[1].ID).Return(true, nil)
+
+		notified := make(chan struct{})
+		m.notifier.EXPECT().NotifyRecoveryCodeUsed(gomock.Any(), challenge.UserID, 1).DoAndReturn(func(ctx context.Context, guid uuid.UUID, remaining int) error {
+			close(notified)
+			return nil
+		})
+		m.tokenManager.EXPECT().Generate(challenge.UserID, gomock.Any(), "127.0.0.1", gomock.Any()).
+			DoAndReturn(func(guid, jti uuid.UUID, ip string, opts auth.TokenOptions) (string, error) {
+				assert.Equal(t, []string{domain.AMRPassword, domain.AMRMFA}, opts.AMR)
+				return "access", nil
+			})
+		m.tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), challenge.UserID, gomock.Any(), gomock.Any()).Return(nil)
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.AuthEvent) error {
+			assert.Equal(t, domain.AuthEventRecoveryCodeUsed, event.Type)
+			assert.Equal(t, 1, event.Details["remaining"])
+			return nil
+		})
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.AuthEvent) error {
+			assert.Equal(t, domain.AuthEventTokenIssued, event.Type)
+			assert.Equal(t, "password+recovery_code", event.Details["method"])
+			return nil
+		})
+
+		result, err := svc.VerifyMFARecovery(context.Background(), mfaToken, "abcde-fgh23", "127.0.0.1", "")
+		require.NoError(t, err)
+		assert.Equal(t, "access", result.AccessToken)
+
+		select {
+		case <-notified:
+		case <-time.After(time.Second):
+			t.Fatal("user was not notified about recovery code usage")
+		}
+	})
+
+	t.Run("wrong code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, challenge, codes := setup(t, ctrl)
+
+		m.recoveryRepo.EXPECT().ListUnused(gomock.Any(), challenge.UserID).Return(codes, nil)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventMFAFailed, domain.FailureReasonBadRecoveryCode)
+		m.challengeRepo.EXPECT().IncrementAttempts(gomock.Any(), challenge.ID).Return(1, nil)
+
+		_, err := svc.VerifyMFARecovery(context.Background(), mfaToken, "AAAAA-AAAAA", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidMFACode)
+	})
+
+	t.Run("malformed code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, challenge, _ := setup(t, ctrl)
+
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventMFAFailed, domain.FailureReasonBadRecoveryCode)
+		m.challengeRepo.EXPECT().IncrementAttempts(gomock.Any(), challenge.ID).Return(1, nil)
+
+		_, err := svc.VerifyMFARecovery(context.Background(), mfaToken, "123456", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidMFACode)
+	})
+
+	t.Run("code used concurrently", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, challenge, codes := setup(t, ctrl)
+
+		m.recoveryRepo.EXPECT().ListUnused(gomock.Any(), challenge.UserID).Return(codes, nil)
+		m.challengeRepo.EXPECT().Delete(gomock.Any(), challenge.ID).Return(nil)
+		m.recoveryRepo.EXPECT().MarkUsed(gomock.Any(), codes[1].ID).Return(false, nil)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventMFAFailed, domain.FailureReasonBadRecoveryCode)
+		m.challengeRepo.EXPECT().IncrementAttempts(gomock.Any(), challenge.ID).Return(0, domain.ErrMFAChallengeNotFound)
+
+		_, err := svc.VerifyMFARecovery(context.Background(), mfaToken, "ABCDE-FGH23", "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidMFACode)
+	})
+}
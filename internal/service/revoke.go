@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"go.uber.org/zap"
+)
+
+// RevokeToken отзывает сессию, которой выдан Access токен: удаляет ее Refresh токен, после чего пара токенов
+// больше не обновляется, а Access токен отклоняется /verify. Если allSessions равен true, отзываются все сессии пользователя.
+// Возвращает количество отозванных сессий.
+// Токен, требующий повторной аутентификации, тоже позволяет отозвать сессию: выход не расширяет доступ.
+func (s *AuthServiceImpl) RevokeToken(ctx context.Context, accessToken string, allSessions bool, ip, userAgent string) (int, error) {
+	claims, err := s.sessionClaims(ctx, accessToken)
+	if err != nil {
+		return 0, err
+	}
+
+	guid := claims.GetGUID()
+	jti := claims.GetJTI()
+
+	revoked := 1
+	if allSessions {
+		revoked, err = s.tokenRepo.RevokeUserTokens(ctx, guid, uuid.Nil)
+	} else {
+		err = s.tokenRepo.RevokeToken(ctx, guid, jti)
+	}
+
+	if err != nil {
+		// Сессию успели отозвать параллельным запросом
+		if errors.Is(err, domain.ErrTokenNotFound) {
+			return 0, domain.ErrInvalidAccessToken
+		}
+		s.logger.Error("Error revoking sessions", zap.String("guid", guid.String()), zap.Error(err))
+		return 0, domain.ErrUnexpected
+	}
+
+	// Отзыв текущего токена вступает в силу для /verify сразу, без ожидания истечения кеша
+	s.verifyCache.set(jti, true)
+
+	s.logger.Info("Sessions revoked", zap.String("guid", guid.String()), zap.Int("revoked_sessions", revoked))
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventTokenRevoked,
+		GUID:      guid,
+		JTI:       jti,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details:   map[string]any{"revoked_sessions": revoked, "all_sessions": allSessions},
+	})
+
+	return revoked, nil
+}
@@ -0,0 +1,134 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	mock_auth "github.com/maksemen2/medods-task/internal/pkg/auth/mocks"
+	mock_repository "github.com/maksemen2/medods-task/internal/repository/mocks"
+	"github.com/maksemen2/medods-task/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestAuthService_RevokeToken(t *testing.T) {
+	type mocks struct {
+		tokenRepo    *mock_repository.MockITokenRepo
+		auditRepo    *mock_repository.MockIAuditRepo
+		tokenManager *mock_auth.MockAccessTokenManager
+	}
+
+	newService := func(ctrl *gomock.Controller) (service.IAuthService, mocks) {
+		m := mocks{
+			tokenRepo:    mock_repository.NewMockITokenRepo(ctrl),
+			auditRepo:    mock_repository.NewMockIAuditRepo(ctrl),
+			tokenManager: mock_auth.NewMockAccessTokenManager(ctrl),
+		}
+		svc := service.NewAuthServiceImpl(nil, m.tokenRepo, m.tokenManager, zap.NewNop(), time.Hour, service.WithAuditRepo(m.auditRepo))
+		return svc, m
+	}
+
+	session := func(ctrl *gomock.Controller, m mocks, guid, jti uuid.UUID) {
+		claims := mock_auth.NewMockClaims(ctrl)
+		claims.EXPECT().GetGUID().Return(guid).AnyTimes()
+		claims.EXPECT().GetJTI().Return(jti).AnyTimes()
+		m.tokenManager.EXPECT().Parse("access").Return(claims, nil)
+		m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.New(), "hash", time.Time{}, nil)
+	}
+
+	t.Run("current session", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl)
+		guid, jti := uuid.New(), uuid.New()
+
+		session(ctrl, m, guid, jti)
+		m.tokenRepo.EXPECT().RevokeToken(gomock.Any(), guid, jti).Return(nil)
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.AuthEvent) error {
+			assert.Equal(t, domain.AuthEventTokenRevoked, event.Type)
+			assert.Equal(t, false, event.Details["all_sessions"])
+			return nil
+		})
+
+		revoked, err := svc.RevokeToken(context.Background(), "access", false, "127.0.0.1", "")
+		require.NoError(t, err)
+		assert.Equal(t, 1, revoked)
+	})
+
+	t.Run("all sessions", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl)
+		guid, jti := uuid.New(), uuid.New()
+
+		session(ctrl, m, guid, jti)
+		m.tokenRepo.EXPECT().RevokeUserTokens(gomock.Any(), guid, uuid.Nil).Return(3, nil)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventTokenRevoked, "")
+
+		revoked, err := svc.RevokeToken(context.Background(), "access", true, "127.0.0.1", "")
+		require.NoError(t, err)
+		assert.Equal(t, 3, revoked)
+	})
+
+	t.Run("revoked token is rejected by verify", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl)
+		guid, jti := uuid.New(), uuid.New()
+
+		session(ctrl, m, guid, jti)
+		m.tokenRepo.EXPECT().RevokeToken(gomock.Any(), guid, jti).Return(nil)
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+		_, err := svc.RevokeToken(context.Background(), "access", false, "127.0.0.1", "")
+		require.NoError(t, err)
+
+		claims := mock_auth.NewMockClaims(ctrl)
+		claims.EXPECT().GetJTI().Return(jti).AnyTimes()
+		claims.EXPECT().GetSubjectID().Return(guid.String()).AnyTimes()
+		m.tokenManager.EXPECT().Parse("access").Return(claims, nil)
+
+		_, err = svc.VerifyToken(context.Background(), "access")
+		assert.ErrorIs(t, err, domain.ErrInvalidAccessToken)
+	})
+
+	t.Run("revoked session", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl)
+		guid, jti := uuid.New(), uuid.New()
+
+		claims := mock_auth.NewMockClaims(ctrl)
+		claims.EXPECT().GetGUID().Return(guid).AnyTimes()
+		claims.EXPECT().GetJTI().Return(jti).AnyTimes()
+		m.tokenManager.EXPECT().Parse("access").Return(claims, nil)
+		m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.Nil, "", time.Time{}, domain.ErrTokenNotFound)
+
+		_, err := svc.RevokeToken(context.Background(), "access", false, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidAccessToken)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl)
+		guid, jti := uuid.New(), uuid.New()
+
+		session(ctrl, m, guid, jti)
+		m.tokenRepo.EXPECT().RevokeUserTokens(gomock.Any(), guid, uuid.Nil).Return(0, errors.New("db error"))
+
+		_, err := svc.RevokeToken(context.Background(), "access", true, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrUnexpected)
+	})
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/oauth"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	"slices"
+)
+
+const maxRoleNameLen = 64 // Максимальная длина названия роли
+
+// IRoleService - интерфейс для управления ролями пользователей и их разрешениями.
+// Роли и разрешения попадают в Access токены пользователя при их выпуске и обновлении.
+type IRoleService interface {
+	// SaveRole создает роль или заменяет ее разрешения.
+	SaveRole(ctx context.Context, role domain.Role) (*domain.Role, error)
+	// ListRoles возвращает все роли с их разрешениями.
+	ListRoles(ctx context.Context) ([]domain.Role, error)
+	// DeleteRole удаляет роль, снимая ее со всех пользователей.
+	DeleteRole(ctx context.Context, name string) error
+	// GetUserRoles возвращает роли пользователя.
+	GetUserRoles(ctx context.Context, guid uuid.UUID) ([]domain.Role, error)
+	// SetUserRoles заменяет роли пользователя.
+	SetUserRoles(ctx context.Context, guid uuid.UUID, roles []string) ([]domain.Role, error)
+}
+
+type RoleServiceImpl struct {
+	roleRepo repository.IRoleRepo
+	userRepo repository.IUserRepo
+	logger   *zap.Logger
+}
+
+func NewRoleServiceImpl(roleRepo repository.IRoleRepo, userRepo repository.IUserRepo, logger *zap.Logger) IRoleService {
+	return &RoleServiceImpl{
+		roleRepo: roleRepo,
+		userRepo: userRepo,
+		logger:   logger,
+	}
+}
+
+// WithRoles добавляет в Access токены, выданные пользователю напрямую, его роли (claim roles)
+// и разрешения этих ролей (claim scope). Токены клиентов OAuth по-прежнему несут только согласованные области доступа.
+func WithRoles(roleRepo repository.IRoleRepo) AuthServiceOption {
+	return func(s *AuthServiceImpl) {
+		s.roleRepo = roleRepo
+	}
+}
+
+// userAccess возвращает названия ролей пользователя и объединение их разрешений без повторов.
+// Если роли не настроены, возвращает nil.
+func (s *AuthServiceImpl) userAccess(ctx context.Context, guid uuid.UUID) ([]string, []string, error) {
+	if s.roleRepo == nil {
+		return nil, nil, nil
+	}
+
+	roles, err := s.roleRepo.GetUserRoles(ctx, guid)
+	if err != nil {
+		s.logger.Error("Error getting user roles", zap.String("guid", guid.String()), zap.Error(err))
+		return nil, nil, domain.ErrUnexpected
+	}
+
+	var names, permissions []string
+	for _, role := range roles {
+		names = append(names, role.Name)
+		permissions = append(permissions, role.Permissions...)
+	}
+
+	return names, uniqueSorted(permissions), nil
+}
+
+// validRoleName проверяет название роли. Название должно быть допустимой областью доступа (RFC 6749, раздел 3.3),
+// чтобы его можно было передавать в claim roles и в параметрах запросов без экранирования.
+func validRoleName(name string) bool {
+	return len(name) <= maxRoleNameLen && oauth.ValidScope(name)
+}
+
+// uniqueSorted возвращает отсортированную копию строк без повторов.
+func uniqueSorted(values []string) []string {
+	result := slices.Clone(values)
+	slices.Sort(result)
+	return slices.Compact(result)
+}
+
+// SaveRole создает роль или заменяет ее разрешения. Повторяющиеся разрешения удаляются.
+// Новые разрешения попадают в токены пользователей при их обновлении.
+// Возвращает domain.ErrInvalidRole, если название роли или разрешения недопустимы.
+func (s *RoleServiceImpl) SaveRole(ctx context.Context, role domain.Role) (*domain.Role, error) {
+	if !validRoleName(role.Name) {
+		return nil, domain.ErrInvalidRole
+	}
+	for _, permission := range role.Permissions {
+		if !oauth.ValidScope(permission) {
+			return nil, domain.ErrInvalidRole
+		}
+	}
+	role.Permissions = uniqueSorted(role.Permissions)
+
+	if err := s.roleRepo.Save(ctx, &role); err != nil {
+		return nil, domain.ErrUnexpected
+	}
+
+	s.logger.Info("Role saved", zap.String("role", role.Name), zap.Strings("permissions", role.Permissions))
+
+	return &role, nil
+}
+
+func (s *RoleServiceImpl) ListRoles(ctx context.Context) ([]domain.Role, error) {
+	roles, err := s.roleRepo.List(ctx)
+	if err != nil {
+		return nil, domain.ErrUnexpected
+	}
+	return roles, nil
+}
+
+// DeleteRole удаляет роль, снимая ее со всех пользователей.
+// Уже выданные Access токены сохраняют роль до истечения, но при обновлении теряют ее.
+// Возвращает domain.ErrRoleNotFound, если роль не найдена.
+func (s *RoleServiceImpl) DeleteRole(ctx context.Context, name string) error {
+	if err := s.roleRepo.Delete(ctx, name); err != nil {
+		if errors.Is(err, domain.ErrRoleNotFound) {
+			return err
+		}
+		return domain.ErrUnexpected
+	}
+
+	s.logger.Info("Role deleted", zap.String("role", name))
+
+	return nil
+}
+
+// GetUserRoles возвращает роли пользователя с их разрешениями.
+// Возвращает domain.ErrUserNotFound, если пользователь не найден.
+func (s *RoleServiceImpl) GetUserRoles(ctx context.Context, guid uuid.UUID) ([]domain.Role, error) {
+	exists, err := s.userRepo.Exists(ctx, guid)
+	if err != nil {
+		return nil, domain.ErrUnexpected
+	}
+	if !exists {
+		return nil, domain.ErrUserNotFound
+	}
+
+	roles, err := s.roleRepo.GetUserRoles(ctx, guid)
+	if err != nil {
+		return nil, domain.ErrUnexpected
+	}
+	return roles, nil
+}
+
+// SetUserRoles заменяет роли пользователя и возвращает назначенные роли с их разрешениями.
+// Пустой список снимает с пользователя все роли. Изменения попадают в токены пользователя при их обновлении.
+// Возвращает domain.ErrUserNotFound, если пользователь не найден, domain.ErrInvalidRole, если название роли недопустимо,
+// и domain.ErrRoleNotFound, если какая-либо из ролей не найдена.
+func (s *RoleServiceImpl) SetUserRoles(ctx context.Context, guid uuid.UUID, roles []string) ([]domain.Role, error) {
+	for _, role := range roles {
+		if !validRoleName(role) {
+			return nil, domain.ErrInvalidRole
+		}
+	}
+	roles = uniqueSorted(roles)
+
+	exists, err := s.userRepo.Exists(ctx, guid)
+	if err != nil {
+		return nil, domain.ErrUnexpected
+	}
+	if !exists {
+		return nil, domain.ErrUserNotFound
+	}
+
+	if err := s.roleRepo.SetUserRoles(ctx, guid, roles); err != nil {
+		if errors.Is(err, domain.ErrRoleNotFound) || errors.Is(err, domain.ErrUserNotFound) {
+			return nil, err
+		}
+		return nil, domain.ErrUnexpected
+	}
+
+	s.logger.Info("User roles updated", zap.String("guid", guid.String()), zap.Strings("roles", roles))
+
+	return s.GetUserRoles(ctx, guid)
+}
@@ -0,0 +1,226 @@
+package service_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
+	mock_auth "github.com/maksemen2/medods-task/internal/pkg/auth/mocks"
+	mock_repository "github.com/maksemen2/medods-task/internal/repository/mocks"
+	"github.com/maksemen2/medods-task/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func newRoleService(ctrl *gomock.Controller) (service.IRoleService, *mock_repository.MockIRoleRepo, *mock_repository.MockIUserRepo) {
+	roleRepo := mock_repository.NewMockIRoleRepo(ctrl)
+	userRepo := mock_repository.NewMockIUserRepo(ctrl)
+	return service.NewRoleServiceImpl(roleRepo, userRepo, zap.NewNop()), roleRepo, userRepo
+}
+
+func TestRoleService_SaveRole(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, roleRepo, _ := newRoleService(ctrl)
+
+		roleRepo.EXPECT().Save(gomock.Any(), &domain.Role{Name: "support", Permissions: []string{"orders:read", "users:read"}}).Return(nil)
+
+		role, err := svc.SaveRole(context.Background(), domain.Role{Name: "support", Permissions: []string{"users:read", "orders:read", "users:read"}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"orders:read", "users:read"}, role.Permissions)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, _, _ := newRoleService(ctrl)
+
+		for _, role := range []domain.Role{
+			{Name: ""},
+			{Name: "team lead"},
+			{Name: "support", Permissions: []string{"users read"}},
+			{Name: "support", Permissions: []string{""}},
+		} {
+			_, err := svc.SaveRole(context.Background(), role)
+			assert.ErrorIs(t, err, domain.ErrInvalidRole)
+		}
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, roleRepo, _ := newRoleService(ctrl)
+
+		roleRepo.EXPECT().Save(gomock.Any(), gomock.Any()).Return(errors.New("db error"))
+
+		_, err := svc.SaveRole(context.Background(), domain.Role{Name: "support"})
+		assert.ErrorIs(t, err, domain.ErrUnexpected)
+	})
+}
+
+func TestRoleService_DeleteRole(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc, roleRepo, _ := newRoleService(ctrl)
+
+	roleRepo.EXPECT().Delete(gomock.Any(), "support").Return(nil)
+	assert.NoError(t, svc.DeleteRole(context.Background(), "support"))
+
+	roleRepo.EXPECT().Delete(gomock.Any(), "support").Return(domain.ErrRoleNotFound)
+	assert.ErrorIs(t, svc.DeleteRole(context.Background(), "support"), domain.ErrRoleNotFound)
+}
+
+func TestRoleService_SetUserRoles(t *testing.T) {
+	guid := uuid.New()
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, roleRepo, userRepo := newRoleService(ctrl)
+
+		userRepo.EXPECT().Exists(gomock.Any(), guid).Return(true, nil).Times(2)
+		roleRepo.EXPECT().SetUserRoles(gomock.Any(), guid, []string{"admin", "support"}).Return(nil)
+		roleRepo.EXPECT().GetUserRoles(gomock.Any(), guid).Return([]domain.Role{
+			{Name: "admin", Permissions: []string{"users:write"}},
+			{Name: "support", Permissions: []string{"users:read"}},
+		}, nil)
+
+		roles, err := svc.SetUserRoles(context.Background(), guid, []string{"support", "admin", "support"})
+		require.NoError(t, err)
+		assert.Len(t, roles, 2)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, _, userRepo := newRoleService(ctrl)
+
+		userRepo.EXPECT().Exists(gomock.Any(), guid).Return(false, nil)
+
+		_, err := svc.SetUserRoles(context.Background(), guid, []string{"admin"})
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+
+	t.Run("role not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, roleRepo, userRepo := newRoleService(ctrl)
+
+		userRepo.EXPECT().Exists(gomock.Any(), guid).Return(true, nil)
+		roleRepo.EXPECT().SetUserRoles(gomock.Any(), guid, []string{"unknown"}).Return(domain.ErrRoleNotFound)
+
+		_, err := svc.SetUserRoles(context.Background(), guid, []string{"unknown"})
+		assert.ErrorIs(t, err, domain.ErrRoleNotFound)
+	})
+
+	t.Run("invalid role name", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, _, _ := newRoleService(ctrl)
+
+		_, err := svc.SetUserRoles(context.Background(), guid, []string{"team lead"})
+		assert.ErrorIs(t, err, domain.ErrInvalidRole)
+	})
+}
+
+func TestAuthService_Roles(t *testing.T) {
+	guid := uuid.New()
+	userRoles := []domain.Role{
+		{Name: "admin", Permissions: []string{"users:read", "users:write"}},
+		{Name: "support", Permissions: []string{"orders:read", "users:read"}},
+	}
+	wantOpts := auth.TokenOptions{
+		Roles:       []string{"admin", "support"},
+		Permissions: []string{"orders:read", "users:read", "users:write"},
+	}
+
+	t.Run("authentication adds roles and permissions", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		tokenRepo := mock_repository.NewMockITokenRepo(ctrl)
+		roleRepo := mock_repository.NewMockIRoleRepo(ctrl)
+		tokenManager := mock_auth.NewMockAccessTokenManager(ctrl)
+		svc := service.NewAuthServiceImpl(userRepo, tokenRepo, tokenManager, zap.NewNop(), time.Hour, service.WithRoles(roleRepo))
+
+		userRepo.EXPECT().Exists(gomock.Any(), guid).Return(true, nil)
+		roleRepo.EXPECT().GetUserRoles(gomock.Any(), guid).Return(userRoles, nil)
+		tokenManager.EXPECT().Generate(guid, gomock.Any(), "ip", gomock.Any()).DoAndReturn(func(guid, jti uuid.UUID, ip string, opts auth.TokenOptions) (string, error) {
+			assert.Equal(t, wantOpts.Roles, opts.Roles)
+			assert.Equal(t, wantOpts.Permissions, opts.Permissions)
+			return "access", nil
+		})
+		tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(nil)
+
+		result, err := svc.AuthenticateUser(context.Background(), guid, "ip", "")
+		require.NoError(t, err)
+		assert.Equal(t, "access", result.AccessToken)
+	})
+
+	t.Run("refresh rereads roles", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		tokenRepo := mock_repository.NewMockITokenRepo(ctrl)
+		roleRepo := mock_repository.NewMockIRoleRepo(ctrl)
+		tokenManager := mock_auth.NewMockAccessTokenManager(ctrl)
+		claims := mock_auth.NewMockClaims(ctrl)
+		svc := service.NewAuthServiceImpl(userRepo, tokenRepo, tokenManager, zap.NewNop(), time.Hour, service.WithRoles(roleRepo))
+
+		jti := uuid.New()
+		refreshToken := []byte("refresh")
+		hash, err := crypto.HashBytes(refreshToken)
+		require.NoError(t, err)
+
+		tokenManager.EXPECT().ParseExpired("access").Return(claims, nil)
+		claims.EXPECT().GetGUID().Return(guid)
+		claims.EXPECT().GetJTI().Return(jti)
+		claims.EXPECT().GetIP().Return("ip")
+		claims.EXPECT().RequiresReauth().Return(false)
+		claims.EXPECT().GetAMR().Return(nil)
+		claims.EXPECT().GetClientID().Return("")
+		claims.EXPECT().GetIssueTime().Return(time.Now())
+		tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.New(), hash, time.Time{}, nil)
+		roleRepo.EXPECT().GetUserRoles(gomock.Any(), guid).Return(userRoles, nil)
+		tokenManager.EXPECT().Generate(guid, gomock.Any(), "ip", wantOpts).Return("new_access", nil)
+		tokenRepo.EXPECT().RotateToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), guid, gomock.Any(), gomock.Any()).Return(nil)
+
+		result, err := svc.RefreshToken(context.Background(), "access", base64.URLEncoding.EncodeToString(refreshToken), "ip", "")
+		require.NoError(t, err)
+		assert.Equal(t, "new_access", result.AccessToken)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		roleRepo := mock_repository.NewMockIRoleRepo(ctrl)
+		svc := service.NewAuthServiceImpl(userRepo, nil, nil, zap.NewNop(), time.Hour, service.WithRoles(roleRepo))
+
+		userRepo.EXPECT().Exists(gomock.Any(), guid).Return(true, nil)
+		roleRepo.EXPECT().GetUserRoles(gomock.Any(), guid).Return(nil, errors.New("db error"))
+
+		_, err := svc.AuthenticateUser(context.Background(), guid, "ip", "")
+		assert.ErrorIs(t, err, domain.ErrUnexpected)
+	})
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/oauth"
+	"go.uber.org/zap"
+	"slices"
+	"time"
+)
+
+const defaultTokenExchangeTTL = 5 * time.Minute // Время жизни токенов, полученных обменом, по умолчанию
+
+// WithTokenExchangeTTL задает время жизни токенов, полученных обменом (RFC 8693).
+// Если ttl неположителен, используется defaultTokenExchangeTTL.
+func WithTokenExchangeTTL(ttl time.Duration) AuthServiceOption {
+	return func(s *AuthServiceImpl) {
+		if ttl > 0 {
+			s.tokenExchangeTTL = ttl
+		}
+	}
+}
+
+// failTokenExchange учитывает неудачную попытку обмена токена.
+func (s *AuthServiceImpl) failTokenExchange(ctx context.Context, guid uuid.UUID, clientID, reason, ip, userAgent string) {
+	s.logger.Debug("Token exchange failed", zap.String("client_id", clientID), zap.String("reason", reason))
+	s.recordFailure(ip)
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventLoginFailed,
+		GUID:      guid,
+		IP:        ip,
+		UserAgent: userAgent,
+		Reason:    reason,
+		Details:   withClientID(nil, clientID),
+	})
+}
+
+// exchangeSubject - пользователь, от имени которого запрошен токен при обмене.
+type exchangeSubject struct {
+	guid      uuid.UUID
+	amr       []string  // Методы аутентификации пользователя
+	scopes    []string  // Области доступа, которыми ограничен токен субъекта, или nil, если они не ограничены
+	reauth    bool      // Токен субъекта требует повторной аутентификации
+	sessionID uuid.UUID // Сессия, которой выдан токен субъекта, или uuid.Nil, если пользователь задан GUID
+}
+
+// resolveExchangeSubject возвращает пользователя, от имени которого запрошен токен.
+// Токен субъекта должен принадлежать действующей сессии, поэтому токены клиентов и токены,
+// уже полученные обменом, повторно не обмениваются.
+func (s *AuthServiceImpl) resolveExchangeSubject(ctx context.Context, req domain.TokenExchangeRequest) (*exchangeSubject, error) {
+	switch req.SubjectTokenType {
+	case oauth.TokenTypeAccessToken:
+		claims, err := s.authorizeSession(ctx, req.SubjectToken)
+		if err != nil {
+			if errors.Is(err, domain.ErrUnexpected) {
+				return nil, err
+			}
+			return nil, domain.ErrInvalidGrant
+		}
+
+		subject := &exchangeSubject{
+			guid:      claims.GetGUID(),
+			amr:       claims.GetAMR(),
+			reauth:    claims.RequiresReauth(),
+			sessionID: claims.GetJTI(),
+		}
+		// Токен, выданный клиенту OAuth, не расширяется за пределы его областей доступа
+		if claims.GetClientID() != "" {
+			subject.scopes = claims.GetScopes()
+			if subject.scopes == nil {
+				subject.scopes = []string{}
+			}
+		}
+		return subject, nil
+	case oauth.TokenTypeUserID:
+		guid, err := uuid.Parse(req.SubjectToken)
+		if err != nil {
+			return nil, domain.ErrInvalidGrant
+		}
+
+		exists, err := s.userRepo.Exists(ctx, guid)
+		if err != nil {
+			return nil, domain.ErrUnexpected
+		}
+		if !exists {
+			return nil, domain.ErrInvalidGrant
+		}
+		return &exchangeSubject{guid: guid}, nil
+	default:
+		return nil, domain.ErrInvalidOAuthRequest
+	}
+}
+
+// exchangeActor возвращает сторону, которая будет действовать от имени пользователя: сотрудника,
+// предъявившего свой Access токен, выданный тому же клиенту, или сам клиент, если токен сотрудника не передан.
+// Сотрудник должен иметь разрешение domain.PermissionImpersonate. Разрешения перечитываются из ролей,
+// а не берутся из токена, поэтому отзыв роли действует сразу.
+func (s *AuthServiceImpl) exchangeActor(ctx context.Context, client *domain.OAuthClient, req domain.TokenExchangeRequest) (*auth.Actor, error) {
+	if req.ActorToken == "" {
+		return &auth.Actor{Subject: client.ID, IsClient: true, ClientID: client.ID}, nil
+	}
+
+	if req.ActorTokenType != oauth.TokenTypeAccessToken {
+		return nil, domain.ErrInvalidOAuthRequest
+	}
+
+	claims, err := s.authorizeSession(ctx, req.ActorToken)
+	if err != nil {
+		if errors.Is(err, domain.ErrUnexpected) {
+			return nil, err
+		}
+		return nil, domain.ErrInvalidGrant
+	}
+	if claims.GetClientID() != client.ID {
+		return nil, domain.ErrInvalidGrant
+	}
+
+	agent := claims.GetGUID()
+	_, permissions, err := s.userAccess(ctx, agent)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(permissions, domain.PermissionImpersonate) {
+		s.logger.Debug("Actor is not allowed to impersonate users", zap.String("guid", agent.String()))
+		return nil, domain.ErrInvalidGrant
+	}
+
+	return &auth.Actor{Subject: agent.String(), ClientID: client.ID, SessionID: claims.GetJTI()}, nil
+}
+
+// ExchangeToken выдает конфиденциальному клиенту Access токен, действующий от имени пользователя
+// (grant_type=urn:ietf:params:oauth:grant-type:token-exchange, RFC 8693).
+// Пользователь задается его действующим Access токеном (делегирование) или GUID (доступ поддержки без учетных данных
+// пользователя). Сторона, действующая от имени пользователя, записывается в claim act: сотрудник, если передан
+// его Access токен, выданный тому же клиенту, иначе сам клиент. Доступ по GUID выдается только сотруднику
+// с разрешением domain.PermissionImpersonate.
+// Токен привязывается к сессии субъекта (claim sid) и сессии сотрудника (act.sid): при отзыве любой из них
+// он отклоняется /verify. Признак повторной аутентификации токена субъекта сохраняется.
+// Области доступа ограничены разрешенными клиенту и областями токена субъекта, если тот выдан клиенту OAuth.
+// Если scope пуст, выдаются все допустимые области. Токен живет не дольше tokenExchangeTTL, Refresh токен не выдается.
+// Возвращает domain.ErrUnauthorizedClient, если клиент публичный или обмен ему не разрешен,
+// domain.ErrInvalidOAuthRequest при неподдерживаемом типе токена или доступе по GUID без токена сотрудника,
+// domain.ErrInvalidGrant, если токен субъекта или сотрудника не прошел проверку либо у сотрудника нет разрешения,
+// и domain.ErrInvalidScope, если области доступа недопустимы.
+func (s *AuthServiceImpl) ExchangeToken(ctx context.Context, creds domain.ClientCredentials, req domain.TokenExchangeRequest, ip, userAgent string) (*domain.UserAuth, error) {
+	client, err := s.authenticateClient(ctx, creds, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	if !client.Confidential() || !client.AllowsGrant(domain.GrantTypeTokenExchange) {
+		return nil, domain.ErrUnauthorizedClient
+	}
+
+	// Без учетных данных пользователя от его имени может действовать только сотрудник, которому это разрешено
+	if req.SubjectTokenType == oauth.TokenTypeUserID && req.ActorToken == "" {
+		return nil, domain.ErrInvalidOAuthRequest
+	}
+
+	subject, err := s.resolveExchangeSubject(ctx, req)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidGrant) {
+			s.failTokenExchange(ctx, uuid.Nil, client.ID, domain.FailureReasonBadSubjectToken, ip, userAgent)
+		}
+		return nil, err
+	}
+
+	guid := subject.guid
+
+	actor, err := s.exchangeActor(ctx, client, req)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidGrant) {
+			s.failTokenExchange(ctx, guid, client.ID, domain.FailureReasonBadActorToken, ip, userAgent)
+		}
+		return nil, err
+	}
+
+	allowed := client.Scopes
+	if subject.scopes != nil {
+		allowed = slices.DeleteFunc(slices.Clone(allowed), func(scope string) bool {
+			return !slices.Contains(subject.scopes, scope)
+		})
+	}
+
+	scopes, ok := oauth.ParseScope(req.Scope)
+	if !ok {
+		return nil, domain.ErrInvalidScope
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(allowed, scope) {
+			return nil, domain.ErrInvalidScope
+		}
+	}
+	if len(scopes) == 0 {
+		scopes = allowed
+	}
+
+	emailVerified, err := s.emailVerified(ctx, guid)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := min(s.tokenExchangeTTL, s.clientAccessTTL(client))
+
+	jti := uuid.New()
+	accessToken, err := s.tokenManager.Generate(guid, jti, ip, auth.TokenOptions{
+		RequireReauth: subject.reauth,
+		AMR:           subject.amr,
+		EmailVerified: emailVerified,
+		ClientID:      client.ID,
+		Scopes:        scopes,
+		TTL:           ttl,
+		Actor:         actor,
+		SessionID:     subject.sessionID,
+	})
+	if err != nil {
+		s.logger.Error("Error generating exchanged token", zap.Error(err))
+		return nil, domain.ErrUnexpected
+	}
+
+	s.logger.Info("Token exchanged", zap.String("guid", guid.String()), zap.String("client_id", client.ID),
+		zap.String("actor", actor.Subject), zap.String("jti", jti.String()), zap.String("ip", ip))
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventTokenExchanged,
+		GUID:      guid,
+		JTI:       jti,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details: withClientID(map[string]any{
+			"actor":              actor.Subject,
+			"subject_token_type": req.SubjectTokenType,
+			"scope":              oauth.FormatScope(scopes),
+		}, client.ID),
+	})
+
+	return &domain.UserAuth{
+		AccessToken: accessToken,
+		ExpiresIn:   ttl,
+		Scopes:      scopes,
+	}, nil
+}
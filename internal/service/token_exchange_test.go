@@ -0,0 +1,328 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
+	mock_auth "github.com/maksemen2/medods-task/internal/pkg/auth/mocks"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/oauth"
+	mock_repository "github.com/maksemen2/medods-task/internal/repository/mocks"
+	"github.com/maksemen2/medods-task/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// testSupportClient возвращает конфиденциального клиента, которому разрешен обмен токенов.
+func testSupportClient() *domain.OAuthClient {
+	return &domain.OAuthClient{
+		ID:         "support",
+		Name:       "Support console",
+		SecretHash: crypto.HashToken([]byte("secret")),
+		GrantTypes: []domain.GrantType{domain.GrantTypeTokenExchange},
+		Scopes:     []string{"profile", "email"},
+	}
+}
+
+var supportCreds = domain.ClientCredentials{ID: "support", Secret: "secret"}
+
+// newExchangeService создает сервис с клиентом поддержки и хранилищем ролей, из которого читаются разрешения сотрудников.
+func newExchangeService(t *testing.T, ctrl *gomock.Controller) (service.IAuthService, oauthMocks, *mock_repository.MockIRoleRepo) {
+	roleRepo := mock_repository.NewMockIRoleRepo(ctrl)
+	svc, m := newOAuthServiceWithClient(t, ctrl, testSupportClient(), service.WithRoles(roleRepo))
+	return svc, m, roleRepo
+}
+
+// expectAgent настраивает Access токен сотрудника, выданный клиенту поддержки, и роли сотрудника с разрешениями permissions.
+func expectAgent(ctrl *gomock.Controller, m oauthMocks, roleRepo *mock_repository.MockIRoleRepo, token string, agent uuid.UUID, permissions ...string) *mock_auth.MockClaims {
+	claims := expectSession(ctrl, m, token, agent)
+	claims.EXPECT().GetClientID().Return("support")
+	roleRepo.EXPECT().GetUserRoles(gomock.Any(), agent).Return([]domain.Role{{Name: "support", Permissions: permissions}}, nil)
+	return claims
+}
+
+// expectSession настраивает проверку Access токена действующей сессии и возвращает его claims.
+func expectSession(ctrl *gomock.Controller, m oauthMocks, token string, guid uuid.UUID) *mock_auth.MockClaims {
+	claims := mock_auth.NewMockClaims(ctrl)
+	jti := uuid.New()
+	m.tokenManager.EXPECT().Parse(token).Return(claims, nil)
+	claims.EXPECT().GetGUID().Return(guid).AnyTimes()
+	claims.EXPECT().GetJTI().Return(jti).AnyTimes()
+	claims.EXPECT().RequiresReauth().Return(false).AnyTimes()
+	m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.New(), "hash", time.Time{}, nil)
+	return claims
+}
+
+func TestAuthService_ExchangeToken(t *testing.T) {
+	t.Run("impersonation by user id requires actor token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, _, _ := newExchangeService(t, ctrl)
+
+		_, err := svc.ExchangeToken(context.Background(), supportCreds, domain.TokenExchangeRequest{
+			SubjectToken:     uuid.New().String(),
+			SubjectTokenType: oauth.TokenTypeUserID,
+		}, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidOAuthRequest)
+	})
+
+	t.Run("delegation keeps subject scopes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testSupportClient())
+		guid := uuid.New()
+
+		claims := expectSession(ctrl, m, "user-access", guid)
+		claims.EXPECT().GetClientID().Return("spa")
+		claims.EXPECT().GetScopes().Return([]string{"profile"})
+		claims.EXPECT().GetAMR().Return([]string{domain.AMRPassword})
+		m.tokenManager.EXPECT().TTL().Return(time.Minute)
+		m.tokenManager.EXPECT().Generate(guid, gomock.Any(), "127.0.0.1", gomock.Any()).
+			DoAndReturn(func(guid, jti uuid.UUID, ip string, opts auth.TokenOptions) (string, error) {
+				assert.Equal(t, []string{"profile"}, opts.Scopes, "scopes absent from the subject token are not granted")
+				assert.Equal(t, []string{domain.AMRPassword}, opts.AMR)
+				assert.Equal(t, time.Minute, opts.TTL, "client access ttl is shorter than the exchange ttl")
+				assert.Equal(t, claims.GetJTI(), opts.SessionID, "exchanged token is revoked with the subject session")
+				assert.Equal(t, &auth.Actor{Subject: "support", IsClient: true, ClientID: "support"}, opts.Actor)
+				return "access", nil
+			})
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+		result, err := svc.ExchangeToken(context.Background(), supportCreds, domain.TokenExchangeRequest{
+			SubjectToken:     "user-access",
+			SubjectTokenType: oauth.TokenTypeAccessToken,
+		}, "127.0.0.1", "")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"profile"}, result.Scopes)
+	})
+
+	t.Run("support agent as actor", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, roleRepo := newExchangeService(t, ctrl)
+		guid, agent := uuid.New(), uuid.New()
+
+		m.userRepo.EXPECT().Exists(gomock.Any(), guid).Return(true, nil)
+		agentClaims := expectAgent(ctrl, m, roleRepo, "agent-access", agent, "orders:read", domain.PermissionImpersonate)
+		m.tokenManager.EXPECT().TTL().Return(15 * time.Minute)
+		m.tokenManager.EXPECT().Generate(guid, gomock.Any(), "127.0.0.1", gomock.Any()).
+			DoAndReturn(func(guid, jti uuid.UUID, ip string, opts auth.TokenOptions) (string, error) {
+				assert.Equal(t, []string{"email"}, opts.Scopes)
+				assert.Equal(t, 5*time.Minute, opts.TTL, "exchanged tokens live shorter than client tokens")
+				assert.Equal(t, &auth.Actor{Subject: agent.String(), ClientID: "support", SessionID: agentClaims.GetJTI()}, opts.Actor)
+				assert.Equal(t, uuid.Nil, opts.SessionID, "user given by guid has no session")
+				return "access", nil
+			})
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.AuthEvent) error {
+			assert.Equal(t, domain.AuthEventTokenExchanged, event.Type)
+			assert.Equal(t, guid, event.GUID)
+			assert.Equal(t, "support", event.Details["client_id"])
+			assert.Equal(t, agent.String(), event.Details["actor"])
+			assert.Equal(t, oauth.TokenTypeUserID, event.Details["subject_token_type"])
+			assert.Equal(t, "email", event.Details["scope"])
+			return nil
+		})
+
+		result, err := svc.ExchangeToken(context.Background(), supportCreds, domain.TokenExchangeRequest{
+			SubjectToken:     guid.String(),
+			SubjectTokenType: oauth.TokenTypeUserID,
+			ActorToken:       "agent-access",
+			ActorTokenType:   oauth.TokenTypeAccessToken,
+			Scope:            "email",
+		}, "127.0.0.1", "")
+		require.NoError(t, err)
+		assert.Equal(t, "access", result.AccessToken)
+		assert.Empty(t, result.RefreshToken)
+		assert.Equal(t, 5*time.Minute, result.ExpiresIn)
+	})
+
+	t.Run("agent without impersonation permission", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, roleRepo := newExchangeService(t, ctrl)
+		guid, agent := uuid.New(), uuid.New()
+
+		m.userRepo.EXPECT().Exists(gomock.Any(), guid).Return(true, nil)
+		expectAgent(ctrl, m, roleRepo, "agent-access", agent, "orders:read")
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.AuthEvent) error {
+			assert.Equal(t, domain.FailureReasonBadActorToken, event.Reason)
+			assert.Equal(t, guid, event.GUID)
+			return nil
+		})
+
+		_, err := svc.ExchangeToken(context.Background(), supportCreds, domain.TokenExchangeRequest{
+			SubjectToken:     guid.String(),
+			SubjectTokenType: oauth.TokenTypeUserID,
+			ActorToken:       "agent-access",
+			ActorTokenType:   oauth.TokenTypeAccessToken,
+		}, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidGrant)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testSupportClient())
+		guid := uuid.New()
+
+		m.userRepo.EXPECT().Exists(gomock.Any(), guid).Return(false, nil)
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.AuthEvent) error {
+			assert.Equal(t, domain.AuthEventLoginFailed, event.Type)
+			assert.Equal(t, domain.FailureReasonBadSubjectToken, event.Reason)
+			assert.Equal(t, "support", event.Details["client_id"])
+			return nil
+		})
+
+		_, err := svc.ExchangeToken(context.Background(), supportCreds, domain.TokenExchangeRequest{
+			SubjectToken:     guid.String(),
+			SubjectTokenType: oauth.TokenTypeUserID,
+			ActorToken:       "agent-access",
+			ActorTokenType:   oauth.TokenTypeAccessToken,
+		}, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidGrant)
+	})
+
+	t.Run("revoked subject session", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testSupportClient())
+		claims := mock_auth.NewMockClaims(ctrl)
+
+		m.tokenManager.EXPECT().Parse("user-access").Return(claims, nil)
+		claims.EXPECT().GetGUID().Return(uuid.New())
+		claims.EXPECT().GetJTI().Return(uuid.New())
+		m.tokenRepo.EXPECT().GetToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(uuid.Nil, "", time.Time{}, domain.ErrTokenNotFound)
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+		_, err := svc.ExchangeToken(context.Background(), supportCreds, domain.TokenExchangeRequest{
+			SubjectToken:     "user-access",
+			SubjectTokenType: oauth.TokenTypeAccessToken,
+		}, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidGrant)
+	})
+
+	t.Run("subject token requires re-authentication", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testSupportClient())
+		m.expectStepUpToken(ctrl, "user-access")
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonBadSubjectToken)
+
+		_, err := svc.ExchangeToken(context.Background(), supportCreds, domain.TokenExchangeRequest{
+			SubjectToken:     "user-access",
+			SubjectTokenType: oauth.TokenTypeAccessToken,
+		}, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidGrant)
+	})
+
+	t.Run("actor token requires re-authentication", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testSupportClient())
+		guid := uuid.New()
+
+		m.userRepo.EXPECT().Exists(gomock.Any(), guid).Return(true, nil)
+		m.expectStepUpToken(ctrl, "agent-access")
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonBadActorToken)
+
+		_, err := svc.ExchangeToken(context.Background(), supportCreds, domain.TokenExchangeRequest{
+			SubjectToken:     guid.String(),
+			SubjectTokenType: oauth.TokenTypeUserID,
+			ActorToken:       "agent-access",
+			ActorTokenType:   oauth.TokenTypeAccessToken,
+		}, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidGrant)
+	})
+
+	t.Run("actor token of another client", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testSupportClient())
+		guid := uuid.New()
+
+		m.userRepo.EXPECT().Exists(gomock.Any(), guid).Return(true, nil)
+		agentClaims := expectSession(ctrl, m, "agent-access", uuid.New())
+		agentClaims.EXPECT().GetClientID().Return("spa")
+		m.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.AuthEvent) error {
+			assert.Equal(t, domain.FailureReasonBadActorToken, event.Reason)
+			assert.Equal(t, guid, event.GUID)
+			return nil
+		})
+
+		_, err := svc.ExchangeToken(context.Background(), supportCreds, domain.TokenExchangeRequest{
+			SubjectToken:     guid.String(),
+			SubjectTokenType: oauth.TokenTypeUserID,
+			ActorToken:       "agent-access",
+			ActorTokenType:   oauth.TokenTypeAccessToken,
+		}, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidGrant)
+	})
+
+	t.Run("scope beyond subject token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newOAuthServiceWithClient(t, ctrl, testSupportClient())
+
+		claims := expectSession(ctrl, m, "user-access", uuid.New())
+		claims.EXPECT().GetClientID().Return("spa")
+		claims.EXPECT().GetScopes().Return([]string{"profile"})
+		claims.EXPECT().GetAMR().Return(nil)
+
+		_, err := svc.ExchangeToken(context.Background(), supportCreds, domain.TokenExchangeRequest{
+			SubjectToken:     "user-access",
+			SubjectTokenType: oauth.TokenTypeAccessToken,
+			Scope:            "email",
+		}, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidScope)
+	})
+
+	tests := []struct {
+		name    string
+		modify  func(client *domain.OAuthClient)
+		creds   domain.ClientCredentials
+		req     domain.TokenExchangeRequest
+		wantErr error
+	}{
+		{"grant not allowed", func(client *domain.OAuthClient) {
+			client.GrantTypes = []domain.GrantType{domain.GrantTypeClientCredentials}
+		}, supportCreds, domain.TokenExchangeRequest{}, domain.ErrUnauthorizedClient},
+		{"public client", func(client *domain.OAuthClient) {
+			client.SecretHash = ""
+		}, domain.ClientCredentials{ID: "support"}, domain.TokenExchangeRequest{}, domain.ErrUnauthorizedClient},
+		{"wrong secret", func(*domain.OAuthClient) {}, domain.ClientCredentials{ID: "support", Secret: "wrong"},
+			domain.TokenExchangeRequest{}, domain.ErrInvalidClient},
+		{"unsupported subject token type", func(*domain.OAuthClient) {}, supportCreds, domain.TokenExchangeRequest{
+			SubjectToken:     "token",
+			SubjectTokenType: "urn:ietf:params:oauth:token-type:refresh_token",
+		}, domain.ErrInvalidOAuthRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			client := testSupportClient()
+			tt.modify(client)
+			svc, _ := newOAuthServiceWithClient(t, ctrl, client)
+
+			_, err := svc.ExchangeToken(context.Background(), tt.creds, tt.req, "127.0.0.1", "")
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/password"
+	"github.com/maksemen2/medods-task/internal/pkg/mail"
+	"github.com/maksemen2/medods-task/internal/repository"
+	"go.uber.org/zap"
+	netmail "net/mail"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MaxEmailLength       = 255 // Максимальная длина email, соответствует ограничению в таблице users
+	MaxDisplayNameLength = 100 // Максимальная длина отображаемого имени в символах
+)
+
+// IUserService - интерфейс для управления пользователями.
+type IUserService interface {
+	// Register регистрирует нового пользователя с указанным email, отображаемым именем и паролем.
+	// Email нормализуется перед сохранением, отображаемое имя и пароль необязательны.
+	// Без пароля пользователь не сможет войти по email.
+	// Если подтверждение email включено, на email отправляется письмо со ссылкой подтверждения.
+	Register(ctx context.Context, email, displayName, plainPassword string) (*domain.User, error)
+	// RequestEmailVerification повторно отправляет письмо со ссылкой подтверждения email.
+	// Ответ не зависит от того, зарегистрирован ли email, подтвержден ли он и было ли письмо отправлено.
+	RequestEmailVerification(ctx context.Context, email string) error
+	// VerifyEmail подтверждает email пользователя по одноразовому токену из письма.
+	VerifyEmail(ctx context.Context, token string) error
+	// SetIPChangePolicy переопределяет политику смены IP для пользователя.
+	// Пустая политика сбрасывает переопределение, и к пользователю применяется глобальная политика.
+	SetIPChangePolicy(ctx context.Context, guid uuid.UUID, policy string) error
+}
+
+type UserServiceImpl struct {
+	userRepo repository.IUserRepo
+	logger   *zap.Logger
+
+	verificationRepo   repository.IEmailVerificationRepo // Токены подтверждения email. Если nil, подтверждение email отключено
+	mailSender         mail.Sender                       // Отправитель писем со ссылкой подтверждения
+	verificationURL    string                            // Адрес страницы подтверждения email
+	verificationTTL    time.Duration                     // Время жизни токена подтверждения
+	verificationResend time.Duration                     // Минимальный интервал между повторными отправками письма
+}
+
+// UserServiceOption - функциональная опция для настройки UserServiceImpl.
+type UserServiceOption func(s *UserServiceImpl)
+
+func NewUserServiceImpl(userRepo repository.IUserRepo, logger *zap.Logger, opts ...UserServiceOption) IUserService {
+	s := &UserServiceImpl{
+		userRepo:           userRepo,
+		logger:             logger,
+		verificationTTL:    defaultEmailVerificationTTL,
+		verificationResend: defaultEmailVerificationResend,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// NormalizeEmail проверяет email и приводит его к каноническому виду:
+// без окружающих пробелов и в нижнем регистре.
+// Адреса с отображаемым именем ("Name <user@example.com>") не допускаются.
+// Возвращает domain.ErrInvalidEmail, если email некорректен.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" || len(email) > MaxEmailLength {
+		return "", domain.ErrInvalidEmail
+	}
+
+	addr, err := netmail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", domain.ErrInvalidEmail
+	}
+
+	_, domainPart, _ := strings.Cut(email, "@")
+	if !strings.Contains(domainPart, ".") {
+		return "", domain.ErrInvalidEmail
+	}
+
+	return email, nil
+}
+
+// normalizeDisplayName удаляет окружающие пробелы и проверяет длину и допустимость символов имени.
+// Возвращает domain.ErrInvalidDisplayName, если имя некорректно.
+func normalizeDisplayName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if !utf8.ValidString(name) || utf8.RuneCountInString(name) > MaxDisplayNameLength {
+		return "", domain.ErrInvalidDisplayName
+	}
+
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return "", domain.ErrInvalidDisplayName
+		}
+	}
+
+	return name, nil
+}
+
+// Register регистрирует нового пользователя.
+// Возвращает domain.ErrInvalidEmail, domain.ErrInvalidDisplayName или domain.ErrWeakPassword при некорректных данных
+// и domain.ErrEmailTaken, если email уже занят.
+func (s *UserServiceImpl) Register(ctx context.Context, email, displayName, plainPassword string) (*domain.User, error) {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	displayName, err = normalizeDisplayName(displayName)
+	if err != nil {
+		return nil, err
+	}
+
+	var passwordHash string
+	if plainPassword != "" {
+		if err = password.Validate(plainPassword); err != nil {
+			return nil, domain.ErrWeakPassword
+		}
+
+		passwordHash, err = password.Hash(plainPassword)
+		if err != nil {
+			s.logger.Error("Error hashing password", zap.Error(err))
+			return nil, domain.ErrUnexpected
+		}
+	}
+
+	user := &domain.User{
+		GUID:        uuid.New(),
+		Email:       email,
+		DisplayName: displayName,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	if err = s.userRepo.Create(ctx, user, passwordHash); err != nil {
+		if errors.Is(err, domain.ErrEmailTaken) {
+			return nil, domain.ErrEmailTaken
+		}
+		s.logger.Error("Error creating user", zap.Error(err))
+		return nil, domain.ErrUnexpected
+	}
+
+	s.logger.Info("User registered", zap.String("guid", user.GUID.String()))
+
+	if s.verificationRepo != nil {
+		// Пользователь уже создан, поэтому ошибка отправки письма не прерывает регистрацию:
+		// письмо можно запросить повторно
+		if err = s.sendVerification(ctx, user.GUID, user.Email); err != nil {
+			s.logger.Warn("Failed to send email verification after registration", zap.String("guid", user.GUID.String()), zap.Error(err))
+		}
+	}
+
+	return user, nil
+}
+
+// SetIPChangePolicy сохраняет персональную политику смены IP пользователя.
+// Возвращает domain.ErrInvalidIPChangePolicy, если политика неизвестна,
+// и domain.ErrUserNotFound, если пользователь не найден.
+func (s *UserServiceImpl) SetIPChangePolicy(ctx context.Context, guid uuid.UUID, policy string) error {
+	var parsed domain.IPChangePolicy
+	if policy != "" {
+		var err error
+		if parsed, err = domain.ParseIPChangePolicy(policy); err != nil {
+			return domain.ErrInvalidIPChangePolicy
+		}
+	}
+
+	if err := s.userRepo.SetIPChangePolicy(ctx, guid, parsed); err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return domain.ErrUserNotFound
+		}
+		s.logger.Error("Error updating user ip change policy", zap.Error(err))
+		return domain.ErrUnexpected
+	}
+
+	s.logger.Info("User ip change policy updated", zap.String("guid", guid.String()), zap.String("policy", string(parsed)))
+
+	return nil
+}
@@ -0,0 +1,209 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/password"
+	mock_repository "github.com/maksemen2/medods-task/internal/repository/mocks"
+	"github.com/maksemen2/medods-task/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	valid := map[string]string{
+		"user@example.com":         "user@example.com",
+		"  User.Name@Example.COM ": "user.name@example.com",
+		"user+tag@mail.example.ru": "user+tag@mail.example.ru",
+	}
+	for input, expected := range valid {
+		email, err := service.NormalizeEmail(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, email)
+	}
+
+	invalid := []string{
+		"",
+		"user",
+		"user@localhost",
+		"@example.com",
+		"User <user@example.com>",
+		"user@example.com, other@example.com",
+		strings.Repeat("a", 250) + "@example.com",
+	}
+	for _, input := range invalid {
+		_, err := service.NormalizeEmail(input)
+		assert.ErrorIs(t, err, domain.ErrInvalidEmail, input)
+	}
+}
+
+func TestUserService_Register(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		svc := service.NewUserServiceImpl(userRepo, zap.NewNop())
+
+		userRepo.EXPECT().Create(gomock.Any(), gomock.Any(), "").DoAndReturn(func(ctx context.Context, user *domain.User, passwordHash string) error {
+			assert.NotEqual(t, uuid.Nil, user.GUID)
+			assert.Equal(t, "user@example.com", user.Email)
+			assert.Equal(t, "Ivan", user.DisplayName)
+			assert.False(t, user.CreatedAt.IsZero())
+			return nil
+		})
+
+		user, err := svc.Register(context.Background(), " User@Example.com", " Ivan ", "")
+		require.NoError(t, err)
+		assert.Equal(t, "user@example.com", user.Email)
+	})
+
+	t.Run("with password", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		svc := service.NewUserServiceImpl(userRepo, zap.NewNop())
+
+		userRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, user *domain.User, passwordHash string) error {
+			ok, err := password.Verify("correct horse", passwordHash)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			return nil
+		})
+
+		_, err := svc.Register(context.Background(), "user@example.com", "", "correct horse")
+		require.NoError(t, err)
+	})
+
+	t.Run("weak password", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc := service.NewUserServiceImpl(mock_repository.NewMockIUserRepo(ctrl), zap.NewNop())
+
+		_, err := svc.Register(context.Background(), "user@example.com", "", "short")
+		assert.ErrorIs(t, err, domain.ErrWeakPassword)
+	})
+
+	t.Run("invalid email", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc := service.NewUserServiceImpl(mock_repository.NewMockIUserRepo(ctrl), zap.NewNop())
+
+		_, err := svc.Register(context.Background(), "not-an-email", "", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidEmail)
+	})
+
+	t.Run("invalid display name", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc := service.NewUserServiceImpl(mock_repository.NewMockIUserRepo(ctrl), zap.NewNop())
+
+		_, err := svc.Register(context.Background(), "user@example.com", "bad\nname", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidDisplayName)
+
+		_, err = svc.Register(context.Background(), "user@example.com", strings.Repeat("я", service.MaxDisplayNameLength+1), "")
+		assert.ErrorIs(t, err, domain.ErrInvalidDisplayName)
+	})
+
+	t.Run("email taken", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		svc := service.NewUserServiceImpl(userRepo, zap.NewNop())
+
+		userRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).Return(domain.ErrEmailTaken)
+
+		_, err := svc.Register(context.Background(), "user@example.com", "", "")
+		assert.ErrorIs(t, err, domain.ErrEmailTaken)
+	})
+
+	t.Run("repo error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		svc := service.NewUserServiceImpl(userRepo, zap.NewNop())
+
+		userRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("db error"))
+
+		_, err := svc.Register(context.Background(), "user@example.com", "", "")
+		assert.ErrorIs(t, err, domain.ErrUnexpected)
+	})
+}
+
+func TestUserService_SetIPChangePolicy(t *testing.T) {
+	guid := uuid.New()
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		svc := service.NewUserServiceImpl(userRepo, zap.NewNop())
+
+		userRepo.EXPECT().SetIPChangePolicy(gomock.Any(), guid, domain.IPChangePolicyDeny).Return(nil)
+
+		assert.NoError(t, svc.SetIPChangePolicy(context.Background(), guid, "deny"))
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		svc := service.NewUserServiceImpl(userRepo, zap.NewNop())
+
+		userRepo.EXPECT().SetIPChangePolicy(gomock.Any(), guid, domain.IPChangePolicy("")).Return(nil)
+
+		assert.NoError(t, svc.SetIPChangePolicy(context.Background(), guid, ""))
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		svc := service.NewUserServiceImpl(userRepo, zap.NewNop())
+
+		err := svc.SetIPChangePolicy(context.Background(), guid, "allow")
+		assert.ErrorIs(t, err, domain.ErrInvalidIPChangePolicy)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		svc := service.NewUserServiceImpl(userRepo, zap.NewNop())
+
+		userRepo.EXPECT().SetIPChangePolicy(gomock.Any(), guid, domain.IPChangePolicyStepUp).Return(domain.ErrUserNotFound)
+
+		err := svc.SetIPChangePolicy(context.Background(), guid, "step_up")
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		userRepo := mock_repository.NewMockIUserRepo(ctrl)
+		svc := service.NewUserServiceImpl(userRepo, zap.NewNop())
+
+		userRepo.EXPECT().SetIPChangePolicy(gomock.Any(), guid, domain.IPChangePolicyNotify).Return(errors.New("db down"))
+
+		err := svc.SetIPChangePolicy(context.Background(), guid, "notify")
+		assert.ErrorIs(t, err, domain.ErrUnexpected)
+	})
+}
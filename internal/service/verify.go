@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+const defaultVerifyCacheTTL = 5 * time.Second // Время кеширования результата проверки отзыва токена по умолчанию
+
+// WithVerifyCacheTTL задает время, на которое VerifyToken запоминает результат проверки отзыва токена.
+// Если ttl неположителен, используется defaultVerifyCacheTTL.
+func WithVerifyCacheTTL(ttl time.Duration) AuthServiceOption {
+	return func(s *AuthServiceImpl) {
+		if ttl > 0 {
+			s.verifyCache = newRevocationCache(ttl)
+		}
+	}
+}
+
+// revocationCache запоминает в памяти процесса, отозван ли токен с указанным jti.
+// Подпись и срок действия токена проверяются при каждом запросе, кешируется только обращение к базе данных.
+type revocationCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[uuid.UUID]revocationEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type revocationEntry struct {
+	revoked   bool
+	expiresAt time.Time
+}
+
+func newRevocationCache(ttl time.Duration) *revocationCache {
+	return &revocationCache{
+		ttl:     ttl,
+		entries: map[uuid.UUID]revocationEntry{},
+		now:     time.Now,
+	}
+}
+
+// get возвращает запомненный результат проверки токена jti. ok равен false, если результата нет или он устарел.
+func (c *revocationCache) get(jti uuid.UUID) (revoked bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[jti]
+	if !ok || !c.now().Before(entry.expiresAt) {
+		return false, false
+	}
+	return entry.revoked, true
+}
+
+func (c *revocationCache) set(jti uuid.UUID, revoked bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.entries[jti] = revocationEntry{revoked: revoked, expiresAt: now.Add(c.ttl)}
+
+	// Периодически удаляем устаревшие записи, чтобы память не росла неограниченно
+	if now.Sub(c.lastSweep) >= c.ttl {
+		for key, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+		c.lastSweep = now
+	}
+}
+
+// tokenRevoked проверяет, отозван ли токен. Токен пользователя отозван, если удалена его сессия.
+// У токенов, выданных клиенту OAuth от его имени или полученных обменом, собственной сессии нет:
+// они отозваны, если клиент удален. Токен, полученный обменом, также отозван, если удалена сессия субъекта
+// или сотрудника, с которой он получен.
+func (s *AuthServiceImpl) tokenRevoked(ctx context.Context, claims auth.Claims) (bool, error) {
+	actor := claims.GetActor()
+	if !claims.IsClientToken() && actor == nil {
+		return s.sessionRevoked(ctx, claims.GetGUID(), claims.GetJTI())
+	}
+
+	if s.clientRepo == nil {
+		return true, nil
+	}
+	if _, err := s.clientRepo.Get(ctx, claims.GetClientID()); err != nil {
+		if errors.Is(err, domain.ErrClientNotFound) {
+			return true, nil
+		}
+		return false, domain.ErrUnexpected
+	}
+
+	if sessionID := claims.GetSessionID(); sessionID != uuid.Nil {
+		if revoked, err := s.sessionRevoked(ctx, claims.GetGUID(), sessionID); err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	if actor != nil && actor.SessionID != uuid.Nil {
+		agent, err := uuid.Parse(actor.Subject)
+		if err != nil {
+			return true, nil
+		}
+		return s.sessionRevoked(ctx, agent, actor.SessionID)
+	}
+
+	return false, nil
+}
+
+// sessionRevoked проверяет, удалена ли сессия jti пользователя guid.
+func (s *AuthServiceImpl) sessionRevoked(ctx context.Context, guid, jti uuid.UUID) (bool, error) {
+	if _, _, _, err := s.tokenRepo.GetToken(ctx, guid, jti, time.Now()); err != nil {
+		if errors.Is(err, domain.ErrTokenNotFound) {
+			return true, nil
+		}
+		return false, domain.ErrUnexpected
+	}
+	return false, nil
+}
+
+// VerifyToken проверяет подпись, срок действия и отзыв Access токена для прокси, которые защищают
+// другие приложения (nginx auth_request, Traefik ForwardAuth). Результат проверки отзыва запоминается
+// по jti на время verifyCache, поэтому отзыв сессии вступает в силу с этой задержкой.
+// Возвращает domain.ErrInvalidAccessToken, если токен невалиден или отозван, и domain.ErrReauthRequired,
+// если токен выдан по политике step_up и требует повторной аутентификации пользователя.
+func (s *AuthServiceImpl) VerifyToken(ctx context.Context, accessToken string) (*domain.VerifiedToken, error) {
+	claims, err := s.tokenManager.Parse(accessToken)
+	if err != nil {
+		s.logger.Debug("Bad token provided", zap.Error(err))
+		return nil, domain.ErrInvalidAccessToken
+	}
+
+	jti := claims.GetJTI()
+	revoked, ok := s.verifyCache.get(jti)
+	if !ok {
+		revoked, err = s.tokenRevoked(ctx, claims)
+		if err != nil {
+			return nil, err
+		}
+		s.verifyCache.set(jti, revoked)
+	}
+
+	if revoked {
+		s.logger.Debug("Revoked token provided", zap.String("subject", claims.GetSubjectID()), zap.String("jti", jti.String()))
+		return nil, domain.ErrInvalidAccessToken
+	}
+
+	if claims.RequiresReauth() {
+		s.logger.Debug("Token requires re-authentication", zap.String("subject", claims.GetSubjectID()), zap.String("jti", jti.String()))
+		return nil, domain.ErrReauthRequired
+	}
+
+	verified := &domain.VerifiedToken{
+		Subject:     claims.GetSubjectID(),
+		JTI:         jti,
+		ClientID:    claims.GetClientID(),
+		Scopes:      claims.GetScopes(),
+		Permissions: claims.GetPermissions(),
+	}
+	if actor := claims.GetActor(); actor != nil {
+		verified.Actor = actor.Subject
+	}
+	return verified, nil
+}
@@ -0,0 +1,272 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth"
+	mock_auth "github.com/maksemen2/medods-task/internal/pkg/auth/mocks"
+	mock_repository "github.com/maksemen2/medods-task/internal/repository/mocks"
+	"github.com/maksemen2/medods-task/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestAuthService_VerifyToken(t *testing.T) {
+	type mocks struct {
+		tokenRepo    *mock_repository.MockITokenRepo
+		clientRepo   *mock_repository.MockIClientRepo
+		tokenManager *mock_auth.MockAccessTokenManager
+	}
+
+	newService := func(ctrl *gomock.Controller, opts ...service.AuthServiceOption) (service.IAuthService, mocks) {
+		m := mocks{
+			tokenRepo:    mock_repository.NewMockITokenRepo(ctrl),
+			clientRepo:   mock_repository.NewMockIClientRepo(ctrl),
+			tokenManager: mock_auth.NewMockAccessTokenManager(ctrl),
+		}
+		opts = append(opts, service.WithOAuth(m.clientRepo, nil, 0))
+		svc := service.NewAuthServiceImpl(nil, m.tokenRepo, m.tokenManager, zap.NewNop(), time.Hour, opts...)
+		return svc, m
+	}
+
+	userClaims := func(ctrl *gomock.Controller, guid, jti uuid.UUID) *mock_auth.MockClaims {
+		claims := mock_auth.NewMockClaims(ctrl)
+		claims.EXPECT().GetGUID().Return(guid).AnyTimes()
+		claims.EXPECT().GetSubjectID().Return(guid.String()).AnyTimes()
+		claims.EXPECT().GetJTI().Return(jti).AnyTimes()
+		claims.EXPECT().IsClientToken().Return(false).AnyTimes()
+		claims.EXPECT().GetActor().Return(nil).AnyTimes()
+		claims.EXPECT().GetClientID().Return("").AnyTimes()
+		claims.EXPECT().GetScopes().Return(nil).AnyTimes()
+		claims.EXPECT().GetPermissions().Return([]string{"orders:read"}).AnyTimes()
+		claims.EXPECT().RequiresReauth().Return(false).AnyTimes()
+		return claims
+	}
+
+	t.Run("user token is cached by jti", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl)
+		guid, jti := uuid.New(), uuid.New()
+
+		m.tokenManager.EXPECT().Parse("access").Return(userClaims(ctrl, guid, jti), nil).Times(2)
+		m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.New(), "hash", time.Time{}, nil)
+
+		for range 2 {
+			verified, err := svc.VerifyToken(context.Background(), "access")
+			require.NoError(t, err)
+			assert.Equal(t, &domain.VerifiedToken{Subject: guid.String(), JTI: jti, Permissions: []string{"orders:read"}}, verified)
+		}
+	})
+
+	t.Run("revoked session is cached", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl)
+		guid, jti := uuid.New(), uuid.New()
+
+		m.tokenManager.EXPECT().Parse("access").Return(userClaims(ctrl, guid, jti), nil).Times(2)
+		m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.Nil, "", time.Time{}, domain.ErrTokenNotFound)
+
+		for range 2 {
+			_, err := svc.VerifyToken(context.Background(), "access")
+			assert.ErrorIs(t, err, domain.ErrInvalidAccessToken)
+		}
+	})
+
+	t.Run("cache expires", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl, service.WithVerifyCacheTTL(time.Millisecond))
+		guid, jti := uuid.New(), uuid.New()
+
+		m.tokenManager.EXPECT().Parse("access").Return(userClaims(ctrl, guid, jti), nil).Times(2)
+		gomock.InOrder(
+			m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.New(), "hash", time.Time{}, nil),
+			m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.Nil, "", time.Time{}, domain.ErrTokenNotFound),
+		)
+
+		_, err := svc.VerifyToken(context.Background(), "access")
+		require.NoError(t, err)
+
+		time.Sleep(2 * time.Millisecond)
+
+		_, err = svc.VerifyToken(context.Background(), "access")
+		assert.ErrorIs(t, err, domain.ErrInvalidAccessToken)
+	})
+
+	t.Run("repository error is not cached", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl)
+		guid, jti := uuid.New(), uuid.New()
+
+		m.tokenManager.EXPECT().Parse("access").Return(userClaims(ctrl, guid, jti), nil).Times(2)
+		gomock.InOrder(
+			m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.Nil, "", time.Time{}, errors.New("db error")),
+			m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.New(), "hash", time.Time{}, nil),
+		)
+
+		_, err := svc.VerifyToken(context.Background(), "access")
+		assert.ErrorIs(t, err, domain.ErrUnexpected)
+
+		_, err = svc.VerifyToken(context.Background(), "access")
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl)
+
+		m.tokenManager.EXPECT().Parse("invalid").Return(nil, auth.ErrInvalidSignature)
+
+		_, err := svc.VerifyToken(context.Background(), "invalid")
+		assert.ErrorIs(t, err, domain.ErrInvalidAccessToken)
+	})
+
+	t.Run("client token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl)
+		jti := uuid.New()
+
+		claims := mock_auth.NewMockClaims(ctrl)
+		claims.EXPECT().GetSubjectID().Return("worker").AnyTimes()
+		claims.EXPECT().GetJTI().Return(jti).AnyTimes()
+		claims.EXPECT().IsClientToken().Return(true).AnyTimes()
+		claims.EXPECT().GetActor().Return(nil).AnyTimes()
+		claims.EXPECT().GetSessionID().Return(uuid.Nil).AnyTimes()
+		claims.EXPECT().GetClientID().Return("worker").AnyTimes()
+		claims.EXPECT().GetScopes().Return([]string{"reports"}).AnyTimes()
+		claims.EXPECT().GetPermissions().Return(nil).AnyTimes()
+		claims.EXPECT().RequiresReauth().Return(false).AnyTimes()
+
+		m.tokenManager.EXPECT().Parse("access").Return(claims, nil)
+		m.clientRepo.EXPECT().Get(gomock.Any(), "worker").Return(&domain.OAuthClient{ID: "worker"}, nil)
+
+		verified, err := svc.VerifyToken(context.Background(), "access")
+		require.NoError(t, err)
+		assert.Equal(t, &domain.VerifiedToken{Subject: "worker", JTI: jti, ClientID: "worker", Scopes: []string{"reports"}}, verified)
+	})
+
+	t.Run("exchanged token of deleted client", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl)
+		guid, jti := uuid.New(), uuid.New()
+
+		claims := mock_auth.NewMockClaims(ctrl)
+		claims.EXPECT().GetSubjectID().Return(guid.String()).AnyTimes()
+		claims.EXPECT().GetJTI().Return(jti).AnyTimes()
+		claims.EXPECT().IsClientToken().Return(false).AnyTimes()
+		claims.EXPECT().GetActor().Return(&auth.Actor{Subject: "support", IsClient: true, ClientID: "support"}).AnyTimes()
+		claims.EXPECT().GetClientID().Return("support").AnyTimes()
+
+		m.tokenManager.EXPECT().Parse("access").Return(claims, nil)
+		m.clientRepo.EXPECT().Get(gomock.Any(), "support").Return(nil, domain.ErrClientNotFound)
+
+		_, err := svc.VerifyToken(context.Background(), "access")
+		assert.ErrorIs(t, err, domain.ErrInvalidAccessToken)
+	})
+
+	exchangedClaims := func(ctrl *gomock.Controller, guid, jti, sessionID uuid.UUID, actor *auth.Actor) *mock_auth.MockClaims {
+		claims := mock_auth.NewMockClaims(ctrl)
+		claims.EXPECT().GetGUID().Return(guid).AnyTimes()
+		claims.EXPECT().GetSubjectID().Return(guid.String()).AnyTimes()
+		claims.EXPECT().GetJTI().Return(jti).AnyTimes()
+		claims.EXPECT().IsClientToken().Return(false).AnyTimes()
+		claims.EXPECT().GetActor().Return(actor).AnyTimes()
+		claims.EXPECT().GetSessionID().Return(sessionID).AnyTimes()
+		claims.EXPECT().GetClientID().Return("support").AnyTimes()
+		claims.EXPECT().GetScopes().Return([]string{"profile"}).AnyTimes()
+		claims.EXPECT().GetPermissions().Return(nil).AnyTimes()
+		claims.EXPECT().RequiresReauth().Return(false).AnyTimes()
+		return claims
+	}
+
+	t.Run("exchanged token exposes actor", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl)
+		guid, jti, agent, agentSession := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+		actor := &auth.Actor{Subject: agent.String(), ClientID: "support", SessionID: agentSession}
+		m.tokenManager.EXPECT().Parse("access").Return(exchangedClaims(ctrl, guid, jti, uuid.Nil, actor), nil)
+		m.clientRepo.EXPECT().Get(gomock.Any(), "support").Return(&domain.OAuthClient{ID: "support"}, nil)
+		m.tokenRepo.EXPECT().GetToken(gomock.Any(), agent, agentSession, gomock.Any()).Return(uuid.New(), "hash", time.Time{}, nil)
+
+		verified, err := svc.VerifyToken(context.Background(), "access")
+		require.NoError(t, err)
+		assert.Equal(t, agent.String(), verified.Actor)
+	})
+
+	t.Run("exchanged token is revoked with subject session", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl)
+		guid, jti, session := uuid.New(), uuid.New(), uuid.New()
+
+		actor := &auth.Actor{Subject: "support", IsClient: true, ClientID: "support"}
+		m.tokenManager.EXPECT().Parse("access").Return(exchangedClaims(ctrl, guid, jti, session, actor), nil)
+		m.clientRepo.EXPECT().Get(gomock.Any(), "support").Return(&domain.OAuthClient{ID: "support"}, nil)
+		m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, session, gomock.Any()).Return(uuid.Nil, "", time.Time{}, domain.ErrTokenNotFound)
+
+		_, err := svc.VerifyToken(context.Background(), "access")
+		assert.ErrorIs(t, err, domain.ErrInvalidAccessToken)
+	})
+
+	t.Run("exchanged token is revoked with agent session", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl)
+		guid, jti, agent, agentSession := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+		actor := &auth.Actor{Subject: agent.String(), ClientID: "support", SessionID: agentSession}
+		m.tokenManager.EXPECT().Parse("access").Return(exchangedClaims(ctrl, guid, jti, uuid.Nil, actor), nil)
+		m.clientRepo.EXPECT().Get(gomock.Any(), "support").Return(&domain.OAuthClient{ID: "support"}, nil)
+		m.tokenRepo.EXPECT().GetToken(gomock.Any(), agent, agentSession, gomock.Any()).Return(uuid.Nil, "", time.Time{}, domain.ErrTokenNotFound)
+
+		_, err := svc.VerifyToken(context.Background(), "access")
+		assert.ErrorIs(t, err, domain.ErrInvalidAccessToken)
+	})
+
+	t.Run("token requires re-authentication", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newService(ctrl)
+		guid, jti := uuid.New(), uuid.New()
+
+		claims := mock_auth.NewMockClaims(ctrl)
+		claims.EXPECT().GetGUID().Return(guid).AnyTimes()
+		claims.EXPECT().GetSubjectID().Return(guid.String()).AnyTimes()
+		claims.EXPECT().GetJTI().Return(jti).AnyTimes()
+		claims.EXPECT().IsClientToken().Return(false).AnyTimes()
+		claims.EXPECT().GetActor().Return(nil).AnyTimes()
+		claims.EXPECT().RequiresReauth().Return(true).AnyTimes()
+
+		m.tokenManager.EXPECT().Parse("access").Return(claims, nil)
+		m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.New(), "hash", time.Time{}, nil)
+
+		_, err := svc.VerifyToken(context.Background(), "access")
+		assert.ErrorIs(t, err, domain.ErrReauthRequired)
+	})
+}
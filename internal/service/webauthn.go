@@ -0,0 +1,257 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/webauthn"
+	"github.com/maksemen2/medods-task/internal/pkg/risk"
+	"go.uber.org/zap"
+	"time"
+)
+
+const defaultWebAuthnCeremonyTTL = 5 * time.Minute // Время жизни церемонии WebAuthn по умолчанию
+
+// startCeremony генерирует challenge и сохраняет церемонию WebAuthn.
+// Для церемонии входа guid равен uuid.Nil - пользователь становится известен только из ответа аутентификатора.
+func (s *AuthServiceImpl) startCeremony(ctx context.Context, ceremony domain.WebAuthnCeremony, guid uuid.UUID) ([]byte, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		s.logger.Error("Error generating webauthn challenge", zap.Error(err))
+		return nil, domain.ErrUnexpected
+	}
+
+	session := &domain.WebAuthnSession{
+		ID:            uuid.New(),
+		ChallengeHash: crypto.HashToken(challenge),
+		Ceremony:      ceremony,
+		UserID:        guid,
+		ExpiresAt:     time.Now().Add(s.webAuthnCeremonyTTL),
+	}
+
+	if err = s.webAuthnSessionRepo.Create(ctx, session); err != nil {
+		return nil, domain.ErrUnexpected
+	}
+
+	return challenge, nil
+}
+
+// consumeCeremony находит церемонию по challenge из clientDataJSON и удаляет ее, чтобы ответ нельзя было использовать повторно.
+// Возвращает challenge и церемонию или domain.ErrWebAuthnCeremonyNotFound.
+func (s *AuthServiceImpl) consumeCeremony(ctx context.Context, ceremony domain.WebAuthnCeremony, clientDataJSON []byte) ([]byte, *domain.WebAuthnSession, error) {
+	challenge, err := webauthn.ChallengeFromClientData(clientDataJSON)
+	if err != nil {
+		return nil, nil, domain.ErrInvalidWebAuthnResponse
+	}
+
+	session, err := s.webAuthnSessionRepo.Consume(ctx, crypto.HashToken(challenge), ceremony, time.Now())
+	if err != nil {
+		if errors.Is(err, domain.ErrWebAuthnCeremonyNotFound) {
+			return nil, nil, err
+		}
+		return nil, nil, domain.ErrUnexpected
+	}
+
+	return challenge, session, nil
+}
+
+// BeginPasskeyRegistration начинает регистрацию passkey для владельца Access токена.
+// Возвращает параметры для navigator.credentials.create(). Уже зарегистрированные passkey пользователя
+// передаются в excludeCredentials, чтобы аутентификатор не создал дубликат.
+func (s *AuthServiceImpl) BeginPasskeyRegistration(ctx context.Context, accessToken string) (*webauthn.CreationOptions, error) {
+	if s.relyingParty == nil {
+		return nil, domain.ErrWebAuthnDisabled
+	}
+
+	claims, err := s.authorizeSession(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	guid := claims.GetGUID()
+
+	email, err := s.userRepo.GetEmail(ctx, guid)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, domain.ErrInvalidAccessToken
+		}
+		return nil, domain.ErrUnexpected
+	}
+
+	name := email
+	if name == "" {
+		name = guid.String()
+	}
+
+	exclude, err := s.passkeyRepo.ListCredentialIDs(ctx, guid)
+	if err != nil {
+		return nil, domain.ErrUnexpected
+	}
+
+	challenge, err := s.startCeremony(ctx, domain.WebAuthnCeremonyRegistration, guid)
+	if err != nil {
+		return nil, err
+	}
+
+	user := webauthn.User{ID: guid[:], Name: name, DisplayName: name}
+
+	return s.relyingParty.CreationOptions(challenge, user, exclude), nil
+}
+
+// FinishPasskeyRegistration проверяет ответ аутентификатора на регистрацию и сохраняет passkey.
+// Церемония должна быть начата владельцем того же Access токена.
+// Возвращает domain.ErrWebAuthnCeremonyNotFound, если церемония не найдена или истекла,
+// domain.ErrInvalidWebAuthnResponse, если ответ не прошел проверку, и domain.ErrPasskeyExists,
+// если passkey уже зарегистрирован.
+func (s *AuthServiceImpl) FinishPasskeyRegistration(ctx context.Context, accessToken string, response webauthn.RegistrationResponse, ip, userAgent string) (*domain.Passkey, error) {
+	if s.relyingParty == nil {
+		return nil, domain.ErrWebAuthnDisabled
+	}
+
+	claims, err := s.authorizeSession(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	guid := claims.GetGUID()
+
+	challenge, session, err := s.consumeCeremony(ctx, domain.WebAuthnCeremonyRegistration, response.ClientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.UserID != guid {
+		s.logger.Debug("Passkey registration ceremony of another user", zap.String("guid", guid.String()))
+		return nil, domain.ErrWebAuthnCeremonyNotFound
+	}
+
+	credential, err := s.relyingParty.VerifyRegistration(challenge, response)
+	if err != nil {
+		s.logger.Debug("Invalid passkey registration", zap.String("guid", guid.String()), zap.Error(err))
+		return nil, domain.ErrInvalidWebAuthnResponse
+	}
+
+	passkey := &domain.Passkey{
+		CredentialID: credential.ID,
+		UserID:       guid,
+		PublicKey:    credential.PublicKey,
+		SignCount:    credential.SignCount,
+		CreatedAt:    time.Now().UTC(),
+	}
+
+	if err = s.passkeyRepo.Create(ctx, passkey); err != nil {
+		if errors.Is(err, domain.ErrPasskeyExists) {
+			return nil, err
+		}
+		return nil, domain.ErrUnexpected
+	}
+
+	s.logger.Info("Passkey registered", zap.String("guid", guid.String()))
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventPasskeyRegistered,
+		GUID:      guid,
+		JTI:       claims.GetJTI(),
+		IP:        ip,
+		UserAgent: userAgent,
+	})
+
+	return passkey, nil
+}
+
+// BeginPasskeyLogin начинает вход по passkey и возвращает параметры для navigator.credentials.get().
+// Список учетных данных не передается: пользователь выбирает passkey в аутентификаторе.
+func (s *AuthServiceImpl) BeginPasskeyLogin(ctx context.Context) (*webauthn.RequestOptions, error) {
+	if s.relyingParty == nil {
+		return nil, domain.ErrWebAuthnDisabled
+	}
+
+	challenge, err := s.startCeremony(ctx, domain.WebAuthnCeremonyLogin, uuid.Nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.relyingParty.RequestOptions(challenge), nil
+}
+
+// failPasskeyLogin учитывает неудачный вход по passkey по причине reason.
+func (s *AuthServiceImpl) failPasskeyLogin(ctx context.Context, guid uuid.UUID, reason, ip, userAgent string) {
+	s.logger.Debug("Invalid passkey login", zap.String("guid", guid.String()), zap.String("reason", reason))
+	s.recordFailure(ip)
+	s.audit(ctx, domain.AuthEvent{
+		Type:      domain.AuthEventLoginFailed,
+		GUID:      guid,
+		IP:        ip,
+		UserAgent: userAgent,
+		Reason:    reason,
+	})
+}
+
+// FinishPasskeyLogin проверяет ответ аутентификатора на вход и выдает пару токенов владельцу passkey.
+// Passkey с верификацией пользователя сам по себе является многофакторной аутентификацией,
+// поэтому MFA-челлендж TOTP не выдается.
+// Для неизвестного passkey, невалидной подписи и не увеличившегося счетчика подписей возвращается
+// domain.ErrInvalidCredentials. Если церемония не найдена или истекла - domain.ErrWebAuthnCeremonyNotFound.
+// Если настроен движок оценки риска, вход может быть запрещен с ошибкой domain.ErrRiskDenied.
+func (s *AuthServiceImpl) FinishPasskeyLogin(ctx context.Context, response webauthn.AssertionResponse, ip, userAgent string) (*domain.UserAuth, error) {
+	if s.relyingParty == nil {
+		return nil, domain.ErrWebAuthnDisabled
+	}
+
+	jti := uuid.New()
+
+	if s.riskEngine != nil {
+		input := risk.Input{Operation: risk.OperationLogin, RecentFailures: s.recentFailures(ip)}
+		if err := s.assessRisk(ctx, uuid.Nil, jti, ip, userAgent, input); err != nil {
+			return nil, err
+		}
+	}
+
+	challenge, _, err := s.consumeCeremony(ctx, domain.WebAuthnCeremonyLogin, response.ClientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	passkey, err := s.passkeyRepo.Get(ctx, response.CredentialID)
+	if err != nil {
+		if errors.Is(err, domain.ErrPasskeyNotFound) {
+			s.failPasskeyLogin(ctx, uuid.Nil, domain.FailureReasonBadPasskey, ip, userAgent)
+			return nil, domain.ErrInvalidCredentials
+		}
+		return nil, domain.ErrUnexpected
+	}
+
+	guid := passkey.UserID
+
+	// Аутентификатор возвращает user handle, записанный при регистрации, - он должен совпадать с владельцем passkey
+	if len(response.UserHandle) > 0 && !bytes.Equal(response.UserHandle, guid[:]) {
+		s.failPasskeyLogin(ctx, guid, domain.FailureReasonBadPasskey, ip, userAgent)
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	signCount, err := s.relyingParty.VerifyAssertion(challenge, passkey.PublicKey, passkey.SignCount, response)
+	if err != nil {
+		reason := domain.FailureReasonBadPasskey
+		if errors.Is(err, webauthn.ErrSignCountRollback) {
+			s.logger.Warn("Passkey signature counter did not increase", zap.String("guid", guid.String()))
+			reason = domain.FailureReasonSignCount
+		}
+		s.failPasskeyLogin(ctx, guid, reason, ip, userAgent)
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	updated, err := s.passkeyRepo.UpdateSignCount(ctx, passkey.CredentialID, signCount)
+	if err != nil {
+		return nil, domain.ErrUnexpected
+	}
+
+	// Счетчик мог быть увеличен параллельным входом с тем же значением
+	if !updated {
+		s.failPasskeyLogin(ctx, guid, domain.FailureReasonSignCount, ip, userAgent)
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	return s.issueTokens(ctx, guid, jti, ip, userAgent, domain.AuthMethodPasskey)
+}
@@ -0,0 +1,316 @@
+package service_test
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/domain"
+	"github.com/maksemen2/medods-task/internal/pkg/auth"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/crypto"
+	mock_auth "github.com/maksemen2/medods-task/internal/pkg/auth/mocks"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/webauthn"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/webauthn/webauthntest"
+	mock_repository "github.com/maksemen2/medods-task/internal/repository/mocks"
+	"github.com/maksemen2/medods-task/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "https://localhost:8080"
+)
+
+type passkeyMocks struct {
+	passwordMocks
+	passkeyRepo *mock_repository.MockIPasskeyRepo
+	sessionRepo *mock_repository.MockIWebAuthnSessionRepo
+}
+
+func newPasskeyService(t *testing.T, ctrl *gomock.Controller) (service.IAuthService, passkeyMocks) {
+	rp, err := webauthn.NewRelyingParty(testRPID, "medods-task", []string{testOrigin}, time.Minute)
+	require.NoError(t, err)
+
+	_, password := newPasswordService(ctrl)
+	m := passkeyMocks{
+		passwordMocks: password,
+		passkeyRepo:   mock_repository.NewMockIPasskeyRepo(ctrl),
+		sessionRepo:   mock_repository.NewMockIWebAuthnSessionRepo(ctrl),
+	}
+	svc := service.NewAuthServiceImpl(m.userRepo, m.tokenRepo, m.tokenManager, zap.NewNop(), time.Hour,
+		service.WithAuditRepo(m.auditRepo), service.WithWebAuthn(rp, m.passkeyRepo, m.sessionRepo, time.Minute))
+	return svc, m
+}
+
+// expectSession сохраняет церемонию, созданную сервисом, и возвращает ее из Consume по хешу challenge.
+func (m passkeyMocks) expectSession(t *testing.T) *domain.WebAuthnSession {
+	stored := &domain.WebAuthnSession{}
+	m.sessionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, session *domain.WebAuthnSession) error {
+		*stored = *session
+		return nil
+	})
+	m.sessionRepo.EXPECT().Consume(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, challengeHash string, ceremony domain.WebAuthnCeremony, notAfter time.Time) (*domain.WebAuthnSession, error) {
+			if challengeHash != stored.ChallengeHash || ceremony != stored.Ceremony {
+				return nil, domain.ErrWebAuthnCeremonyNotFound
+			}
+			return stored, nil
+		}).MaxTimes(1)
+	return stored
+}
+
+func decodeChallenge(t *testing.T, challenge string) []byte {
+	decoded, err := base64.RawURLEncoding.DecodeString(challenge)
+	require.NoError(t, err)
+	return decoded
+}
+
+func TestAuthService_PasskeyRegistration(t *testing.T) {
+	setup := func(t *testing.T, ctrl *gomock.Controller) (service.IAuthService, passkeyMocks, uuid.UUID) {
+		svc, m := newPasskeyService(t, ctrl)
+		claims := mock_auth.NewMockClaims(ctrl)
+		guid, jti := uuid.New(), uuid.New()
+
+		m.tokenManager.EXPECT().Parse("access").Return(claims, nil).AnyTimes()
+		claims.EXPECT().GetGUID().Return(guid).AnyTimes()
+		claims.EXPECT().GetJTI().Return(jti).AnyTimes()
+		claims.EXPECT().RequiresReauth().Return(false).AnyTimes()
+		m.tokenRepo.EXPECT().GetToken(gomock.Any(), guid, jti, gomock.Any()).Return(uuid.New(), "hash", time.Time{}, nil).AnyTimes()
+		return svc, m, guid
+	}
+
+	t.Run("success with software authenticator", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, guid := setup(t, ctrl)
+		authenticator, err := webauthntest.NewAuthenticator()
+		require.NoError(t, err)
+
+		existing := []byte("existing-credential")
+		m.userRepo.EXPECT().GetEmail(gomock.Any(), guid).Return("user@example.com", nil)
+		m.passkeyRepo.EXPECT().ListCredentialIDs(gomock.Any(), guid).Return([][]byte{existing}, nil)
+		session := m.expectSession(t)
+
+		options, err := svc.BeginPasskeyRegistration(context.Background(), "access")
+		require.NoError(t, err)
+		assert.Equal(t, guid, session.UserID)
+		assert.Equal(t, domain.WebAuthnCeremonyRegistration, session.Ceremony)
+		assert.Equal(t, "user@example.com", options.User.Name)
+		require.Len(t, options.ExcludeCredentials, 1)
+		assert.Equal(t, base64.RawURLEncoding.EncodeToString(existing), options.ExcludeCredentials[0].ID)
+
+		challenge := decodeChallenge(t, options.Challenge)
+		assert.Equal(t, crypto.HashToken(challenge), session.ChallengeHash)
+
+		var stored *domain.Passkey
+		m.passkeyRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, passkey *domain.Passkey) error {
+			stored = passkey
+			return nil
+		})
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventPasskeyRegistered, "")
+
+		response := authenticator.Register(testRPID, testOrigin, challenge, decodeChallenge(t, options.User.ID))
+		passkey, err := svc.FinishPasskeyRegistration(context.Background(), "access", response, "127.0.0.1", "")
+		require.NoError(t, err)
+		assert.Equal(t, authenticator.CredentialID(), passkey.CredentialID)
+		assert.Equal(t, guid, stored.UserID)
+		assert.NotEmpty(t, stored.PublicKey)
+	})
+
+	t.Run("ceremony of another user", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, _ := setup(t, ctrl)
+		authenticator, err := webauthntest.NewAuthenticator()
+		require.NoError(t, err)
+
+		challenge := []byte("another-user-challenge")
+		m.sessionRepo.EXPECT().Consume(gomock.Any(), crypto.HashToken(challenge), domain.WebAuthnCeremonyRegistration, gomock.Any()).
+			Return(&domain.WebAuthnSession{UserID: uuid.New(), Ceremony: domain.WebAuthnCeremonyRegistration}, nil)
+
+		response := authenticator.Register(testRPID, testOrigin, challenge, []byte("user"))
+		_, err = svc.FinishPasskeyRegistration(context.Background(), "access", response, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrWebAuthnCeremonyNotFound)
+	})
+
+	t.Run("wrong origin", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m, guid := setup(t, ctrl)
+		authenticator, err := webauthntest.NewAuthenticator()
+		require.NoError(t, err)
+
+		challenge := []byte("challenge")
+		m.sessionRepo.EXPECT().Consume(gomock.Any(), crypto.HashToken(challenge), domain.WebAuthnCeremonyRegistration, gomock.Any()).
+			Return(&domain.WebAuthnSession{UserID: guid, Ceremony: domain.WebAuthnCeremonyRegistration}, nil)
+
+		response := authenticator.Register(testRPID, "https://evil.example", challenge, guid[:])
+		_, err = svc.FinishPasskeyRegistration(context.Background(), "access", response, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidWebAuthnResponse)
+	})
+
+	t.Run("begin with token requiring re-authentication", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newPasskeyService(t, ctrl)
+		m.expectStepUpToken(ctrl, "step-up")
+
+		_, err := svc.BeginPasskeyRegistration(context.Background(), "step-up")
+		assert.ErrorIs(t, err, domain.ErrReauthRequired)
+	})
+
+	t.Run("finish with token requiring re-authentication", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newPasskeyService(t, ctrl)
+		authenticator, err := webauthntest.NewAuthenticator()
+		require.NoError(t, err)
+		m.expectStepUpToken(ctrl, "step-up")
+
+		response := authenticator.Register(testRPID, testOrigin, []byte("challenge"), []byte("user"))
+		_, err = svc.FinishPasskeyRegistration(context.Background(), "step-up", response, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrReauthRequired)
+	})
+}
+
+func TestAuthService_PasskeyLogin(t *testing.T) {
+	// register регистрирует passkey программного аутентификатора для нового пользователя.
+	register := func(t *testing.T, rp *webauthn.RelyingParty) (*webauthntest.Authenticator, *domain.Passkey) {
+		authenticator, err := webauthntest.NewAuthenticator()
+		require.NoError(t, err)
+
+		guid := uuid.New()
+		challenge := []byte("registration-challenge")
+		credential, err := rp.VerifyRegistration(challenge, authenticator.Register(testRPID, testOrigin, challenge, guid[:]))
+		require.NoError(t, err)
+
+		return authenticator, &domain.Passkey{
+			CredentialID: credential.ID,
+			UserID:       guid,
+			PublicKey:    credential.PublicKey,
+			SignCount:    credential.SignCount,
+		}
+	}
+
+	rp, err := webauthn.NewRelyingParty(testRPID, "medods-task", []string{testOrigin}, time.Minute)
+	require.NoError(t, err)
+
+	// begin начинает вход и возвращает challenge, выданный сервисом.
+	begin := func(t *testing.T, svc service.IAuthService, m passkeyMocks) []byte {
+		session := m.expectSession(t)
+		options, err := svc.BeginPasskeyLogin(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, uuid.Nil, session.UserID)
+		assert.Equal(t, testRPID, options.RPID)
+		return decodeChallenge(t, options.Challenge)
+	}
+
+	t.Run("success skips totp and marks mfa", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newPasskeyService(t, ctrl)
+		authenticator, passkey := register(t, rp)
+		challenge := begin(t, svc, m)
+
+		m.passkeyRepo.EXPECT().Get(gomock.Any(), passkey.CredentialID).Return(passkey, nil)
+		m.passkeyRepo.EXPECT().UpdateSignCount(gomock.Any(), passkey.CredentialID, uint32(1)).Return(true, nil)
+		m.tokenManager.EXPECT().Generate(passkey.UserID, gomock.Any(), "127.0.0.1", gomock.Any()).
+			DoAndReturn(func(guid, jti uuid.UUID, ip string, opts auth.TokenOptions) (string, error) {
+				assert.Equal(t, []string{domain.AMRHWK, domain.AMRMFA}, opts.AMR)
+				return "access", nil
+			})
+		m.tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), passkey.UserID, gomock.Any(), gomock.Any()).Return(nil)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventTokenIssued, "")
+
+		result, err := svc.FinishPasskeyLogin(context.Background(), authenticator.Login(testRPID, testOrigin, challenge), "127.0.0.1", "")
+		require.NoError(t, err)
+		assert.Equal(t, "access", result.AccessToken)
+		assert.NotEmpty(t, result.RefreshToken)
+	})
+
+	t.Run("replayed ceremony", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newPasskeyService(t, ctrl)
+		authenticator, _ := register(t, rp)
+
+		m.sessionRepo.EXPECT().Consume(gomock.Any(), gomock.Any(), domain.WebAuthnCeremonyLogin, gomock.Any()).
+			Return(nil, domain.ErrWebAuthnCeremonyNotFound)
+
+		_, err := svc.FinishPasskeyLogin(context.Background(), authenticator.Login(testRPID, testOrigin, []byte("used")), "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrWebAuthnCeremonyNotFound)
+	})
+
+	t.Run("unknown passkey", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newPasskeyService(t, ctrl)
+		authenticator, passkey := register(t, rp)
+		challenge := begin(t, svc, m)
+
+		m.passkeyRepo.EXPECT().Get(gomock.Any(), passkey.CredentialID).Return(nil, domain.ErrPasskeyNotFound)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonBadPasskey)
+
+		_, err := svc.FinishPasskeyLogin(context.Background(), authenticator.Login(testRPID, testOrigin, challenge), "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	})
+
+	t.Run("signature from another key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newPasskeyService(t, ctrl)
+		_, passkey := register(t, rp)
+		impostor, _ := register(t, rp)
+		challenge := begin(t, svc, m)
+
+		response := impostor.Login(testRPID, testOrigin, challenge)
+		response.CredentialID = passkey.CredentialID
+		response.UserHandle = passkey.UserID[:]
+
+		m.passkeyRepo.EXPECT().Get(gomock.Any(), passkey.CredentialID).Return(passkey, nil)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonBadPasskey)
+
+		_, err := svc.FinishPasskeyLogin(context.Background(), response, "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	})
+
+	t.Run("cloned authenticator", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, m := newPasskeyService(t, ctrl)
+		authenticator, passkey := register(t, rp)
+		passkey.SignCount = 10
+		challenge := begin(t, svc, m)
+
+		m.passkeyRepo.EXPECT().Get(gomock.Any(), passkey.CredentialID).Return(passkey, nil)
+		expectAuditEvent(t, m.auditRepo, domain.AuthEventLoginFailed, domain.FailureReasonSignCount)
+
+		_, err := svc.FinishPasskeyLogin(context.Background(), authenticator.Login(testRPID, testOrigin, challenge), "127.0.0.1", "")
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc, _ := newPasswordService(ctrl)
+
+		_, err := svc.BeginPasskeyLogin(context.Background())
+		assert.ErrorIs(t, err, domain.ErrWebAuthnDisabled)
+	})
+}
@@ -1,6 +1,11 @@
 CREATE TABLE IF NOT EXISTS users (
     guid uuid PRIMARY KEY,
-    email VARCHAR(255) NOT NULL UNIQUE
+    email VARCHAR(255),
+    display_name VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    ip_change_policy VARCHAR(16) CHECK (ip_change_policy IN ('notify', 'deny', 'step_up')),
+    email_verified_at TIMESTAMP,
+    CONSTRAINT users_email_key UNIQUE (email)
 );
 
 CREATE TABLE IF NOT EXISTS tokens (
@@ -8,8 +13,192 @@ CREATE TABLE IF NOT EXISTS tokens (
     user_id uuid NOT NULL REFERENCES users(guid),
     token VARCHAR(255) NOT NULL,
     jti uuid NOT NULL UNIQUE,
-    expires_at TIMESTAMP NOT NULL
+    expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMPTZ NOT NULL DEFAULT now()
 );
 
 CREATE INDEX IF NOT EXISTS idx_tokens_jti ON tokens(jti);
-CREATE INDEX IF NOT EXISTS idx_tokens_user_id ON users(guid);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_tokens_user_id ON users(guid);
+
+CREATE TABLE IF NOT EXISTS credentials (
+    user_id uuid PRIMARY KEY REFERENCES users(guid),
+    password_hash VARCHAR(255) NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS totp_enrollments (
+    user_id uuid PRIMARY KEY REFERENCES users(guid),
+    secret TEXT NOT NULL,
+    confirmed_at TIMESTAMP,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    id uuid PRIMARY KEY,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    user_id uuid NOT NULL REFERENCES users(guid),
+    method VARCHAR(32) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_challenges_expires_at ON mfa_challenges(expires_at);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL REFERENCES users(guid),
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes(user_id);
+
+CREATE TABLE IF NOT EXISTS passkeys (
+    credential_id BYTEA PRIMARY KEY,
+    user_id uuid NOT NULL REFERENCES users(guid),
+    public_key BYTEA NOT NULL,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_passkeys_user_id ON passkeys(user_id);
+
+CREATE TABLE IF NOT EXISTS webauthn_sessions (
+    id uuid PRIMARY KEY,
+    challenge_hash VARCHAR(64) NOT NULL UNIQUE,
+    ceremony VARCHAR(16) NOT NULL,
+    user_id uuid REFERENCES users(guid),
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_sessions_expires_at ON webauthn_sessions(expires_at);
+
+CREATE TABLE IF NOT EXISTS email_verifications (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL REFERENCES users(guid),
+    email VARCHAR(255) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_verifications_user_id ON email_verifications(user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS password_resets (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL REFERENCES users(guid),
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON password_resets(user_id);
+
+CREATE TABLE IF NOT EXISTS consumed_magic_links (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL REFERENCES users(guid),
+    consumed_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_consumed_magic_links_expires_at ON consumed_magic_links(expires_at);
+
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    secret_hash VARCHAR(64) NOT NULL DEFAULT '',
+    grant_types TEXT[] NOT NULL,
+    redirect_uris TEXT[] NOT NULL DEFAULT '{}',
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    access_ttl_seconds INTEGER NOT NULL DEFAULT 0,
+    refresh_ttl_seconds INTEGER NOT NULL DEFAULT 0,
+    registration_token_hash VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS authorization_codes (
+    code_hash VARCHAR(64) PRIMARY KEY,
+    client_id VARCHAR(255) NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users(guid),
+    redirect_uri TEXT NOT NULL,
+    code_challenge VARCHAR(64) NOT NULL,
+    auth_methods TEXT[] NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_authorization_codes_expires_at ON authorization_codes(expires_at);
+
+CREATE TABLE IF NOT EXISTS device_authorizations (
+    device_code_hash VARCHAR(64) PRIMARY KEY,
+    user_code_hash VARCHAR(64) NOT NULL UNIQUE,
+    client_id VARCHAR(255) NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    status VARCHAR(16) NOT NULL,
+    user_id uuid REFERENCES users(guid),
+    auth_methods TEXT[] NOT NULL DEFAULT '{}',
+    interval_seconds INTEGER NOT NULL,
+    last_polled_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_authorizations_expires_at ON device_authorizations(expires_at);
+
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(64) PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role VARCHAR(64) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission VARCHAR(255) NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id uuid NOT NULL REFERENCES users(guid) ON DELETE CASCADE,
+    role VARCHAR(64) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role)
+);
+
+CREATE TABLE IF NOT EXISTS provisioning_allowlist (
+    guid uuid PRIMARY KEY,
+    added_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS auth_events (
+    id uuid PRIMARY KEY,
+    type VARCHAR(32) NOT NULL,
+    user_id uuid,
+    jti uuid,
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    reason VARCHAR(64) NOT NULL DEFAULT '',
+    details JSONB,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_events_user_id ON auth_events(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_auth_events_created_at ON auth_events(created_at);
+
+-- Журнал аудита допускает только добавление записей
+CREATE OR REPLACE FUNCTION forbid_auth_events_modification() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'auth_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS auth_events_immutable ON auth_events;
+CREATE TRIGGER auth_events_immutable
+    BEFORE UPDATE OR DELETE ON auth_events
+    FOR EACH ROW EXECUTE FUNCTION forbid_auth_events_modification();
+
+DROP TRIGGER IF EXISTS auth_events_no_truncate ON auth_events;
+CREATE TRIGGER auth_events_no_truncate
+    BEFORE TRUNCATE ON auth_events
+    FOR EACH STATEMENT EXECUTE FUNCTION forbid_auth_events_modification();
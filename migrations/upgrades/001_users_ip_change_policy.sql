@@ -0,0 +1,3 @@
+-- Политика смены IP пользователя
+ALTER TABLE users ADD COLUMN IF NOT EXISTS ip_change_policy VARCHAR(16)
+    CHECK (ip_change_policy IN ('notify', 'deny', 'step_up'));
@@ -0,0 +1,4 @@
+-- Регистрация пользователей и создание пользователей без email по GUID
+ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS created_at TIMESTAMP NOT NULL DEFAULT now();
+ALTER TABLE users ALTER COLUMN email DROP NOT NULL;
@@ -0,0 +1,2 @@
+-- Подтверждение email
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
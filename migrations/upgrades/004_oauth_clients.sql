@@ -0,0 +1,13 @@
+-- Реестр клиентов OAuth: коды авторизации ссылаются на клиента и хранят области доступа
+ALTER TABLE authorization_codes ADD COLUMN IF NOT EXISTS scopes TEXT[] NOT NULL DEFAULT '{}';
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'authorization_codes_client_id_fkey') THEN
+        -- Коды живут несколько минут, поэтому коды клиентов, которых нет в реестре, просто удаляются
+        DELETE FROM authorization_codes WHERE client_id NOT IN (SELECT id FROM oauth_clients);
+        ALTER TABLE authorization_codes ADD CONSTRAINT authorization_codes_client_id_fkey
+            FOREIGN KEY (client_id) REFERENCES oauth_clients(id) ON DELETE CASCADE;
+    END IF;
+END;
+$$;
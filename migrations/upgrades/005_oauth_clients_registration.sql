@@ -0,0 +1,2 @@
+-- Динамическая регистрация клиентов OAuth (RFC 7591)
+ALTER TABLE oauth_clients ADD COLUMN IF NOT EXISTS registration_token_hash VARCHAR(64) NOT NULL DEFAULT '';
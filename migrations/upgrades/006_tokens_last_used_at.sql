@@ -0,0 +1,2 @@
+-- Время последнего использования сессии для оценки риска
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMPTZ NOT NULL DEFAULT now();
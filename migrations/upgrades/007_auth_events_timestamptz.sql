@@ -0,0 +1,10 @@
+-- Время событий журнала аудита хранится с часовым поясом. Сервис записывал время без него в UTC
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_name = 'auth_events' AND column_name = 'created_at'
+                 AND data_type = 'timestamp without time zone') THEN
+        ALTER TABLE auth_events ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
+    END IF;
+END;
+$$;
@@ -0,0 +1,232 @@
+// Package authz - мидлвари для Gin, которыми сервисы проверяют Access токены сервиса аутентификации
+// и разграничивают доступ по разрешениям и ролям пользователя.
+package authz
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	claimsKey      = "auth_claims"   // Ключ контекста Gin, под которым NewAccessAuth сохраняет claims Access токена
+	defaultTimeout = 5 * time.Second // Таймаут запроса к /verify по умолчанию
+)
+
+// Claims - claims проверенного Access токена.
+type Claims struct {
+	Subject     string    // GUID пользователя или идентификатор клиента OAuth, если токен выдан клиенту от его имени
+	UserID      uuid.UUID // GUID пользователя или uuid.Nil для токенов клиента OAuth
+	TokenID     uuid.UUID // Идентификатор (jti) токена
+	ClientID    string    // Клиент OAuth, которому выдан токен, или пустая строка
+	Scopes      []string  // Области доступа клиента OAuth
+	Roles       []string  // Роли пользователя
+	Permissions []string  // Разрешения ролей пользователя
+	AMR         []string  // Методы аутентификации пользователя (RFC 8176)
+	Actor       string    // Субъект стороны, действующей от имени пользователя (RFC 8693), или пустая строка
+	IssuedAt    time.Time // Время выпуска токена
+}
+
+// accessClaims - payload Access токена сервиса аутентификации.
+type accessClaims struct {
+	jwt.RegisteredClaims
+	SubjectType string   `json:"sub_type,omitempty"` // Тип субъекта, не являющегося пользователем
+	ClientID    string   `json:"client_id,omitempty"`
+	Scope       string   `json:"scope,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	AMR         []string `json:"amr,omitempty"`
+	Act         *struct {
+		Subject string `json:"sub"`
+	} `json:"act,omitempty"`
+}
+
+// parseClaims читает claims Access токена без проверки подписи: токен уже проверил /verify.
+func parseClaims(accessToken string) (*Claims, error) {
+	var claims accessClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(accessToken, &claims); err != nil {
+		return nil, err
+	}
+
+	tokenID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Claims{
+		Subject:     claims.Subject,
+		TokenID:     tokenID,
+		ClientID:    claims.ClientID,
+		Scopes:      strings.Fields(claims.Scope),
+		Roles:       claims.Roles,
+		Permissions: claims.Permissions,
+		AMR:         claims.AMR,
+	}
+	if claims.SubjectType == "" {
+		if result.UserID, err = uuid.Parse(claims.Subject); err != nil {
+			return nil, err
+		}
+	}
+	if claims.Act != nil {
+		result.Actor = claims.Act.Subject
+	}
+	if claims.IssuedAt != nil {
+		result.IssuedAt = claims.IssuedAt.Time
+	}
+	return result, nil
+}
+
+type accessAuth struct {
+	verifyURL  string
+	httpClient *http.Client
+}
+
+type Option func(*accessAuth)
+
+// WithHTTPClient задает HTTP клиент для запросов к /verify.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(a *accessAuth) {
+		a.httpClient = httpClient
+	}
+}
+
+// NewAccessAuth - мидлварь для Gin, которая пропускает только запросы с действующим Access токеном
+// в заголовке "Authorization: Bearer <токен>" и сохраняет его claims в контексте запроса (см. FromContext).
+// verifyURL - адрес эндпоинта /verify сервиса аутентификации, например http://medods-task:8080/verify.
+// Подпись, срок действия и отзыв токена проверяет сервис аутентификации, поэтому сервисам не нужен ключ подписи токенов.
+// На недействительный токен отвечает 401 с заголовком WWW-Authenticate из ответа /verify (RFC 6750, раздел 3),
+// в том числе с ошибкой insufficient_user_authentication (RFC 9470) для токена, требующего повторной аутентификации.
+// Если сервис аутентификации недоступен, отвечает 503.
+func NewAccessAuth(verifyURL string, opts ...Option) gin.HandlerFunc {
+	a := &accessAuth{
+		verifyURL:  verifyURL,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return func(c *gin.Context) {
+		accessToken, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || accessToken == "" {
+			c.Header("WWW-Authenticate", `Bearer`)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		status, challenge := a.verify(c.Request, accessToken)
+		switch status {
+		case http.StatusOK:
+		case http.StatusUnauthorized:
+			if challenge == "" {
+				challenge = `Bearer`
+			}
+			c.Header("WWW-Authenticate", challenge)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		default:
+			c.AbortWithStatus(http.StatusServiceUnavailable)
+			return
+		}
+
+		claims, err := parseClaims(accessToken)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		c.Set(claimsKey, claims)
+		c.Next()
+	}
+}
+
+// verify проверяет токен через /verify и возвращает статус ответа и заголовок WWW-Authenticate.
+// Если сервис аутентификации недоступен, возвращает статус 0.
+func (a *accessAuth) verify(r *http.Request, accessToken string) (int, string) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, a.verifyURL, nil)
+	if err != nil {
+		return 0, ""
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return 0, ""
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	return resp.StatusCode, resp.Header.Get("WWW-Authenticate")
+}
+
+// FromContext возвращает claims Access токена, сохраненные NewAccessAuth, или nil, если запрос не прошел через нее.
+func FromContext(c *gin.Context) *Claims {
+	claims, _ := c.Get(claimsKey)
+	result, _ := claims.(*Claims)
+	return result
+}
+
+// RequireScope - мидлварь для Gin, которая пропускает только запросы, Access токен которых содержит
+// все перечисленные области доступа, выданные клиенту OAuth.
+// Должна подключаться после NewAccessAuth. Иначе отвечает 403 с ошибкой insufficient_scope (RFC 6750, раздел 3.1).
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	challenge := `Bearer error="insufficient_scope", scope="` + strings.Join(scopes, " ") + `"`
+	return requireAll(func(claims *Claims) []string { return claims.Scopes }, scopes, challenge)
+}
+
+// RequirePermission - мидлварь для Gin, которая пропускает только запросы, Access токен которых содержит
+// все перечисленные разрешения ролей пользователя.
+// Должна подключаться после NewAccessAuth. Иначе отвечает 403 с ошибкой insufficient_scope (RFC 6750, раздел 3.1).
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return requireAll(func(claims *Claims) []string { return claims.Permissions }, permissions, `Bearer error="insufficient_scope"`)
+}
+
+// requireAll пропускает только запросы, в claims которых granted содержит все значения required.
+func requireAll(granted func(claims *Claims) []string, required []string, challenge string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := FromContext(c)
+		if claims == nil {
+			c.Header("WWW-Authenticate", `Bearer`)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		for _, value := range required {
+			if !slices.Contains(granted(claims), value) {
+				c.Header("WWW-Authenticate", challenge)
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// RequireRole - мидлварь для Gin, которая пропускает только запросы, Access токен которых содержит
+// хотя бы одну из перечисленных ролей. Должна подключаться после NewAccessAuth. Иначе отвечает 403.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := FromContext(c)
+		if claims == nil {
+			c.Header("WWW-Authenticate", `Bearer`)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		if !slices.ContainsFunc(claims.Roles, func(role string) bool { return slices.Contains(roles, role) }) {
+			c.Header("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		c.Next()
+	}
+}
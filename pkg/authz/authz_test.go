@@ -0,0 +1,123 @@
+package authz_test
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/maksemen2/medods-task/internal/pkg/auth"
+	"github.com/maksemen2/medods-task/internal/pkg/auth/jwt"
+	"github.com/maksemen2/medods-task/pkg/authz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthorization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	secret := []byte("very_secret_key")
+	manager := jwt.NewManager(secret, 10*time.Minute)
+	guid := uuid.New()
+
+	newToken := func(opts auth.TokenOptions) string {
+		token, err := manager.Generate(guid, uuid.New(), "127.0.0.1", opts)
+		require.NoError(t, err)
+		return "Bearer " + token
+	}
+
+	// Заменяет эндпоинт /verify сервиса аутентификации
+	verifyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessToken, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		claims, err := manager.Parse(accessToken)
+		switch {
+		case err != nil:
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		case claims.RequiresReauth():
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_user_authentication"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer verifyServer.Close()
+
+	unavailableServer := httptest.NewServer(http.NotFoundHandler())
+	unavailableServer.Close()
+
+	router := gin.New()
+	protected := router.Group("/", authz.NewAccessAuth(verifyServer.URL))
+	protected.GET("/me", func(c *gin.Context) {
+		claims := authz.FromContext(c)
+		c.String(http.StatusOK, claims.UserID.String()+" "+strings.Join(claims.Permissions, " "))
+	})
+	protected.GET("/users", authz.RequirePermission("users:read", "users:write"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	protected.GET("/reports", authz.RequireScope("reports"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	protected.GET("/admin", authz.RequireRole("admin", "support"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/unprotected", authz.RequireRole("admin"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/unavailable", authz.NewAccessAuth(unavailableServer.URL), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	admin := newToken(auth.TokenOptions{Roles: []string{"admin"}, Permissions: []string{"users:read", "users:write"}})
+	reader := newToken(auth.TokenOptions{Roles: []string{"viewer"}, Permissions: []string{"users:read"}})
+	stepUp := newToken(auth.TokenOptions{Roles: []string{"admin"}, Permissions: []string{"users:read", "users:write"}, RequireReauth: true})
+	// Разрешения ролей не заменяют области доступа клиента OAuth, даже если совпадают с ними по названию
+	client := newToken(auth.TokenOptions{ClientID: "reporter", Scopes: []string{"reports"}})
+	permitted := newToken(auth.TokenOptions{Permissions: []string{"reports"}})
+
+	tests := []struct {
+		name      string
+		path      string
+		header    string
+		status    int
+		challenge string
+	}{
+		{name: "valid token", path: "/me", header: reader, status: http.StatusOK},
+		{name: "no token", path: "/me", status: http.StatusUnauthorized, challenge: `Bearer`},
+		{name: "invalid token", path: "/me", header: "Bearer invalid", status: http.StatusUnauthorized, challenge: `Bearer error="invalid_token"`},
+		{name: "token requires re-authentication", path: "/users", header: stepUp, status: http.StatusUnauthorized,
+			challenge: `Bearer error="insufficient_user_authentication"`},
+		{name: "all permissions granted", path: "/users", header: admin, status: http.StatusOK},
+		{name: "missing permission", path: "/users", header: reader, status: http.StatusForbidden, challenge: `Bearer error="insufficient_scope"`},
+		{name: "scope granted", path: "/reports", header: client, status: http.StatusOK},
+		{name: "missing scope", path: "/reports", header: permitted, status: http.StatusForbidden,
+			challenge: `Bearer error="insufficient_scope", scope="reports"`},
+		{name: "role granted", path: "/admin", header: admin, status: http.StatusOK},
+		{name: "missing role", path: "/admin", header: reader, status: http.StatusForbidden, challenge: `Bearer error="insufficient_scope"`},
+		{name: "without access auth", path: "/unprotected", header: admin, status: http.StatusUnauthorized, challenge: `Bearer`},
+		{name: "verify unavailable", path: "/unavailable", header: admin, status: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.challenge, w.Header().Get("WWW-Authenticate"))
+		})
+	}
+
+	t.Run("claims in context", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/me", nil)
+		req.Header.Set("Authorization", reader)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, guid.String()+" users:read", w.Body.String())
+	})
+}
@@ -0,0 +1,277 @@
+// Package client - Go клиент сервиса аутентификации. Получает пару токенов через GET /auth, хранит ее
+// и обновляет через POST /refresh. Transport подставляет Access токен в запросы к другим сервисам
+// и обновляет его незадолго до истечения.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRefreshBefore = 30 * time.Second // За сколько до истечения Access токена он обновляется по умолчанию
+	defaultTimeout       = 10 * time.Second // Таймаут запросов к сервису аутентификации по умолчанию
+)
+
+var (
+	ErrNoTokens   = errors.New("client: no tokens, call Authenticate or SetTokens first") // Пара токенов еще не получена
+	ErrBadRequest = errors.New("client: bad request")                                     // Сервис ответил 400, например, передан невалидный GUID или токен
+	// Сервис ответил 401: токены отозваны или обновление запрещено политикой смены IP либо оценкой риска
+	ErrUnauthorized = errors.New("client: unauthorized")
+	ErrUserNotFound = errors.New("client: user not found") // Пользователь с указанным GUID не зарегистрирован
+)
+
+// TokenPair - пара токенов, выданная сервисом аутентификации.
+type TokenPair struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"` // Refresh токен, закодированный в base64
+	ExpiresAt    time.Time `json:"-"`             // Время истечения Access токена. Нулевое, если его не удалось определить
+}
+
+// MFARequiredError возвращается Authenticate, если у пользователя подключен второй фактор.
+// Пара токенов выдается через POST /mfa/verify в обмен на MFAToken и код второго фактора.
+type MFARequiredError struct {
+	MFAToken  string
+	ExpiresAt time.Time // Время истечения MFA-челленджа
+}
+
+func (e *MFARequiredError) Error() string {
+	return "client: second factor required"
+}
+
+// StatusError - неожиданный ответ сервиса аутентификации.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("client: unexpected status %d", e.StatusCode)
+}
+
+// Client хранит пару токенов пользователя и обновляет ее. Безопасен для использования из нескольких горутин.
+type Client struct {
+	baseURL       string
+	httpClient    *http.Client
+	refreshBefore time.Duration
+	// Таймаут обновления пары. Запрос не отменяется контекстом вызывающего, поэтому ограничивается отдельно
+	refreshTimeout time.Duration
+	now            func() time.Time
+
+	mu     sync.RWMutex
+	tokens *TokenPair
+	// Refresh токен одноразовый, поэтому одновременные обновления объединяются в один запрос
+	refreshes singleflight.Group
+}
+
+type Option func(*Client)
+
+// WithHTTPClient задает HTTP клиент для запросов к сервису аутентификации.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRefreshBefore задает, за сколько до истечения Access токена Transport его обновляет.
+func WithRefreshBefore(d time.Duration) Option {
+	return func(c *Client) {
+		c.refreshBefore = d
+	}
+}
+
+// WithRefreshTimeout задает максимальное время обновления пары токенов.
+// Все вызывающие, ожидающие общего обновления, получают ошибку по его истечении.
+func WithRefreshTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.refreshTimeout = d
+	}
+}
+
+// New создает клиент сервиса аутентификации с адресом baseURL, например http://medods-task:8080.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:        strings.TrimRight(baseURL, "/"),
+		httpClient:     &http.Client{Timeout: defaultTimeout},
+		refreshBefore:  defaultRefreshBefore,
+		refreshTimeout: defaultTimeout,
+		now:            time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Tokens возвращает текущую пару токенов или nil, если она еще не получена.
+func (c *Client) Tokens() *TokenPair {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.tokens == nil {
+		return nil
+	}
+	tokens := *c.tokens
+	return &tokens
+}
+
+// SetTokens сохраняет пару токенов, полученную другим способом, например через POST /login или POST /mfa/verify.
+func (c *Client) SetTokens(accessToken, refreshToken string) {
+	c.setTokens(newTokenPair(accessToken, refreshToken))
+}
+
+func (c *Client) setTokens(tokens *TokenPair) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokens = tokens
+}
+
+// newTokenPair определяет время истечения Access токена по claim exp. Подпись здесь не проверяется:
+// токен проверяет сервис, которому он передается, а клиенту нужно только знать, когда его обновить.
+func newTokenPair(accessToken, refreshToken string) *TokenPair {
+	tokens := &TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}
+
+	var claims jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(accessToken, &claims); err == nil && claims.ExpiresAt != nil {
+		tokens.ExpiresAt = claims.ExpiresAt.Time
+	}
+
+	return tokens
+}
+
+// Authenticate получает пару токенов пользователя по GUID через GET /auth и сохраняет ее.
+// Если у пользователя подключен второй фактор, возвращает *MFARequiredError.
+func (c *Client) Authenticate(ctx context.Context, guid string) (*TokenPair, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/auth?guid="+url.QueryEscape(guid), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ответ GET /auth - либо пара токенов, либо MFA-челлендж
+	var body struct {
+		AccessToken  string    `json:"access_token"`
+		RefreshToken string    `json:"refresh_token"`
+		MFARequired  bool      `json:"mfa_required"`
+		MFAToken     string    `json:"mfa_token"`
+		ExpiresAt    time.Time `json:"expires_at"`
+	}
+	if err = c.do(req, &body); err != nil {
+		return nil, err
+	}
+
+	if body.MFARequired {
+		return nil, &MFARequiredError{MFAToken: body.MFAToken, ExpiresAt: body.ExpiresAt}
+	}
+
+	tokens := newTokenPair(body.AccessToken, body.RefreshToken)
+	c.setTokens(tokens)
+	return tokens, nil
+}
+
+// Refresh обновляет пару токенов через POST /refresh и сохраняет новую пару.
+// Одновременные вызовы объединяются в один запрос к сервису.
+func (c *Client) Refresh(ctx context.Context) (*TokenPair, error) {
+	current := c.Tokens()
+	if current == nil {
+		return nil, ErrNoTokens
+	}
+
+	result := c.refreshes.DoChan(current.RefreshToken, func() (any, error) {
+		// Запрос не отменяется вместе с контекстом вызывающего: после ротации на сервере старый Refresh токен
+		// недействителен, и новую пару нужно сохранить, даже если ее ждать уже некому.
+		// Зависший запрос при этом не должен блокировать всех ожидающих, поэтому время обновления ограничено
+		refreshCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.refreshTimeout)
+		defer cancel()
+		return c.refresh(refreshCtx, current)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-result:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		tokens := *res.Val.(*TokenPair)
+		return &tokens, nil
+	}
+}
+
+func (c *Client) refresh(ctx context.Context, current *TokenPair) (*TokenPair, error) {
+	// Пару могли обновить, пока вызывающий читал текущую: повторное обновление старым Refresh токеном отклонит сервис
+	if stored := c.Tokens(); stored != nil && stored.RefreshToken != current.RefreshToken {
+		return stored, nil
+	}
+
+	payload, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/refresh", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var body TokenPair
+	if err = c.do(req, &body); err != nil {
+		return nil, err
+	}
+
+	tokens := newTokenPair(body.AccessToken, body.RefreshToken)
+	c.setTokens(tokens)
+	return tokens, nil
+}
+
+// AccessToken возвращает действующий Access токен. Если до его истечения осталось меньше refreshBefore,
+// токен предварительно обновляется.
+func (c *Client) AccessToken(ctx context.Context) (string, error) {
+	tokens := c.Tokens()
+	if tokens == nil {
+		return "", ErrNoTokens
+	}
+
+	if tokens.ExpiresAt.IsZero() || c.now().Add(c.refreshBefore).Before(tokens.ExpiresAt) {
+		return tokens.AccessToken, nil
+	}
+
+	tokens, err := c.Refresh(ctx)
+	if err != nil {
+		return "", err
+	}
+	return tokens.AccessToken, nil
+}
+
+// do выполняет запрос к сервису аутентификации и разбирает JSON ответа в out.
+func (c *Client) do(req *http.Request, out any) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return json.NewDecoder(resp.Body).Decode(out)
+	case http.StatusBadRequest:
+		return ErrBadRequest
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusNotFound:
+		return ErrUserNotFound
+	default:
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+}
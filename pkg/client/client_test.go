@@ -0,0 +1,212 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/maksemen2/medods-task/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func accessToken(t *testing.T, expiresIn time.Duration) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	return token
+}
+
+// authServer имитирует эндпоинты /auth и /refresh сервиса аутентификации
+type authServer struct {
+	t         *testing.T
+	expiresIn time.Duration
+	refreshes atomic.Int32
+	release   chan struct{} // Если задан, /refresh отвечает только после закрытия канала
+}
+
+func (s *authServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/auth":
+		switch r.URL.Query().Get("guid") {
+		case "mfa":
+			json.NewEncoder(w).Encode(map[string]any{"mfa_required": true, "mfa_token": "challenge", "expires_at": time.Now()})
+		case "unknown":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			json.NewEncoder(w).Encode(map[string]string{"access_token": accessToken(s.t, s.expiresIn), "refresh_token": "refresh-0"})
+		}
+	case "/refresh":
+		if s.release != nil {
+			<-s.release
+		}
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+
+		n := s.refreshes.Add(1)
+		if req["refresh_token"] != "refresh-"+strconv.Itoa(int(n-1)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token":  accessToken(s.t, time.Hour),
+			"refresh_token": "refresh-" + strconv.Itoa(int(n)),
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestClient_Authenticate(t *testing.T) {
+	srv := httptest.NewServer(&authServer{t: t, expiresIn: time.Hour})
+	defer srv.Close()
+
+	t.Run("token pair", func(t *testing.T) {
+		c := client.New(srv.URL)
+
+		tokens, err := c.Authenticate(context.Background(), "guid")
+		require.NoError(t, err)
+		assert.Equal(t, "refresh-0", tokens.RefreshToken)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), tokens.ExpiresAt, time.Minute)
+		assert.Equal(t, tokens, c.Tokens())
+	})
+
+	t.Run("mfa required", func(t *testing.T) {
+		c := client.New(srv.URL)
+
+		_, err := c.Authenticate(context.Background(), "mfa")
+		var mfaErr *client.MFARequiredError
+		require.ErrorAs(t, err, &mfaErr)
+		assert.Equal(t, "challenge", mfaErr.MFAToken)
+		assert.Nil(t, c.Tokens())
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		c := client.New(srv.URL)
+
+		_, err := c.Authenticate(context.Background(), "unknown")
+		assert.ErrorIs(t, err, client.ErrUserNotFound)
+	})
+}
+
+func TestClient_Refresh(t *testing.T) {
+	t.Run("no tokens", func(t *testing.T) {
+		_, err := client.New("http://localhost").Refresh(context.Background())
+		assert.ErrorIs(t, err, client.ErrNoTokens)
+	})
+
+	t.Run("concurrent refreshes are deduplicated", func(t *testing.T) {
+		server := &authServer{t: t, expiresIn: time.Hour, release: make(chan struct{})}
+		srv := httptest.NewServer(server)
+		defer srv.Close()
+
+		c := client.New(srv.URL)
+		c.SetTokens(accessToken(t, time.Second), "refresh-0")
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				tokens, err := c.Refresh(context.Background())
+				if err == nil && tokens.RefreshToken != "refresh-1" {
+					err = errors.New("unexpected refresh token " + tokens.RefreshToken)
+				}
+				errs <- err
+			}()
+		}
+
+		time.Sleep(50 * time.Millisecond)
+		close(server.release)
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, int32(1), server.refreshes.Load())
+		assert.Equal(t, "refresh-1", c.Tokens().RefreshToken)
+	})
+
+	t.Run("hung refresh times out", func(t *testing.T) {
+		server := &authServer{t: t, expiresIn: time.Hour, release: make(chan struct{})}
+		srv := httptest.NewServer(server)
+		defer srv.Close()
+		defer close(server.release)
+
+		// HTTP клиент без таймаута: ожидание ограничивает только таймаут обновления
+		c := client.New(srv.URL, client.WithHTTPClient(&http.Client{}), client.WithRefreshTimeout(50*time.Millisecond))
+		c.SetTokens(accessToken(t, time.Second), "refresh-0")
+
+		_, err := c.Refresh(context.Background())
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, "refresh-0", c.Tokens().RefreshToken)
+	})
+}
+
+func TestTransport(t *testing.T) {
+	newAPI := func(t *testing.T) (*httptest.Server, *atomic.Value) {
+		var authorization atomic.Value
+		api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization.Store(r.Header.Get("Authorization"))
+		}))
+		t.Cleanup(api.Close)
+		return api, &authorization
+	}
+
+	t.Run("attaches access token", func(t *testing.T) {
+		server := &authServer{t: t, expiresIn: time.Hour}
+		srv := httptest.NewServer(server)
+		defer srv.Close()
+		api, authorization := newAPI(t)
+
+		c := client.New(srv.URL)
+		tokens, err := c.Authenticate(context.Background(), "guid")
+		require.NoError(t, err)
+
+		req, _ := http.NewRequest(http.MethodGet, api.URL, nil)
+		resp, err := (&http.Client{Transport: c.Transport(nil)}).Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, "Bearer "+tokens.AccessToken, authorization.Load())
+		assert.Empty(t, req.Header.Get("Authorization"))
+		assert.Equal(t, int32(0), server.refreshes.Load())
+	})
+
+	t.Run("refreshes token before expiry", func(t *testing.T) {
+		server := &authServer{t: t, expiresIn: 10 * time.Second}
+		srv := httptest.NewServer(server)
+		defer srv.Close()
+		api, authorization := newAPI(t)
+
+		c := client.New(srv.URL, client.WithRefreshBefore(time.Minute))
+		_, err := c.Authenticate(context.Background(), "guid")
+		require.NoError(t, err)
+
+		resp, err := (&http.Client{Transport: c.Transport(nil)}).Get(api.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, int32(1), server.refreshes.Load())
+		assert.Equal(t, "Bearer "+c.Tokens().AccessToken, authorization.Load())
+		assert.Equal(t, "refresh-1", c.Tokens().RefreshToken)
+	})
+
+	t.Run("no tokens", func(t *testing.T) {
+		api, _ := newAPI(t)
+
+		_, err := (&http.Client{Transport: client.New("http://localhost").Transport(nil)}).Get(api.URL)
+		assert.ErrorIs(t, err, client.ErrNoTokens)
+	})
+}
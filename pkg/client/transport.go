@@ -0,0 +1,40 @@
+package client
+
+import (
+	"net/http"
+)
+
+// Transport - http.RoundTripper, который добавляет в запросы заголовок "Authorization: Bearer <токен>"
+// с Access токеном клиента и обновляет токен незадолго до истечения.
+type Transport struct {
+	Client *Client
+	Base   http.RoundTripper // Транспорт, которым выполняются запросы. Если не задан, используется http.DefaultTransport
+}
+
+// Transport возвращает http.RoundTripper, который авторизует запросы токенами клиента.
+func (c *Client) Transport(base http.RoundTripper) *Transport {
+	return &Transport{Client: c, Base: base}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	accessToken, err := t.Client.AccessToken(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	// RoundTripper не должен изменять исходный запрос
+	authorized := req.Clone(req.Context())
+	authorized.Header.Set("Authorization", "Bearer "+accessToken)
+
+	return t.base().RoundTrip(authorized)
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}